@@ -18,7 +18,11 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -27,11 +31,57 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"{{MODULE_NAME}}/internal/anonymize"
+	"{{MODULE_NAME}}/internal/apitest"
+	"{{MODULE_NAME}}/internal/backup"
+	"{{MODULE_NAME}}/internal/blob"
+	"{{MODULE_NAME}}/internal/cache"
+	"{{MODULE_NAME}}/internal/changefeed"
 	"{{MODULE_NAME}}/internal/config"
+	"{{MODULE_NAME}}/internal/connectors"
+	"{{MODULE_NAME}}/internal/connectors/shopify"
+	"{{MODULE_NAME}}/internal/consumers"
 	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/database/embedded"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/events/kafka"
+	natsevents "{{MODULE_NAME}}/internal/events/nats"
+	"{{MODULE_NAME}}/internal/export"
+	"{{MODULE_NAME}}/internal/forecast"
 	"{{MODULE_NAME}}/internal/handlers"
+	"{{MODULE_NAME}}/internal/id"
+	"{{MODULE_NAME}}/internal/inbox"
+	"{{MODULE_NAME}}/internal/inventory"
+	"{{MODULE_NAME}}/internal/jsonenc"
+	"{{MODULE_NAME}}/internal/lock"
+	"{{MODULE_NAME}}/internal/logging"
+	"{{MODULE_NAME}}/internal/lots"
+	"{{MODULE_NAME}}/internal/maintenance"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/notify"
+	"{{MODULE_NAME}}/internal/operations"
+	"{{MODULE_NAME}}/internal/outbox"
+	"{{MODULE_NAME}}/internal/preflight"
+	"{{MODULE_NAME}}/internal/pricing"
+	"{{MODULE_NAME}}/internal/promotions"
+	"{{MODULE_NAME}}/internal/pubsub"
+	"{{MODULE_NAME}}/internal/purchasing"
+	"{{MODULE_NAME}}/internal/queue"
+	"{{MODULE_NAME}}/internal/quota"
+	"{{MODULE_NAME}}/internal/reports"
 	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/repricing"
+	"{{MODULE_NAME}}/internal/returns"
 	"{{MODULE_NAME}}/internal/router"
+	"{{MODULE_NAME}}/internal/scheduler"
+	"{{MODULE_NAME}}/internal/search"
+	"{{MODULE_NAME}}/internal/serials"
+	"{{MODULE_NAME}}/internal/sku"
+	"{{MODULE_NAME}}/internal/stocktake"
+	"{{MODULE_NAME}}/internal/units"
+	"{{MODULE_NAME}}/internal/valuation"
+	"{{MODULE_NAME}}/internal/visibility"
+	"{{MODULE_NAME}}/internal/workflow"
 )
 
 func main() {
@@ -41,53 +91,606 @@ func main() {
 		}
 	}
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "preflight":
+			os.Exit(preflightCommand(os.Args[2:]))
+		case "admin":
+			os.Exit(adminCommand(os.Args[2:]))
+		case "apitest":
+			os.Exit(apitestCommand(os.Args[2:]))
+		}
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		slog.Error("failed to load configuration", "error", err)
 		os.Exit(1)
 	}
 
-	logger := setupLogger(cfg.LogLevel)
+	logger, logLevels, err := logging.New(os.Stdout, logging.Options{
+		Level:           cfg.LogLevel,
+		Format:          cfg.LogFormat,
+		ComponentLevels: cfg.LogLevels,
+		AddSource:       cfg.LogLevel == "debug",
+	})
+	if err != nil {
+		slog.Error("failed to set up logging", "error", err)
+		os.Exit(1)
+	}
+	encoder, _ := jsonenc.New(cfg.JSONEncoder) // Validated by config
+	jsonenc.Use(encoder)
+	idGenerator, _ := id.New(cfg.IDStrategy, cfg.Region) // Validated by config
+	id.Use(idGenerator)
+
 	logger.Info("starting {{SERVICE_NAME}}",
 		"environment", cfg.Environment,
 		"port", cfg.Port,
-		"database_type", "postgres",
+		"database_type", database.DialectOf(cfg.DBDriver),
+		"database_driver", cfg.DBDriver,
+		"json_encoder", encoder.Name(),
+		"id_strategy", idGenerator.Strategy(),
+		"region", cfg.Region,
 	)
 
+	// Embedded Postgres outlives a failed start-up unless stopped, so from here
+	// on exit runs the cleanups that os.Exit would skip
+	defer runAtExit()
+	if cfg.EmbeddedPostgres {
+		logger.Warn("starting embedded Postgres, meant for demos and CI", "port", cfg.EmbeddedPostgresPort)
+		server, err := embedded.Start(embedded.Config{
+			Port:    cfg.EmbeddedPostgresPort,
+			DataDir: cfg.EmbeddedPostgresDataDir,
+			Version: cfg.EmbeddedPostgresVersion,
+		}, logger)
+		if err != nil {
+			logger.Error("failed to start embedded Postgres", "error", err)
+			os.Exit(1)
+		}
+		atExit = append(atExit, func() {
+			if err := server.Stop(); err != nil {
+				logger.Error("failed to stop embedded Postgres", "error", err)
+			}
+		})
+	}
+
 	dbConfig := database.Config{
-		URL:      cfg.DatabaseURL,
+		URL:      databaseURL(cfg),
+		Driver:   cfg.DBDriver,
 		MaxConns: cfg.DBMaxConns,
 		MaxIdle:  cfg.DBMaxIdle,
+		Session: database.Session{
+			StatementTimeout:                cfg.DBStatementTimeout,
+			LockTimeout:                     cfg.DBLockTimeout,
+			IdleInTransactionSessionTimeout: cfg.DBIdleInTxTimeout,
+		},
 	}
 
 	db, err := database.NewConnection(dbConfig)
 	if err != nil {
 		logger.Error("failed to connect to database", "error", err)
-		os.Exit(1)
+		exit(1)
 	}
-	defer db.Close()
+	atExit = append(atExit, func() { db.Close() })
 
-	// Run migrations
-	logger.Info("running database migrations")
-	migrationsPath := os.Getenv("MIGRATIONS_PATH")
-	if migrationsPath == "" {
-		// Auto-detect migrations path relative to project root
-		if _, err := os.Stat("migrations"); err == nil {
-			migrationsPath = "migrations"
-		} else {
-			migrationsPath = "../../migrations"
+	// sqlcommenter tags for pg_stat_statements and APM tools
+	if len(cfg.QueryTags) > 0 && cfg.QueryTags[0] != "off" {
+		db.SetQueryTags("{{SERVICE_NAME}}", cfg.QueryTags)
+	}
+
+	// Pool resets and readiness during failovers of the primary
+	var failover *database.FailoverWatcher
+	if cfg.DBFailoverCheckInterval > 0 && db.Dialect() == database.Postgres {
+		failover = database.NewFailoverWatcher(db, dbConfig.URL, cfg.DBFailoverCheckInterval, cfg.ReadOnly, logger)
+	}
+
+	// Run migrations, except against a read-only database such as a replica
+	if cfg.ReadOnly {
+		logger.Warn("starting read-only: migrations, background workers, and writes are disabled")
+	} else {
+		logger.Info("running database migrations")
+		if err := database.RunMigrations(db, migrationsDir()); err != nil {
+			logger.Error("failed to run migrations", "error", err)
+			exit(1)
 		}
 	}
-	if err := database.RunMigrations(db, migrationsPath); err != nil {
-		logger.Error("failed to run migrations", "error", err)
-		os.Exit(1)
+
+	if cfg.SchemaCheck != "off" {
+		if err := db.VerifySchema(context.Background(), repository.ScannedTables); err != nil {
+			if cfg.SchemaCheck == "fail" {
+				logger.Error("database schema does not match models", "error", err)
+				exit(1)
+			}
+			logger.Warn("database schema does not match models", "error", err)
+		}
+	}
+
+	if cfg.EmbeddedPostgres {
+		seeded, err := embedded.Seed(context.Background(), repository.NewProductRepository(db), cfg.EmbeddedPostgresSeed)
+		if err != nil {
+			logger.Error("failed to seed embedded Postgres", "error", err)
+			exit(1)
+		}
+		if seeded > 0 {
+			logger.Info("seeded demo products", "count", seeded)
+		}
+	}
+
+	if cfg.PreflightOnStart {
+		report := preflight.Run(context.Background(), preflightChecks(cfg, db), 5*time.Second)
+		for _, result := range report.Results {
+			logger.Info("preflight check", "check", result.Name, "status", result.Status, "message", result.Message)
+		}
+		if !report.OK {
+			logger.Error("preflight checks failed")
+			exit(1)
+		}
+	}
+
+	// With FEATURE_FLAGS=shadow_list_filtered, listings run on their
+	// filter-based replacement too, to compare before switching to it
+	baseProductRepo := repository.NewProductRepository(db)
+	shadowedProductRepo := repository.NewShadowProductRepository(
+		baseProductRepo,
+		repository.NewFilteredListProductRepository(baseProductRepo),
+		func(ctx context.Context) bool {
+			cfg := config.FromContext(ctx)
+			return cfg != nil && cfg.FeatureEnabled("shadow_list_filtered")
+		},
+		logLevels.Component(logging.ComponentRepository),
+	)
+	productRepo := repository.NewInstrumentedProductRepository(
+		shadowedProductRepo,
+		cfg.RepositorySlowThreshold,
+		logLevels.Component(logging.ComponentRepository),
+	)
+	if cfg.DeduplicateReads {
+		productRepo = repository.NewDedupedProductRepository(productRepo)
+	}
+	auditRepo := repository.NewAuditRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	syncStateRepo := repository.NewSyncStateRepository(db)
+	statsRepo := repository.NewProductStatsRepository(db)
+	promotionRepo := repository.NewPromotionRepository(db)
+	bundleRepo := repository.NewBundleRepository(db)
+	regionRepo := repository.NewProductRegionRepository(db)
+	savedSearchRepo := repository.NewSavedSearchRepository(db)
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	trashRepo := repository.NewTrashRepository(db)
+
+	unitTable, err := units.Load(context.Background(), repository.NewUnitRepository(db))
+	if err != nil {
+		logger.Error("failed to load units", "error", err)
+		exit(1)
+	}
+
+	store := config.NewStore(cfg, reloadConfig)
+	store.OnReload(reloadHook(logger, logLevels, auditRepo))
+
+	bus := events.NewBus(logger)
+
+	// Background workers stop when workerCtx is cancelled during shutdown
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	if failover != nil {
+		go failover.Run(workerCtx)
+	}
+
+	var relayDone chan struct{}
+	if cfg.EventBroker != "" && !cfg.ReadOnly {
+		sink, err := newEventSink(workerCtx, cfg)
+		if err != nil {
+			logger.Error("failed to set up event broker", "broker", cfg.EventBroker, "error", err)
+			exit(1)
+		}
+		defer sink.Close()
+
+		relay := outbox.NewRelay(outboxRepo, db, sink, outbox.Config{
+			PollInterval: cfg.OutboxPollInterval,
+			BatchSize:    cfg.OutboxBatchSize,
+		}, logger)
+		bus.SubscribeAll(relay.Writer())
+
+		relayDone = make(chan struct{})
+		go func() {
+			defer close(relayDone)
+			relay.Run(workerCtx)
+		}()
+	} else if cfg.ChangeFeed && !cfg.ReadOnly {
+		// Without a relay the change feed still needs the outbox written
+		bus.SubscribeAll(outbox.Recorder(outboxRepo))
+	}
+	var changeFeed *changefeed.Feed
+	if cfg.ChangeFeed {
+		changeFeed = changefeed.New(outboxRepo)
+	}
+
+	// Deliveries to external destinations; read-only instances make none
+	var pool *queue.Pool
+	if !cfg.ReadOnly {
+		pool = queue.NewPool(repository.NewQueueRepository(db), db, queue.Config{
+			Workers:        cfg.QueueWorkers,
+			PerDestination: cfg.QueuePerDestination,
+			QueueSize:      cfg.QueueSize,
+			MaxAttempts:    cfg.QueueMaxAttempts,
+			RetryDelay:     cfg.QueueRetryDelay,
+			PollInterval:   cfg.QueuePollInterval,
+		}, logLevels.Component(logging.ComponentJobs))
+	}
+
+	// Notifications of watched product fields, delivered on the pool
+	if pool != nil {
+		notifier := notify.New(subscriptionRepo, pool, logLevels.Component(logging.ComponentJobs))
+		bus.Subscribe(notifier.Handler(), events.TypeProductUpdated, events.TypeStockAdjusted, events.TypeLotExpiring, events.TypeNoteMentioned)
+	}
+
+	lotRepo := repository.NewLotRepository(db)
+	lotService := lots.NewService(lotRepo, productRepo, db, bus, cfg.LotExpiryWarning, logLevels.Component(logging.ComponentJobs))
+	inventoryService := inventory.NewService(productRepo, bundleRepo, orderRepo, db, lotService, unitTable, bus, cfg.LowStockThreshold, logger)
+	orderInbox := inbox.New(repository.NewInboxRepository(db), db)
+	valuationRepo := repository.NewValuationRepository(db)
+	valuer := valuation.NewService(valuationRepo, productRepo, db, bus, cfg.ValuationMethod, logLevels.Component(logging.ComponentJobs))
+	serialRepo := repository.NewSerialRepository(db)
+	serialService := serials.NewService(serialRepo, productRepo, db)
+	stockTakeRepo := repository.NewStockTakeRepository(db)
+	stockTakes := stocktake.NewService(stockTakeRepo, productRepo, auditRepo, db, bus, logger)
+	returnRepo := repository.NewReturnRepository(db)
+	forecastRepo := repository.NewForecastRepository(db)
+	forecaster := forecast.NewService(forecastRepo, productRepo, db, forecast.Config{
+		Window:      cfg.ForecastWindow,
+		Horizon:     cfg.ForecastHorizon,
+		LeadTime:    cfg.ForecastLeadTime,
+		SafetyStock: cfg.LowStockThreshold,
+	}, logLevels.Component(logging.ComponentJobs))
+	reportRepo := repository.NewReportRepository(db)
+	reportService := reports.NewService(reportRepo, auditRepo, db, reports.Config{Timeout: cfg.ReportTimeout, MaxRows: cfg.ReportMaxRows}, logger)
+	purchaseOrderRepo := repository.NewPurchaseOrderRepository(db)
+	purchaser := purchasing.NewService(purchaseOrderRepo, productRepo, auditRepo, db, bus, purchasing.Config{
+		OrderCost:   cfg.PurchaseOrderCost,
+		HoldingRate: cfg.PurchaseHoldingRate,
+	}, logLevels.Component(logging.ComponentJobs))
+	returnService := returns.NewService(returnRepo, productRepo, repository.NewStockMovementRepository(db), auditRepo, db, bus, logger)
+
+	var consumerRunner *consumers.Runner
+	if cfg.ConsumersEnabled && !cfg.ReadOnly {
+		nc, js, err := natsevents.Connect(natsevents.Config{URL: cfg.NATSURL, Name: "{{SERVICE_NAME}}-consumers"})
+		if err != nil {
+			logger.Error("failed to connect consumers to NATS", "error", err)
+			exit(1)
+		}
+		defer nc.Close()
+
+		consumerRunner = consumers.NewRunner(js, logger)
+		consumerRunner.Register(consumers.Consumer{
+			Name:    cfg.NATSDurablePrefix + "-orders",
+			Stream:  cfg.NATSOrdersStream,
+			Subject: cfg.NATSOrdersSubject,
+			Handler: consumers.Orders(inventoryService, orderInbox),
+		})
+		if err := consumerRunner.Start(workerCtx); err != nil {
+			logger.Error("failed to start consumers", "error", err)
+			exit(1)
+		}
+	}
+
+	jobs := scheduler.New(logLevels.Component(logging.ComponentJobs))
+	locker := lock.New(db) // Jobs on shared state run on one instance at a time
+	if cfg.CatalogConnector != "" {
+		connector, err := newCatalogConnector(cfg)
+		if err != nil {
+			logger.Error("failed to set up catalog connector", "connector", cfg.CatalogConnector, "error", err)
+			exit(1)
+		}
+		policy, _ := connectors.ParseConflictPolicy(cfg.CatalogSyncConflict) // Validated by config
+		syncer := connectors.NewSyncer(connector, productRepo, syncStateRepo, db, bus, policy, logLevels.Component(logging.ComponentJobs))
+		if pool != nil {
+			syncer.QueuePushes(pool)
+		}
+
+		if err := jobs.Register(scheduler.Job{
+			Name:       "catalog-sync-" + connector.Name(),
+			Interval:   cfg.CatalogSyncInterval,
+			Run:        locker.Exclusive("catalog-sync-"+connector.Name(), syncer.Run),
+			RunOnStart: true,
+		}); err != nil {
+			logger.Error("failed to schedule catalog sync", "error", err)
+			exit(1)
+		}
+	}
+	// SQLite tables aren't partitioned; AUDIT_LOG_RETENTION purges audit rows there
+	if cfg.PartitionMaintenanceInterval > 0 && db.Dialect() == database.Postgres {
+		partitioner := maintenance.NewPartitioner(db, []maintenance.PartitionPolicy{
+			{Table: "audit_log", Retention: cfg.AuditLogRetentionMonths},
+			{Table: "stock_movements", Retention: cfg.StockMovementRetentionMonths},
+		}, cfg.PartitionPremakeMonths, cfg.PartitionExpired != "drop", logLevels.Component(logging.ComponentJobs))
+
+		if err := jobs.Register(scheduler.Job{
+			Name:       "partition-maintenance",
+			Interval:   cfg.PartitionMaintenanceInterval,
+			Timeout:    5 * time.Minute,
+			Run:        locker.Exclusive("partition-maintenance", partitioner.Run),
+			RunOnStart: true,
+		}); err != nil {
+			logger.Error("failed to schedule partition maintenance", "error", err)
+			exit(1)
+		}
+	}
+	purger := maintenance.NewPurger(db, []maintenance.RetentionPolicy{
+		{Name: "audit_log", Table: "audit_log", Key: "id", Column: "created_at", Retention: cfg.AuditLogRetention},
+		{Name: "processed_orders", Table: "processed_orders", Key: "source, order_id", Column: "processed_at", Retention: cfg.ProcessedOrderRetention},
+		{Name: "inbox_messages", Table: "inbox_messages", Key: "source, message_id", Column: "received_at", Retention: cfg.InboxRetention},
+		{Name: "event_outbox", Table: "event_outbox", Key: "id", Column: "published_at", Where: "published_at IS NOT NULL", Retention: cfg.OutboxRetention},
+		{Name: "trashed_products", Table: "trashed_products", Key: "id", Column: "trashed_at", Retention: cfg.TrashRetention},
+		{Name: "webhook_deliveries", Table: "webhook_deliveries", Key: "id", Column: "created_at", Retention: cfg.WebhookDeliveryRetention},
+	}, cfg.RetentionBatchSize, logLevels.Component(logging.ComponentJobs))
+	if cfg.RetentionPurgeInterval > 0 {
+		if err := jobs.Register(scheduler.Job{
+			Name:     "retention-purge",
+			Interval: cfg.RetentionPurgeInterval,
+			Run:      locker.Exclusive("retention-purge", purger.Run),
+		}); err != nil {
+			logger.Error("failed to schedule retention purge", "error", err)
+			exit(1)
+		}
+	}
+	if cfg.ValuationInterval > 0 {
+		if err := jobs.Register(scheduler.Job{
+			Name:     "inventory-valuation",
+			Interval: cfg.ValuationInterval,
+			Timeout:  5 * time.Minute,
+			Run:      locker.Exclusive("inventory-valuation", valuer.Run),
+		}); err != nil {
+			logger.Error("failed to schedule inventory valuation", "error", err)
+			exit(1)
+		}
+	}
+	if cfg.ForecastInterval > 0 {
+		if err := jobs.Register(scheduler.Job{
+			Name:     "demand-forecast",
+			Interval: cfg.ForecastInterval,
+			Timeout:  10 * time.Minute,
+			Run:      locker.Exclusive("demand-forecast", forecaster.Run),
+		}); err != nil {
+			logger.Error("failed to schedule demand forecast", "error", err)
+			exit(1)
+		}
+	}
+	if cfg.PurchaseOrderDraftInterval > 0 {
+		if err := jobs.Register(scheduler.Job{
+			Name:     "purchase-order-drafts",
+			Interval: cfg.PurchaseOrderDraftInterval,
+			Timeout:  5 * time.Minute,
+			Run:      locker.Exclusive("purchase-order-drafts", purchaser.Run),
+		}); err != nil {
+			logger.Error("failed to schedule purchase order drafts", "error", err)
+			exit(1)
+		}
+	}
+	if cfg.LotExpiryCheckInterval > 0 {
+		if err := jobs.Register(scheduler.Job{
+			Name:     "lot-expiry-alerts",
+			Interval: cfg.LotExpiryCheckInterval,
+			Timeout:  5 * time.Minute,
+			Run:      locker.Exclusive("lot-expiry-alerts", lotService.Run),
+		}); err != nil {
+			logger.Error("failed to schedule lot expiry alerts", "error", err)
+			exit(1)
+		}
+	}
+	if cfg.VisibilityCheckInterval > 0 {
+		announcer := visibility.NewService(repository.NewProductVisibilityRepository(db), productRepo, db, bus, logLevels.Component(logging.ComponentJobs))
+		if err := jobs.Register(scheduler.Job{
+			Name:     "product-visibility",
+			Interval: cfg.VisibilityCheckInterval,
+			Timeout:  5 * time.Minute,
+			Run:      locker.Exclusive("product-visibility", announcer.Run),
+		}); err != nil {
+			logger.Error("failed to schedule product visibility announcements", "error", err)
+			exit(1)
+		}
+	}
+	integrity := maintenance.NewIntegrityChecker(db, maintenance.IntegrityChecks, cfg.IntegrityRepair, logLevels.Component(logging.ComponentJobs))
+	if cfg.IntegrityCheckInterval > 0 {
+		if err := jobs.Register(scheduler.Job{
+			Name:     "integrity-check",
+			Interval: cfg.IntegrityCheckInterval,
+			Timeout:  5 * time.Minute,
+			Run:      locker.Exclusive("integrity-check", integrity.Run),
+		}); err != nil {
+			logger.Error("failed to schedule integrity check", "error", err)
+			exit(1)
+		}
+	}
+	if cfg.ProductStatsRefreshInterval > 0 {
+		if err := jobs.Register(scheduler.Job{
+			Name:     "product-stats-refresh",
+			Interval: cfg.ProductStatsRefreshInterval,
+			Run:      locker.Exclusive("product-stats-refresh", statsRepo.Refresh),
+		}); err != nil {
+			logger.Error("failed to schedule product stats refresh", "error", err)
+			exit(1)
+		}
+	}
+	// Long-running operations of async endpoints; read-only instances can't
+	// record them
+	operationRepo := repository.NewOperationRepository(db)
+	var runner *operations.Runner
+	if !cfg.ReadOnly {
+		runner = operations.NewRunner(operationRepo, logLevels.Component(logging.ComponentJobs))
+		if err := runner.Start(workerCtx); err != nil {
+			logger.Error("failed to start operations", "error", err)
+			exit(1)
+		}
+	}
+	// Sagas, multi-step workflows across systems, started through the
+	// coordinator their definitions are registered with; the resume job runs
+	// those an instance stopped running to their end
+	if !cfg.ReadOnly {
+		coordinator := workflow.NewCoordinator(repository.NewSagaRepository(db), logLevels.Component(logging.ComponentJobs))
+		if err := jobs.Register(scheduler.Job{
+			Name:       "saga-resume",
+			Interval:   cfg.SagaResumeInterval,
+			Run:        coordinator.Resume,
+			RunOnStart: true,
+		}); err != nil {
+			logger.Error("failed to schedule saga resumption", "error", err)
+			exit(1)
+		}
 	}
 
-	productRepo := repository.NewProductRepository(db)
+	// Catalog exports to the blob store; read-only instances can't record them
+	exportRepo := repository.NewExportRepository(db)
+	var exporter *export.Exporter
+	if cfg.BlobStore != "" && !cfg.ReadOnly {
+		blobStore, err := newBlobStore(cfg)
+		if err != nil {
+			logger.Error("failed to set up blob store", "store", cfg.BlobStore, "error", err)
+			exit(1)
+		}
+		exporter = export.New(exportRepo, blobStore, export.Config{
+			Prefix:  cfg.ExportPrefix,
+			Format:  cfg.ExportFormat,
+			Filter:  models.ExportFilter{Category: cfg.ExportCategory, Tag: cfg.ExportTag},
+			Timeout: cfg.ExportTimeout,
+		}, logLevels.Component(logging.ComponentJobs))
+		if err := exporter.Start(workerCtx); err != nil {
+			logger.Error("failed to start exporter", "error", err)
+			exit(1)
+		}
 
-	productHandler := handlers.NewProductHandler(productRepo, logger)
+		if cfg.ExportInterval > 0 {
+			if err := jobs.Register(scheduler.Job{
+				Name:     "catalog-export",
+				Interval: cfg.ExportInterval,
+				Timeout:  cfg.ExportTimeout,
+				Run:      locker.Exclusive("catalog-export", exporter.Run),
+			}); err != nil {
+				logger.Error("failed to schedule catalog export", "error", err)
+				exit(1)
+			}
+		}
+	}
+	// Product search; an OpenSearch or Meilisearch index is synced from the
+	// change feed
+	searchRepo := repository.NewSearchRepository(db)
+	fuzzyThreshold := cfg.SearchFuzzyThreshold
+	if db.Dialect() == database.SQLite {
+		fuzzyThreshold = 0 // No pg_trgm; SQLite searches match substrings already
+	}
+	var searchBackend search.Backend = search.NewPostgres(searchRepo, fuzzyThreshold)
+	var indexer *search.Indexer
+	if cfg.SearchBackend == "opensearch" || cfg.SearchBackend == "meilisearch" {
+		index, err := newSearchIndex(cfg)
+		if err != nil {
+			logger.Error("failed to set up search index", "error", err)
+			exit(1)
+		}
+		searchBackend = index
 
-	handler := router.New(productHandler, logger)
+		indexer = search.NewIndexer(index, changeFeed, searchRepo, exportRepo, logLevels.Component(logging.ComponentJobs))
+		if err := jobs.Register(scheduler.Job{
+			Name:       "search-index",
+			Interval:   cfg.SearchSyncInterval,
+			Timeout:    30 * time.Minute, // A sync may rebuild the index
+			Run:        locker.Exclusive("search-index", indexer.Sync),
+			RunOnStart: true,
+		}); err != nil {
+			logger.Error("failed to schedule search indexing", "error", err)
+			exit(1)
+		}
+	}
+
+	// API key usage, counted in memory and stored by a job; read-only
+	// instances can't store it, so they don't meter
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	usageRepo := repository.NewUsageRepository(db)
+	var meter *quota.Meter
+	if !cfg.ReadOnly {
+		meter = quota.New(apiKeyRepo, usageRepo)
+		if err := jobs.Register(scheduler.Job{
+			Name:     "usage-flush",
+			Interval: cfg.QuotaFlushInterval,
+			Run:      meter.Flush,
+		}); err != nil {
+			logger.Error("failed to schedule usage flush", "error", err)
+			exit(1)
+		}
+	}
+
+	if !cfg.ReadOnly {
+		pool.Start(workerCtx)
+		jobs.Start(workerCtx)
+	}
+
+	var skuGenerator *sku.Generator
+	if cfg.SKUPattern != "" {
+		sequence := repository.NewSKUCounterRepository(db)
+		if cfg.SKUSequence == "sequence" {
+			sequence = repository.NewSKUSequenceRepository(db)
+		}
+		if skuGenerator, err = sku.New(cfg.SKUPattern, sequence, productRepo); err != nil {
+			logger.Error("invalid SKU pattern", "error", err)
+			exit(1)
+		}
+	}
+	facets := models.FacetSpec{PriceBounds: cfg.FacetPriceBounds, LowStock: cfg.AvailabilityLowStock}
+	noteRepo := repository.NewProductNoteRepository(db)
+	productHandler := handlers.NewProductHandler(productRepo, savedSearchRepo, trashRepo, repository.NewProductRevisionRepository(db), repository.NewProductSlugRepository(db), noteRepo, db, bus, promotions.NewService(promotionRepo), unitTable, skuGenerator, facets, logger)
+	mode := maintenance.NewMode(cfg.MaintenanceMode, cfg.ReadOnly, cfg.MaintenanceRetryAfter)
+	if cfg.MaintenanceMode {
+		logger.Warn("starting in maintenance mode, writes are refused until it is switched off")
+	}
+	adminHandler := handlers.NewAdminHandler(store, logLevels, auditRepo, purger, integrity, mode, backup.NewArchiver(db, backup.Tables), repository.NewQueryExplainer(db), logger)
+	integrationHandler := handlers.NewIntegrationHandler(inventoryService, orderInbox, syncStateRepo, cfg.OrderWebhookSecret, logger)
+	if cfg.OrderWebhookSecret == "" {
+		logger.Warn("ORDER_WEBHOOK_SECRET is not set, order webhooks will be rejected")
+	}
+
+	// Signals between instances, like cache invalidations
+	cluster := pubsub.New(db, databaseURL(cfg), logLevels.Component(logging.ComponentJobs))
+
+	var responseCache *cache.Cache
+	if cfg.ResponseCache != "" {
+		var cacheStore cache.Store = cache.NewLRU(cfg.ResponseCacheSize)
+		if cfg.ResponseCache == "redis" {
+			redisStore, err := cache.NewRedis(cfg.RedisURL, "{{SERVICE_NAME}}:cache:")
+			if err != nil {
+				logger.Error("failed to set up response cache", "error", err)
+				exit(1)
+			}
+			defer redisStore.Close()
+			cacheStore = redisStore
+		}
+		responseCache = cache.New(cacheStore, cfg.ResponseCacheTTL, logger)
+		bus.SubscribeAll(responseCache.Invalidator())
+		if cfg.ResponseCache == "memory" {
+			responseCache.Share(cluster) // Each instance holds its own entries
+		}
+		logger.Info("response cache enabled", "store", cfg.ResponseCache, "ttl", cfg.ResponseCacheTTL)
+	}
+	if err := cluster.Start(workerCtx); err != nil {
+		logger.Error("failed to listen for other instances", "error", err)
+		exit(1)
+	}
+
+	priceRules, err := pricing.New(cfg.Pricing())
+	if err != nil {
+		logger.Error("invalid tax rules", "error", err)
+		exit(1)
+	}
+	pricingHandler := handlers.NewPricingHandler(productRepo, priceRules, repricing.NewService(productRepo, auditRepo, db, bus, priceRules, logger), logger)
+	availabilityHandler := handlers.NewAvailabilityHandler(productRepo, cfg.AvailabilityLowStock, logger)
+	relatedHandler := handlers.NewRelatedHandler(productRepo, handlers.RelatedLimits{
+		DefaultLimit: cfg.RelatedProductsLimit,
+		MaxLimit:     cfg.RelatedProductsMaxLimit,
+		PriceBand:    cfg.RelatedPriceBand,
+	}, logger)
+
+	promotionHandler := handlers.NewPromotionHandler(promotionRepo, responseCache, logger)
+	savedSearchHandler := handlers.NewSavedSearchHandler(savedSearchRepo, responseCache, logger)
+
+	handler := router.New(productHandler, handlers.NewStatsHandler(statsRepo, valuer, cfg.ABCAnalysisWindow, logger), handlers.NewReceiptHandler(valuationRepo, productRepo, valuer, logger), handlers.NewLotHandler(lotRepo, productRepo, lotService, logger), handlers.NewSerialHandler(serialRepo, productRepo, serialService, logger), handlers.NewStockTakeHandler(stockTakeRepo, stockTakes, logger), handlers.NewReturnHandler(returnRepo, returnService, logger), handlers.NewForecastHandler(forecastRepo, forecaster, logger), handlers.NewPurchaseOrderHandler(purchaseOrderRepo, purchaser, logger), handlers.NewTimelineHandler(repository.NewTimelineRepository(db), productRepo, logger), handlers.NewChangeHandler(changeFeed, regionRepo, logger), handlers.NewSearchHandler(searchBackend, facets, indexer, runner, auditRepo, logger), pricingHandler, availabilityHandler, relatedHandler, handlers.NewBundleHandler(bundleRepo, logger), promotionHandler, savedSearchHandler, handlers.NewSubscriptionHandler(subscriptionRepo, productRepo, logger), handlers.NewTrashHandler(trashRepo, productRepo, db, bus, cfg.TrashRetention, logger), adminHandler, handlers.NewExportHandler(exportRepo, exporter, runner, auditRepo, logger), handlers.NewOperationHandler(operationRepo, runner, auditRepo, logger), handlers.NewReportHandler(reportRepo, reportService, logger), handlers.NewAPIKeyHandler(apiKeyRepo, usageRepo, meter, auditRepo, logger), handlers.NewRegionHandler(regionRepo, productRepo, db, bus, auditRepo, logger), handlers.NewNoteHandler(noteRepo, productRepo, db, bus, logger), integrationHandler, handlers.NewReadinessHandler(failover, logger), productRepo, meter, store, mode, responseCache, logLevels.Component(logging.ComponentHTTP))
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
@@ -101,7 +704,16 @@ func main() {
 		logger.Info("server starting", "address", srv.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("server failed to start", "error", err)
-			os.Exit(1)
+			exit(1)
+		}
+	}()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			logger.Info("received SIGHUP, reloading configuration")
+			_, _ = store.Reload(context.Background(), "signal", "SIGHUP")
 		}
 	}()
 
@@ -116,40 +728,589 @@ func main() {
 
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Error("server forced to shutdown", "error", err)
-		os.Exit(1)
+		exit(1)
+	}
+
+	if consumerRunner != nil {
+		consumerRunner.Stop(ctx)
+	}
+
+	stopWorkers()
+	jobs.Wait()
+	// After the server, so no request is counted past the last flush
+	if meter != nil {
+		if err := meter.Flush(ctx); err != nil {
+			logger.Error("failed to store API key usage", "error", err)
+		}
+	}
+	if runner != nil {
+		runner.Wait()
+	}
+	// After the jobs, which may still be submitting tasks
+	if pool != nil {
+		pool.Stop(ctx)
+	}
+	if relayDone != nil {
+		<-relayDone
 	}
 
 	logger.Info("server stopped")
 }
 
-// setupLogger configures structured logging with slog
-func setupLogger(level string) *slog.Logger {
-	var logLevel slog.Level
+// atExit holds cleanups run in reverse order when main returns or exits
+var atExit []func()
 
-	switch level {
-	case "debug":
-		logLevel = slog.LevelDebug
-	case "info":
-		logLevel = slog.LevelInfo
-	case "warn":
-		logLevel = slog.LevelWarn
-	case "error":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
+func runAtExit() {
+	for i := len(atExit) - 1; i >= 0; i-- {
+		atExit[i]()
+	}
+	atExit = nil
+}
+
+// exit runs the atExit cleanups, which os.Exit skips like deferred calls,
+// and exits with code
+func exit(code int) {
+	runAtExit()
+	os.Exit(code)
+}
+
+// databaseURL returns the embedded server's URL with EMBEDDED_POSTGRES,
+// DATABASE_URL otherwise
+func databaseURL(cfg *config.Config) string {
+	if cfg.EmbeddedPostgres {
+		return embedded.URL(cfg.EmbeddedPostgresPort)
+	}
+	return cfg.DatabaseURL
+}
+
+// migrationsDir returns MIGRATIONS_PATH, or the migrations directory found
+// relative to the working directory
+func migrationsDir() string {
+	if path := os.Getenv("MIGRATIONS_PATH"); path != "" {
+		return path
+	}
+	// Auto-detect migrations path relative to project root
+	if _, err := os.Stat("migrations"); err == nil {
+		return "migrations"
 	}
+	return "../../migrations"
+}
 
-	opts := &slog.HandlerOptions{
-		Level:     logLevel,
-		AddSource: level == "debug",
+// preflightCommand runs `api preflight [-json] [-timeout 5s]`, printing a
+// report of every check and returning the process exit code
+func preflightCommand(args []string) int {
+	flags := flag.NewFlagSet("preflight", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	timeout := flags.Duration("timeout", 5*time.Second, "timeout per check")
+	if err := flags.Parse(args); err != nil {
+		return 2
 	}
 
-	var handler slog.Handler
-	if os.Getenv("ENVIRONMENT") == "production" {
-		handler = slog.NewJSONHandler(os.Stdout, opts)
+	var checks []preflight.Check
+	cfg, err := config.Load()
+	if err != nil {
+		checks = append(checks, failedCheck("config", err))
 	} else {
-		handler = slog.NewTextHandler(os.Stdout, opts)
+		db, err := database.NewConnection(database.Config{URL: databaseURL(cfg), Driver: cfg.DBDriver, MaxConns: 2})
+		if err != nil {
+			checks = append(checks, preflight.Config(cfg), failedCheck("database", err))
+		} else {
+			defer db.Close()
+			checks = preflightChecks(cfg, db)
+		}
 	}
 
-	return slog.New(handler)
+	report := preflight.Run(context.Background(), checks, *timeout)
+	if *asJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil || !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// apitestCommand runs `api apitest -base-url URL [-token t] [-admin-token t]`,
+// the smoke-test scenario of apitest against a running instance, printing
+// each step and returning 1 if any failed. It needs no configuration or
+// database, only the instance's URL.
+func apitestCommand(args []string) int {
+	flags := flag.NewFlagSet("apitest", flag.ContinueOnError)
+	baseURL := flags.String("base-url", "", "base URL of the instance, e.g. https://api.example.com")
+	token := flags.String("token", os.Getenv("APITEST_TOKEN"), "bearer token of product requests, if the instance requires API keys")
+	adminToken := flags.String("admin-token", os.Getenv("APITEST_ADMIN_TOKEN"), "admin token, to check the admin API accepts it; skipped when empty")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout per request")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *baseURL == "" {
+		fmt.Fprintln(os.Stderr, "usage: api apitest -base-url URL [-token t] [-admin-token t] [-timeout 10s]")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report := apitest.Run(ctx, apitest.NewClient(*baseURL, *token, *timeout), apitest.Config{AdminToken: *adminToken}, os.Stdout)
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
+
+// adminCommand runs `api admin backup [-o file]`,
+// `api admin restore -i file -yes`, `api admin reindex`, and
+// `api admin anonymize [-rules file] -yes`. Backups go to stdout by default,
+// so they can be piped to object storage; the manifest, or the rebuilt search
+// index, is printed to stderr.
+func adminCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "backup" && args[0] != "restore" && args[0] != "reindex" && args[0] != "assign-uids" && args[0] != "assign-slugs" && args[0] != "anonymize") {
+		fmt.Fprintln(os.Stderr, "usage: api admin backup [-o file] | api admin restore -i file -yes | api admin reindex | api admin assign-uids [-batch n] | api admin assign-slugs [-all] [-batch n] | api admin anonymize [-rules file] [-salt s] [-batch n] -yes")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("admin "+args[0], flag.ContinueOnError)
+	output := flags.String("o", "-", "backup: output file, - for stdout")
+	input := flags.String("i", "-", "restore: backup file, - for stdin")
+	confirm := flags.Bool("yes", false, "restore, anonymize: confirm replacing data")
+	batch := flags.Int("batch", 1000, "assign-uids, assign-slugs, anonymize: rows per transaction")
+	all := flags.Bool("all", false, "assign-slugs: make every product's slug anew from its name, not only missing ones")
+	rulesFile := flags.String("rules", "", "anonymize: JSON ruleset, empty for the default rules")
+	salt := flags.String("salt", "", "anonymize: key of hashed values, random if empty; reuse it to anonymize another snapshot the same way")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if args[0] == "restore" && !*confirm {
+		fmt.Fprintln(os.Stderr, "restore replaces all data in the database; pass -yes to confirm")
+		return 2
+	}
+	if args[0] == "anonymize" && !*confirm {
+		fmt.Fprintln(os.Stderr, "anonymize overwrites data in the database; pass -yes to confirm")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to load configuration:", err)
+		return 1
+	}
+	db, err := database.NewConnection(database.Config{URL: databaseURL(cfg), Driver: cfg.DBDriver, MaxConns: 2})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to connect to database:", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if args[0] == "assign-uids" {
+		assigned, err := runAssignUIDs(ctx, cfg, db, *batch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "assign-uids failed after %d products: %v\n", assigned, err)
+			return 1
+		}
+		enc := json.NewEncoder(os.Stderr)
+		enc.SetIndent("", "  ")
+		enc.Encode(map[string]interface{}{"assigned": assigned, "strategy": cfg.IDStrategy, "region": cfg.Region})
+		return 0
+	}
+
+	if args[0] == "assign-slugs" {
+		changed, err := runAssignSlugs(ctx, db, *all, *batch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "assign-slugs failed after %d products: %v\n", changed, err)
+			return 1
+		}
+		enc := json.NewEncoder(os.Stderr)
+		enc.SetIndent("", "  ")
+		enc.Encode(map[string]interface{}{"changed": changed, "all": *all})
+		return 0
+	}
+
+	if args[0] == "anonymize" {
+		if cfg.IsProduction() {
+			fmt.Fprintln(os.Stderr, "anonymize refuses to run with ENVIRONMENT=production; restore the snapshot into another database first")
+			return 2
+		}
+		rewritten, err := runAnonymize(ctx, db, *rulesFile, *salt, *batch)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "anonymize failed:", err)
+			return 1
+		}
+		enc := json.NewEncoder(os.Stderr)
+		enc.SetIndent("", "  ")
+		enc.Encode(map[string]interface{}{"rewritten": rewritten})
+		return 0
+	}
+
+	if args[0] == "reindex" {
+		state, err := runReindex(ctx, cfg, db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "reindex failed:", err)
+			return 1
+		}
+		enc := json.NewEncoder(os.Stderr)
+		enc.SetIndent("", "  ")
+		enc.Encode(state)
+		return 0
+	}
+
+	archiver := backup.NewArchiver(db, backup.Tables)
+	var manifest *backup.Manifest
+	if args[0] == "backup" {
+		manifest, err = runBackup(ctx, archiver, *output)
+	} else {
+		manifest, err = runRestore(ctx, archiver, db, *input)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", args[0], err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stderr)
+	enc.SetIndent("", "  ")
+	enc.Encode(manifest)
+	return 0
+}
+
+func runBackup(ctx context.Context, archiver *backup.Archiver, output string) (*backup.Manifest, error) {
+	if output == "-" {
+		return archiver.Export(ctx, os.Stdout)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := archiver.Export(ctx, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output) // Don't leave a partial backup behind
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func runRestore(ctx context.Context, archiver *backup.Archiver, db *database.DB, input string) (*backup.Manifest, error) {
+	r := io.Reader(os.Stdin)
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	manifest, err := archiver.Restore(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recorded after the restore, which replaced the audit log
+	details, _ := json.Marshal(map[string]interface{}{"source": input, "rows": manifest.Rows, "sha256": manifest.SHA256})
+	entry := &models.AuditEntry{Action: "backup.restore", Actor: "cli", EntityType: "backup", Details: details}
+	if err := repository.NewAuditRepository(db).Create(ctx, entry); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to record restore in audit log:", err)
+	}
+
+	// Views still hold the replaced data
+	if err := db.RefreshMaterializedViews(ctx, false); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to refresh materialized views, they catch up on the next scheduled refresh:", err)
+	}
+	return manifest, nil
+}
+
+// runAnonymize applies the rules in rulesFile, or the default ones, to the
+// database, then refreshes materialized views, which still hold the
+// original data
+func runAnonymize(ctx context.Context, db *database.DB, rulesFile, salt string, batch int) (map[string]int64, error) {
+	rules := anonymize.DefaultRules
+	if rulesFile != "" {
+		f, err := os.Open(rulesFile)
+		if err != nil {
+			return nil, err
+		}
+		rules, err = anonymize.ReadRules(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key := []byte(salt)
+	if salt == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	anonymizer, err := anonymize.New(db, rules, key, batch)
+	if err != nil {
+		return nil, err
+	}
+	rewritten, err := anonymizer.Run(ctx)
+	if err != nil {
+		return rewritten, err
+	}
+
+	// Recorded after the rules, which may have scrubbed the audit log
+	details, _ := json.Marshal(map[string]interface{}{"rules": len(rules), "rewritten": rewritten})
+	entry := &models.AuditEntry{Action: "data.anonymize", Actor: "cli", EntityType: "database", Details: details}
+	if err := repository.NewAuditRepository(db).Create(ctx, entry); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to record anonymize in audit log:", err)
+	}
+
+	if err := db.RefreshMaterializedViews(ctx, false); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to refresh materialized views, they catch up on the next scheduled refresh:", err)
+	}
+	return rewritten, nil
+}
+
+// runReindex rebuilds the search index from scratch. A running server
+// notices on its next sync and continues from the new index's cursor.
+func runReindex(ctx context.Context, cfg *config.Config, db *database.DB) (*models.SearchIndexState, error) {
+	index, err := newSearchIndex(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	searchRepo := repository.NewSearchRepository(db)
+	feed := changefeed.New(repository.NewOutboxRepository(db))
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	state, err := search.NewIndexer(index, feed, searchRepo, repository.NewExportRepository(db), logger).Reindex(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{"alias": state.Name, "index": state.IndexName})
+	entry := &models.AuditEntry{Action: "search.reindex", Actor: "cli", EntityType: "search_index", EntityID: state.Name, Details: details}
+	if err := repository.NewAuditRepository(db).Create(ctx, entry); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to record reindex in audit log:", err)
+	}
+	return state, nil
+}
+
+// runAssignUIDs gives every product created before ID_STRATEGY was switched
+// from serial a unique ID, batch by batch, so it can run against a live
+// database and be resumed. Each product gets a version in its history.
+func runAssignUIDs(ctx context.Context, cfg *config.Config, db *database.DB, batch int) (int, error) {
+	generator, err := id.New(cfg.IDStrategy, cfg.Region)
+	if err != nil {
+		return 0, err
+	}
+	if generator.Strategy() == id.Serial {
+		return 0, fmt.Errorf("ID_STRATEGY is serial, set it to uuid or ulid first")
+	}
+	if batch < 1 {
+		return 0, fmt.Errorf("-batch must be positive")
+	}
+
+	products := repository.NewProductRepository(db)
+	total := 0
+	for {
+		n, err := products.AssignUIDs(ctx, generator.Next, batch)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < batch {
+			break
+		}
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{"assigned": total, "strategy": generator.Strategy(), "region": cfg.Region})
+	entry := &models.AuditEntry{Action: "product.assign_uids", Actor: "cli", EntityType: "product", Details: details}
+	if err := repository.NewAuditRepository(db).Create(ctx, entry); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to record assign-uids in audit log:", err)
+	}
+	return total, nil
+}
+
+// runAssignSlugs gives products without a slug, or every product with all,
+// the slug made from their name, in batches. Slugs replaced keep redirecting.
+func runAssignSlugs(ctx context.Context, db *database.DB, all bool, batch int) (int, error) {
+	if batch < 1 {
+		return 0, fmt.Errorf("-batch must be positive")
+	}
+
+	slugs := repository.NewProductSlugRepository(db)
+	total, after := 0, 0
+	for {
+		n, last, err := slugs.Regenerate(ctx, after, batch, all)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if last == 0 {
+			break
+		}
+		after = last
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{"changed": total, "all": all})
+	entry := &models.AuditEntry{Action: "product.assign_slugs", Actor: "cli", EntityType: "product", Details: details}
+	if err := repository.NewAuditRepository(db).Create(ctx, entry); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to record assign-slugs in audit log:", err)
+	}
+	return total, nil
+}
+
+func preflightChecks(cfg *config.Config, db *database.DB) []preflight.Check {
+	return []preflight.Check{
+		preflight.Config(cfg),
+		preflight.Database(db),
+		preflight.Extensions(db, cfg.RequiredExtensions),
+		preflight.Migrations(db, migrationsDir()),
+		preflight.Schema(db, repository.ScannedTables),
+	}
+}
+
+// failedCheck reports an error that prevented the real check from running
+func failedCheck(name string, err error) preflight.Check {
+	return preflight.Check{Name: name, Run: func(ctx context.Context) (preflight.Status, string) {
+		return preflight.StatusFail, err.Error()
+	}}
+}
+
+// newEventSink creates the outbox sink for the configured event broker
+func newEventSink(ctx context.Context, cfg *config.Config) (outbox.Sink, error) {
+	switch cfg.EventBroker {
+	case "kafka":
+		return kafka.NewProducer(kafka.Config{
+			Brokers:      cfg.KafkaBrokers,
+			Topic:        cfg.KafkaTopic,
+			ClientID:     cfg.KafkaClientID,
+			PartitionKey: cfg.KafkaPartitionKey,
+		})
+	case "nats":
+		return natsevents.NewPublisher(ctx, natsevents.Config{
+			URL:           cfg.NATSURL,
+			Name:          "{{SERVICE_NAME}}",
+			Stream:        cfg.NATSStream,
+			SubjectPrefix: cfg.NATSSubjectPrefix,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported event broker %q", cfg.EventBroker)
+	}
+}
+
+// newCatalogConnector creates the connector for the configured external catalog
+func newCatalogConnector(cfg *config.Config) (connectors.CatalogConnector, error) {
+	switch cfg.CatalogConnector {
+	case "shopify":
+		return shopify.New(shopify.Config{
+			ShopDomain:  cfg.ShopifyShopDomain,
+			AccessToken: cfg.ShopifyAccessToken,
+			APIVersion:  cfg.ShopifyAPIVersion,
+			LocationID:  int64(cfg.ShopifyLocationID),
+			SKUPrefix:   cfg.ShopifySKUPrefix,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported catalog connector %q", cfg.CatalogConnector)
+	}
+}
+
+// newSearchIndex creates the client for the configured external search index
+func newSearchIndex(cfg *config.Config) (search.Index, error) {
+	switch cfg.SearchBackend {
+	case "opensearch":
+		return search.NewOpenSearch(search.OpenSearchConfig{
+			URL:      cfg.OpenSearchURL,
+			Username: cfg.OpenSearchUsername,
+			Password: cfg.OpenSearchPassword,
+			Alias:    cfg.OpenSearchIndex,
+		})
+	case "meilisearch":
+		return search.NewMeilisearch(search.MeilisearchConfig{
+			URL:                 cfg.MeilisearchURL,
+			APIKey:              cfg.MeilisearchAPIKey,
+			Index:               cfg.MeilisearchIndex,
+			TypoTolerance:       cfg.MeilisearchTypoTolerance,
+			MinWordSizeOneTypo:  cfg.MeilisearchMinWordSizeOneTypo,
+			MinWordSizeTwoTypos: cfg.MeilisearchMinWordSizeTwoTypos,
+		})
+	default:
+		return nil, fmt.Errorf("SEARCH_BACKEND=%s has no index to rebuild", cfg.SearchBackend)
+	}
+}
+
+func newBlobStore(cfg *config.Config) (blob.Store, error) {
+	switch cfg.BlobStore {
+	case "file":
+		return blob.NewFileStore(cfg.BlobDir), nil
+	case "s3":
+		return blob.NewS3Store(blob.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			SessionToken:    cfg.S3SessionToken,
+			PathStyle:       cfg.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported blob store %q", cfg.BlobStore)
+	}
+}
+
+// reloadConfig re-reads the .env file, letting it override previously loaded
+// values, and loads the configuration from the environment
+func reloadConfig() (*config.Config, error) {
+	if err := godotenv.Overload(); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read .env file: %w", err)
+	}
+	return config.Load()
+}
+
+// reloadHook applies reloaded settings to the logger and records every reload
+// attempt in the audit log
+func reloadHook(logger *slog.Logger, logLevels *logging.Levels, auditRepo repository.AuditRepository) config.ReloadHook {
+	return func(ctx context.Context, event config.ReloadEvent) {
+		details := map[string]interface{}{
+			"source":  event.Source,
+			"changes": event.Changes,
+			"ignored": event.Ignored,
+		}
+
+		action := "config.reload"
+		if event.Err != nil {
+			action = "config.reload_failed"
+			details["error"] = event.Err.Error()
+			logger.Warn("configuration reload failed", "source", event.Source, "error", event.Err)
+		} else {
+			// Both were validated before the swap
+			level, _ := logging.ParseLevel(event.Current.LogLevel)
+			logLevels.SetBase(level)
+			_ = logLevels.SetComponents(event.Current.LogLevels)
+			logger.Info("configuration reloaded",
+				"source", event.Source,
+				"changes", len(event.Changes),
+			)
+			if len(event.Ignored) > 0 {
+				logger.Warn("configuration changes require a restart", "settings", event.Ignored)
+			}
+		}
+
+		payload, err := json.Marshal(details)
+		if err != nil {
+			logger.Error("failed to encode audit details", "error", err)
+			return
+		}
+
+		entry := &models.AuditEntry{
+			Action:     action,
+			Actor:      event.Actor,
+			EntityType: "config",
+			Details:    payload,
+		}
+		if err := auditRepo.Create(ctx, entry); err != nil {
+			logger.Error("failed to record config reload in audit log", "error", err)
+		}
+	}
 }
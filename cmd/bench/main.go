@@ -0,0 +1,109 @@
+// Command bench generates a load-test scenario for the HTTP API, as vegeta
+// JSON targets or a k6 script:
+//
+//	go run ./cmd/bench -format vegeta | vegeta attack -format=json -rate=200 -duration=30s | vegeta report
+//	go run ./cmd/bench -format k6 -o scenario.js && k6 run scenario.js
+//
+// Gets and updates target products listed from the running API first.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/bench"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func main() {
+	baseURL := flag.String("base-url", "http://localhost:8080", "API base URL")
+	format := flag.String("format", "vegeta", "output format: vegeta or k6")
+	requests := flag.Int("n", 1000, "number of requests in the scenario")
+	seed := flag.Int64("seed", 1, "random seed; the same seed gives the same scenario")
+	sample := flag.Int("sample", 100, "existing products to fetch for gets and updates, 0 to skip")
+	output := flag.String("o", "-", "output file, - for stdout")
+	flag.Parse()
+
+	if err := run(*baseURL, *format, *requests, *seed, *sample, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "bench:", err)
+		os.Exit(1)
+	}
+}
+
+func run(baseURL, format string, requests int, seed int64, sample int, output string) error {
+	var write func(io.Writer, []bench.Target) error
+	switch format {
+	case "vegeta":
+		write = bench.WriteVegeta
+	case "k6":
+		write = bench.WriteK6
+	default:
+		return fmt.Errorf("unknown format %q, want vegeta or k6", format)
+	}
+
+	var products []*models.Product
+	if sample > 0 {
+		var err error
+		if products, err = fetchProducts(baseURL, sample); err != nil {
+			return err
+		}
+	}
+
+	targets, err := bench.Generate(baseURL, bench.DefaultMix, products, requests, seed)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return write(w, targets)
+}
+
+// fetchProducts lists up to limit products from the API, at most 100 at a
+// time as the API allows
+func fetchProducts(baseURL string, limit int) ([]*models.Product, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	api := strings.TrimSuffix(baseURL, "/") + "/api/v1/products"
+
+	var products []*models.Product
+	for len(products) < limit {
+		url := fmt.Sprintf("%s?limit=%d&offset=%d", api, min(limit-len(products), 100), len(products))
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list products: %w", err)
+		}
+
+		var page struct {
+			Data []*models.Product `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to list products: %s", resp.Status)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode products: %w", err)
+		}
+
+		products = append(products, page.Data...)
+		if len(page.Data) < 100 {
+			break
+		}
+	}
+
+	return products, nil
+}
@@ -0,0 +1,150 @@
+// Command replay sends recorded requests, from a HAR file or NDJSON in
+// vegeta's target format, to another environment, to reproduce production
+// issues on staging:
+//
+//	go run ./cmd/replay -target https://staging.example.com -rate 20 \
+//		-H "Authorization: Bearer $STAGING_TOKEN" -H "Cookie:" capture.har
+//	go run ./cmd/bench -n 100 | go run ./cmd/replay -target http://localhost:8081 -methods GET
+//
+// Each response's status and duration is printed as it comes, and a
+// summary at the end.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"{{MODULE_NAME}}/internal/replay"
+)
+
+// headerFlags collects repeated -H flags
+type headerFlags []replay.Rewrite
+
+func (h *headerFlags) String() string {
+	return fmt.Sprint(*h)
+}
+
+func (h *headerFlags) Set(s string) error {
+	rw, err := replay.ParseRewrite(s)
+	if err != nil {
+		return err
+	}
+	*h = append(*h, rw)
+	return nil
+}
+
+func main() {
+	var headers headerFlags
+	target := flag.String("target", "", "base URL of the environment to replay against, e.g. https://staging.example.com")
+	format := flag.String("format", "auto", "input format: har, ndjson, or auto (har for .har files)")
+	rate := flag.Float64("rate", 10, "requests per second at most, 0 for no limit")
+	concurrency := flag.Int("concurrency", 1, "requests in flight at most; 1 keeps the recorded order")
+	methods := flag.String("methods", "", "comma-separated methods to replay, e.g. GET,HEAD; all when empty")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of each request")
+	flag.Var(&headers, "H", `header rewrite, "Name: value" to set or "Name:" to remove; repeatable`)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: replay -target URL [flags] [file|-]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *target == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	input := flag.Arg(0)
+	if input == "" {
+		input = "-"
+	}
+
+	cfg := replay.Config{
+		Target:      *target,
+		Rate:        *rate,
+		Concurrency: *concurrency,
+		Rewrites:    headers,
+		Timeout:     *timeout,
+	}
+	if *methods != "" {
+		cfg.Methods = strings.Split(*methods, ",")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg, *format, input); err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, cfg replay.Config, format, input string) error {
+	replayer, err := replay.New(cfg)
+	if err != nil {
+		return err
+	}
+	requests, err := read(format, input)
+	if err != nil {
+		return err
+	}
+
+	summary, err := replayer.Replay(ctx, requests, func(r replay.Result) {
+		if r.Err != nil {
+			fmt.Printf("%s %s failed after %s: %v\n", r.Method, r.URL, r.Duration.Round(time.Millisecond), r.Err)
+			return
+		}
+		fmt.Printf("%s %s %d %s\n", r.Method, r.URL, r.Status, r.Duration.Round(time.Millisecond))
+	})
+
+	statuses := make([]int, 0, len(summary.Statuses))
+	for status := range summary.Statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	fmt.Fprintf(os.Stderr, "%d sent, %d skipped, %d failed", summary.Sent, summary.Skipped, summary.Failed)
+	for _, status := range statuses {
+		fmt.Fprintf(os.Stderr, ", %d × %d", summary.Statuses[status], status)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// read reads the recorded requests of input, a file or - for stdin
+func read(format, input string) ([]replay.Request, error) {
+	r := io.Reader(os.Stdin)
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", input, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if format == "auto" {
+		format = "ndjson"
+		if strings.EqualFold(filepath.Ext(input), ".har") {
+			format = "har"
+		}
+	}
+	switch format {
+	case "har":
+		return replay.ReadHAR(r)
+	case "ndjson":
+		return replay.ReadNDJSON(r)
+	default:
+		return nil, fmt.Errorf("unknown format %q, want har, ndjson, or auto", format)
+	}
+}
@@ -0,0 +1,288 @@
+// Package anonymize scrambles the sensitive columns of a restored production
+// dump, so it can be debugged locally with realistic data volumes.
+//
+// Each Rule rewrites one column of one table, row by row in batches, with a
+// strategy: mask replaces values with a prefix and the row's key, hash with a
+// keyed hash of the value, jitter scales numbers by a factor near 1, and set
+// with a fixed value. Hash and jitter derive their output from the value and
+// the salt alone, so equal values stay equal across tables, e.g. a product's
+// name in products and products_history, while the salt keeps the originals
+// from being guessed. NULLs, and empty values masked or hashed, are left
+// alone.
+package anonymize
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/database"
+)
+
+// Strategies of a rule
+const (
+	Mask   = "mask"   // Value followed by the row's key, e.g. "API key 42"
+	Hash   = "hash"   // Value followed by 16 hex digits of the value's keyed hash
+	Jitter = "jitter" // Number scaled by up to ±Value percent, keeping its decimals
+	Set    = "set"    // Value as is, e.g. "" or "{}"
+)
+
+// Rule rewrites every non-NULL value of a column
+type Rule struct {
+	Table    string `json:"table"`
+	Column   string `json:"column"`
+	Key      string `json:"key,omitempty"` // Unique column rows are read in order of, "id" by default
+	Strategy string `json:"strategy"`
+	Value    string `json:"value,omitempty"`
+}
+
+// DefaultRules scramble product names, descriptions, prices, and supplier
+// costs, including their copies in history and trash, and the fields that could identify
+// people or let production credentials work against the copy. JSON copies
+// of products in the outbox and trash are emptied, so trashed products can't
+// be restored from an anonymized database.
+var DefaultRules = []Rule{
+	{Table: "products", Column: "name", Strategy: Hash, Value: "Product "},
+	{Table: "products", Column: "description", Strategy: Hash, Value: "Description "},
+	{Table: "products", Column: "unit_price", Strategy: Jitter, Value: "20"},
+	{Table: "products_history", Column: "name", Strategy: Hash, Value: "Product "},
+	{Table: "products_history", Column: "description", Strategy: Hash, Value: "Description "},
+	{Table: "products_history", Column: "unit_price", Strategy: Jitter, Value: "20"},
+	{Table: "stock_receipts", Column: "unit_cost", Strategy: Jitter, Value: "20"},
+	{Table: "stock_receipts", Column: "reference", Strategy: Hash, Value: "REF-"},
+	{Table: "trashed_products", Column: "name", Strategy: Hash, Value: "Product "},
+	{Table: "trashed_products", Column: "product", Strategy: Set, Value: "{}"},
+	{Table: "event_outbox", Column: "payload", Strategy: Set, Value: "{}"},
+	{Table: "audit_log", Column: "actor", Strategy: Hash, Value: "actor-"},
+	{Table: "stock_takes", Column: "opened_by", Strategy: Hash, Value: "actor-"},
+	{Table: "product_returns", Column: "created_by", Strategy: Hash, Value: "actor-"},
+	{Table: "product_suppliers", Column: "supplier", Key: "product_id", Strategy: Hash, Value: "Supplier "},
+	{Table: "purchase_orders", Column: "supplier", Strategy: Hash, Value: "Supplier "},
+	{Table: "purchase_orders", Column: "created_by", Strategy: Hash, Value: "actor-"},
+	{Table: "purchase_orders", Column: "approved_by", Strategy: Hash, Value: "actor-"},
+	{Table: "product_revisions", Column: "product", Strategy: Set, Value: "{}"},
+	{Table: "product_revisions", Column: "author", Strategy: Hash, Value: "actor-"},
+	{Table: "product_revisions", Column: "reviewer", Strategy: Hash, Value: "actor-"},
+	{Table: "report_definitions", Column: "created_by", Key: "name", Strategy: Hash, Value: "actor-"},
+	{Table: "audit_log", Column: "details", Strategy: Set, Value: "{}"},
+	{Table: "subscriptions", Column: "callback_url", Strategy: Mask, Value: "https://example.invalid/callbacks/"},
+	{Table: "subscriptions", Column: "secret", Strategy: Set, Value: ""},
+	{Table: "webhook_deliveries", Column: "callback_url", Strategy: Mask, Value: "https://example.invalid/callbacks/"},
+	{Table: "webhook_deliveries", Column: "error", Strategy: Set, Value: ""},
+	{Table: "saved_searches", Column: "name", Strategy: Mask, Value: "Saved search "},
+	{Table: "api_keys", Column: "name", Strategy: Mask, Value: "API key "},
+	{Table: "api_keys", Column: "key_hash", Strategy: Hash},
+}
+
+// ReadRules reads a ruleset, a JSON array of rules
+func ReadRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to decode rules: %w", err)
+	}
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return rules, nil
+}
+
+var identifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+func (r Rule) validate() error {
+	for _, name := range []string{r.Table, r.Column, r.key()} {
+		if !identifier.MatchString(name) {
+			return fmt.Errorf("invalid table or column name %q", name)
+		}
+	}
+	switch r.Strategy {
+	case Mask, Hash, Set:
+	case Jitter:
+		if percent, err := strconv.ParseFloat(r.Value, 64); err != nil || percent < 0 || percent >= 100 {
+			return fmt.Errorf("invalid jitter %q: must be a percentage below 100", r.Value)
+		}
+	default:
+		return fmt.Errorf("invalid strategy %q: must be mask, hash, jitter, or set", r.Strategy)
+	}
+	return nil
+}
+
+func (r Rule) key() string {
+	if r.Key == "" {
+		return "id"
+	}
+	return r.Key
+}
+
+// Anonymizer applies rules to a database
+type Anonymizer struct {
+	db        *database.DB
+	rules     []Rule
+	salt      []byte
+	batchSize int
+}
+
+// New returns an anonymizer applying rules with salt, batchSize rows per
+// transaction
+func New(db *database.DB, rules []Rule, salt []byte, batchSize int) (*Anonymizer, error) {
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	if batchSize < 1 {
+		return nil, fmt.Errorf("batch size must be positive")
+	}
+	return &Anonymizer{db: db, rules: rules, salt: salt, batchSize: batchSize}, nil
+}
+
+// Run applies every rule in order and returns the rows rewritten per
+// "table.column". On PostgreSQL triggers are disabled for its transactions,
+// so products_history doesn't gain a version per anonymized product; that
+// takes a superuser, as owns a database restored locally. SQLite can't, so
+// there anonymizing products adds one.
+func (a *Anonymizer) Run(ctx context.Context) (map[string]int64, error) {
+	rewritten := make(map[string]int64, len(a.rules))
+	for _, rule := range a.rules {
+		n, err := a.apply(ctx, rule)
+		rewritten[rule.Table+"."+rule.Column] += n
+		if err != nil {
+			return rewritten, fmt.Errorf("failed to anonymize %s.%s: %w", rule.Table, rule.Column, err)
+		}
+	}
+	return rewritten, nil
+}
+
+// apply rewrites a rule's column batch by batch, in order of its key
+func (a *Anonymizer) apply(ctx context.Context, rule Rule) (int64, error) {
+	key := rule.key()
+	query := fmt.Sprintf(`SELECT %s, %s FROM %s WHERE %s IS NOT NULL AND %s > $1 ORDER BY %s LIMIT $2`,
+		key, rule.Column, rule.Table, rule.Column, key, key)
+	first := fmt.Sprintf(`SELECT %s, %s FROM %s WHERE %s IS NOT NULL ORDER BY %s LIMIT $1`,
+		key, rule.Column, rule.Table, rule.Column, key)
+	update := fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE %s = $2`, rule.Table, rule.Column, key)
+
+	var total int64
+	var after interface{}
+	for {
+		n := 0
+		err := a.db.WithTx(ctx, func(ctx context.Context) error {
+			conn := a.db.Conn(ctx)
+			if a.db.Dialect() == database.Postgres {
+				if _, err := conn.ExecContext(ctx, `SET LOCAL session_replication_role = replica`); err != nil {
+					return fmt.Errorf("failed to disable triggers: %w", err)
+				}
+			}
+
+			var rows *sql.Rows
+			var err error
+			if after == nil {
+				rows, err = conn.QueryContext(ctx, first, a.batchSize)
+			} else {
+				rows, err = conn.QueryContext(ctx, query, after, a.batchSize)
+			}
+			if err != nil {
+				return err
+			}
+			type row struct {
+				key   interface{}
+				value string
+			}
+			var batch []row
+			for rows.Next() {
+				var r row
+				if err := rows.Scan(&r.key, &r.value); err != nil {
+					rows.Close()
+					return err
+				}
+				if b, ok := r.key.([]byte); ok {
+					r.key = string(b) // Compared as text, not bytea
+				}
+				batch = append(batch, r)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			for _, r := range batch {
+				value, err := a.rewrite(rule, r.key, r.value)
+				if err != nil {
+					return fmt.Errorf("row %v: %w", r.key, err)
+				}
+				if _, err := conn.ExecContext(ctx, update, value, r.key); err != nil {
+					return fmt.Errorf("row %v: %w", r.key, err)
+				}
+			}
+			n = len(batch)
+			if n > 0 {
+				after = batch[n-1].key
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		total += int64(n)
+		if n < a.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// rewrite returns the anonymized value of a row's column
+func (a *Anonymizer) rewrite(rule Rule, key interface{}, value string) (string, error) {
+	if value == "" && (rule.Strategy == Mask || rule.Strategy == Hash) {
+		return "", nil // Nothing to hide
+	}
+	switch rule.Strategy {
+	case Mask:
+		return rule.Value + fmt.Sprint(key), nil
+	case Hash:
+		return rule.Value + hex.EncodeToString(a.hash(value))[:16], nil
+	case Jitter:
+		percent, _ := strconv.ParseFloat(rule.Value, 64) // Validated
+		return a.jitter(value, percent)
+	default:
+		return rule.Value, nil
+	}
+}
+
+func (a *Anonymizer) hash(value string) []byte {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// jitter scales a number by a factor in [1-percent/100, 1+percent/100]
+// derived from its hash, keeping as many decimals as it had
+func (a *Anonymizer) jitter(value string, percent float64) (string, error) {
+	value = strings.TrimSpace(value)
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", fmt.Errorf("jitter needs a number, got %q", value)
+	}
+	decimals := 0
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		decimals = len(value) - i - 1
+	}
+
+	// Uniform in [0, 1) from the top 53 bits of the hash
+	u := float64(binary.BigEndian.Uint64(a.hash(value))>>11) / (1 << 53)
+	scaled := n * (1 + (2*u-1)*percent/100)
+	if decimals == 0 {
+		scaled = math.Round(scaled)
+	}
+	return strconv.FormatFloat(scaled, 'f', decimals, 64), nil
+}
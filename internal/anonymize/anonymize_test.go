@@ -0,0 +1,100 @@
+package anonymize
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestAnonymizer_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "anonymize.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	products := repository.NewProductRepository(db)
+	for _, p := range []*models.Product{
+		{SKU: "ANON-1", Name: "Acme Secret Widget", Description: "Supplied by Acme", Quantity: 3, UnitPrice: 10.50},
+		{SKU: "ANON-2", Name: "Acme Secret Widget", Description: "", Quantity: 1, UnitPrice: 10.50},
+		{SKU: "ANON-3", Name: "Gadget", Description: "Imported", Quantity: 2, UnitPrice: 99.99},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO subscriptions (product_id, fields, callback_url, secret) VALUES (1, '[]', 'https://partner.example.com/hook', 's3cret')`); err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	// A batch smaller than the table pages through it
+	anonymizer, err := New(db, DefaultRules, []byte("salt"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewritten, err := anonymizer.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if rewritten["products.name"] != 3 || rewritten["subscriptions.secret"] != 1 {
+		t.Errorf("rewritten = %v, want 3 product names and 1 secret", rewritten)
+	}
+
+	all, err := products.List(ctx, 10, 0)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("List = %v, %v", all, err)
+	}
+	byID := make(map[int]*models.Product, len(all))
+	for _, p := range all {
+		byID[p.ID] = p
+		if !strings.HasPrefix(p.Name, "Product ") || strings.Contains(p.Name+p.Description, "Acme") {
+			t.Errorf("product %d not anonymized: %+v", p.ID, p)
+		}
+	}
+	if byID[1].Name != byID[2].Name || byID[1].Name == byID[3].Name {
+		t.Errorf("names %q, %q, %q: want equal names kept equal and others apart", byID[1].Name, byID[2].Name, byID[3].Name)
+	}
+	if byID[1].UnitPrice != byID[2].UnitPrice || byID[1].UnitPrice < 8.40 || byID[1].UnitPrice > 12.60 {
+		t.Errorf("prices %v, %v: want equal prices within 20%% of 10.50", byID[1].UnitPrice, byID[2].UnitPrice)
+	}
+	if byID[1].SKU != "ANON-1" || byID[1].Quantity != 3 {
+		t.Errorf("product 1 = %+v, want SKU and quantity kept", byID[1])
+	}
+
+	// History carries the same names
+	var historyName string
+	if err := db.QueryRow(`SELECT name FROM products_history WHERE product_id = 1 ORDER BY id LIMIT 1`).Scan(&historyName); err != nil || historyName != byID[1].Name {
+		t.Errorf("first history name = %q, %v, want %q", historyName, err, byID[1].Name)
+	}
+
+	var url, secret string
+	if err := db.QueryRow(`SELECT callback_url, secret FROM subscriptions`).Scan(&url, &secret); err != nil || url != "https://example.invalid/callbacks/1" || secret != "" {
+		t.Errorf("subscription = %q, %q, %v", url, secret, err)
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	rules, err := ReadRules(strings.NewReader(`[{"table": "products", "column": "unit_price", "strategy": "jitter", "value": "5"}]`))
+	if err != nil || len(rules) != 1 || rules[0].key() != "id" {
+		t.Errorf("ReadRules = %+v, %v", rules, err)
+	}
+
+	for _, rules := range []string{
+		`[{"table": "products; DROP TABLE products", "column": "name", "strategy": "set"}]`,
+		`[{"table": "products", "column": "name", "strategy": "shuffle"}]`,
+		`[{"table": "products", "column": "unit_price", "strategy": "jitter", "value": "100"}]`,
+	} {
+		if _, err := ReadRules(strings.NewReader(rules)); err == nil {
+			t.Errorf("ReadRules(%s) succeeded, want an error", rules)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// fakeAPI serves the product and admin routes the scenario calls, from
+// memory, the way the API answers them
+type fakeAPI struct {
+	mu          sync.Mutex
+	products    map[int]models.Product
+	nextID      int
+	adminToken  string
+	allowDupSKU bool // Misbehaves: creates products with a taken SKU
+}
+
+func (f *fakeAPI) handler() http.Handler {
+	respond := func(w http.ResponseWriter, status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+	fail := func(w http.ResponseWriter, status int, message string) {
+		respond(w, status, models.NewErrorResponse(status, message))
+	}
+	productID := func(w http.ResponseWriter, r *http.Request) (int, bool) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			fail(w, http.StatusBadRequest, "Invalid product ID")
+			return 0, false
+		}
+		if _, ok := f.products[id]; !ok {
+			fail(w, http.StatusNotFound, "Product not found")
+			return 0, false
+		}
+		return id, true
+	}
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Get("/api/v1/health", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, models.NewSuccessResponse(http.StatusOK, "ok", nil))
+	})
+	r.Post("/api/v1/products", func(w http.ResponseWriter, r *http.Request) {
+		var p models.Product
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			fail(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if p.Name == "" {
+			fail(w, http.StatusBadRequest, "Product name is required")
+			return
+		}
+		for _, existing := range f.products {
+			if existing.SKU == p.SKU && !f.allowDupSKU {
+				fail(w, http.StatusConflict, "Product with this SKU already exists")
+				return
+			}
+		}
+		f.nextID++
+		p.ID = f.nextID
+		f.products[p.ID] = p
+		w.Header().Set("Location", "/api/v1/products/"+strconv.Itoa(p.ID))
+		respond(w, http.StatusCreated, models.NewSuccessResponse(http.StatusCreated, "Product created successfully", p))
+	})
+	r.Get("/api/v1/products", func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		var page []models.Product
+		for _, p := range f.products {
+			if len(page) < limit {
+				page = append(page, p)
+			}
+		}
+		respond(w, http.StatusOK, models.PaginatedResponse{Data: page, Pagination: &models.PaginationMeta{Limit: limit, Total: len(f.products)}})
+	})
+	r.Get("/api/v1/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := productID(w, r); ok {
+			respond(w, http.StatusOK, models.NewSuccessResponse(http.StatusOK, "", f.products[id]))
+		}
+	})
+	r.Put("/api/v1/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := productID(w, r)
+		if !ok {
+			return
+		}
+		var p models.Product
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			fail(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		p.ID = id
+		f.products[id] = p
+		respond(w, http.StatusOK, models.NewSuccessResponse(http.StatusOK, "Product updated successfully", p))
+	})
+	r.Delete("/api/v1/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := productID(w, r); ok {
+			delete(f.products, id)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	r.Get("/api/v1/admin/products", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+f.adminToken {
+			fail(w, http.StatusUnauthorized, "Invalid or missing admin token")
+			return
+		}
+		respond(w, http.StatusOK, models.PaginatedResponse{Data: []models.Product{}})
+	})
+	return r
+}
+
+func TestRun(t *testing.T) {
+	api := &fakeAPI{products: make(map[int]models.Product), adminToken: "secret"}
+	server := httptest.NewServer(api.handler())
+	defer server.Close()
+
+	var out bytes.Buffer
+	report := Run(context.Background(), NewClient(server.URL+"/", "", 5*time.Second), Config{AdminToken: "secret"}, &out)
+	if !report.OK() {
+		t.Fatalf("Run failed:\n%s", out.String())
+	}
+	for _, result := range report.Results {
+		if result.Skipped {
+			t.Errorf("step %q was skipped", result.Name)
+		}
+	}
+	if len(api.products) != 0 {
+		t.Errorf("%d products left behind", len(api.products))
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	api := &fakeAPI{products: make(map[int]models.Product), adminToken: "secret", allowDupSKU: true}
+	server := httptest.NewServer(api.handler())
+	defer server.Close()
+
+	var out bytes.Buffer
+	report := Run(context.Background(), NewClient(server.URL, "", 5*time.Second), Config{}, &out)
+	if report.OK() {
+		t.Fatalf("Run passed against an API accepting duplicate SKUs:\n%s", out.String())
+	}
+
+	outcomes := make(map[string]string)
+	for _, result := range report.Results {
+		switch {
+		case result.Skipped:
+			outcomes[result.Name] = "skip"
+		case result.Err != nil:
+			outcomes[result.Name] = "fail"
+		default:
+			outcomes[result.Name] = "ok"
+		}
+	}
+	for name, want := range map[string]string{
+		"duplicate SKU is refused":          "fail",
+		"delete product":                    "ok",
+		"admin API accepts the admin token": "skip",
+	} {
+		if outcomes[name] != want {
+			t.Errorf("step %q = %s, want %s", name, outcomes[name], want)
+		}
+	}
+	if !strings.Contains(out.String(), "FAIL  duplicate SKU is refused") {
+		t.Errorf("output doesn't report the failure:\n%s", out.String())
+	}
+
+	// The duplicate is cleaned up by hand; the scenario only deletes its own
+	if len(api.products) != 1 {
+		t.Errorf("%d products left behind, want the duplicate", len(api.products))
+	}
+}
+
+func TestRun_Unreachable(t *testing.T) {
+	var out bytes.Buffer
+	report := Run(context.Background(), NewClient("http://127.0.0.1:1", "", time.Second), Config{}, &out)
+	if report.OK() {
+		t.Fatal("Run passed against no server")
+	}
+	for _, result := range report.Results {
+		if result.Name == "get product" && !result.Skipped {
+			t.Errorf("get product ran without a product")
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package apitest smoke-tests a deployed instance: it runs a product CRUD
+// scenario, with auth and error cases, against the instance's base URL
+// through a small typed client of the API, and reports every step. `api
+// apitest` runs it after deploys.
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// APIError is a response with an error status
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+}
+
+// StatusOf returns the status of err's response, or 0 when it isn't an
+// APIError
+func StatusOf(err error) int {
+	if e, ok := err.(*APIError); ok {
+		return e.Status
+	}
+	return 0
+}
+
+// Client calls the API of a running instance
+type Client struct {
+	baseURL string
+	token   string // Bearer token of product requests, if any
+	http    *http.Client
+}
+
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Health calls GET /api/v1/health
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodGet, "/api/v1/health", c.token, nil, nil)
+	return err
+}
+
+// CreateProduct calls POST /api/v1/products, returning the product created
+// and its Location
+func (c *Client) CreateProduct(ctx context.Context, product *models.Product) (*models.Product, string, error) {
+	var created models.Product
+	header, err := c.do(ctx, http.MethodPost, "/api/v1/products", c.token, product, &models.SuccessResponse{Data: &created})
+	if err != nil {
+		return nil, "", err
+	}
+	return &created, header.Get("Location"), nil
+}
+
+// GetProduct calls GET /api/v1/products/{id}
+func (c *Client) GetProduct(ctx context.Context, id string) (*models.Product, error) {
+	var product models.Product
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/products/"+id, c.token, nil, &models.SuccessResponse{Data: &product}); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// ListProducts calls GET /api/v1/products for a page of limit products
+func (c *Client) ListProducts(ctx context.Context, limit int) ([]models.Product, *models.PaginationMeta, error) {
+	var products []models.Product
+	page := models.PaginatedResponse{Data: &products}
+	if _, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/products?limit=%d", limit), c.token, nil, &page); err != nil {
+		return nil, nil, err
+	}
+	return products, page.Pagination, nil
+}
+
+// UpdateProduct calls PUT /api/v1/products/{id}
+func (c *Client) UpdateProduct(ctx context.Context, id string, product *models.Product) (*models.Product, error) {
+	var updated models.Product
+	if _, err := c.do(ctx, http.MethodPut, "/api/v1/products/"+id, c.token, product, &models.SuccessResponse{Data: &updated}); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteProduct calls DELETE /api/v1/products/{id}
+func (c *Client) DeleteProduct(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/v1/products/"+id, c.token, nil, nil)
+	return err
+}
+
+// ListAdminProducts calls GET /api/v1/admin/products with token, the admin
+// token or none
+func (c *Client) ListAdminProducts(ctx context.Context, token string) error {
+	_, err := c.do(ctx, http.MethodGet, "/api/v1/admin/products?limit=1", token, nil, nil)
+	return err
+}
+
+// Post calls POST path with a raw body, for requests the typed methods can't
+// make, such as malformed ones
+func (c *Client) Post(ctx context.Context, path, body string) error {
+	_, err := c.do(ctx, http.MethodPost, path, c.token, rawBody(body), nil)
+	return err
+}
+
+// rawBody is a request body sent as it is, not encoded
+type rawBody string
+
+// do sends a request with in as its JSON body, if any, and decodes a
+// successful response into out, if any: a response envelope whose Data
+// points at the value to fill. Error statuses return an APIError with the
+// response's message.
+func (c *Client) do(ctx context.Context, method, path, token string, in, out interface{}) (http.Header, error) {
+	var body io.Reader
+	if raw, ok := in.(rawBody); ok {
+		body = strings.NewReader(string(raw))
+	} else if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var failure models.ErrorResponse
+		json.Unmarshal(raw, &failure) // The message is a nicety; the status says it
+		return resp.Header, &APIError{Status: resp.StatusCode, Message: failure.Message}
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
@@ -0,0 +1,464 @@
+// Package backup exports the full dataset as gzip-compressed NDJSON and
+// restores it, on PostgreSQL or SQLite alike.
+//
+// A backup is one JSON value per line: a header, then for each table a line
+// naming it and its columns followed by one array per row, and finally a
+// trailer with the row counts and the SHA-256 of every line before it.
+// Restore checks the trailer before committing, so a truncated or corrupted
+// backup changes nothing.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+)
+
+// Format identifies backups written by this package in their header
+const Format = "catalog-backup"
+
+// Version is bumped when the layout of a backup changes
+const Version = 1
+
+// Table is a table included in backups
+type Table struct {
+	Name        string
+	Partitioned bool // Partitioned by month on created_at (PostgreSQL only)
+}
+
+// Tables are backed up and restored in this order, parents before the
+// tables derived from them
+var Tables = []Table{
+	{Name: "units"},
+	{Name: "products"},
+	{Name: "products_history"},
+	{Name: "product_tags"},
+	{Name: "product_regions"},
+	{Name: "product_slugs"},
+	{Name: "bundle_components"},
+	{Name: "stock_movements", Partitioned: true},
+	{Name: "audit_log", Partitioned: true},
+	{Name: "event_outbox"},
+	{Name: "processed_orders"},
+	{Name: "inbox_messages"},
+	{Name: "catalog_sync_state"},
+	{Name: "queue_tasks"},
+	{Name: "promotions"},
+	{Name: "sku_counters"},
+	{Name: "export_runs"},
+	{Name: "search_index_state"},
+	{Name: "saved_searches"},
+	{Name: "subscriptions"},
+	{Name: "trashed_products"},
+	{Name: "api_keys"},
+	{Name: "api_usage"},
+	{Name: "stock_receipts"},
+	{Name: "inventory_valuations"},
+	{Name: "stock_takes"},
+	{Name: "stock_take_counts"},
+	{Name: "product_lots"},
+	{Name: "serial_numbers"},
+	{Name: "product_returns"},
+	{Name: "product_forecasts"},
+	{Name: "product_lead_times"},
+	{Name: "product_suppliers"},
+	{Name: "purchase_orders"},
+	{Name: "purchase_order_lines"},
+	{Name: "report_definitions"},
+	{Name: "webhook_deliveries"},
+	{Name: "product_revisions"},
+	{Name: "product_notes"},
+	{Name: "operations"},
+	{Name: "sagas"},
+}
+
+// ErrChecksum is returned by Restore when the backup doesn't match its trailer
+var ErrChecksum = errors.New("backup checksum mismatch")
+
+// Manifest summarises a backup
+type Manifest struct {
+	Rows   map[string]int64 `json:"rows"`   // Rows per table
+	SHA256 string           `json:"sha256"` // Of the uncompressed lines before the trailer
+
+	// When the snapshot the backup was read from was taken, which every row
+	// reflects; absent from backups written before it was recorded
+	SnapshotAt *time.Time `json:"snapshot_at,omitempty"`
+}
+
+// line is one line of a backup; exactly one field is set
+type line struct {
+	Header  *header         `json:"header,omitempty"`
+	Table   *section        `json:"table,omitempty"`
+	Row     json.RawMessage `json:"row,omitempty"`
+	Trailer *Manifest       `json:"trailer,omitempty"`
+}
+
+type header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	Dialect   string    `json:"dialect"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type section struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// Archiver backs up and restores a set of tables
+type Archiver struct {
+	db     *database.DB
+	tables []Table
+}
+
+func NewArchiver(db *database.DB, tables []Table) *Archiver {
+	return &Archiver{db: db, tables: tables}
+}
+
+// hashWriter writes lines and hashes them as written
+type hashWriter struct {
+	w    io.Writer
+	hash hash.Hash
+}
+
+func (hw *hashWriter) writeLine(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	hw.hash.Write(data)
+	_, err = hw.w.Write(data)
+	return err
+}
+
+// Export writes a backup of every table to w. It reads from one snapshot, so
+// the backup is consistent while writes continue, and records when the
+// snapshot was taken in the manifest.
+func (a *Archiver) Export(ctx context.Context, w io.Writer) (*Manifest, error) {
+	gz := gzip.NewWriter(w)
+	hw := &hashWriter{w: gz, hash: sha256.New()}
+	manifest := &Manifest{Rows: make(map[string]int64, len(a.tables))}
+	createdAt := time.Now().UTC()
+
+	err := a.db.WithTxOptions(ctx, a.db.Dialect().SnapshotTxOptions(), func(ctx context.Context) error {
+		snapshotAt, err := a.db.SnapshotTime(ctx)
+		if err != nil {
+			return err
+		}
+		manifest.SnapshotAt = &snapshotAt
+
+		err = hw.writeLine(line{Header: &header{
+			Format:    Format,
+			Version:   Version,
+			Dialect:   string(a.db.Dialect()),
+			CreatedAt: createdAt,
+		}})
+		if err != nil {
+			return fmt.Errorf("failed to write backup header: %w", err)
+		}
+
+		for _, table := range a.tables {
+			n, err := a.exportTable(ctx, hw, table.Name)
+			if err != nil {
+				return fmt.Errorf("failed to export %s: %w", table.Name, err)
+			}
+			manifest.Rows[table.Name] = n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest.SHA256 = hex.EncodeToString(hw.hash.Sum(nil))
+	if err := hw.writeLine(line{Trailer: manifest}); err != nil {
+		return nil, fmt.Errorf("failed to write backup trailer: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish backup: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func (a *Archiver) exportTable(ctx context.Context, hw *hashWriter, table string) (int64, error) {
+	columns, err := a.db.DescribeTable(ctx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	names := make([]string, len(columns))
+	codecs := make([]codec, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+		codecs[i] = codecFor(column.DataType)
+	}
+	if err := hw.writeLine(line{Table: &section{Name: table, Columns: names}}); err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s`, strings.Join(names, ", "), table)
+	rows, err := a.db.Conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	for rows.Next() {
+		dest := make([]interface{}, len(codecs))
+		for i, c := range codecs {
+			dest[i] = c.scanTarget()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return count, err
+		}
+
+		values := make([]interface{}, len(codecs))
+		for i, c := range codecs {
+			values[i] = c.encode(dest[i])
+		}
+		row, err := json.Marshal(values)
+		if err != nil {
+			return count, err
+		}
+		if err := hw.writeLine(line{Row: row}); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, rows.Err()
+}
+
+// Restore replaces the contents of every table with the backup read from r,
+// in a single transaction. The schema must already be migrated; columns the
+// backup doesn't have keep their defaults. A backup whose checksum or row
+// counts don't match its trailer is rejected with ErrChecksum.
+func (a *Archiver) Restore(ctx context.Context, r io.Reader) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *Manifest
+	err = a.db.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		manifest, err = a.restore(ctx, gz)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func (a *Archiver) restore(ctx context.Context, r io.Reader) (*Manifest, error) {
+	known := make(map[string]Table, len(a.tables))
+	for _, table := range a.tables {
+		known[table.Name] = table
+	}
+
+	// Clear children first; tables filled by triggers are cleared again when
+	// their section starts
+	for i := len(a.tables) - 1; i >= 0; i-- {
+		if err := a.clear(ctx, a.tables[i].Name); err != nil {
+			return nil, err
+		}
+	}
+
+	reader := bufio.NewReaderSize(r, 64*1024)
+	digest := sha256.New()
+	rows := make(map[string]int64)
+	var current *tableRestore
+	sawHeader := false
+
+	for {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(data) == 0 {
+			return nil, fmt.Errorf("%w: backup has no trailer, it may be truncated", ErrChecksum)
+		}
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		var l line
+		if err := json.Unmarshal(data, &l); err != nil {
+			return nil, fmt.Errorf("failed to parse backup line: %w", err)
+		}
+
+		switch {
+		case l.Trailer != nil:
+			if sum := hex.EncodeToString(digest.Sum(nil)); sum != l.Trailer.SHA256 {
+				return nil, fmt.Errorf("%w: content hashes to %s, trailer says %s", ErrChecksum, sum, l.Trailer.SHA256)
+			}
+			for table, want := range l.Trailer.Rows {
+				if rows[table] != want {
+					return nil, fmt.Errorf("%w: %d rows of %s restored, trailer says %d", ErrChecksum, rows[table], table, want)
+				}
+			}
+			if err := a.finish(ctx, current); err != nil {
+				return nil, err
+			}
+			return &Manifest{Rows: rows, SHA256: l.Trailer.SHA256, SnapshotAt: l.Trailer.SnapshotAt}, nil
+
+		case l.Header != nil:
+			if l.Header.Format != Format || l.Header.Version > Version {
+				return nil, fmt.Errorf("unsupported backup %s version %d", l.Header.Format, l.Header.Version)
+			}
+			sawHeader = true
+
+		case !sawHeader:
+			return nil, fmt.Errorf("backup has no header")
+
+		case l.Table != nil:
+			if err := a.finish(ctx, current); err != nil {
+				return nil, err
+			}
+			table, ok := known[l.Table.Name]
+			if !ok {
+				return nil, fmt.Errorf("backup contains unknown table %s", l.Table.Name)
+			}
+			if current, err = a.startTable(ctx, table, l.Table.Columns); err != nil {
+				return nil, err
+			}
+			rows[table.Name] = 0
+
+		case l.Row != nil:
+			if current == nil {
+				return nil, fmt.Errorf("backup has a row outside a table")
+			}
+			if err := current.insert(ctx, l.Row); err != nil {
+				return nil, fmt.Errorf("failed to restore row %d of %s: %w", rows[current.table.Name]+1, current.table.Name, err)
+			}
+			rows[current.table.Name]++
+		}
+
+		digest.Write(data)
+	}
+}
+
+func (a *Archiver) clear(ctx context.Context, table string) error {
+	if _, err := a.db.Conn(ctx).ExecContext(ctx, `DELETE FROM `+table); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", table, err)
+	}
+	return nil
+}
+
+// finish resets the id sequence of a restored table past its restored rows
+func (a *Archiver) finish(ctx context.Context, current *tableRestore) error {
+	if current == nil || !current.hasID {
+		return nil
+	}
+	query := a.db.Dialect().ResetSequenceQuery(current.table.Name, "id")
+	if query == "" {
+		return nil
+	}
+	if _, err := a.db.Conn(ctx).ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to reset id sequence of %s: %w", current.table.Name, err)
+	}
+	return nil
+}
+
+// tableRestore inserts the rows of one table section
+type tableRestore struct {
+	db         *database.DB
+	table      Table
+	query      string
+	codecs     []codec
+	createdAt  int // Index of created_at, for partition creation; -1 if absent
+	hasID      bool
+	partitions map[time.Time]bool
+}
+
+func (a *Archiver) startTable(ctx context.Context, table Table, columns []string) (*tableRestore, error) {
+	// Triggers on tables restored earlier may have filled this one
+	if err := a.clear(ctx, table.Name); err != nil {
+		return nil, err
+	}
+
+	described, err := a.db.DescribeTable(ctx, table.Name)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string, len(described))
+	for _, column := range described {
+		types[column.Name] = column.DataType
+	}
+
+	t := &tableRestore{
+		db:         a.db,
+		table:      table,
+		codecs:     make([]codec, len(columns)),
+		createdAt:  -1,
+		partitions: make(map[time.Time]bool),
+	}
+	placeholders := make([]string, len(columns))
+	for i, name := range columns {
+		dataType, ok := types[name]
+		if !ok {
+			return nil, fmt.Errorf("backup column %s.%s does not exist in the database", table.Name, name)
+		}
+		t.codecs[i] = codecFor(dataType)
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		switch name {
+		case "id":
+			t.hasID = true
+		case "created_at":
+			t.createdAt = i
+		}
+	}
+	t.query = fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, table.Name, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	return t, nil
+}
+
+func (t *tableRestore) insert(ctx context.Context, row json.RawMessage) error {
+	var values []interface{}
+	decoder := json.NewDecoder(strings.NewReader(string(row)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return err
+	}
+	if len(values) != len(t.codecs) {
+		return fmt.Errorf("row has %d values for %d columns", len(values), len(t.codecs))
+	}
+
+	args := make([]interface{}, len(values))
+	for i, c := range t.codecs {
+		arg, err := c.decode(values[i])
+		if err != nil {
+			return err
+		}
+		args[i] = arg
+	}
+
+	if t.table.Partitioned && t.createdAt >= 0 && t.db.Dialect() == database.Postgres {
+		if createdAt, ok := args[t.createdAt].(time.Time); ok {
+			month := database.MonthStart(createdAt)
+			if !t.partitions[month] {
+				if _, err := t.db.CreateMonthlyPartition(ctx, t.table.Name, month); err != nil {
+					return err
+				}
+				t.partitions[month] = true
+			}
+		}
+	}
+
+	_, err := t.db.Conn(ctx).ExecContext(ctx, t.query, args...)
+	return err
+}
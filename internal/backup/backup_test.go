@@ -0,0 +1,192 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func setupSQLiteDB(t *testing.T) *database.DB {
+	t.Helper()
+
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(t.TempDir(), "backup.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return db
+}
+
+// seed fills db with products, their history and stock movements, and an
+// audit entry
+func seed(t *testing.T, db *database.DB) {
+	t.Helper()
+	ctx := context.Background()
+
+	products := repository.NewProductRepository(db)
+	for _, p := range []*models.Product{
+		{SKU: "B-1", Name: "One", Description: "First", Quantity: 4, UnitPrice: 12.34},
+		{SKU: "B-2", Name: "Two", Quantity: 0, UnitPrice: 0.5},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+	if _, err := products.AdjustStock(ctx, 1, -1); err != nil {
+		t.Fatalf("failed to adjust stock: %v", err)
+	}
+
+	audit := repository.NewAuditRepository(db)
+	if err := audit.Create(ctx, &models.AuditEntry{Action: "test", Actor: "me", EntityType: "product", EntityID: "1", Details: []byte(`{"n":1}`)}); err != nil {
+		t.Fatalf("failed to create audit entry: %v", err)
+	}
+}
+
+func export(t *testing.T, db *database.DB) ([]byte, *Manifest) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	manifest, err := NewArchiver(db, Tables).Export(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	return buf.Bytes(), manifest
+}
+
+func TestArchiver_RoundTrip(t *testing.T) {
+	source := setupSQLiteDB(t)
+	seed(t, source)
+	data, manifest := export(t, source)
+
+	want := map[string]int64{"products": 2, "products_history": 3, "stock_movements": 2, "audit_log": 1}
+	for table, n := range want {
+		if manifest.Rows[table] != n {
+			t.Errorf("exported %d rows of %s, want %d", manifest.Rows[table], table, n)
+		}
+	}
+
+	target := setupSQLiteDB(t)
+	ctx := context.Background()
+	// Existing rows are replaced
+	if err := repository.NewProductRepository(target).Create(ctx, &models.Product{SKU: "OLD", Name: "Replaced"}); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	restored, err := NewArchiver(target, Tables).Restore(ctx, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.SHA256 != manifest.SHA256 {
+		t.Errorf("restored checksum %s, exported %s", restored.SHA256, manifest.SHA256)
+	}
+	if manifest.SnapshotAt == nil || restored.SnapshotAt == nil || !restored.SnapshotAt.Equal(*manifest.SnapshotAt) {
+		t.Errorf("restored snapshot at %v, exported %v", restored.SnapshotAt, manifest.SnapshotAt)
+	}
+	for table, n := range manifest.Rows {
+		var count int64
+		if err := target.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&count); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		if count != n {
+			t.Errorf("%s has %d rows after restore, want %d", table, count, n)
+		}
+	}
+
+	products := repository.NewProductRepository(target)
+	got, err := products.GetBySKU(ctx, "B-1")
+	if err != nil {
+		t.Fatalf("failed to get restored product: %v", err)
+	}
+	if got.ID != 1 || got.Quantity != 3 || got.UnitPrice != 12.34 || got.Description != "First" {
+		t.Errorf("restored product = %+v", got)
+	}
+	original, _ := repository.NewProductRepository(source).GetBySKU(ctx, "B-1")
+	if !got.UpdatedAt.Equal(original.UpdatedAt) {
+		t.Errorf("UpdatedAt = %v, want %v", got.UpdatedAt, original.UpdatedAt)
+	}
+
+	entries, err := repository.NewAuditRepository(target).List(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 10)
+	if err != nil || len(entries) != 1 || string(entries[0].Details) != `{"n":1}` {
+		t.Errorf("restored audit entries = %+v, %v", entries, err)
+	}
+
+	// New rows continue after the restored ids
+	next := &models.Product{SKU: "B-3", Name: "Three"}
+	if err := products.Create(ctx, next); err != nil {
+		t.Fatalf("failed to create product after restore: %v", err)
+	}
+	if next.ID != 3 {
+		t.Errorf("new product got id %d, want 3", next.ID)
+	}
+}
+
+func TestArchiver_RejectsDamagedBackups(t *testing.T) {
+	source := setupSQLiteDB(t)
+	seed(t, source)
+	data, _ := export(t, source)
+
+	content := gunzip(t, data)
+	tampered := strings.Replace(content, `"One"`, `"Uno"`, 1)
+	truncated := content[:strings.LastIndex(content, `{"trailer"`)]
+
+	for name, damaged := range map[string]string{"tampered": tampered, "truncated": truncated} {
+		t.Run(name, func(t *testing.T) {
+			target := setupSQLiteDB(t)
+			ctx := context.Background()
+			if err := repository.NewProductRepository(target).Create(ctx, &models.Product{SKU: "KEEP", Name: "Kept"}); err != nil {
+				t.Fatalf("failed to create product: %v", err)
+			}
+
+			_, err := NewArchiver(target, Tables).Restore(ctx, bytes.NewReader(gzipped(t, damaged)))
+			if !errors.Is(err, ErrChecksum) {
+				t.Fatalf("Restore error = %v, want ErrChecksum", err)
+			}
+
+			// The failed restore was rolled back
+			if _, err := repository.NewProductRepository(target).GetBySKU(ctx, "KEEP"); err != nil {
+				t.Errorf("existing product lost after failed restore: %v", err)
+			}
+		})
+	}
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("backup isn't gzip: %v", err)
+	}
+	content, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to decompress backup: %v", err)
+	}
+	return string(content)
+}
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(content))
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
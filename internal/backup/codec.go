@@ -0,0 +1,195 @@
+package backup
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// codec moves one column between the database and its JSON form in a backup,
+// chosen by the column's information_schema data type. Values keep their
+// JSON type where one fits and times are RFC 3339 in UTC, so backups can be
+// restored across dialects.
+type codec interface {
+	scanTarget() interface{}
+	encode(scanned interface{}) interface{}
+	decode(value interface{}) (interface{}, error)
+}
+
+func codecFor(dataType string) codec {
+	switch dataType {
+	case "integer", "bigint", "smallint":
+		return intCodec{}
+	case "numeric", "double precision", "real":
+		return numberCodec{}
+	case "boolean":
+		return boolCodec{}
+	case "timestamp without time zone", "timestamp with time zone", "date":
+		return timeCodec{}
+	case "jsonb", "json":
+		return jsonCodec{}
+	case "bytea":
+		return bytesCodec{}
+	default:
+		return stringCodec{}
+	}
+}
+
+type intCodec struct{}
+
+func (intCodec) scanTarget() interface{} { return new(sql.NullInt64) }
+
+func (intCodec) encode(scanned interface{}) interface{} {
+	if v := scanned.(*sql.NullInt64); v.Valid {
+		return v.Int64
+	}
+	return nil
+}
+
+func (intCodec) decode(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	n, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("expected an integer, got %v", value)
+	}
+	return n.Int64()
+}
+
+// numberCodec keeps decimals as their digits, so numeric values aren't
+// rounded through float64
+type numberCodec struct{}
+
+func (numberCodec) scanTarget() interface{} { return new(sql.NullString) }
+
+func (numberCodec) encode(scanned interface{}) interface{} {
+	if v := scanned.(*sql.NullString); v.Valid {
+		return json.Number(v.String)
+	}
+	return nil
+}
+
+func (numberCodec) decode(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	n, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("expected a number, got %v", value)
+	}
+	return n.String(), nil
+}
+
+type boolCodec struct{}
+
+func (boolCodec) scanTarget() interface{} { return new(sql.NullBool) }
+
+func (boolCodec) encode(scanned interface{}) interface{} {
+	if v := scanned.(*sql.NullBool); v.Valid {
+		return v.Bool
+	}
+	return nil
+}
+
+func (boolCodec) decode(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("expected a boolean, got %v", value)
+	}
+	return b, nil
+}
+
+type timeCodec struct{}
+
+func (timeCodec) scanTarget() interface{} { return new(sql.NullTime) }
+
+func (timeCodec) encode(scanned interface{}) interface{} {
+	if v := scanned.(*sql.NullTime); v.Valid {
+		return v.Time.UTC().Format(time.RFC3339Nano)
+	}
+	return nil
+}
+
+func (timeCodec) decode(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a timestamp, got %v", value)
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// jsonCodec embeds JSON columns as JSON rather than as an escaped string
+type jsonCodec struct{}
+
+func (jsonCodec) scanTarget() interface{} { return new([]byte) }
+
+func (jsonCodec) encode(scanned interface{}) interface{} {
+	data := *scanned.(*[]byte)
+	if data == nil {
+		return nil
+	}
+	if !json.Valid(data) {
+		return string(data)
+	}
+	return json.RawMessage(data)
+}
+
+func (jsonCodec) decode(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
+
+type bytesCodec struct{}
+
+func (bytesCodec) scanTarget() interface{} { return new([]byte) }
+
+func (bytesCodec) encode(scanned interface{}) interface{} {
+	if data := *scanned.(*[]byte); data != nil {
+		return base64.StdEncoding.EncodeToString(data)
+	}
+	return nil
+}
+
+func (bytesCodec) decode(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected base64, got %v", value)
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+type stringCodec struct{}
+
+func (stringCodec) scanTarget() interface{} { return new(sql.NullString) }
+
+func (stringCodec) encode(scanned interface{}) interface{} {
+	if v := scanned.(*sql.NullString); v.Valid {
+		return v.String
+	}
+	return nil
+}
+
+func (stringCodec) decode(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a string, got %v", value)
+	}
+	return s, nil
+}
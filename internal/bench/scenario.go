@@ -0,0 +1,165 @@
+// Package bench holds the performance harness: Go benchmarks for the
+// repositories against a seeded database, and a generator of weighted
+// request scenarios for load-testing the HTTP API with vegeta or k6.
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// Mix weights the kinds of request in a scenario. Weights are relative;
+// zero leaves a kind out.
+type Mix struct {
+	List   int
+	Get    int
+	Create int
+	Update int
+}
+
+// DefaultMix is a read-heavy catalog workload
+var DefaultMix = Mix{List: 40, Get: 40, Create: 10, Update: 10}
+
+// Target is one HTTP request of a scenario. It marshals to vegeta's JSON
+// target format, which expects the body base64-encoded.
+type Target struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// Generate returns n requests against the API at baseURL, drawn according to
+// mix. Gets and updates target the given existing products; without any,
+// only lists and creates are generated. The same seed gives the same
+// scenario, so runs against different builds are comparable.
+func Generate(baseURL string, mix Mix, products []*models.Product, n int, seed int64) ([]Target, error) {
+	if len(products) == 0 {
+		mix.Get, mix.Update = 0, 0
+	}
+	total := mix.List + mix.Get + mix.Create + mix.Update
+	if total <= 0 {
+		return nil, fmt.Errorf("scenario mix has no request kinds")
+	}
+
+	api := strings.TrimSuffix(baseURL, "/") + "/api/v1/products"
+	rng := rand.New(rand.NewSource(seed))
+	targets := make([]Target, 0, n)
+
+	for i := 0; i < n; i++ {
+		pick := rng.Intn(total)
+		switch {
+		case pick < mix.List:
+			targets = append(targets, Target{
+				Method: http.MethodGet,
+				URL:    fmt.Sprintf("%s?limit=50&offset=%d", api, rng.Intn(len(products)+1)),
+			})
+
+		case pick < mix.List+mix.Get:
+			product := products[rng.Intn(len(products))]
+			targets = append(targets, Target{
+				Method: http.MethodGet,
+				URL:    fmt.Sprintf("%s/%d", api, product.ID),
+			})
+
+		case pick < mix.List+mix.Get+mix.Create:
+			product := &models.Product{
+				SKU:       fmt.Sprintf("LOAD-%d-%06d", seed, i),
+				Name:      fmt.Sprintf("Load test product %d", i),
+				Quantity:  rng.Intn(1000),
+				UnitPrice: float64(rng.Intn(10000)) / 100,
+			}
+			target, err := jsonTarget(http.MethodPost, api, product)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, target)
+
+		default:
+			product := *products[rng.Intn(len(products))]
+			product.Quantity = rng.Intn(1000)
+			target, err := jsonTarget(http.MethodPut, fmt.Sprintf("%s/%d", api, product.ID), &product)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, target)
+		}
+	}
+
+	return targets, nil
+}
+
+func jsonTarget(method, url string, product *models.Product) (Target, error) {
+	body, err := json.Marshal(product)
+	if err != nil {
+		return Target{}, fmt.Errorf("failed to encode product: %w", err)
+	}
+	return Target{
+		Method: method,
+		URL:    url,
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   body,
+	}, nil
+}
+
+// WriteVegeta writes targets one JSON object per line, for
+// `vegeta attack -format=json`
+func WriteVegeta(w io.Writer, targets []Target) error {
+	enc := json.NewEncoder(w)
+	for _, target := range targets {
+		if err := enc.Encode(target); err != nil {
+			return fmt.Errorf("failed to write target: %w", err)
+		}
+	}
+	return nil
+}
+
+// k6Request is a target as k6's http.request takes it, with a string body
+type k6Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+const k6Script = `import http from 'k6/http';
+import { check } from 'k6';
+
+export const options = { vus: 10, duration: '30s' };
+
+const targets = %s;
+
+export default function () {
+  const t = targets[(__VU * 7919 + __ITER) %% targets.length];
+  const res = http.request(t.method, t.url, t.body || null, { headers: t.headers || {} });
+  check(res, { 'no server error': (r) => r.status < 500 });
+}
+`
+
+// WriteK6 writes targets as a k6 script that replays them, for `k6 run`.
+// Adjust options in the script or override them with k6 flags.
+func WriteK6(w io.Writer, targets []Target) error {
+	requests := make([]k6Request, len(targets))
+	for i, target := range targets {
+		requests[i] = k6Request{Method: target.Method, URL: target.URL, Body: string(target.Body)}
+		if len(target.Header) > 0 {
+			requests[i].Headers = make(map[string]string, len(target.Header))
+			for name := range target.Header {
+				requests[i].Headers[name] = target.Header.Get(name)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(requests, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode targets: %w", err)
+	}
+	_, err = fmt.Fprintf(w, k6Script, data)
+	return err
+}
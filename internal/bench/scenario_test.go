@@ -0,0 +1,110 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func TestGenerate(t *testing.T) {
+	products := []*models.Product{{ID: 7, SKU: "A", Name: "a"}, {ID: 9, SKU: "B", Name: "b"}}
+
+	targets, err := Generate("http://localhost:8080/", DefaultMix, products, 500, 1)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(targets) != 500 {
+		t.Fatalf("got %d targets, want 500", len(targets))
+	}
+
+	counts := make(map[string]int)
+	for _, target := range targets {
+		if !strings.HasPrefix(target.URL, "http://localhost:8080/api/v1/products") {
+			t.Fatalf("unexpected URL %s", target.URL)
+		}
+		counts[target.Method]++
+
+		if target.Method == http.MethodPut {
+			var product models.Product
+			if err := json.Unmarshal(target.Body, &product); err != nil {
+				t.Fatalf("invalid update body: %v", err)
+			}
+			if product.ID != 7 && product.ID != 9 {
+				t.Errorf("update targets unknown product %d", product.ID)
+			}
+		}
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut} {
+		if counts[method] == 0 {
+			t.Errorf("no %s requests in a default mix", method)
+		}
+	}
+
+	again, _ := Generate("http://localhost:8080/", DefaultMix, products, 500, 1)
+	if !reflect.DeepEqual(targets, again) {
+		t.Error("the same seed generated a different scenario")
+	}
+}
+
+func TestGenerate_NoProducts(t *testing.T) {
+	targets, err := Generate("http://api", DefaultMix, nil, 100, 1)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for _, target := range targets {
+		if target.Method == http.MethodPut || strings.HasPrefix(target.URL, "http://api/api/v1/products/") {
+			t.Fatalf("generated %s %s without existing products", target.Method, target.URL)
+		}
+	}
+
+	if _, err := Generate("http://api", Mix{Get: 1}, nil, 1, 1); err == nil {
+		t.Error("expected an error for a mix with nothing to generate")
+	}
+}
+
+func TestWriteVegeta(t *testing.T) {
+	targets, _ := Generate("http://api", Mix{Create: 1}, nil, 3, 1)
+
+	var buf bytes.Buffer
+	if err := WriteVegeta(&buf, targets); err != nil {
+		t.Fatalf("WriteVegeta: %v", err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	lines := 0
+	for scanner.Scan() {
+		var target Target
+		if err := json.Unmarshal(scanner.Bytes(), &target); err != nil {
+			t.Fatalf("line %d isn't a JSON target: %v", lines, err)
+		}
+		if !reflect.DeepEqual(target, targets[lines]) {
+			t.Errorf("line %d = %+v, want %+v", lines, target, targets[lines])
+		}
+		lines++
+	}
+	if lines != len(targets) {
+		t.Errorf("wrote %d lines, want %d", lines, len(targets))
+	}
+}
+
+func TestWriteK6(t *testing.T) {
+	targets, _ := Generate("http://api", Mix{Create: 1}, nil, 2, 1)
+
+	var buf bytes.Buffer
+	if err := WriteK6(&buf, targets); err != nil {
+		t.Fatalf("WriteK6: %v", err)
+	}
+
+	script := buf.String()
+	for _, want := range []string{"import http from 'k6/http'", `"method": "POST"`, `"Content-Type": "application/json"`, "% targets.length"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q:\n%s", want, script)
+		}
+	}
+}
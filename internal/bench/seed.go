@@ -0,0 +1,33 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Products returns n products with SKUs <prefix>-000000 upwards
+func Products(prefix string, n int) []*models.Product {
+	products := make([]*models.Product, n)
+	for i := range products {
+		products[i] = &models.Product{
+			SKU:         fmt.Sprintf("%s-%06d", prefix, i),
+			Name:        fmt.Sprintf("Benchmark product %d", i),
+			Description: "Seeded for benchmarks",
+			Quantity:    i % 1000,
+			UnitPrice:   float64(i%10000) / 100,
+		}
+	}
+	return products
+}
+
+// Seed inserts n products through BulkCreate and returns them with their IDs
+func Seed(ctx context.Context, repo repository.ProductRepository, prefix string, n int) ([]*models.Product, error) {
+	products := Products(prefix, n)
+	if _, err := repo.BulkCreate(ctx, products); err != nil {
+		return nil, fmt.Errorf("failed to seed products: %w", err)
+	}
+	return products, nil
+}
@@ -0,0 +1,78 @@
+// Package blob stores objects such as catalog exports in a directory or an
+// S3-compatible bucket.
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store writes objects by key. Keys are slash-separated paths, e.g.
+// exports/products/dt=2026-10-14/products.csv.gz.
+type Store interface {
+	// Put stores size bytes of body under key, replacing any object there.
+	// body is read from the start and may be read more than once.
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) error
+
+	// URL is where key is stored, for logs and export history
+	URL(key string) string
+}
+
+// FileStore stores objects as files under a directory, for development and
+// for exports picked up by another process
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	// Written aside and renamed, so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.CopyN(tmp, body, size); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) URL(key string) string {
+	path, _ := s.path(key)
+	return "file://" + filepath.ToSlash(path)
+}
+
+// path maps key into the directory, refusing keys that would leave it
+func (s *FileStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
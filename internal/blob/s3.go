@@ -0,0 +1,176 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config locates a bucket and the credentials to write to it
+type S3Config struct {
+	Endpoint        string // e.g. http://localhost:9000 for MinIO; empty for AWS in Region
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // For temporary credentials
+	PathStyle       bool   // Bucket in the path rather than the host name, as most S3-compatible stores need
+}
+
+// S3Store stores objects in an S3-compatible bucket. Requests are signed
+// with AWS Signature Version 4, which is all PutObject needs, so the store
+// doesn't pull in an SDK.
+type S3Store struct {
+	cfg    S3Config
+	base   *url.URL
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3: a bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", endpoint)
+	}
+	if !cfg.PathStyle {
+		base.Host = cfg.Bucket + "." + base.Host
+	}
+	return &S3Store{cfg: cfg, base: base, client: &http.Client{}, now: time.Now}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) error {
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.CopyN(hash, body, size); err != nil {
+		return fmt.Errorf("failed to hash blob: %w", err)
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), io.LimitReader(body, size))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, hex.EncodeToString(hash.Sum(nil)), s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3: failed to put %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var s3err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		_ = xml.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&s3err)
+		return fmt.Errorf("s3: failed to put %s: %s %s %s", key, resp.Status, s3err.Code, s3err.Message)
+	}
+	return nil
+}
+
+func (s *S3Store) URL(key string) string {
+	return "s3://" + s.cfg.Bucket + "/" + key
+}
+
+// objectURL is encoded as it's signed, including characters such as = that
+// net/url leaves alone in paths
+func (s *S3Store) objectURL(key string) string {
+	path := "/" + key
+	if s.cfg.PathStyle {
+		path = "/" + s.cfg.Bucket + path
+	}
+	return s.base.Scheme + "://" + s.base.Host + uriEncode(path)
+}
+
+// sign adds the Signature Version 4 Authorization header, signing the host
+// and every header already set. Requests have no query string.
+func (s *S3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	canonical.WriteString(req.Method + "\n")
+	canonical.WriteString(req.URL.EscapedPath() + "\n")
+	canonical.WriteString("\n")
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonical.WriteString("\n" + signedHeaders + "\n" + payloadHash)
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonical.String()))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), date)
+	for _, part := range []string{s.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.cfg.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode percent-encodes everything but unreserved characters and
+// slashes, as Signature Version 4 expects
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,277 @@
+// Package cache caches successful GET responses. Entries are keyed by the
+// full request URL and the caller's auth scope, tagged with what they show,
+// and invalidated by tag when product events are published.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/formats"
+	"{{MODULE_NAME}}/internal/pubsub"
+	"{{MODULE_NAME}}/internal/quota"
+)
+
+var (
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_cache_requests_total",
+		Help: "Cacheable requests, by route and result (hit, miss, bypass).",
+	}, []string{"route", "result"})
+	cacheInvalidations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "http_cache_invalidations_total",
+		Help: "Cache invalidations triggered by events.",
+	})
+	cacheErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_cache_errors_total",
+		Help: "Failed cache store operations, by operation (get, set, invalidate, share).",
+	}, []string{"op"})
+)
+
+// Header reports whether a response was served from the cache
+const Header = "X-Cache"
+
+// Store holds encoded responses. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Get returns the entry stored under key, if any and not expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores an entry for ttl, tagged for Invalidate
+	Set(ctx context.Context, key string, entry []byte, ttl time.Duration, tags []string) error
+	// Invalidate removes every entry carrying any of the tags
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// ProductsTag is the tag of product listings, which any product change can
+// affect
+const ProductsTag = "products"
+
+// PromotionsTag is the tag of responses showing effective prices, which any
+// promotion change can affect
+const PromotionsTag = "promotions"
+
+// SavedSearchesTag is the tag of product listings through a saved search,
+// which changing or deleting saved searches invalidates
+const SavedSearchesTag = "saved_searches"
+
+// InvalidationChannel is the pubsub channel of shared invalidations
+const InvalidationChannel = "cache_invalidations"
+
+// allTag tags every entry of a cache sharing its invalidations, so a resync
+// can drop them all
+const allTag = "*"
+
+// ProductTag is the tag of responses showing the product with id
+func ProductTag(id string) string {
+	return "product:" + id
+}
+
+// Cache wraps GET handlers, storing their 200 responses. A nil *Cache caches
+// nothing.
+type Cache struct {
+	store  Store
+	ttl    time.Duration
+	epoch  atomic.Uint64 // Bumped by every invalidation
+	shared *pubsub.PubSub
+	logger *slog.Logger
+}
+
+func New(store Store, ttl time.Duration, logger *slog.Logger) *Cache {
+	return &Cache{
+		store:  store,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// Middleware serves GET requests from the cache, and caches the 200 responses
+// of the handler it wraps under tags(r), except those sent with
+// Cache-Control: no-store. route labels the metrics. Requests with
+// Cache-Control: no-cache skip the lookup but refresh the entry.
+func (c *Cache) Middleware(route string, tags func(r *http.Request) []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if c == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := Key(r)
+			result := "bypass"
+			if r.Header.Get("Cache-Control") != "no-cache" {
+				result = "miss"
+				entry, ok, err := c.store.Get(r.Context(), key)
+				if err != nil {
+					cacheErrors.WithLabelValues("get").Inc()
+					c.logger.Warn("response cache lookup failed", "error", err)
+				}
+				if ok {
+					if contentType, body, valid := decodeEntry(entry); valid {
+						cacheRequests.WithLabelValues(route, "hit").Inc()
+						w.Header().Set("Content-Type", contentType)
+						w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+						w.Header().Set(Header, "HIT")
+						w.WriteHeader(http.StatusOK)
+						w.Write(body)
+						return
+					}
+				}
+			}
+			cacheRequests.WithLabelValues(route, result).Inc()
+
+			// A write committed while the handler ran may not be in its
+			// response, which then mustn't be stored
+			epoch := c.epoch.Load()
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			w.Header().Set(Header, strings.ToUpper(result))
+			next.ServeHTTP(rec, r)
+
+			if rec.status != http.StatusOK || c.epoch.Load() != epoch || w.Header().Get("Cache-Control") == "no-store" {
+				return
+			}
+			entry := encodeEntry(w.Header().Get("Content-Type"), rec.body.Bytes())
+			entryTags := tags(r)
+			if c.shared != nil {
+				entryTags = append(entryTags[:len(entryTags):len(entryTags)], allTag)
+			}
+			if err := c.store.Set(r.Context(), key, entry, c.ttl, entryTags); err != nil {
+				cacheErrors.WithLabelValues("set").Inc()
+				c.logger.Warn("failed to store response in cache", "error", err)
+			}
+		})
+	}
+}
+
+// Invalidate removes the entries carrying any of the tags, on every instance
+// if the cache is shared
+func (c *Cache) Invalidate(ctx context.Context, tags ...string) {
+	if c == nil {
+		return
+	}
+	c.invalidate(ctx, tags...)
+	if c.shared != nil {
+		if err := c.shared.Publish(ctx, InvalidationChannel, tags); err != nil {
+			cacheErrors.WithLabelValues("share").Inc()
+			c.logger.Error("failed to share response cache invalidation", "tags", tags, "error", err)
+		}
+	}
+}
+
+// Share makes invalidations apply on every instance sharing through ps, for
+// stores each instance holds in memory. When ps reconnects, and
+// invalidations may have been missed, every entry is dropped.
+func (c *Cache) Share(ps *pubsub.PubSub) {
+	if c == nil {
+		return
+	}
+	c.shared = ps
+	ps.Subscribe(InvalidationChannel, func(ctx context.Context, msg pubsub.Message) error {
+		tags := []string{allTag}
+		if !msg.Resync {
+			if err := msg.Decode(&tags); err != nil {
+				return err
+			}
+		}
+		c.invalidate(ctx, tags...)
+		return nil
+	})
+}
+
+func (c *Cache) invalidate(ctx context.Context, tags ...string) {
+	c.epoch.Add(1)
+	cacheInvalidations.Inc()
+	if err := c.store.Invalidate(ctx, tags...); err != nil {
+		cacheErrors.WithLabelValues("invalidate").Inc()
+		c.logger.Error("failed to invalidate response cache", "tags", tags, "error", err)
+	}
+}
+
+// Invalidator returns an event handler invalidating the responses that show
+// a changed product. Inside a transaction the entries are removed once it
+// commits, so a concurrent read can't cache the state before the change; a
+// rolled-back write (or dry run) leaves them alone.
+func (c *Cache) Invalidator() events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		switch event.Type {
+		case events.TypeProductCreated, events.TypeProductUpdated, events.TypeProductDeleted, events.TypeStockAdjusted, events.TypeProductVisibilityChanged:
+		default:
+			return nil
+		}
+
+		tags := []string{ProductsTag, ProductTag(event.Payload.AggregateID())}
+		database.AfterCommit(ctx, func() {
+			c.Invalidate(context.WithoutCancel(ctx), tags...)
+		})
+		return nil
+	}
+}
+
+// Key identifies a response: the full request URL and the auth scope, a hash
+// of the Authorization header, so callers never share entries across
+// credentials. A regional API key's region joins the scope, as it narrows
+// the products shown, and so does a format other than JSON.
+func Key(r *http.Request) string {
+	scope := "anonymous"
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		scope = hex.EncodeToString(sum[:8])
+	}
+	if key := quota.FromContext(r.Context()); key != nil && key.Region != "" {
+		scope += " region=" + key.Region
+	}
+	if format := formats.FromContext(r.Context()); format != nil {
+		scope += " format=" + format.Name()
+	}
+	return scope + " " + r.Host + r.URL.RequestURI()
+}
+
+// An entry is the Content-Type, a newline, and the body
+func encodeEntry(contentType string, body []byte) []byte {
+	entry := make([]byte, 0, len(contentType)+1+len(body))
+	entry = append(entry, contentType...)
+	entry = append(entry, '\n')
+	return append(entry, body...)
+}
+
+func decodeEntry(entry []byte) (contentType string, body []byte, ok bool) {
+	i := bytes.IndexByte(entry, '\n')
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(entry[:i]), entry[i+1:], true
+}
+
+// recorder passes a response through while keeping a copy of its body
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
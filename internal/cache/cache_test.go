@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	lru := NewLRU(2)
+	lru.now = func() time.Time { return now }
+
+	lru.Set(ctx, "a", []byte("A"), time.Second, []string{"products"})
+	lru.Set(ctx, "b", []byte("B"), time.Second, []string{"product:1"})
+	lru.Get(ctx, "a") // a is now the most recently used
+	lru.Set(ctx, "c", []byte("C"), time.Second, nil)
+
+	if _, ok, _ := lru.Get(ctx, "b"); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	if value, ok, _ := lru.Get(ctx, "a"); !ok || string(value) != "A" {
+		t.Errorf("Get(a) = %q, %v", value, ok)
+	}
+
+	lru.Invalidate(ctx, "products")
+	if _, ok, _ := lru.Get(ctx, "a"); ok {
+		t.Error("expected the tagged entry to be invalidated")
+	}
+	if _, ok, _ := lru.Get(ctx, "c"); !ok {
+		t.Error("untagged entry was invalidated")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok, _ := lru.Get(ctx, "c"); ok {
+		t.Error("expected the entry to expire")
+	}
+	if lru.Len() != 0 || len(lru.tags) != 0 {
+		t.Errorf("%d entries and %d tags left", lru.Len(), len(lru.tags))
+	}
+}
+
+// countingHandler answers with its call count, or status if set
+type countingHandler struct {
+	calls  int
+	status int
+	during func() // Runs while the request is handled
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	if h.during != nil {
+		h.during()
+	}
+	if h.status != 0 {
+		w.WriteHeader(h.status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"calls":%d}`, h.calls)
+}
+
+func get(handler http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func tagged(tags ...string) func(*http.Request) []string {
+	return func(*http.Request) []string { return tags }
+}
+
+func TestMiddleware(t *testing.T) {
+	next := &countingHandler{}
+	c := New(NewLRU(100), time.Minute, testLogger)
+	handler := c.Middleware("test", tagged(ProductsTag))(next)
+
+	first := get(handler, "/products?limit=5", nil)
+	second := get(handler, "/products?limit=5", nil)
+	if first.Header().Get(Header) != "MISS" || second.Header().Get(Header) != "HIT" {
+		t.Errorf("X-Cache = %q then %q, want MISS then HIT", first.Header().Get(Header), second.Header().Get(Header))
+	}
+	if second.Body.String() != `{"calls":1}` || second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("cached response = %q (%s)", second.Body.String(), second.Header().Get("Content-Type"))
+	}
+
+	// The query, credentials, and Cache-Control: no-cache each miss
+	get(handler, "/products?limit=6", nil)
+	get(handler, "/products?limit=5", http.Header{"Authorization": {"Bearer other"}})
+	if w := get(handler, "/products?limit=5", http.Header{"Cache-Control": {"no-cache"}}); w.Header().Get(Header) != "BYPASS" {
+		t.Errorf("X-Cache = %q with no-cache, want BYPASS", w.Header().Get(Header))
+	}
+	if next.calls != 4 {
+		t.Errorf("handler called %d times, want 4", next.calls)
+	}
+
+	c.Invalidate(context.Background(), ProductsTag)
+	if w := get(handler, "/products?limit=5", nil); w.Header().Get(Header) != "MISS" {
+		t.Error("expected a miss after invalidation")
+	}
+}
+
+func TestMiddleware_DoesNotStore(t *testing.T) {
+	c := New(NewLRU(100), time.Minute, testLogger)
+
+	notFound := &countingHandler{status: http.StatusNotFound}
+	handler := c.Middleware("test", tagged())(notFound)
+	get(handler, "/products/9", nil)
+	get(handler, "/products/9", nil)
+	if notFound.calls != 2 {
+		t.Errorf("404 served from cache: handler called %d times", notFound.calls)
+	}
+
+	// A write committed while the response was built may be missing from it
+	racing := &countingHandler{}
+	racing.during = func() { c.Invalidate(context.Background(), "product:1") }
+	handler = c.Middleware("test", tagged("product:1"))(racing)
+	get(handler, "/products/1", nil)
+	get(handler, "/products/1", nil)
+	if racing.calls != 2 {
+		t.Errorf("response overlapping an invalidation was cached: handler called %d times", racing.calls)
+	}
+
+	// Nor one its handler marks as not to be stored
+	uncacheable := &countingHandler{}
+	handler = c.Middleware("test", tagged())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		uncacheable.ServeHTTP(w, r)
+	}))
+	get(handler, "/products?sort=random", nil)
+	get(handler, "/products?sort=random", nil)
+	if uncacheable.calls != 2 {
+		t.Errorf("no-store response served from cache: handler called %d times", uncacheable.calls)
+	}
+}
+
+type productPayload struct{ id string }
+
+func (p productPayload) EventType() events.Type { return events.TypeProductUpdated }
+func (p productPayload) SchemaVersion() int     { return 1 }
+func (p productPayload) AggregateID() string    { return p.id }
+
+func TestInvalidator(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "cache.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	lru := NewLRU(100)
+	c := New(lru, time.Minute, testLogger)
+	bus := events.NewBus(testLogger)
+	bus.SubscribeAll(c.Invalidator())
+
+	cached := func() int {
+		lru.Set(ctx, "list", []byte("x"), time.Minute, []string{ProductsTag})
+		lru.Set(ctx, "one", []byte("x"), time.Minute, []string{ProductTag("1")})
+		lru.Set(ctx, "two", []byte("x"), time.Minute, []string{ProductTag("2")})
+		return lru.Len()
+	}
+
+	// Published inside a transaction, entries stay until it commits
+	cached()
+	err = db.WithTx(ctx, func(ctx context.Context) error {
+		bus.Publish(ctx, events.New(productPayload{id: "1"}))
+		if lru.Len() != 3 {
+			t.Errorf("%d entries before commit, want 3", lru.Len())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if _, ok, _ := lru.Get(ctx, "two"); !ok || lru.Len() != 1 {
+		t.Errorf("%d entries after commit, want only product 2's", lru.Len())
+	}
+
+	// A rolled-back write invalidates nothing
+	cached()
+	db.WithTx(ctx, func(ctx context.Context) error {
+		bus.Publish(ctx, events.New(productPayload{id: "1"}))
+		return errors.New("rollback")
+	})
+	if lru.Len() != 3 {
+		t.Errorf("%d entries after rollback, want 3", lru.Len())
+	}
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in-memory Store holding up to a fixed number of entries, evicting
+// the least recently used. Each instance has its own, so after a write other
+// instances serve their entries until the TTL passes.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Most recently used at the front
+	entries  map[string]*list.Element
+	tags     map[string]map[string]struct{} // Tag to keys
+	now      func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+	tags    []string
+}
+
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		tags:     make(map[string]map[string]struct{}),
+		now:      time.Now,
+	}
+}
+
+func (l *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*lruEntry)
+	if !l.now().Before(entry.expires) {
+		l.remove(elem)
+		return nil, false, nil
+	}
+	l.order.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+func (l *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.entries[key]; ok {
+		l.remove(elem)
+	}
+	entry := &lruEntry{key: key, value: value, expires: l.now().Add(ttl), tags: tags}
+	l.entries[key] = l.order.PushFront(entry)
+	for _, tag := range tags {
+		if l.tags[tag] == nil {
+			l.tags[tag] = make(map[string]struct{})
+		}
+		l.tags[tag][key] = struct{}{}
+	}
+
+	for l.order.Len() > l.capacity {
+		l.remove(l.order.Back())
+	}
+	return nil
+}
+
+func (l *LRU) Invalidate(ctx context.Context, tags ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range l.tags[tag] {
+			l.remove(l.entries[key])
+		}
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet removed
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+func (l *LRU) remove(elem *list.Element) {
+	entry := l.order.Remove(elem).(*lruEntry)
+	delete(l.entries, entry.key)
+	for _, tag := range entry.tags {
+		delete(l.tags[tag], entry.key)
+		if len(l.tags[tag]) == 0 {
+			delete(l.tags, tag)
+		}
+	}
+}
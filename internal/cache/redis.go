@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Store shared by every instance, so an invalidation after a
+// write on one instance clears the entries for all of them. A tag is a set
+// of the keys carrying it.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis connects to the Redis server at url (redis://host:port/db) and
+// prefixes every key written with prefix
+func NewRedis(url, prefix string) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &Redis{client: client, prefix: prefix}, nil
+}
+
+func (s *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (s *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.prefix+key, value, ttl)
+		for _, tag := range tags {
+			// The set outlives the entries added to it; it is refreshed by
+			// every entry and empty when the last one expires
+			pipe.SAdd(ctx, s.tagKey(tag), s.prefix+key)
+			pipe.Expire(ctx, s.tagKey(tag), ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *Redis) Invalidate(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		keys, err := s.client.SMembers(ctx, s.tagKey(tag)).Result()
+		if err != nil {
+			return err
+		}
+		if err := s.client.Del(ctx, append(keys, s.tagKey(tag))...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Redis) Close() error {
+	return s.client.Close()
+}
+
+func (s *Redis) tagKey(tag string) string {
+	return s.prefix + "tag:" + tag
+}
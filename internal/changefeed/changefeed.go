@@ -0,0 +1,189 @@
+// Package changefeed serves the product change feed: the product events of
+// the outbox, in commit order, as upserts, stock changes, and delete
+// tombstones for downstream caches and search indexes to sync from.
+package changefeed
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Now starts reading at the current end of the feed, so a new consumer can
+// take a cursor before copying the catalog and catch up from it
+const Now = "now"
+
+var (
+	ErrInvalidCursor = errors.New("invalid change cursor")
+
+	// ErrCursorExpired means the change at the cursor was purged from the
+	// outbox, so changes after it may be lost; the consumer must resync
+	ErrCursorExpired = errors.New("change cursor expired")
+)
+
+// types are the outbox events the feed is derived from
+var types = []string{
+	string(events.TypeProductCreated),
+	string(events.TypeProductUpdated),
+	string(events.TypeProductDeleted),
+	string(events.TypeStockAdjusted),
+	string(events.TypeProductVisibilityChanged),
+}
+
+type Feed struct {
+	repo repository.OutboxRepository
+}
+
+func New(repo repository.OutboxRepository) *Feed {
+	return &Feed{repo: repo}
+}
+
+// Read returns up to limit changes after the since cursor: from the start of
+// the retained outbox when since is empty, or none but the current cursor
+// when it is Now
+func (f *Feed) Read(ctx context.Context, since string, limit int) (*models.ChangeFeed, error) {
+	if since == Now {
+		head, err := f.repo.Head(ctx, types)
+		if err != nil {
+			return nil, err
+		}
+		return &models.ChangeFeed{Changes: []*models.ProductChange{}, NextCursor: EncodeCursor(head)}, nil
+	}
+
+	var after models.ChangeCursor
+	if since != "" {
+		cursor, err := DecodeCursor(since)
+		if err != nil {
+			return nil, err
+		}
+		// The zero cursor, handed out while the outbox was empty, is the start
+		if cursor != (models.ChangeCursor{}) {
+			exists, err := f.repo.Has(ctx, cursor)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, ErrCursorExpired
+			}
+		}
+		after = cursor
+	}
+
+	// One extra message tells whether there are more
+	messages, err := f.repo.Changes(ctx, types, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &models.ChangeFeed{Changes: []*models.ProductChange{}, NextCursor: EncodeCursor(after)}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		feed.HasMore = true
+	}
+	for _, msg := range messages {
+		change, err := newChange(msg)
+		if err != nil {
+			return nil, err
+		}
+		feed.Changes = append(feed.Changes, change)
+		feed.NextCursor = change.Cursor
+	}
+
+	return feed, nil
+}
+
+// InRegion limits a page of the feed to the products sold in region, judged
+// by the regions they're limited to now: upserts of products sold elsewhere
+// become delete tombstones, so consumers drop what was taken out of the
+// region, and their stock changes are left out. The cursor is unchanged.
+func InRegion(ctx context.Context, feed *models.ChangeFeed, region string, regions repository.ProductRegionRepository) error {
+	var ids []int
+	for _, change := range feed.Changes {
+		if change.Op != models.ChangeDelete {
+			ids = append(ids, change.ProductID)
+		}
+	}
+	limited, err := regions.Of(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	changes := feed.Changes[:0]
+	for _, change := range feed.Changes {
+		product := models.Product{Regions: limited[change.ProductID]}
+		switch {
+		case change.Op == models.ChangeDelete || product.SoldIn(region):
+		case change.Op == models.ChangeStock:
+			continue
+		default:
+			change.Op, change.Product = models.ChangeDelete, nil
+		}
+		changes = append(changes, change)
+	}
+	feed.Changes = changes
+	return nil
+}
+
+// newChange derives a change from an outbox message
+func newChange(msg *models.OutboxMessage) (*models.ProductChange, error) {
+	event, err := events.Decode(msg.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode outbox message %d: %w", msg.ID, err)
+	}
+
+	change := &models.ProductChange{
+		Cursor:     EncodeCursor(models.ChangeCursor{TxID: msg.TxID, ID: msg.ID}),
+		EventID:    event.ID,
+		EventType:  string(event.Type),
+		OccurredAt: event.OccurredAt,
+	}
+	switch p := event.Payload.(type) {
+	case *events.ProductCreated:
+		change.Op, change.ProductID, change.SKU, change.Product = models.ChangeUpsert, p.Product.ID, p.Product.SKU, &p.Product
+	case *events.ProductUpdated:
+		change.Op, change.ProductID, change.SKU, change.Product = models.ChangeUpsert, p.Product.ID, p.Product.SKU, &p.Product
+	case *events.ProductVisibilityChanged:
+		change.Op, change.ProductID, change.SKU, change.Product = models.ChangeUpsert, p.Product.ID, p.Product.SKU, &p.Product
+	case *events.StockAdjusted:
+		change.Op, change.ProductID, change.SKU, change.Quantity = models.ChangeStock, p.ProductID, p.SKU, &p.Current
+	case *events.ProductDeleted:
+		change.Op, change.ProductID, change.SKU = models.ChangeDelete, p.ProductID, p.SKU
+	default:
+		return nil, fmt.Errorf("outbox message %d: %s is not a product change", msg.ID, event.Type)
+	}
+
+	return change, nil
+}
+
+// EncodeCursor renders a cursor as the opaque string clients pass back
+func EncodeCursor(c models.ChangeCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.TxID, 10) + "." + strconv.FormatInt(c.ID, 10)))
+}
+
+// DecodeCursor parses a cursor rendered by EncodeCursor
+func DecodeCursor(s string) (models.ChangeCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return models.ChangeCursor{}, ErrInvalidCursor
+	}
+	txid, id, ok := strings.Cut(string(data), ".")
+	if !ok {
+		return models.ChangeCursor{}, ErrInvalidCursor
+	}
+
+	var c models.ChangeCursor
+	if c.TxID, err = strconv.ParseInt(txid, 10, 64); err != nil || c.TxID < 0 {
+		return models.ChangeCursor{}, ErrInvalidCursor
+	}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil || c.ID < 0 {
+		return models.ChangeCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,175 @@
+package changefeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/outbox"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// memoryOutbox keeps messages in order, as if every transaction committed
+type memoryOutbox struct {
+	repository.OutboxRepository
+	messages []*models.OutboxMessage
+}
+
+func (o *memoryOutbox) Changes(ctx context.Context, types []string, after models.ChangeCursor, limit int) ([]*models.OutboxMessage, error) {
+	var changes []*models.OutboxMessage
+	for _, msg := range o.messages {
+		if msg.ID > after.ID && len(changes) < limit {
+			changes = append(changes, msg)
+		}
+	}
+	return changes, nil
+}
+
+func (o *memoryOutbox) Head(ctx context.Context, types []string) (models.ChangeCursor, error) {
+	if len(o.messages) == 0 {
+		return models.ChangeCursor{}, nil
+	}
+	last := o.messages[len(o.messages)-1]
+	return models.ChangeCursor{TxID: last.TxID, ID: last.ID}, nil
+}
+
+func (o *memoryOutbox) Has(ctx context.Context, cursor models.ChangeCursor) (bool, error) {
+	for _, msg := range o.messages {
+		if msg.ID == cursor.ID && msg.TxID == cursor.TxID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (o *memoryOutbox) publish(t *testing.T, payload events.Payload) {
+	t.Helper()
+	msg, err := outbox.NewMessage(events.New(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.ID = int64(len(o.messages) + 1)
+	msg.TxID = 100
+	o.messages = append(o.messages, msg)
+}
+
+func TestRead(t *testing.T) {
+	repo := &memoryOutbox{}
+	feed := New(repo)
+	ctx := context.Background()
+
+	start, err := feed.Read(ctx, Now, 10)
+	if err != nil || len(start.Changes) != 0 || start.NextCursor != EncodeCursor(models.ChangeCursor{}) {
+		t.Fatalf("Read(now) of an empty feed = %+v, %v", start, err)
+	}
+
+	product := models.Product{ID: 7, SKU: "BK-7", Name: "Atlas", Quantity: 3}
+	repo.publish(t, events.ProductCreated{Product: product})
+	repo.publish(t, events.StockAdjusted{ProductID: 7, SKU: "BK-7", Previous: 3, Current: 1, Delta: -2, Reason: "order"})
+	repo.publish(t, events.ProductDeleted{ProductID: 7, SKU: "BK-7"})
+
+	page, err := feed.Read(ctx, start.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(page.Changes) != 2 || !page.HasMore {
+		t.Fatalf("page = %+v, want 2 changes and more", page)
+	}
+	upsert, stock := page.Changes[0], page.Changes[1]
+	if upsert.Op != models.ChangeUpsert || upsert.Product == nil || upsert.Product.Name != "Atlas" || upsert.EventType != "product.created" {
+		t.Errorf("first change = %+v, want an upsert of the product", upsert)
+	}
+	if stock.Op != models.ChangeStock || stock.Quantity == nil || *stock.Quantity != 1 || stock.Product != nil {
+		t.Errorf("second change = %+v, want a stock change to 1", stock)
+	}
+	if page.NextCursor != stock.Cursor {
+		t.Errorf("NextCursor = %s, want the last change's %s", page.NextCursor, stock.Cursor)
+	}
+
+	page, err = feed.Read(ctx, page.NextCursor, 2)
+	if err != nil || len(page.Changes) != 1 || page.HasMore {
+		t.Fatalf("second page = %+v, %v; want the last change", page, err)
+	}
+	if tombstone := page.Changes[0]; tombstone.Op != models.ChangeDelete || tombstone.ProductID != 7 || tombstone.SKU != "BK-7" || tombstone.Product != nil {
+		t.Errorf("last change = %+v, want a tombstone", tombstone)
+	}
+
+	// Caught up: the cursor stays put
+	caughtUp, err := feed.Read(ctx, page.NextCursor, 2)
+	if err != nil || len(caughtUp.Changes) != 0 || caughtUp.NextCursor != page.NextCursor {
+		t.Errorf("Read at the end = %+v, %v; want no changes and the same cursor", caughtUp, err)
+	}
+}
+
+// memoryRegions limits products to the regions in the map
+type memoryRegions struct {
+	repository.ProductRegionRepository
+	regions map[int][]string
+}
+
+func (m memoryRegions) Of(ctx context.Context, productIDs []int) (map[int][]string, error) {
+	return m.regions, nil
+}
+
+func TestInRegion(t *testing.T) {
+	repo := &memoryOutbox{}
+	repo.publish(t, events.ProductCreated{Product: models.Product{ID: 1, SKU: "DE-1"}})
+	repo.publish(t, events.ProductCreated{Product: models.Product{ID: 2, SKU: "FR-2"}})
+	repo.publish(t, events.StockAdjusted{ProductID: 2, SKU: "FR-2", Current: 4})
+	repo.publish(t, events.StockAdjusted{ProductID: 3, SKU: "ANY-3", Current: 5})
+	repo.publish(t, events.ProductDeleted{ProductID: 4, SKU: "GONE-4"})
+	ctx := context.Background()
+
+	page, err := New(repo).Read(ctx, "", 10)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	next := page.NextCursor
+	if err := InRegion(ctx, page, "DE", memoryRegions{regions: map[int][]string{1: {"DE"}, 2: {"FR"}}}); err != nil {
+		t.Fatalf("InRegion: %v", err)
+	}
+
+	var ops []string
+	for _, change := range page.Changes {
+		ops = append(ops, fmt.Sprintf("%s %d", change.Op, change.ProductID))
+	}
+	// FR-2's upsert turns into a tombstone and its stock change is dropped
+	want := []string{"upsert 1", "delete 2", "stock 3", "delete 4"}
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("changes in DE = %v, want %v", ops, want)
+	}
+	if page.Changes[1].Product != nil {
+		t.Errorf("tombstone of FR-2 = %+v, want no product", page.Changes[1])
+	}
+	if page.NextCursor != next {
+		t.Errorf("NextCursor = %s, want %s, past the dropped changes", page.NextCursor, next)
+	}
+}
+
+func TestRead_Cursors(t *testing.T) {
+	repo := &memoryOutbox{}
+	repo.publish(t, events.ProductDeleted{ProductID: 1})
+	feed := New(repo)
+	ctx := context.Background()
+
+	for _, since := range []string{"not base64!", EncodeCursor(models.ChangeCursor{ID: 1})[:2], "MS4y.x"} {
+		if _, err := feed.Read(ctx, since, 10); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Read(%q) = %v, want ErrInvalidCursor", since, err)
+		}
+	}
+
+	// The message behind a cursor has been purged
+	purged := EncodeCursor(models.ChangeCursor{TxID: 90, ID: 1})
+	if _, err := feed.Read(ctx, purged, 10); !errors.Is(err, ErrCursorExpired) {
+		t.Errorf("Read of a purged cursor = %v, want ErrCursorExpired", err)
+	}
+
+	cursor, err := DecodeCursor(EncodeCursor(models.ChangeCursor{TxID: 1 << 40, ID: 42}))
+	if err != nil || cursor != (models.ChangeCursor{TxID: 1 << 40, ID: 42}) {
+		t.Errorf("round trip = %+v, %v", cursor, err)
+	}
+}
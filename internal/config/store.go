@@ -0,0 +1,209 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Loader produces a freshly loaded configuration, used by Store.Reload
+type Loader func() (*Config, error)
+
+// Change describes a single runtime setting that differs between two snapshots
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ReloadEvent is passed to reload hooks after every reload attempt,
+// including failed ones
+type ReloadEvent struct {
+	Source   string // "signal" or "api"
+	Actor    string
+	Previous *Config
+	Current  *Config
+	Changes  []Change
+	Ignored  []string // Structural settings that changed but require a restart
+	Err      error
+}
+
+type ReloadHook func(ctx context.Context, event ReloadEvent)
+
+// Store holds the active configuration snapshot. Readers always see a
+// complete, validated Config; reloads swap the pointer atomically.
+type Store struct {
+	current atomic.Pointer[Config]
+	load    Loader
+
+	mu    sync.Mutex // Serializes reloads and guards hooks
+	hooks []ReloadHook
+}
+
+func NewStore(cfg *Config, load Loader) *Store {
+	s := &Store{load: load}
+	s.current.Store(cfg)
+	return s
+}
+
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// OnReload registers a hook invoked after each reload attempt
+func (s *Store) OnReload(hook ReloadHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Reload loads and validates a new configuration and swaps it in. Structural
+// settings (listen address, database, environment) are kept from the running
+// configuration and reported as ignored.
+func (s *Store) Reload(ctx context.Context, source, actor string) (ReloadEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.current.Load()
+	event := ReloadEvent{
+		Source:   source,
+		Actor:    actor,
+		Previous: previous,
+		Current:  previous,
+	}
+
+	next, err := s.load()
+	if err == nil {
+		next, event.Ignored = mergeRuntime(previous, next)
+		err = next.Validate()
+	}
+	if err != nil {
+		event.Err = fmt.Errorf("config reload rejected: %w", err)
+		s.notify(ctx, event)
+		return event, event.Err
+	}
+
+	event.Current = next
+	event.Changes = Diff(previous, next)
+	s.current.Store(next)
+	s.notify(ctx, event)
+
+	return event, nil
+}
+
+func (s *Store) notify(ctx context.Context, event ReloadEvent) {
+	for _, hook := range s.hooks {
+		hook(ctx, event)
+	}
+}
+
+// runtimeFields can change without a restart, every other Config field is
+// structural and keeps its running value on reload
+var runtimeFields = map[string]bool{
+	"LogLevel":           true,
+	"LogLevels":          true,
+	"CORSAllowedOrigins": true,
+	"RateLimitRPS":       true,
+	"RateLimitBurst":     true,
+	"FeatureFlags":       true,
+
+	"AvailabilityRateLimitRPS":   true,
+	"AvailabilityRateLimitBurst": true,
+
+	"ConcurrencyLimitRoute":  true,
+	"ConcurrencyLimitTenant": true,
+
+	"PolicyRules": true,
+	"PolicyRoles": true,
+
+	"APIKeysRequired": true,
+}
+
+// mergeRuntime returns the running configuration with the runtime settings of
+// next applied, plus the names of structural settings that changed
+func mergeRuntime(running, next *Config) (*Config, []string) {
+	merged := *running
+	var ignored []string
+
+	rv := reflect.ValueOf(running).Elem()
+	nv := reflect.ValueOf(next).Elem()
+	mv := reflect.ValueOf(&merged).Elem()
+
+	for i := 0; i < rv.NumField(); i++ {
+		name := rv.Type().Field(i).Name
+		if runtimeFields[name] {
+			mv.Field(i).Set(nv.Field(i))
+			continue
+		}
+		if !reflect.DeepEqual(rv.Field(i).Interface(), nv.Field(i).Interface()) {
+			ignored = append(ignored, name)
+		}
+	}
+
+	return &merged, ignored
+}
+
+// Diff lists the runtime settings that differ between two configurations
+func Diff(old, new *Config) []Change {
+	var changes []Change
+	add := func(field string, o, n interface{}) {
+		if !reflect.DeepEqual(o, n) {
+			changes = append(changes, Change{Field: field, Old: fmt.Sprint(o), New: fmt.Sprint(n)})
+		}
+	}
+
+	add("LOG_LEVEL", old.LogLevel, new.LogLevel)
+	add("LOG_LEVELS", formatMap(old.LogLevels), formatMap(new.LogLevels))
+	add("CORS_ALLOWED_ORIGINS", strings.Join(old.CORSAllowedOrigins, ","), strings.Join(new.CORSAllowedOrigins, ","))
+	add("RATE_LIMIT_RPS", old.RateLimitRPS, new.RateLimitRPS)
+	add("RATE_LIMIT_BURST", old.RateLimitBurst, new.RateLimitBurst)
+	add("AVAILABILITY_RATE_LIMIT_RPS", old.AvailabilityRateLimitRPS, new.AvailabilityRateLimitRPS)
+	add("AVAILABILITY_RATE_LIMIT_BURST", old.AvailabilityRateLimitBurst, new.AvailabilityRateLimitBurst)
+	add("CONCURRENCY_LIMIT_ROUTE", old.ConcurrencyLimitRoute, new.ConcurrencyLimitRoute)
+	add("CONCURRENCY_LIMIT_TENANT", old.ConcurrencyLimitTenant, new.ConcurrencyLimitTenant)
+	add("FEATURE_FLAGS", formatFlags(old.FeatureFlags), formatFlags(new.FeatureFlags))
+	add("POLICY_RULES", formatMap(old.PolicyRules), formatMap(new.PolicyRules))
+	add("POLICY_ROLES", formatMap(old.PolicyRoles), formatMap(new.PolicyRoles))
+	add("API_KEYS_REQUIRED", old.APIKeysRequired, new.APIKeysRequired)
+
+	return changes
+}
+
+func formatFlags(flags map[string]bool) string {
+	names := make([]string, 0, len(flags))
+	for name, enabled := range flags {
+		if enabled {
+			names = append(names, name)
+		} else {
+			names = append(names, "!"+name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func formatMap(items map[string]string) string {
+	pairs := make([]string, 0, len(items))
+	for k, v := range items {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+type contextKey struct{}
+
+// WithConfig returns a context carrying the given configuration snapshot
+func WithConfig(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, contextKey{}, cfg)
+}
+
+// FromContext returns the snapshot attached by WithConfig, or nil
+func FromContext(ctx context.Context) *Config {
+	cfg, _ := ctx.Value(contextKey{}).(*Config)
+	return cfg
+}
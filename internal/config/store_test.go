@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func baseConfig() *Config {
+	return &Config{
+		Port:            "8080",
+		Host:            "0.0.0.0",
+		DatabaseURL:     "postgres://localhost/test",
+		LogLevel:        "info",
+		LogFormat:       "text",
+		Environment:     "development",
+		RateLimitBurst:  20,
+		OutboxBatchSize: 100,
+		FeatureFlags:    map[string]bool{},
+
+		QuotaFlushInterval: 10 * time.Second,
+		ForecastWindow:     28 * 24 * time.Hour,
+		ForecastHorizon:    30 * 24 * time.Hour,
+		ABCAnalysisWindow:  90 * 24 * time.Hour,
+		ReportTimeout:      30 * time.Second,
+		ReportMaxRows:      10000,
+		SagaResumeInterval: time.Minute,
+	}
+}
+
+func TestStore_Reload(t *testing.T) {
+	next := baseConfig()
+	next.LogLevel = "debug"
+	next.Port = "9090"
+	next.FeatureFlags = map[string]bool{"shadow_reads": true}
+
+	store := NewStore(baseConfig(), func() (*Config, error) { return next, nil })
+
+	var hookCalls int
+	store.OnReload(func(ctx context.Context, event ReloadEvent) { hookCalls++ })
+
+	event, err := store.Reload(context.Background(), "api", "tester")
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	current := store.Current()
+	if current.LogLevel != "debug" {
+		t.Errorf("LogLevel = %v, want debug", current.LogLevel)
+	}
+	if current.Port != "8080" {
+		t.Errorf("Port = %v, want structural setting to be kept at 8080", current.Port)
+	}
+	if !current.FeatureEnabled("shadow_reads") {
+		t.Error("expected shadow_reads feature flag to be enabled")
+	}
+	if len(event.Changes) != 2 {
+		t.Errorf("len(Changes) = %d, want 2: %+v", len(event.Changes), event.Changes)
+	}
+	if len(event.Ignored) != 1 || event.Ignored[0] != "Port" {
+		t.Errorf("Ignored = %v, want [Port]", event.Ignored)
+	}
+	if hookCalls != 1 {
+		t.Errorf("hook called %d times, want 1", hookCalls)
+	}
+}
+
+func TestStore_ReloadRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		load Loader
+	}{
+		{"loader error", func() (*Config, error) { return nil, errors.New("boom") }},
+		{"invalid log level", func() (*Config, error) {
+			cfg := baseConfig()
+			cfg.LogLevel = "verbose"
+			return cfg, nil
+		}},
+		{"negative rate limit", func() (*Config, error) {
+			cfg := baseConfig()
+			cfg.RateLimitRPS = -1
+			return cfg, nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := baseConfig()
+			store := NewStore(original, tt.load)
+
+			var event ReloadEvent
+			store.OnReload(func(ctx context.Context, e ReloadEvent) { event = e })
+
+			if _, err := store.Reload(context.Background(), "signal", "SIGHUP"); err == nil {
+				t.Fatal("expected reload to be rejected")
+			}
+			if store.Current() != original {
+				t.Error("expected the running configuration to be kept")
+			}
+			if event.Err == nil {
+				t.Error("expected hook to receive the reload error")
+			}
+		})
+	}
+}
+
+func TestParseFeatureFlags(t *testing.T) {
+	flags := parseFeatureFlags(" a, b ,!c,,")
+
+	want := map[string]bool{"a": true, "b": true, "c": false}
+	if len(flags) != len(want) {
+		t.Fatalf("parseFeatureFlags() = %v, want %v", flags, want)
+	}
+	for name, enabled := range want {
+		if flags[name] != enabled {
+			t.Errorf("flag %s = %v, want %v", name, flags[name], enabled)
+		}
+	}
+}
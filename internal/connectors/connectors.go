@@ -0,0 +1,64 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// RemoteProduct is a product as it exists in an external catalog
+type RemoteProduct struct {
+	ExternalID  string
+	SKU         string // SKU in the external catalog, see CatalogConnector.MapSKU
+	Name        string
+	Description string
+	Quantity    int
+	UnitPrice   float64
+	UpdatedAt   time.Time
+}
+
+// CatalogConnector synchronises products with an external catalog such as a
+// storefront or marketplace
+type CatalogConnector interface {
+	// Name identifies the connector in sync state and logs
+	Name() string
+
+	// Pull returns remote products changed after since. A zero since returns
+	// the full catalog.
+	Pull(ctx context.Context, since time.Time) ([]RemoteProduct, error)
+
+	// Push writes local products to the remote catalog and returns how many
+	// were written. Products unknown to the remote catalog are skipped.
+	Push(ctx context.Context, products []*models.Product) (int, error)
+
+	// MapSKU translates a remote product's SKU to the local SKU. It returns
+	// false for products that should not be synced.
+	MapSKU(remote RemoteProduct) (string, bool)
+}
+
+// ConflictPolicy decides which side wins when a product changed both locally
+// and remotely since the last sync
+type ConflictPolicy string
+
+const (
+	// LastWriteWins keeps whichever change is newer and syncs both ways
+	LastWriteWins ConflictPolicy = "last_write_wins"
+	// RemoteWins treats the external catalog as the source of truth: remote
+	// changes are pulled and local changes are never pushed
+	RemoteWins ConflictPolicy = "remote"
+	// LocalWins treats this service as the source of truth: local changes are
+	// pushed and remote changes are never pulled
+	LocalWins ConflictPolicy = "local"
+)
+
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case LastWriteWins, RemoteWins, LocalWins:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid conflict policy %q: must be %s, %s, or %s",
+			s, LastWriteWins, RemoteWins, LocalWins)
+	}
+}
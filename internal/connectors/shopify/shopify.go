@@ -0,0 +1,295 @@
+package shopify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"{{MODULE_NAME}}/internal/connectors"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type Config struct {
+	ShopDomain  string // e.g. my-store.myshopify.com
+	AccessToken string // Admin API access token
+	APIVersion  string // e.g. 2024-07
+	LocationID  int64  // Inventory location that stock is read from and written to
+	SKUPrefix   string // Stripped from Shopify SKUs before matching local SKUs
+	HTTPClient  *http.Client
+}
+
+// Connector syncs products with a Shopify store through the REST Admin API.
+// Every variant is one product; pushes update variant prices and inventory
+// levels at the configured location.
+type Connector struct {
+	cfg     Config
+	baseURL string
+	client  *http.Client
+
+	mu       sync.Mutex
+	variants map[string]variantRef // Local SKU to Shopify variant, filled by pulls
+}
+
+type variantRef struct {
+	ID              int64
+	InventoryItemID int64
+}
+
+func New(cfg Config) (*Connector, error) {
+	if cfg.ShopDomain == "" || cfg.AccessToken == "" {
+		return nil, fmt.Errorf("shopify: shop domain and access token are required")
+	}
+	if cfg.LocationID == 0 {
+		return nil, fmt.Errorf("shopify: location ID is required")
+	}
+	if cfg.APIVersion == "" {
+		cfg.APIVersion = "2024-07"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Connector{
+		cfg:      cfg,
+		baseURL:  fmt.Sprintf("https://%s/admin/api/%s", cfg.ShopDomain, cfg.APIVersion),
+		client:   cfg.HTTPClient,
+		variants: make(map[string]variantRef),
+	}, nil
+}
+
+func (c *Connector) Name() string {
+	return "shopify"
+}
+
+type product struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	BodyHTML  string    `json:"body_html"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Variants  []variant `json:"variants"`
+}
+
+type variant struct {
+	ID                int64     `json:"id"`
+	Title             string    `json:"title"`
+	SKU               string    `json:"sku"`
+	Price             string    `json:"price"`
+	InventoryItemID   int64     `json:"inventory_item_id"`
+	InventoryQuantity int       `json:"inventory_quantity"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func (c *Connector) Pull(ctx context.Context, since time.Time) ([]connectors.RemoteProduct, error) {
+	query := url.Values{}
+	query.Set("limit", "250")
+	query.Set("fields", "id,title,body_html,updated_at,variants")
+	if !since.IsZero() {
+		query.Set("updated_at_min", since.UTC().Format(time.RFC3339))
+	}
+
+	var remote []connectors.RemoteProduct
+	next := c.baseURL + "/products.json?" + query.Encode()
+
+	for next != "" {
+		var page struct {
+			Products []product `json:"products"`
+		}
+		header, err := c.do(ctx, http.MethodGet, next, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range page.Products {
+			for _, v := range p.Variants {
+				rp, err := toRemote(p, v)
+				if err != nil {
+					return nil, err
+				}
+				remote = append(remote, rp)
+
+				if sku, ok := c.MapSKU(rp); ok {
+					c.mu.Lock()
+					c.variants[sku] = variantRef{ID: v.ID, InventoryItemID: v.InventoryItemID}
+					c.mu.Unlock()
+				}
+			}
+		}
+
+		next = nextPage(header.Get("Link"))
+	}
+
+	return remote, nil
+}
+
+// Push updates price and stock of variants. Names and descriptions belong to
+// the Shopify product, which may hold several variants, and are not pushed.
+func (c *Connector) Push(ctx context.Context, products []*models.Product) (int, error) {
+	c.mu.Lock()
+	missing := false
+	for _, p := range products {
+		if _, ok := c.variants[p.SKU]; !ok {
+			missing = true
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	// Learn variant IDs of products that haven't been pulled since start-up
+	if missing {
+		if _, err := c.Pull(ctx, time.Time{}); err != nil {
+			return 0, err
+		}
+	}
+
+	pushed := 0
+	for _, p := range products {
+		c.mu.Lock()
+		ref, ok := c.variants[p.SKU]
+		c.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		body := map[string]interface{}{
+			"variant": map[string]interface{}{
+				"id":    ref.ID,
+				"price": strconv.FormatFloat(p.UnitPrice, 'f', 2, 64),
+			},
+		}
+		if _, err := c.do(ctx, http.MethodPut, fmt.Sprintf("%s/variants/%d.json", c.baseURL, ref.ID), body, nil); err != nil {
+			return pushed, err
+		}
+
+		body = map[string]interface{}{
+			"location_id":       c.cfg.LocationID,
+			"inventory_item_id": ref.InventoryItemID,
+			"available":         p.Quantity,
+		}
+		if _, err := c.do(ctx, http.MethodPost, c.baseURL+"/inventory_levels/set.json", body, nil); err != nil {
+			return pushed, err
+		}
+
+		pushed++
+	}
+
+	return pushed, nil
+}
+
+func (c *Connector) MapSKU(remote connectors.RemoteProduct) (string, bool) {
+	sku := strings.TrimSpace(remote.SKU)
+	sku = strings.TrimPrefix(sku, c.cfg.SKUPrefix)
+	return sku, sku != ""
+}
+
+func toRemote(p product, v variant) (connectors.RemoteProduct, error) {
+	price, err := strconv.ParseFloat(v.Price, 64)
+	if err != nil {
+		return connectors.RemoteProduct{}, fmt.Errorf("shopify: invalid price %q for variant %d", v.Price, v.ID)
+	}
+
+	name := p.Title
+	if v.Title != "" && v.Title != "Default Title" {
+		name += " - " + v.Title
+	}
+
+	updatedAt := p.UpdatedAt
+	if v.UpdatedAt.After(updatedAt) {
+		updatedAt = v.UpdatedAt
+	}
+
+	return connectors.RemoteProduct{
+		ExternalID:  strconv.FormatInt(v.ID, 10),
+		SKU:         v.SKU,
+		Name:        name,
+		Description: p.BodyHTML,
+		Quantity:    v.InventoryQuantity,
+		UnitPrice:   price,
+		UpdatedAt:   updatedAt,
+	}, nil
+}
+
+// do sends a request to the Admin API, retrying when rate limited
+func (c *Connector) do(ctx context.Context, method, endpoint string, body, out interface{}) (http.Header, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Shopify-Access-Token", c.cfg.AccessToken)
+		req.Header.Set("Accept", "application/json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("shopify: %s %s: %w", method, req.URL.Path, err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < 3 {
+			resp.Body.Close()
+			if err := sleep(ctx, retryAfter(resp.Header.Get("Retry-After"))); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			return nil, fmt.Errorf("shopify: %s %s: status %d: %s", method, req.URL.Path, resp.StatusCode, msg)
+		}
+
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return nil, fmt.Errorf("shopify: failed to decode response: %w", err)
+			}
+		}
+		return resp.Header, nil
+	}
+}
+
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextPage extracts the cursor URL of the next page from a Link header
+func nextPage(link string) string {
+	if m := nextLink.FindStringSubmatch(link); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func retryAfter(value string) time.Duration {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return 2 * time.Second
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
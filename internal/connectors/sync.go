@@ -0,0 +1,277 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/queue"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Syncer runs catalog syncs for one connector. Each run pulls remote changes
+// since the last successful run, resolves conflicts with the configured
+// policy, pushes local changes, and records the outcome in the sync state.
+type Syncer struct {
+	connector CatalogConnector
+	products  repository.ProductRepository
+	states    repository.SyncStateRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	policy    ConflictPolicy
+	pushes    *queue.Pool // Runs pushes when set, see QueuePushes
+	logger    *slog.Logger
+
+	mu sync.Mutex // Serialises runs
+}
+
+func NewSyncer(connector CatalogConnector, products repository.ProductRepository, states repository.SyncStateRepository, tx repository.Transactor, publisher events.Publisher, policy ConflictPolicy, logger *slog.Logger) *Syncer {
+	return &Syncer{
+		connector: connector,
+		products:  products,
+		states:    states,
+		tx:        tx,
+		publisher: publisher,
+		policy:    policy,
+		logger:    logger.With("connector", connector.Name()),
+	}
+}
+
+// Run performs one sync. Products are applied one transaction at a time, so a
+// failure part way keeps the products already synced; the watermark only
+// advances when the whole run succeeds.
+func (s *Syncer) Run(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.states.Get(ctx, s.connector.Name())
+	if err != nil {
+		return err
+	}
+
+	var since time.Time
+	if state.LastSuccessAt != nil {
+		since = *state.LastSuccessAt
+	}
+
+	start := time.Now()
+	result := &models.SyncState{
+		Connector:     s.connector.Name(),
+		LastRunAt:     &start,
+		LastSuccessAt: state.LastSuccessAt,
+	}
+
+	runErr := s.sync(ctx, since, result)
+	if runErr != nil {
+		msg := runErr.Error()
+		result.LastError = &msg
+	} else {
+		result.LastSuccessAt = &start
+	}
+
+	if err := s.states.Save(ctx, result); err != nil {
+		return errors.Join(runErr, err)
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+
+	s.logger.Info("catalog sync finished",
+		"pulled", result.Pulled,
+		"created", result.Created,
+		"updated", result.Updated,
+		"pushed", result.Pushed,
+		"conflicts", result.Conflicts,
+		"skipped", result.Skipped,
+	)
+	return nil
+}
+
+func (s *Syncer) sync(ctx context.Context, since time.Time, result *models.SyncState) error {
+	// Local changes since the last sync, candidates for pushing
+	var changed map[string]*models.Product
+	if s.policy != RemoteWins {
+		local, err := s.products.ListUpdatedSince(ctx, since)
+		if err != nil {
+			return err
+		}
+		changed = make(map[string]*models.Product, len(local))
+		for _, p := range local {
+			changed[p.SKU] = p
+		}
+	}
+
+	if s.policy != LocalWins {
+		remote, err := s.connector.Pull(ctx, since)
+		if err != nil {
+			return fmt.Errorf("failed to pull from %s: %w", s.connector.Name(), err)
+		}
+		result.Pulled = len(remote)
+
+		for _, rp := range remote {
+			sku, ok := s.connector.MapSKU(rp)
+			if !ok {
+				result.Skipped++
+				continue
+			}
+
+			if err := s.apply(ctx, sku, rp, changed, result); err != nil {
+				return fmt.Errorf("failed to sync %s: %w", sku, err)
+			}
+		}
+	}
+
+	if s.policy != RemoteWins && len(changed) > 0 {
+		push := make([]*models.Product, 0, len(changed))
+		for _, p := range changed {
+			push = append(push, p)
+		}
+
+		if s.pushes != nil {
+			return s.queuePush(ctx, push, result)
+		}
+
+		pushed, err := s.connector.Push(ctx, push)
+		result.Pushed = pushed
+		if err != nil {
+			return fmt.Errorf("failed to push to %s: %w", s.connector.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// pushChunk is how many products one queued push carries
+const pushChunk = 50
+
+type pushPayload struct {
+	ProductIDs []int `json:"product_ids"`
+}
+
+// QueuePushes hands pushes to pool instead of making them during the sync.
+// A slow or failing remote then doesn't hold up the sync, and failed pushes
+// are retried by the pool; the run counts products as pushed once queued.
+func (s *Syncer) QueuePushes(pool *queue.Pool) {
+	s.pushes = pool
+	pool.Handle(s.pushKind(), s.runPush)
+}
+
+func (s *Syncer) pushKind() string {
+	return "catalog.push." + s.connector.Name()
+}
+
+func (s *Syncer) queuePush(ctx context.Context, push []*models.Product, result *models.SyncState) error {
+	for start := 0; start < len(push); start += pushChunk {
+		chunk := push[start:min(start+pushChunk, len(push))]
+		ids := make([]int, len(chunk))
+		for i, p := range chunk {
+			ids[i] = p.ID
+		}
+
+		payload, err := json.Marshal(pushPayload{ProductIDs: ids})
+		if err != nil {
+			return err
+		}
+		task := queue.Task{Kind: s.pushKind(), Destination: s.connector.Name(), Payload: payload}
+		if err := s.pushes.Submit(ctx, task); err != nil {
+			return fmt.Errorf("failed to queue push to %s: %w", s.connector.Name(), err)
+		}
+		result.Pushed += len(chunk)
+	}
+	return nil
+}
+
+// runPush pushes the current version of the queued products, which may have
+// changed or been deleted since they were queued
+func (s *Syncer) runPush(ctx context.Context, task queue.Task) error {
+	var payload pushPayload
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return queue.Permanent(fmt.Errorf("invalid push payload: %w", err))
+	}
+
+	push := make([]*models.Product, 0, len(payload.ProductIDs))
+	for _, id := range payload.ProductIDs {
+		p, err := s.products.GetByID(ctx, id)
+		if err != nil {
+			if err.Error() == "product not found" {
+				continue
+			}
+			return err
+		}
+		push = append(push, p)
+	}
+	if len(push) == 0 {
+		return nil
+	}
+
+	if _, err := s.connector.Push(ctx, push); err != nil {
+		return fmt.Errorf("failed to push to %s: %w", s.connector.Name(), err)
+	}
+	return nil
+}
+
+// apply creates or updates the local product from the remote one, unless the
+// conflict policy keeps the local version. Products taken from the remote
+// side are removed from changed so they aren't pushed back.
+func (s *Syncer) apply(ctx context.Context, sku string, rp RemoteProduct, changed map[string]*models.Product, result *models.SyncState) error {
+	return s.tx.WithTx(ctx, func(ctx context.Context) error {
+		existing, err := s.products.GetBySKU(ctx, sku)
+		if err != nil {
+			if err.Error() != "product not found" {
+				return err
+			}
+
+			product := &models.Product{
+				SKU:         sku,
+				Name:        rp.Name,
+				Description: rp.Description,
+				Quantity:    rp.Quantity,
+				UnitPrice:   rp.UnitPrice,
+			}
+			if err := s.products.Create(ctx, product); err != nil {
+				return err
+			}
+			result.Created++
+			return s.publisher.Publish(ctx, events.New(events.ProductCreated{Product: *product}))
+		}
+
+		if sameAsRemote(existing, rp) {
+			delete(changed, sku)
+			return nil
+		}
+
+		if local, ok := changed[sku]; ok {
+			result.Conflicts++
+			if s.policy == LastWriteWins && local.UpdatedAt.After(rp.UpdatedAt) {
+				s.logger.Debug("keeping newer local product", "sku", sku)
+				return nil
+			}
+			delete(changed, sku)
+		}
+
+		updated := *existing
+		updated.Name = rp.Name
+		updated.Description = rp.Description
+		updated.Quantity = rp.Quantity
+		updated.UnitPrice = rp.UnitPrice
+		if err := s.products.Update(ctx, &updated); err != nil {
+			return err
+		}
+		result.Updated++
+		return s.publisher.Publish(ctx, events.ProductUpdates(existing, &updated, "catalog_sync")...)
+	})
+}
+
+func sameAsRemote(p *models.Product, rp RemoteProduct) bool {
+	return p.Name == rp.Name &&
+		p.Description == rp.Description &&
+		p.Quantity == rp.Quantity &&
+		p.UnitPrice == rp.UnitPrice
+}
@@ -0,0 +1,172 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type fakeConnector struct {
+	remote []RemoteProduct
+	pushed []*models.Product
+}
+
+func (c *fakeConnector) Name() string { return "fake" }
+
+func (c *fakeConnector) Pull(ctx context.Context, since time.Time) ([]RemoteProduct, error) {
+	return c.remote, nil
+}
+
+func (c *fakeConnector) Push(ctx context.Context, products []*models.Product) (int, error) {
+	c.pushed = append(c.pushed, products...)
+	return len(products), nil
+}
+
+func (c *fakeConnector) MapSKU(remote RemoteProduct) (string, bool) {
+	return remote.SKU, remote.SKU != ""
+}
+
+// fakeProducts implements the parts of ProductRepository the syncer uses
+type fakeProducts struct {
+	repository.ProductRepository
+	bySKU  map[string]*models.Product
+	nextID int
+}
+
+func (r *fakeProducts) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	p, ok := r.bySKU[sku]
+	if !ok {
+		return nil, fmt.Errorf("product not found")
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (r *fakeProducts) Create(ctx context.Context, product *models.Product) error {
+	r.nextID++
+	product.ID = r.nextID
+	cp := *product
+	r.bySKU[product.SKU] = &cp
+	return nil
+}
+
+func (r *fakeProducts) Update(ctx context.Context, product *models.Product) error {
+	cp := *product
+	r.bySKU[product.SKU] = &cp
+	return nil
+}
+
+func (r *fakeProducts) ListUpdatedSince(ctx context.Context, since time.Time) ([]*models.Product, error) {
+	var out []*models.Product
+	for _, p := range r.bySKU {
+		if p.UpdatedAt.After(since) {
+			cp := *p
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+type fakeStates struct {
+	saved *models.SyncState
+}
+
+func (s *fakeStates) Get(ctx context.Context, connector string) (*models.SyncState, error) {
+	return &models.SyncState{Connector: connector}, nil
+}
+
+func (s *fakeStates) Save(ctx context.Context, state *models.SyncState) error {
+	s.saved = state
+	return nil
+}
+
+func (s *fakeStates) List(ctx context.Context) ([]*models.SyncState, error) {
+	return nil, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(ctx context.Context, evts ...events.Event) error { return nil }
+
+func TestSyncer_ConflictPolicies(t *testing.T) {
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+
+	tests := []struct {
+		name       string
+		policy     ConflictPolicy
+		localAt    time.Time
+		remoteAt   time.Time
+		wantName   string
+		wantPushed int
+	}{
+		{"last write wins, remote newer", LastWriteWins, older, newer, "remote", 0},
+		{"last write wins, local newer", LastWriteWins, newer, older, "local", 1},
+		{"remote is source of truth", RemoteWins, newer, older, "remote", 0},
+		{"local is source of truth", LocalWins, older, newer, "local", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products := &fakeProducts{bySKU: map[string]*models.Product{
+				"A": {ID: 1, SKU: "A", Name: "local", Quantity: 1, UpdatedAt: tt.localAt},
+			}, nextID: 1}
+			connector := &fakeConnector{remote: []RemoteProduct{
+				{SKU: "A", Name: "remote", Quantity: 1, UpdatedAt: tt.remoteAt},
+				{SKU: "B", Name: "new", Quantity: 3, UpdatedAt: tt.remoteAt},
+				{SKU: "", Name: "unmapped"},
+			}}
+			states := &fakeStates{}
+
+			syncer := NewSyncer(connector, products, states, fakeTx{}, nopPublisher{}, tt.policy, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err := syncer.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if got := products.bySKU["A"].Name; got != tt.wantName {
+				t.Errorf("product name = %q, want %q", got, tt.wantName)
+			}
+			if len(connector.pushed) != tt.wantPushed {
+				t.Errorf("pushed %d products, want %d", len(connector.pushed), tt.wantPushed)
+			}
+
+			state := states.saved
+			if state == nil || state.LastSuccessAt == nil || state.LastError != nil {
+				t.Fatalf("expected a successful sync state, got %+v", state)
+			}
+			if tt.policy != LocalWins {
+				if _, ok := products.bySKU["B"]; !ok {
+					t.Error("expected remote-only product to be created")
+				}
+				if state.Skipped != 1 {
+					t.Errorf("skipped = %d, want 1", state.Skipped)
+				}
+			}
+			if tt.policy == LastWriteWins && state.Conflicts != 1 {
+				t.Errorf("conflicts = %d, want 1", state.Conflicts)
+			}
+		})
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	if _, err := ParseConflictPolicy("remote"); err != nil {
+		t.Errorf("ParseConflictPolicy(remote) error = %v", err)
+	}
+	if _, err := ParseConflictPolicy("newest"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,200 @@
+package consumers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	consumedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "consumer_messages_total",
+		Help: "Messages handled by consumers, by consumer and result (ack, nak, term).",
+	}, []string{"consumer", "result"})
+	handleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "consumer_handle_duration_seconds",
+		Help:    "Time spent handling a single message.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"consumer"})
+)
+
+// Message is a broker-independent view of a delivered message
+type Message struct {
+	ID           string // Nats-Msg-Id header if the producer set one
+	Subject      string
+	Data         []byte
+	Header       map[string][]string
+	NumDelivered uint64
+}
+
+// Handler processes one message. Returning nil acknowledges it, a Permanent
+// error terminates it, and any other error schedules a redelivery.
+type Handler func(ctx context.Context, msg Message) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable: the message is poison (malformed, or
+// refers to something that will never exist) and redelivering it can't help
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Consumer describes a durable JetStream consumer and its handler
+type Consumer struct {
+	Name       string // Durable name, survives restarts
+	Stream     string
+	Subject    string // Filter subject within the stream
+	Handler    Handler
+	MaxDeliver int           // Deliveries before the message is given up on, default 10
+	AckWait    time.Duration // Also the handler timeout, default 30s
+}
+
+// Runner runs registered consumers until stopped. Stop drains: messages
+// already pulled are handled and acknowledged before it returns.
+type Runner struct {
+	js        jetstream.JetStream
+	consumers []Consumer
+	running   []jetstream.ConsumeContext
+	logger    *slog.Logger
+}
+
+func NewRunner(js jetstream.JetStream, logger *slog.Logger) *Runner {
+	return &Runner{js: js, logger: logger}
+}
+
+func (r *Runner) Register(c Consumer) {
+	if c.MaxDeliver <= 0 {
+		c.MaxDeliver = 10
+	}
+	if c.AckWait <= 0 {
+		c.AckWait = 30 * time.Second
+	}
+	r.consumers = append(r.consumers, c)
+}
+
+// Start creates or updates the durable consumers and begins consuming
+func (r *Runner) Start(ctx context.Context) error {
+	for _, c := range r.consumers {
+		consumer, err := r.js.CreateOrUpdateConsumer(ctx, c.Stream, jetstream.ConsumerConfig{
+			Durable:       c.Name,
+			FilterSubject: c.Subject,
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			AckWait:       c.AckWait,
+			MaxDeliver:    c.MaxDeliver,
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create consumer %s on stream %s: %w", c.Name, c.Stream, err)
+		}
+
+		cc, err := consumer.Consume(r.handle(c), jetstream.PullMaxMessages(10))
+		if err != nil {
+			return fmt.Errorf("failed to start consumer %s: %w", c.Name, err)
+		}
+		r.running = append(r.running, cc)
+
+		r.logger.Info("consumer started", "consumer", c.Name, "stream", c.Stream, "subject", c.Subject)
+	}
+
+	return nil
+}
+
+// Stop stops pulling new messages and waits for in-flight ones to finish,
+// or for ctx to expire
+func (r *Runner) Stop(ctx context.Context) {
+	for _, cc := range r.running {
+		cc.Drain()
+	}
+
+	for _, cc := range r.running {
+		select {
+		case <-cc.Closed():
+		case <-ctx.Done():
+			r.logger.Warn("consumers did not drain before shutdown deadline")
+			return
+		}
+	}
+
+	r.logger.Info("consumers stopped")
+}
+
+func (r *Runner) handle(c Consumer) jetstream.MessageHandler {
+	logger := r.logger.With("consumer", c.Name)
+
+	return func(m jetstream.Msg) {
+		msg := Message{
+			Subject: m.Subject(),
+			Data:    m.Data(),
+			Header:  m.Headers(),
+		}
+		msg.ID = m.Headers().Get(jetstream.MsgIDHeader)
+		if md, err := m.Metadata(); err == nil {
+			msg.NumDelivered = md.NumDelivered
+		}
+
+		// Handlers get their own context so a drain lets them finish
+		ctx, cancel := context.WithTimeout(context.Background(), c.AckWait)
+		defer cancel()
+
+		start := time.Now()
+		err := c.Handler(ctx, msg)
+		handleDuration.WithLabelValues(c.Name).Observe(time.Since(start).Seconds())
+
+		switch {
+		case err == nil:
+			consumedMessages.WithLabelValues(c.Name, "ack").Inc()
+			if ackErr := m.Ack(); ackErr != nil {
+				logger.Warn("failed to ack message", "subject", msg.Subject, "error", ackErr)
+			}
+
+		case IsPermanent(err):
+			consumedMessages.WithLabelValues(c.Name, "term").Inc()
+			logger.Error("message rejected permanently", "subject", msg.Subject, "msg_id", msg.ID, "error", err)
+			if termErr := m.TermWithReason(err.Error()); termErr != nil {
+				logger.Warn("failed to terminate message", "subject", msg.Subject, "error", termErr)
+			}
+
+		default:
+			consumedMessages.WithLabelValues(c.Name, "nak").Inc()
+			delay := redeliveryDelay(msg.NumDelivered)
+			logger.Warn("message handling failed, will redeliver",
+				"subject", msg.Subject,
+				"msg_id", msg.ID,
+				"delivery", msg.NumDelivered,
+				"retry_in", delay.String(),
+				"error", err,
+			)
+			if nakErr := m.NakWithDelay(delay); nakErr != nil {
+				logger.Warn("failed to nak message", "subject", msg.Subject, "error", nakErr)
+			}
+		}
+	}
+}
+
+// redeliveryDelay backs off exponentially from 1s, capped at one minute
+func redeliveryDelay(delivered uint64) time.Duration {
+	if delivered < 1 {
+		delivered = 1
+	}
+	delay := time.Second << (delivered - 1)
+	if delay > time.Minute || delay <= 0 {
+		return time.Minute
+	}
+	return delay
+}
@@ -0,0 +1,45 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"{{MODULE_NAME}}/internal/inbox"
+	"{{MODULE_NAME}}/internal/inventory"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Orders returns a handler that decrements stock for order-placed messages.
+// The message body is a models.Order. Orders that can never be applied
+// (malformed, unknown SKU, not enough stock) are rejected permanently;
+// redeliveries of an order that was already applied, or of a message whose
+// ID is in box, are acknowledged.
+func Orders(svc *inventory.Service, box *inbox.Inbox) Handler {
+	return func(ctx context.Context, msg Message) error {
+		var order models.Order
+		if err := json.Unmarshal(msg.Data, &order); err != nil {
+			return Permanent(fmt.Errorf("malformed order message: %w", err))
+		}
+
+		err := box.Process(ctx, "nats", msg.ID, func(ctx context.Context) error {
+			_, err := svc.ApplyOrder(ctx, "nats", &order)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, inventory.ErrDuplicateOrder) || errors.Is(err, inbox.ErrDuplicate) {
+				return nil
+			}
+			if errors.Is(err, inventory.ErrInvalidOrder) ||
+				errors.Is(err, inventory.ErrUnknownSKU) ||
+				errors.Is(err, repository.ErrInsufficientStock) {
+				return Permanent(err)
+			}
+			return err
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Batch is a list of statements run together: with the pgx driver they are
+// sent in one round trip, otherwise one after another in a transaction.
+// Either way a batch is all or nothing: the first failing statement stops
+// it, and the statements before it are rolled back.
+type Batch struct {
+	statements []batchStatement
+}
+
+type batchStatement struct {
+	query    string
+	args     []interface{}
+	dst      interface{}   // Query: a pointer to a slice, as for ScanAll
+	dest     []interface{} // QueryRow: the values of the row
+	affected int64         // Exec: rows affected once sent
+}
+
+// Exec queues a statement run for its effect, returning its index for
+// RowsAffected
+func (b *Batch) Exec(query string, args ...interface{}) int {
+	b.statements = append(b.statements, batchStatement{query: query, args: args})
+	return len(b.statements) - 1
+}
+
+// Query queues a statement whose rows are scanned into dst, a pointer to a
+// slice of structs or struct pointers, as by ScanAll
+func (b *Batch) Query(dst interface{}, query string, args ...interface{}) {
+	b.statements = append(b.statements, batchStatement{query: query, args: args, dst: dst})
+}
+
+// QueryRow queues a statement whose single row is scanned into dest. No rows
+// fails the batch with sql.ErrNoRows.
+func (b *Batch) QueryRow(query string, args []interface{}, dest ...interface{}) {
+	b.statements = append(b.statements, batchStatement{query: query, args: args, dest: dest})
+}
+
+// Len returns the number of queued statements
+func (b *Batch) Len() int {
+	return len(b.statements)
+}
+
+// RowsAffected returns the rows affected by the statement Exec returned i
+// for, once the batch was sent
+func (b *Batch) RowsAffected(i int) int64 {
+	return b.statements[i].affected
+}
+
+// BatchError is the failure of a statement of a batch. Statements before it
+// were rolled back and statements after it didn't run.
+type BatchError struct {
+	Index int // Of the failed statement, in queue order
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch statement %d failed: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Pipelined reports whether SendBatch sends batches in one round trip: the
+// pool uses the pgx driver and ctx carries no transaction or dry run. pgx
+// can't reach the connection of a database/sql transaction, so batches in
+// one run statement by statement.
+func (db *DB) Pipelined(ctx context.Context) bool {
+	return db.pgx && !InTx(ctx) && !IsDryRun(ctx)
+}
+
+// SendBatch runs the statements of b, see Batch. A failed statement is
+// reported as a *BatchError.
+func (db *DB) SendBatch(ctx context.Context, b *Batch) error {
+	if b.Len() == 0 {
+		return nil
+	}
+	if !db.Pipelined(ctx) {
+		return db.WithTx(ctx, func(ctx context.Context) error {
+			for i := range b.statements {
+				if err := db.runStatement(ctx, &b.statements[i]); err != nil {
+					return &BatchError{Index: i, Err: err}
+				}
+			}
+			return nil
+		})
+	}
+
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn interface{}) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("pgx driver connection is a %T", driverConn)
+		}
+
+		// Statements not already in a transaction run in an implicit one,
+		// which the first failure aborts
+		batch := &pgx.Batch{}
+		for _, s := range b.statements {
+			batch.Queue(s.query, s.args...)
+		}
+		results := pgxConn.Conn().SendBatch(ctx, batch)
+		for i := range b.statements {
+			if err := readResult(results, &b.statements[i]); err != nil {
+				results.Close()
+				return &BatchError{Index: i, Err: err}
+			}
+		}
+		return results.Close()
+	})
+}
+
+// runStatement runs one statement on the connection ctx carries
+func (db *DB) runStatement(ctx context.Context, s *batchStatement) error {
+	switch {
+	case s.dst != nil:
+		rows, err := db.Conn(ctx).QueryContext(ctx, s.query, s.args...)
+		if err != nil {
+			return err
+		}
+		return ScanAll(s.dst, rows)
+	case s.dest != nil:
+		return db.Conn(ctx).QueryRowContext(ctx, s.query, s.args...).Scan(s.dest...)
+	default:
+		result, err := db.Conn(ctx).ExecContext(ctx, s.query, s.args...)
+		if err != nil {
+			return err
+		}
+		s.affected, _ = result.RowsAffected()
+		return nil
+	}
+}
+
+// readResult reads the result of the next statement of a sent batch
+func readResult(results pgx.BatchResults, s *batchStatement) error {
+	switch {
+	case s.dst != nil:
+		rows, err := results.Query()
+		if err != nil {
+			return err
+		}
+		return pgxscan.ScanAll(s.dst, rows)
+	case s.dest != nil:
+		err := results.QueryRow().Scan(s.dest...)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return err
+	default:
+		tag, err := results.Exec()
+		s.affected = tag.RowsAffected()
+		return err
+	}
+}
@@ -7,22 +7,44 @@ import (
 	"log/slog"
 	"time"
 
-	// Database driver
-	_ "github.com/lib/pq" // PostgreSQL driver
+	// Database drivers
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers "pgx", supports COPY and batches
+	_ "github.com/lib/pq"              // PostgreSQL driver
 )
 
 type Config struct {
-	URL      string // PostgreSQL connection string
+	URL      string // PostgreSQL connection string, or SQLite database file
+	Driver   string // "postgres" (lib/pq, default), "pgx", or "sqlite"
 	MaxConns int
 	MaxIdle  int
+	Session  Session // Postgres settings of each connection, ignored by SQLite
 }
 
 type DB struct {
 	*sql.DB
+	dialect Dialect
+	pgx     bool // The pool uses the pgx driver, which can send batches
+	maxIdle int
+
+	application string          // Value of the application query tag
+	queryTags   map[string]bool // Tags queries carry, see SetQueryTags
 }
 
 func NewConnection(cfg Config) (*DB, error) {
-	db, err := sql.Open("postgres", cfg.URL)
+	driver := cfg.Driver
+	if driver == "" {
+		driver = "postgres"
+	}
+
+	dialect := DialectOf(driver)
+	dsn := cfg.URL
+	session := cfg.Session
+	if dialect == SQLite {
+		dsn = sqliteDSN(dsn)
+		session = Session{}
+	}
+
+	db, err := openPool(driver, dsn, session)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -33,11 +55,11 @@ func NewConnection(cfg Config) (*DB, error) {
 		db.SetMaxOpenConns(25) // Default
 	}
 
-	if cfg.MaxIdle > 0 {
-		db.SetMaxIdleConns(cfg.MaxIdle)
-	} else {
-		db.SetMaxIdleConns(5) // Default
+	maxIdle := cfg.MaxIdle
+	if maxIdle <= 0 {
+		maxIdle = 5 // Default
 	}
+	db.SetMaxIdleConns(maxIdle)
 
 	db.SetConnMaxLifetime(5 * time.Minute)
 
@@ -49,17 +71,30 @@ func NewConnection(cfg Config) (*DB, error) {
 	}
 
 	slog.Info("database connection established",
-		"type", "postgres",
+		"type", dialect,
+		"driver", driver,
 		"max_conns", db.Stats().MaxOpenConnections,
+		"session", session.statements(),
 	)
 
 	return &DB{
-		DB: db,
+		DB:      db,
+		dialect: dialect,
+		pgx:     driver == "pgx",
+		maxIdle: maxIdle,
 	}, nil
 }
 
 func (db *DB) Type() string {
-	return "postgres"
+	return string(db.Dialect())
+}
+
+// Dialect returns the SQL dialect of the database
+func (db *DB) Dialect() Dialect {
+	if db.dialect == "" {
+		return Postgres
+	}
+	return db.dialect
 }
 
 func (db *DB) Close() error {
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// ErrCopyUnsupported is returned by CopyFrom when COPY can't be used: the pool
+// isn't using the pgx driver, or ctx carries a transaction or a dry run
+var ErrCopyUnsupported = errors.New("copy is not supported on this connection")
+
+// CopyFrom loads rows into table with COPY FROM STDIN and returns the number
+// of rows copied. It runs on its own connection, so it is all or nothing but
+// can't join a transaction started by WithTx; callers fall back to INSERT on
+// ErrCopyUnsupported.
+func (db *DB) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	if InTx(ctx) || IsDryRun(ctx) {
+		return 0, ErrCopyUnsupported
+	}
+
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var copied int64
+	err = conn.Raw(func(driverConn interface{}) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return ErrCopyUnsupported
+		}
+
+		n, err := pgxConn.Conn().CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+		copied = n
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return copied, nil
+}
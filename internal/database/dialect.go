@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	_ "modernc.org/sqlite" // Registers "sqlite", embedded storage without cgo
+)
+
+// Dialect is the SQL flavour of the database behind a DB. Queries are written
+// for PostgreSQL; the few constructs SQLite lacks are produced by the methods
+// here, so every dialect difference lives in this file.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// DialectOf returns the dialect spoken through a database/sql driver name
+func DialectOf(driver string) Dialect {
+	if driver == "sqlite" {
+		return SQLite
+	}
+	return Postgres
+}
+
+// SkipLocked returns the clause that locks selected rows for the rest of the
+// transaction, skipping rows other transactions hold. SQLite has a single
+// writer and no row locks, so it needs none.
+func (d Dialect) SkipLocked() string {
+	if d == SQLite {
+		return ""
+	}
+	return "FOR UPDATE SKIP LOCKED"
+}
+
+// ForUpdate returns the clause that locks selected rows for the rest of the
+// transaction. SQLite has a single writer and no row locks, so it needs none.
+func (d Dialect) ForUpdate() string {
+	if d == SQLite {
+		return ""
+	}
+	return "FOR UPDATE"
+}
+
+// AnyOf returns a condition matching column against any element of the array
+// bound at placeholder, e.g. AnyOf("id", 1) with Array(ids) as $1
+func (d Dialect) AnyOf(column string, placeholder int) string {
+	if d == SQLite {
+		return fmt.Sprintf("%s IN (SELECT value FROM json_each($%d))", column, placeholder)
+	}
+	return fmt.Sprintf("%s = ANY($%d)", column, placeholder)
+}
+
+// Array binds a slice for AnyOf: a Postgres array, or a JSON array that
+// SQLite expands with json_each
+func (d Dialect) Array(values interface{}) interface{} {
+	if d == SQLite {
+		data, _ := json.Marshal(values)
+		return string(data)
+	}
+	return pq.Array(values)
+}
+
+// CommitHorizon returns an expression for the oldest transaction ID that may
+// still be in flight: every transaction below it has committed or rolled
+// back. SQLite has a single writer, so nothing it can read is in flight.
+func (d Dialect) CommitHorizon() string {
+	if d == SQLite {
+		return "1"
+	}
+	return "pg_snapshot_xmin(pg_current_snapshot())::text::bigint"
+}
+
+// SnapshotTxOptions returns options for a read-only transaction that sees
+// one consistent snapshot. SQLite transactions always do.
+func (d Dialect) SnapshotTxOptions() *sql.TxOptions {
+	if d == SQLite {
+		return nil
+	}
+	return &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+}
+
+// ResetSequenceQuery returns a statement moving the sequence behind
+// table.column past the largest value in it, after rows were inserted with
+// explicit values. SQLite tracks AUTOINCREMENT itself and needs none.
+func (d Dialect) ResetSequenceQuery(table, column string) string {
+	if d == SQLite {
+		return ""
+	}
+	return fmt.Sprintf(`SELECT setval(pg_get_serial_sequence(%s, %s), COALESCE(MAX(%s), 1), MAX(%s) IS NOT NULL) FROM %s`,
+		pq.QuoteLiteral(table), pq.QuoteLiteral(column), column, column, table)
+}
+
+// RowEstimateQuery returns a query selecting the planner's estimate of the
+// rows in table, 0 before it was ever analyzed. SQLite keeps none, so it
+// returns "" and callers treat tables as small.
+func (d Dialect) RowEstimateQuery(table string) string {
+	if d == SQLite {
+		return ""
+	}
+	return fmt.Sprintf(`SELECT GREATEST(reltuples, 0)::bigint FROM pg_class WHERE oid = to_regclass(%s)`, pq.QuoteLiteral(table))
+}
+
+// TableSample returns the clause reading each row of a table with
+// probability percent/100, independently of the others. Only Postgres has
+// one, used once RowEstimateQuery says a table is large.
+func (d Dialect) TableSample(percent float64) string {
+	if d == SQLite {
+		return ""
+	}
+	return fmt.Sprintf("TABLESAMPLE BERNOULLI (%s)", strconv.FormatFloat(percent, 'f', -1, 64))
+}
+
+// VersionQuery returns a query selecting the server version as text
+func (d Dialect) VersionQuery() string {
+	if d == SQLite {
+		return "SELECT 'SQLite ' || sqlite_version()"
+	}
+	return "SELECT 'PostgreSQL ' || current_setting('server_version')"
+}
+
+// tableExistsQuery returns a query reporting whether the table named by $1
+// exists in the current schema
+func (d Dialect) tableExistsQuery() string {
+	if d == SQLite {
+		return "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = $1"
+	}
+	return "SELECT to_regclass($1) IS NOT NULL"
+}
+
+// columnsQuery returns a query listing the name, information_schema data type,
+// and nullability of the columns of the table named by $1
+func (d Dialect) columnsQuery() string {
+	if d == SQLite {
+		return `SELECT name, type, "notnull" = 0 AND pk = 0 FROM pragma_table_info($1) ORDER BY cid`
+	}
+	return `
+		SELECT column_name, data_type, is_nullable = 'YES'
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		ORDER BY ordinal_position
+	`
+}
+
+// dataType maps a column type reported by columnsQuery to its
+// information_schema name, which CompareSchema checks against
+func (d Dialect) dataType(declared string) string {
+	if d != SQLite {
+		return declared
+	}
+
+	declared = strings.ToUpper(declared)
+	if i := strings.IndexByte(declared, '('); i >= 0 {
+		declared = declared[:i]
+	}
+	switch strings.TrimSpace(declared) {
+	case "INTEGER", "INT":
+		return "integer"
+	case "BIGINT":
+		return "bigint"
+	case "VARCHAR":
+		return "character varying"
+	case "TEXT":
+		return "text"
+	case "DECIMAL", "NUMERIC":
+		return "numeric"
+	case "REAL":
+		return "real"
+	case "BOOLEAN":
+		return "boolean"
+	case "TIMESTAMP", "DATETIME":
+		return "timestamp without time zone"
+	case "JSONB", "JSON":
+		return "jsonb"
+	case "BLOB":
+		return "bytea"
+	default:
+		return strings.ToLower(declared)
+	}
+}
+
+// migrationsPath returns where the dialect's migrations are kept. SQLite has
+// its own set in the sqlite subdirectory; the schema matches Postgres minus
+// partitioning.
+func (d Dialect) migrationsPath(path string) string {
+	if d == SQLite {
+		return filepath.Join(path, "sqlite")
+	}
+	return path
+}
+
+// sqliteDSN adds the connection settings the repositories rely on unless the
+// DSN sets them: times written as sortable text, a busy timeout and immediate
+// transactions so concurrent writers wait for each other instead of failing,
+// WAL so reads don't block on writes, and enforced foreign keys.
+func sqliteDSN(dsn string) string {
+	path, query, _ := strings.Cut(dsn, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return dsn
+	}
+
+	if params.Get("_time_format") == "" {
+		params.Set("_time_format", "sqlite")
+	}
+	if params.Get("_txlock") == "" {
+		params.Set("_txlock", "immediate")
+	}
+
+	pragmas := strings.Join(params["_pragma"], ",")
+	for _, pragma := range []string{"busy_timeout(5000)", "journal_mode(WAL)", "foreign_keys(1)"} {
+		name, _, _ := strings.Cut(pragma, "(")
+		if !strings.Contains(pragmas, name) {
+			params.Add("_pragma", pragma)
+		}
+	}
+
+	return path + "?" + params.Encode()
+}
+
+// sqliteQuerier binds times in UTC, so the text SQLite stores them as sorts
+// and compares in time order
+type sqliteQuerier struct {
+	Querier
+}
+
+func (q sqliteQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return q.Querier.ExecContext(ctx, query, utcArgs(args)...)
+}
+
+func (q sqliteQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return q.Querier.QueryContext(ctx, query, utcArgs(args)...)
+}
+
+func (q sqliteQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return q.Querier.QueryRowContext(ctx, query, utcArgs(args)...)
+}
+
+func utcArgs(args []interface{}) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch t := arg.(type) {
+		case time.Time:
+			converted[i] = t.UTC()
+		case *time.Time:
+			if t != nil {
+				converted[i] = t.UTC()
+			}
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
@@ -0,0 +1,73 @@
+package embedded
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestSeed(t *testing.T) {
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(t.TempDir(), "seed.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	repo := repository.NewProductRepository(db)
+	ctx := context.Background()
+	inserted, err := Seed(ctx, repo, 20)
+	if err != nil || inserted != 20 {
+		t.Fatalf("Seed = %d, %v; want 20", inserted, err)
+	}
+
+	// A catalog with products isn't seeded again
+	inserted, err = Seed(ctx, repo, 20)
+	if err != nil || inserted != 0 {
+		t.Errorf("second Seed = %d, %v; want 0", inserted, err)
+	}
+	if count, _ := repo.Count(ctx); count != 20 {
+		t.Errorf("Count = %d, want 20", count)
+	}
+}
+
+// TestStart downloads and runs a real server, so it only runs when
+// TEST_EMBEDDED_POSTGRES is set
+func TestStart(t *testing.T) {
+	if os.Getenv("TEST_EMBEDDED_POSTGRES") == "" {
+		t.Skip("Skipping test - set TEST_EMBEDDED_POSTGRES=1 to run embedded Postgres")
+	}
+
+	server, err := Start(Config{Port: 15432}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() {
+		if err := server.Stop(); err != nil {
+			t.Errorf("Stop: %v", err)
+		}
+	}()
+
+	db, err := database.NewConnection(database.Config{URL: server.URL()})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	if inserted, err := Seed(context.Background(), repository.NewProductRepository(db), 5); err != nil || inserted != 5 {
+		t.Errorf("Seed = %d, %v; want 5", inserted, err)
+	}
+}
@@ -0,0 +1,43 @@
+package embedded
+
+import (
+	"context"
+	"fmt"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// demoNames are combined with an index to name the demo products
+var demoNames = []string{"Notebook", "Desk Lamp", "Mug", "Backpack", "Water Bottle", "Headphones", "Stapler", "Monitor Stand"}
+
+// Seed fills an empty catalog with n demo products and returns how many it
+// inserted. A catalog that already has products, such as one kept in a data
+// directory from an earlier run, is left alone.
+func Seed(ctx context.Context, repo repository.ProductRepository, n int) (int, error) {
+	count, err := repo.Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count products: %w", err)
+	}
+	if count > 0 || n <= 0 {
+		return 0, nil
+	}
+
+	products := make([]*models.Product, n)
+	for i := range products {
+		name := demoNames[i%len(demoNames)]
+		products[i] = &models.Product{
+			SKU:         fmt.Sprintf("DEMO-%04d", i+1),
+			Name:        fmt.Sprintf("%s %d", name, i/len(demoNames)+1),
+			Description: "Demo product",
+			Quantity:    (i * 7) % 50, // Some start out of stock
+			UnitPrice:   float64(499+i*250) / 100,
+		}
+	}
+
+	inserted, err := repo.BulkCreate(ctx, products)
+	if err != nil {
+		return 0, fmt.Errorf("failed to seed products: %w", err)
+	}
+	return inserted, nil
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	databaseReady = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "database_ready",
+		Help: "Whether the database answers as the primary (1) or an outage is in progress (0).",
+	})
+	databaseOutages = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "database_outages_total",
+		Help: "Database outages detected by the failover watcher, failovers included.",
+	})
+)
+
+// Failover backoff between checks during an outage
+const (
+	failoverMinBackoff = time.Second
+	failoverMaxBackoff = 30 * time.Second
+)
+
+// ErrNotPrimary is reported when the database answers but is in recovery,
+// a replica, as the old primary becomes after a failover
+var ErrNotPrimary = errors.New("database is in recovery, not the primary")
+
+// FailoverWatcher checks the database at an interval and rides out
+// failovers: when a check fails it marks the database not ready, drops the
+// pool's idle connections so new ones resolve the host again, and checks
+// with backoff until the database answers, as the primary unless replicas
+// are expected. Connections in use when the primary went away fail once and
+// are discarded by the driver.
+type FailoverWatcher struct {
+	db             *DB
+	host           string // Resolved for the failover log
+	interval       time.Duration
+	replicaAllowed bool
+	logger         *slog.Logger
+
+	ready atomic.Bool
+}
+
+// NewFailoverWatcher watches db, connected to dsn. replicaAllowed accepts a
+// database in recovery, for read-only instances pointed at a replica.
+func NewFailoverWatcher(db *DB, dsn string, interval time.Duration, replicaAllowed bool, logger *slog.Logger) *FailoverWatcher {
+	w := &FailoverWatcher{db: db, host: dsnHost(dsn), interval: interval, replicaAllowed: replicaAllowed, logger: logger}
+	w.ready.Store(true)
+	databaseReady.Set(1)
+	return w
+}
+
+// Ready reports whether the last check succeeded. A nil watcher is always
+// ready.
+func (w *FailoverWatcher) Ready() bool {
+	return w == nil || w.ready.Load()
+}
+
+// Run checks the database until ctx is cancelled
+func (w *FailoverWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := w.check(ctx); err != nil && ctx.Err() == nil {
+			w.rideOut(ctx, err)
+		}
+	}
+}
+
+// rideOut handles an outage from its first failed check until the database
+// is back or ctx is cancelled
+func (w *FailoverWatcher) rideOut(ctx context.Context, cause error) {
+	start := time.Now()
+	w.ready.Store(false)
+	databaseReady.Set(0)
+	databaseOutages.Inc()
+	w.logger.Error("database failover", "phase", "outage", "host", w.host, "addresses", w.resolve(ctx), "error", cause)
+
+	backoff := failoverMinBackoff
+	attempts := 0
+	for {
+		w.db.resetPool()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		attempts++
+		err := w.check(ctx)
+		if err == nil {
+			break
+		}
+		w.logger.Warn("database still unavailable", "attempt", attempts, "retry_in", backoff.String(), "error", err)
+		backoff = min(backoff*2, failoverMaxBackoff)
+	}
+
+	w.ready.Store(true)
+	databaseReady.Set(1)
+	w.logger.Warn("database failover", "phase", "recovered", "host", w.host, "addresses", w.resolve(ctx),
+		"outage", time.Since(start).String(), "attempts", attempts)
+}
+
+// check runs one query through the pool, failing on a database in recovery
+// unless replicas are allowed
+func (w *FailoverWatcher) check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, min(w.interval, 5*time.Second))
+	defer cancel()
+
+	var recovering bool
+	if err := w.db.QueryRowContext(ctx, `SELECT pg_is_in_recovery()`).Scan(&recovering); err != nil {
+		return err
+	}
+	if recovering && !w.replicaAllowed {
+		return ErrNotPrimary
+	}
+	return nil
+}
+
+// resolve returns the addresses the host resolves to now, for the log
+func (w *FailoverWatcher) resolve(ctx context.Context) []string {
+	if w.host == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, w.host)
+	if err != nil {
+		return []string{"unresolved: " + err.Error()}
+	}
+	return addrs
+}
+
+// resetPool closes the pool's idle connections, which may lead to the old
+// primary, so the next queries dial, and resolve, the host again
+func (db *DB) resetPool() {
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(db.maxIdle)
+}
+
+// dsnHost returns the host of a postgres:// URL, "" for other forms
+func dsnHost(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+func TestFailoverWatcher(t *testing.T) {
+	// SQLite stands in for a primary that fails over to a replica and back
+	var recovering atomic.Bool
+	sqlite.RegisterScalarFunction("pg_is_in_recovery", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return recovering.Load(), nil
+	})
+	db, err := NewConnection(Config{URL: filepath.Join(t.TempDir(), "test.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := NewFailoverWatcher(db, "sqlite", 10*time.Millisecond, false, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	go w.Run(ctx)
+
+	waitFor := func(ready bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for w.Ready() != ready {
+			if time.Now().After(deadline) {
+				t.Fatalf("Ready() still %v", !ready)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	if !w.Ready() {
+		t.Fatal("Ready() = false before any outage")
+	}
+	recovering.Store(true)
+	waitFor(false)
+	recovering.Store(false)
+	waitFor(true)
+
+	var none *FailoverWatcher
+	if !none.Ready() {
+		t.Error("a nil watcher must be ready")
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Materialized views are created by migrations over a plain view named
+// <view>_source that defines their contents, and registered in the
+// materialized_views table with their last refresh. A migration that
+// creates or recreates one WITH NO DATA sets refreshed_at to NULL, and the
+// view is populated once migrations have run. SQLite has no materialized
+// views; there the view is a table of the same columns, rebuilt on refresh.
+
+// RefreshMaterializedView recomputes view from its source and records the
+// refresh, returning the time the refreshed data is as of. Once populated,
+// PostgreSQL views are refreshed concurrently so reads continue meanwhile.
+func (db *DB) RefreshMaterializedView(ctx context.Context, view string) (time.Time, error) {
+	var refreshedAt time.Time
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		queries, err := db.refreshQueries(ctx, view)
+		if err != nil {
+			return err
+		}
+
+		// The refresh sees the data committed before it starts
+		refreshedAt = time.Now().UTC()
+		for _, query := range queries {
+			if _, err := db.Conn(ctx).ExecContext(ctx, query); err != nil {
+				return err
+			}
+		}
+
+		_, err = db.Conn(ctx).ExecContext(ctx, `UPDATE materialized_views SET refreshed_at = $1 WHERE name = $2`, refreshedAt, view)
+		return err
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to refresh materialized view %s: %w", view, err)
+	}
+
+	return refreshedAt, nil
+}
+
+func (db *DB) refreshQueries(ctx context.Context, view string) ([]string, error) {
+	name := pq.QuoteIdentifier(view)
+	source := pq.QuoteIdentifier(view + "_source")
+
+	if db.dialect == SQLite {
+		return []string{
+			`DELETE FROM ` + name,
+			`INSERT INTO ` + name + ` SELECT * FROM ` + source,
+		}, nil
+	}
+
+	// CONCURRENTLY needs a unique index, and refuses views never populated
+	var populated bool
+	query := `SELECT ispopulated FROM pg_matviews WHERE schemaname = current_schema() AND matviewname = $1`
+	if err := db.Conn(ctx).QueryRowContext(ctx, query, view).Scan(&populated); err != nil {
+		return nil, fmt.Errorf("failed to look up materialized view: %w", err)
+	}
+	if !populated {
+		return []string{`REFRESH MATERIALIZED VIEW ` + name}, nil
+	}
+	return []string{`REFRESH MATERIALIZED VIEW CONCURRENTLY ` + name}, nil
+}
+
+// MaterializedViewRefreshedAt returns when view was last refreshed, or nil
+// if it never was and holds no data
+func (db *DB) MaterializedViewRefreshedAt(ctx context.Context, view string) (*time.Time, error) {
+	var refreshedAt *time.Time
+	query := `SELECT refreshed_at FROM materialized_views WHERE name = $1`
+	if err := db.Conn(ctx).QueryRowContext(ctx, query, view).Scan(&refreshedAt); err != nil {
+		return nil, fmt.Errorf("failed to get refresh time of materialized view %s: %w", view, err)
+	}
+	return refreshedAt, nil
+}
+
+// RefreshMaterializedViews refreshes every registered view, or with
+// pendingOnly those never refreshed
+func (db *DB) RefreshMaterializedViews(ctx context.Context, pendingOnly bool) error {
+	var registered bool
+	if err := db.QueryRowContext(ctx, db.dialect.tableExistsQuery(), "materialized_views").Scan(&registered); err != nil {
+		return fmt.Errorf("failed to look up materialized views table: %w", err)
+	}
+	if !registered {
+		return nil
+	}
+
+	query := `SELECT name FROM materialized_views ORDER BY name`
+	if pendingOnly {
+		query = `SELECT name FROM materialized_views WHERE refreshed_at IS NULL ORDER BY name`
+	}
+	var views []string
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to list materialized views: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var view string
+		if err := rows.Scan(&view); err != nil {
+			return fmt.Errorf("failed to list materialized views: %w", err)
+		}
+		views = append(views, view)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list materialized views: %w", err)
+	}
+
+	for _, view := range views {
+		start := time.Now()
+		if _, err := db.RefreshMaterializedView(ctx, view); err != nil {
+			return err
+		}
+		slog.Info("materialized view refreshed", "view", view, "duration", time.Since(start).String())
+	}
+	return nil
+}
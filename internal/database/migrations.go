@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
@@ -16,7 +17,11 @@ type Migration struct {
 	Down    string
 }
 
+// RunMigrations applies the pending migrations in migrationsPath, or in its
+// sqlite subdirectory for a SQLite database
 func RunMigrations(db *DB, migrationsPath string) error {
+	migrationsPath = db.Dialect().migrationsPath(migrationsPath)
+
 	if err := createMigrationsTable(db); err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
@@ -43,6 +48,15 @@ func RunMigrations(db *DB, migrationsPath string) error {
 			return fmt.Errorf("failed to begin transaction: %w", err)
 		}
 
+		// Schema changes may rightly run longer than the statement timeout
+		// set for the application's queries
+		if db.Dialect() == Postgres {
+			if _, err := tx.Exec("SET LOCAL statement_timeout = 0"); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to lift statement timeout for migration %s: %w", migration.Version, err)
+			}
+		}
+
 		if _, err := tx.Exec(migration.Up); err != nil {
 			tx.Rollback()
 			return fmt.Errorf("failed to execute migration %s: %w", migration.Version, err)
@@ -63,6 +77,11 @@ func RunMigrations(db *DB, migrationsPath string) error {
 		slog.Info("migration applied successfully", "version", migration.Version)
 	}
 
+	// Views created or recreated WITH NO DATA by the migrations above
+	if err := db.RefreshMaterializedViews(context.Background(), true); err != nil {
+		return fmt.Errorf("failed to populate materialized views: %w", err)
+	}
+
 	return nil
 }
 
@@ -163,3 +182,41 @@ func loadMigrations(path string) ([]Migration, error) {
 
 	return migrations, nil
 }
+
+// MigrationStatus compares the migrations in migrationsPath with those applied
+// to db, without changing anything. pending are not applied yet; unknown are
+// applied but have no file, meaning a newer build migrated the database.
+func MigrationStatus(db *DB, migrationsPath string) (pending, unknown []string, err error) {
+	migrationsPath = db.Dialect().migrationsPath(migrationsPath)
+	migrations, err := loadMigrations(migrationsPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	applied := make(map[string]bool)
+	var tracked bool
+	if err := db.QueryRow(db.Dialect().tableExistsQuery(), "schema_migrations").Scan(&tracked); err != nil {
+		return nil, nil, fmt.Errorf("failed to look up migrations table: %w", err)
+	}
+	if tracked {
+		if applied, err = getAppliedMigrations(db); err != nil {
+			return nil, nil, fmt.Errorf("failed to get applied migrations: %w", err)
+		}
+	}
+
+	known := make(map[string]bool, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = true
+		if !applied[migration.Version] {
+			pending = append(pending, migration.Version)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			unknown = append(unknown, version)
+		}
+	}
+	sort.Strings(unknown)
+
+	return pending, unknown, nil
+}
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Monthly partitions of a table are named <table>_pYYYY_MM and hold rows with
+// created_at in [first of the month, first of the next month)
+const partitionSuffix = "_p2006_01"
+
+// MonthStart returns midnight UTC on the first day of t's month
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// PartitionName returns the name of the monthly partition of table holding month
+func PartitionName(table string, month time.Time) string {
+	return table + MonthStart(month).Format(partitionSuffix)
+}
+
+// PartitionMonth parses the month out of a partition name made by PartitionName
+func PartitionMonth(table, partition string) (time.Time, bool) {
+	if !strings.HasPrefix(partition, table+"_p") {
+		return time.Time{}, false
+	}
+	month, err := time.Parse(partitionSuffix, strings.TrimPrefix(partition, table))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return month, true
+}
+
+// CreateMonthlyPartition creates the partition of table for month unless it
+// already exists, and reports whether it was created. Rows of the month in
+// the table's DEFAULT partition are moved into the new partition.
+func (db *DB) CreateMonthlyPartition(ctx context.Context, table string, month time.Time) (bool, error) {
+	name := PartitionName(table, month)
+
+	var exists bool
+	if err := db.Conn(ctx).QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up partition %s: %w", name, err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	from := MonthStart(month)
+	to := from.AddDate(0, 1, 0)
+	create := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)`,
+		pq.QuoteIdentifier(name),
+		pq.QuoteIdentifier(table),
+		pq.QuoteLiteral(from.Format(time.DateOnly)),
+		pq.QuoteLiteral(to.Format(time.DateOnly)),
+	)
+
+	def, err := db.DefaultPartition(ctx, table)
+	if err != nil {
+		return false, err
+	}
+	if def != "" {
+		var held bool
+		query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE created_at >= $1 AND created_at < $2)`, pq.QuoteIdentifier(def))
+		if err := db.Conn(ctx).QueryRowContext(ctx, query, from, to).Scan(&held); err != nil {
+			return false, fmt.Errorf("failed to look up rows of %s in %s: %w", name, def, err)
+		}
+		if held {
+			if err := db.moveFromDefault(ctx, table, def, name, create, from, to); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+
+	if _, err := db.Conn(ctx).ExecContext(ctx, create); err != nil {
+		return false, fmt.Errorf("failed to create partition %s: %w", name, err)
+	}
+
+	return true, nil
+}
+
+// moveFromDefault creates the partition name of table with create, and moves
+// the rows of [from, to) into it from the DEFAULT partition def. Postgres
+// refuses a partition whose rows the DEFAULT partition holds, so def is
+// detached meanwhile, all in one transaction.
+func (db *DB) moveFromDefault(ctx context.Context, table, def, name, create string, from, to time.Time) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if err := db.DetachPartition(ctx, table, def); err != nil {
+			return err
+		}
+		if _, err := db.Conn(ctx).ExecContext(ctx, create); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", name, err)
+		}
+
+		move := fmt.Sprintf(`
+			WITH moved AS (
+				DELETE FROM %s WHERE created_at >= $1 AND created_at < $2 RETURNING *
+			)
+			INSERT INTO %s SELECT * FROM moved
+		`, pq.QuoteIdentifier(def), pq.QuoteIdentifier(name))
+		if _, err := db.Conn(ctx).ExecContext(ctx, move, from, to); err != nil {
+			return fmt.Errorf("failed to move rows from %s to %s: %w", def, name, err)
+		}
+
+		attach := fmt.Sprintf(`ALTER TABLE %s ATTACH PARTITION %s DEFAULT`, pq.QuoteIdentifier(table), pq.QuoteIdentifier(def))
+		if _, err := db.Conn(ctx).ExecContext(ctx, attach); err != nil {
+			return fmt.Errorf("failed to attach partition %s: %w", def, err)
+		}
+		return nil
+	})
+}
+
+// DefaultPartition returns the name of the DEFAULT partition of table, which
+// holds rows whose month has no partition, or "" if it has none
+func (db *DB) DefaultPartition(ctx context.Context, table string) (string, error) {
+	query := `
+		SELECT c.relname
+		FROM pg_partitioned_table p
+		JOIN pg_class c ON c.oid = p.partdefid
+		WHERE p.partrelid = to_regclass($1)
+	`
+
+	var name string
+	err := db.Conn(ctx).QueryRowContext(ctx, query, table).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up default partition of %s: %w", table, err)
+	}
+	return name, nil
+}
+
+// DefaultPartitionMonths returns the months, oldest first, of the rows in the
+// DEFAULT partition of table, none if it has no DEFAULT partition
+func (db *DB) DefaultPartitionMonths(ctx context.Context, table string) ([]time.Time, error) {
+	def, err := db.DefaultPartition(ctx, table)
+	if err != nil || def == "" {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT DISTINCT date_trunc('month', created_at) AS month FROM %s ORDER BY month`, pq.QuoteIdentifier(def))
+	rows, err := db.Conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list months in %s: %w", def, err)
+	}
+	defer rows.Close()
+
+	var months []time.Time
+	for rows.Next() {
+		var month time.Time
+		if err := rows.Scan(&month); err != nil {
+			return nil, fmt.Errorf("failed to scan month: %w", err)
+		}
+		months = append(months, MonthStart(month))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return months, nil
+}
+
+// MonthlyPartitions returns the attached partitions of table by month.
+// Partitions not named by PartitionName are left out.
+func (db *DB) MonthlyPartitions(ctx context.Context, table string) (map[string]time.Time, error) {
+	query := `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = to_regclass($1)
+	`
+
+	rows, err := db.Conn(ctx).QueryContext(ctx, query, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	partitions := make(map[string]time.Time)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		if month, ok := PartitionMonth(table, name); ok {
+			partitions[name] = month
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return partitions, nil
+}
+
+// DetachPartition detaches partition from table, keeping it as a standalone
+// table that can be archived and dropped later
+func (db *DB) DetachPartition(ctx context.Context, table, partition string) error {
+	query := fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, pq.QuoteIdentifier(table), pq.QuoteIdentifier(partition))
+	if _, err := db.Conn(ctx).ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to detach partition %s: %w", partition, err)
+	}
+	return nil
+}
+
+// DropPartition drops partition and its rows
+func (db *DB) DropPartition(ctx context.Context, partition string) error {
+	query := fmt.Sprintf(`DROP TABLE IF EXISTS %s`, pq.QuoteIdentifier(partition))
+	if _, err := db.Conn(ctx).ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to drop partition %s: %w", partition, err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPartitionName(t *testing.T) {
+	month := time.Date(2024, time.March, 17, 23, 0, 0, 0, time.UTC)
+
+	name := PartitionName("audit_log", month)
+	if name != "audit_log_p2024_03" {
+		t.Fatalf("PartitionName() = %q, want audit_log_p2024_03", name)
+	}
+
+	parsed, ok := PartitionMonth("audit_log", name)
+	if !ok || !parsed.Equal(MonthStart(month)) {
+		t.Errorf("PartitionMonth(%q) = %v, %v, want %v", name, parsed, ok, MonthStart(month))
+	}
+
+	for _, other := range []string{"audit_log_default", "stock_movements_p2024_03", "audit_log_p2024"} {
+		if _, ok := PartitionMonth("audit_log", other); ok {
+			t.Errorf("PartitionMonth(%q) matched, want no match", other)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Query tags, sqlcommenter's names where it has one
+const (
+	TagApplication = "application" // The service, set on the DB
+	TagController  = "controller"  // The repository, e.g. "product"
+	TagAction      = "action"      // The repository method, e.g. "List"
+	TagRoute       = "route"       // The route pattern, e.g. "GET /api/v1/products/{id}"
+	TagRequestID   = "request_id"
+	TagTraceparent = "traceparent" // W3C trace context of the repository call's span
+)
+
+// QueryTagNames are the tags known to WithQueryTag's callers, in the order
+// they're documented
+var QueryTagNames = []string{TagApplication, TagController, TagAction, TagRoute, TagRequestID, TagTraceparent}
+
+type queryTagsKey struct{}
+
+// queryTag is one tag of the list a context carries, newest first
+type queryTag struct {
+	name  string
+	value func() string
+	next  *queryTag
+}
+
+// WithQueryTag tags the queries run with ctx with name=value. value is called
+// when a query runs, for tags not known yet, like the route, which chi has
+// matched only once the handler runs. A later tag of the same name wins.
+func WithQueryTag(ctx context.Context, name string, value func() string) context.Context {
+	next, _ := ctx.Value(queryTagsKey{}).(*queryTag)
+	return context.WithValue(ctx, queryTagsKey{}, &queryTag{name: name, value: value, next: next})
+}
+
+// SetQueryTags makes the queries run through Conn carry the tags named in
+// include as a trailing sqlcommenter comment, /*action='List',...*/, so that
+// pg_stat_statements, the logs, and APM tools attribute load to endpoints.
+// application is the value of the application tag. No tags, the default,
+// leaves queries alone.
+func (db *DB) SetQueryTags(application string, include []string) {
+	db.application = application
+	db.queryTags = make(map[string]bool, len(include))
+	for _, name := range include {
+		db.queryTags[name] = true
+	}
+}
+
+// comment returns the comment carrying ctx's tags, "" when there are none
+func (db *DB) comment(ctx context.Context) string {
+	tags := make(map[string]string)
+	if db.queryTags[TagApplication] && db.application != "" {
+		tags[TagApplication] = db.application
+	}
+	for tag, _ := ctx.Value(queryTagsKey{}).(*queryTag); tag != nil; tag = tag.next {
+		if _, seen := tags[tag.name]; seen || !db.queryTags[tag.name] {
+			continue
+		}
+		if value := tag.value(); value != "" {
+			tags[tag.name] = value
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	return formatComment(tags)
+}
+
+// formatComment formats tags as sqlcommenter does: sorted by name, names and
+// values URL-encoded, values quoted. Encoding leaves neither quotes nor "*/"
+// in the comment, whatever a tag's value.
+func formatComment(tags map[string]string) string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("/*")
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(escapeTag(name))
+		b.WriteString("='")
+		b.WriteString(escapeTag(tags[name]))
+		b.WriteByte('\'')
+	}
+	b.WriteString("*/")
+	return b.String()
+}
+
+func escapeTag(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// tagQuery appends comment to query, ahead of a trailing semicolon
+func tagQuery(query, comment string) string {
+	trimmed := strings.TrimRight(query, " \t\n")
+	if strings.HasSuffix(trimmed, ";") {
+		return trimmed[:len(trimmed)-1] + " " + comment + ";"
+	}
+	return trimmed + " " + comment
+}
+
+// taggingQuerier appends the tags of each query's context to it
+type taggingQuerier struct {
+	Querier
+	db *DB
+}
+
+func (q taggingQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return q.Querier.ExecContext(ctx, q.tag(ctx, query), args...)
+}
+
+func (q taggingQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return q.Querier.QueryContext(ctx, q.tag(ctx, query), args...)
+}
+
+func (q taggingQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return q.Querier.QueryRowContext(ctx, q.tag(ctx, query), args...)
+}
+
+func (q taggingQuerier) tag(ctx context.Context, query string) string {
+	if comment := q.db.comment(ctx); comment != "" {
+		return tagQuery(query, comment)
+	}
+	return query
+}
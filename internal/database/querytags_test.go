@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueryTags(t *testing.T) {
+	db, err := NewConnection(Config{URL: filepath.Join(t.TempDir(), "test.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+
+	route := ""
+	ctx := WithQueryTag(context.Background(), TagController, func() string { return "product" })
+	ctx = WithQueryTag(ctx, TagAction, func() string { return "List" })
+	ctx = WithQueryTag(ctx, TagRoute, func() string { return route })
+	ctx = WithQueryTag(ctx, TagRequestID, func() string { return "host/abc-000001" })
+
+	if got := db.comment(ctx); got != "" {
+		t.Errorf("comment without SetQueryTags = %q, want none", got)
+	}
+
+	db.SetQueryTags("o'brien api", []string{TagApplication, TagController, TagAction, TagRoute})
+	if got, want := db.comment(ctx), `/*action='List',application='o%27brien%20api',controller='product'*/`; got != want {
+		t.Errorf("comment = %q, want %q", got, want)
+	}
+
+	// Read when the query runs, and most recent first
+	route = "GET /api/v1/products/{id}"
+	ctx = WithQueryTag(ctx, TagAction, func() string { return "GetByID" })
+	want := `/*action='GetByID',application='o%27brien%20api',controller='product',route='GET%20%2Fapi%2Fv1%2Fproducts%2F%7Bid%7D'*/`
+	if got := db.comment(ctx); got != want {
+		t.Errorf("comment = %q, want %q", got, want)
+	}
+
+	// Values can't end the comment early
+	if got, want := formatComment(map[string]string{"route": "*/ DROP TABLE products; /*"}), `/*route='%2A%2F%20DROP%20TABLE%20products%3B%20%2F%2A'*/`; got != want {
+		t.Errorf("formatComment = %q, want %q", got, want)
+	}
+
+	for query, want := range map[string]string{
+		"SELECT 1":    "SELECT 1 /*a='b'*/",
+		"SELECT 1;\n": "SELECT 1 /*a='b'*/;",
+	} {
+		if got := tagQuery(query, "/*a='b'*/"); got != want {
+			t.Errorf("tagQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+
+	// Tagged queries still run
+	var one int
+	if err := db.Conn(ctx).QueryRowContext(ctx, `SELECT 1`).Scan(&one); err != nil || one != 1 {
+		t.Errorf("tagged query = %d, %v", one, err)
+	}
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/georgysavva/scany/v2/sqlscan"
+)
+
+// Columns returns the column names of a model, taken from the db tags of its
+// fields in declaration order. Fields without a db tag, or tagged "-", are
+// left out. Embedded structs contribute their own columns.
+func Columns(model interface{}) []string {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, Columns(reflect.New(field.Type).Interface())...)
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("db"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		columns = append(columns, name)
+	}
+
+	return columns
+}
+
+// ColumnList returns the model's columns as a comma-separated list for
+// SELECT and RETURNING clauses, so queries pick up new columns from the model
+func ColumnList(model interface{}) string {
+	return strings.Join(Columns(model), ", ")
+}
+
+// ScanOne scans the single row of rows into dst, a pointer to a struct,
+// matching columns to db tags. It closes rows. No rows is reported as
+// sql.ErrNoRows.
+func ScanOne(dst interface{}, rows *sql.Rows) error {
+	if err := sqlscan.ScanOne(dst, rows); err != nil {
+		if sqlscan.NotFound(err) {
+			return sql.ErrNoRows
+		}
+		return err
+	}
+	return nil
+}
+
+// ScanAll scans all rows into dst, a pointer to a slice of structs or struct
+// pointers, matching columns to db tags. It closes rows.
+func ScanAll(dst interface{}, rows *sql.Rows) error {
+	return sqlscan.ScanAll(dst, rows)
+}
+
+// TableColumns returns the column names of a table in the current schema
+func (db *DB) TableColumns(ctx context.Context, table string) ([]string, error) {
+	described, err := db.DescribeTable(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(described))
+	for i, c := range described {
+		columns[i] = c.Name
+	}
+	return columns, nil
+}
+
+// ColumnDrift compares a model's db tags with a table's columns. missing are
+// model columns the table lacks, which break queries; extra are table
+// columns the model doesn't map, which are safe but usually unintended.
+func ColumnDrift(model interface{}, tableColumns []string) (missing, extra []string) {
+	inTable := make(map[string]bool, len(tableColumns))
+	for _, c := range tableColumns {
+		inTable[c] = true
+	}
+
+	inModel := make(map[string]bool)
+	for _, c := range Columns(model) {
+		inModel[c] = true
+		if !inTable[c] {
+			missing = append(missing, c)
+		}
+	}
+
+	for _, c := range tableColumns {
+		if !inModel[c] {
+			extra = append(extra, c)
+		}
+	}
+
+	return missing, extra
+}
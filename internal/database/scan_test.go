@@ -0,0 +1,69 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type baseModel struct {
+	ID        int       `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type testModel struct {
+	baseModel
+	Name     string `db:"name"`
+	Internal string `db:"-"`
+	Computed string
+	Price    float64 `db:"price,omitempty"`
+}
+
+func TestColumns(t *testing.T) {
+	want := []string{"id", "created_at", "name", "price"}
+	if got := Columns(&testModel{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Columns() = %v, want %v", got, want)
+	}
+	if got := ColumnList(testModel{}); got != "id, created_at, name, price" {
+		t.Errorf("ColumnList() = %q", got)
+	}
+}
+
+func TestColumnDrift(t *testing.T) {
+	missing, extra := ColumnDrift(testModel{}, []string{"id", "created_at", "name", "legacy_code"})
+
+	if !reflect.DeepEqual(missing, []string{"price"}) {
+		t.Errorf("missing = %v, want [price]", missing)
+	}
+	if !reflect.DeepEqual(extra, []string{"legacy_code"}) {
+		t.Errorf("extra = %v, want [legacy_code]", extra)
+	}
+}
+
+type schemaModel struct {
+	ID        int64      `db:"id"`
+	Name      string     `db:"name"`
+	Note      *string    `db:"note"`
+	Price     float64    `db:"price"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+func TestCompareSchema(t *testing.T) {
+	columns := []Column{
+		{Name: "id", DataType: "bigint"},
+		{Name: "name", DataType: "text", Nullable: true},
+		{Name: "note", DataType: "text", Nullable: true},
+		{Name: "price", DataType: "character varying"},
+		{Name: "unmapped", DataType: "integer"},
+	}
+
+	got := CompareSchema(schemaModel{}, columns)
+	want := []string{
+		"column deleted_at is missing",
+		"column name is nullable but the field is string; use a pointer or make the column NOT NULL",
+		"column price is character varying, which can't be scanned into float64",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareSchema() =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Column describes a table column as reported by information_schema
+type Column struct {
+	Name     string
+	DataType string // information_schema data_type, e.g. "integer", "character varying"; SQLite types are mapped to these
+	Nullable bool
+}
+
+// DescribeTable returns the columns of a table in the current schema
+func (db *DB) DescribeTable(ctx context.Context, table string) ([]Column, error) {
+	rows, err := db.Conn(ctx).QueryContext(ctx, db.Dialect().columnsQuery(), table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var c Column
+		if err := rows.Scan(&c.Name, &c.DataType, &c.Nullable); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		c.DataType = db.Dialect().dataType(c.DataType)
+		columns = append(columns, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s does not exist", table)
+	}
+
+	return columns, nil
+}
+
+// compatibleTypes lists the column data types each Go kind can be scanned from
+var compatibleTypes = map[reflect.Kind][]string{
+	reflect.Int:     {"integer", "bigint", "smallint"},
+	reflect.Int32:   {"integer", "smallint"},
+	reflect.Int64:   {"integer", "bigint", "smallint"},
+	reflect.Float64: {"numeric", "double precision", "real"},
+	reflect.String:  {"character varying", "text", "character", "uuid"},
+	reflect.Bool:    {"boolean"},
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	bytesType   = reflect.TypeOf([]byte(nil))
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// CompareSchema checks a model's db-tagged fields against a table's columns
+// and returns one problem per mismatch: columns missing from the table, data
+// types the field can't be scanned from, and nullable columns mapped to
+// fields that can't hold NULL. Table columns the model doesn't map are fine.
+func CompareSchema(model interface{}, columns []Column) []string {
+	byName := make(map[string]Column, len(columns))
+	for _, c := range columns {
+		byName[c.Name] = c
+	}
+
+	var problems []string
+	for name, field := range modelFields(reflect.TypeOf(model)) {
+		column, ok := byName[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("column %s is missing", name))
+			continue
+		}
+		problems = append(problems, checkField(name, field, column)...)
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+func checkField(name string, t reflect.Type, column Column) []string {
+	// Custom scanners such as sql.NullString decide for themselves
+	if reflect.PointerTo(t).Implements(scannerType) {
+		return nil
+	}
+
+	var problems []string
+	nullable := t == bytesType
+	if t.Kind() == reflect.Ptr {
+		nullable = true
+		t = t.Elem()
+	}
+
+	if column.Nullable && !nullable {
+		problems = append(problems, fmt.Sprintf("column %s is nullable but the field is %s; use a pointer or make the column NOT NULL", name, t))
+	}
+
+	var allowed []string
+	switch {
+	case t == timeType:
+		allowed = []string{"timestamp without time zone", "timestamp with time zone", "date"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		allowed = []string{"jsonb", "json", "bytea"}
+	default:
+		allowed = compatibleTypes[t.Kind()]
+	}
+
+	for _, dataType := range allowed {
+		if dataType == column.DataType {
+			return problems
+		}
+	}
+	return append(problems, fmt.Sprintf("column %s is %s, which can't be scanned into %s", name, column.DataType, t))
+}
+
+// modelFields maps the db tags of a struct, including embedded structs, to
+// their field types
+func modelFields(t reflect.Type) map[string]reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for name, ft := range modelFields(field.Type) {
+				fields[name] = ft
+			}
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("db"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+// VerifySchema compares every table with the model its rows are scanned into
+// and returns an error listing all mismatches
+func (db *DB) VerifySchema(ctx context.Context, tables map[string]interface{}) error {
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, table := range names {
+		columns, err := db.DescribeTable(ctx, table)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, problem := range CompareSchema(tables[table], columns) {
+			errs = append(errs, fmt.Errorf("%s: %s", table, problem))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("schema drift detected: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Session holds the Postgres settings every connection of the pool starts
+// with, so that one runaway query or forgotten transaction can't hold
+// connections and locks indefinitely. Zero leaves the server's default.
+type Session struct {
+	StatementTimeout                time.Duration // statement_timeout: longest a statement may run
+	LockTimeout                     time.Duration // lock_timeout: longest a statement waits for a lock
+	IdleInTransactionSessionTimeout time.Duration // idle_in_transaction_session_timeout: longest a transaction may sit idle
+}
+
+// statements returns the SET statements applying s, none when s is zero
+func (s Session) statements() []string {
+	var stmts []string
+	for _, setting := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"statement_timeout", s.StatementTimeout},
+		{"lock_timeout", s.LockTimeout},
+		{"idle_in_transaction_session_timeout", s.IdleInTransactionSessionTimeout},
+	} {
+		if setting.value <= 0 {
+			continue
+		}
+		// In milliseconds, the settings' unit; 0 would disable the timeout
+		ms := max(setting.value.Milliseconds(), 1)
+		stmts = append(stmts, fmt.Sprintf("SET %s = %d", setting.name, ms))
+	}
+	return stmts
+}
+
+// openPool opens the pool of driverName connections to dsn, running the
+// statements of session on each connection before the pool hands it out
+func openPool(driverName, dsn string, session Session) (*sql.DB, error) {
+	setup := session.statements()
+	if len(setup) == 0 {
+		return sql.Open(driverName, dsn)
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	db.Close()
+
+	dc, ok := drv.(driver.DriverContext)
+	if !ok {
+		return nil, fmt.Errorf("driver %s can't apply session settings", driverName)
+	}
+	connector, err := dc.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&sessionConnector{Connector: connector, setup: setup}), nil
+}
+
+// sessionConnector is the pool's after-connect hook: it runs setup on every
+// new connection
+type sessionConnector struct {
+	driver.Connector
+	setup []string
+}
+
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exec, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("connection can't apply session settings")
+	}
+	for _, stmt := range c.setup {
+		if _, err := exec.ExecContext(ctx, stmt, nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to apply session settings (%s): %w", stmt, err)
+		}
+	}
+	return conn, nil
+}
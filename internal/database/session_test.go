@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSessionStatements(t *testing.T) {
+	if got := (Session{}).statements(); len(got) != 0 {
+		t.Errorf("zero session = %q, want no statements", got)
+	}
+
+	got := Session{
+		StatementTimeout:                30 * time.Second,
+		IdleInTransactionSessionTimeout: 500 * time.Microsecond,
+	}.statements()
+	want := []string{
+		"SET statement_timeout = 30000",
+		"SET idle_in_transaction_session_timeout = 1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+// dsnConnector opens connections of drv, which has no connector of its own
+type dsnConnector struct {
+	drv driver.Driver
+	dsn string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+func TestSessionConnector(t *testing.T) {
+	// SQLite has no session timeouts; a per-connection temp table shows
+	// that setup runs on every connection the pool opens
+	plain, err := sql.Open("sqlite", sqliteDSN(filepath.Join(t.TempDir(), "test.db")))
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer plain.Close()
+	connector := dsnConnector{drv: plain.Driver(), dsn: sqliteDSN(filepath.Join(t.TempDir(), "test.db"))}
+
+	db := sql.OpenDB(&sessionConnector{Connector: connector, setup: []string{"CREATE TEMP TABLE session_applied (id INTEGER)"}})
+	defer db.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("failed to get connection: %v", err)
+		}
+		defer conn.Close()
+		if _, err := conn.ExecContext(ctx, `SELECT COUNT(*) FROM session_applied`); err != nil {
+			t.Errorf("connection %d not set up: %v", i, err)
+		}
+	}
+
+	failing := sql.OpenDB(&sessionConnector{Connector: connector, setup: []string{"SET statement_timeout = 1"}})
+	defer failing.Close()
+	if err := failing.PingContext(ctx); err == nil {
+		t.Error("Ping succeeded although the session setup failed")
+	}
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Querier is the subset of *sql.DB and *sql.Tx used by repositories
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+type dryRunKey struct{}
+
+type txState struct {
+	tx          *sql.Tx
+	afterCommit []func()
+}
+
+// Conn returns the transaction carried by ctx, or the pool when there is none.
+// Repositories use it so their methods join a transaction started by WithTx.
+func (db *DB) Conn(ctx context.Context) Querier {
+	var conn Querier = db.DB
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		conn = state.tx
+	}
+	if len(db.queryTags) > 0 {
+		conn = taggingQuerier{Querier: conn, db: db}
+	}
+	if db.dialect == SQLite {
+		return sqliteQuerier{conn}
+	}
+	return conn
+}
+
+// WithTx runs fn inside a transaction that is committed if fn returns nil and
+// rolled back otherwise. Nested calls join the outer transaction. In a dry run
+// (see WithDryRun) the transaction is always rolled back.
+func (db *DB) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return db.withTx(ctx, nil, fn)
+}
+
+// WithTxOptions is WithTx with explicit isolation level / read-only options
+func (db *DB) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	return db.withTx(ctx, opts, fn)
+}
+
+// SnapshotTime takes the snapshot of the transaction ctx carries, started
+// with SnapshotTxOptions, and returns when it was taken, in UTC: everything
+// the transaction reads is as of then. Call it before the transaction's
+// first query, which would otherwise take the snapshot.
+func (db *DB) SnapshotTime(ctx context.Context) (time.Time, error) {
+	if db.Dialect() == SQLite {
+		// The first read starts the read transaction; SQLite has no
+		// sub-second clock to ask in a form that scans into a time
+		var tables int
+		if err := db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master`).Scan(&tables); err != nil {
+			return time.Time{}, fmt.Errorf("failed to take snapshot: %w", err)
+		}
+		return time.Now().UTC(), nil
+	}
+
+	// In REPEATABLE READ the first statement takes the snapshot, as it starts
+	var at time.Time
+	if err := db.Conn(ctx).QueryRowContext(ctx, `SELECT statement_timestamp()`).Scan(&at); err != nil {
+		return time.Time{}, fmt.Errorf("failed to take snapshot: %w", err)
+	}
+	return at.UTC(), nil
+}
+
+func (db *DB) withTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Everything ran, including constraint checks, but nothing is kept
+	if IsDryRun(ctx) {
+		if err := tx.Rollback(); err != nil {
+			return fmt.Errorf("failed to roll back dry run: %w", err)
+		}
+		return nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, callback := range state.afterCommit {
+		callback()
+	}
+
+	return nil
+}
+
+// AfterCommit schedules fn to run once the transaction carried by ctx has
+// committed. Outside a transaction fn runs immediately. Callbacks are dropped
+// when the transaction rolls back.
+func AfterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// InTx reports whether ctx carries a transaction started by WithTx
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*txState)
+	return ok
+}
+
+// WithDryRun marks ctx as a dry run: transactions started with it run fully
+// but are rolled back instead of committed, and their AfterCommit callbacks
+// never run. Writes made outside WithTx are not covered.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether ctx was marked with WithDryRun
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Bus is an in-process, synchronous Publisher. Handlers run in subscription
+// order on the publishing goroutine; a failing handler doesn't stop the others.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+	all      []Handler
+	logger   *slog.Logger
+}
+
+func NewBus(logger *slog.Logger) *Bus {
+	return &Bus{
+		handlers: make(map[Type][]Handler),
+		logger:   logger,
+	}
+}
+
+// Subscribe registers a handler for events of the given types
+func (b *Bus) Subscribe(handler Handler, types ...Type) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, t := range types {
+		b.handlers[t] = append(b.handlers[t], handler)
+	}
+}
+
+// SubscribeAll registers a handler for every event
+func (b *Bus) SubscribeAll(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.all = append(b.all, handler)
+}
+
+func (b *Bus) Publish(ctx context.Context, events ...Event) error {
+	var errs []error
+
+	for _, event := range events {
+		b.mu.RLock()
+		handlers := make([]Handler, 0, len(b.all)+len(b.handlers[event.Type]))
+		handlers = append(handlers, b.all...)
+		handlers = append(handlers, b.handlers[event.Type]...)
+		b.mu.RUnlock()
+
+		for _, handler := range handlers {
+			if err := handler(ctx, event); err != nil {
+				b.logger.Error("event handler failed",
+					"event_id", event.ID,
+					"event_type", event.Type,
+					"error", err,
+				)
+				errs = append(errs, fmt.Errorf("%s %s: %w", event.Type, event.ID, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,160 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type Type string
+
+const (
+	TypeProductCreated Type = "product.created"
+	TypeProductUpdated Type = "product.updated"
+	TypeProductDeleted Type = "product.deleted"
+	TypeStockAdjusted  Type = "stock.adjusted"
+	TypeStockLow       Type = "stock.low"
+	TypeLotExpiring    Type = "lot.expiring"
+
+	TypeProductVisibilityChanged Type = "product.visibility_changed"
+
+	TypePurchaseOrderSubmitted Type = "purchase_order.submitted"
+
+	TypeNoteMentioned Type = "note.mentioned"
+)
+
+// Payload is implemented by every typed event body. SchemaVersion must be
+// bumped whenever the JSON shape of the payload changes incompatibly.
+// AggregateID identifies the entity the event belongs to and is used as the
+// ordering/partition key by brokers.
+type Payload interface {
+	EventType() Type
+	SchemaVersion() int
+	AggregateID() string
+}
+
+// Event wraps a typed payload with the metadata shared by all events
+type Event struct {
+	ID         string
+	Type       Type
+	Version    int
+	OccurredAt time.Time
+	Payload    Payload
+}
+
+// New creates an event for the payload with a fresh ID and timestamp
+func New(payload Payload) Event {
+	return Event{
+		ID:         newID(),
+		Type:       payload.EventType(),
+		Version:    payload.SchemaVersion(),
+		OccurredAt: time.Now().UTC(),
+		Payload:    payload,
+	}
+}
+
+// envelope is the wire format shared by all transports
+type envelope struct {
+	ID         string          `json:"id"`
+	Type       Type            `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(e.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", e.Type, err)
+	}
+	return json.Marshal(envelope{
+		ID:         e.ID,
+		Type:       e.Type,
+		Version:    e.Version,
+		OccurredAt: e.OccurredAt,
+		Data:       data,
+	})
+}
+
+// Decode parses an event produced by MarshalJSON back into its typed payload
+func Decode(data []byte) (Event, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Event{}, fmt.Errorf("failed to decode event envelope: %w", err)
+	}
+
+	payload, err := newPayload(env.Type)
+	if err != nil {
+		return Event{}, err
+	}
+	if env.Version != payload.SchemaVersion() {
+		return Event{}, fmt.Errorf("unsupported %s schema version %d", env.Type, env.Version)
+	}
+	if err := json.Unmarshal(env.Data, payload); err != nil {
+		return Event{}, fmt.Errorf("failed to decode %s payload: %w", env.Type, err)
+	}
+
+	return Event{
+		ID:         env.ID,
+		Type:       env.Type,
+		Version:    env.Version,
+		OccurredAt: env.OccurredAt,
+		Payload:    payload,
+	}, nil
+}
+
+func newPayload(t Type) (Payload, error) {
+	switch t {
+	case TypeProductCreated:
+		return &ProductCreated{}, nil
+	case TypeProductUpdated:
+		return &ProductUpdated{}, nil
+	case TypeProductDeleted:
+		return &ProductDeleted{}, nil
+	case TypeStockAdjusted:
+		return &StockAdjusted{}, nil
+	case TypeStockLow:
+		return &StockLow{}, nil
+	case TypeLotExpiring:
+		return &LotExpiring{}, nil
+	case TypeProductVisibilityChanged:
+		return &ProductVisibilityChanged{}, nil
+	case TypePurchaseOrderSubmitted:
+		return &PurchaseOrderSubmitted{}, nil
+	case TypeNoteMentioned:
+		return &NoteMentioned{}, nil
+	default:
+		return nil, fmt.Errorf("unknown event type %q", t)
+	}
+}
+
+// Publisher delivers events to interested parties. Implementations decide
+// whether delivery is synchronous and how failures are retried.
+type Publisher interface {
+	Publish(ctx context.Context, events ...Event) error
+}
+
+// Handler processes a single event. Handlers receive the typed payload and
+// should type-switch on Event.Payload.
+type Handler func(ctx context.Context, event Event) error
+
+func productKey(id int) string {
+	return strconv.Itoa(id)
+}
+
+// newID returns a random RFC 4122 version 4 UUID
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("events: failed to generate event ID: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
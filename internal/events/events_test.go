@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func TestEvent_RoundTrip(t *testing.T) {
+	original := New(StockAdjusted{
+		ProductID: 42,
+		SKU:       "SKU-42",
+		Previous:  10,
+		Current:   7,
+		Delta:     -3,
+		Reason:    "order",
+	})
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if decoded.ID != original.ID || decoded.Type != TypeStockAdjusted || decoded.Version != 1 {
+		t.Errorf("decoded metadata = %+v, want %+v", decoded, original)
+	}
+
+	payload, ok := decoded.Payload.(*StockAdjusted)
+	if !ok {
+		t.Fatalf("Payload type = %T, want *StockAdjusted", decoded.Payload)
+	}
+	if payload.Delta != -3 || payload.SKU != "SKU-42" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	if _, err := Schema(decoded.Type, decoded.Version); err != nil {
+		t.Errorf("Schema() error = %v", err)
+	}
+}
+
+func TestDecode_RejectsUnknownVersion(t *testing.T) {
+	data := []byte(`{"id":"1","type":"product.deleted","version":99,"data":{"product_id":1}}`)
+	if _, err := Decode(data); err == nil {
+		t.Error("expected error for unsupported schema version")
+	}
+}
+
+func TestDiffProducts(t *testing.T) {
+	before := &models.Product{ID: 1, SKU: "A", Name: "Old", Quantity: 5, UnitPrice: 1.50}
+	after := &models.Product{ID: 1, SKU: "A", Name: "New", Quantity: 5, UnitPrice: 2.00}
+
+	changes := DiffProducts(before, after)
+	if len(changes) != 2 {
+		t.Fatalf("DiffProducts() = %+v, want 2 changes", changes)
+	}
+	if changes[0].Field != "name" || changes[1].Field != "unit_price" {
+		t.Errorf("changed fields = %s, %s, want name, unit_price", changes[0].Field, changes[1].Field)
+	}
+}
+
+func TestBus_Publish(t *testing.T) {
+	bus := NewBus(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var created, all int
+	bus.Subscribe(func(ctx context.Context, e Event) error {
+		created++
+		return errors.New("subscriber failed")
+	}, TypeProductCreated)
+	bus.SubscribeAll(func(ctx context.Context, e Event) error {
+		all++
+		return nil
+	})
+
+	err := bus.Publish(context.Background(),
+		New(ProductCreated{Product: models.Product{ID: 1}}),
+		New(ProductDeleted{ProductID: 1}),
+	)
+	if err == nil {
+		t.Error("expected handler error to be returned")
+	}
+	if created != 1 {
+		t.Errorf("product.created handler called %d times, want 1", created)
+	}
+	if all != 2 {
+		t.Errorf("catch-all handler called %d times, want 2", all)
+	}
+}
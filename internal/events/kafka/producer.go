@@ -0,0 +1,145 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	kafkago "github.com/segmentio/kafka-go"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// Partition key strategies
+const (
+	KeyProductID = "product_id" // All events of a product land on one partition, in order
+	KeyEventType = "event_type"
+	KeyNone      = "none" // Round-robin, no ordering guarantees
+)
+
+var (
+	producedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_produced_messages_total",
+		Help: "Messages acknowledged by Kafka, by event type.",
+	}, []string{"event_type"})
+	produceErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "kafka_produce_errors_total",
+		Help: "Failed Kafka produce batches.",
+	})
+	produceDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kafka_produce_duration_seconds",
+		Help:    "Time to produce and acknowledge a batch.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+type Config struct {
+	Brokers      []string
+	Topic        string
+	ClientID     string
+	PartitionKey string // KeyProductID, KeyEventType, or KeyNone
+	WriteTimeout time.Duration
+}
+
+// Producer is an outbox sink writing event envelopes to a Kafka topic. It
+// waits for acknowledgement from all in-sync replicas before reporting
+// success so the relay only marks durable messages as published.
+type Producer struct {
+	writer *kafkago.Writer
+	keyBy  string
+}
+
+func NewProducer(cfg Config) (*Producer, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka: at least one broker is required")
+	}
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka: topic is required")
+	}
+
+	var balancer kafkago.Balancer = &kafkago.Hash{}
+	switch cfg.PartitionKey {
+	case KeyProductID, KeyEventType, "":
+	case KeyNone:
+		balancer = &kafkago.RoundRobin{}
+	default:
+		return nil, fmt.Errorf("kafka: invalid partition key %q: must be %s, %s, or %s",
+			cfg.PartitionKey, KeyProductID, KeyEventType, KeyNone)
+	}
+
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+
+	writer := &kafkago.Writer{
+		Addr:         kafkago.TCP(cfg.Brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     balancer,
+		RequiredAcks: kafkago.RequireAll,
+		WriteTimeout: cfg.WriteTimeout,
+		BatchTimeout: 10 * time.Millisecond, // Batches come from the relay, don't linger
+	}
+	if cfg.ClientID != "" {
+		writer.Transport = &kafkago.Transport{ClientID: cfg.ClientID}
+	}
+
+	keyBy := cfg.PartitionKey
+	if keyBy == "" {
+		keyBy = KeyProductID
+	}
+
+	return &Producer{writer: writer, keyBy: keyBy}, nil
+}
+
+func (p *Producer) Name() string {
+	return "kafka"
+}
+
+func (p *Producer) Send(ctx context.Context, messages []*models.OutboxMessage) error {
+	batch := make([]kafkago.Message, len(messages))
+	for i, msg := range messages {
+		batch[i] = kafkago.Message{
+			Key:   p.key(msg),
+			Value: msg.Payload,
+			Time:  msg.CreatedAt,
+			Headers: []kafkago.Header{
+				{Key: "event_id", Value: []byte(msg.EventID)},
+				{Key: "event_type", Value: []byte(msg.EventType)},
+				{Key: "schema_version", Value: []byte(strconv.Itoa(msg.SchemaVersion))},
+				{Key: "content_type", Value: []byte("application/json")},
+			},
+		}
+	}
+
+	start := time.Now()
+	err := p.writer.WriteMessages(ctx, batch...)
+	produceDuration.Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		produceErrors.Inc()
+		return fmt.Errorf("failed to produce %d messages: %w", len(batch), err)
+	}
+
+	for _, msg := range messages {
+		producedMessages.WithLabelValues(msg.EventType).Inc()
+	}
+
+	return nil
+}
+
+func (p *Producer) key(msg *models.OutboxMessage) []byte {
+	switch p.keyBy {
+	case KeyEventType:
+		return []byte(msg.EventType)
+	case KeyNone:
+		return nil
+	default:
+		return []byte(msg.AggregateID)
+	}
+}
+
+func (p *Producer) Close() error {
+	return p.writer.Close()
+}
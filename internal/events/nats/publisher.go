@@ -0,0 +1,129 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+var (
+	publishedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nats_published_messages_total",
+		Help: "Messages acknowledged by JetStream, by event type.",
+	}, []string{"event_type"})
+	publishErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "nats_publish_errors_total",
+		Help: "Failed JetStream publishes.",
+	})
+	publishDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "nats_publish_duration_seconds",
+		Help:    "Time to publish and acknowledge a relay batch.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+type Config struct {
+	URL           string
+	Name          string // Client connection name
+	Stream        string // Stream holding product events, created if missing
+	SubjectPrefix string // Events are published to <prefix>.<event type>
+	Replicas      int
+}
+
+// Connect opens a NATS connection that keeps reconnecting forever, so a broker
+// restart doesn't require restarting the service
+func Connect(cfg Config) (*natsgo.Conn, jetstream.JetStream, error) {
+	nc, err := natsgo.Connect(cfg.URL,
+		natsgo.Name(cfg.Name),
+		natsgo.MaxReconnects(-1),
+		natsgo.ReconnectWait(2*time.Second),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	return nc, js, nil
+}
+
+// Publisher is an outbox sink publishing event envelopes to JetStream. The
+// event ID is sent as Nats-Msg-Id so redeliveries from the outbox within the
+// stream's duplicate window are dropped by the server.
+type Publisher struct {
+	nc     *natsgo.Conn
+	js     jetstream.JetStream
+	prefix string
+}
+
+// NewPublisher connects to NATS and makes sure the event stream exists
+func NewPublisher(ctx context.Context, cfg Config) (*Publisher, error) {
+	if cfg.Stream == "" || cfg.SubjectPrefix == "" {
+		return nil, fmt.Errorf("nats: stream and subject prefix are required")
+	}
+	if cfg.Replicas < 1 {
+		cfg.Replicas = 1
+	}
+
+	nc, js, err := Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       cfg.Stream,
+		Subjects:   []string{cfg.SubjectPrefix + ".>"},
+		Storage:    jetstream.FileStorage,
+		Replicas:   cfg.Replicas,
+		Duplicates: 10 * time.Minute,
+	})
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to create stream %s: %w", cfg.Stream, err)
+	}
+
+	return &Publisher{nc: nc, js: js, prefix: cfg.SubjectPrefix}, nil
+}
+
+func (p *Publisher) Name() string {
+	return "nats"
+}
+
+// Send publishes messages one by one, waiting for each acknowledgement so the
+// per-product ordering of the outbox is preserved in the stream
+func (p *Publisher) Send(ctx context.Context, messages []*models.OutboxMessage) error {
+	start := time.Now()
+	defer func() { publishDuration.Observe(time.Since(start).Seconds()) }()
+
+	for _, msg := range messages {
+		m := natsgo.NewMsg(p.prefix + "." + msg.EventType)
+		m.Data = msg.Payload
+		m.Header.Set("Event-Type", msg.EventType)
+		m.Header.Set("Schema-Version", strconv.Itoa(msg.SchemaVersion))
+		m.Header.Set("Aggregate-Id", msg.AggregateID)
+		m.Header.Set("Content-Type", "application/json")
+
+		if _, err := p.js.PublishMsg(ctx, m, jetstream.WithMsgID(msg.EventID)); err != nil {
+			publishErrors.Inc()
+			return fmt.Errorf("failed to publish event %s: %w", msg.EventID, err)
+		}
+		publishedMessages.WithLabelValues(msg.EventType).Inc()
+	}
+
+	return nil
+}
+
+func (p *Publisher) Close() error {
+	return p.nc.Drain()
+}
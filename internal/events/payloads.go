@@ -0,0 +1,218 @@
+package events
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// ProductCreated is emitted after a product has been stored
+type ProductCreated struct {
+	Product models.Product `json:"product"`
+}
+
+func (ProductCreated) EventType() Type       { return TypeProductCreated }
+func (ProductCreated) SchemaVersion() int    { return 1 }
+func (e ProductCreated) AggregateID() string { return productKey(e.Product.ID) }
+
+// ProductUpdated is emitted after a product has changed. Changes lists only
+// the fields that differ from the previous state.
+type ProductUpdated struct {
+	Product models.Product `json:"product"`
+	Changes []FieldChange  `json:"changes"`
+}
+
+func (ProductUpdated) EventType() Type       { return TypeProductUpdated }
+func (ProductUpdated) SchemaVersion() int    { return 1 }
+func (e ProductUpdated) AggregateID() string { return productKey(e.Product.ID) }
+
+// ProductDeleted is emitted after a product has been removed
+type ProductDeleted struct {
+	ProductID int    `json:"product_id"`
+	SKU       string `json:"sku,omitempty"`
+}
+
+func (ProductDeleted) EventType() Type       { return TypeProductDeleted }
+func (ProductDeleted) SchemaVersion() int    { return 1 }
+func (e ProductDeleted) AggregateID() string { return productKey(e.ProductID) }
+
+// ProductVisibilityChanged is emitted once a product enters or leaves its
+// visibility window, by the product-visibility job rather than the write
+// that set the window. Product has Listed set to Visible.
+type ProductVisibilityChanged struct {
+	Product models.Product `json:"product"`
+	Visible bool           `json:"visible"`
+}
+
+func (ProductVisibilityChanged) EventType() Type       { return TypeProductVisibilityChanged }
+func (ProductVisibilityChanged) SchemaVersion() int    { return 1 }
+func (e ProductVisibilityChanged) AggregateID() string { return productKey(e.Product.ID) }
+
+// StockAdjusted is emitted whenever a product's quantity changes
+type StockAdjusted struct {
+	ProductID int    `json:"product_id"`
+	SKU       string `json:"sku"`
+	Previous  int    `json:"previous"`
+	Current   int    `json:"current"`
+	Delta     int    `json:"delta"`
+	Reason    string `json:"reason"` // e.g. "manual_update", "order", "return"
+}
+
+func (StockAdjusted) EventType() Type       { return TypeStockAdjusted }
+func (StockAdjusted) SchemaVersion() int    { return 1 }
+func (e StockAdjusted) AggregateID() string { return productKey(e.ProductID) }
+
+// StockLow is emitted when a product's quantity drops to or below the low
+// stock threshold. It fires once when the threshold is crossed, not on every
+// change while stock stays low.
+type StockLow struct {
+	ProductID int    `json:"product_id"`
+	SKU       string `json:"sku"`
+	Quantity  int    `json:"quantity"`
+	Threshold int    `json:"threshold"`
+}
+
+func (StockLow) EventType() Type       { return TypeStockLow }
+func (StockLow) SchemaVersion() int    { return 1 }
+func (e StockLow) AggregateID() string { return productKey(e.ProductID) }
+
+// LotExpiring is emitted once per lot of a product, when the lot, with
+// stock left, comes within the expiry warning of its expiry or is found
+// already past it
+type LotExpiring struct {
+	ProductID int       `json:"product_id"`
+	SKU       string    `json:"sku"`
+	LotID     int       `json:"lot_id"`
+	LotNumber string    `json:"lot_number"`
+	Quantity  int       `json:"quantity"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Expired   bool      `json:"expired"`
+}
+
+func (LotExpiring) EventType() Type       { return TypeLotExpiring }
+func (LotExpiring) SchemaVersion() int    { return 1 }
+func (e LotExpiring) AggregateID() string { return productKey(e.ProductID) }
+
+// PurchaseOrderSubmitted is emitted when an approved purchase order is
+// submitted, for delivery to its supplier
+type PurchaseOrderSubmitted struct {
+	PurchaseOrderID int              `json:"purchase_order_id"`
+	Supplier        string           `json:"supplier"`
+	Lines           []OrderedProduct `json:"lines"`
+}
+
+// OrderedProduct is a line of a purchase order
+type OrderedProduct struct {
+	ProductID int    `json:"product_id"`
+	SKU       string `json:"sku"`
+	Quantity  int    `json:"quantity"`
+}
+
+func (PurchaseOrderSubmitted) EventType() Type    { return TypePurchaseOrderSubmitted }
+func (PurchaseOrderSubmitted) SchemaVersion() int { return 1 }
+func (e PurchaseOrderSubmitted) AggregateID() string {
+	return "purchase_order:" + strconv.Itoa(e.PurchaseOrderID)
+}
+
+// NoteMentioned is emitted when a product note is written mentioning
+// handles, once per write: Mentioned holds those the note didn't mention
+// before, other than its author
+type NoteMentioned struct {
+	NoteID    int      `json:"note_id"`
+	ProductID int      `json:"product_id"`
+	SKU       string   `json:"sku"`
+	Author    string   `json:"author"`
+	Body      string   `json:"body"`
+	Mentioned []string `json:"mentioned"`
+}
+
+func (NoteMentioned) EventType() Type       { return TypeNoteMentioned }
+func (NoteMentioned) SchemaVersion() int    { return 1 }
+func (e NoteMentioned) AggregateID() string { return productKey(e.ProductID) }
+
+// ProductUpdates builds the events for a product update: always
+// ProductUpdated, plus StockAdjusted with the given reason when the quantity
+// changed
+func ProductUpdates(before, after *models.Product, reason string) []Event {
+	evts := []Event{New(ProductUpdated{
+		Product: *after,
+		Changes: DiffProducts(before, after),
+	})}
+
+	if after.Quantity != before.Quantity {
+		evts = append(evts, New(StockAdjusted{
+			ProductID: after.ID,
+			SKU:       after.SKU,
+			Previous:  before.Quantity,
+			Current:   after.Quantity,
+			Delta:     after.Quantity - before.Quantity,
+			Reason:    reason,
+		}))
+	}
+
+	return evts
+}
+
+// FieldChange records the old and new value of a single field, keyed by its
+// JSON name
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// timestampFields change on every write and are left out of diffs
+var timestampFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// DiffProducts returns the fields that differ between two product states
+func DiffProducts(before, after *models.Product) []FieldChange {
+	changes := []FieldChange{}
+
+	bv := reflect.ValueOf(before).Elem()
+	av := reflect.ValueOf(after).Elem()
+	t := bv.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || timestampFields[name] {
+			continue
+		}
+
+		oldValue := bv.Field(i).Interface()
+		newValue := av.Field(i).Interface()
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, FieldChange{Field: name, Old: oldValue, New: newValue})
+		}
+	}
+
+	return changes
+}
+
+// ProductFields are the JSON names of the fields DiffProducts compares,
+// except the ID, which never changes
+var ProductFields = productFields()
+
+// AlertFields are the fields subscriptions can watch besides ProductFields,
+// notified of by alerts rather than product changes: "expiry" is a lot of the
+// product coming close to its expiry, as reported by LotExpiring, and
+// "mentions" a note on the product mentioning someone, by NoteMentioned
+var AlertFields = []string{"expiry", "mentions"}
+
+func productFields() []string {
+	var names []string
+	t := reflect.TypeOf(models.Product{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" && name != "id" && !timestampFields[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,21 @@
+package events
+
+import (
+	"embed"
+	"fmt"
+)
+
+// JSON Schema documents for each payload version, named <type>.v<version>.json
+//
+//go:embed schemas/*.json
+var schemas embed.FS
+
+// Schema returns the JSON Schema describing a payload version, for publishing
+// to schema registries or external consumers
+func Schema(t Type, version int) ([]byte, error) {
+	data, err := schemas.ReadFile(fmt.Sprintf("schemas/%s.v%d.json", t, version))
+	if err != nil {
+		return nil, fmt.Errorf("no schema for %s version %d", t, version)
+	}
+	return data, nil
+}
@@ -0,0 +1,209 @@
+// Package export writes the product catalog to a blob store as gzip-compressed
+// CSV or Parquet, on a schedule or when an admin asks for it.
+//
+// Objects are partitioned by the UTC date of the export:
+//
+//	{prefix}/dt=2026-10-14/products-20261014T020000Z-12.csv.gz
+//
+// where 12 is the ID of the run in export_runs, which records every export
+// and its outcome.
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"{{MODULE_NAME}}/internal/blob"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// ErrRunning is returned when an export is started while another one runs
+var ErrRunning = errors.New("an export is already running")
+
+// ErrUnknownFormat is returned for a format other than csv or parquet
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Config sets where exports go and what scheduled exports contain
+type Config struct {
+	Prefix  string              // Key prefix, e.g. exports/products
+	Format  string              // Of scheduled exports
+	Filter  models.ExportFilter // Of scheduled exports
+	Timeout time.Duration       // Bounds an ad-hoc export; the scheduler bounds scheduled ones
+}
+
+// Exporter exports the catalog, one export at a time
+type Exporter struct {
+	repo   repository.ExportRepository
+	store  blob.Store
+	cfg    Config
+	logger *slog.Logger
+	now    func() time.Time
+
+	running atomic.Bool
+}
+
+func New(repo repository.ExportRepository, store blob.Store, cfg Config, logger *slog.Logger) *Exporter {
+	return &Exporter{repo: repo, store: store, cfg: cfg, logger: logger, now: time.Now}
+}
+
+// ValidFormat reports whether format is one exports can be written in
+func ValidFormat(format string) bool {
+	_, ok := formats[format]
+	return ok
+}
+
+// Start fails the runs a restart interrupted
+func (e *Exporter) Start(ctx context.Context) error {
+	failed, err := e.repo.FailInterrupted(ctx)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		e.logger.Warn("failed exports interrupted by a restart", "count", failed)
+	}
+	return nil
+}
+
+// Run exports the catalog with the configured format and filter, as the
+// scheduled job
+func (e *Exporter) Run(ctx context.Context) error {
+	run, err := e.begin(ctx, models.ExportScheduled, e.cfg.Format, e.cfg.Filter)
+	if err != nil {
+		return err
+	}
+	return e.export(ctx, run, nil)
+}
+
+// Begin claims the exporter for an ad-hoc export and records its run, which
+// Export then writes, or Abort gives up. The format defaults to the
+// configured one.
+func (e *Exporter) Begin(ctx context.Context, format string, filter models.ExportFilter) (*models.ExportRun, error) {
+	if format == "" {
+		format = e.cfg.Format
+	}
+	return e.begin(ctx, models.ExportManual, format, filter)
+}
+
+// Export writes an ad-hoc export Begin recorded, within the configured
+// timeout, telling progress, if any, how many of how many products it has
+// written. The outcome is recorded on the run, too.
+func (e *Exporter) Export(ctx context.Context, run *models.ExportRun, progress func(done, total int64)) error {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+	return e.export(ctx, run, progress)
+}
+
+// Abort records an ad-hoc export Begin recorded as failed with err, without
+// writing it, when it can't be started after all
+func (e *Exporter) Abort(ctx context.Context, run *models.ExportRun, err error) {
+	defer e.running.Store(false)
+
+	msg := err.Error()
+	run.Status, run.Error = models.ExportFailed, &msg
+	if finishErr := e.repo.Finish(context.WithoutCancel(ctx), run); finishErr != nil {
+		e.logger.Error("failed to record export run", "run", run.ID, "error", finishErr)
+	}
+}
+
+// begin claims the exporter and records the run. The key includes the run's
+// ID, so two exports in the same second don't overwrite each other.
+func (e *Exporter) begin(ctx context.Context, triggeredBy, format string, filter models.ExportFilter) (*models.ExportRun, error) {
+	f, ok := formats[format]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, must be csv or parquet", ErrUnknownFormat, format)
+	}
+	if !e.running.CompareAndSwap(false, true) {
+		return nil, ErrRunning
+	}
+
+	run := &models.ExportRun{TriggeredBy: triggeredBy, Format: format, ExportFilter: filter}
+	if err := e.repo.Create(ctx, run); err != nil {
+		e.running.Store(false)
+		return nil, err
+	}
+
+	at := run.StartedAt.UTC()
+	name := fmt.Sprintf("products-%s-%d%s", at.Format("20060102T150405Z"), run.ID, f.extension)
+	run.Key = path.Join(e.cfg.Prefix, "dt="+at.Format("2006-01-02"), name)
+	run.URL = e.store.URL(run.Key)
+	return run, nil
+}
+
+// export writes the catalog to a temporary file, uploads it, and records the
+// outcome of the run, which begin claimed
+func (e *Exporter) export(ctx context.Context, run *models.ExportRun, progress func(done, total int64)) error {
+	defer e.running.Store(false)
+
+	start := e.now()
+	err := e.write(ctx, run, progress)
+	if err != nil {
+		msg := err.Error()
+		run.Status, run.Error = models.ExportFailed, &msg
+		e.logger.Error("catalog export failed", "run", run.ID, "key", run.Key, "error", err)
+	} else {
+		run.Status = models.ExportSucceeded
+		e.logger.Info("catalog exported", "run", run.ID, "url", run.URL, "rows", run.Rows, "bytes", run.Bytes, "duration", e.now().Sub(start).String())
+	}
+
+	// Recorded even when ctx ended the export
+	if finishErr := e.repo.Finish(context.WithoutCancel(ctx), run); finishErr != nil {
+		e.logger.Error("failed to record export run", "run", run.ID, "error", finishErr)
+		if err == nil {
+			err = finishErr
+		}
+	}
+	return err
+}
+
+func (e *Exporter) write(ctx context.Context, run *models.ExportRun, progress func(done, total int64)) error {
+	f := formats[run.Format]
+
+	// Counted apart from the snapshot, which is close enough for progress
+	total := 0
+	if progress != nil {
+		var err error
+		if total, err = e.repo.CountProducts(ctx, run.ExportFilter); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "catalog-export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := f.writer(tmp)
+	snapshotAt, err := e.repo.EachProduct(ctx, run.ExportFilter, func(p *models.Product) error {
+		run.Rows++
+		if progress != nil {
+			progress(run.Rows, int64(total))
+		}
+		return w.Write(p)
+	})
+	if err != nil {
+		return err
+	}
+	run.SnapshotAt = &snapshotAt
+	w.SetSnapshot(snapshotAt)
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to encode export: %w", err)
+	}
+
+	if run.Bytes, err = tmp.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("failed to size export: %w", err)
+	}
+	if err := e.store.Put(ctx, run.Key, tmp, run.Bytes, f.contentType); err != nil {
+		return fmt.Errorf("failed to upload export: %w", err)
+	}
+	return nil
+}
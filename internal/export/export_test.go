@@ -0,0 +1,283 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// memoryRepo serves products and records runs
+type memoryRepo struct {
+	repository.ExportRepository
+	products []*models.Product
+
+	mu   sync.Mutex
+	runs []models.ExportRun
+}
+
+// exportSnapshot is when memoryRepo's snapshot was taken
+var exportSnapshot = time.Date(2026, time.October, 14, 1, 59, 59, 0, time.UTC)
+
+func (r *memoryRepo) EachProduct(ctx context.Context, filter models.ExportFilter, fn func(*models.Product) error) (time.Time, error) {
+	for _, p := range r.products {
+		if filter.Category != "" && !strings.EqualFold(p.Category, filter.Category) {
+			continue
+		}
+		if err := fn(p); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return exportSnapshot, nil
+}
+
+func (r *memoryRepo) CountProducts(ctx context.Context, filter models.ExportFilter) (int, error) {
+	count := 0
+	_, err := r.EachProduct(ctx, filter, func(*models.Product) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryRepo) Create(ctx context.Context, run *models.ExportRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run.ID = len(r.runs) + 1
+	run.Status = models.ExportRunning
+	run.StartedAt = time.Date(2026, time.October, 14, 2, 0, 0, 0, time.UTC)
+	r.runs = append(r.runs, *run)
+	return nil
+}
+
+func (r *memoryRepo) Finish(ctx context.Context, run *models.ExportRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[run.ID-1] = *run
+	return nil
+}
+
+func (r *memoryRepo) run(id int) models.ExportRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.runs[id-1]
+}
+
+// memoryStore keeps objects in a map
+type memoryStore struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) error {
+	if s.err != nil {
+		return s.err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(body, size))
+	s.objects[key] = data
+	return err
+}
+
+func (s *memoryStore) URL(key string) string {
+	return "mem://" + key
+}
+
+var testProducts = []*models.Product{
+	{ID: 1, SKU: "BK-1", Name: "Atlas", Category: "books", Unit: "each", Quantity: 3, UnitPrice: 12.5, Tags: []string{"maps", "new"},
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), UpdatedAt: time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC)},
+	{ID: 2, SKU: "TL-1", Name: "Hammer, claw", Description: `16 "oz"`, Category: "tools", Unit: "each", Quantity: 10, UnitPrice: 20,
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), UpdatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+}
+
+func newTestExporter(repo *memoryRepo, store *memoryStore, format string) *Exporter {
+	return New(repo, store, Config{Prefix: "exports/products", Format: format, Timeout: time.Minute}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRun_CSV(t *testing.T) {
+	repo := &memoryRepo{products: testProducts}
+	store := &memoryStore{objects: map[string][]byte{}}
+	if err := newTestExporter(repo, store, models.ExportCSV).Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	run := repo.run(1)
+	key := "exports/products/dt=2026-10-14/products-20261014T020000Z-1.csv.gz"
+	if run.Status != models.ExportSucceeded || run.Key != key || run.URL != "mem://"+key || run.Rows != 2 || run.TriggeredBy != models.ExportScheduled {
+		t.Fatalf("run = %+v", run)
+	}
+	if run.SnapshotAt == nil || !run.SnapshotAt.Equal(exportSnapshot) {
+		t.Errorf("SnapshotAt = %v, want %v", run.SnapshotAt, exportSnapshot)
+	}
+	if run.Bytes != int64(len(store.objects[key])) {
+		t.Errorf("Bytes = %d, stored %d", run.Bytes, len(store.objects[key]))
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(store.objects[key]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(gz).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		csvHeader,
+		{"1", "BK-1", "Atlas", "", "books", "each", "3", "12.5", "maps|new", "2026-01-02T03:04:05Z", "2026-02-03T04:05:06Z"},
+		{"2", "TL-1", "Hammer, claw", `16 "oz"`, "tools", "each", "10", "20", "", "2026-01-02T03:04:05Z", "2026-01-02T03:04:05Z"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV = %q\nwant %q", records, want)
+	}
+}
+
+func TestRun_Parquet(t *testing.T) {
+	repo := &memoryRepo{products: testProducts}
+	store := &memoryStore{objects: map[string][]byte{}}
+	e := newTestExporter(repo, store, models.ExportParquet)
+	e.cfg.Filter = models.ExportFilter{Category: "BOOKS"}
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	data := store.objects["exports/products/dt=2026-10-14/products-20261014T020000Z-1.parquet"]
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open Parquet export: %v", err)
+	}
+	if version, _ := file.Lookup("catalog.schema_version"); version != "1" {
+		t.Errorf("catalog.schema_version = %q, want 1", version)
+	}
+	if at, _ := file.Lookup("catalog.snapshot_at"); at != "2026-10-14T01:59:59Z" {
+		t.Errorf("catalog.snapshot_at = %q, want 2026-10-14T01:59:59Z", at)
+	}
+	rows, err := parquet.Read[parquetProduct](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to read Parquet export: %v", err)
+	}
+	want := []parquetProduct{{
+		ID: 1, SKU: "BK-1", Name: "Atlas", Category: "books", Unit: "each", Quantity: 3, UnitPrice: 1250, Tags: []string{"maps", "new"},
+		CreatedAt: testProducts[0].CreatedAt.UnixMilli(), UpdatedAt: testProducts[0].UpdatedAt.UnixMilli(),
+	}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v\nwant %+v", rows, want)
+	}
+	if run := repo.run(1); run.Rows != 1 || run.Category != "BOOKS" {
+		t.Errorf("run = %+v, want 1 row filtered by category", run)
+	}
+}
+
+// TestParquetSchema pins the schema of Parquet exports, which consumers
+// depend on; see ParquetSchemaVersion before changing it
+func TestParquetSchema(t *testing.T) {
+	want := `message product {
+	required int32 id (INT(32,true)) = 1;
+	required binary sku (STRING) = 2;
+	required binary name (STRING) = 3;
+	required binary description (STRING) = 4;
+	required binary category (STRING) = 5;
+	required binary unit (STRING) = 6;
+	required int32 quantity (INT(32,true)) = 7;
+	required int64 unit_price (DECIMAL(10,2)) = 8;
+	required group tags (LIST) = 9 {
+		repeated group list {
+			required binary element (STRING);
+		}
+	}
+	required int64 created_at (TIMESTAMP(isAdjustedToUTC=true,unit=MILLIS)) = 10;
+	required int64 updated_at (TIMESTAMP(isAdjustedToUTC=true,unit=MILLIS)) = 11;
+}`
+	if got := parquetSchema.String(); got != want {
+		t.Errorf("schema =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParquetProduct_UnitPrice(t *testing.T) {
+	// 0.29 * 100 is 28.999..., so truncating would lose a cent
+	for price, want := range map[float64]int64{19.99: 1999, 0.29: 29, 0: 0} {
+		if got := newParquetProduct(&models.Product{UnitPrice: price}).UnitPrice; got != want {
+			t.Errorf("UnitPrice %v = %d cents, want %d", price, got, want)
+		}
+	}
+}
+
+func TestRun_UploadFailure(t *testing.T) {
+	repo := &memoryRepo{products: testProducts}
+	store := &memoryStore{objects: map[string][]byte{}, err: errors.New("bucket unreachable")}
+	if err := newTestExporter(repo, store, models.ExportCSV).Run(context.Background()); err == nil {
+		t.Fatal("Run succeeded with a failing store")
+	}
+
+	run := repo.run(1)
+	if run.Status != models.ExportFailed || run.Error == nil || !strings.Contains(*run.Error, "bucket unreachable") {
+		t.Errorf("run = %+v, want failed with the store's error", run)
+	}
+}
+
+func TestBegin_OneAtATime(t *testing.T) {
+	repo := &memoryRepo{products: testProducts}
+	store := &memoryStore{objects: map[string][]byte{}}
+	e := newTestExporter(repo, store, models.ExportCSV)
+
+	run, err := e.Begin(context.Background(), "", models.ExportFilter{})
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if run.Status != models.ExportRunning || run.TriggeredBy != models.ExportManual || run.Format != models.ExportCSV {
+		t.Errorf("run = %+v, want a running manual CSV export", run)
+	}
+
+	if _, err := e.Begin(context.Background(), models.ExportParquet, models.ExportFilter{}); !errors.Is(err, ErrRunning) {
+		t.Errorf("second Begin = %v, want ErrRunning", err)
+	}
+	if err := e.Run(context.Background()); !errors.Is(err, ErrRunning) {
+		t.Errorf("Run during an export = %v, want ErrRunning", err)
+	}
+
+	var progress [][2]int64
+	if err := e.Export(context.Background(), run, func(done, total int64) {
+		progress = append(progress, [2]int64{done, total})
+	}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if got := repo.run(run.ID); got.Status != models.ExportSucceeded {
+		t.Errorf("status = %s after Export, want succeeded", got.Status)
+	}
+	if want := [][2]int64{{1, 2}, {2, 2}}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+	if _, err := e.Begin(context.Background(), "xml", models.ExportFilter{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Begin(xml) = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	repo := &memoryRepo{products: testProducts}
+	e := newTestExporter(repo, &memoryStore{objects: map[string][]byte{}}, models.ExportCSV)
+
+	run, err := e.Begin(context.Background(), "", models.ExportFilter{})
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	e.Abort(context.Background(), run, errors.New("no operation"))
+	if got := repo.run(run.ID); got.Status != models.ExportFailed || got.Error == nil || *got.Error != "no operation" {
+		t.Errorf("run = %+v, want failed", got)
+	}
+	if _, err := e.Begin(context.Background(), "", models.ExportFilter{}); err != nil {
+		t.Errorf("Begin after Abort = %v, want the exporter released", err)
+	}
+}
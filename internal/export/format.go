@@ -0,0 +1,76 @@
+package export
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// csvHeader names the columns of CSV exports. Tags are joined with |, and
+// timestamps are RFC 3339 in UTC.
+var csvHeader = []string{"id", "sku", "name", "description", "category", "unit", "quantity", "unit_price", "tags", "created_at", "updated_at"}
+
+// productWriter encodes exported products
+type productWriter interface {
+	Write(p *models.Product) error
+	// SetSnapshot records when the snapshot the products were read from was
+	// taken, in formats with metadata; call it before Close
+	SetSnapshot(at time.Time)
+	Close() error // Flushes the encoding, without closing the underlying writer
+}
+
+// formats maps each export format to its file extension, content type, and
+// writer
+var formats = map[string]struct {
+	extension   string
+	contentType string
+	writer      func(w io.Writer) productWriter
+}{
+	models.ExportCSV:     {".csv.gz", "application/gzip", newCSVWriter},
+	models.ExportParquet: {".parquet", "application/vnd.apache.parquet", newParquetWriter},
+}
+
+type csvWriter struct {
+	gz  *gzip.Writer
+	csv *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) productWriter {
+	gz := gzip.NewWriter(w)
+	c := &csvWriter{gz: gz, csv: csv.NewWriter(gz)}
+	c.csv.Write(csvHeader) // Errors surface on Flush
+	return c
+}
+
+func (c *csvWriter) Write(p *models.Product) error {
+	return c.csv.Write([]string{
+		strconv.Itoa(p.ID),
+		p.SKU,
+		p.Name,
+		p.Description,
+		p.Category,
+		p.Unit,
+		strconv.Itoa(p.Quantity),
+		strconv.FormatFloat(p.UnitPrice, 'f', -1, 64),
+		strings.Join(p.Tags, "|"),
+		p.CreatedAt.UTC().Format(time.RFC3339Nano),
+		p.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// SetSnapshot does nothing: CSV has no room for metadata, and the gzip
+// header is written before the snapshot is known. The run records it.
+func (c *csvWriter) SetSnapshot(time.Time) {}
+
+func (c *csvWriter) Close() error {
+	c.csv.Flush()
+	if err := c.csv.Error(); err != nil {
+		return err
+	}
+	return c.gz.Close()
+}
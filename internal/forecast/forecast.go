@@ -0,0 +1,255 @@
+// Package forecast projects product demand from the stock movement ledger
+// and suggests reorders from the projections.
+//
+// A product's demand is the units its stock movements took out, bucketed by
+// day over the forecast window. Its forecast is the moving average of the
+// daily demand with a least-squares linear trend, projected over the
+// horizon and over the product's supplier lead time. The reorder point is
+// the demand forecast over the lead time plus the safety stock; a product at
+// or below it should be ordered up to the demand forecast over the lead time
+// and the horizon after it, plus the safety stock.
+//
+// Every decrement counts as demand: the ledger doesn't say why a quantity
+// changed, so stock take corrections and write-offs are demand too.
+package forecast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+const day = 24 * time.Hour
+
+// MaxLeadTimeDays is the longest lead time a product can have
+const MaxLeadTimeDays = 365
+
+var ErrInvalidLeadTime = errors.New("invalid lead time")
+
+var reorderSuggestions = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "inventory_reorder_suggestions",
+	Help: "Products at or below their reorder point at the last demand forecast.",
+})
+
+// Config is the forecast window, horizon, and defaults
+type Config struct {
+	Window      time.Duration // Of history the daily demand is averaged over, in whole days
+	Horizon     time.Duration // Demand is projected over, in whole days
+	LeadTime    time.Duration // Of products without their own, in whole days
+	SafetyStock int           // Units added to the reorder point and order-up-to level
+}
+
+// Fit returns the mean of daily demand, oldest day first, and its
+// least-squares trend, the change of daily demand per day
+func Fit(daily []float64) (average, trend float64) {
+	n := float64(len(daily))
+	if n == 0 {
+		return 0, 0
+	}
+	for _, d := range daily {
+		average += d
+	}
+	average /= n
+
+	mid := (n - 1) / 2
+	var num, den float64
+	for i, d := range daily {
+		x := float64(i) - mid
+		num += x * (d - average)
+		den += x * x
+	}
+	if den > 0 {
+		trend = num / den
+	}
+	return average, trend
+}
+
+// Project returns the demand over the days after a window of windowDays,
+// following the trend from the window's average, no day below zero
+func Project(average, trend float64, windowDays, days int) float64 {
+	mid := float64(windowDays-1) / 2
+	var total float64
+	for k := 1; k <= days; k++ {
+		total += math.Max(0, average+trend*(mid+float64(k)))
+	}
+	return total
+}
+
+// Service computes demand forecasts and the reorder points from them
+type Service struct {
+	repo     repository.ForecastRepository
+	products repository.ProductRepository
+	tx       repository.Transactor
+	cfg      Config
+	logger   *slog.Logger
+}
+
+func NewService(repo repository.ForecastRepository, products repository.ProductRepository, tx repository.Transactor, cfg Config, logger *slog.Logger) *Service {
+	return &Service{repo: repo, products: products, tx: tx, cfg: cfg, logger: logger}
+}
+
+// windowDays is the forecast window in whole days
+func (s *Service) windowDays() int {
+	return max(int(s.cfg.Window/day), 1)
+}
+
+// forecast computes a product's forecast at now from its demand history
+func (s *Service) forecast(history *models.DemandHistory, now time.Time) *models.Forecast {
+	windowDays := s.windowDays()
+	daily := make([]float64, windowDays)
+	demand := 0
+	for _, m := range history.Outbound {
+		i := windowDays - 1 - int(now.Sub(m.CreatedAt)/day)
+		daily[min(max(i, 0), windowDays-1)] += float64(-m.Delta)
+		demand -= m.Delta
+	}
+	average, trend := Fit(daily)
+
+	leadTimeDays := int(s.cfg.LeadTime / day)
+	if history.LeadTimeDays != nil {
+		leadTimeDays = *history.LeadTimeDays
+	}
+	horizonDays := int(s.cfg.Horizon / day)
+	leadTimeDemand := Project(average, trend, windowDays, leadTimeDays)
+
+	return &models.Forecast{
+		ProductID:          history.ProductID,
+		SKU:                history.SKU,
+		WindowDays:         windowDays,
+		Demand:             demand,
+		AverageDailyDemand: round(average),
+		Trend:              round(trend),
+		HorizonDays:        horizonDays,
+		ProjectedDemand:    round(Project(average, trend, windowDays, horizonDays)),
+		LeadTimeDays:       leadTimeDays,
+		LeadTimeDemand:     round(leadTimeDemand),
+		ReorderPoint:       ceil(leadTimeDemand) + s.cfg.SafetyStock,
+		OrderUpTo:          ceil(Project(average, trend, windowDays, leadTimeDays+horizonDays)) + s.cfg.SafetyStock,
+		ComputedAt:         now,
+	}
+}
+
+// compute computes the forecast of every product, or only productID when
+// it's not 0
+func (s *Service) compute(ctx context.Context, productID int) ([]*models.Forecast, error) {
+	now := time.Now()
+	var forecasts []*models.Forecast
+	from := now.Add(-time.Duration(s.windowDays()) * day)
+	err := s.repo.EachDemand(ctx, productID, from, now, func(history *models.DemandHistory) error {
+		forecasts = append(forecasts, s.forecast(history, now))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return forecasts, nil
+}
+
+// Product returns a product's last forecast, or forecasts it now when the
+// demand-forecast job hasn't yet
+func (s *Service) Product(ctx context.Context, productID int) (*models.Forecast, error) {
+	if _, err := s.products.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	forecast, err := s.repo.GetByProduct(ctx, productID)
+	if err == nil || err.Error() != "forecast not found" {
+		return forecast, err
+	}
+
+	forecasts, err := s.compute(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(forecasts) == 0 {
+		return nil, fmt.Errorf("product not found")
+	}
+	return forecasts[0], nil
+}
+
+// SetLeadTime sets a product's supplier lead time and recomputes its
+// forecast, returning it
+func (s *Service) SetLeadTime(ctx context.Context, productID, days int) (*models.Forecast, error) {
+	if days < 0 || days > MaxLeadTimeDays {
+		return nil, fmt.Errorf("%w: lead_time_days must be between 0 and %d", ErrInvalidLeadTime, MaxLeadTimeDays)
+	}
+	if _, err := s.products.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetLeadTime(ctx, productID, days); err != nil {
+		return nil, err
+	}
+	return s.refresh(ctx, productID)
+}
+
+// ResetLeadTime removes a product's lead time, so the default applies, and
+// recomputes its forecast, returning it
+func (s *Service) ResetLeadTime(ctx context.Context, productID int) (*models.Forecast, error) {
+	if err := s.repo.DeleteLeadTime(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.refresh(ctx, productID)
+}
+
+// refresh recomputes and stores a product's forecast
+func (s *Service) refresh(ctx context.Context, productID int) (*models.Forecast, error) {
+	forecasts, err := s.compute(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(forecasts) == 0 {
+		return nil, fmt.Errorf("product not found")
+	}
+	if err := s.repo.Upsert(ctx, forecasts[0]); err != nil {
+		return nil, err
+	}
+	return forecasts[0], nil
+}
+
+// Run recomputes and stores every product's forecast, for use as a
+// scheduled job
+func (s *Service) Run(ctx context.Context) error {
+	forecasts, err := s.compute(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	err = s.tx.WithTx(ctx, func(ctx context.Context) error {
+		for _, f := range forecasts {
+			if err := s.repo.Upsert(ctx, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	reorder, err := s.repo.CountReorder(ctx)
+	if err != nil {
+		return err
+	}
+	reorderSuggestions.Set(float64(reorder))
+
+	s.logger.Info("demand forecast computed", "products", len(forecasts), "reorder", reorder)
+	return nil
+}
+
+// round rounds to the precision forecasts are stored at
+func round(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+// ceil rounds demand up to whole units, ignoring floating-point noise
+func ceil(v float64) int {
+	return int(math.Ceil(v - 1e-9))
+}
@@ -0,0 +1,132 @@
+package forecast
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestFitAndProject(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		daily     []float64
+		days      int
+		average   float64
+		trend     float64
+		projected float64
+	}{
+		{"flat", []float64{4, 4, 4, 4}, 3, 4, 0, 12},
+		{"none", []float64{0, 0, 0}, 5, 0, 0, 0},
+		{"rising", []float64{1, 2, 3, 4}, 2, 2.5, 1, 11},     // 5 + 6
+		{"falling", []float64{6, 4, 2, 0}, 3, 3, -2, 0},      // -2, -4, -6 clamp to 0
+		{"falling to zero", []float64{3, 2, 1}, 2, 2, -1, 0}, // 0, -1
+	} {
+		average, trend := Fit(tt.daily)
+		if math.Abs(average-tt.average) > 1e-9 || math.Abs(trend-tt.trend) > 1e-9 {
+			t.Errorf("%s: Fit() = %v, %v, want %v, %v", tt.name, average, trend, tt.average, tt.trend)
+		}
+		if got := Project(average, trend, len(tt.daily), tt.days); math.Abs(got-tt.projected) > 1e-9 {
+			t.Errorf("%s: Project() = %v, want %v", tt.name, got, tt.projected)
+		}
+	}
+}
+
+func TestService_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "forecast.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	products := repository.NewProductRepository(db)
+	repo := repository.NewForecastRepository(db)
+	service := NewService(repo, products, db, Config{
+		Window:      10 * day,
+		Horizon:     10 * day,
+		LeadTime:    5 * day,
+		SafetyStock: 2,
+	}, logger)
+
+	widget := &models.Product{SKU: "FC-1", Name: "Widget", Quantity: 100, UnitPrice: 1}
+	idle := &models.Product{SKU: "FC-2", Name: "Idle", Quantity: 1, UnitPrice: 1}
+	for _, p := range []*models.Product{widget, idle} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+	// 80 units out today and 10 back in, all counted as the window's demand
+	for _, delta := range []int{-30, -50, 10} {
+		if _, err := products.AdjustStock(ctx, widget.ID, delta); err != nil {
+			t.Fatalf("AdjustStock(%d) error = %v", delta, err)
+		}
+	}
+
+	f, err := service.Product(ctx, widget.ID)
+	if err != nil {
+		t.Fatalf("Product() error = %v", err)
+	}
+	if f.Demand != 80 || f.WindowDays != 10 || f.AverageDailyDemand != 8 || f.LeadTimeDays != 5 || f.ComputedAt.IsZero() {
+		t.Errorf("Product() = %+v", f)
+	}
+	if f.Trend <= 0 || f.ReorderPoint <= 2 {
+		t.Errorf("Product() trend = %v, reorder point = %d", f.Trend, f.ReorderPoint)
+	}
+	if _, err := service.Product(ctx, 999); err == nil || err.Error() != "product not found" {
+		t.Errorf("Product(999) error = %v, want product not found", err)
+	}
+
+	if err := service.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	stored, err := repo.GetByProduct(ctx, idle.ID)
+	if err != nil {
+		t.Fatalf("GetByProduct() error = %v", err)
+	}
+	if stored.Demand != 0 || stored.ReorderPoint != 2 || stored.OrderUpTo != 2 {
+		t.Errorf("idle forecast = %+v", stored)
+	}
+
+	suggestions, err := repo.ListReorder(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListReorder() error = %v", err)
+	}
+	if len(suggestions) != 2 || suggestions[0].ProductID != widget.ID || suggestions[0].SuggestedQuantity != suggestions[0].OrderUpTo-30 {
+		t.Errorf("ListReorder() = %+v", suggestions)
+	}
+	if n, err := repo.CountReorder(ctx); err != nil || n != 2 {
+		t.Errorf("CountReorder() = %d, %v, want 2", n, err)
+	}
+
+	if _, err := service.SetLeadTime(ctx, widget.ID, -1); !errors.Is(err, ErrInvalidLeadTime) {
+		t.Errorf("SetLeadTime(-1) error = %v, want ErrInvalidLeadTime", err)
+	}
+	f, err = service.SetLeadTime(ctx, widget.ID, 0)
+	if err != nil {
+		t.Fatalf("SetLeadTime() error = %v", err)
+	}
+	if f.LeadTimeDays != 0 || f.LeadTimeDemand != 0 || f.ReorderPoint != 2 {
+		t.Errorf("SetLeadTime(0) = %+v", f)
+	}
+	if n, _ := repo.CountReorder(ctx); n != 1 {
+		t.Errorf("CountReorder() after SetLeadTime = %d, want 1", n)
+	}
+	if f, err = service.ResetLeadTime(ctx, widget.ID); err != nil || f.LeadTimeDays != 5 {
+		t.Errorf("ResetLeadTime() = %+v, %v", f, err)
+	}
+	if _, err := service.ResetLeadTime(ctx, widget.ID); err == nil || err.Error() != "lead time not found" {
+		t.Errorf("ResetLeadTime() twice error = %v, want lead time not found", err)
+	}
+}
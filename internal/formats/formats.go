@@ -0,0 +1,163 @@
+// Package formats renders response bodies and reads request bodies in
+// formats other than JSON, for clients that can't speak it. Responses are in
+// the format Accept prefers, requests in that of their Content-Type; JSON
+// stays the default for both.
+//
+// A Format encodes the JSON of a value, so responses keep the field names and
+// shape of their JSON, and decodes into the JSON-tagged request types of
+// handlers. Adding a format is implementing Format and registering it.
+package formats
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"{{MODULE_NAME}}/internal/jsonenc"
+)
+
+// Format encodes and decodes bodies in one media type
+type Format interface {
+	// Name and Append encode responses, the value's JSON in the format
+	jsonenc.Encoder
+
+	// MediaTypes names the format in Accept and Content-Type; the first is
+	// the Content-Type of responses
+	MediaTypes() []string
+
+	// NewDecoder returns a decoder of a request body in the format, into
+	// the values encoding/json would decode its JSON into
+	NewDecoder(r io.Reader) Decoder
+}
+
+// RequestFormat is implemented by formats whose responses depend on the
+// request, like the links of JSON:API; ForRequest returns the format of the
+// response to r
+type RequestFormat interface {
+	Format
+	ForRequest(r *http.Request) Format
+}
+
+// Decoder decodes request bodies, like json.Decoder
+type Decoder interface {
+	Decode(v interface{}) error
+
+	// DisallowUnknownFields fails decoding of fields the value doesn't have
+	DisallowUnknownFields()
+}
+
+var (
+	mu     sync.RWMutex
+	byType = make(map[string]Format)
+)
+
+func init() {
+	Register(XML{})
+	Register(MsgPack{})
+	Register(JSONAPI{})
+}
+
+// Register makes f available to content negotiation, replacing the format
+// of any of its media types registered before
+func Register(f Format) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, mediaType := range f.MediaTypes() {
+		byType[mediaType] = f
+	}
+}
+
+// ForContentType returns the format of a request with contentType, nil for
+// JSON or a media type no format is registered for
+func ForContentType(contentType string) Format {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return byType[mediaType]
+}
+
+// Negotiate returns the format Accept prefers, nil for JSON. A format is
+// chosen when Accept names it at least as strongly as application/json;
+// wildcards don't count, so clients get JSON unless they name another format.
+func Negotiate(accept []string) Format {
+	f, _ := negotiate(accept)
+	return f
+}
+
+// NegotiateDefault returns the format Accept prefers, as Negotiate does,
+// but def when Accept names neither JSON nor a registered format
+func NegotiateDefault(accept []string, def Format) Format {
+	f, named := negotiate(accept)
+	if !named {
+		return def
+	}
+	return f
+}
+
+// negotiate returns the format Accept prefers and whether it names JSON or
+// a registered format at all
+func negotiate(accept []string) (Format, bool) {
+	var best Format
+	var bestQ, jsonQ float64
+	named := false
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, value := range accept {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(v, 64); err != nil {
+					continue
+				}
+			}
+			if mediaType == "application/json" {
+				jsonQ = max(jsonQ, q)
+				named = named || q > 0
+			} else if f, ok := byType[mediaType]; ok {
+				named = named || q > 0
+				if q > bestQ {
+					best, bestQ = f, q
+				}
+			}
+		}
+	}
+	if best == nil || bestQ <= 0 || bestQ < jsonQ {
+		return nil, named
+	}
+	return best, named
+}
+
+// NewDecoder returns a decoder of body in the format of contentType: a
+// registered one, or JSON
+func NewDecoder(body io.Reader, contentType string) Decoder {
+	if f := ForContentType(contentType); f != nil {
+		return f.NewDecoder(body)
+	}
+	return json.NewDecoder(body)
+}
+
+type contextKey struct{}
+
+// WithFormat returns ctx carrying the format negotiated for its response,
+// nil for JSON
+func WithFormat(ctx context.Context, f Format) context.Context {
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+// FromContext returns the format negotiated for the response, nil for JSON
+func FromContext(ctx context.Context) Format {
+	f, _ := ctx.Value(contextKey{}).(Format)
+	return f
+}
@@ -0,0 +1,174 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+	}{
+		{"", nil},
+		{"*/*", nil},
+		{"application/json", nil},
+		{"application/xml", XML{}},
+		{"text/xml", XML{}},
+		{"application/json, application/xml", XML{}},
+		{"application/xml;q=0.5, application/json", nil},
+		{"application/json;q=0.5, application/xml;q=0.9", XML{}},
+		{"application/xml;q=0", nil},
+		{"application/msword, text/html", nil},
+		{"application/x-msgpack", MsgPack{}},
+		{"application/vnd.api+json", JSONAPI{}},
+		{"application/msgpack, application/xml;q=0.9", MsgPack{}},
+	}
+	for _, tt := range tests {
+		var accept []string
+		if tt.accept != "" {
+			accept = []string{tt.accept}
+		}
+		if got := Negotiate(accept); got != tt.want {
+			t.Errorf("Negotiate(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateDefault(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+	}{
+		{"", JSONAPI{}},
+		{"*/*", JSONAPI{}},
+		{"text/html", JSONAPI{}},
+		{"application/json", nil},
+		{"application/xml", XML{}},
+		{"application/json;q=0", JSONAPI{}},
+	}
+	for _, tt := range tests {
+		if got := NegotiateDefault([]string{tt.accept}, JSONAPI{}); got != tt.want {
+			t.Errorf("NegotiateDefault(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestXMLAppend(t *testing.T) {
+	body := map[string]interface{}{
+		"code": 200,
+		"data": map[string]interface{}{
+			"id":         42,
+			"name":       "Bolts <M6> & nuts",
+			"tags":       []string{"a", "b"},
+			"retired_at": nil,
+			"by region":  map[string]int{"eu-west": 3},
+			"active":     true,
+		},
+	}
+
+	got, err := XML{}.Append(nil, body)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<response><code>200</code><data><active>true</active><entry key="by region"><eu-west>3</eu-west></entry>` +
+		`<id>42</id><name>Bolts &lt;M6&gt; &amp; nuts</name><retired_at nil="true"></retired_at>` +
+		`<tags><item>a</item><item>b</item></tags></data></response>` + "\n"
+	if string(got) != want {
+		t.Errorf("Append =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestXMLDecode(t *testing.T) {
+	var req models.NoteRequest
+	dec := XML{}.NewDecoder(strings.NewReader(`<note><body>Shelf B7 @dana</body><pinned>true</pinned></note>`))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if req.Body == nil || *req.Body != "Shelf B7 @dana" || req.Pinned == nil || !*req.Pinned {
+		t.Errorf("Decode = %+v", req)
+	}
+
+	// Lists as items or as repeated elements, nil and typed fields
+	var v struct {
+		IDs    []int             `json:"ids"`
+		Tags   []string          `json:"tags"`
+		Price  *float64          `json:"price"`
+		Retire time.Time         `json:"retire"`
+		Meta   map[string]string `json:"meta"`
+		Extra  interface{}       `json:"extra"`
+		Embedded
+	}
+	input := `<request>
+		<ids><item>1</item><item>2</item></ids>
+		<tags>a</tags><tags>b</tags>
+		<price nil="true"/>
+		<retire>2024-05-01T12:00:00Z</retire>
+		<meta><entry key="lot no">L-1</entry></meta>
+		<extra><n>1</n><flags><item>true</item></flags></extra>
+		<sku>BOLT-1</sku>
+	</request>`
+	if err := (XML{}).NewDecoder(strings.NewReader(input)).Decode(&v); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	extra, _ := json.Marshal(v.Extra)
+	if len(v.IDs) != 2 || v.IDs[1] != 2 || strings.Join(v.Tags, ",") != "a,b" || v.Price != nil ||
+		!v.Retire.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) || v.Meta["lot no"] != "L-1" ||
+		string(extra) != `{"flags":[true],"n":1}` || v.SKU != "BOLT-1" {
+		t.Errorf("Decode = %+v", v)
+	}
+}
+
+type Embedded struct {
+	SKU string `json:"sku"`
+}
+
+func TestXMLDecodeErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown field": `<note><body>x</body><color>red</color></note>`,
+		"wrong type":    `<note><pinned>maybe</pinned></note>`,
+		"invalid XML":   `<note><body>x</note>`,
+		"empty":         ``,
+	}
+	for name, input := range tests {
+		var req models.NoteRequest
+		dec := XML{}.NewDecoder(strings.NewReader(input))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err == nil {
+			t.Errorf("%s: Decode = %+v, want an error", name, req)
+		}
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	if _, ok := NewDecoder(strings.NewReader(""), "application/xml; charset=utf-8").(*xmlDecoder); !ok {
+		t.Error("application/xml isn't decoded as XML")
+	}
+	for _, contentType := range []string{"", "application/json", "text/plain", "invalid;;"} {
+		if _, ok := NewDecoder(strings.NewReader(""), contentType).(*json.Decoder); !ok {
+			t.Errorf("%q isn't decoded as JSON", contentType)
+		}
+	}
+}
+
+// FuzzNewDecoder decodes arbitrary bodies as each content type a request can
+// declare, into a request type and into an untyped value: decoding must fail
+// or succeed, never panic or hang. Seeds are in testdata/fuzz/FuzzNewDecoder.
+func FuzzNewDecoder(f *testing.F) {
+	f.Fuzz(func(t *testing.T, contentType string, body []byte) {
+		var product models.Product
+		dec := NewDecoder(bytes.NewReader(body), contentType)
+		dec.DisallowUnknownFields()
+		dec.Decode(&product)
+
+		var v interface{}
+		NewDecoder(bytes.NewReader(body), contentType).Decode(&v)
+	})
+}
@@ -0,0 +1,370 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"{{MODULE_NAME}}/internal/jsonenc"
+)
+
+// JSONAPI renders the standard envelopes as JSON:API 1.1 documents, for
+// clients like Ember Data:
+//
+//	{"status":"success","code":200,"data":{"id":42,"sku":"BOLT-M6","region_id":3},"pagination":{...}}
+//
+//	{"jsonapi":{"version":"1.1"},"data":{"type":"products","id":"42","attributes":{"sku":"BOLT-M6"},
+//	 "relationships":{"region":{"data":{"type":"regions","id":"3"}}},"links":{"self":"/api/v1/products/42"}},
+//	 "meta":{"status":"success","code":200,"pagination":{...}},"links":{"self":...,"next":...}}
+//
+// Objects with an id are resource objects typed by the route, the last of
+// its literal segments, and their *_id fields are relationships. Other data
+// goes in meta, as do the envelope's status, message, and pagination, from
+// which the document's pagination links are made. Errors are error objects.
+//
+// Request bodies are resource objects: their attributes and relationships
+// are read as the fields of the JSON body they stand for.
+type JSONAPI struct {
+	request *http.Request // Of the response, for types and links
+}
+
+// JSONAPIVersion is the version of JSON:API documents
+const JSONAPIVersion = "1.1"
+
+func (JSONAPI) Name() string { return "jsonapi" }
+
+func (JSONAPI) MediaTypes() []string { return []string{"application/vnd.api+json"} }
+
+// ForRequest returns the format writing the response to r
+func (JSONAPI) ForRequest(r *http.Request) Format {
+	return JSONAPI{request: r}
+}
+
+func (f JSONAPI) Append(dst []byte, v interface{}) ([]byte, error) {
+	data, err := jsonenc.Current().Append(nil, v)
+	if err != nil {
+		return dst, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var body interface{}
+	if err := dec.Decode(&body); err != nil {
+		return dst, err
+	}
+
+	doc := map[string]interface{}{"jsonapi": map[string]string{"version": JSONAPIVersion}}
+	envelope, ok := body.(map[string]interface{})
+	if _, hasStatus := envelope["status"]; !ok || !hasStatus {
+		doc["meta"] = map[string]interface{}{"data": body}
+		return jsonenc.Current().Append(dst, doc)
+	}
+
+	meta := make(map[string]interface{})
+	for key, value := range envelope {
+		if key != "data" {
+			meta[key] = value
+		}
+	}
+	doc["meta"] = meta
+
+	if envelope["status"] == "error" {
+		code, _ := envelope["code"].(json.Number)
+		status, _ := strconv.Atoi(code.String())
+		errObj := map[string]interface{}{"status": code.String(), "title": http.StatusText(status)}
+		if message, ok := envelope["message"].(string); ok && message != "" {
+			errObj["detail"] = message
+		}
+		doc["errors"] = []interface{}{errObj}
+		delete(meta, "message")
+		return jsonenc.Current().Append(dst, doc)
+	}
+
+	links := map[string]interface{}{}
+	if f.request != nil {
+		links["self"] = f.request.URL.RequestURI()
+		if pagination, ok := envelope["pagination"].(map[string]interface{}); ok {
+			f.pageLinks(links, pagination)
+		}
+	}
+	doc["links"] = links
+
+	switch data := envelope["data"].(type) {
+	case nil:
+		doc["data"] = nil
+	case map[string]interface{}:
+		if resource, ok := f.resource(data, f.resourceType(), true); ok {
+			doc["data"] = resource
+		} else {
+			meta["data"] = data
+		}
+	case []interface{}:
+		typ := f.resourceType()
+		resources := make([]interface{}, 0, len(data))
+		for _, item := range data {
+			obj, _ := item.(map[string]interface{})
+			resource, ok := f.resource(obj, typ, false)
+			if !ok {
+				resources = nil
+				break
+			}
+			resources = append(resources, resource)
+		}
+		if resources != nil {
+			doc["data"] = resources
+		} else {
+			meta["data"] = data
+		}
+	default:
+		meta["data"] = data
+	}
+	return jsonenc.Current().Append(dst, doc)
+}
+
+// resource returns obj as a resource object of type typ, not ok when it
+// has no id. Keys JSON:API reserves go in the resource's meta.
+func (f JSONAPI) resource(obj map[string]interface{}, typ string, single bool) (map[string]interface{}, bool) {
+	id, ok := obj["id"]
+	if !ok || id == nil {
+		return nil, false
+	}
+
+	attributes := make(map[string]interface{})
+	relationships := make(map[string]interface{})
+	meta := make(map[string]interface{})
+	for key, value := range obj {
+		switch {
+		case key == "id":
+		case key == "type" || key == "links" || key == "relationships" || key == "meta":
+			meta[key] = value
+		case strings.HasSuffix(key, "_id") && len(key) > len("_id") && isIdentifier(value):
+			name := strings.TrimSuffix(key, "_id")
+			var linkage interface{}
+			if value != nil {
+				linkage = map[string]interface{}{"type": plural(name), "id": fmt.Sprint(value)}
+			}
+			relationships[name] = map[string]interface{}{"data": linkage}
+		default:
+			attributes[key] = value
+		}
+	}
+
+	resource := map[string]interface{}{"type": typ, "id": fmt.Sprint(id), "attributes": attributes}
+	if len(relationships) > 0 {
+		resource["relationships"] = relationships
+	}
+	if len(meta) > 0 {
+		resource["meta"] = meta
+	}
+	if single && f.request != nil && f.routeEndsInParam() {
+		resource["links"] = map[string]string{"self": f.request.URL.Path}
+	}
+	return resource, true
+}
+
+// pageLinks adds the first, prev, next, and last links of an offset
+// paginated list
+func (f JSONAPI) pageLinks(links map[string]interface{}, pagination map[string]interface{}) {
+	number := func(key string) int {
+		n, _ := pagination[key].(json.Number)
+		i, _ := strconv.Atoi(n.String())
+		return i
+	}
+	limit, offset, total := number("limit"), number("offset"), number("total")
+	if limit <= 0 {
+		return
+	}
+
+	link := func(offset int) string {
+		query := f.request.URL.Query()
+		query.Set("limit", strconv.Itoa(limit))
+		query.Set("offset", strconv.Itoa(offset))
+		return (&url.URL{Path: f.request.URL.Path, RawQuery: query.Encode()}).RequestURI()
+	}
+	last := 0
+	if total > 0 {
+		last = (total - 1) / limit * limit
+	}
+	links["first"] = link(0)
+	links["last"] = link(last)
+	links["prev"], links["next"] = nil, nil
+	if offset > 0 {
+		links["prev"] = link(max(offset-limit, 0))
+	}
+	if offset+limit < total {
+		links["next"] = link(offset + limit)
+	}
+}
+
+// resourceType returns the type of the resources of the route: the last of
+// its literal segments, such as "notes" of /api/v1/products/{id}/notes
+func (f JSONAPI) resourceType() string {
+	segments := f.routeSegments()
+	for i := len(segments) - 1; i >= 0; i-- {
+		if s := segments[i]; s != "" && !strings.HasPrefix(s, "{") && s != "*" {
+			return s
+		}
+	}
+	return "resources"
+}
+
+func (f JSONAPI) routeEndsInParam() bool {
+	segments := f.routeSegments()
+	return len(segments) > 0 && strings.HasPrefix(segments[len(segments)-1], "{")
+}
+
+// routeSegments returns the segments of the route pattern, none when the
+// request isn't routed
+func (f JSONAPI) routeSegments() []string {
+	if f.request == nil {
+		return nil
+	}
+	rctx := chi.RouteContext(f.request.Context())
+	if rctx == nil || rctx.RoutePattern() == "" {
+		return nil
+	}
+	return strings.Split(strings.Trim(rctx.RoutePattern(), "/"), "/")
+}
+
+// isIdentifier reports whether v can be the ID of a related resource
+func isIdentifier(v interface{}) bool {
+	switch v.(type) {
+	case nil, string, json.Number:
+		return true
+	}
+	return false
+}
+
+var pluralSuffix = regexp.MustCompile(`([^aeiou])y$|(s|x|z|ch|sh)$`)
+
+// plural returns the type of resources named name, such as "regions" of
+// "region" or "categories" of "category"
+func plural(name string) string {
+	name = strings.ReplaceAll(name, "_", "-")
+	if m := pluralSuffix.FindStringSubmatch(name); m != nil {
+		if m[1] != "" {
+			return strings.TrimSuffix(name, "y") + "ies"
+		}
+		return name + "es"
+	}
+	return name + "s"
+}
+
+func (JSONAPI) NewDecoder(r io.Reader) Decoder {
+	return &jsonAPIDecoder{r: r}
+}
+
+type jsonAPIDecoder struct {
+	r      io.Reader
+	strict bool
+}
+
+func (d *jsonAPIDecoder) DisallowUnknownFields() {
+	d.strict = true
+}
+
+// jsonAPIResource is a resource object of a request body
+type jsonAPIResource struct {
+	Type          string                     `json:"type"`
+	ID            string                     `json:"id,omitempty"`
+	Attributes    map[string]json.RawMessage `json:"attributes"`
+	Relationships map[string]struct {
+		Data *struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+		Meta  json.RawMessage `json:"meta,omitempty"`
+		Links json.RawMessage `json:"links,omitempty"`
+	} `json:"relationships"`
+	Meta  json.RawMessage `json:"meta,omitempty"`
+	Links json.RawMessage `json:"links,omitempty"`
+}
+
+var errNoPrimaryData = errors.New("JSON:API document has no data")
+
+// Decode reads the primary data of the document as the JSON of its
+// attributes, with to-one relationships as *_id fields, which encoding/json
+// then decodes. A list of resources is read as a list of their JSON.
+func (d *jsonAPIDecoder) Decode(v interface{}) error {
+	var doc struct {
+		Data    json.RawMessage `json:"data"`
+		Meta    json.RawMessage `json:"meta"`
+		JSONAPI json.RawMessage `json:"jsonapi"`
+		Links   json.RawMessage `json:"links"`
+	}
+	dec := json.NewDecoder(d.r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	data := bytes.TrimSpace(doc.Data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return errNoPrimaryData
+	}
+
+	var body []byte
+	var err error
+	if data[0] == '[' {
+		var resources []jsonAPIResource
+		if err := strictDecode(data, &resources); err != nil {
+			return err
+		}
+		flat := make([]map[string]json.RawMessage, len(resources))
+		for i, resource := range resources {
+			flat[i] = resource.flatten()
+		}
+		body, err = json.Marshal(flat)
+	} else {
+		var resource jsonAPIResource
+		if err := strictDecode(data, &resource); err != nil {
+			return err
+		}
+		body, err = json.Marshal(resource.flatten())
+	}
+	if err != nil {
+		return err
+	}
+
+	dec = json.NewDecoder(bytes.NewReader(body))
+	if d.strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
+
+// strictDecode decodes a JSON:API object, which has no other members
+func strictDecode(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// flatten returns the fields of the JSON the resource stands for: its
+// attributes and, for each to-one relationship, a *_id field with a number
+// ID as a number
+func (r jsonAPIResource) flatten() map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage, len(r.Attributes)+len(r.Relationships))
+	for key, value := range r.Attributes {
+		fields[key] = value
+	}
+	for name, rel := range r.Relationships {
+		id := json.RawMessage("null")
+		if rel.Data != nil {
+			if _, err := strconv.ParseInt(rel.Data.ID, 10, 64); err == nil {
+				id = json.RawMessage(rel.Data.ID)
+			} else {
+				id, _ = json.Marshal(rel.Data.ID)
+			}
+		}
+		fields[strings.ReplaceAll(name, "-", "_")+"_id"] = id
+	}
+	return fields
+}
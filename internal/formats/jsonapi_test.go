@@ -0,0 +1,157 @@
+package formats
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// jsonAPIDocument returns the JSON:API document of v as the response to a
+// request for path on a route of pattern
+func jsonAPIDocument(t *testing.T, pattern, path string, v interface{}) map[string]interface{} {
+	t.Helper()
+	var doc map[string]interface{}
+	r := chi.NewRouter()
+	r.Get(pattern, func(w http.ResponseWriter, r *http.Request) {
+		data, err := JSONAPI{}.ForRequest(r).Append(nil, v)
+		if err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("invalid JSON %s: %v", data, err)
+		}
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	return doc
+}
+
+func TestJSONAPIResource(t *testing.T) {
+	note := &models.ProductNote{ID: 7, ProductID: 42, Author: "dana", Body: "Shelf B7"}
+	doc := jsonAPIDocument(t, "/api/v1/products/{id}/notes/{noteID}", "/api/v1/products/42/notes/7",
+		models.NewSuccessResponse(http.StatusOK, "Note retrieved", note))
+
+	data := doc["data"].(map[string]interface{})
+	if data["type"] != "notes" || data["id"] != "7" {
+		t.Errorf("type, id = %v, %v", data["type"], data["id"])
+	}
+	attributes := data["attributes"].(map[string]interface{})
+	if attributes["body"] != "Shelf B7" || attributes["product_id"] != nil || attributes["id"] != nil {
+		t.Errorf("attributes = %v", attributes)
+	}
+	want := map[string]interface{}{"product": map[string]interface{}{"data": map[string]interface{}{"type": "products", "id": "42"}}}
+	if !reflect.DeepEqual(data["relationships"], want) {
+		t.Errorf("relationships = %v, want %v", data["relationships"], want)
+	}
+	if self := data["links"].(map[string]interface{})["self"]; self != "/api/v1/products/42/notes/7" {
+		t.Errorf("self = %v", self)
+	}
+	meta := doc["meta"].(map[string]interface{})
+	if meta["status"] != "success" || meta["message"] != "Note retrieved" {
+		t.Errorf("meta = %v", meta)
+	}
+	if doc["jsonapi"].(map[string]interface{})["version"] != JSONAPIVersion {
+		t.Errorf("jsonapi = %v", doc["jsonapi"])
+	}
+}
+
+func TestJSONAPIList(t *testing.T) {
+	products := []models.Product{{ID: 1, SKU: "A"}, {ID: 2, SKU: "B"}}
+	doc := jsonAPIDocument(t, "/api/v1/products", "/api/v1/products?limit=2&offset=2&sort=sku",
+		models.NewPaginatedResponse(http.StatusOK, "", products, &models.PaginationMeta{Limit: 2, Offset: 2, Total: 7}))
+
+	data := doc["data"].([]interface{})
+	if len(data) != 2 || data[1].(map[string]interface{})["type"] != "products" || data[1].(map[string]interface{})["id"] != "2" {
+		t.Errorf("data = %v", data)
+	}
+	if _, ok := data[0].(map[string]interface{})["links"]; ok {
+		t.Error("list items have links")
+	}
+	want := map[string]interface{}{
+		"self":  "/api/v1/products?limit=2&offset=2&sort=sku",
+		"first": "/api/v1/products?limit=2&offset=0&sort=sku",
+		"prev":  "/api/v1/products?limit=2&offset=0&sort=sku",
+		"next":  "/api/v1/products?limit=2&offset=4&sort=sku",
+		"last":  "/api/v1/products?limit=2&offset=6&sort=sku",
+	}
+	if !reflect.DeepEqual(doc["links"], want) {
+		t.Errorf("links = %v, want %v", doc["links"], want)
+	}
+
+	// Empty lists are resource lists; data without IDs is meta
+	doc = jsonAPIDocument(t, "/api/v1/products", "/api/v1/products", models.NewSuccessResponse(http.StatusOK, "", []models.Product{}))
+	if data, ok := doc["data"].([]interface{}); !ok || len(data) != 0 {
+		t.Errorf("data = %v, want []", doc["data"])
+	}
+	doc = jsonAPIDocument(t, "/api/v1/stats", "/api/v1/stats", models.NewSuccessResponse(http.StatusOK, "", map[string]int{"products": 3}))
+	if _, ok := doc["data"]; ok || doc["meta"].(map[string]interface{})["data"] == nil {
+		t.Errorf("document = %v, want the data in meta", doc)
+	}
+}
+
+func TestJSONAPIError(t *testing.T) {
+	doc := jsonAPIDocument(t, "/api/v1/products/{id}", "/api/v1/products/999", models.NewErrorResponse(http.StatusNotFound, "Product not found"))
+
+	want := []interface{}{map[string]interface{}{"status": "404", "title": "Not Found", "detail": "Product not found"}}
+	if !reflect.DeepEqual(doc["errors"], want) {
+		t.Errorf("errors = %v, want %v", doc["errors"], want)
+	}
+	if _, ok := doc["data"]; ok {
+		t.Error("error document has data")
+	}
+}
+
+func TestJSONAPIDecode(t *testing.T) {
+	var req struct {
+		SKU      string `json:"sku"`
+		Quantity int    `json:"quantity"`
+		RegionID *int   `json:"region_id"`
+		LotID    *int   `json:"lot_id"`
+	}
+	body := `{"data": {"type": "products", "attributes": {"sku": "BOLT-M6", "quantity": 5},
+		"relationships": {"region": {"data": {"type": "regions", "id": "3"}}, "lot": {"data": null}}}}`
+	dec := JSONAPI{}.NewDecoder(strings.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if req.SKU != "BOLT-M6" || req.Quantity != 5 || req.RegionID == nil || *req.RegionID != 3 || req.LotID != nil {
+		t.Errorf("Decode = %+v", req)
+	}
+
+	var list []models.NoteRequest
+	body = `{"data": [{"type": "notes", "attributes": {"body": "a"}}, {"type": "notes", "attributes": {"pinned": true}}]}`
+	if err := (JSONAPI{}).NewDecoder(strings.NewReader(body)).Decode(&list); err != nil || len(list) != 2 || *list[0].Body != "a" || !*list[1].Pinned {
+		t.Errorf("Decode = %+v, %v", list, err)
+	}
+
+	for name, body := range map[string]string{
+		"no data":          `{"meta": {}}`,
+		"unknown member":   `{"data": {"type": "notes", "attributes": {}}, "extra": 1}`,
+		"unknown field":    `{"data": {"type": "notes", "attributes": {"color": "red"}}}`,
+		"not a resource":   `{"data": {"body": "a"}}`,
+		"attribute type":   `{"data": {"type": "notes", "attributes": {"pinned": "yes"}}}`,
+		"invalid document": `{"data": `,
+	} {
+		var req models.NoteRequest
+		dec := JSONAPI{}.NewDecoder(strings.NewReader(body))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err == nil {
+			t.Errorf("%s: Decode = %+v, want an error", name, req)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	for name, want := range map[string]string{"region": "regions", "category": "categories", "day": "days", "box": "boxes", "purchase_order": "purchase-orders"} {
+		if got := plural(name); got != want {
+			t.Errorf("plural(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// MsgPack renders the JSON of a value as MessagePack, for services that want
+// smaller payloads. The value is encoded as JSON and its JSON as MessagePack,
+// so objects are maps keyed by the same names, times RFC 3339 strings, and
+// byte slices base64, exactly as in JSON. The MessagePack itself is written
+// and read by github.com/vmihailenco/msgpack.
+//
+// Request bodies are MessagePack of the JSON they stand for: binary is read
+// as base64, as JSON has []byte, and timestamps as RFC 3339 strings.
+type MsgPack struct{}
+
+func init() {
+	// JSON numbers keep their integers exact
+	msgpack.Register(json.Number(""), encodeMsgPackNumber, nil)
+}
+
+func (MsgPack) Name() string { return "msgpack" }
+
+func (MsgPack) MediaTypes() []string {
+	return []string{"application/x-msgpack", "application/msgpack", "application/vnd.msgpack"}
+}
+
+func (MsgPack) Append(dst []byte, v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return dst, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return dst, err
+	}
+
+	buf := bytes.NewBuffer(dst)
+	enc := msgpack.NewEncoder(buf)
+	enc.SetSortMapKeys(true)
+	enc.UseCompactInts(true)
+	if err := enc.Encode(value); err != nil {
+		return dst, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeMsgPackNumber encodes a JSON number as an integer unless it has a
+// fraction or exponent, or doesn't fit one
+func encodeMsgPackNumber(enc *msgpack.Encoder, v reflect.Value) error {
+	n := json.Number(v.String())
+	if !strings.ContainsAny(string(n), ".eE") {
+		if i, err := n.Int64(); err == nil {
+			return enc.EncodeInt(i)
+		}
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return err
+	}
+	return enc.EncodeFloat64(f)
+}
+
+func (MsgPack) NewDecoder(r io.Reader) Decoder {
+	return &msgPackDecoder{r: r}
+}
+
+type msgPackDecoder struct {
+	r      io.Reader
+	strict bool
+}
+
+func (d *msgPackDecoder) DisallowUnknownFields() {
+	d.strict = true
+}
+
+// Decode reads the next value and writes it as JSON, which encoding/json
+// then decodes: field names, type errors, and unknown fields are those of
+// JSON
+func (d *msgPackDecoder) Decode(v interface{}) error {
+	value, err := decodeMsgPack(msgpack.NewDecoder(d.r), 0)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if d.strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
+
+// maxMsgPackDepth bounds the nesting of request bodies, as encoding/json does
+const maxMsgPackDepth = 10000
+
+// decodeMsgPack returns the next value of dec in the types json.Marshal
+// writes as the JSON it stands for: maps need string keys, and binary and
+// timestamps are []byte and time.Time. Maps and arrays are read here rather
+// than by dec, to bound their nesting.
+func decodeMsgPack(dec *msgpack.Decoder, depth int) (interface{}, error) {
+	if depth > maxMsgPackDepth {
+		return nil, errors.New("invalid MessagePack: nested too deeply")
+	}
+	c, err := dec.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case msgpcode.IsFixedMap(c) || c == msgpcode.Map16 || c == msgpcode.Map32:
+		n, err := dec.DecodeMapLen()
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{})
+		for i := 0; i < n; i++ {
+			key, err := dec.DecodeString()
+			if err != nil {
+				return nil, err
+			}
+			if m[key], err = decodeMsgPack(dec, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case msgpcode.IsFixedArray(c) || c == msgpcode.Array16 || c == msgpcode.Array32:
+		n, err := dec.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		a := []interface{}{}
+		for i := 0; i < n; i++ {
+			value, err := decodeMsgPack(dec, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		return a, nil
+	default:
+		return dec.DecodeInterface()
+	}
+}
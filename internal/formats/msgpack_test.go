@@ -0,0 +1,182 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/id"
+	"{{MODULE_NAME}}/internal/jsonenc"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func productPage(n int) *models.PaginatedResponse {
+	products := make([]models.Product, n)
+	for i := range products {
+		products[i] = models.Product{
+			ID:          i + 1,
+			UID:         id.ProductID(fmt.Sprintf("01J9ZQ4V8X2M6T0K3R5N7P%04d", i)),
+			SKU:         fmt.Sprintf("SKU-%06d", i),
+			Name:        fmt.Sprintf("Product <%d> & more", i),
+			Description: "Ships in 2–3 days",
+			Quantity:    i % 100,
+			UnitPrice:   float64(i%1000) / 100,
+			CreatedAt:   time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC),
+			UpdatedAt:   time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+		}
+	}
+	return &models.PaginatedResponse{
+		BaseResponse: models.BaseResponse{Status: "success", Code: 200, Message: "Products retrieved", Timestamp: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)},
+		Data:         products,
+		Pagination:   &models.PaginationMeta{Limit: n, Offset: 0, Total: 10 * n},
+	}
+}
+
+// TestMsgPackMatchesJSON decodes the MessagePack of values as a request body
+// and checks it's their JSON
+func TestMsgPackMatchesJSON(t *testing.T) {
+	event := events.New(events.ProductCreated{Product: models.Product{ID: 42, SKU: "BOLT-M6"}})
+	values := map[string]interface{}{
+		"product page":   productPage(3),
+		"error response": models.NewErrorResponse(400, `invalid "sku"`),
+		"map":            map[string]interface{}{"b": 1, "a": []string{"x", "<y>"}, "c": nil, "d": 0.1, "e": -70000, "f": strings.Repeat("long ", 20)},
+		"int keys":       map[int]bool{3: true, 1: false},
+		"raw message":    struct{ Details json.RawMessage }{json.RawMessage(`{"n": 1, "list": [1.5, "x"]}`)},
+		"marshaler":      event,
+		"nil slice":      struct{ Items []int }{},
+		"bytes":          struct{ Data []byte }{[]byte("binary\x00data")},
+		"embedded":       models.AdminProduct{Product: &productPage(1).Data.([]models.Product)[0]},
+		"nil embedded":   models.AdminProduct{},
+		"floats":         []float64{0, 1e21, 1e-7, 12.3, -0.5},
+		"ints":           []int64{0, 127, 128, 255, 256, 65536, 1 << 40, -1, -32, -33, -200, -40000, -1 << 40},
+	}
+
+	for name, v := range values {
+		t.Run(name, func(t *testing.T) {
+			data, err := MsgPack{}.Append(nil, v)
+			if err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+			var got interface{}
+			if err := (MsgPack{}).NewDecoder(bytes.NewReader(data)).Decode(&got); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			var want interface{}
+			encoded, _ := json.Marshal(v)
+			json.Unmarshal(encoded, &want)
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				t.Errorf("msgpack = %s\njson    = %s", gotJSON, encoded)
+			}
+		})
+	}
+}
+
+func TestMsgPackAppend(t *testing.T) {
+	got, err := MsgPack{}.Append(nil, map[string]interface{}{"id": 42, "tags": []string{"a"}, "price": 1.5, "gone": nil, "ok": true})
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	// fixmap of 5; keys sorted
+	want := "85" + "a4676f6e65" + "c0" + "a26964" + "2a" + "a26f6b" + "c3" + "a57072696365" + "cb3ff8000000000000" + "a474616773" + "91a161"
+	if hex.EncodeToString(got) != want {
+		t.Errorf("Append = %x, want %s", got, want)
+	}
+}
+
+func TestMsgPackDecode(t *testing.T) {
+	// {"body": "Shelf B7", "pinned": true}
+	body, _ := hex.DecodeString("82" + "a4626f6479" + "a8" + hex.EncodeToString([]byte("Shelf B7")) + "a670696e6e6564" + "c3")
+	var req models.NoteRequest
+	dec := MsgPack{}.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if req.Body == nil || *req.Body != "Shelf B7" || req.Pinned == nil || !*req.Pinned {
+		t.Errorf("Decode = %+v", req)
+	}
+
+	// {"at": timestamp 96 of 2024-05-01T12:00:00.5Z}
+	var ts struct {
+		At time.Time `json:"at"`
+	}
+	at := time.Date(2024, 5, 1, 12, 0, 0, 500000000, time.UTC)
+	ext := fmt.Sprintf("c70cff%08x%016x", at.Nanosecond(), at.Unix())
+	body, _ = hex.DecodeString("81a26174" + ext)
+	if err := (MsgPack{}).NewDecoder(bytes.NewReader(body)).Decode(&ts); err != nil || !ts.At.Equal(at) {
+		t.Errorf("Decode = %v, %v; want %v", ts.At, err, at)
+	}
+}
+
+func TestMsgPackDecodeErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":   "81a5636f6c6f72a3726564",
+		"wrong type":      "81a670696e6e6564a3796573",
+		"truncated":       "82a4626f6479",
+		"huge string":     "81a4626f6479dbffffffff",
+		"non-string key":  "81c3c3",
+		"unknown type":    "c1",
+		"other extension": "d40501",
+		"NaN":             "cb7ff8000000000001",
+		"nested too deep": strings.Repeat("91", maxMsgPackDepth+1) + "c0",
+	}
+	for name, input := range tests {
+		body, _ := hex.DecodeString(input)
+		var req models.NoteRequest
+		dec := MsgPack{}.NewDecoder(bytes.NewReader(body))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err == nil {
+			t.Errorf("%s: Decode = %+v, want an error", name, req)
+		}
+	}
+}
+
+// BenchmarkProductPage compares encoding a page of the product list as JSON
+// and MessagePack, and decoding it again
+func BenchmarkProductPage(b *testing.B) {
+	for _, n := range []int{1, 50} {
+		page := productPage(n)
+		for _, enc := range []jsonenc.Encoder{jsonenc.Segment{}, MsgPack{}} {
+			b.Run(fmt.Sprintf("products=%d/encode-%s", n, enc.Name()), func(b *testing.B) {
+				var buf []byte
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					var err error
+					if buf, err = enc.Append(buf[:0], page); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(len(buf)), "bytes/response")
+			})
+		}
+
+		encoded, _ := jsonenc.Segment{}.Append(nil, page)
+		b.Run(fmt.Sprintf("products=%d/decode-json", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var out models.PaginatedResponse
+				if err := json.Unmarshal(encoded, &out); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		packed, _ := MsgPack{}.Append(nil, page)
+		b.Run(fmt.Sprintf("products=%d/decode-msgpack", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var out models.PaginatedResponse
+				if err := (MsgPack{}).NewDecoder(bytes.NewReader(packed)).Decode(&out); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
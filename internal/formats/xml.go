@@ -0,0 +1,418 @@
+package formats
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/jsonenc"
+)
+
+// XML renders the JSON of a value as elements named by its keys, under a
+// response root element:
+//
+//	{"code":200,"data":{"id":42,"tags":["a","b"],"retired_at":null}}
+//
+//	<response><code>200</code><data><id>42</id><tags><item>a</item><item>b</item></tags><retired_at nil="true"></retired_at></data></response>
+//
+// Array elements are item elements, and keys that aren't XML names, like
+// those of some maps, become entry elements with a key attribute. Request
+// bodies are read the same way under any root element, typed by the fields
+// they're decoded into; a list field also takes its items as repeated
+// elements of its name.
+type XML struct{}
+
+const (
+	xmlRoot    = "response"
+	xmlItem    = "item"
+	xmlEntry   = "entry"
+	xmlKeyAttr = "key"
+	xmlNilAttr = "nil"
+)
+
+func (XML) Name() string { return "xml" }
+
+func (XML) MediaTypes() []string { return []string{"application/xml", "text/xml"} }
+
+func (XML) Append(dst []byte, v interface{}) ([]byte, error) {
+	data, err := jsonenc.Current().Append(nil, v)
+	if err != nil {
+		return dst, err
+	}
+
+	buf := bytes.NewBuffer(dst)
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := writeElement(enc, dec, xml.StartElement{Name: xml.Name{Local: xmlRoot}}); err != nil {
+		return dst, err
+	}
+	if err := enc.Flush(); err != nil {
+		return dst, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// writeElement writes the next JSON value of dec as the element start
+func writeElement(enc *xml.Encoder, dec *json.Decoder, start xml.StartElement) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	var text string
+	switch t := tok.(type) {
+	case json.Delim:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for dec.More() {
+			child := xml.StartElement{Name: xml.Name{Local: xmlItem}}
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child = keyElement(key.(string))
+			}
+			if err := writeElement(enc, dec, child); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil { // The closing delimiter
+			return err
+		}
+		return enc.EncodeToken(start.End())
+	case nil:
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: xmlNilAttr}, Value: "true"})
+	case string:
+		text = t
+	case json.Number:
+		text = t.String()
+	case bool:
+		text = strconv.FormatBool(t)
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if text != "" {
+		if err := enc.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// keyElement returns the element of an object's key: named by the key, or
+// an entry element carrying it when it isn't an XML name
+func keyElement(key string) xml.StartElement {
+	if isXMLName(key) {
+		return xml.StartElement{Name: xml.Name{Local: key}}
+	}
+	return xml.StartElement{
+		Name: xml.Name{Local: xmlEntry},
+		Attr: []xml.Attr{{Name: xml.Name{Local: xmlKeyAttr}, Value: key}},
+	}
+}
+
+// isXMLName reports whether s is an XML name without a namespace prefix, of
+// ASCII letters, digits, hyphens, underscores, and dots, not starting with
+// a digit, hyphen, dot, or "xml"
+func isXMLName(s string) bool {
+	if s == "" || strings.HasPrefix(strings.ToLower(s), "xml") {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case i > 0 && (c >= '0' && c <= '9' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (XML) NewDecoder(r io.Reader) Decoder {
+	return &xmlDecoder{r: r}
+}
+
+type xmlDecoder struct {
+	r      io.Reader
+	strict bool
+}
+
+func (d *xmlDecoder) DisallowUnknownFields() {
+	d.strict = true
+}
+
+// Decode reads the body into a tree of elements and writes it as the JSON
+// v's type expects, which encoding/json then decodes: field names, type
+// errors, and unknown fields are those of JSON
+func (d *xmlDecoder) Decode(v interface{}) error {
+	root, err := parseXML(d.r)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeJSON(&buf, root, reflect.TypeOf(v))
+	dec := json.NewDecoder(&buf)
+	if d.strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
+
+// node is an element of a request body
+type node struct {
+	name     string // Or its key attribute
+	null     bool
+	text     string
+	children []*node
+}
+
+func parseXML(r io.Reader) (*node, error) {
+	dec := xml.NewDecoder(r)
+	var stack []*node
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{name: t.Name.Local}
+			for _, attr := range t.Attr {
+				switch attr.Name.Local {
+				case xmlKeyAttr:
+					n.name = attr.Value
+				case xmlNilAttr:
+					n.null = attr.Value == "true"
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			}
+			stack = append(stack, n)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		case xml.EndElement:
+			n := stack[len(stack)-1]
+			if stack = stack[:len(stack)-1]; len(stack) == 0 {
+				return n, nil
+			}
+		}
+	}
+}
+
+var (
+	rawMessageType      = reflect.TypeOf(json.RawMessage(nil))
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// writeJSON writes n as JSON for a value of type t; text that isn't what t
+// needs is written as a string, for encoding/json to reject
+func writeJSON(buf *bytes.Buffer, n *node, t reflect.Type) {
+	if n.null {
+		buf.WriteString("null")
+		return
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == nil || t == rawMessageType || t.Kind() == reflect.Interface:
+		writeUntyped(buf, n)
+		return
+	case reflect.PointerTo(t).Implements(unmarshalerType), reflect.PointerTo(t).Implements(textUnmarshalerType):
+		writeString(buf, n.text)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		writeObject(buf, n, func(name string) reflect.Type {
+			if field, ok := fields[name]; ok {
+				return field
+			}
+			return fields[strings.ToLower(name)]
+		})
+	case reflect.Map:
+		writeObject(buf, n, func(string) reflect.Type { return t.Elem() })
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			writeString(buf, n.text) // Base64, as in JSON
+			return
+		}
+		buf.WriteByte('[')
+		for i, item := range items(n) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(buf, item, t.Elem())
+		}
+		buf.WriteByte(']')
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		writeLiteral(buf, n.text)
+	default:
+		writeString(buf, n.text)
+	}
+}
+
+// writeObject writes the children of n as an object, with the type of each
+// from field, nil for unknown ones. A name given more than once is written
+// once: the children of a list field are its items together, and otherwise
+// the last one counts, as in JSON.
+func writeObject(buf *bytes.Buffer, n *node, field func(name string) reflect.Type) {
+	var names []string
+	byName := make(map[string][]*node)
+	for _, child := range n.children {
+		if _, ok := byName[child.name]; !ok {
+			names = append(names, child.name)
+		}
+		byName[child.name] = append(byName[child.name], child)
+	}
+
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeString(buf, name)
+		buf.WriteByte(':')
+
+		nodes, t := byName[name], field(name)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if len(nodes) > 1 && t != nil && t.Kind() == reflect.Slice {
+			list := &node{}
+			for _, n := range nodes {
+				list.children = append(list.children, items(n)...)
+			}
+			writeJSON(buf, list, t)
+			continue
+		}
+		writeJSON(buf, nodes[len(nodes)-1], t)
+	}
+	buf.WriteByte('}')
+}
+
+// items returns the items of a list element: its children, or itself when
+// it has text instead
+func items(n *node) []*node {
+	if len(n.children) > 0 {
+		return n.children
+	}
+	if strings.TrimSpace(n.text) != "" {
+		return []*node{n}
+	}
+	return nil
+}
+
+// writeUntyped writes n for a value of no particular type: elements with
+// children as objects, or arrays when they're all items, and text as a
+// number or boolean when it reads as one
+func writeUntyped(buf *bytes.Buffer, n *node) {
+	if len(n.children) == 0 {
+		writeLiteral(buf, n.text)
+		return
+	}
+
+	list := true
+	for _, child := range n.children {
+		list = list && child.name == xmlItem
+	}
+	if !list {
+		writeObject(buf, n, func(string) reflect.Type { return nil })
+		return
+	}
+	buf.WriteByte('[')
+	for i, child := range n.children {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSON(buf, child, nil)
+	}
+	buf.WriteByte(']')
+}
+
+// writeLiteral writes text as a JSON number or boolean, or as a string when
+// it's neither
+func writeLiteral(buf *bytes.Buffer, text string) {
+	text = strings.TrimSpace(text)
+	if text == "true" || text == "false" {
+		buf.WriteString(text)
+		return
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil && json.Valid([]byte(text)) {
+		buf.WriteString(text)
+		return
+	}
+	writeString(buf, text)
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	data, _ := json.Marshal(s)
+	buf.Write(data)
+}
+
+// jsonFields returns the types of the fields encoding/json decodes into t,
+// by JSON name and by its lowercase, as encoding/json matches names without
+// regard to case
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+
+		// Fields of embedded structs are promoted, unless the tag names it
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for embedded, et := range jsonFields(ft) {
+				if _, ok := fields[embedded]; !ok {
+					fields[embedded] = et
+				}
+			}
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+		if _, ok := fields[strings.ToLower(name)]; !ok {
+			fields[strings.ToLower(name)] = f.Type
+		}
+	}
+	return fields
+}
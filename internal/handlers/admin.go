@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/backup"
+	"{{MODULE_NAME}}/internal/config"
+	"{{MODULE_NAME}}/internal/logging"
+	"{{MODULE_NAME}}/internal/maintenance"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type AdminHandler struct {
+	store     *config.Store
+	levels    *logging.Levels
+	auditRepo repository.AuditRepository
+	purger    *maintenance.Purger
+	integrity *maintenance.IntegrityChecker
+	mode      *maintenance.Mode
+	archiver  *backup.Archiver
+	explainer repository.QueryExplainer
+	logger    *slog.Logger
+}
+
+func NewAdminHandler(store *config.Store, levels *logging.Levels, auditRepo repository.AuditRepository, purger *maintenance.Purger, integrity *maintenance.IntegrityChecker, mode *maintenance.Mode, archiver *backup.Archiver, explainer repository.QueryExplainer, logger *slog.Logger) *AdminHandler {
+	return &AdminHandler{
+		store:     store,
+		levels:    levels,
+		auditRepo: auditRepo,
+		purger:    purger,
+		integrity: integrity,
+		mode:      mode,
+		archiver:  archiver,
+		explainer: explainer,
+		logger:    logger,
+	}
+}
+
+// LogLevelRequest changes the base level and/or component levels. An empty
+// component level resets that component to the base level.
+type LogLevelRequest struct {
+	Level      string            `json:"level,omitempty" example:"info"`
+	Components map[string]string `json:"components,omitempty"`
+}
+
+type LogLevelResponse struct {
+	Level      string            `json:"level"`
+	Format     string            `json:"format"`
+	Components map[string]string `json:"components"` // Effective level per component
+	Overrides  map[string]string `json:"overrides"`  // Components with an explicit level
+}
+
+// ReloadConfig handles POST /api/v1/admin/config/reload
+// It reloads runtime configuration from the environment and .env file
+//
+//	@Summary		Reload configuration
+//	@Description	Re-read runtime settings (log level, rate limits, feature flags, CORS origins) without a restart
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	models.SuccessResponse	"Applied changes"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		422	{object}	models.ErrorResponse	"New configuration is invalid"
+//	@Router			/admin/config/reload [post]
+func (h *AdminHandler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	event, err := h.store.Reload(r.Context(), "api", r.RemoteAddr)
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	changes := event.Changes
+	if changes == nil {
+		changes = []config.Change{}
+	}
+	data := map[string]interface{}{
+		"changes": changes,
+		"ignored": event.Ignored,
+	}
+	response := models.NewSuccessResponse(http.StatusOK, "Configuration reloaded", data)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// GetLogLevel handles GET /api/v1/admin/log-level
+// It returns the current base and per-component log levels
+//
+//	@Summary		Get log levels
+//	@Description	Get the base log level, output format, and effective level per component
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	models.SuccessResponse{data=LogLevelResponse}	"Current log levels"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Router			/admin/log-level [get]
+func (h *AdminHandler) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	response := models.NewSuccessResponse(http.StatusOK, "Log levels retrieved successfully", h.logLevels())
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// UpdateLogLevel handles PUT /api/v1/admin/log-level
+// It changes log levels without a restart
+//
+//	@Summary		Update log levels
+//	@Description	Change the base log level and/or per-component levels (http, repository, jobs) at runtime
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			levels	body		LogLevelRequest	true	"New levels"
+//	@Success		200		{object}	models.SuccessResponse{data=LogLevelResponse}	"Updated log levels"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid level or component"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Router			/admin/log-level [put]
+func (h *AdminHandler) UpdateLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Level == "" && len(req.Components) == 0 {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Level or components is required")
+		return
+	}
+
+	// Validate everything before applying anything
+	var base *slog.Level
+	if req.Level != "" {
+		level, err := logging.ParseLevel(req.Level)
+		if err != nil {
+			respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+			return
+		}
+		base = &level
+	}
+
+	components := make(map[string]*slog.Level, len(req.Components))
+	for name, value := range req.Components {
+		if !logging.IsComponent(name) {
+			respondWithError(h.logger, w, http.StatusBadRequest, "Unknown log component: "+name)
+			return
+		}
+		if value == "" {
+			components[name] = nil
+			continue
+		}
+		level, err := logging.ParseLevel(value)
+		if err != nil {
+			respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+			return
+		}
+		components[name] = &level
+	}
+
+	if base != nil {
+		h.levels.SetBase(*base)
+	}
+	for name, level := range components {
+		_ = h.levels.SetComponent(name, level) // Names validated above
+	}
+
+	current := h.logLevels()
+	h.logger.Info("log levels updated", "level", current.Level, "overrides", current.Overrides)
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"request": req,
+		"result":  current,
+	})
+	entry := &models.AuditEntry{
+		Action:     "log_level.update",
+		Actor:      r.RemoteAddr,
+		EntityType: "config",
+		Details:    details,
+	}
+	if err := h.auditRepo.Create(r.Context(), entry); err != nil {
+		h.logger.Error("failed to record log level change in audit log", "error", err)
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Log levels updated successfully", current)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// PurgeResponse reports the rows deleted per retention policy
+type PurgeResponse struct {
+	Purged map[string]int64 `json:"purged"`
+}
+
+// PurgeExpired handles POST /api/v1/admin/retention/purge
+// It runs the retention purge immediately instead of waiting for the job
+//
+//	@Summary		Purge expired data
+//	@Description	Delete rows older than their retention (audit log, processed orders, delivered outbox messages) now
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	models.SuccessResponse{data=PurgeResponse}	"Rows purged per policy"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	models.ErrorResponse	"Purge failed"
+//	@Router			/admin/retention/purge [post]
+func (h *AdminHandler) PurgeExpired(w http.ResponseWriter, r *http.Request) {
+	purged, err := h.purger.Purge(r.Context())
+
+	audit := map[string]interface{}{"purged": purged}
+	if err != nil {
+		audit["error"] = err.Error()
+	}
+	details, _ := json.Marshal(audit)
+	entry := &models.AuditEntry{
+		Action:     "retention.purge",
+		Actor:      r.RemoteAddr,
+		EntityType: "retention",
+		Details:    details,
+	}
+	if auditErr := h.auditRepo.Create(r.Context(), entry); auditErr != nil {
+		h.logger.Error("failed to record purge in audit log", "error", auditErr)
+	}
+
+	if err != nil {
+		h.logger.Error("retention purge failed", "purged", purged, "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to purge expired data")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Expired data purged", PurgeResponse{Purged: purged})
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+type IntegrityResponse struct {
+	Results []maintenance.IntegrityResult `json:"results"`
+}
+
+// CheckIntegrity handles POST /api/v1/admin/integrity/check
+// It runs the integrity checks immediately instead of waiting for the job
+//
+//	@Summary		Check data integrity
+//	@Description	Find orphaned rows and count mismatches now. repair=on deletes the orphans, repair=dry-run (or dry_run=true) reports what it would delete.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			repair	query		string	false	"off (default), dry-run, or on"
+//	@Param			dry_run	query		bool	false	"Roll back any repairs"
+//	@Success		200		{object}	models.SuccessResponse{data=IntegrityResponse}	"Violations and repairs per check"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid repair mode"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	models.ErrorResponse	"A check failed"
+//	@Router			/admin/integrity/check [post]
+func (h *AdminHandler) CheckIntegrity(w http.ResponseWriter, r *http.Request) {
+	repair := r.URL.Query().Get("repair")
+	switch repair {
+	case "":
+		repair = maintenance.RepairOff
+	case maintenance.RepairOff, maintenance.RepairDryRun, maintenance.RepairOn:
+	default:
+		respondWithError(h.logger, w, http.StatusBadRequest, "repair must be off, dry-run, or on")
+		return
+	}
+
+	results, err := h.integrity.Check(r.Context(), repair)
+
+	if repair != maintenance.RepairOff {
+		details, _ := json.Marshal(map[string]interface{}{"repair": repair, "results": results})
+		entry := &models.AuditEntry{
+			Action:     "integrity.repair",
+			Actor:      r.RemoteAddr,
+			EntityType: "integrity",
+			Details:    details,
+		}
+		if auditErr := h.auditRepo.Create(r.Context(), entry); auditErr != nil {
+			h.logger.Error("failed to record integrity repair in audit log", "error", auditErr)
+		}
+	}
+
+	if err != nil {
+		h.logger.Error("integrity check failed", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to check data integrity")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Integrity checked", IntegrityResponse{Results: results})
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// MaintenanceRequest switches maintenance mode on or off
+type MaintenanceRequest struct {
+	Enabled    bool   `json:"enabled" example:"true"`
+	Message    string `json:"message,omitempty" example:"Database upgrade in progress"`
+	RetryAfter int    `json:"retry_after_seconds,omitempty" example:"300"`
+}
+
+// GetMaintenance handles GET /api/v1/admin/maintenance
+// It returns whether writes are currently refused
+//
+//	@Summary		Get maintenance mode
+//	@Description	Get whether maintenance mode is on, since when, and the Retry-After given to clients
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	models.SuccessResponse{data=maintenance.ModeStatus}	"Maintenance mode"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Router			/admin/maintenance [get]
+func (h *AdminHandler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	response := models.NewSuccessResponse(http.StatusOK, "Maintenance mode retrieved successfully", h.mode.Status())
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// UpdateMaintenance handles PUT /api/v1/admin/maintenance
+// It switches maintenance mode on or off without a restart
+//
+//	@Summary		Update maintenance mode
+//	@Description	Refuse writes with 503 and Retry-After while reads continue, or accept writes again
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			mode	body		MaintenanceRequest	true	"New mode"
+//	@Success		200		{object}	models.SuccessResponse{data=maintenance.ModeStatus}	"Updated maintenance mode"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid request body"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		409		{object}	models.ErrorResponse	"Instance is read-only"
+//	@Router			/admin/maintenance [put]
+func (h *AdminHandler) UpdateMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req MaintenanceRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.RetryAfter < 0 {
+		respondWithError(h.logger, w, http.StatusBadRequest, "retry_after_seconds must not be negative")
+		return
+	}
+
+	if req.Enabled {
+		h.mode.Enable(req.Message, time.Duration(req.RetryAfter)*time.Second)
+	} else if err := h.mode.Disable(); err != nil {
+		respondWithError(h.logger, w, http.StatusConflict, "Instance was started read-only, maintenance mode can't be switched off")
+		return
+	}
+
+	status := h.mode.Status()
+	h.logger.Info("maintenance mode updated", "enabled", status.Enabled, "message", status.Message)
+
+	details, _ := json.Marshal(req)
+	entry := &models.AuditEntry{
+		Action:     "maintenance.update",
+		Actor:      r.RemoteAddr,
+		EntityType: "maintenance",
+		Details:    details,
+	}
+	if err := h.auditRepo.Create(r.Context(), entry); err != nil {
+		h.logger.Error("failed to record maintenance mode change in audit log", "error", err)
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Maintenance mode updated successfully", status)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// BackupSHA256Trailer carries the checksum of a streamed backup once it is complete
+const BackupSHA256Trailer = "X-Backup-SHA256"
+
+// Backup handles GET /api/v1/admin/backup
+// It streams a backup of the full dataset; restore it with `api admin restore`
+//
+//	@Summary		Download a backup
+//	@Description	Stream every table as gzip-compressed NDJSON, read from one snapshot. The last line holds row counts and a SHA-256 checked on restore; the checksum is also sent in the X-Backup-SHA256 trailer.
+//	@Tags			admin
+//	@Produce		application/gzip
+//	@Success		200	{file}		file					"Backup"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Router			/admin/backup [get]
+func (h *AdminHandler) Backup(w http.ResponseWriter, r *http.Request) {
+	filename := "backup-" + time.Now().UTC().Format("20060102T150405Z") + ".ndjson.gz"
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Trailer", BackupSHA256Trailer)
+	// The server's write timeout is sized for ordinary responses; the request
+	// timeout still bounds the export
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	// Once streaming has started the status can't change; a failed backup
+	// lacks its trailer line and is rejected on restore
+	manifest, err := h.archiver.Export(r.Context(), w)
+
+	audit := map[string]interface{}{"filename": filename}
+	if err != nil {
+		audit["error"] = err.Error()
+	} else {
+		audit["rows"] = manifest.Rows
+		audit["sha256"] = manifest.SHA256
+		w.Header().Set(BackupSHA256Trailer, manifest.SHA256)
+	}
+	details, _ := json.Marshal(audit)
+	entry := &models.AuditEntry{
+		Action:     "backup.export",
+		Actor:      r.RemoteAddr,
+		EntityType: "backup",
+		Details:    details,
+	}
+	if auditErr := h.auditRepo.Create(r.Context(), entry); auditErr != nil {
+		h.logger.Error("failed to record backup in audit log", "error", auditErr)
+	}
+
+	if err != nil {
+		h.logger.Error("backup failed", "error", err)
+		return
+	}
+	h.logger.Info("backup exported", "rows", manifest.Rows, "sha256", manifest.SHA256)
+}
+
+// ExplainRequest names an allowlisted repository query and its parameters
+type ExplainRequest struct {
+	Query  string                     `json:"query" example:"products.list"`
+	Params map[string]json.RawMessage `json:"params" swaggertype:"object"`
+}
+
+type ExplainResponse struct {
+	Query    string          `json:"query" example:"products.list"`
+	Duration string          `json:"duration" example:"12ms"`
+	Plan     json.RawMessage `json:"plan" swaggertype:"array,object"` // EXPLAIN (FORMAT JSON) output
+}
+
+// Explain handles POST /api/v1/admin/explain
+// It returns the executed plan of a repository query
+//
+//	@Summary		Explain a repository query
+//	@Description	Run a named repository read (products.list, products.get_by_sku, audit_log.list, ...) under EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) with the given parameters, in a read-only transaction, and return the plan. PostgreSQL only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		ExplainRequest	true	"Query and parameters"
+//	@Success		200		{object}	models.SuccessResponse{data=ExplainResponse}	"Plan"
+//	@Failure		400		{object}	models.ErrorResponse	"Unknown query or invalid parameters"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		501		{object}	models.ErrorResponse	"Database isn't PostgreSQL"
+//	@Router			/admin/explain [post]
+func (h *AdminHandler) Explain(w http.ResponseWriter, r *http.Request) {
+	var req ExplainRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	start := time.Now()
+	plan, err := h.explainer.Explain(r.Context(), req.Query, req.Params)
+	duration := time.Since(start).Round(time.Millisecond)
+
+	var paramErr *repository.ParamError
+	switch {
+	case errors.Is(err, repository.ErrUnknownQuery):
+		names := make([]string, len(repository.ExplainableQueries))
+		for i, query := range repository.ExplainableQueries {
+			names[i] = query.Name
+		}
+		respondWithError(h.logger, w, http.StatusBadRequest, "Unknown query, must be one of: "+strings.Join(names, ", "))
+		return
+	case errors.As(err, &paramErr):
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid params: "+paramErr.Error())
+		return
+	case errors.Is(err, repository.ErrExplainUnsupported):
+		respondWithError(h.logger, w, http.StatusNotImplemented, "Explain needs a PostgreSQL database")
+		return
+	}
+
+	// Only queries that ran are audited; parameters may identify customers, so
+	// they are kept out of the log
+	details, _ := json.Marshal(map[string]interface{}{"query": req.Query, "duration": duration.String()})
+	entry := &models.AuditEntry{
+		Action:     "query.explain",
+		Actor:      r.RemoteAddr,
+		EntityType: "query",
+		EntityID:   req.Query,
+		Details:    details,
+	}
+	if auditErr := h.auditRepo.Create(r.Context(), entry); auditErr != nil {
+		h.logger.Error("failed to record explain in audit log", "error", auditErr)
+	}
+
+	if err != nil {
+		h.logger.Error("explain failed", "query", req.Query, "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to explain query")
+		return
+	}
+
+	data := ExplainResponse{Query: req.Query, Duration: duration.String(), Plan: plan}
+	response := models.NewSuccessResponse(http.StatusOK, "Query explained", data)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+func (h *AdminHandler) logLevels() LogLevelResponse {
+	effective := make(map[string]string)
+	for _, name := range logging.Components() {
+		effective[name] = logging.LevelName(h.levels.Effective(name))
+	}
+
+	return LogLevelResponse{
+		Level:      logging.LevelName(h.levels.Base()),
+		Format:     h.levels.Format(),
+		Components: effective,
+		Overrides:  h.levels.Overrides(),
+	}
+}
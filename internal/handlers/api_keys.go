@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/quota"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type APIKeyHandler struct {
+	keys      repository.APIKeyRepository
+	usage     repository.UsageRepository
+	meter     *quota.Meter // nil on a read-only instance
+	auditRepo repository.AuditRepository
+	logger    *slog.Logger
+}
+
+func NewAPIKeyHandler(keys repository.APIKeyRepository, usage repository.UsageRepository, meter *quota.Meter, auditRepo repository.AuditRepository, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys, usage: usage, meter: meter, auditRepo: auditRepo, logger: logger}
+}
+
+// APIKeyRequest creates or changes an API key
+type APIKeyRequest struct {
+	Name         string `json:"name" example:"Acme storefront"`
+	MonthlyQuota int64  `json:"monthly_quota" example:"100000"` // Requests per calendar month (UTC), 0 for no limit
+	Region       string `json:"region,omitempty" example:"DE"`  // Of a regional storefront, which then only sees the products sold there
+}
+
+// ListAPIKeys handles GET /api/v1/admin/api-keys
+// It returns a paginated list of API keys
+//
+//	@Summary		List API keys
+//	@Description	Get a paginated list of API keys, revoked ones included, oldest first. Keys themselves are never returned after creation.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int	false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.APIKey}	"List of API keys with pagination metadata"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	keys, err := h.keys.List(ctx, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list API keys", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve API keys")
+		return
+	}
+	if keys == nil {
+		keys = []*models.APIKey{}
+	}
+
+	total, err := h.keys.Count(ctx)
+	if err != nil {
+		h.logger.Error("failed to count API keys", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count API keys")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "API keys retrieved successfully", keys, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// GetAPIKey handles GET /api/v1/admin/api-keys/{id}
+//
+//	@Summary		Get API key by ID
+//	@Description	Get an API key's name, prefix, quota, and region
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"API key ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.APIKey}	"API key"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid API key ID"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	models.ErrorResponse	"API key not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/api-keys/{id} [get]
+func (h *APIKeyHandler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.keyID(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := h.keys.GetByID(r.Context(), id)
+	if err != nil {
+		h.respondWithRepoError(w, err, "get", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "API key retrieved successfully", key)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CreateAPIKey handles POST /api/v1/admin/api-keys
+//
+//	@Summary		Create an API key
+//	@Description	Creates a key for an external consumer, sent in X-API-Key and metered against its monthly quota. A key with a region serves a regional storefront: its product lists, searches, and lookups only show the products sold in that region. The key is returned only in this response.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			key	body		APIKeyRequest	true	"Name, quota, and region"
+//	@Success		201	{object}	models.SuccessResponse{data=models.APIKey}	"Created API key, with the key itself"
+//	@Header			201	{string}	Location				"/api/v1/admin/api-keys/{id}"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid request"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+
+	raw, hash, prefix, err := quota.GenerateKey()
+	if err != nil {
+		h.logger.Error("failed to generate API key", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+	key := &models.APIKey{Name: req.Name, KeyHash: hash, Prefix: prefix, MonthlyQuota: req.MonthlyQuota, Region: req.Region}
+	if err := h.keys.Create(r.Context(), key); err != nil {
+		h.logger.Error("failed to create API key", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	h.audit(r, "api_key.create", key.ID, &req)
+	h.logger.Info("API key created", "api_key_id", key.ID, "name", key.Name, "monthly_quota", key.MonthlyQuota)
+	key.Key = raw
+	response := models.NewSuccessResponse(http.StatusCreated, "API key created successfully", key)
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/admin/api-keys/%d", key.ID), response)
+}
+
+// UpdateAPIKey handles PUT /api/v1/admin/api-keys/{id}
+//
+//	@Summary		Update an API key
+//	@Description	Renames a key or changes its monthly quota or region; other instances apply the change within a minute
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int				true	"API key ID"
+//	@Param			key	body		APIKeyRequest	true	"Name, quota, and region"
+//	@Success		200	{object}	models.SuccessResponse{data=models.APIKey}	"Updated API key"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid request"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	models.ErrorResponse	"API key not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/api-keys/{id} [put]
+func (h *APIKeyHandler) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.keyID(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.keys.Update(ctx, &models.APIKey{ID: id, Name: req.Name, MonthlyQuota: req.MonthlyQuota, Region: req.Region}); err != nil {
+		h.respondWithRepoError(w, err, "update", id)
+		return
+	}
+	h.audit(r, "api_key.update", id, &req)
+	h.logger.Info("API key updated", "api_key_id", id, "monthly_quota", req.MonthlyQuota, "region", req.Region)
+
+	key, err := h.keys.GetByID(ctx, id)
+	if err != nil {
+		h.respondWithRepoError(w, err, "get", id)
+		return
+	}
+	h.forget(key)
+	response := models.NewSuccessResponse(http.StatusOK, "API key updated successfully", key)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// RevokeAPIKey handles DELETE /api/v1/admin/api-keys/{id}
+//
+//	@Summary		Revoke an API key
+//	@Description	Revokes a key; its requests get 401, within a minute on other instances. The key and its usage are kept for billing.
+//	@Tags			admin
+//	@Param			id	path	int	true	"API key ID"
+//	@Success		204	"API key revoked successfully"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid API key ID"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	models.ErrorResponse	"API key not found or already revoked"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.keyID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.keys.Revoke(ctx, id); err != nil {
+		h.respondWithRepoError(w, err, "revoke", id)
+		return
+	}
+	if key, err := h.keys.GetByID(ctx, id); err == nil {
+		h.forget(key)
+	}
+
+	h.audit(r, "api_key.revoke", id, nil)
+	h.logger.Info("API key revoked", "api_key_id", id)
+	respondNoContent(w)
+}
+
+// usageCSVHeader lists the columns of the CSV usage export
+var usageCSVHeader = []string{"month", "api_key_id", "api_key_name", "endpoint_class", "requests", "bytes"}
+
+// ExportUsage handles GET /api/v1/admin/usage
+// It exports the metered usage of a month for billing
+//
+//	@Summary		Export API usage
+//	@Description	Usage records of a calendar month (UTC), one per API key and endpoint class (e.g. products:read), with requests and response bytes. Includes this instance's unflushed counts; other instances' appear after their next flush.
+//	@Tags			admin
+//	@Produce		json
+//	@Produce		text/csv
+//	@Param			month	query		string	false	"Month as YYYY-MM, default the current one"
+//	@Param			format	query		string	false	"json or csv"	Enums(json, csv)	default(json)
+//	@Success		200		{object}	models.SuccessResponse{data=[]models.UsageRecord}	"Usage records"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid month or format"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/usage [get]
+func (h *APIKeyHandler) ExportUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = quota.Month(time.Now())
+	} else if _, err := time.Parse("2006-01", month); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "month must be YYYY-MM")
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "csv" {
+		respondWithError(h.logger, w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	if h.meter != nil {
+		if err := h.meter.Flush(ctx); err != nil {
+			h.logger.Error("failed to flush API usage", "error", err)
+			respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to export usage")
+			return
+		}
+	}
+	records, err := h.usage.ForMonth(ctx, month)
+	if err != nil {
+		h.logger.Error("failed to list API usage", "error", err, "month", month)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to export usage")
+		return
+	}
+	if records == nil {
+		records = []models.UsageRecord{}
+	}
+
+	if format != "csv" {
+		response := models.NewSuccessResponse(http.StatusOK, "Usage retrieved successfully", records)
+		respondWithJSON(h.logger, w, http.StatusOK, response)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="usage-`+month+`.csv"`)
+	out := csv.NewWriter(w)
+	_ = out.Write(usageCSVHeader)
+	for _, rec := range records {
+		_ = out.Write([]string{
+			rec.Month,
+			strconv.Itoa(rec.APIKeyID),
+			rec.APIKeyName,
+			rec.EndpointClass,
+			strconv.FormatInt(rec.Requests, 10),
+			strconv.FormatInt(rec.Bytes, 10),
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		h.logger.Error("failed to write usage export", "error", err)
+	}
+}
+
+func (h *APIKeyHandler) decode(w http.ResponseWriter, r *http.Request) (APIKeyRequest, bool) {
+	var req APIKeyRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return req, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	switch {
+	case req.Name == "":
+		respondWithError(h.logger, w, http.StatusBadRequest, "name is required")
+		return req, false
+	case req.MonthlyQuota < 0:
+		respondWithError(h.logger, w, http.StatusBadRequest, "monthly_quota must not be negative")
+		return req, false
+	}
+	if req.Region != "" {
+		region, ok := models.NormalizeRegion(req.Region)
+		if !ok {
+			respondWithError(h.logger, w, http.StatusBadRequest, "region must be a region code")
+			return req, false
+		}
+		req.Region = region
+	}
+	return req, true
+}
+
+func (h *APIKeyHandler) keyID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid API key ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// forget applies a changed key to this instance's next request
+func (h *APIKeyHandler) forget(key *models.APIKey) {
+	if h.meter != nil {
+		h.meter.Forget(key.KeyHash)
+	}
+}
+
+func (h *APIKeyHandler) audit(r *http.Request, action string, id int, req *APIKeyRequest) {
+	entry := &models.AuditEntry{
+		Action:     action,
+		Actor:      r.RemoteAddr,
+		EntityType: "api_key",
+		EntityID:   strconv.Itoa(id),
+	}
+	if req != nil {
+		entry.Details, _ = json.Marshal(req)
+	}
+	if err := h.auditRepo.Create(r.Context(), entry); err != nil {
+		h.logger.Error("failed to record API key change in audit log", "error", err, "action", action)
+	}
+}
+
+func (h *APIKeyHandler) respondWithRepoError(w http.ResponseWriter, err error, action string, id int) {
+	if err.Error() == "API key not found" {
+		respondWithError(h.logger, w, http.StatusNotFound, "API key not found")
+		return
+	}
+	h.logger.Error("failed to "+action+" API key", "error", err, "api_key_id", id)
+	respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action+" API key")
+}
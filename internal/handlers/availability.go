@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type AvailabilityHandler struct {
+	repo     repository.ProductRepository
+	lowStock int
+	logger   *slog.Logger
+}
+
+// NewAvailabilityHandler creates the availability handler. Products with at
+// most lowStock units left are reported as low.
+func NewAvailabilityHandler(repo repository.ProductRepository, lowStock int, logger *slog.Logger) *AvailabilityHandler {
+	return &AvailabilityHandler{repo: repo, lowStock: lowStock, logger: logger}
+}
+
+// GetAvailability handles GET /api/v1/products/{id}/availability
+// It returns whether a product is in stock, for storefronts polling at high
+// rates. The body is the bare availability, without the response envelope.
+//
+//	@Summary		Get product availability
+//	@Description	Whether the product is in stock and a coarse stock level, never the quantity. Public and cacheable for AVAILABILITY_MAX_AGE; rate limited separately from the rest of the API.
+//	@Tags			products
+//	@Produce		json
+//	@Param			id		path		int		true	"Product ID"
+//	@Param			region	query		string	false	"Sales region the product must be sold in"
+//	@Success		200		{object}	models.Availability		"Availability"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID or region"
+//	@Failure		403		{object}	models.ErrorResponse	"API key limited to another region"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		429		{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/availability [get]
+func (h *AvailabilityHandler) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	region, ok := salesRegion(h.logger, w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.repo.GetByID(r.Context(), id)
+	if err == nil && !product.SoldIn(region) {
+		err = fmt.Errorf("product not found")
+	}
+	if err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve availability")
+		return
+	}
+
+	respondWithJSON(h.logger, w, http.StatusOK, h.availability(product))
+}
+
+func (h *AvailabilityHandler) availability(product *models.Product) *models.Availability {
+	level := models.AvailabilityInStock
+	switch {
+	case product.Quantity <= 0:
+		level = models.AvailabilityOutOfStock
+	case product.Quantity <= h.lowStock:
+		level = models.AvailabilityLow
+	}
+	return &models.Availability{ProductID: product.ID, InStock: product.Quantity > 0, Level: level}
+}
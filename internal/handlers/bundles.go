@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// maxBundleComponents bounds the products in one bundle
+const maxBundleComponents = 50
+
+type BundleHandler struct {
+	repo   repository.BundleRepository
+	logger *slog.Logger
+}
+
+func NewBundleHandler(repo repository.BundleRepository, logger *slog.Logger) *BundleHandler {
+	return &BundleHandler{repo: repo, logger: logger}
+}
+
+// CreateBundleRequest makes an existing product a bundle
+type CreateBundleRequest struct {
+	ProductID  int                      `json:"product_id"`
+	Components []models.BundleComponent `json:"components"`
+}
+
+// UpdateBundleRequest replaces a bundle's components
+type UpdateBundleRequest struct {
+	Components []models.BundleComponent `json:"components"`
+}
+
+// ListBundles handles GET /api/v1/bundles
+// It returns a paginated list of bundles
+//
+//	@Summary		List bundles
+//	@Description	Get a paginated list of bundles by product ID, with their components and the bundles their stock makes up
+//	@Tags			bundles
+//	@Produce		json
+//	@Param			limit	query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int		false	"Number of items to skip"				default(0)
+//	@Param			region	query		string	false	"Only bundles sold in this region"
+//	@Success		200		{object}	models.PaginatedResponse	"List of bundles with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid region"
+//	@Failure		403		{object}	models.ErrorResponse	"API key limited to another region"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/bundles [get]
+func (h *BundleHandler) ListBundles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	region, ok := salesRegion(h.logger, w, r)
+	if !ok {
+		return
+	}
+
+	bundles, err := h.repo.List(ctx, region, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list bundles", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve bundles")
+		return
+	}
+	if bundles == nil {
+		bundles = []*models.Bundle{}
+	}
+
+	total, err := h.repo.Count(ctx, region)
+	if err != nil {
+		h.logger.Error("failed to count bundles", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count bundles")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Bundles retrieved successfully", bundles, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// GetBundle handles GET /api/v1/bundles/{id}
+//
+//	@Summary		Get bundle
+//	@Description	The bundle made of the product, with its components' stock. available is the minimum over components of their stock divided by the units each bundle takes.
+//	@Tags			bundles
+//	@Produce		json
+//	@Param			id		path		int		true	"Product ID of the bundle"
+//	@Param			region	query		string	false	"Sales region the bundle must be sold in"
+//	@Success		200		{object}	models.SuccessResponse{data=models.Bundle}	"Bundle"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID or region"
+//	@Failure		403		{object}	models.ErrorResponse	"API key limited to another region"
+//	@Failure		404		{object}	models.ErrorResponse	"Bundle not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/bundles/{id} [get]
+func (h *BundleHandler) GetBundle(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.productID(w, r)
+	if !ok {
+		return
+	}
+	region, ok := salesRegion(h.logger, w, r)
+	if !ok {
+		return
+	}
+
+	bundle, err := h.repo.Get(r.Context(), id, region)
+	if err != nil {
+		h.respondWithRepoError(w, err, "get", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Bundle retrieved successfully", bundle)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CreateBundle handles POST /api/v1/bundles
+//
+//	@Summary		Create a bundle
+//	@Description	Makes an existing product a bundle of other products. Components can't be bundles themselves, and a product in a bundle can't become one. Orders for the bundle's SKU decrement its components.
+//	@Tags			bundles
+//	@Accept			json
+//	@Produce		json
+//	@Param			bundle	body		CreateBundleRequest		true	"Bundle product and components"
+//	@Success		201		{object}	models.SuccessResponse{data=models.Bundle}	"Created bundle, with its URL in the Location header"
+//	@Header			201		{string}	Location				"/api/v1/bundles/{id}"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid bundle"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		409		{object}	models.ErrorResponse	"Product is already a bundle"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/bundles [post]
+func (h *BundleHandler) CreateBundle(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateBundleRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.ProductID < 1 {
+		respondWithError(h.logger, w, http.StatusBadRequest, "product_id is required")
+		return
+	}
+	if !h.validComponents(w, req.ProductID, req.Components) {
+		return
+	}
+
+	if components, err := h.repo.Components(ctx, req.ProductID); err != nil {
+		h.respondWithRepoError(w, err, "create", req.ProductID)
+		return
+	} else if len(components) > 0 {
+		respondWithError(h.logger, w, http.StatusConflict, "Product is already a bundle")
+		return
+	}
+
+	bundle, ok := h.set(w, r, req.ProductID, req.Components, "create")
+	if !ok {
+		return
+	}
+
+	h.logger.Info("bundle created", "product_id", req.ProductID, "components", len(req.Components))
+	response := models.NewSuccessResponse(http.StatusCreated, "Bundle created successfully", bundle)
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/bundles/%d", req.ProductID), response)
+}
+
+// UpdateBundle handles PUT /api/v1/bundles/{id}
+//
+//	@Summary		Update bundle
+//	@Description	Replaces the bundle's components
+//	@Tags			bundles
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Product ID of the bundle"
+//	@Param			bundle	body		UpdateBundleRequest	true	"Components"
+//	@Success		200		{object}	models.SuccessResponse{data=models.Bundle}	"Updated bundle"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid bundle"
+//	@Failure		404		{object}	models.ErrorResponse	"Bundle not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/bundles/{id} [put]
+func (h *BundleHandler) UpdateBundle(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.productID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateBundleRequest
+	if err := newDecoder(r).Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !h.validComponents(w, id, req.Components) {
+		return
+	}
+
+	if _, err := h.repo.Get(r.Context(), id, ""); err != nil {
+		h.respondWithRepoError(w, err, "update", id)
+		return
+	}
+
+	bundle, ok := h.set(w, r, id, req.Components, "update")
+	if !ok {
+		return
+	}
+
+	h.logger.Info("bundle updated", "product_id", id, "components", len(req.Components))
+	response := models.NewSuccessResponse(http.StatusOK, "Bundle updated successfully", bundle)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// DeleteBundle handles DELETE /api/v1/bundles/{id}
+//
+//	@Summary		Delete bundle
+//	@Description	Removes the bundle's components; the product itself is kept
+//	@Tags			bundles
+//	@Param			id	path	int	true	"Product ID of the bundle"
+//	@Success		204	"Bundle deleted successfully"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid product ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Bundle not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/bundles/{id} [delete]
+func (h *BundleHandler) DeleteBundle(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.productID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), id); err != nil {
+		h.respondWithRepoError(w, err, "delete", id)
+		return
+	}
+
+	h.logger.Info("bundle deleted", "product_id", id)
+	respondNoContent(w)
+}
+
+func (h *BundleHandler) productID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// validComponents checks what can be checked without the database
+func (h *BundleHandler) validComponents(w http.ResponseWriter, bundleID int, components []models.BundleComponent) bool {
+	if len(components) == 0 {
+		respondWithError(h.logger, w, http.StatusBadRequest, "A bundle needs at least one component")
+		return false
+	}
+	if len(components) > maxBundleComponents {
+		respondWithError(h.logger, w, http.StatusBadRequest, fmt.Sprintf("A bundle has at most %d components", maxBundleComponents))
+		return false
+	}
+
+	seen := make(map[int]bool, len(components))
+	for i, c := range components {
+		switch {
+		case c.ProductID < 1:
+			respondWithError(h.logger, w, http.StatusBadRequest, fmt.Sprintf("Component %d: product_id is required", i))
+		case c.ProductID == bundleID:
+			respondWithError(h.logger, w, http.StatusBadRequest, "A bundle can't contain itself")
+		case seen[c.ProductID]:
+			respondWithError(h.logger, w, http.StatusBadRequest, fmt.Sprintf("Component %d: product %d is listed twice", i, c.ProductID))
+		case c.Quantity < 1:
+			respondWithError(h.logger, w, http.StatusBadRequest, fmt.Sprintf("Component %d: quantity must be positive", i))
+		default:
+			seen[c.ProductID] = true
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// set stores the components and returns the bundle as stored
+func (h *BundleHandler) set(w http.ResponseWriter, r *http.Request, id int, components []models.BundleComponent, action string) (*models.Bundle, bool) {
+	ctx := r.Context()
+
+	if err := h.repo.Set(ctx, id, components); err != nil {
+		if errors.Is(err, repository.ErrNestedBundle) || errors.Is(err, repository.ErrUnknownComponent) {
+			respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+			return nil, false
+		}
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return nil, false
+		}
+		h.respondWithRepoError(w, err, action, id)
+		return nil, false
+	}
+
+	bundle, err := h.repo.Get(ctx, id, "")
+	if err != nil {
+		h.respondWithRepoError(w, err, action, id)
+		return nil, false
+	}
+	return bundle, true
+}
+
+func (h *BundleHandler) respondWithRepoError(w http.ResponseWriter, err error, action string, id int) {
+	if err.Error() == "bundle not found" {
+		respondWithError(h.logger, w, http.StatusNotFound, "Bundle not found")
+		return
+	}
+	h.logger.Error("failed to "+action+" bundle", "error", err, "product_id", id)
+	respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action+" bundle")
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"{{MODULE_NAME}}/internal/changefeed"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type ChangeHandler struct {
+	feed    *changefeed.Feed // nil unless CHANGE_FEED is set
+	regions repository.ProductRegionRepository
+	logger  *slog.Logger
+}
+
+func NewChangeHandler(feed *changefeed.Feed, regions repository.ProductRegionRepository, logger *slog.Logger) *ChangeHandler {
+	return &ChangeHandler{feed: feed, regions: regions, logger: logger}
+}
+
+// ListChanges handles GET /api/v1/products/changes
+// It returns the product changes after a cursor, in commit order
+//
+//	@Summary		List product changes
+//	@Description	Incremental sync: the product changes committed after since, oldest first. Each change is an upsert with the product's new state, a stock change with its new quantity, or a delete tombstone. Pass next_cursor as since to continue; has_more says whether to fetch again right away. since=now returns no changes but the current cursor, to take before copying the catalog; without since the feed starts at the oldest retained change. A cursor older than OUTBOX_RETENTION is gone, and the consumer must resync. With a sales region, products sold elsewhere appear only as delete tombstones.
+//	@Tags			products
+//	@Produce		json
+//	@Param			since	query		string	false	"Cursor of the last change read, or now"
+//	@Param			limit	query		int		false	"Number of changes to return (max 1000)"	default(100)
+//	@Param			region	query		string	false	"Only changes of products sold in this region"
+//	@Success		200		{object}	models.SuccessResponse{data=models.ChangeFeed}	"Changes and the cursor to continue from"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid cursor or region"
+//	@Failure		403		{object}	models.ErrorResponse	"API key limited to another region"
+//	@Failure		410		{object}	models.ErrorResponse	"Cursor expired, resync"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Failure		503		{object}	models.ErrorResponse	"The change feed is disabled"
+//	@Router			/products/changes [get]
+func (h *ChangeHandler) ListChanges(w http.ResponseWriter, r *http.Request) {
+	if h.feed == nil {
+		respondWithError(h.logger, w, http.StatusServiceUnavailable, "The change feed is disabled, set CHANGE_FEED to enable it")
+		return
+	}
+
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 1000)
+		}
+	}
+
+	region, ok := salesRegion(h.logger, w, r)
+	if !ok {
+		return
+	}
+
+	feed, err := h.feed.Read(r.Context(), r.URL.Query().Get("since"), limit)
+	if err == nil && region != "" {
+		err = changefeed.InRegion(r.Context(), feed, region, h.regions)
+	}
+	switch {
+	case errors.Is(err, changefeed.ErrInvalidCursor):
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid cursor")
+		return
+	case errors.Is(err, changefeed.ErrCursorExpired):
+		respondWithError(h.logger, w, http.StatusGone, "Cursor expired, the changes after it are no longer retained; resync from since=now")
+		return
+	case err != nil:
+		h.logger.Error("failed to read change feed", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve changes")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Changes retrieved successfully", feed)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
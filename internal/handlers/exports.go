@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/export"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/operations"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type ExportHandler struct {
+	repo      repository.ExportRepository
+	exporter  *export.Exporter // nil without a blob store or on a read-only instance
+	runner    *operations.Runner
+	auditRepo repository.AuditRepository
+	logger    *slog.Logger
+}
+
+func NewExportHandler(repo repository.ExportRepository, exporter *export.Exporter, runner *operations.Runner, auditRepo repository.AuditRepository, logger *slog.Logger) *ExportHandler {
+	return &ExportHandler{repo: repo, exporter: exporter, runner: runner, auditRepo: auditRepo, logger: logger}
+}
+
+// ExportRequest starts an ad-hoc export. Every field is optional: the format
+// may also be given as ?format= and defaults to EXPORT_FORMAT, and an empty
+// filter exports the full catalog.
+type ExportRequest struct {
+	Format   string `json:"format,omitempty" example:"parquet"`
+	Category string `json:"category,omitempty" example:"books"`
+	Tag      string `json:"tag,omitempty" example:"sale"`
+}
+
+// TriggerExport handles POST /api/v1/admin/exports
+// It starts a catalog export to the blob store as an operation
+//
+//	@Summary		Export the catalog
+//	@Description	Start exporting products, optionally filtered by category and/or tag, as gzip-compressed CSV or Parquet to the blob store, under a dt=YYYY-MM-DD partition. Returns the operation running the export; poll its Location for progress and, once it has succeeded, the link to the export. One export runs at a time.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			format	query		string			false	"csv or parquet, when the body has no format"	Enums(csv, parquet)
+//	@Param			export	body		ExportRequest	false	"Format and filter"
+//	@Success		202		{object}	models.SuccessResponse{data=models.Operation}	"Export started"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid request body or format"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		409		{object}	models.ErrorResponse	"An export is already running"
+//	@Failure		503		{object}	models.ErrorResponse	"Exports are disabled"
+//	@Router			/admin/exports [post]
+func (h *ExportHandler) TriggerExport(w http.ResponseWriter, r *http.Request) {
+	if h.exporter == nil || h.runner == nil {
+		respondWithError(h.logger, w, http.StatusServiceUnavailable, "Exports are disabled, set BLOB_STORE to enable them")
+		return
+	}
+
+	var req ExportRequest
+	if err := newDecoder(r).Decode(&req); err != nil && err != io.EOF {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Format == "" {
+		req.Format = r.URL.Query().Get("format")
+	}
+	req.Category = strings.TrimSpace(req.Category)
+	req.Tag = strings.TrimSpace(req.Tag)
+
+	run, err := h.exporter.Begin(r.Context(), req.Format, models.ExportFilter{Category: req.Category, Tag: req.Tag})
+	switch {
+	case errors.Is(err, export.ErrUnknownFormat):
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid format, must be csv or parquet")
+		return
+	case errors.Is(err, export.ErrRunning):
+		respondWithError(h.logger, w, http.StatusConflict, "An export is already running")
+		return
+	case err != nil:
+		h.logger.Error("failed to start export", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to start export")
+		return
+	}
+
+	op, err := h.runner.Run(r.Context(), models.OperationExport, func(ctx context.Context, p *operations.Progress) (string, error) {
+		// The run tells how far a failed or cancelled export got, too
+		err := h.exporter.Export(ctx, run, p.Of("products exported"))
+		return fmt.Sprintf("/api/v1/admin/exports/%d", run.ID), err
+	})
+	if err != nil {
+		h.exporter.Abort(r.Context(), run, err)
+		h.logger.Error("failed to start export", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to start export")
+		return
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{"format": run.Format, "filter": run.ExportFilter, "key": run.Key, "operation": op.ID})
+	entry := &models.AuditEntry{
+		Action:     "export.trigger",
+		Actor:      r.RemoteAddr,
+		EntityType: "export",
+		EntityID:   strconv.Itoa(run.ID),
+		Details:    details,
+	}
+	if err := h.auditRepo.Create(r.Context(), entry); err != nil {
+		h.logger.Error("failed to record export in audit log", "error", err)
+	}
+
+	response := models.NewSuccessResponse(http.StatusAccepted, "Export started", op)
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/operations/%d", op.ID))
+	respondWithJSON(h.logger, w, http.StatusAccepted, response)
+}
+
+// ListExports handles GET /api/v1/admin/exports
+// It returns the history of exports, most recent first
+//
+//	@Summary		List exports
+//	@Description	Get a paginated history of scheduled and ad-hoc catalog exports with their status, object, and size
+//	@Tags			admin
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(20)
+//	@Param			offset	query		int	false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.ExportRun}	"Export history with pagination metadata"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/exports [get]
+func (h *ExportHandler) ListExports(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 20
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	runs, err := h.repo.List(ctx, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list exports", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve exports")
+		return
+	}
+	if runs == nil {
+		runs = []*models.ExportRun{}
+	}
+
+	total, err := h.repo.Count(ctx)
+	if err != nil {
+		h.logger.Error("failed to count exports", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count exports")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Exports retrieved successfully", runs, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// GetExport handles GET /api/v1/admin/exports/{id}
+// It returns the status of one export
+//
+//	@Summary		Get export
+//	@Description	Get an export's status (running, succeeded, or failed), with its object, rows, and size once it has finished
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"Export ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.ExportRun}	"Export"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid export ID"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	models.ErrorResponse	"Export not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/exports/{id} [get]
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid export ID")
+		return
+	}
+
+	run, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		if err.Error() == "export run not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Export not found")
+			return
+		}
+		h.logger.Error("failed to get export", "id", id, "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve export")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Export retrieved successfully", run)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/forecast"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type ForecastHandler struct {
+	repo     repository.ForecastRepository
+	forecast *forecast.Service
+	logger   *slog.Logger
+}
+
+func NewForecastHandler(repo repository.ForecastRepository, forecastService *forecast.Service, logger *slog.Logger) *ForecastHandler {
+	return &ForecastHandler{repo: repo, forecast: forecastService, logger: logger}
+}
+
+// LeadTimeRequest sets a product's supplier lead time
+type LeadTimeRequest struct {
+	LeadTimeDays *int `json:"lead_time_days" example:"14"`
+}
+
+// GetForecast handles GET /api/v1/products/{id}/forecast
+// It returns a product's demand forecast
+//
+//	@Summary		Get demand forecast
+//	@Description	Get a product's demand forecast as of the last demand-forecast job, or computed now if the job hasn't covered it yet: the moving average and trend of its daily demand, the units stock movements took out, projected over the horizon and its lead time, with the reorder point and order-up-to level
+//	@Tags			products
+//	@Produce		json
+//	@Param			id	path		int	true	"Product ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.Forecast}	"Forecast"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid product ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/forecast [get]
+func (h *ForecastHandler) GetForecast(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	f, err := h.forecast.Product(r.Context(), id)
+	if err != nil {
+		h.respondWithServiceError(w, err, "retrieve forecast", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Forecast retrieved successfully", f)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// SetLeadTime handles PUT /api/v1/products/{id}/lead-time
+// It sets a product's supplier lead time
+//
+//	@Summary		Set lead time
+//	@Description	Set how many days a product's supplier takes to deliver, in place of FORECAST_LEAD_TIME, and recompute its forecast
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int				true	"Product ID"
+//	@Param			lead_time	body		LeadTimeRequest	true	"Lead time"
+//	@Success		200			{object}	models.SuccessResponse{data=models.Forecast}	"Recomputed forecast"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid lead time"
+//	@Failure		404			{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/lead-time [put]
+func (h *ForecastHandler) SetLeadTime(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var req LeadTimeRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if req.LeadTimeDays == nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "lead_time_days is required")
+		return
+	}
+
+	f, err := h.forecast.SetLeadTime(r.Context(), id, *req.LeadTimeDays)
+	if err != nil {
+		h.respondWithServiceError(w, err, "set lead time", id)
+		return
+	}
+
+	h.logger.Info("lead time set", "product_id", id, "lead_time_days", f.LeadTimeDays)
+	response := models.NewSuccessResponse(http.StatusOK, "Lead time set successfully", f)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// DeleteLeadTime handles DELETE /api/v1/products/{id}/lead-time
+// It returns a product to the default lead time
+//
+//	@Summary		Reset lead time
+//	@Description	Remove a product's own supplier lead time, so FORECAST_LEAD_TIME applies, and recompute its forecast
+//	@Tags			products
+//	@Produce		json
+//	@Param			id	path		int	true	"Product ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.Forecast}	"Recomputed forecast"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid product ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Product has no lead time of its own"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/lead-time [delete]
+func (h *ForecastHandler) DeleteLeadTime(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	f, err := h.forecast.ResetLeadTime(r.Context(), id)
+	if err != nil {
+		h.respondWithServiceError(w, err, "reset lead time", id)
+		return
+	}
+
+	h.logger.Info("lead time reset", "product_id", id, "lead_time_days", f.LeadTimeDays)
+	response := models.NewSuccessResponse(http.StatusOK, "Lead time reset successfully", f)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// ListReorderSuggestions handles GET /api/v1/products/reorder-suggestions
+// It returns the products to reorder
+//
+//	@Summary		List reorder suggestions
+//	@Description	Get a paginated list of the products at or below the reorder point of their last forecast, largest shortfall first, with the quantity bringing each up to its order-up-to level
+//	@Tags			products
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int	false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.ReorderSuggestion}	"Reorder suggestions with pagination metadata"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/reorder-suggestions [get]
+func (h *ForecastHandler) ListReorderSuggestions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	suggestions, err := h.repo.ListReorder(ctx, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list reorder suggestions", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve reorder suggestions")
+		return
+	}
+
+	total, err := h.repo.CountReorder(ctx)
+	if err != nil {
+		h.logger.Error("failed to count reorder suggestions", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count reorder suggestions")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Reorder suggestions retrieved successfully", suggestions, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+func (h *ForecastHandler) respondWithServiceError(w http.ResponseWriter, err error, action string, id int) {
+	switch {
+	case err.Error() == "product not found":
+		respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+	case err.Error() == "lead time not found":
+		respondWithError(h.logger, w, http.StatusNotFound, "Product has no lead time of its own")
+	case errors.Is(err, forecast.ErrInvalidLeadTime):
+		respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error("failed to "+action, "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action)
+	}
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/inbox"
+	"{{MODULE_NAME}}/internal/inventory"
+	"{{MODULE_NAME}}/internal/lots"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/pricing"
+	"{{MODULE_NAME}}/internal/reports"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/repricing"
+	"{{MODULE_NAME}}/internal/search"
+	"{{MODULE_NAME}}/internal/units"
+	"{{MODULE_NAME}}/internal/valuation"
+)
+
+// Golden files hold the canonical response of each request in
+// TestGoldenResponses. When a response changes on purpose, rewrite them with
+//
+//	go test ./internal/handlers -run TestGoldenResponses -update
+//
+// and review the diff like any other change to the API.
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of TestGoldenResponses")
+
+// goldenHeaders are the response headers recorded in golden files; the rest
+// vary per run or are set by middleware
+var goldenHeaders = []string{"Content-Type", "Location"}
+
+var (
+	goldenTimestamp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$`)
+	goldenHTTPDate  = regexp.MustCompile(`^[A-Z][a-z]{2}, \d{2} [A-Z][a-z]{2} \d{4} \d{2}:\d{2}:\d{2} GMT$`)
+)
+
+// goldenVolatile are the placeholders of fields whose values differ per run:
+// random IDs, durations counted from now, and how long queries took. Serial
+// IDs are kept: each run starts from an empty database, so they're stable.
+var goldenVolatile = map[string]string{
+	"uid":               "<id>",
+	"request_id":        "<id>",
+	"remaining_seconds": "<seconds>",
+	"duration":          "<duration>",
+	"age_seconds":       "<seconds>",
+}
+
+// normalizeGolden replaces the values of a decoded JSON document that differ
+// per run, timestamps, random IDs, and durations from now, by placeholders
+func normalizeGolden(key string, v interface{}) interface{} {
+	if placeholder, ok := goldenVolatile[key]; ok && v != nil && v != "" {
+		return placeholder
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			v[k] = normalizeGolden(k, value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalizeGolden(key, value)
+		}
+		return v
+	case string:
+		if goldenTimestamp.MatchString(v) || goldenHTTPDate.MatchString(v) {
+			return "<timestamp>"
+		}
+	}
+	return v
+}
+
+// goldenResponse is what a golden file records of a response
+type goldenResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+}
+
+// canonicalResponse returns the normalized, indented JSON of a response
+func canonicalResponse(t *testing.T, w *httptest.ResponseRecorder) []byte {
+	t.Helper()
+
+	golden := goldenResponse{Status: w.Code, Headers: make(map[string]string)}
+	for _, name := range goldenHeaders {
+		if value := w.Header().Get(name); value != "" {
+			golden.Headers[name] = value
+		}
+	}
+	if w.Body.Len() > 0 {
+		var body interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("response isn't JSON: %v: %s", err, w.Body.String())
+		}
+		golden.Body = normalizeGolden("", body)
+	}
+
+	return encodeGolden(t, golden)
+}
+
+// encodeGolden returns v as indented JSON, with maps' keys sorted and the
+// placeholders' angle brackets as they are
+func encodeGolden(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// goldenWebhookSecret signs the order webhooks of TestGoldenResponses
+const goldenWebhookSecret = "golden-secret"
+
+// newGoldenRouter serves the routes of TestGoldenResponses on a fresh SQLite
+// database, with the repositories and services the API uses. Middleware
+// isn't installed: admin routes are served without auth, and responses
+// aren't cached.
+func newGoldenRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(t.TempDir(), "golden.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	unitTable, err := units.Load(context.Background(), repository.NewUnitRepository(db))
+	if err != nil {
+		t.Fatalf("failed to load units: %v", err)
+	}
+	priceRules, err := pricing.New(pricing.Config{
+		Currency:         "EUR",
+		Rates:            map[string]string{"DE": "19", "DE/tools": "7", "US": "0"},
+		InclusiveRegions: []string{"DE"},
+	})
+	if err != nil {
+		t.Fatalf("failed to load pricing rules: %v", err)
+	}
+	facets := models.FacetSpec{PriceBounds: []float64{10, 100}, LowStock: 5}
+
+	products := repository.NewProductRepository(db)
+	trash := repository.NewTrashRepository(db)
+	notes := repository.NewProductNoteRepository(db)
+	bundles := repository.NewBundleRepository(db)
+	audit := repository.NewAuditRepository(db)
+	reportRepo := repository.NewReportRepository(db)
+	lotService := lots.NewService(repository.NewLotRepository(db), products, db, discardPublisher{}, 7*24*time.Hour, testLogger)
+	inventoryService := inventory.NewService(products, bundles, repository.NewOrderRepository(db), db, lotService, unitTable, discardPublisher{}, 5, testLogger)
+	valuer := valuation.NewService(repository.NewValuationRepository(db), products, db, discardPublisher{}, "fifo", testLogger)
+
+	productHandler := NewProductHandler(products, repository.NewSavedSearchRepository(db), trash, nil, repository.NewProductSlugRepository(db), notes, db, discardPublisher{}, nil, unitTable, nil, facets, testLogger)
+	noteHandler := NewNoteHandler(notes, products, db, discardPublisher{}, testLogger)
+	trashHandler := NewTrashHandler(trash, products, db, discardPublisher{}, 30*24*time.Hour, testLogger)
+	statsHandler := NewStatsHandler(repository.NewProductStatsRepository(db), valuer, 90*24*time.Hour, testLogger)
+	searchHandler := NewSearchHandler(search.NewPostgres(repository.NewSearchRepository(db), 0), facets, nil, nil, audit, testLogger)
+	pricingHandler := NewPricingHandler(products, priceRules, repricing.NewService(products, audit, db, discardPublisher{}, priceRules, testLogger), testLogger)
+	availabilityHandler := NewAvailabilityHandler(products, 5, testLogger)
+	relatedHandler := NewRelatedHandler(products, RelatedLimits{DefaultLimit: 10, MaxLimit: 50, PriceBand: 0.5}, testLogger)
+	bundleHandler := NewBundleHandler(bundles, testLogger)
+	promotionHandler := NewPromotionHandler(repository.NewPromotionRepository(db), nil, testLogger)
+	regionHandler := NewRegionHandler(repository.NewProductRegionRepository(db), products, db, discardPublisher{}, audit, testLogger)
+	reportHandler := NewReportHandler(reportRepo, reports.NewService(reportRepo, audit, db, reports.Config{Timeout: 5 * time.Second, MaxRows: 100}, testLogger), testLogger)
+	integrationHandler := NewIntegrationHandler(inventoryService, inbox.New(repository.NewInboxRepository(db), db), repository.NewSyncStateRepository(db), goldenWebhookSecret, testLogger)
+
+	r := chi.NewRouter()
+	r.Route("/api/v1/products", func(r chi.Router) {
+		r.Get("/", productHandler.ListProducts)
+		r.Post("/", productHandler.CreateProduct)
+		r.Get("/stats", statsHandler.Summary)
+		r.Get("/search", searchHandler.SearchProducts)
+		r.Get("/suggest", searchHandler.SuggestProducts)
+		r.Get("/{id}", productHandler.GetProduct)
+		r.Get("/slug/{slug}", productHandler.GetProductBySlug)
+		r.Put("/{id}", productHandler.UpdateProduct)
+		r.Delete("/{id}", productHandler.DeleteProduct)
+		r.Get("/{id}/stats", statsHandler.ProductStats)
+		r.Get("/{id}/price", pricingHandler.GetPrice)
+		r.Get("/{id}/related", relatedHandler.GetRelated)
+		r.Get("/{id}/availability", availabilityHandler.GetAvailability)
+		r.Get("/{id}/notes", noteHandler.ListNotes)
+		r.Post("/{id}/notes", noteHandler.CreateNote)
+		r.Get("/{id}/notes/{noteID}", noteHandler.GetNote)
+		r.Put("/{id}/notes/{noteID}", noteHandler.UpdateNote)
+		r.Delete("/{id}/notes/{noteID}", noteHandler.DeleteNote)
+	})
+	r.Route("/api/v1/trash", func(r chi.Router) {
+		r.Get("/", trashHandler.ListTrash)
+		r.Post("/{id}/restore", trashHandler.RestoreProduct)
+		r.Delete("/{id}", trashHandler.PurgeProduct)
+	})
+	r.Get("/api/v1/stats/abc-analysis", statsHandler.ABCAnalysis)
+	r.Route("/api/v1/bundles", func(r chi.Router) {
+		r.Get("/", bundleHandler.ListBundles)
+		r.Post("/", bundleHandler.CreateBundle)
+		r.Get("/{id}", bundleHandler.GetBundle)
+		r.Put("/{id}", bundleHandler.UpdateBundle)
+		r.Delete("/{id}", bundleHandler.DeleteBundle)
+	})
+	r.Route("/api/v1/promotions", func(r chi.Router) {
+		r.Get("/", promotionHandler.ListPromotions)
+		r.Post("/", promotionHandler.CreatePromotion)
+		r.Get("/{id}", promotionHandler.GetPromotion)
+		r.Put("/{id}", promotionHandler.UpdatePromotion)
+		r.Delete("/{id}", promotionHandler.DeletePromotion)
+	})
+	r.Route("/api/v1/reports", func(r chi.Router) {
+		r.Get("/", reportHandler.ListReports)
+		r.Get("/{name}", reportHandler.GetReport)
+		r.Put("/{name}", reportHandler.PutReport)
+		r.Delete("/{name}", reportHandler.DeleteReport)
+		r.Post("/{name}/run", reportHandler.RunReport)
+	})
+	r.Get("/api/v1/admin/product-regions", regionHandler.ListRegions)
+	r.Post("/api/v1/admin/product-regions", regionHandler.AssignRegions)
+	r.Post("/api/v1/integrations/orders", integrationHandler.ReceiveOrder)
+	return r
+}
+
+// TestGoldenResponses sends a fixed sequence of requests, each against the
+// state the ones before left, and compares every response with its golden
+// file in testdata/golden, so that unintended changes to the shape of
+// responses fail here before clients notice them. Webhooks are signed with
+// goldenWebhookSecret and delivered with the request's name as their ID
+func TestGoldenResponses(t *testing.T) {
+	router := newGoldenRouter(t)
+
+	requests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"create_product", http.MethodPost, "/api/v1/products", `{"sku":"GLD-1","name":"Golden Hammer","description":"Forged steel","category":"tools","quantity":8,"unit_price":24.5,"tags":["Steel","hand"]}`},
+		{"create_second_product", http.MethodPost, "/api/v1/products", `{"sku":"GLD-2","name":"Golden Nails","category":"fasteners","unit":"kg","quantity":2,"unit_price":4}`},
+		{"create_product_duplicate_sku", http.MethodPost, "/api/v1/products", `{"sku":"GLD-1","name":"Another Hammer"}`},
+		{"create_product_without_name", http.MethodPost, "/api/v1/products", `{"sku":"GLD-3"}`},
+		{"create_product_malformed", http.MethodPost, "/api/v1/products", `{"sku":`},
+		{"create_product_unknown_unit", http.MethodPost, "/api/v1/products", `{"sku":"GLD-4","name":"Gold Dust","unit":"bushel"}`},
+		{"get_product", http.MethodGet, "/api/v1/products/1", ""},
+		{"get_product_not_found", http.MethodGet, "/api/v1/products/999", ""},
+		{"get_product_invalid_id", http.MethodGet, "/api/v1/products/abc", ""},
+		{"get_product_by_slug", http.MethodGet, "/api/v1/products/slug/golden-hammer", ""},
+		{"list_products", http.MethodGet, "/api/v1/products?limit=10", ""},
+		{"list_products_paged", http.MethodGet, "/api/v1/products?limit=1&offset=1", ""},
+		{"list_products_facets", http.MethodGet, "/api/v1/products?facets=category,price_range,status", ""},
+		{"list_products_unknown_facet", http.MethodGet, "/api/v1/products?facets=color", ""},
+		{"update_product", http.MethodPut, "/api/v1/products/1", `{"sku":"GLD-1","name":"Golden Hammer","description":"Forged steel, hickory handle","category":"tools","quantity":6,"unit_price":26}`},
+		{"update_product_not_found", http.MethodPut, "/api/v1/products/999", `{"sku":"GLD-9","name":"Nothing"}`},
+		{"create_note", http.MethodPost, "/api/v1/products/1/notes", `{"body":"Supplier raises prices in March"}`},
+		{"get_note", http.MethodGet, "/api/v1/products/1/notes/1", ""},
+		{"update_note", http.MethodPut, "/api/v1/products/1/notes/1", `{"body":"Supplier raises prices in April"}`},
+		{"list_notes", http.MethodGet, "/api/v1/products/1/notes", ""},
+		{"delete_note", http.MethodDelete, "/api/v1/products/1/notes/1", ""},
+		{"delete_product", http.MethodDelete, "/api/v1/products/2", ""},
+		{"get_deleted_product", http.MethodGet, "/api/v1/products/2", ""},
+		{"list_trash", http.MethodGet, "/api/v1/trash", ""},
+		{"restore_product", http.MethodPost, "/api/v1/trash/2/restore", ""},
+		{"purge_product_not_in_trash", http.MethodDelete, "/api/v1/trash/2", ""},
+		{"stats_summary", http.MethodGet, "/api/v1/products/stats", ""},
+		{"product_stats", http.MethodGet, "/api/v1/products/1/stats", ""},
+		{"product_stats_not_found", http.MethodGet, "/api/v1/products/999/stats", ""},
+		{"search_products", http.MethodGet, "/api/v1/products/search?q=golden&facets=category", ""},
+		{"suggest_products", http.MethodGet, "/api/v1/products/suggest?q=gol", ""},
+		{"suggest_products_invalid_region", http.MethodGet, "/api/v1/products/suggest?q=gol&region=de%20fr", ""},
+		{"create_third_product", http.MethodPost, "/api/v1/products", `{"sku":"GLD-5","name":"Golden Toolkit","category":"tools","unit_price":30}`},
+		{"related_products", http.MethodGet, "/api/v1/products/1/related", ""},
+		{"related_products_not_found", http.MethodGet, "/api/v1/products/999/related", ""},
+		{"get_price", http.MethodGet, "/api/v1/products/1/price?tax_region=DE", ""},
+		{"get_price_tax_exclusive", http.MethodGet, "/api/v1/products/1/price?tax_region=DE&tax=exclusive", ""},
+		{"get_price_without_region", http.MethodGet, "/api/v1/products/1/price", ""},
+		{"get_price_unknown_region", http.MethodGet, "/api/v1/products/1/price?tax_region=FR", ""},
+		{"get_availability", http.MethodGet, "/api/v1/products/2/availability", ""},
+		{"get_availability_not_found", http.MethodGet, "/api/v1/products/999/availability", ""},
+		{"assign_regions", http.MethodPost, "/api/v1/admin/product-regions", `{"product_ids":[3],"regions":["de"],"action":"add"}`},
+		{"assign_regions_invalid_action", http.MethodPost, "/api/v1/admin/product-regions", `{"product_ids":[3],"regions":["de"],"action":"merge"}`},
+		{"list_regions", http.MethodGet, "/api/v1/admin/product-regions", ""},
+		{"related_products_in_region", http.MethodGet, "/api/v1/products/1/related?region=us", ""},
+		{"create_bundle", http.MethodPost, "/api/v1/bundles", `{"product_id":3,"components":[{"product_id":1,"quantity":1},{"product_id":2,"quantity":1}]}`},
+		{"create_bundle_existing", http.MethodPost, "/api/v1/bundles", `{"product_id":3,"components":[{"product_id":1,"quantity":1}]}`},
+		{"create_bundle_of_itself", http.MethodPost, "/api/v1/bundles", `{"product_id":1,"components":[{"product_id":1,"quantity":1}]}`},
+		{"get_bundle", http.MethodGet, "/api/v1/bundles/3", ""},
+		{"update_bundle", http.MethodPut, "/api/v1/bundles/3", `{"components":[{"product_id":1,"quantity":2}]}`},
+		{"list_bundles", http.MethodGet, "/api/v1/bundles", ""},
+		{"create_promotion", http.MethodPost, "/api/v1/promotions", `{"name":"Tool week","kind":"percentage","value":10,"scope":"category","target":"tools","priority":1}`},
+		{"create_promotion_invalid_kind", http.MethodPost, "/api/v1/promotions", `{"name":"Free","kind":"gift","value":1,"scope":"all"}`},
+		{"get_promotion", http.MethodGet, "/api/v1/promotions/1", ""},
+		{"update_promotion", http.MethodPut, "/api/v1/promotions/1", `{"name":"Tool week","kind":"percentage","value":15,"scope":"category","target":"tools","stackable":true,"priority":1}`},
+		{"list_promotions", http.MethodGet, "/api/v1/promotions", ""},
+		{"receive_order", http.MethodPost, "/api/v1/integrations/orders", `{"order_id":"ORD-1","lines":[{"sku":"GLD-1","quantity":2,"unit":"each"}]}`},
+		{"receive_order_again", http.MethodPost, "/api/v1/integrations/orders", `{"order_id":"ORD-1","lines":[{"sku":"GLD-1","quantity":2,"unit":"each"}]}`},
+		{"receive_order_unknown_sku", http.MethodPost, "/api/v1/integrations/orders", `{"order_id":"ORD-2","lines":[{"sku":"GLD-404","quantity":1,"unit":"each"}]}`},
+		{"receive_order_insufficient_stock", http.MethodPost, "/api/v1/integrations/orders", `{"order_id":"ORD-3","lines":[{"sku":"GLD-2","quantity":50,"unit":"kg"}]}`},
+		{"product_stats_after_order", http.MethodGet, "/api/v1/products/1/stats", ""},
+		{"abc_analysis", http.MethodGet, "/api/v1/stats/abc-analysis", ""},
+		{"put_report", http.MethodPut, "/api/v1/reports/low_stock", `{"description":"Products with little stock","query":"SELECT sku, quantity FROM products WHERE quantity <= :max ORDER BY sku","params":[{"name":"max","type":"int"}]}`},
+		{"put_report_forbidden_table", http.MethodPut, "/api/v1/reports/keys", `{"query":"SELECT * FROM api_keys","params":[]}`},
+		{"get_report", http.MethodGet, "/api/v1/reports/low_stock", ""},
+		{"list_reports", http.MethodGet, "/api/v1/reports", ""},
+		{"run_report", http.MethodPost, "/api/v1/reports/low_stock/run", `{"params":{"max":5}}`},
+		{"run_report_missing_param", http.MethodPost, "/api/v1/reports/low_stock/run", `{"params":{}}`},
+		{"run_report_not_found", http.MethodPost, "/api/v1/reports/missing/run", ""},
+		{"delete_report", http.MethodDelete, "/api/v1/reports/low_stock", ""},
+		{"delete_promotion", http.MethodDelete, "/api/v1/promotions/1", ""},
+		{"delete_bundle", http.MethodDelete, "/api/v1/bundles/3", ""},
+	}
+
+	for _, req := range requests {
+		t.Run(req.name, func(t *testing.T) {
+			r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+			if req.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			if strings.HasPrefix(req.path, "/api/v1/integrations/") {
+				mac := hmac.New(sha256.New, []byte(goldenWebhookSecret))
+				mac.Write([]byte(req.body))
+				r.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+				r.Header.Set(WebhookIDHeader, req.name)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			got := canonicalResponse(t, w)
+			path := filepath.Join("testdata", "golden", req.name+".json")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read golden file, run with -update to create it: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s %s: response differs from %s; if the change is intended, run with -update\ngot:\n%s\nwant:\n%s", req.method, req.path, path, got, want)
+			}
+		})
+	}
+}
+
+func TestNormalizeGolden(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal([]byte(`{"uid":"prod_01J9ZQ4V8X2M6T0K3R5N7P9B1C","id":7,"timestamp":"2026-10-15T09:30:00.123456789Z","visible_from":"2026-10-15T09:30:00+02:00","name":"2026","slug":"","items":[{"uid":""},{"created_at":"2026-10-15T09:30:00Z","remaining_seconds":59}]}`), &doc)
+
+	var got bytes.Buffer
+	enc := json.NewEncoder(&got)
+	enc.SetEscapeHTML(false)
+	enc.Encode(normalizeGolden("", doc))
+	want := `{"id":7,"items":[{"uid":""},{"created_at":"<timestamp>","remaining_seconds":"<seconds>"}],"name":"2026","slug":"","timestamp":"<timestamp>","uid":"<id>","visible_from":"<timestamp>"}` + "\n"
+	if got.String() != want {
+		t.Errorf("normalizeGolden = %s, want %s", got.String(), want)
+	}
+}
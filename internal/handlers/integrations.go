@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/inbox"
+	"{{MODULE_NAME}}/internal/inventory"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the raw request body,
+// optionally prefixed with "sha256="
+const SignatureHeader = "X-Webhook-Signature"
+
+// WebhookIDHeader carries the ID of a webhook delivery, the same on its
+// retries
+const WebhookIDHeader = "X-Webhook-Id"
+
+// maxWebhookBody caps webhook payloads; orders are small
+const maxWebhookBody = 1 << 20
+
+type IntegrationHandler struct {
+	inventory     *inventory.Service
+	inbox         *inbox.Inbox
+	syncStates    repository.SyncStateRepository
+	webhookSecret []byte
+	logger        *slog.Logger
+}
+
+// NewIntegrationHandler creates the handler for inbound webhooks and catalog
+// sync status. Webhooks are rejected unless signed with webhookSecret, and
+// applied once per delivery ID through box.
+func NewIntegrationHandler(inventory *inventory.Service, box *inbox.Inbox, syncStates repository.SyncStateRepository, webhookSecret string, logger *slog.Logger) *IntegrationHandler {
+	return &IntegrationHandler{
+		inventory:     inventory,
+		inbox:         box,
+		syncStates:    syncStates,
+		webhookSecret: []byte(webhookSecret),
+		logger:        logger,
+	}
+}
+
+// ReceiveOrder handles POST /api/v1/integrations/orders
+// It decrements stock for an order placed on the e-commerce platform
+//
+//	@Summary		Receive order webhook
+//	@Description	Apply an order-placed webhook to stock. All lines are applied atomically and each delivery ID and order ID is applied at most once; redeliveries return 200 without changing stock.
+//	@Tags			integrations
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Webhook-Signature	header		string					true	"Hex HMAC-SHA256 of the body, optionally prefixed with sha256="
+//	@Param			X-Webhook-Id		header		string					false	"Delivery ID, the same on retries"
+//	@Param			order				body		models.Order			true	"Order"
+//	@Param			dry_run				query		bool					false	"Validate the order against current stock without applying it"
+//	@Success		200					{object}	models.SuccessResponse	"Order applied or already processed"
+//	@Failure		400					{object}	models.ErrorResponse	"Malformed or invalid order"
+//	@Failure		401					{object}	models.ErrorResponse	"Missing or invalid signature"
+//	@Failure		422					{object}	models.ErrorResponse	"Unknown SKU or insufficient stock"
+//	@Failure		500					{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/integrations/orders [post]
+func (h *IntegrationHandler) ReceiveOrder(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody+1))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	if len(body) > maxWebhookBody {
+		respondWithError(h.logger, w, http.StatusRequestEntityTooLarge, "Request body too large")
+		return
+	}
+
+	if !h.validSignature(body, r.Header.Get(SignatureHeader)) {
+		h.logger.Warn("rejected order webhook with invalid signature", "remote_addr", r.RemoteAddr)
+		respondWithError(h.logger, w, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+
+	var order models.Order
+	if err := json.Unmarshal(body, &order); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var products []*models.Product
+	err = h.inbox.Process(r.Context(), "webhook", r.Header.Get(WebhookIDHeader), func(ctx context.Context) error {
+		var err error
+		products, err = h.inventory.ApplyOrder(ctx, "webhook", &order)
+		return err
+	})
+	switch {
+	case err == nil && database.IsDryRun(r.Context()):
+		response := models.NewSuccessResponse(http.StatusOK, "Dry run: order would be applied", products)
+		respondWithJSON(h.logger, w, http.StatusOK, response)
+
+	case err == nil:
+		response := models.NewSuccessResponse(http.StatusOK, "Order applied successfully", products)
+		respondWithJSON(h.logger, w, http.StatusOK, response)
+
+	case errors.Is(err, inventory.ErrDuplicateOrder), errors.Is(err, inbox.ErrDuplicate):
+		h.logger.Info("ignoring duplicate order webhook", "order_id", order.ID)
+		response := models.NewSuccessResponse(http.StatusOK, "Order already processed", nil)
+		respondWithJSON(h.logger, w, http.StatusOK, response)
+
+	case errors.Is(err, inventory.ErrInvalidOrder):
+		respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+
+	case errors.Is(err, inventory.ErrUnknownSKU), errors.Is(err, repository.ErrInsufficientStock):
+		h.logger.Warn("order could not be applied", "order_id", order.ID, "error", err)
+		respondWithError(h.logger, w, http.StatusUnprocessableEntity, err.Error())
+
+	default:
+		h.logger.Error("failed to apply order", "order_id", order.ID, "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to apply order")
+	}
+}
+
+// SyncStatus handles GET /api/v1/integrations/sync-status
+// It returns the outcome of the last catalog sync of every connector
+//
+//	@Summary		Catalog sync status
+//	@Description	Get the last run, last success, error, and counters of each catalog connector
+//	@Tags			integrations
+//	@Produce		json
+//	@Success		200	{object}	models.SuccessResponse{data=[]models.SyncState}	"Sync state per connector"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/integrations/sync-status [get]
+func (h *IntegrationHandler) SyncStatus(w http.ResponseWriter, r *http.Request) {
+	states, err := h.syncStates.List(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list sync states", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve sync status")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Sync status retrieved successfully", states)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// validSignature checks the signature in constant time. Without a configured
+// secret every request is rejected.
+func (h *IntegrationHandler) validSignature(body []byte, signature string) bool {
+	if len(h.webhookSecret) == 0 || signature == "" {
+		return false
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, h.webhookSecret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
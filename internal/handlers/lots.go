@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/lots"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type LotHandler struct {
+	repo     repository.LotRepository
+	products repository.ProductRepository
+	lots     *lots.Service
+	logger   *slog.Logger
+}
+
+func NewLotHandler(repo repository.LotRepository, products repository.ProductRepository, lotService *lots.Service, logger *slog.Logger) *LotHandler {
+	return &LotHandler{repo: repo, products: products, lots: lotService, logger: logger}
+}
+
+// LotRequest is a lot received into a product's stock
+type LotRequest struct {
+	LotNumber string     `json:"lot_number" example:"L2026-1014"`
+	Quantity  int        `json:"quantity" example:"24"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Omitted for lots that don't expire
+}
+
+// LotResponse is a received lot and the product it was added to
+type LotResponse struct {
+	Lot     *models.Lot     `json:"lot"`
+	Product *models.Product `json:"product"`
+}
+
+// ConsumeRequest takes units from a product's lots
+type ConsumeRequest struct {
+	Quantity  int    `json:"quantity" example:"6"`
+	LotNumber string `json:"lot_number,omitempty"` // Takes the units from this lot instead of first-expired-first-out
+}
+
+// ConsumeResponse is what consuming took from each lot and the product as
+// updated. Units beyond the lots' were untracked stock.
+type ConsumeResponse struct {
+	Consumed []models.LotConsumption `json:"consumed"`
+	Product  *models.Product         `json:"product"`
+}
+
+// ReceiveLot handles POST /api/v1/products/{id}/lots
+// It adds a lot's units to a product's stock
+//
+//	@Summary		Receive a lot
+//	@Description	Add a lot's units to a product's stock. quantity is in the product's unit; expires_at is omitted for lots that don't expire. A lot number the product already has takes the units into that lot, if its expiry is the same. Lot receipts carry no cost: inventory valuation reports their units as uncosted. Publishes stock.adjusted with reason receipt.
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int			true	"Product ID"
+//	@Param			lot	body		LotRequest	true	"Lot"
+//	@Success		201	{object}	models.SuccessResponse{data=LotResponse}	"Lot received, with the updated product"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid lot"
+//	@Failure		404	{object}	models.ErrorResponse	"Product not found"
+//	@Failure		409	{object}	models.ErrorResponse	"Lot number received before with another expiry"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/lots [post]
+func (h *LotHandler) ReceiveLot(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var req LotRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	lot, product, err := h.lots.Receive(r.Context(), &models.Lot{ProductID: id, LotNumber: req.LotNumber, Quantity: req.Quantity, ExpiresAt: req.ExpiresAt})
+	if err != nil {
+		switch {
+		case errors.Is(err, lots.ErrInvalidLot):
+			respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, lots.ErrExpiryMismatch):
+			respondWithError(h.logger, w, http.StatusConflict, err.Error())
+		case err.Error() == "product not found":
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+		default:
+			h.logger.Error("failed to receive lot", "error", err, "product_id", id)
+			respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to receive lot")
+		}
+		return
+	}
+
+	h.logger.Info("lot received", "product_id", id, "lot_id", lot.ID, "lot_number", lot.LotNumber, "quantity", req.Quantity)
+	response := models.NewSuccessResponse(http.StatusCreated, "Lot received successfully", LotResponse{Lot: lot, Product: product})
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/products/%d/lots", id), response)
+}
+
+// ConsumeLots handles POST /api/v1/products/{id}/lots/consume
+// It takes units from a product's stock and lots
+//
+//	@Summary		Consume stock from lots
+//	@Description	Take units from a product's stock, from its lots first-expired-first-out, expired lots first and lots that don't expire last, or from the lot named by lot_number, e.g. to write an expired lot off. Units beyond what the lots have left are taken from untracked stock. Publishes stock.adjusted with reason consumption.
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Product ID"
+//	@Param			consume	body		ConsumeRequest	true	"Units to consume"
+//	@Success		200		{object}	models.SuccessResponse{data=ConsumeResponse}	"What was taken from each lot, with the updated product"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid quantity"
+//	@Failure		404		{object}	models.ErrorResponse	"Product or lot not found"
+//	@Failure		422		{object}	models.ErrorResponse	"Insufficient stock"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/lots/consume [post]
+func (h *LotHandler) ConsumeLots(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var req ConsumeRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	consumed, product, err := h.lots.Consume(r.Context(), id, req.Quantity, req.LotNumber)
+	if err != nil {
+		switch {
+		case errors.Is(err, lots.ErrInvalidLot):
+			respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, repository.ErrInsufficientStock):
+			respondWithError(h.logger, w, http.StatusUnprocessableEntity, err.Error())
+		case err.Error() == "product not found":
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+		case err.Error() == "lot not found":
+			respondWithError(h.logger, w, http.StatusNotFound, "Lot not found")
+		default:
+			h.logger.Error("failed to consume lots", "error", err, "product_id", id)
+			respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to consume stock")
+		}
+		return
+	}
+
+	h.logger.Info("stock consumed", "product_id", id, "quantity", req.Quantity, "lots", len(consumed))
+	response := models.NewSuccessResponse(http.StatusOK, "Stock consumed successfully", ConsumeResponse{Consumed: consumed, Product: product})
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// ListLots handles GET /api/v1/products/{id}/lots
+// It returns a product's lots
+//
+//	@Summary		List product lots
+//	@Description	Get a paginated list of a product's lots in the order they're consumed, soonest expiring first. Only lots with stock left are listed unless all is true.
+//	@Tags			products
+//	@Produce		json
+//	@Param			id		path		int		true	"Product ID"
+//	@Param			all		query		bool	false	"Include lots used up"
+//	@Param			limit	query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int		false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.Lot}	"List of lots with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/lots [get]
+func (h *LotHandler) ListLots(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	limit, offset := lotPagination(r)
+	all := r.URL.Query().Get("all") == "true"
+
+	if _, err := h.products.GetByID(ctx, id); err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve lots")
+		return
+	}
+
+	lots, err := h.repo.ListByProduct(ctx, id, all, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list lots", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve lots")
+		return
+	}
+
+	total, err := h.repo.CountByProduct(ctx, id, all)
+	if err != nil {
+		h.logger.Error("failed to count lots", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count lots")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Lots retrieved successfully", lots, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// ListExpiringLots handles GET /api/v1/lots/expiring
+// It returns the lots of every product that expire soon
+//
+//	@Summary		List expiring lots
+//	@Description	Get a paginated list of lots with stock left that expire within the given duration, including those already expired, soonest first. within defaults to LOT_EXPIRY_WARNING.
+//	@Tags			lots
+//	@Produce		json
+//	@Param			within	query		string	false	"Go duration from now, e.g. 72h"
+//	@Param			limit	query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int		false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.ExpiringLot}	"List of expiring lots with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid within"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/lots/expiring [get]
+func (h *LotHandler) ListExpiringLots(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	within := h.lots.Warning()
+	if v := r.URL.Query().Get("within"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			respondWithError(h.logger, w, http.StatusBadRequest, "within must be a non-negative duration, e.g. 72h")
+			return
+		}
+		within = d
+	}
+	limit, offset := lotPagination(r)
+	before := time.Now().Add(within)
+
+	lots, err := h.repo.Expiring(ctx, before, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list expiring lots", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve expiring lots")
+		return
+	}
+
+	total, err := h.repo.CountExpiring(ctx, before)
+	if err != nil {
+		h.logger.Error("failed to count expiring lots", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count expiring lots")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Expiring lots retrieved successfully", lots, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+func lotPagination(r *http.Request) (limit, offset int) {
+	limit = 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+	return limit, offset
+}
@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/tokens"
+)
+
+// noteAdmin is the author of notes written with ADMIN_TOKEN
+const noteAdmin = "admin"
+
+var errNotNoteAuthor = errors.New("Only the note's author or an admin can change or delete it")
+
+// NoteHandler serves the notes internal users leave on products. Writes and
+// the NoteMentioned events they publish share one transaction.
+type NoteHandler struct {
+	repo      repository.ProductNoteRepository
+	products  repository.ProductRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	logger    *slog.Logger
+}
+
+func NewNoteHandler(repo repository.ProductNoteRepository, products repository.ProductRepository, tx repository.Transactor, publisher events.Publisher, logger *slog.Logger) *NoteHandler {
+	return &NoteHandler{repo: repo, products: products, tx: tx, publisher: publisher, logger: logger}
+}
+
+// ListNotes handles GET /api/v1/products/{id}/notes
+// It returns a product's notes
+//
+//	@Summary		List product notes
+//	@Description	Get a paginated list of a product's notes, pinned ones first, newest first
+//	@Tags			notes
+//	@Produce		json
+//	@Param			id		path		int	true	"Product ID"
+//	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int	false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.ProductNote}	"List of notes with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/notes [get]
+func (h *NoteHandler) ListNotes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	if _, err := h.products.GetByID(ctx, id); err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve notes")
+		return
+	}
+
+	notes, err := h.repo.List(ctx, id, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list product notes", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve notes")
+		return
+	}
+
+	total, err := h.repo.Count(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to count product notes", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count notes")
+		return
+	}
+
+	for _, note := range notes {
+		note.Mentions = models.Mentions(note.Body)
+	}
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Notes retrieved successfully", notes, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// GetNote handles GET /api/v1/products/{id}/notes/{noteID}
+// It returns a note of a product
+//
+//	@Summary		Get a product note
+//	@Description	Get a note of a product, with the handles it mentions
+//	@Tags			notes
+//	@Produce		json
+//	@Param			id		path		int	true	"Product ID"
+//	@Param			noteID	path		int	true	"Note ID"
+//	@Success		200		{object}	models.SuccessResponse{data=models.ProductNote}	"Note"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product or note ID"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	models.ErrorResponse	"Note not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/notes/{noteID} [get]
+func (h *NoteHandler) GetNote(w http.ResponseWriter, r *http.Request) {
+	productID, noteID, ok := h.noteIDs(w, r)
+	if !ok {
+		return
+	}
+
+	note, err := h.repo.GetByID(r.Context(), productID, noteID)
+	if err != nil {
+		h.respondWithNoteError(w, err, "retrieve note", noteID)
+		return
+	}
+
+	note.Mentions = models.Mentions(note.Body)
+	response := models.NewSuccessResponse(http.StatusOK, "Note retrieved successfully", note)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CreateNote handles POST /api/v1/products/{id}/notes
+// It leaves a note on a product
+//
+//	@Summary		Create a product note
+//	@Description	Leave a note on a product, written by the subject of the access token, or admin. Handles @mentioned in the body, other than the author's, are announced with note.mentioned and notified to subscriptions watching mentions.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Product ID"
+//	@Param			note	body		models.NoteRequest	true	"Note"
+//	@Success		201		{object}	models.SuccessResponse{data=models.ProductNote}	"Created note, with its URL in the Location header"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid request body or note"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/notes [post]
+func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	req, ok := h.decodeNote(w, r)
+	if !ok {
+		return
+	}
+	if req.Body == nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "body is required")
+		return
+	}
+
+	note := &models.ProductNote{ProductID: id, Author: noteAuthor(r), Body: *req.Body}
+	if req.Pinned != nil {
+		note.Pinned = *req.Pinned
+	}
+
+	err = h.tx.WithTx(ctx, func(ctx context.Context) error {
+		product, err := h.products.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := h.repo.Create(ctx, note); err != nil {
+			return err
+		}
+		return h.announce(ctx, product, note, "")
+	})
+	if err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to create product note", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to create note")
+		return
+	}
+
+	h.logger.Info("product note created", "note_id", note.ID, "product_id", id, "author", note.Author)
+	note.Mentions = models.Mentions(note.Body)
+	response := models.NewSuccessResponse(http.StatusCreated, "Note created successfully", note)
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/products/%d/notes/%d", id, note.ID), response)
+}
+
+// UpdateNote handles PUT /api/v1/products/{id}/notes/{noteID}
+// It edits or pins a note
+//
+//	@Summary		Update a product note
+//	@Description	Change a note's body, which only its author or an admin can do, and/or pin or unpin it, which anyone can. Handles the new body mentions that the old one didn't are announced as on creation.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Product ID"
+//	@Param			noteID	path		int					true	"Note ID"
+//	@Param			note	body		models.NoteRequest	true	"Fields to change"
+//	@Success		200		{object}	models.SuccessResponse{data=models.ProductNote}	"Updated note"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid request body or note"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		403		{object}	models.ErrorResponse	"Not the note's author"
+//	@Failure		404		{object}	models.ErrorResponse	"Note not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/notes/{noteID} [put]
+func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	productID, noteID, ok := h.noteIDs(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := h.decodeNote(w, r)
+	if !ok {
+		return
+	}
+
+	var note *models.ProductNote
+	err := h.tx.WithTx(ctx, func(ctx context.Context) error {
+		product, err := h.products.GetByID(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if note, err = h.repo.GetByID(ctx, productID, noteID); err != nil {
+			return err
+		}
+
+		old := note.Body
+		if req.Body != nil && *req.Body != old {
+			if !mayChangeNote(r, note) {
+				return errNotNoteAuthor
+			}
+			note.Body = *req.Body
+		}
+		if req.Pinned != nil {
+			note.Pinned = *req.Pinned
+		}
+		if err := h.repo.Update(ctx, note); err != nil {
+			return err
+		}
+		return h.announce(ctx, product, note, old)
+	})
+	if err != nil {
+		h.respondWithNoteError(w, err, "update note", noteID)
+		return
+	}
+
+	h.logger.Info("product note updated", "note_id", note.ID, "product_id", productID)
+	note.Mentions = models.Mentions(note.Body)
+	response := models.NewSuccessResponse(http.StatusOK, "Note updated successfully", note)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// DeleteNote handles DELETE /api/v1/products/{id}/notes/{noteID}
+// It deletes a note
+//
+//	@Summary		Delete a product note
+//	@Description	Delete a note, which only its author or an admin can do
+//	@Tags			notes
+//	@Param			id		path	int	true	"Product ID"
+//	@Param			noteID	path	int	true	"Note ID"
+//	@Success		204		"Note deleted successfully"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product or note ID"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		403		{object}	models.ErrorResponse	"Not the note's author"
+//	@Failure		404		{object}	models.ErrorResponse	"Note not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/notes/{noteID} [delete]
+func (h *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	productID, noteID, ok := h.noteIDs(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.tx.WithTx(r.Context(), func(ctx context.Context) error {
+		note, err := h.repo.GetByID(ctx, productID, noteID)
+		if err != nil {
+			return err
+		}
+		if !mayChangeNote(r, note) {
+			return errNotNoteAuthor
+		}
+		return h.repo.Delete(ctx, productID, noteID)
+	})
+	if err != nil {
+		h.respondWithNoteError(w, err, "delete note", noteID)
+		return
+	}
+
+	h.logger.Info("product note deleted", "note_id", noteID, "product_id", productID)
+	respondNoContent(w)
+}
+
+// announce publishes NoteMentioned for the handles note mentions that its
+// body before, old, didn't, leaving out its author
+func (h *NoteHandler) announce(ctx context.Context, product *models.Product, note *models.ProductNote, old string) error {
+	before := make(map[string]bool)
+	for _, handle := range models.Mentions(old) {
+		before[handle] = true
+	}
+	before[strings.ToLower(note.Author)] = true
+
+	var mentioned []string
+	for _, handle := range models.Mentions(note.Body) {
+		if !before[handle] {
+			mentioned = append(mentioned, handle)
+		}
+	}
+	if len(mentioned) == 0 {
+		return nil
+	}
+
+	return h.publisher.Publish(ctx, events.New(events.NoteMentioned{
+		NoteID:    note.ID,
+		ProductID: product.ID,
+		SKU:       product.SKU,
+		Author:    note.Author,
+		Body:      note.Body,
+		Mentioned: mentioned,
+	}))
+}
+
+// decodeNote decodes and validates a note write
+func (h *NoteHandler) decodeNote(w http.ResponseWriter, r *http.Request) (*models.NoteRequest, bool) {
+	var req models.NoteRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil, false
+	}
+
+	if req.Body != nil {
+		body := strings.TrimSpace(*req.Body)
+		switch {
+		case body == "":
+			respondWithError(h.logger, w, http.StatusBadRequest, "body must not be empty")
+			return nil, false
+		case utf8.RuneCountInString(body) > models.MaxNoteLength:
+			respondWithError(h.logger, w, http.StatusBadRequest, fmt.Sprintf("body must be at most %d characters", models.MaxNoteLength))
+			return nil, false
+		}
+		req.Body = &body
+	}
+	return &req, true
+}
+
+// noteIDs parses the product and note IDs of a note's path
+func (h *NoteHandler) noteIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return 0, 0, false
+	}
+	noteID, err := strconv.Atoi(chi.URLParam(r, "noteID"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid note ID")
+		return 0, 0, false
+	}
+	return productID, noteID, true
+}
+
+func (h *NoteHandler) respondWithNoteError(w http.ResponseWriter, err error, action string, noteID int) {
+	switch {
+	case errors.Is(err, errNotNoteAuthor):
+		respondWithError(h.logger, w, http.StatusForbidden, err.Error())
+	case err.Error() == "product note not found", err.Error() == "product not found":
+		respondWithError(h.logger, w, http.StatusNotFound, "Note not found")
+	default:
+		h.logger.Error("failed to "+action, "error", err, "note_id", noteID)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action)
+	}
+}
+
+// noteAuthor names the writer of a note: the subject of the request's
+// scoped token, or admin for ADMIN_TOKEN
+func noteAuthor(r *http.Request) string {
+	if claims := tokens.FromContext(r.Context()); claims != nil {
+		return claims.Subject
+	}
+	return noteAdmin
+}
+
+// mayChangeNote reports whether the request may change the body of note or
+// delete it: its author's, or an admin's. A token minted for a subject named
+// admin is still only that subject's.
+func mayChangeNote(r *http.Request, note *models.ProductNote) bool {
+	claims := tokens.FromContext(r.Context())
+	return claims == nil || claims.Subject == note.Author
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/operations"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// operationPollInterval is the Retry-After of running operations, how long
+// clients wait before polling again
+const operationPollInterval = "2"
+
+// OperationHandler serves the long-running operations async endpoints
+// answer 202 with
+type OperationHandler struct {
+	repo      repository.OperationRepository
+	runner    *operations.Runner // nil on a read-only instance
+	auditRepo repository.AuditRepository
+	logger    *slog.Logger
+}
+
+func NewOperationHandler(repo repository.OperationRepository, runner *operations.Runner, auditRepo repository.AuditRepository, logger *slog.Logger) *OperationHandler {
+	return &OperationHandler{repo: repo, runner: runner, auditRepo: auditRepo, logger: logger}
+}
+
+// GetOperation handles GET /api/v1/operations/{id}
+// It returns the status of a long-running operation
+//
+//	@Summary		Get operation
+//	@Description	Get a long-running operation's status (running, succeeded, failed, or cancelled), progress in percent, and checkpoint of the work done, with a link to its result once it has succeeded, or to what it got done once cancelled, and the error once it has failed. Running operations come with a Retry-After of when to poll again.
+//	@Tags			operations
+//	@Produce		json
+//	@Param			id	path		int	true	"Operation ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.Operation}	"Operation"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid operation ID"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	models.ErrorResponse	"Operation not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/operations/{id} [get]
+func (h *OperationHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	op, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		if err.Error() == "operation not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Operation not found")
+			return
+		}
+		h.logger.Error("failed to get operation", "id", id, "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve operation")
+		return
+	}
+
+	if !op.Done() {
+		w.Header().Set("Retry-After", operationPollInterval)
+	}
+	response := models.NewSuccessResponse(http.StatusOK, "Operation retrieved successfully", op)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CancelOperation handles POST /api/v1/operations/{id}/cancel
+// It asks a running operation to stop
+//
+//	@Summary		Cancel operation
+//	@Description	Ask a running operation to stop. It stops at its next check, within a few seconds, and is then cancelled, keeping its checkpoint and a link to what it got done, if anything; poll its Location until then. Cancelling an operation that was already asked to stop does nothing.
+//	@Tags			operations
+//	@Produce		json
+//	@Param			id	path		int	true	"Operation ID"
+//	@Success		202	{object}	models.SuccessResponse{data=models.Operation}	"Cancellation requested"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid operation ID"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	models.ErrorResponse	"Operation not found"
+//	@Failure		409	{object}	models.ErrorResponse	"Operation has finished"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Failure		503	{object}	models.ErrorResponse	"Read-only instance"
+//	@Router			/operations/{id}/cancel [post]
+func (h *OperationHandler) CancelOperation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+	if h.runner == nil {
+		respondWithError(h.logger, w, http.StatusServiceUnavailable, "Operations can't be cancelled on a read-only instance")
+		return
+	}
+
+	op, err := h.runner.Cancel(r.Context(), id)
+	if err != nil {
+		switch err.Error() {
+		case "operation not found":
+			respondWithError(h.logger, w, http.StatusNotFound, "Operation not found")
+		case "operation has finished":
+			respondWithError(h.logger, w, http.StatusConflict, fmt.Sprintf("Operation has already finished, %s", op.Status))
+		default:
+			h.logger.Error("failed to cancel operation", "id", id, "error", err)
+			respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to cancel operation")
+		}
+		return
+	}
+
+	entry := &models.AuditEntry{
+		Action:     "operation.cancel",
+		Actor:      r.RemoteAddr,
+		EntityType: "operation",
+		EntityID:   strconv.Itoa(op.ID),
+	}
+	if err := h.auditRepo.Create(r.Context(), entry); err != nil {
+		h.logger.Error("failed to record operation cancellation in audit log", "error", err)
+	}
+
+	response := models.NewSuccessResponse(http.StatusAccepted, "Cancellation requested", op)
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/operations/%d", op.ID))
+	respondWithJSON(h.logger, w, http.StatusAccepted, response)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/policy"
+)
+
+// PolicyResponse is the effective route policy. Decisions is filled in when
+// a path is given.
+type PolicyResponse struct {
+	Roles     map[string][]string `json:"roles"` // Granted permissions per role
+	Rules     []policy.Rule       `json:"rules"` // In evaluation order, the first match applies
+	Default   string              `json:"default"`
+	Decisions []policy.Decision   `json:"decisions,omitempty"`
+}
+
+// GetPolicies handles GET /api/v1/admin/policies
+// It returns the effective route policy, and how it decides a request
+//
+//	@Summary		Get the route policy
+//	@Description	Get the permissions granted per role and the rules of POLICY_RULES in evaluation order. With path (and optionally method and role), also returns the decision for that request per role.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			path	query		string	false	"Request path to evaluate, e.g. /api/v1/products/42"
+//	@Param			method	query		string	false	"Request method to evaluate"	default(GET)
+//	@Param			role	query		string	false	"Role to evaluate, all roles when empty"	Enums(anonymous, admin, token, editor, approver)
+//	@Success		200		{object}	models.SuccessResponse{data=PolicyResponse}	"Effective policy"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid path or role"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Router			/admin/policies [get]
+func (h *AdminHandler) GetPolicies(w http.ResponseWriter, r *http.Request) {
+	cfg := h.store.Current()
+	p, err := policy.New(cfg.PolicyRules, cfg.PolicyRoles)
+	if err != nil {
+		// Validated on load and reload, so only reachable through a bug
+		h.logger.Error("invalid route policy", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Invalid authorization policy")
+		return
+	}
+
+	response := PolicyResponse{
+		Roles:   make(map[string][]string, len(policy.Roles)),
+		Rules:   p.Rules(),
+		Default: "GET, HEAD, and OPTIONS need <resource>:read, other methods <resource>:write, for /api/v1/<resource>/...; other paths need nothing",
+	}
+	for _, role := range policy.Roles {
+		response.Roles[role] = p.Grants(role)
+	}
+	if response.Rules == nil {
+		response.Rules = []policy.Rule{}
+	}
+
+	query := r.URL.Query()
+	if path := query.Get("path"); path != "" {
+		if !strings.HasPrefix(path, "/") {
+			respondWithError(h.logger, w, http.StatusBadRequest, "path must start with /")
+			return
+		}
+		method := strings.ToUpper(query.Get("method"))
+		if method == "" {
+			method = http.MethodGet
+		}
+		roles := policy.Roles
+		if role := query.Get("role"); role != "" {
+			if _, ok := response.Roles[role]; !ok {
+				respondWithError(h.logger, w, http.StatusBadRequest, "role must be one of "+strings.Join(policy.Roles, ", "))
+				return
+			}
+			roles = []string{role}
+		}
+		for _, role := range roles {
+			response.Decisions = append(response.Decisions, p.Decide(role, method, path))
+		}
+	}
+
+	respondWithJSON(h.logger, w, http.StatusOK, models.NewSuccessResponse(http.StatusOK, "Policy retrieved successfully", response))
+}
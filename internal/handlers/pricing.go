@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/pricing"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/repricing"
+)
+
+type PricingHandler struct {
+	repo      repository.ProductRepository
+	rules     *pricing.Rules
+	repricing *repricing.Service
+	logger    *slog.Logger
+}
+
+func NewPricingHandler(repo repository.ProductRepository, rules *pricing.Rules, repricing *repricing.Service, logger *slog.Logger) *PricingHandler {
+	return &PricingHandler{repo: repo, rules: rules, repricing: repricing, logger: logger}
+}
+
+// GetPrice handles GET /api/v1/products/{id}/price
+// It returns a product's price in a tax region
+//
+//	@Summary		Get regional price
+//	@Description	The product's unit price with the region's tax applied (a category rate when one matches the product's category), rounded to the currency. amount includes tax in tax-inclusive regions unless tax says otherwise.
+//	@Tags			products
+//	@Produce		json
+//	@Param			id			path		int		true	"Product ID"
+//	@Param			tax_region	query		string	false	"Tax region code, e.g. DE (default TAX_DEFAULT_REGION)"
+//	@Param			tax			query		string	false	"Presentation: inclusive or exclusive (default per region)"
+//	@Param			region		query		string	false	"Sales region the product must be sold in"
+//	@Success		200			{object}	models.SuccessResponse{data=models.Price}	"Regional price"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid product ID, tax region, tax, or region"
+//	@Failure		403			{object}	models.ErrorResponse	"API key limited to another region"
+//	@Failure		404			{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/price [get]
+func (h *PricingHandler) GetPrice(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	// Not ?region=, which is the sales region on product resources
+	region := r.URL.Query().Get("tax_region")
+	if region == "" && h.rules.DefaultRegion() == "" {
+		respondWithError(h.logger, w, http.StatusBadRequest, "tax_region is required")
+		return
+	}
+
+	var inclusive *bool
+	switch tax := r.URL.Query().Get("tax"); tax {
+	case "":
+	case "inclusive", "exclusive":
+		included := tax == "inclusive"
+		inclusive = &included
+	default:
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid tax: must be inclusive or exclusive")
+		return
+	}
+
+	sales, ok := salesRegion(h.logger, w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.repo.GetByID(r.Context(), id)
+	if err == nil && !product.SoldIn(sales) {
+		err = fmt.Errorf("product not found")
+	}
+	if err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve product")
+		return
+	}
+
+	price, err := h.rules.Price(product, region, inclusive)
+	if errors.Is(err, pricing.ErrUnknownRegion) {
+		message := "Unknown tax_region: no regions are configured"
+		if regions := h.rules.Regions(); len(regions) > 0 {
+			message = fmt.Sprintf("Unknown tax_region: must be one of %s", strings.Join(regions, ", "))
+		}
+		respondWithError(h.logger, w, http.StatusBadRequest, message)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to price product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to compute price")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Price computed successfully", price)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// UpdatePrices handles POST /api/v1/products/price-update
+// It previews or applies a bulk price update
+//
+//	@Summary		Bulk price update
+//	@Description	Change the unit prices of the products matching a filter by a percentage, optionally moving them to the nearest price ending in end_with (e.g. 0.99). The percentage is rounded to the cent by PRICE_ROUNDING. Preview first: it lists every product whose price changes, by ID, with its old and new price, and a preview_token. Apply with that token runs the update in transactions of 100 products, each repricing from the price it locks, publishing product.updated, and audited as product.price_update; a token that no longer matches what the update would change is refused with 409. Batches applied before a failing one stay applied. Up to 5000 products per update.
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			update	body		models.PriceUpdateRequest	true	"Filter, rule, and mode"
+//	@Param			dry_run	query		bool	false	"Apply without committing (also X-Dry-Run header)"
+//	@Success		200		{object}	models.SuccessResponse{data=models.PriceUpdatePreview}	"Preview"
+//	@Success		201		{object}	models.SuccessResponse{data=models.PriceUpdateResult}	"Applied"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		409		{object}	models.ErrorResponse	"Preview out of date"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/price-update [post]
+func (h *PricingHandler) UpdatePrices(w http.ResponseWriter, r *http.Request) {
+	var req models.PriceUpdateRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	switch req.Mode {
+	case "", models.PriceUpdateModePreview:
+		preview, err := h.repricing.Preview(r.Context(), req.Filter, req.Rule)
+		if err != nil {
+			h.respondWithUpdateError(w, err)
+			return
+		}
+		response := models.NewSuccessResponse(http.StatusOK, "Price update previewed successfully", preview)
+		respondWithJSON(h.logger, w, http.StatusOK, response)
+	case models.PriceUpdateModeApply:
+		result, err := h.repricing.Apply(r.Context(), req.Filter, req.Rule, req.PreviewToken, requestActor(r))
+		if err != nil {
+			if result != nil && result.Batches > 0 {
+				h.logger.Error("price update failed partway", "error", err, "batches", result.Batches, "updated", result.Updated)
+				respondWithError(h.logger, w, http.StatusInternalServerError, fmt.Sprintf("Failed to update prices after updating %d products", result.Updated))
+				return
+			}
+			h.respondWithUpdateError(w, err)
+			return
+		}
+		response := models.NewSuccessResponse(http.StatusCreated, "Prices updated successfully", result)
+		respondWithJSON(h.logger, w, http.StatusCreated, response)
+	default:
+		respondWithError(h.logger, w, http.StatusBadRequest, "mode must be preview or apply")
+	}
+}
+
+func (h *PricingHandler) respondWithUpdateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repricing.ErrInvalidUpdate):
+		respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repricing.ErrStalePreview):
+		respondWithError(h.logger, w, http.StatusConflict, "Preview is out of date: the catalog changed since, preview the update again")
+	default:
+		h.logger.Error("failed to update prices", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to update prices")
+	}
+}
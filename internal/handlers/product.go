@@ -1,25 +1,91 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/id"
+	"{{MODULE_NAME}}/internal/jsonenc"
 	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/policy"
+	"{{MODULE_NAME}}/internal/promotions"
 	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/sku"
+	"{{MODULE_NAME}}/internal/slug"
+	"{{MODULE_NAME}}/internal/units"
+)
+
+// maxCategoryLength is the size of the products.category column
+const maxCategoryLength = 100
+
+// Tag limits, keeping a bulk insert of tagged products within Postgres'
+// parameter limit
+const (
+	maxTags      = 20
+	maxTagLength = 100
 )
 
 type ProductHandler struct {
-	repo   repository.ProductRepository
-	logger *slog.Logger
+	repo       repository.ProductRepository
+	views      repository.SavedSearchRepository
+	trash      repository.TrashRepository
+	revisions  repository.ProductRevisionRepository
+	slugs      repository.ProductSlugRepository
+	notes      repository.ProductNoteRepository
+	tx         repository.Transactor
+	publisher  events.Publisher
+	promotions *promotions.Service
+	units      *units.Table
+	skus       *sku.Generator
+	facets     models.FacetSpec
+	logger     *slog.Logger
+}
+
+// ProductListResponse is a page of products, with counts of all the listed
+// products per facet on request
+type ProductListResponse struct {
+	models.PaginatedResponse
+	Facets models.Facets `json:"facets,omitempty"`
 }
 
-func NewProductHandler(repo repository.ProductRepository, logger *slog.Logger) *ProductHandler {
+// NewProductHandler creates the product handler. Writes and the events they
+// publish share one transaction, so synchronous subscribers (such as the
+// outbox writer) commit or roll back together with the change. Reads include
+// effective prices from promotionService on request; nil leaves them out.
+// Product units must be in unitTable. Products created without a SKU get one
+// from skuGenerator; nil requires a SKU. Listings through ?view run the saved
+// searches of views; nil rejects them. Deleted products are moved to trash;
+// nil deletes them outright. Updates by editors are held in revisions until
+// an approver approves them; nil applies them right away. Slugs given in
+// writes are checked against slugs, which also finds products by slug; nil
+// leaves them to the unique index and finds none. Admin lists include each
+// product's latest note from notes; nil leaves them out. ?facets= buckets
+// prices and stock by the bounds of facets.
+func NewProductHandler(repo repository.ProductRepository, views repository.SavedSearchRepository, trash repository.TrashRepository, revisions repository.ProductRevisionRepository, slugs repository.ProductSlugRepository, notes repository.ProductNoteRepository, tx repository.Transactor, publisher events.Publisher, promotionService *promotions.Service, unitTable *units.Table, skuGenerator *sku.Generator, facets models.FacetSpec, logger *slog.Logger) *ProductHandler {
 	return &ProductHandler{
-		repo:   repo,
-		logger: logger,
+		repo:       repo,
+		views:      views,
+		trash:      trash,
+		revisions:  revisions,
+		slugs:      slugs,
+		notes:      notes,
+		tx:         tx,
+		publisher:  publisher,
+		promotions: promotionService,
+		units:      unitTable,
+		skus:       skuGenerator,
+		facets:     facets,
+		logger:     logger,
 	}
 }
 
@@ -27,16 +93,56 @@ func NewProductHandler(repo repository.ProductRepository, logger *slog.Logger) *
 // It returns a paginated list of products
 //
 //	@Summary		List products
-//	@Description	Get a paginated list of products in inventory
+//	@Description	Get a paginated list of the products in inventory visible now (see visible_from and visible_until). With view, the products matching that saved search, in its order. With facets, counts of all the listed products per category, price range, and/or stock status. With sort=random, a uniformly random sample of limit products instead of a page, never cached. With region, or a regional API key, only the products sold in that region.
 //	@Tags			products
 //	@Accept			json
 //	@Produce		json
 //	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(50)
 //	@Param			offset	query		int	false	"Number of items to skip"				default(0)
-//	@Success		200		{object}	models.PaginatedResponse	"List of products with pagination metadata"
+//	@Param			view	query		int	false	"ID of a saved search to list the products of"
+//	@Param			effective_price	query	bool	false	"Include each product's price after promotions"
+//	@Param			at		query		string	false	"RFC 3339 timestamp to evaluate promotions at (default now)"
+//	@Param			facets	query		string	false	"Facets to count: category, price_range, and/or status, comma-separated"
+//	@Param			sort	query		string	false	"random for a random sample; offset is ignored"	Enums(random)
+//	@Param			region	query		string	false	"Only products sold in this region; a regional API key's own by default"
+//	@Success		200		{object}	ProductListResponse	"List of products with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid view, sort, or region, or unknown facet"
+//	@Failure		403		{object}	models.ErrorResponse	"Region outside the API key's"
+//	@Failure		404		{object}	models.ErrorResponse	"Saved search not found"
 //	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
 //	@Router			/products [get]
 func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, false)
+}
+
+// ListAdminProducts handles GET /api/v1/admin/products
+// It returns a paginated list of products, hidden ones included
+//
+//	@Summary		List products (admin)
+//	@Description	GET /products for admins: hidden products are listed too, unless visibility says otherwise, and expiring_within finds visible products about to be hidden. Each product comes with its latest note, if any.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			limit			query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset			query		int		false	"Number of items to skip"				default(0)
+//	@Param			view			query		int		false	"ID of a saved search to list the products of"
+//	@Param			visibility		query		string	false	"Only products with this visibility now"	Enums(visible, hidden, scheduled, expired)
+//	@Param			expiring_within	query		string	false	"Only visible products hidden within this Go duration, e.g. 72h"
+//	@Param			facets			query		string	false	"Facets to count: category, price_range, and/or status, comma-separated"
+//	@Param			sort			query		string	false	"random for a random sample; offset is ignored"	Enums(random)
+//	@Param			region			query		string	false	"Only products sold in this region"
+//	@Success		200				{object}	ProductListResponse{data=[]models.AdminProduct}	"List of products with pagination metadata"
+//	@Failure		400				{object}	models.ErrorResponse	"Invalid view, visibility, expiring_within, sort, or region, or unknown facet"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse	"Saved search not found"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/products [get]
+func (h *ProductHandler) ListAdminProducts(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, true)
+}
+
+// list lists products for ListProducts, or with admin for
+// ListAdminProducts, which lists hidden products too
+func (h *ProductHandler) list(w http.ResponseWriter, r *http.Request, admin bool) {
 	ctx := r.Context()
 
 	limit := 50
@@ -57,17 +163,62 @@ func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	products, err := h.repo.List(ctx, limit, offset)
-	if err != nil {
-		h.logger.Error("failed to list products", "error", err)
-		h.respondWithError(w, http.StatusInternalServerError, "Failed to retrieve products")
+	pricedAt, ok := h.effectivePriceAt(w, r, time.Now())
+	if !ok {
+		return
+	}
+
+	filter, ok := h.view(w, r)
+	if !ok {
+		return
+	}
+
+	region, ok := salesRegion(h.logger, w, r)
+	if !ok {
+		return
+	}
+	if region != "" {
+		// Listed through the filter, whose default order is that of List
+		if filter == nil {
+			filter = &models.ProductFilter{}
+		}
+		filter.Region = region
+	}
+
+	if filter, ok = h.visibility(w, r, filter, admin); !ok {
+		return
+	}
+
+	spec, ok := facetSpec(h.logger, w, r, h.facets)
+	if !ok {
 		return
 	}
 
-	total, err := h.repo.Count(ctx)
+	random := false
+	switch r.URL.Query().Get("sort") {
+	case "":
+	case "random":
+		random, offset = true, 0
+	default:
+		h.respondWithError(w, http.StatusBadRequest, "sort must be random")
+		return
+	}
+
+	var products []*models.Product
+	var total int
+	var err error
+	if random {
+		// Each sample differs, so none is worth caching
+		w.Header().Set("Cache-Control", "no-store")
+		products, total, err = h.sample(ctx, filter, limit)
+	} else if filter != nil {
+		products, total, err = h.repo.ListFilteredWithCount(ctx, *filter, limit, offset)
+	} else {
+		products, total, err = h.repo.ListWithCount(ctx, limit, offset)
+	}
 	if err != nil {
-		h.logger.Error("failed to count products", "error", err)
-		h.respondWithError(w, http.StatusInternalServerError, "Failed to count products")
+		h.logger.Error("failed to list products", "error", err)
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to retrieve products")
 		return
 	}
 
@@ -76,24 +227,168 @@ func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 		Offset: offset,
 		Total:  total,
 	}
-	response := models.NewPaginatedResponse(http.StatusOK, "Products retrieved successfully", products, pagination)
+	var data interface{} = products
+	var priced []*models.PricedProduct
+	if pricedAt != nil {
+		if priced, err = h.priced(ctx, *pricedAt, products...); err != nil {
+			h.logger.Error("failed to evaluate promotions", "error", err)
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to compute effective prices")
+			return
+		}
+		data = priced
+	}
+	if admin && h.notes != nil {
+		if data, err = h.withLatestNotes(ctx, products, priced); err != nil {
+			h.logger.Error("failed to get latest product notes", "error", err)
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to retrieve notes")
+			return
+		}
+	}
+	response := ProductListResponse{
+		PaginatedResponse: *models.NewPaginatedResponse(http.StatusOK, "Products retrieved successfully", data, pagination),
+	}
+	if len(spec.Names) > 0 {
+		all := models.ProductFilter{}
+		if filter != nil {
+			all = *filter
+		}
+		if response.Facets, err = h.repo.Facets(ctx, all, spec); err != nil {
+			h.logger.Error("failed to count facets", "error", err)
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to count facets")
+			return
+		}
+	}
 
 	h.respondWithJSON(w, http.StatusOK, response)
 }
 
+// withLatestNotes returns products as admin lists them, with their latest
+// notes and, unless priced is nil, their effective prices
+func (h *ProductHandler) withLatestNotes(ctx context.Context, products []*models.Product, priced []*models.PricedProduct) ([]*models.AdminProduct, error) {
+	ids := make([]int, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	latest, err := h.notes.Latest(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make([]*models.AdminProduct, len(products))
+	for i, product := range products {
+		listed[i] = &models.AdminProduct{Product: product, LatestNote: latest[product.ID]}
+		if note := listed[i].LatestNote; note != nil {
+			note.Mentions = models.Mentions(note.Body)
+		}
+		if priced != nil {
+			listed[i].EffectivePrice = priced[i].EffectivePrice
+		}
+	}
+	return listed, nil
+}
+
+// sample returns a random sample of n of the products matching filter, all
+// when nil, and how many match
+func (h *ProductHandler) sample(ctx context.Context, filter *models.ProductFilter, n int) ([]*models.Product, int, error) {
+	all := models.ProductFilter{}
+	if filter != nil {
+		all = *filter
+	}
+	products, err := h.repo.Sample(ctx, all, n)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := h.repo.CountFiltered(ctx, all)
+	return products, total, err
+}
+
+// visibility narrows filter, nil for none, to the products public lists
+// show; for admin, to those of ?visibility and ?expiring_within, if given
+func (h *ProductHandler) visibility(w http.ResponseWriter, r *http.Request, filter *models.ProductFilter, admin bool) (*models.ProductFilter, bool) {
+	f := models.ProductFilter{}
+	if filter != nil {
+		f = *filter
+	}
+	if !admin {
+		f.Visibility = models.VisibilityVisible
+		return &f, true
+	}
+
+	visibility, within := r.URL.Query().Get("visibility"), r.URL.Query().Get("expiring_within")
+	if visibility == "" && within == "" {
+		return filter, true
+	}
+	if visibility != "" {
+		f.Visibility = visibility
+	}
+	if within != "" {
+		f.ExpiringWithin = within
+	}
+	if err := f.Validate(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return &f, true
+}
+
+// soldIn returns "product not found" unless the product, as it is now, is
+// sold in region
+func (h *ProductHandler) soldIn(ctx context.Context, id int, region string) error {
+	current, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !current.SoldIn(region) {
+		return fmt.Errorf("product not found")
+	}
+	return nil
+}
+
+// view returns the filter of the saved search named by ?view, or nil without
+// one
+func (h *ProductHandler) view(w http.ResponseWriter, r *http.Request) (*models.ProductFilter, bool) {
+	v := r.URL.Query().Get("view")
+	if v == "" {
+		return nil, true
+	}
+	id, err := strconv.Atoi(v)
+	if err != nil || h.views == nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid view")
+		return nil, false
+	}
+
+	search, err := h.views.GetByID(r.Context(), id)
+	if err != nil {
+		if err.Error() == "saved search not found" {
+			h.respondWithError(w, http.StatusNotFound, "Saved search not found")
+			return nil, false
+		}
+		h.logger.Error("failed to get saved search", "error", err, "saved_search_id", id)
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to retrieve saved search")
+		return nil, false
+	}
+	return &search.Filter, true
+}
+
 // GetProduct handles GET /api/v1/products/{id}
-// It returns a single product by ID
+// It returns a single product by ID, optionally as it was at a point in time
 //
 //	@Summary		Get product by ID
-//	@Description	Get a single product with all details
+//	@Description	Get a single product with all details. With as_of, the product as it was at that time. With region, or a regional API key, a product not sold in that region isn't found.
 //	@Tags			products
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Product ID"
-//	@Success		200	{object}	models.SuccessResponse	"Product details"
-//	@Failure		400	{object}	models.ErrorResponse	"Bad request"
-//	@Failure		404	{object}	models.ErrorResponse	"Product not found"
-//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Param			id		path		int		true	"Product ID"
+//	@Param			as_of	query		string	false	"RFC 3339 timestamp to reconstruct the product at"
+//	@Param			effective_price	query	bool	false	"Include the product's price after promotions"
+//	@Param			at		query		string	false	"RFC 3339 timestamp to evaluate promotions at (default as_of, or now)"
+//	@Param			region	query		string	false	"Only a product sold in this region; a regional API key's own by default"
+//	@Success		200		{object}	models.SuccessResponse	"Product details"
+//	@Header			200		{string}	Last-Modified			"When the product was last updated, for If-Unmodified-Since"
+//	@Failure		400		{object}	models.ErrorResponse	"Bad request"
+//	@Failure		403		{object}	models.ErrorResponse	"Region outside the API key's"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
 //	@Router			/products/{id} [get]
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -110,7 +405,38 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := h.repo.GetByID(ctx, id)
+	var product *models.Product
+	asOf := time.Now()
+	asOfStr := r.URL.Query().Get("as_of")
+	if asOfStr != "" {
+		if asOf, err = time.Parse(time.RFC3339, asOfStr); err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid as_of: must be an RFC 3339 timestamp")
+			return
+		}
+	}
+
+	pricedAt, ok := h.effectivePriceAt(w, r, asOf)
+	if !ok {
+		return
+	}
+
+	region, ok := salesRegion(h.logger, w, r)
+	if !ok {
+		return
+	}
+
+	if asOfStr != "" {
+		product, err = h.repo.GetByIDAsOf(ctx, id, asOf)
+		if err == nil && region != "" {
+			// Regions aren't versioned: the current ones apply
+			err = h.soldIn(ctx, id, region)
+		}
+	} else {
+		product, err = h.repo.GetByID(ctx, id)
+		if err == nil && !product.SoldIn(region) {
+			err = fmt.Errorf("product not found")
+		}
+	}
 	if err != nil {
 		if err.Error() == "product not found" {
 			h.respondWithError(w, http.StatusNotFound, "Product not found")
@@ -121,8 +447,84 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := models.NewSuccessResponse(http.StatusOK, "Product retrieved successfully", product)
+	var data interface{} = product
+	if pricedAt != nil {
+		priced, err := h.priced(ctx, *pricedAt, product)
+		if err != nil {
+			h.logger.Error("failed to evaluate promotions", "error", err, "product_id", id)
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to compute effective price")
+			return
+		}
+		data = priced[0]
+	}
+	response := models.NewSuccessResponse(http.StatusOK, "Product retrieved successfully", data)
 
+	setLastModified(w, product.UpdatedAt)
+	h.respondWithJSON(w, http.StatusOK, response)
+}
+
+// GetProductBySlug handles GET /api/v1/products/slug/{slug}
+// It returns a product by its slug, or redirects from one it had before
+//
+//	@Summary		Get a product by slug
+//	@Description	Get a product by its current slug. A slug the product had before answers 301 with the URL of the current one in Location, and the redirect in data, so clients can update the links they keep.
+//	@Tags			products
+//	@Produce		json
+//	@Param			slug	path		string	true	"Product slug"
+//	@Param			region	query		string	false	"Only a product sold in this region; a regional API key's own by default"
+//	@Success		200		{object}	models.SuccessResponse	"Product details"
+//	@Success		301		{object}	models.SuccessResponse{data=models.SlugRedirect}	"Former slug"
+//	@Header			200		{string}	Last-Modified			"When the product was last updated"
+//	@Header			301		{string}	Location				"/api/v1/products/slug/{slug}, with the current slug"
+//	@Failure		403		{object}	models.ErrorResponse	"Region outside the API key's"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/slug/{slug} [get]
+func (h *ProductHandler) GetProductBySlug(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requested := chi.URLParam(r, "slug")
+
+	region, ok := salesRegion(h.logger, w, r)
+	if !ok {
+		return
+	}
+
+	if h.slugs == nil {
+		h.respondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	id, current, err := h.slugs.Lookup(ctx, requested)
+	var product *models.Product
+	if err == nil {
+		product, err = h.repo.GetByID(ctx, id)
+	}
+	if err == nil && !product.SoldIn(region) {
+		err = fmt.Errorf("product not found")
+	}
+	if err != nil {
+		if err.Error() == "product not found" {
+			h.respondWithError(w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product by slug", "error", err, "slug", requested)
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to retrieve product")
+		return
+	}
+
+	if current != requested {
+		location := "/api/v1/products/slug/" + current
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		w.Header().Set("Location", location)
+		redirect := models.SlugRedirect{ProductID: id, Slug: current, OldSlug: requested, Location: location}
+		response := models.NewSuccessResponse(http.StatusMovedPermanently, "Product moved to a new slug", redirect)
+		h.respondWithJSON(w, http.StatusMovedPermanently, response)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Product retrieved successfully", product)
+	setLastModified(w, product.UpdatedAt)
 	h.respondWithJSON(w, http.StatusOK, response)
 }
 
@@ -130,12 +532,15 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 // It creates a new product
 //
 //	@Summary		Create a new product
-//	@Description	Create a new product in the inventory
+//	@Description	Create a new product in the inventory. quantity and unit_price are in unit, which defaults to each. With SKU_PATTERN set, a product without a sku gets the next generated one.
 //	@Tags			products
 //	@Accept			json
 //	@Produce		json
 //	@Param			product	body		models.Product			true	"Product data"
-//	@Success		201		{object}	models.SuccessResponse	"Created product"
+//	@Param			dry_run	query		bool					false	"Validate and return the result without creating (also X-Dry-Run header)"
+//	@Success		200		{object}	models.SuccessResponse	"Dry run result"
+//	@Success		201		{object}	models.SuccessResponse	"Created product, with its URL in the Location header"
+//	@Header			201		{string}	Location				"/api/v1/products/{id}"
 //	@Failure		400		{object}	models.ErrorResponse	"Bad request"
 //	@Failure		409		{object}	models.ErrorResponse	"Product with SKU already exists"
 //	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
@@ -144,12 +549,12 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var product models.Product
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+	if err := newDecoder(r).Decode(&product); err != nil {
 		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	if product.SKU == "" {
+	if product.SKU == "" && h.skus == nil {
 		h.respondWithError(w, http.StatusBadRequest, "SKU is required")
 		return
 	}
@@ -159,37 +564,148 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if SKU already exists
-	existing, err := h.repo.GetBySKU(ctx, product.SKU)
-	if err == nil && existing != nil {
-		h.respondWithError(w, http.StatusConflict, "Product with this SKU already exists")
+	if len(product.Category) > maxCategoryLength {
+		h.respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Category must be at most %d characters", maxCategoryLength))
+		return
+	}
+
+	if product.Unit != "" {
+		if _, err := h.units.Lookup(product.Unit); err != nil {
+			h.respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unknown unit %q, expected one of %s", product.Unit, strings.Join(h.units.Codes(), ", ")))
+			return
+		}
+	}
+
+	if err := product.ValidateVisibility(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if product.Slug != "" && !slug.Valid(product.Slug) {
+		h.respondWithError(w, http.StatusBadRequest, errInvalidSlug.Error())
 		return
 	}
 
-	if err := h.repo.Create(ctx, &product); err != nil {
+	tags, err := normalizeTags(product.Tags)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	product.Tags = tags
+	product.Regions = nil // Assigned through /admin/product-regions
+
+	// Check if SKU already exists
+	if product.SKU != "" {
+		existing, err := h.repo.GetBySKU(ctx, product.SKU)
+		if err == nil && existing != nil {
+			h.respondWithError(w, http.StatusConflict, "Product with this SKU already exists")
+			return
+		}
+	}
+
+	// A unique ID is given when copying a product from another region
+	if product.UID != "" {
+		if !id.Valid(string(product.UID)) {
+			h.respondWithError(w, http.StatusBadRequest, "uid must be a UUID or ULID, optionally prefixed with a region")
+			return
+		}
+		if _, err := h.repo.ResolveUID(ctx, string(product.UID)); err == nil {
+			h.respondWithError(w, http.StatusConflict, "Product with this uid already exists")
+			return
+		}
+	}
+
+	// Without a slug, one is made from the name
+	if product.Slug != "" {
+		if err := h.checkSlug(ctx, product.Slug, 0); err != nil {
+			if errors.Is(err, errSlugTaken) {
+				h.respondWithError(w, http.StatusConflict, "Another product has or had this slug")
+				return
+			}
+			h.logger.Error("failed to check product slug", "error", err, "slug", product.Slug)
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to create product")
+			return
+		}
+	}
+
+	err = h.tx.WithTx(ctx, func(ctx context.Context) error {
+		// Numbered in the transaction, so a failed create gives the number back
+		if product.SKU == "" {
+			generated, err := h.skus.Next(ctx)
+			if err != nil {
+				return err
+			}
+			product.SKU = generated
+		}
+		if err := h.repo.Create(ctx, &product); err != nil {
+			return err
+		}
+		return h.publisher.Publish(ctx, events.New(events.ProductCreated{Product: product}))
+	})
+	if err != nil {
 		h.logger.Error("failed to create product", "error", err, "sku", product.SKU)
 		h.respondWithError(w, http.StatusInternalServerError, "Failed to create product")
 		return
 	}
 
+	if database.IsDryRun(ctx) {
+		product.ID = 0 // Rolled back, the ID isn't reserved
+		response := models.NewSuccessResponse(http.StatusOK, "Dry run: product would be created", product)
+		h.respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
 	h.logger.Info("product created", "product_id", product.ID, "sku", product.SKU)
 	response := models.NewSuccessResponse(http.StatusCreated, "Product created successfully", product)
-	h.respondWithJSON(w, http.StatusCreated, response)
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/products/%d", product.ID), response)
+}
+
+// NextSKU handles GET /api/v1/products/next-sku
+// It previews the SKU the next product created without one gets
+//
+//	@Summary		Preview the next generated SKU
+//	@Description	The SKU a product created now without one would get, from SKU_PATTERN. It isn't reserved: a concurrent create may take it first.
+//	@Tags			products
+//	@Produce		json
+//	@Success		200	{object}	models.SuccessResponse{data=models.SKUPreview}	"Next SKU"
+//	@Failure		404	{object}	models.ErrorResponse	"SKU generation is disabled"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/next-sku [get]
+func (h *ProductHandler) NextSKU(w http.ResponseWriter, r *http.Request) {
+	if h.skus == nil {
+		h.respondWithError(w, http.StatusNotFound, "SKU generation is disabled")
+		return
+	}
+
+	next, err := h.skus.Preview(r.Context())
+	if err != nil {
+		h.logger.Error("failed to preview SKU", "error", err)
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to preview SKU")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Next SKU retrieved successfully", models.SKUPreview{SKU: next, Pattern: h.skus.Pattern()})
+	h.respondWithJSON(w, http.StatusOK, response)
 }
 
 // UpdateProduct handles PUT /api/v1/products/{id}
 // It updates an existing product
 //
 //	@Summary		Update product
-//	@Description	Update an existing product's information
+//	@Description	Update an existing product's information. An omitted unit keeps the current one. Updates by editor tokens aren't applied but held as a pending revision, returned with 202, until an approver approves it.
 //	@Tags			products
 //	@Accept			json
 //	@Produce		json
 //	@Param			id		path		int				true	"Product ID"
 //	@Param			product	body		models.Product	true	"Updated product data"
+//	@Param			dry_run	query		bool			false	"Validate and return the result without updating (also X-Dry-Run header)"
+//	@Param			If-Unmodified-Since	header	string	false	"Refuse with 412 if the product was updated after this HTTP date"
 //	@Success		200		{object}	models.SuccessResponse	"Updated product"
+//	@Success		202		{object}	models.SuccessResponse{data=models.ProductRevision}	"Revision awaiting approval, by an editor"
+//	@Header			202		{string}	Location				"/api/v1/revisions/{id}"
 //	@Failure		400		{object}	models.ErrorResponse	"Bad request"
 //	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		412		{object}	models.ErrorResponse	"Product modified after If-Unmodified-Since"
 //	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
 //	@Router			/products/{id} [put]
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
@@ -208,51 +724,155 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var product models.Product
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+	if err := newDecoder(r).Decode(&product); err != nil {
 		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	product.ID = id
 
-	if product.SKU == "" {
-		h.respondWithError(w, http.StatusBadRequest, "SKU is required")
+	if err := h.checkUpdate(&product); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if product.Name == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Product name is required")
-		return
-	}
+	// The precondition holds for editors too: their revision is of the
+	// product as it is now, so one over a newer product is refused alike
+	since, conditional := unmodifiedSince(r)
+	propose := h.revisions != nil && requestRole(r) == policy.RoleEditor
+	var current *models.Product
+	var rev *models.ProductRevision
+	err = h.tx.WithTx(ctx, func(ctx context.Context) error {
+		before, err := h.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		current = before
+		if conditional && modifiedSince(before.UpdatedAt, since) {
+			return errModified
+		}
 
-	if err := h.repo.Update(ctx, &product); err != nil {
+		if propose {
+			rev = &models.ProductRevision{ProductID: id, Product: product, Author: requestActor(r), BaseUpdatedAt: before.UpdatedAt}
+			return h.revisions.Create(ctx, rev)
+		}
+		return h.update(ctx, before, &product, "manual_update")
+	})
+	if err != nil {
 		if err.Error() == "product not found" {
 			h.respondWithError(w, http.StatusNotFound, "Product not found")
 			return
 		}
+		if errors.Is(err, errModified) {
+			setLastModified(w, current.UpdatedAt)
+			h.respondWithError(w, http.StatusPreconditionFailed, "Product was modified after If-Unmodified-Since")
+			return
+		}
+		if errors.Is(err, errSlugTaken) {
+			h.respondWithError(w, http.StatusConflict, "Another product has or had this slug")
+			return
+		}
+		if propose {
+			h.logger.Error("failed to propose product revision", "error", err, "product_id", id)
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to propose revision")
+			return
+		}
 		h.logger.Error("failed to update product", "error", err, "product_id", id)
 		h.respondWithError(w, http.StatusInternalServerError, "Failed to update product")
 		return
 	}
 
+	if propose {
+		h.revisionProposed(w, r, rev, current)
+		return
+	}
+
+	if database.IsDryRun(ctx) {
+		response := models.NewSuccessResponse(http.StatusOK, "Dry run: product would be updated", product)
+		h.respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
 	h.logger.Info("product updated", "product_id", id, "sku", product.SKU)
+	setLastModified(w, product.UpdatedAt)
 	response := models.NewSuccessResponse(http.StatusOK, "Product updated successfully", product)
 	h.respondWithJSON(w, http.StatusOK, response)
 }
 
+// checkUpdate validates an update of a product, normalizing its tags
+func (h *ProductHandler) checkUpdate(product *models.Product) error {
+	if product.SKU == "" {
+		return fmt.Errorf("SKU is required")
+	}
+	if product.Name == "" {
+		return fmt.Errorf("Product name is required")
+	}
+	if len(product.Category) > maxCategoryLength {
+		return fmt.Errorf("Category must be at most %d characters", maxCategoryLength)
+	}
+	if product.Unit != "" {
+		if _, err := h.units.Lookup(product.Unit); err != nil {
+			return fmt.Errorf("Unknown unit %q, expected one of %s", product.Unit, strings.Join(h.units.Codes(), ", "))
+		}
+	}
+	if err := product.ValidateVisibility(); err != nil {
+		return err
+	}
+	if product.Slug != "" && !slug.Valid(product.Slug) {
+		return errInvalidSlug
+	}
+
+	tags, err := normalizeTags(product.Tags)
+	if err != nil {
+		return err
+	}
+	product.Tags = tags
+	return nil
+}
+
+// update stores product over before, the locked current state, keeping what
+// updates don't change, and publishes the update with reason. A slug another
+// product has, or had, fails with errSlugTaken.
+func (h *ProductHandler) update(ctx context.Context, before, product *models.Product, reason string) error {
+	product.CreatedAt = before.CreatedAt
+	product.UID = before.UID // Never changes
+	product.Regions = before.Regions
+	product.Listed = before.Listed // Announced by the product-visibility job
+	if product.Unit == "" {
+		product.Unit = before.Unit
+	}
+	if product.Slug == "" {
+		product.Slug = before.Slug
+	} else if product.Slug != before.Slug {
+		if err := h.checkSlug(ctx, product.Slug, before.ID); err != nil {
+			return err
+		}
+	}
+	if err := h.repo.Update(ctx, product); err != nil {
+		return err
+	}
+
+	return h.publisher.Publish(ctx, events.ProductUpdates(before, product, reason)...)
+}
+
 // DeleteProduct handles DELETE /api/v1/products/{id}
-// It deletes a product
+// It moves a product to trash
 //
 //	@Summary		Delete product
-//	@Description	Delete a product by ID
+//	@Description	Delete a product by ID, moving it to trash (see /trash) until it's restored or the trash retention runs out
 //	@Tags			products
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path	int	true	"Product ID"
-//	@Success		204	{object}	models.SuccessResponse	"Product deleted successfully"
-//	@Failure		400	{object}	models.ErrorResponse	"Bad request"
-//	@Failure		404	{object}	models.ErrorResponse	"Product not found"
-//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Param			id		path		int		true	"Product ID"
+//	@Param			dry_run	query		bool	false	"Check the delete without performing it (also X-Dry-Run header)"
+//	@Param			If-Unmodified-Since	header	string	false	"Refuse with 412 if the product was updated after this HTTP date"
+//	@Success		200		{object}	models.SuccessResponse	"Dry run result: the product that would be deleted"
+//	@Success		204		"Product deleted successfully"
+//	@Failure		400		{object}	models.ErrorResponse	"Bad request"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		409		{object}	models.ErrorResponse	"Product is a component of a bundle"
+//	@Failure		412		{object}	models.ErrorResponse	"Product modified after If-Unmodified-Since"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
 //	@Router			/products/{id} [delete]
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -269,19 +889,104 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.repo.Delete(ctx, id); err != nil {
+	since, conditional := unmodifiedSince(r)
+	var existing *models.Product
+	err = h.tx.WithTx(ctx, func(ctx context.Context) error {
+		existing, err = h.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if conditional && modifiedSince(existing.UpdatedAt, since) {
+			return errModified
+		}
+
+		if err := h.repo.Delete(ctx, id); err != nil {
+			return err
+		}
+		if h.trash != nil {
+			if _, err := h.trash.Add(ctx, existing); err != nil {
+				return err
+			}
+		}
+
+		return h.publisher.Publish(ctx, events.New(events.ProductDeleted{ProductID: id, SKU: existing.SKU}))
+	})
+	if err != nil {
 		if err.Error() == "product not found" {
 			h.respondWithError(w, http.StatusNotFound, "Product not found")
 			return
 		}
+		if errors.Is(err, repository.ErrInBundle) {
+			h.respondWithError(w, http.StatusConflict, "Product is a component of a bundle; remove it from the bundle first")
+			return
+		}
+		if errors.Is(err, errModified) {
+			setLastModified(w, existing.UpdatedAt)
+			h.respondWithError(w, http.StatusPreconditionFailed, "Product was modified after If-Unmodified-Since")
+			return
+		}
 		h.logger.Error("failed to delete product", "error", err, "product_id", id)
 		h.respondWithError(w, http.StatusInternalServerError, "Failed to delete product")
 		return
 	}
 
+	if database.IsDryRun(ctx) {
+		response := models.NewSuccessResponse(http.StatusOK, "Dry run: product would be deleted", existing)
+		h.respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
 	h.logger.Info("product deleted", "product_id", id)
-	response := models.NewSuccessResponse(http.StatusNoContent, "Product deleted successfully", nil)
-	h.respondWithJSON(w, http.StatusNoContent, response)
+	respondNoContent(w)
+}
+
+// errSlugTaken fails a write giving a product a slug another product has,
+// or had: former slugs keep leading to their product
+var errSlugTaken = errors.New("slug taken by another product")
+
+var errInvalidSlug = errors.New("slug must be lowercase letters and digits, in words joined by hyphens")
+
+// checkSlug fails with errSlugTaken unless s is free for product id, 0 for a
+// new product
+func (h *ProductHandler) checkSlug(ctx context.Context, s string, id int) error {
+	if h.slugs == nil {
+		return nil
+	}
+	available, err := h.slugs.Available(ctx, s, id)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return errSlugTaken
+	}
+	return nil
+}
+
+// errModified fails the transaction of a write whose product changed after
+// the request's If-Unmodified-Since
+var errModified = errors.New("product modified since If-Unmodified-Since")
+
+// unmodifiedSince returns the date of a request's If-Unmodified-Since. An
+// invalid date is ignored, as RFC 9110 requires.
+func unmodifiedSince(r *http.Request) (time.Time, bool) {
+	value := r.Header.Get("If-Unmodified-Since")
+	if value == "" {
+		return time.Time{}, false
+	}
+	since, err := http.ParseTime(value)
+	return since, err == nil
+}
+
+// modifiedSince reports whether updated is after since, compared to the
+// second like the HTTP dates clients send back
+func modifiedSince(updated, since time.Time) bool {
+	return updated.Truncate(time.Second).After(since)
+}
+
+// setLastModified sends the time a product was last updated, for clients
+// to send back in If-Unmodified-Since
+func setLastModified(w http.ResponseWriter, updated time.Time) {
+	w.Header().Set("Last-Modified", updated.UTC().Format(http.TimeFormat))
 }
 
 // HealthCheck handles GET /api/v1/health
@@ -295,25 +1000,91 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 //	@Success		200	{object}	models.SuccessResponse	"Health status"
 //	@Router			/health [get]
 func (h *ProductHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
+	healthResponse.Write(w, http.StatusOK, time.Now())
+}
+
+// healthResponse is encoded once; probes hit it often and only the timestamp
+// changes
+var healthResponse = jsonenc.MustTimestamped(func(t time.Time) interface{} {
 	data := map[string]interface{}{
 		"service": "{{SERVICE_NAME}}",
 		"version": "1.0.0",
 	}
 	response := models.NewSuccessResponse(http.StatusOK, "Service is healthy", data)
-	h.respondWithJSON(w, http.StatusOK, response)
+	response.Timestamp = t
+	return response
+})
+
+// effectivePriceAt reads ?effective_price and ?at. It returns when to evaluate
+// promotions, or nil when effective prices weren't asked for, and false after
+// answering an invalid request.
+func (h *ProductHandler) effectivePriceAt(w http.ResponseWriter, r *http.Request, fallback time.Time) (*time.Time, bool) {
+	query := r.URL.Query()
+	if query.Get("effective_price") == "" {
+		return nil, true
+	}
+	if include, err := strconv.ParseBool(query.Get("effective_price")); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid effective_price: must be true or false")
+		return nil, false
+	} else if !include || h.promotions == nil {
+		return nil, true
+	}
+
+	at := fallback
+	if atStr := query.Get("at"); atStr != "" {
+		parsed, err := time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid at: must be an RFC 3339 timestamp")
+			return nil, false
+		}
+		at = parsed
+	}
+	return &at, true
+}
+
+// priced pairs products with their effective prices at a point in time
+func (h *ProductHandler) priced(ctx context.Context, at time.Time, products ...*models.Product) ([]*models.PricedProduct, error) {
+	prices, err := h.promotions.EffectivePrices(ctx, at, products...)
+	if err != nil {
+		return nil, err
+	}
+
+	priced := make([]*models.PricedProduct, len(products))
+	for i, product := range products {
+		priced[i] = &models.PricedProduct{Product: product, EffectivePrice: prices[i]}
+	}
+	return priced, nil
+}
+
+// normalizeTags trims, lowercases, sorts, and deduplicates tags, which are
+// nil when there are none
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("Tags must be at most %d characters", maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("A product has at most %d tags", maxTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
 }
 
 // Helper methods for consistent JSON responses
 
 func (h *ProductHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		h.logger.Error("failed to encode response", "error", err)
-	}
+	respondWithJSON(h.logger, w, code, payload)
 }
 
 func (h *ProductHandler) respondWithError(w http.ResponseWriter, code int, message string) {
-	response := models.NewErrorResponse(code, message)
-	h.respondWithJSON(w, code, response)
+	respondWithError(h.logger, w, code, message)
 }
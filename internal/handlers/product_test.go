@@ -0,0 +1,529 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/policy"
+	"{{MODULE_NAME}}/internal/promotions"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/tokens"
+	"{{MODULE_NAME}}/internal/units"
+)
+
+// memoryRepo is an in-memory ProductRepository for handler tests
+type memoryRepo struct {
+	repository.ProductRepository
+	products map[int]*models.Product
+	nextID   int
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{products: make(map[int]*models.Product)}
+}
+
+func (r *memoryRepo) Create(ctx context.Context, product *models.Product) error {
+	r.nextID++
+	product.ID = r.nextID
+	cp := *product
+	r.products[product.ID] = &cp
+	return nil
+}
+
+func (r *memoryRepo) GetByID(ctx context.Context, id int) (*models.Product, error) {
+	p, ok := r.products[id]
+	if !ok {
+		return nil, fmt.Errorf("product not found")
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (r *memoryRepo) GetByIDForUpdate(ctx context.Context, id int) (*models.Product, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r *memoryRepo) Update(ctx context.Context, product *models.Product) error {
+	if _, ok := r.products[product.ID]; !ok {
+		return fmt.Errorf("product not found")
+	}
+	product.UpdatedAt = time.Now()
+	cp := *product
+	r.products[product.ID] = &cp
+	return nil
+}
+
+// GetByIDAsOf finds the product only if it was created by asOf; the memory
+// repo keeps no history
+func (r *memoryRepo) GetByIDAsOf(ctx context.Context, id int, asOf time.Time) (*models.Product, error) {
+	p, err := r.GetByID(ctx, id)
+	if err != nil || p.CreatedAt.After(asOf) {
+		return nil, fmt.Errorf("product not found")
+	}
+	return p, nil
+}
+
+func (r *memoryRepo) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	for _, p := range r.products {
+		if p.SKU == sku {
+			cp := *p
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("product not found")
+}
+
+func (r *memoryRepo) Delete(ctx context.Context, id int) error {
+	if _, ok := r.products[id]; !ok {
+		return fmt.Errorf("product not found")
+	}
+	delete(r.products, id)
+	return nil
+}
+
+type inlineTx struct{}
+
+func (inlineTx) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type discardPublisher struct{}
+
+func (discardPublisher) Publish(ctx context.Context, evts ...events.Event) error { return nil }
+
+func newTestRouter(repo repository.ProductRepository) http.Handler {
+	unitTable, err := units.New([]*models.Unit{{Code: "each", Dimension: "count", Factor: 1}, {Code: "kg", Dimension: "mass", Factor: 1000}, {Code: "g", Dimension: "mass", Factor: 1}})
+	if err != nil {
+		panic(err)
+	}
+	h := NewProductHandler(repo, nil, nil, nil, nil, nil, inlineTx{}, discardPublisher{}, nil, unitTable, nil, models.FacetSpec{}, testLogger)
+	r := chi.NewRouter()
+	r.Post("/api/v1/products", h.CreateProduct)
+	r.Get("/api/v1/products/{id}", h.GetProduct)
+	r.Put("/api/v1/products/{id}", h.UpdateProduct)
+	r.Delete("/api/v1/products/{id}", h.DeleteProduct)
+	return r
+}
+
+func TestCreateProduct_Location(t *testing.T) {
+	router := newTestRouter(newMemoryRepo())
+
+	body := `{"sku":"LOC-1","name":"Location Test","quantity":1,"unit_price":1.5}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/products", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	if loc := w.Header().Get("Location"); loc != "/api/v1/products/1" {
+		t.Errorf("Location = %q, want %q", loc, "/api/v1/products/1")
+	}
+}
+
+func TestCreateProduct_Unit(t *testing.T) {
+	repo := newMemoryRepo()
+	router := newTestRouter(repo)
+
+	for body, want := range map[string]int{
+		`{"sku":"U-1","name":"Flour","unit":"kg"}`:       http.StatusCreated,
+		`{"sku":"U-2","name":"Flour","unit":"bushel"}`:   http.StatusBadRequest,
+		`{"sku":"U-3","name":"Flour","unit":"Kilogram"}`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/products", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Errorf("%s: status = %d, want %d: %s", body, w.Code, want, w.Body.String())
+		}
+	}
+	if p, err := repo.GetBySKU(context.Background(), "U-1"); err != nil || p.Unit != "kg" {
+		t.Errorf("created product = %+v, %v", p, err)
+	}
+}
+
+func TestDeleteProduct_NoContent(t *testing.T) {
+	repo := newMemoryRepo()
+	_ = repo.Create(context.Background(), &models.Product{SKU: "DEL-1", Name: "Delete Test"})
+	router := newTestRouter(repo)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/products/1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/products/1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestConditionalWrites(t *testing.T) {
+	repo := newMemoryRepo()
+	updated := time.Date(2026, time.October, 14, 9, 30, 0, 500_000_000, time.UTC)
+	_ = repo.Create(context.Background(), &models.Product{SKU: "IUS-1", Name: "Sheet Row", UpdatedAt: updated})
+	router := newTestRouter(repo)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/products/1", nil))
+	lastModified := w.Header().Get("Last-Modified")
+	if lastModified != "Wed, 14 Oct 2026 09:30:00 GMT" {
+		t.Fatalf("Last-Modified = %q", lastModified)
+	}
+
+	tests := []struct {
+		method, since string
+		want          int
+	}{
+		{http.MethodPut, "Wed, 14 Oct 2026 09:29:59 GMT", http.StatusPreconditionFailed},
+		{http.MethodDelete, "Wed, 14 Oct 2026 09:29:59 GMT", http.StatusPreconditionFailed},
+		{http.MethodPut, "not a date", http.StatusOK},                 // Ignored
+		{http.MethodPut, lastModified, http.StatusPreconditionFailed}, // Updated by the last request
+		{http.MethodDelete, "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/v1/products/1", strings.NewReader(`{"sku":"IUS-1","name":"Edited"}`))
+		if tt.since != "" {
+			req.Header.Set("If-Unmodified-Since", tt.since)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s with If-Unmodified-Since %q: status = %d, want %d: %s", tt.method, tt.since, w.Code, tt.want, w.Body.String())
+		}
+		if w.Code == http.StatusPreconditionFailed && w.Header().Get("Last-Modified") == "" {
+			t.Errorf("%s: 412 without Last-Modified", tt.method)
+		}
+	}
+}
+
+// TestConditionalWrites_Editor checks that an editor's update is held as a
+// revision only if the product is unmodified since If-Unmodified-Since
+func TestConditionalWrites_Editor(t *testing.T) {
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(t.TempDir(), "editor.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	repo := repository.NewProductRepository(db)
+	if err := repo.Create(context.Background(), &models.Product{SKU: "IUS-2", Name: "Sheet Row"}); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	revisions := repository.NewProductRevisionRepository(db)
+	h := NewProductHandler(repo, nil, nil, revisions, nil, nil, db, discardPublisher{}, nil, nil, nil, models.FacetSpec{}, testLogger)
+	router := chi.NewRouter()
+	router.Put("/api/v1/products/{id}", h.UpdateProduct)
+
+	editor := &tokens.Claims{Subject: "editor", Scope: tokens.Scope{Role: policy.RoleEditor}}
+	tests := []struct {
+		since string
+		want  int
+	}{
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), http.StatusPreconditionFailed},
+		{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/products/1", strings.NewReader(`{"sku":"IUS-2","name":"Edited"}`))
+		req.Header.Set("If-Unmodified-Since", tt.since)
+		req = req.WithContext(tokens.WithClaims(req.Context(), editor))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("If-Unmodified-Since %q: status = %d, want %d: %s", tt.since, w.Code, tt.want, w.Body.String())
+		}
+	}
+
+	// Only the update that met its precondition was held
+	if n, err := revisions.Count(context.Background(), 1, models.RevisionPending); err != nil || n != 1 {
+		t.Errorf("pending revisions = %d, %v, want 1", n, err)
+	}
+}
+
+func TestGetProduct_AsOf(t *testing.T) {
+	repo := newMemoryRepo()
+	_ = repo.Create(context.Background(), &models.Product{
+		SKU:       "ASOF-1",
+		Name:      "As Of Test",
+		CreatedAt: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC),
+	})
+	router := newTestRouter(repo)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", http.StatusOK},
+		{"?as_of=2024-07-01T00:00:00Z", http.StatusOK},
+		{"?as_of=2024-01-01T00:00:00Z", http.StatusNotFound},
+		{"?as_of=yesterday", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/1"+tt.query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("GET /api/v1/products/1%s status = %d, want %d", tt.query, w.Code, tt.want)
+		}
+	}
+}
+
+// memorySlugs is a ProductSlugRepository of fixed slugs, current and former
+type memorySlugs struct {
+	repository.ProductSlugRepository
+	current map[string]int    // Slug to product ID
+	former  map[string]string // Former slug to current
+}
+
+func (s memorySlugs) Lookup(ctx context.Context, slug string) (int, string, error) {
+	if current, ok := s.former[slug]; ok {
+		slug = current
+	}
+	id, ok := s.current[slug]
+	if !ok {
+		return 0, "", fmt.Errorf("product not found")
+	}
+	return id, slug, nil
+}
+
+func (s memorySlugs) Available(ctx context.Context, slug string, id int) (bool, error) {
+	if owner, ok := s.current[slug]; ok && owner != id {
+		return false, nil
+	}
+	_, former := s.former[slug]
+	return !former, nil
+}
+
+func TestProductSlugs(t *testing.T) {
+	repo := newMemoryRepo()
+	_ = repo.Create(context.Background(), &models.Product{SKU: "SLUG-1", Name: "Widget", Slug: "widget"})
+	unitTable, _ := units.New([]*models.Unit{{Code: "each", Dimension: "count", Factor: 1}})
+	slugs := memorySlugs{current: map[string]int{"widget": 1}, former: map[string]string{"blue-widget": "widget"}}
+	h := NewProductHandler(repo, nil, nil, nil, slugs, nil, inlineTx{}, discardPublisher{}, nil, unitTable, nil, models.FacetSpec{}, testLogger)
+	router := chi.NewRouter()
+	router.Post("/api/v1/products", h.CreateProduct)
+	router.Get("/api/v1/products/slug/{slug}", h.GetProductBySlug)
+	router.Put("/api/v1/products/{id}", h.UpdateProduct)
+
+	tests := []struct {
+		method, path, body string
+		want               int
+		location           string
+	}{
+		{http.MethodGet, "/api/v1/products/slug/widget", "", http.StatusOK, ""},
+		{http.MethodGet, "/api/v1/products/slug/blue-widget?region=de", "", http.StatusMovedPermanently, "/api/v1/products/slug/widget?region=de"},
+		{http.MethodGet, "/api/v1/products/slug/gadget", "", http.StatusNotFound, ""},
+		{http.MethodPost, "/api/v1/products", `{"sku":"SLUG-2","name":"Widget","slug":"Widget"}`, http.StatusBadRequest, ""},
+		{http.MethodPost, "/api/v1/products", `{"sku":"SLUG-2","name":"Widget","slug":"blue-widget"}`, http.StatusConflict, ""},
+		{http.MethodPut, "/api/v1/products/1", `{"sku":"SLUG-1","name":"Widget","slug":"blue-widget"}`, http.StatusConflict, ""},
+		{http.MethodPut, "/api/v1/products/1", `{"sku":"SLUG-1","name":"Widget"}`, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s = %d, Location %q; want %d, %q: %s", tt.method, tt.path, w.Code, w.Header().Get("Location"), tt.want, tt.location, w.Body.String())
+		}
+	}
+	if p, _ := repo.GetByID(context.Background(), 1); p.Slug != "widget" {
+		t.Errorf("slug after an update without one = %q, want widget kept", p.Slug)
+	}
+}
+
+// memoryNotes is an in-memory ProductNoteRepository
+type memoryNotes struct {
+	repository.ProductNoteRepository
+	notes  map[int]*models.ProductNote
+	nextID int
+}
+
+func (r *memoryNotes) Create(ctx context.Context, note *models.ProductNote) error {
+	r.nextID++
+	note.ID = r.nextID
+	cp := *note
+	r.notes[note.ID] = &cp
+	return nil
+}
+
+func (r *memoryNotes) GetByID(ctx context.Context, productID, id int) (*models.ProductNote, error) {
+	note, ok := r.notes[id]
+	if !ok || note.ProductID != productID {
+		return nil, fmt.Errorf("product note not found")
+	}
+	cp := *note
+	return &cp, nil
+}
+
+func (r *memoryNotes) Update(ctx context.Context, note *models.ProductNote) error {
+	cp := *note
+	r.notes[note.ID] = &cp
+	return nil
+}
+
+func (r *memoryNotes) Delete(ctx context.Context, productID, id int) error {
+	delete(r.notes, id)
+	return nil
+}
+
+// recordingPublisher keeps the events published
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, evts ...events.Event) error {
+	p.events = append(p.events, evts...)
+	return nil
+}
+
+func TestProductNotes(t *testing.T) {
+	repo := newMemoryRepo()
+	_ = repo.Create(context.Background(), &models.Product{SKU: "NOTE-1", Name: "Widget"})
+	notes := &memoryNotes{notes: make(map[int]*models.ProductNote)}
+	publisher := &recordingPublisher{}
+	h := NewNoteHandler(notes, repo, inlineTx{}, publisher, testLogger)
+	router := chi.NewRouter()
+	router.Post("/api/v1/products/{id}/notes", h.CreateNote)
+	router.Put("/api/v1/products/{id}/notes/{noteID}", h.UpdateNote)
+	router.Delete("/api/v1/products/{id}/notes/{noteID}", h.DeleteNote)
+
+	as := func(subject string) *tokens.Claims {
+		if subject == "" {
+			return nil // ADMIN_TOKEN
+		}
+		return &tokens.Claims{Subject: subject}
+	}
+	tests := []struct {
+		name, subject, method, path, body string
+		want                              int
+		mentioned                         []string
+	}{
+		{"create mentioning", "dana", http.MethodPost, "/api/v1/products/1/notes", `{"body":"Overflow on B7, ask @Sam or @dana. Mail sam@example.com"}`, http.StatusCreated, []string{"sam"}},
+		{"empty body", "dana", http.MethodPost, "/api/v1/products/1/notes", `{"body":"  "}`, http.StatusBadRequest, nil},
+		{"unknown product", "dana", http.MethodPost, "/api/v1/products/2/notes", `{"body":"Hi"}`, http.StatusNotFound, nil},
+		{"edit by another", "sam", http.MethodPut, "/api/v1/products/1/notes/1", `{"body":"Moved"}`, http.StatusForbidden, nil},
+		{"pin by another", "sam", http.MethodPut, "/api/v1/products/1/notes/1", `{"pinned":true}`, http.StatusOK, nil},
+		{"edit adding a mention", "dana", http.MethodPut, "/api/v1/products/1/notes/1", `{"body":"Overflow on B7, ask @sam or @lee."}`, http.StatusOK, []string{"lee"}},
+		{"other product's note", "dana", http.MethodPut, "/api/v1/products/2/notes/1", `{"pinned":false}`, http.StatusNotFound, nil},
+		{"delete by another", "sam", http.MethodDelete, "/api/v1/products/1/notes/1", "", http.StatusForbidden, nil},
+		{"delete by admin", "", http.MethodDelete, "/api/v1/products/1/notes/1", "", http.StatusNoContent, nil},
+	}
+	for _, tt := range tests {
+		publisher.events = nil
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		if claims := as(tt.subject); claims != nil {
+			req = req.WithContext(tokens.WithClaims(req.Context(), claims))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+			continue
+		}
+		var mentioned []string
+		for _, evt := range publisher.events {
+			mentioned = append(mentioned, evt.Payload.(events.NoteMentioned).Mentioned...)
+		}
+		if fmt.Sprint(mentioned) != fmt.Sprint(tt.mentioned) {
+			t.Errorf("%s: mentioned %v, want %v", tt.name, mentioned, tt.mentioned)
+		}
+	}
+
+	if got := models.Mentions("@Ann, @ann and (@bob_). a@b.c @"); fmt.Sprint(got) != "[ann bob]" {
+		t.Errorf("Mentions = %v, want [ann bob]", got)
+	}
+}
+
+// FuzzListProducts sends arbitrary query strings to GET /products and GET
+// /admin/products, through the parsing of limit, offset, view,
+// effective_price and at, region, visibility and expiring_within, facets, and
+// sort. Whatever the query, the list must answer 200 or 400, or 404 for a
+// view naming no saved search; never 500. Seeds are in
+// testdata/fuzz/FuzzListProducts.
+func FuzzListProducts(f *testing.F) {
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(f.TempDir(), "fuzz.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		f.Fatalf("failed to open SQLite database: %v", err)
+	}
+	f.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		f.Fatalf("failed to run migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	products := repository.NewProductRepository(db)
+	for _, p := range []*models.Product{
+		{SKU: "FZ-1", Name: "Hammer", Category: "tools", Quantity: 3, UnitPrice: 12},
+		{SKU: "FZ-2", Name: "Nails", Category: "fasteners", Quantity: 0, UnitPrice: 4},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			f.Fatalf("failed to create product: %v", err)
+		}
+	}
+	views := repository.NewSavedSearchRepository(db)
+	if err := views.Create(ctx, &models.SavedSearch{Name: "Tools", Filter: models.ProductFilter{Category: "tools", Sort: "name"}}); err != nil {
+		f.Fatalf("failed to create saved search: %v", err)
+	}
+	unitTable, err := units.Load(ctx, repository.NewUnitRepository(db))
+	if err != nil {
+		f.Fatalf("failed to load units: %v", err)
+	}
+	h := NewProductHandler(products, views, nil, nil, nil, repository.NewProductNoteRepository(db), db, discardPublisher{}, promotions.NewService(repository.NewPromotionRepository(db)), unitTable, nil, models.FacetSpec{PriceBounds: []float64{10, 100}, LowStock: 5}, testLogger)
+
+	f.Fuzz(func(t *testing.T, query string, admin bool) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/products", nil)
+		r.URL.RawQuery = query
+		w := httptest.NewRecorder()
+		if admin {
+			h.ListAdminProducts(w, r)
+		} else {
+			h.ListProducts(w, r)
+		}
+
+		switch {
+		case w.Code == http.StatusOK, w.Code == http.StatusBadRequest:
+		case w.Code == http.StatusNotFound && r.URL.Query().Get("view") != "":
+		default:
+			t.Fatalf("GET ?%s (admin %v) = %d, want 200 or 400: %s", query, admin, w.Code, w.Body.String())
+		}
+		if !json.Valid(w.Body.Bytes()) {
+			t.Fatalf("GET ?%s (admin %v) answered invalid JSON: %s", query, admin, w.Body.String())
+		}
+	})
+}
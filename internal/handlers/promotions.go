@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/cache"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/promotions"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type PromotionHandler struct {
+	repo   repository.PromotionRepository
+	cache  *cache.Cache
+	logger *slog.Logger
+}
+
+// NewPromotionHandler creates the promotion handler. Every change invalidates
+// the cached responses showing effective prices; responseCache may be nil.
+func NewPromotionHandler(repo repository.PromotionRepository, responseCache *cache.Cache, logger *slog.Logger) *PromotionHandler {
+	return &PromotionHandler{repo: repo, cache: responseCache, logger: logger}
+}
+
+// ListPromotions handles GET /api/v1/promotions
+// It returns a paginated list of promotions
+//
+//	@Summary		List promotions
+//	@Description	Get a paginated list of promotions, newest first, whether in effect or not
+//	@Tags			promotions
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int	false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse	"List of promotions with pagination metadata"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/promotions [get]
+func (h *PromotionHandler) ListPromotions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	list, err := h.repo.List(ctx, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list promotions", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve promotions")
+		return
+	}
+
+	total, err := h.repo.Count(ctx)
+	if err != nil {
+		h.logger.Error("failed to count promotions", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count promotions")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Promotions retrieved successfully", list, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// GetPromotion handles GET /api/v1/promotions/{id}
+//
+//	@Summary		Get promotion by ID
+//	@Tags			promotions
+//	@Produce		json
+//	@Param			id	path		int	true	"Promotion ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.Promotion}	"Promotion"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid promotion ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Promotion not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/promotions/{id} [get]
+func (h *PromotionHandler) GetPromotion(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.promotionID(w, r)
+	if !ok {
+		return
+	}
+
+	promotion, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		h.respondWithRepoError(w, err, "get", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Promotion retrieved successfully", promotion)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CreatePromotion handles POST /api/v1/promotions
+//
+//	@Summary		Create a promotion
+//	@Description	A percentage or fixed discount for all products, one product (target is its ID), a category, or a tag, optionally limited to [starts_at, ends_at). Stackable promotions combine; others apply alone, see the stacking rules in the README.
+//	@Tags			promotions
+//	@Accept			json
+//	@Produce		json
+//	@Param			promotion	body		models.Promotion		true	"Promotion"
+//	@Success		201			{object}	models.SuccessResponse{data=models.Promotion}	"Created promotion, with its URL in the Location header"
+//	@Header			201			{string}	Location				"/api/v1/promotions/{id}"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid promotion"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/promotions [post]
+func (h *PromotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	var promotion models.Promotion
+	if !h.decode(w, r, &promotion) {
+		return
+	}
+
+	if err := h.repo.Create(r.Context(), &promotion); err != nil {
+		h.logger.Error("failed to create promotion", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to create promotion")
+		return
+	}
+	h.cache.Invalidate(r.Context(), cache.PromotionsTag)
+
+	h.logger.Info("promotion created", "promotion_id", promotion.ID)
+	response := models.NewSuccessResponse(http.StatusCreated, "Promotion created successfully", promotion)
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/promotions/%d", promotion.ID), response)
+}
+
+// UpdatePromotion handles PUT /api/v1/promotions/{id}
+//
+//	@Summary		Update promotion
+//	@Tags			promotions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"Promotion ID"
+//	@Param			promotion	body		models.Promotion	true	"Updated promotion"
+//	@Success		200			{object}	models.SuccessResponse{data=models.Promotion}	"Updated promotion"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid promotion"
+//	@Failure		404			{object}	models.ErrorResponse	"Promotion not found"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/promotions/{id} [put]
+func (h *PromotionHandler) UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.promotionID(w, r)
+	if !ok {
+		return
+	}
+
+	var promotion models.Promotion
+	if !h.decode(w, r, &promotion) {
+		return
+	}
+	promotion.ID = id
+
+	ctx := r.Context()
+	existing, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		h.respondWithRepoError(w, err, "update", id)
+		return
+	}
+	promotion.CreatedAt = existing.CreatedAt
+
+	if err := h.repo.Update(ctx, &promotion); err != nil {
+		h.respondWithRepoError(w, err, "update", id)
+		return
+	}
+	h.cache.Invalidate(ctx, cache.PromotionsTag)
+
+	h.logger.Info("promotion updated", "promotion_id", id)
+	response := models.NewSuccessResponse(http.StatusOK, "Promotion updated successfully", promotion)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// DeletePromotion handles DELETE /api/v1/promotions/{id}
+//
+//	@Summary		Delete promotion
+//	@Tags			promotions
+//	@Param			id	path	int	true	"Promotion ID"
+//	@Success		204	"Promotion deleted successfully"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid promotion ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Promotion not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/promotions/{id} [delete]
+func (h *PromotionHandler) DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.promotionID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), id); err != nil {
+		h.respondWithRepoError(w, err, "delete", id)
+		return
+	}
+	h.cache.Invalidate(r.Context(), cache.PromotionsTag)
+
+	h.logger.Info("promotion deleted", "promotion_id", id)
+	respondNoContent(w)
+}
+
+func (h *PromotionHandler) promotionID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid promotion ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// decode reads and validates a promotion from the request body
+func (h *PromotionHandler) decode(w http.ResponseWriter, r *http.Request, promotion *models.Promotion) bool {
+	if err := newDecoder(r).Decode(promotion); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body")
+		return false
+	}
+	if err := promotions.Validate(promotion); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func (h *PromotionHandler) respondWithRepoError(w http.ResponseWriter, err error, action string, id int) {
+	if err.Error() == "promotion not found" {
+		respondWithError(h.logger, w, http.StatusNotFound, "Promotion not found")
+		return
+	}
+	h.logger.Error("failed to "+action+" promotion", "error", err, "promotion_id", id)
+	respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action+" promotion")
+}
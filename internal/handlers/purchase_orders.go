@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/purchasing"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type PurchaseOrderHandler struct {
+	repo       repository.PurchaseOrderRepository
+	purchasing *purchasing.Service
+	logger     *slog.Logger
+}
+
+func NewPurchaseOrderHandler(repo repository.PurchaseOrderRepository, purchasingService *purchasing.Service, logger *slog.Logger) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{repo: repo, purchasing: purchasingService, logger: logger}
+}
+
+// SupplierRequest names who supplies a product
+type SupplierRequest struct {
+	Supplier              string `json:"supplier" example:"Acme Wholesale"`
+	EconomicOrderQuantity *int   `json:"economic_order_quantity,omitempty" example:"120"` // Computed when omitted
+}
+
+// PurchaseOrderLinesRequest changes quantities of a draft's lines
+type PurchaseOrderLinesRequest struct {
+	Lines []PurchaseOrderLineQuantity `json:"lines"`
+}
+
+// PurchaseOrderLineQuantity is a line's new quantity, 0 to remove it
+type PurchaseOrderLineQuantity struct {
+	ProductID int `json:"product_id" example:"42"`
+	Quantity  int `json:"quantity" example:"150"`
+}
+
+// GetSupplier handles GET /api/v1/products/{id}/supplier
+//
+//	@Summary		Get supplier
+//	@Description	Get who supplies a product, and its economic order quantity when fixed
+//	@Tags			products
+//	@Produce		json
+//	@Param			id	path		int	true	"Product ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.ProductSupplier}	"Supplier"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid product ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Product has no supplier"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/supplier [get]
+func (h *PurchaseOrderHandler) GetSupplier(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r, "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	supplier, err := h.repo.GetSupplier(r.Context(), id)
+	if err != nil {
+		h.respondWithServiceError(w, err, "retrieve supplier", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Supplier retrieved successfully", supplier)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// SetSupplier handles PUT /api/v1/products/{id}/supplier
+//
+//	@Summary		Set supplier
+//	@Description	Set who supplies a product, which purchase orders are drafted to, optionally fixing its economic order quantity
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int				true	"Product ID"
+//	@Param			supplier	body		SupplierRequest	true	"Supplier"
+//	@Success		200			{object}	models.SuccessResponse{data=models.ProductSupplier}	"Supplier"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid supplier"
+//	@Failure		404			{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/supplier [put]
+func (h *PurchaseOrderHandler) SetSupplier(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r, "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var req SupplierRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	supplier, err := h.purchasing.SetSupplier(r.Context(), id, req.Supplier, req.EconomicOrderQuantity)
+	if err != nil {
+		h.respondWithServiceError(w, err, "set supplier", id)
+		return
+	}
+
+	h.logger.Info("supplier set", "product_id", id, "supplier", supplier.Supplier)
+	response := models.NewSuccessResponse(http.StatusOK, "Supplier set successfully", supplier)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// DeleteSupplier handles DELETE /api/v1/products/{id}/supplier
+//
+//	@Summary		Remove supplier
+//	@Description	Remove a product's supplier; it's no longer drafted on purchase orders
+//	@Tags			products
+//	@Param			id	path	int	true	"Product ID"
+//	@Success		204	"Supplier removed"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid product ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Product has no supplier"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/supplier [delete]
+func (h *PurchaseOrderHandler) DeleteSupplier(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r, "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteSupplier(r.Context(), id); err != nil {
+		h.respondWithServiceError(w, err, "remove supplier", id)
+		return
+	}
+
+	h.logger.Info("supplier removed", "product_id", id)
+	respondNoContent(w)
+}
+
+// ListPurchaseOrders handles GET /api/v1/purchase-orders
+//
+//	@Summary		List purchase orders
+//	@Description	Get a paginated list of purchase orders without their lines, most recently created first, optionally by status or supplier
+//	@Tags			purchase-orders
+//	@Produce		json
+//	@Param			status		query		string	false	"Only orders with this status"	Enums(draft, approved, submitted, received, cancelled)
+//	@Param			supplier	query		string	false	"Only orders from this supplier"
+//	@Param			limit		query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset		query		int		false	"Number of items to skip"				default(0)
+//	@Success		200			{object}	models.PaginatedResponse{data=[]models.PurchaseOrder}	"List of purchase orders with pagination metadata"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid status"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/purchase-orders [get]
+func (h *PurchaseOrderHandler) ListPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	limit := 50
+	offset := 0
+
+	if l := query.Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := query.Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	filter := models.PurchaseOrderFilter{Status: query.Get("status"), Supplier: query.Get("supplier")}
+	if filter.Status != "" && !purchasing.ValidStatus(filter.Status) {
+		respondWithError(h.logger, w, http.StatusBadRequest, "status must be one of "+strings.Join(purchasing.Statuses, ", "))
+		return
+	}
+
+	orders, err := h.repo.List(ctx, filter, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list purchase orders", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve purchase orders")
+		return
+	}
+
+	total, err := h.repo.Count(ctx, filter)
+	if err != nil {
+		h.logger.Error("failed to count purchase orders", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count purchase orders")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Purchase orders retrieved successfully", orders, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// DraftPurchaseOrders handles POST /api/v1/purchase-orders/drafts
+//
+//	@Summary		Draft purchase orders
+//	@Description	Add the products at or below the reorder point of their forecast, with a supplier and no open purchase order, to their supplier's draft, creating drafts as needed. Each is ordered in the larger of its suggested and economic order quantities. Audited as purchase_order.draft; ?dry_run=true previews the drafts.
+//	@Tags			purchase-orders
+//	@Produce		json
+//	@Success		200	{object}	models.SuccessResponse{data=models.PurchaseOrderDrafts}	"Drafts created or added to, and products to reorder without a supplier"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/purchase-orders/drafts [post]
+func (h *PurchaseOrderHandler) DraftPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	drafts, err := h.purchasing.Draft(r.Context(), r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "draft purchase orders", 0)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Purchase orders drafted successfully", drafts)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// GetPurchaseOrder handles GET /api/v1/purchase-orders/{id}
+//
+//	@Summary		Get purchase order
+//	@Description	Get a purchase order with its lines
+//	@Tags			purchase-orders
+//	@Produce		json
+//	@Param			id	path		int	true	"Purchase order ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.PurchaseOrder}	"Purchase order"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid purchase order ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Purchase order not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/purchase-orders/{id} [get]
+func (h *PurchaseOrderHandler) GetPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r, "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	order, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		h.respondWithServiceError(w, err, "retrieve purchase order", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Purchase order retrieved successfully", order)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// UpdatePurchaseOrderLines handles PUT /api/v1/purchase-orders/{id}/lines
+//
+//	@Summary		Edit draft lines
+//	@Description	Change the quantities of a draft's lines by product; a quantity of 0 removes the line. Audited as purchase_order.update.
+//	@Tags			purchase-orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Purchase order ID"
+//	@Param			lines	body		PurchaseOrderLinesRequest	true	"Quantities"
+//	@Success		200		{object}	models.SuccessResponse{data=models.PurchaseOrder}	"Updated draft"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid quantities"
+//	@Failure		404		{object}	models.ErrorResponse	"Purchase order not found"
+//	@Failure		409		{object}	models.ErrorResponse	"Order isn't a draft"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/purchase-orders/{id}/lines [put]
+func (h *PurchaseOrderHandler) UpdatePurchaseOrderLines(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.pathID(w, r, "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	var req PurchaseOrderLinesRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	quantities := make(map[int]int, len(req.Lines))
+	for _, line := range req.Lines {
+		if _, ok := quantities[line.ProductID]; ok {
+			respondWithError(h.logger, w, http.StatusBadRequest, "product "+strconv.Itoa(line.ProductID)+" is listed twice")
+			return
+		}
+		quantities[line.ProductID] = line.Quantity
+	}
+
+	order, err := h.purchasing.SetLines(r.Context(), id, quantities, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "update purchase order", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Purchase order updated successfully", order)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// ApprovePurchaseOrder handles POST /api/v1/purchase-orders/{id}/approve
+//
+//	@Summary		Approve purchase order
+//	@Description	Approve a draft with lines for submission. Audited as purchase_order.approve.
+//	@Tags			purchase-orders
+//	@Produce		json
+//	@Param			id	path		int	true	"Purchase order ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.PurchaseOrder}	"Approved order"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid purchase order ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Purchase order not found"
+//	@Failure		409	{object}	models.ErrorResponse	"Order isn't a draft, or has no lines"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/purchase-orders/{id}/approve [post]
+func (h *PurchaseOrderHandler) ApprovePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	h.step(w, r, "approve", "approved", h.purchasing.Approve)
+}
+
+// SubmitPurchaseOrder handles POST /api/v1/purchase-orders/{id}/submit
+//
+//	@Summary		Submit purchase order
+//	@Description	Submit an approved order to its supplier, publishing purchase_order.submitted. Audited as purchase_order.submit.
+//	@Tags			purchase-orders
+//	@Produce		json
+//	@Param			id	path		int	true	"Purchase order ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.PurchaseOrder}	"Submitted order"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid purchase order ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Purchase order not found"
+//	@Failure		409	{object}	models.ErrorResponse	"Order isn't approved"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/purchase-orders/{id}/submit [post]
+func (h *PurchaseOrderHandler) SubmitPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	h.step(w, r, "submit", "submitted", h.purchasing.Submit)
+}
+
+// ReceivePurchaseOrder handles POST /api/v1/purchase-orders/{id}/receive
+//
+//	@Summary		Receive purchase order
+//	@Description	Close a submitted order whose goods arrived. Stock is added by recording the goods as receipts. Audited as purchase_order.receive.
+//	@Tags			purchase-orders
+//	@Produce		json
+//	@Param			id	path		int	true	"Purchase order ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.PurchaseOrder}	"Received order"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid purchase order ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Purchase order not found"
+//	@Failure		409	{object}	models.ErrorResponse	"Order isn't submitted"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/purchase-orders/{id}/receive [post]
+func (h *PurchaseOrderHandler) ReceivePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	h.step(w, r, "receive", "received", h.purchasing.Receive)
+}
+
+// CancelPurchaseOrder handles POST /api/v1/purchase-orders/{id}/cancel
+//
+//	@Summary		Cancel purchase order
+//	@Description	Close a draft or approved order without submitting it. Audited as purchase_order.cancel.
+//	@Tags			purchase-orders
+//	@Produce		json
+//	@Param			id	path		int	true	"Purchase order ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.PurchaseOrder}	"Cancelled order"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid purchase order ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Purchase order not found"
+//	@Failure		409	{object}	models.ErrorResponse	"Order was submitted already"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/purchase-orders/{id}/cancel [post]
+func (h *PurchaseOrderHandler) CancelPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	h.step(w, r, "cancel", "cancelled", h.purchasing.Cancel)
+}
+
+// step runs a step of the order named by the path
+func (h *PurchaseOrderHandler) step(w http.ResponseWriter, r *http.Request, action, done string, run func(ctx context.Context, id int, actor string) (*models.PurchaseOrder, error)) {
+	id, ok := h.pathID(w, r, "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+
+	order, err := run(r.Context(), id, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, action+" purchase order", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Purchase order "+done+" successfully", order)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+func (h *PurchaseOrderHandler) pathID(w http.ResponseWriter, r *http.Request, invalid string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, invalid)
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *PurchaseOrderHandler) respondWithServiceError(w http.ResponseWriter, err error, action string, id int) {
+	switch {
+	case err.Error() == "purchase order not found":
+		respondWithError(h.logger, w, http.StatusNotFound, "Purchase order not found")
+	case err.Error() == "product not found":
+		respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+	case err.Error() == "supplier not found":
+		respondWithError(h.logger, w, http.StatusNotFound, "Product has no supplier")
+	case errors.Is(err, purchasing.ErrInvalidOrder):
+		respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, purchasing.ErrInvalidStep):
+		respondWithError(h.logger, w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error("failed to "+action, "error", err, "id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action)
+	}
+}
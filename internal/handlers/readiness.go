@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// Readiness reports whether a dependency can serve requests
+type Readiness interface {
+	Ready() bool
+}
+
+type ReadinessHandler struct {
+	database Readiness
+	logger   *slog.Logger
+}
+
+// NewReadinessHandler creates the readiness handler, not ready while
+// database isn't, e.g. during a failover
+func NewReadinessHandler(database Readiness, logger *slog.Logger) *ReadinessHandler {
+	return &ReadinessHandler{database: database, logger: logger}
+}
+
+// Ready handles GET /api/v1/ready
+// It reports whether the instance can serve requests, for load balancers to
+// route around it while it can't
+//
+//	@Summary		Readiness check
+//	@Description	Ready unless the database is unavailable, e.g. during a failover. Unlike the health check, not being ready is expected to pass.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	models.SuccessResponse	"Ready"
+//	@Failure		503	{object}	models.ErrorResponse	"Database unavailable"
+//	@Header			503	{integer}	Retry-After	"Seconds to wait before checking again"
+//	@Router			/ready [get]
+func (h *ReadinessHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if !h.database.Ready() {
+		w.Header().Set("Retry-After", "5")
+		respondWithError(h.logger, w, http.StatusServiceUnavailable, "Database unavailable")
+		return
+	}
+	respondWithJSON(h.logger, w, http.StatusOK, models.NewSuccessResponse(http.StatusOK, "Service is ready", nil))
+}
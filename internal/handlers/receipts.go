@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/valuation"
+)
+
+type ReceiptHandler struct {
+	repo     repository.ValuationRepository
+	products repository.ProductRepository
+	valuer   *valuation.Service
+	logger   *slog.Logger
+}
+
+func NewReceiptHandler(repo repository.ValuationRepository, products repository.ProductRepository, valuer *valuation.Service, logger *slog.Logger) *ReceiptHandler {
+	return &ReceiptHandler{repo: repo, products: products, valuer: valuer, logger: logger}
+}
+
+// ReceiptResponse is a recorded receipt and the product it was added to
+type ReceiptResponse struct {
+	Receipt *models.StockReceipt `json:"receipt"`
+	Product *models.Product      `json:"product"`
+}
+
+// ReceiveStock handles POST /api/v1/products/{id}/receipts
+// It adds received units to a product's stock and records their cost
+//
+//	@Summary		Receive stock
+//	@Description	Add units to a product's stock, recorded as a cost lot for inventory valuation. quantity is in the product's unit and unit_cost per unit; received_at defaults to now and orders the lots for FIFO. Publishes stock.adjusted with reason receipt.
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Product ID"
+//	@Param			receipt	body		models.StockReceipt	true	"Receipt (id and product_id are ignored)"
+//	@Success		201		{object}	models.SuccessResponse{data=ReceiptResponse}	"Receipt recorded, with the updated product"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid receipt"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/receipts [post]
+func (h *ReceiptHandler) ReceiveStock(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var receipt models.StockReceipt
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&receipt); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	receipt.ID, receipt.ProductID = 0, id
+	receipt.Reference = strings.TrimSpace(receipt.Reference)
+	if len(receipt.Reference) > 255 {
+		respondWithError(h.logger, w, http.StatusBadRequest, "reference must be at most 255 characters")
+		return
+	}
+
+	product, err := h.valuer.Receive(r.Context(), &receipt)
+	if err != nil {
+		switch {
+		case errors.Is(err, valuation.ErrInvalidReceipt):
+			respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+		case err.Error() == "product not found":
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+		default:
+			h.logger.Error("failed to receive stock", "error", err, "product_id", id)
+			respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to receive stock")
+		}
+		return
+	}
+
+	h.logger.Info("stock received", "product_id", id, "receipt_id", receipt.ID, "quantity", receipt.Quantity, "unit_cost", receipt.UnitCost)
+	response := models.NewSuccessResponse(http.StatusCreated, "Stock received successfully", ReceiptResponse{Receipt: &receipt, Product: product})
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/products/%d/receipts", id), response)
+}
+
+// ListReceipts handles GET /api/v1/products/{id}/receipts
+// It returns a product's cost lots
+//
+//	@Summary		List stock receipts
+//	@Description	Get a paginated list of a product's stock receipts, most recently received first
+//	@Tags			products
+//	@Produce		json
+//	@Param			id		path		int	true	"Product ID"
+//	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int	false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.StockReceipt}	"List of receipts with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/receipts [get]
+func (h *ReceiptHandler) ListReceipts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	if _, err := h.products.GetByID(ctx, id); err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve receipts")
+		return
+	}
+
+	receipts, err := h.repo.ListReceipts(ctx, id, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list receipts", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve receipts")
+		return
+	}
+
+	total, err := h.repo.CountReceipts(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to count receipts", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count receipts")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Receipts retrieved successfully", receipts, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/quota"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type RegionHandler struct {
+	regions   repository.ProductRegionRepository
+	products  repository.ProductRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	auditRepo repository.AuditRepository
+	logger    *slog.Logger
+}
+
+func NewRegionHandler(regions repository.ProductRegionRepository, products repository.ProductRepository, tx repository.Transactor, publisher events.Publisher, auditRepo repository.AuditRepository, logger *slog.Logger) *RegionHandler {
+	return &RegionHandler{regions: regions, products: products, tx: tx, publisher: publisher, auditRepo: auditRepo, logger: logger}
+}
+
+// RegionAssignmentRequest changes the sales regions of products in bulk
+type RegionAssignmentRequest struct {
+	ProductIDs []int    `json:"product_ids" example:"1,2,3"`
+	Regions    []string `json:"regions" example:"DE,AT"` // Region codes, matched case-insensitively
+	Action     string   `json:"action" example:"add" enums:"add,remove,set"`
+}
+
+// salesRegion returns the region the products r may see are sold in: that of
+// a regional API key, which only sees its own, or else ?region=. "" leaves
+// them unrestricted.
+func salesRegion(logger *slog.Logger, w http.ResponseWriter, r *http.Request) (string, bool) {
+	var region string
+	if q := r.URL.Query().Get("region"); q != "" {
+		var ok bool
+		if region, ok = models.NormalizeRegion(q); !ok {
+			respondWithError(logger, w, http.StatusBadRequest, "region must be a region code")
+			return "", false
+		}
+	}
+	if key := quota.FromContext(r.Context()); key != nil && key.Region != "" {
+		if region != "" && region != key.Region {
+			respondWithError(logger, w, http.StatusForbidden, "API key is limited to region "+key.Region)
+			return "", false
+		}
+		region = key.Region
+	}
+	return region, true
+}
+
+// ListRegions handles GET /api/v1/admin/product-regions
+//
+//	@Summary		List sales regions
+//	@Description	Get the regions products are limited to, by code, with how many products each has. Products limited to no region are sold everywhere and counted under none.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	models.SuccessResponse{data=[]models.RegionCount}	"Regions and their product counts"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/product-regions [get]
+func (h *RegionHandler) ListRegions(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.regions.Counts(r.Context())
+	if err != nil {
+		h.logger.Error("failed to count product regions", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve regions")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Regions retrieved successfully", counts)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// AssignRegions handles POST /api/v1/admin/product-regions
+//
+//	@Summary		Assign sales regions
+//	@Description	Change the regions of up to 1000 products in one transaction: add limits them to the regions too, remove takes them out (leaving a product with none sold everywhere), and set limits them to exactly the regions, or none. Products whose regions change publish product.updated. Audited as product.regions.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			assignment	body		RegionAssignmentRequest	true	"Products, regions, and action"
+//	@Success		200			{object}	models.SuccessResponse{data=[]models.Product}	"The products, with their regions"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid request"
+//	@Failure		401			{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404			{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/product-regions [post]
+func (h *RegionHandler) AssignRegions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req RegionAssignmentRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := normalizeAssignment(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	products := make([]*models.Product, 0, len(req.ProductIDs))
+	var missing int
+	err := h.tx.WithTx(ctx, func(ctx context.Context) error {
+		// IDs are sorted, so concurrent assignments lock in the same order
+		before := make([]*models.Product, 0, len(req.ProductIDs))
+		for _, id := range req.ProductIDs {
+			product, err := h.products.GetByIDForUpdate(ctx, id)
+			if err != nil {
+				missing = id
+				return err
+			}
+			before = append(before, product)
+		}
+
+		var err error
+		switch req.Action {
+		case models.RegionsAdd:
+			err = h.regions.Add(ctx, req.ProductIDs, req.Regions)
+		case models.RegionsRemove:
+			err = h.regions.Remove(ctx, req.ProductIDs, req.Regions)
+		default:
+			err = h.regions.Set(ctx, req.ProductIDs, req.Regions)
+		}
+		if err != nil {
+			return err
+		}
+
+		var evts []events.Event
+		for _, b := range before {
+			after, err := h.products.GetByID(ctx, b.ID)
+			if err != nil {
+				return err
+			}
+			if strings.Join(after.Regions, ",") != strings.Join(b.Regions, ",") {
+				evts = append(evts, events.ProductUpdates(b, after, "")...)
+			}
+			products = append(products, after)
+		}
+		return h.publisher.Publish(ctx, evts...)
+	})
+	if err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, fmt.Sprintf("Product %d not found", missing))
+			return
+		}
+		h.logger.Error("failed to assign product regions", "error", err, "action", req.Action)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to assign regions")
+		return
+	}
+
+	details, _ := json.Marshal(req)
+	entry := &models.AuditEntry{
+		Action:     "product.regions",
+		Actor:      r.RemoteAddr,
+		EntityType: "product",
+		Details:    details,
+	}
+	if err := h.auditRepo.Create(ctx, entry); err != nil {
+		h.logger.Error("failed to record region assignment in audit log", "error", err)
+	}
+
+	h.logger.Info("product regions assigned", "action", req.Action, "products", len(products), "regions", req.Regions)
+	response := models.NewSuccessResponse(http.StatusOK, "Regions assigned successfully", products)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// normalizeAssignment validates req, sorting its product IDs without
+// duplicates and uppercasing its regions
+func normalizeAssignment(req *RegionAssignmentRequest) error {
+	if !containsString(models.RegionActions, req.Action) {
+		return fmt.Errorf("action must be one of %s", strings.Join(models.RegionActions, ", "))
+	}
+	if len(req.ProductIDs) == 0 {
+		return fmt.Errorf("product_ids is required")
+	}
+
+	sort.Ints(req.ProductIDs)
+	ids := req.ProductIDs[:0]
+	for _, id := range req.ProductIDs {
+		if len(ids) > 0 && id == ids[len(ids)-1] {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > models.MaxRegionAssignment {
+		return fmt.Errorf("product_ids must list at most %d products", models.MaxRegionAssignment)
+	}
+	req.ProductIDs = ids
+
+	regions := make([]string, 0, len(req.Regions))
+	for _, code := range req.Regions {
+		region, ok := models.NormalizeRegion(code)
+		if !ok {
+			return fmt.Errorf("regions must be region codes, got %q", code)
+		}
+		if !containsString(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+	if len(regions) == 0 && req.Action != models.RegionsSet {
+		return fmt.Errorf("regions is required")
+	}
+	req.Regions = regions
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
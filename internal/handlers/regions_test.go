@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/pricing"
+	"{{MODULE_NAME}}/internal/quota"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// TestProductResources_Region checks that prices, availability, and bundles
+// of products not sold in the caller's region are not found, as lookups are
+func TestProductResources_Region(t *testing.T) {
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(t.TempDir(), "region.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	products := repository.NewProductRepository(db)
+	for _, p := range []*models.Product{
+		{SKU: "KIT-1", Name: "Kit", UnitPrice: 20},
+		{SKU: "KIT-2", Name: "French kit", UnitPrice: 20},
+		{SKU: "PART-3", Name: "Part", Quantity: 4, UnitPrice: 5},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+	bundles := repository.NewBundleRepository(db)
+	for _, id := range []int{1, 2} {
+		if err := bundles.Set(ctx, id, []models.BundleComponent{{ProductID: 3, Quantity: 1}}); err != nil {
+			t.Fatalf("failed to create bundle: %v", err)
+		}
+	}
+	if err := repository.NewProductRegionRepository(db).Add(ctx, []int{2}, []string{"FR"}); err != nil {
+		t.Fatalf("failed to add region: %v", err)
+	}
+
+	rules, err := pricing.New(pricing.Config{Currency: "EUR", Rates: map[string]string{"DE": "19", "FR": "20"}})
+	if err != nil {
+		t.Fatalf("failed to load pricing rules: %v", err)
+	}
+	pricingHandler := NewPricingHandler(products, rules, nil, testLogger)
+	availabilityHandler := NewAvailabilityHandler(products, 5, testLogger)
+	bundleHandler := NewBundleHandler(bundles, testLogger)
+	router := chi.NewRouter()
+	router.Get("/api/v1/products/{id}/price", pricingHandler.GetPrice)
+	router.Get("/api/v1/products/{id}/availability", availabilityHandler.GetAvailability)
+	router.Get("/api/v1/bundles", bundleHandler.ListBundles)
+	router.Get("/api/v1/bundles/{id}", bundleHandler.GetBundle)
+
+	tests := []struct {
+		name      string
+		path      string
+		keyRegion string // Of the request's API key, if any
+		want      int
+		body      string // Contained in the response, if set
+	}{
+		{"price", "/api/v1/products/2/price?tax_region=FR", "", http.StatusOK, `"region":"FR"`},
+		{"price taxed elsewhere", "/api/v1/products/2/price?tax_region=DE&region=fr", "", http.StatusOK, `"region":"DE"`},
+		{"price outside the region", "/api/v1/products/2/price?tax_region=FR&region=de", "", http.StatusNotFound, ""},
+		{"price with regional key", "/api/v1/products/2/price?tax_region=FR", "DE", http.StatusNotFound, ""},
+		{"price outside the key's region", "/api/v1/products/1/price?tax_region=DE&region=fr", "DE", http.StatusForbidden, ""},
+		{"availability", "/api/v1/products/2/availability", "", http.StatusOK, `"in_stock":false`},
+		{"availability with regional key", "/api/v1/products/2/availability", "DE", http.StatusNotFound, ""},
+		{"availability in invalid region", "/api/v1/products/1/availability?region=de%20fr", "", http.StatusBadRequest, ""},
+		{"bundles", "/api/v1/bundles", "", http.StatusOK, `"total":2`},
+		{"bundles with regional key", "/api/v1/bundles", "DE", http.StatusOK, `"total":1`},
+		{"bundle", "/api/v1/bundles/2?region=fr", "", http.StatusOK, `"sku":"KIT-2"`},
+		{"bundle with regional key", "/api/v1/bundles/2", "DE", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.keyRegion != "" {
+				r = r.WithContext(quota.WithKey(r.Context(), &models.APIKey{ID: 1, Region: tt.keyRegion}))
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// RelatedLimits bounds GET /products/{id}/related
+type RelatedLimits struct {
+	DefaultLimit int     // Products returned without ?limit
+	MaxLimit     int     // Largest ?limit honored
+	PriceBand    float64 // Fraction of the unit price counted as the same price band
+}
+
+type RelatedHandler struct {
+	repo   repository.ProductRepository
+	limits RelatedLimits
+	logger *slog.Logger
+}
+
+func NewRelatedHandler(repo repository.ProductRepository, limits RelatedLimits, logger *slog.Logger) *RelatedHandler {
+	return &RelatedHandler{repo: repo, limits: limits, logger: logger}
+}
+
+// GetRelated handles GET /api/v1/products/{id}/related
+// It returns the products most like a product, for "customers also viewed"
+//
+//	@Summary		List related products
+//	@Description	Products visible now, and sold in the region if any, sharing tags or the category with the product, best match first. score is the number of shared tags, plus one for the same category and one for a unit price within RELATED_PRICE_BAND of the product's. Tags of related products aren't included.
+//	@Tags			products
+//	@Produce		json
+//	@Param			id		path		int	true	"Product ID"
+//	@Param			limit	query		int	false	"Number of products to return (default RELATED_PRODUCTS_LIMIT, max RELATED_PRODUCTS_MAX_LIMIT)"
+//	@Param			region	query		string	false	"Only products sold in this region; a regional API key's own by default"
+//	@Success		200		{object}	models.SuccessResponse{data=[]models.RelatedProduct}	"Related products"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID or region"
+//	@Failure		403		{object}	models.ErrorResponse	"Region outside the API key's"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/related [get]
+func (h *RelatedHandler) GetRelated(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	limit := h.limits.DefaultLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, h.limits.MaxLimit)
+		}
+	}
+
+	region, ok := salesRegion(h.logger, w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.repo.GetByID(ctx, id)
+	if err == nil && !product.SoldIn(region) {
+		err = fmt.Errorf("product not found")
+	}
+	if err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve product")
+		return
+	}
+
+	related, err := h.repo.Related(ctx, id, limit, h.limits.PriceBand, region, time.Now())
+	if err != nil {
+		h.logger.Error("failed to list related products", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve related products")
+		return
+	}
+	if related == nil {
+		related = []*models.RelatedProduct{}
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Related products retrieved successfully", related)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
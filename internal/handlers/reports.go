@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/reports"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type ReportHandler struct {
+	repo    repository.ReportRepository
+	reports *reports.Service
+	logger  *slog.Logger
+}
+
+func NewReportHandler(repo repository.ReportRepository, reportService *reports.Service, logger *slog.Logger) *ReportHandler {
+	return &ReportHandler{repo: repo, reports: reportService, logger: logger}
+}
+
+// ReportRequest defines a report
+type ReportRequest struct {
+	Description string               `json:"description" example:"Products with stock that sold little"`
+	Query       string               `json:"query" example:"SELECT sku, quantity FROM products WHERE quantity >= :min ORDER BY quantity DESC"`
+	Params      []models.ReportParam `json:"params"`
+}
+
+// RunReportRequest binds a report's params by name
+type RunReportRequest struct {
+	Params map[string]json.RawMessage `json:"params" swaggertype:"object"`
+}
+
+// ListReports handles GET /api/v1/reports
+// It returns a paginated list of reports
+//
+//	@Summary		List reports
+//	@Description	Get a paginated list of report definitions, by name
+//	@Tags			reports
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int	false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.Report}	"List of reports with pagination metadata"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/reports [get]
+func (h *ReportHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	list, err := h.repo.List(ctx, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list reports", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve reports")
+		return
+	}
+
+	total, err := h.repo.Count(ctx)
+	if err != nil {
+		h.logger.Error("failed to count reports", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count reports")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Reports retrieved successfully", list, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// GetReport handles GET /api/v1/reports/{name}
+//
+//	@Summary		Get report
+//	@Tags			reports
+//	@Produce		json
+//	@Param			name	path		string	true	"Report name"
+//	@Success		200		{object}	models.SuccessResponse{data=models.Report}	"Report"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	models.ErrorResponse	"Report not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/reports/{name} [get]
+func (h *ReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	report, err := h.repo.GetByName(r.Context(), name)
+	if err != nil {
+		h.respondWithServiceError(w, err, "retrieve report", name)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Report retrieved successfully", report)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// PutReport handles PUT /api/v1/reports/{name}
+//
+//	@Summary		Create or replace report
+//	@Description	Store a named SQL template with typed params, bound to its :name placeholders. The query must be one SELECT, possibly with a WITH clause, reading only the allowlisted tables and calling only the allowlisted functions, without comments or quoted identifiers; the database then plans it with zero values of the params, so one it rejects isn't stored. Audited as report.create or report.update.
+//	@Tags			reports
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	path		string			true	"Report name, lowercase letters, digits, - and _"
+//	@Param			report	body		ReportRequest	true	"Report"
+//	@Success		200		{object}	models.SuccessResponse{data=models.Report}	"Replaced report"
+//	@Success		201		{object}	models.SuccessResponse{data=models.Report}	"Created report, with its URL in the Location header"
+//	@Header			201		{string}	Location				"/api/v1/reports/{name}"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid report"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		422		{object}	models.ErrorResponse	"The database rejected the query"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/reports/{name} [put]
+func (h *ReportHandler) PutReport(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req ReportRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	report := &models.Report{Name: name, Description: req.Description, Query: req.Query, Params: req.Params}
+	created, err := h.reports.Save(r.Context(), report, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "save report", name)
+		return
+	}
+
+	if created {
+		response := models.NewSuccessResponse(http.StatusCreated, "Report created successfully", report)
+		respondCreated(h.logger, w, "/api/v1/reports/"+name, response)
+		return
+	}
+	response := models.NewSuccessResponse(http.StatusOK, "Report updated successfully", report)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// DeleteReport handles DELETE /api/v1/reports/{name}
+//
+//	@Summary		Delete report
+//	@Description	Audited as report.delete
+//	@Tags			reports
+//	@Param			name	path	string	true	"Report name"
+//	@Success		204		"Report deleted"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	models.ErrorResponse	"Report not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/reports/{name} [delete]
+func (h *ReportHandler) DeleteReport(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if err := h.reports.Delete(r.Context(), name, r.RemoteAddr); err != nil {
+		h.respondWithServiceError(w, err, "delete report", name)
+		return
+	}
+
+	respondNoContent(w)
+}
+
+// RunReport handles POST /api/v1/reports/{name}/run
+//
+//	@Summary		Run report
+//	@Description	Run a report with its params bound by name, in a read-only transaction within REPORT_TIMEOUT, returning up to REPORT_MAX_ROWS rows as JSON, or as CSV with ?format=csv. Audited as report.run, without the params.
+//	@Tags			reports
+//	@Accept			json
+//	@Produce		json
+//	@Produce		text/csv
+//	@Param			name	path		string				true	"Report name"
+//	@Param			format	query		string				false	"json (default) or csv"
+//	@Param			params	body		RunReportRequest	false	"Params"
+//	@Success		200		{object}	models.SuccessResponse{data=models.ReportResult}	"Columns and rows"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid params"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	models.ErrorResponse	"Report not found"
+//	@Failure		422		{object}	models.ErrorResponse	"The query failed"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Failure		504		{object}	models.ErrorResponse	"The query outlived REPORT_TIMEOUT"
+//	@Router			/reports/{name}/run [post]
+func (h *ReportHandler) RunReport(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "csv" {
+		respondWithError(h.logger, w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	var req RunReportRequest
+	if r.ContentLength != 0 {
+		dec := newDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+	}
+
+	result, err := h.reports.Run(r.Context(), name, req.Params, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "run report", name)
+		return
+	}
+
+	if format != "csv" {
+		response := models.NewSuccessResponse(http.StatusOK, "Report run successfully", result)
+		respondWithJSON(h.logger, w, http.StatusOK, response)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+	if result.Truncated {
+		w.Header().Set("X-Report-Truncated", "true")
+	}
+	out := csv.NewWriter(w)
+	_ = out.Write(result.Columns)
+	record := make([]string, len(result.Columns))
+	for _, row := range result.Rows {
+		for i, v := range row {
+			record[i] = csvValue(v)
+		}
+		_ = out.Write(record)
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		h.logger.Error("failed to write report", "error", err, "report", name)
+	}
+}
+
+// csvValue formats a value of a report row, NULL as empty
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (h *ReportHandler) respondWithServiceError(w http.ResponseWriter, err error, action, name string) {
+	var queryErr *repository.QueryError
+	switch {
+	case err.Error() == "report not found":
+		respondWithError(h.logger, w, http.StatusNotFound, "Report not found")
+	case errors.Is(err, reports.ErrInvalidReport), errors.Is(err, reports.ErrInvalidParam):
+		respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+	case errors.As(err, &queryErr):
+		respondWithError(h.logger, w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, repository.ErrQueryTimeout):
+		respondWithError(h.logger, w, http.StatusGatewayTimeout, "Report query exceeded REPORT_TIMEOUT")
+	default:
+		h.logger.Error("failed to "+action, "error", err, "report", name)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"{{MODULE_NAME}}/internal/formats"
+	"{{MODULE_NAME}}/internal/jsonenc"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// respondWithJSON writes payload as a JSON body with the given status code.
+// Statuses that must not carry a body (204, 304) are written without one. A
+// payload that can't be encoded is logged and answered with a 500.
+func respondWithJSON(logger *slog.Logger, w http.ResponseWriter, code int, payload interface{}) {
+	if !bodyAllowed(code) {
+		w.WriteHeader(code)
+		return
+	}
+
+	if err := jsonenc.Write(w, code, payload); err != nil {
+		logger.Error("failed to encode response", "error", err)
+		jsonenc.Write(w, http.StatusInternalServerError, models.NewErrorResponse(http.StatusInternalServerError, "Failed to encode response"))
+	}
+}
+
+func respondWithError(logger *slog.Logger, w http.ResponseWriter, code int, message string) {
+	response := models.NewErrorResponse(code, message)
+	respondWithJSON(logger, w, code, response)
+}
+
+// respondCreated writes a 201 with the Location of the new resource
+func respondCreated(logger *slog.Logger, w http.ResponseWriter, location string, payload interface{}) {
+	w.Header().Set("Location", location)
+	respondWithJSON(logger, w, http.StatusCreated, payload)
+}
+
+func respondNoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func bodyAllowed(code int) bool {
+	return code != http.StatusNoContent && code != http.StatusNotModified && code >= 200
+}
+
+// newDecoder returns a decoder of the request body in the format of its
+// Content-Type, JSON unless another one is registered for it
+func newDecoder(r *http.Request) formats.Decoder {
+	return formats.NewDecoder(r.Body, r.Header.Get("Content-Type"))
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestRespondWithJSON_NoBodyStatuses(t *testing.T) {
+	for _, code := range []int{http.StatusNoContent, http.StatusNotModified} {
+		w := httptest.NewRecorder()
+		respondWithJSON(testLogger, w, code, models.NewSuccessResponse(code, "ignored", nil))
+
+		if w.Code != code {
+			t.Errorf("status = %d, want %d", w.Code, code)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("status %d: expected empty body, got %q", code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "" {
+			t.Errorf("status %d: expected no Content-Type, got %q", code, ct)
+		}
+	}
+}
+
+func TestRespondCreated(t *testing.T) {
+	w := httptest.NewRecorder()
+	respondCreated(testLogger, w, "/api/v1/products/7", models.NewSuccessResponse(http.StatusCreated, "created", nil))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if loc := w.Header().Get("Location"); loc != "/api/v1/products/7" {
+		t.Errorf("Location = %q, want %q", loc, "/api/v1/products/7")
+	}
+	if w.Body.Len() == 0 {
+		t.Error("expected a JSON body")
+	}
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/returns"
+)
+
+type ReturnHandler struct {
+	repo    repository.ReturnRepository
+	service *returns.Service
+	logger  *slog.Logger
+}
+
+func NewReturnHandler(repo repository.ReturnRepository, service *returns.Service, logger *slog.Logger) *ReturnHandler {
+	return &ReturnHandler{repo: repo, service: service, logger: logger}
+}
+
+// InspectReturnRequest is the outcome of inspecting a return
+type InspectReturnRequest struct {
+	Disposition string `json:"disposition" example:"restock"` // restock, scrap, or repair
+	Notes       string `json:"notes,omitempty" example:"Box damaged, unit fine"`
+}
+
+// ListReturns handles GET /api/v1/returns
+// It returns a paginated list of returns
+//
+//	@Summary		List returns
+//	@Description	Get a paginated list of returns, most recently authorized first, optionally by status, product, or original order
+//	@Tags			returns
+//	@Produce		json
+//	@Param			status			query		string	false	"Only returns with this status"	Enums(authorized, received, repairing, restocked, scrapped, cancelled)
+//	@Param			product_id		query		int		false	"Only returns of this product"
+//	@Param			order_source	query		string	false	"Only returns of orders from this source"
+//	@Param			order_id		query		string	false	"Only returns of this order"
+//	@Param			limit			query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset			query		int		false	"Number of items to skip"				default(0)
+//	@Success		200				{object}	models.PaginatedResponse{data=[]models.Return}	"List of returns with pagination metadata"
+//	@Failure		400				{object}	models.ErrorResponse	"Invalid filter"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/returns [get]
+func (h *ReturnHandler) ListReturns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	limit := 50
+	offset := 0
+
+	if l := query.Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := query.Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	filter := models.ReturnFilter{
+		Status:      query.Get("status"),
+		OrderSource: query.Get("order_source"),
+		OrderID:     query.Get("order_id"),
+	}
+	if filter.Status != "" && !returns.ValidStatus(filter.Status) {
+		respondWithError(h.logger, w, http.StatusBadRequest, "status must be one of "+strings.Join(returns.Statuses, ", "))
+		return
+	}
+	if p := query.Get("product_id"); p != "" {
+		id, err := strconv.Atoi(p)
+		if err != nil || id <= 0 {
+			respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product_id")
+			return
+		}
+		filter.ProductID = id
+	}
+
+	list, err := h.repo.List(ctx, filter, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list returns", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve returns")
+		return
+	}
+
+	total, err := h.repo.Count(ctx, filter)
+	if err != nil {
+		h.logger.Error("failed to count returns", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count returns")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Returns retrieved successfully", list, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CreateReturn handles POST /api/v1/returns
+//
+//	@Summary		Authorize return
+//	@Description	Authorize a return of units of a product (by product_id or sku) sold, optionally on an order from order_source. The order isn't checked against processed orders. Audited as return.create.
+//	@Tags			returns
+//	@Accept			json
+//	@Produce		json
+//	@Param			return	body		models.ReturnRequest	true	"Return"
+//	@Success		201		{object}	models.SuccessResponse{data=models.Return}	"Return authorized, with its URL in the Location header"
+//	@Header			201		{string}	Location				"/api/v1/returns/{id}"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid return"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/returns [post]
+func (h *ReturnHandler) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	var req models.ReturnRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	ret, err := h.service.Create(r.Context(), req, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "authorize", 0)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusCreated, "Return authorized successfully", ret)
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/returns/%d", ret.ID), response)
+}
+
+// GetReturn handles GET /api/v1/returns/{id}
+//
+//	@Summary		Get return
+//	@Description	Get a return with its status and disposition
+//	@Tags			returns
+//	@Produce		json
+//	@Param			id	path		int	true	"Return ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.Return}	"Return"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid return ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Return not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/returns/{id} [get]
+func (h *ReturnHandler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.returnID(w, r)
+	if !ok {
+		return
+	}
+
+	ret, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		h.respondWithServiceError(w, err, "retrieve", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Return retrieved successfully", ret)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// ReceiveReturn handles POST /api/v1/returns/{id}/receive
+//
+//	@Summary		Receive return
+//	@Description	Record an authorized return's units as arrived, for inspection. Audited as return.receive.
+//	@Tags			returns
+//	@Produce		json
+//	@Param			id	path		int	true	"Return ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.Return}	"Received return"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid return ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Return not found"
+//	@Failure		409	{object}	models.ErrorResponse	"Return isn't authorized"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/returns/{id}/receive [post]
+func (h *ReturnHandler) ReceiveReturn(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.returnID(w, r)
+	if !ok {
+		return
+	}
+
+	ret, err := h.service.Receive(r.Context(), id, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "receive", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Return received successfully", ret)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// InspectReturn handles POST /api/v1/returns/{id}/inspect
+//
+//	@Summary		Inspect return
+//	@Description	Disposition a received return, or one back from repair: restock adds its units back to the product's quantity, audited as stock.adjust and publishing stock.adjusted with reason return; scrap closes it without restocking; repair sends it for repair, to be inspected again. Audited as return.inspect.
+//	@Tags			returns
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int						true	"Return ID"
+//	@Param			inspection	body		InspectReturnRequest	true	"Disposition"
+//	@Success		200			{object}	models.SuccessResponse{data=models.Return}	"Inspected return"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid disposition"
+//	@Failure		404			{object}	models.ErrorResponse	"Return not found"
+//	@Failure		409			{object}	models.ErrorResponse	"Return isn't awaiting inspection"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/returns/{id}/inspect [post]
+func (h *ReturnHandler) InspectReturn(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.returnID(w, r)
+	if !ok {
+		return
+	}
+
+	var req InspectReturnRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	ret, err := h.service.Inspect(r.Context(), id, req.Disposition, req.Notes, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "inspect", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Return inspected successfully", ret)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CancelReturn handles POST /api/v1/returns/{id}/cancel
+//
+//	@Summary		Cancel return
+//	@Description	Close a return that hasn't been inspected, e.g. one never sent. Audited as return.cancel.
+//	@Tags			returns
+//	@Produce		json
+//	@Param			id	path		int	true	"Return ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.Return}	"Cancelled return"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid return ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Return not found"
+//	@Failure		409	{object}	models.ErrorResponse	"Return was inspected already"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/returns/{id}/cancel [post]
+func (h *ReturnHandler) CancelReturn(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.returnID(w, r)
+	if !ok {
+		return
+	}
+
+	ret, err := h.service.Cancel(r.Context(), id, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "cancel", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Return cancelled successfully", ret)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+func (h *ReturnHandler) returnID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid return ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *ReturnHandler) respondWithServiceError(w http.ResponseWriter, err error, action string, id int) {
+	switch {
+	case err.Error() == "return not found":
+		respondWithError(h.logger, w, http.StatusNotFound, "Return not found")
+	case err.Error() == "product not found":
+		respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+	case errors.Is(err, returns.ErrInvalidReturn):
+		respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, returns.ErrInvalidStep):
+		respondWithError(h.logger, w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error("failed to "+action+" return", "error", err, "return_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action+" return")
+	}
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/tokens"
+)
+
+// errStaleRevision fails the approval of a revision whose product was
+// updated after the revision was proposed
+var errStaleRevision = errors.New("product changed since the revision")
+
+// errReviewed fails the review of a revision that isn't pending
+var errReviewed = errors.New("revision already reviewed")
+
+// RevisionResponse is a revision with the changes it makes to the product as
+// it is now; only pending revisions list changes
+type RevisionResponse struct {
+	*models.ProductRevision
+	Changes []events.FieldChange `json:"changes,omitempty"`
+}
+
+// ReviewRequest approves or rejects a revision
+type ReviewRequest struct {
+	Comment string `json:"comment" example:"Price needs sign-off"` // Required to reject
+}
+
+// requestRole returns the policy role of a request's scoped token, or ""
+func requestRole(r *http.Request) string {
+	if claims := tokens.FromContext(r.Context()); claims != nil {
+		return claims.Scope.Role
+	}
+	return ""
+}
+
+// requestActor names who made a request: the subject of its scoped token, or
+// else its address
+func requestActor(r *http.Request) string {
+	if claims := tokens.FromContext(r.Context()); claims != nil {
+		return claims.Subject
+	}
+	return r.RemoteAddr
+}
+
+// revisionProposed responds to an editor's update, held as rev, a pending
+// revision of current
+func (h *ProductHandler) revisionProposed(w http.ResponseWriter, r *http.Request, rev *models.ProductRevision, current *models.Product) {
+	ctx := r.Context()
+
+	if database.IsDryRun(ctx) {
+		response := models.NewSuccessResponse(http.StatusOK, "Dry run: revision would be proposed", rev)
+		h.respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
+	h.logger.Info("product revision proposed", "revision_id", rev.ID, "product_id", rev.ProductID, "author", rev.Author)
+	w.Header().Set("Location", "/api/v1/revisions/"+strconv.Itoa(rev.ID))
+	response := models.NewSuccessResponse(http.StatusAccepted, "Revision awaiting approval", revisionResponse(rev, current))
+	h.respondWithJSON(w, http.StatusAccepted, response)
+}
+
+// ListRevisions handles GET /api/v1/products/{id}/revisions
+// It returns the revisions editors proposed for a product
+//
+//	@Summary		List product revisions
+//	@Description	Get a paginated list of a product's revisions, oldest first: by default the pending ones, each with the changes it makes to the product as it is now
+//	@Tags			revisions
+//	@Produce		json
+//	@Param			id		path		int		true	"Product ID"
+//	@Param			status	query		string	false	"pending, approved, rejected, or all"	default(pending)
+//	@Param			limit	query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int		false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]RevisionResponse}	"Revisions with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID or status"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/revisions [get]
+func (h *ProductHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	query := r.URL.Query()
+	status := models.RevisionPending
+	if s := query.Get("status"); s == "all" {
+		status = ""
+	} else if s != "" {
+		if !containsString(models.RevisionStatuses, s) {
+			h.respondWithError(w, http.StatusBadRequest, "status must be one of "+strings.Join(models.RevisionStatuses, ", ")+", or all")
+			return
+		}
+		status = s
+	}
+
+	limit := 50
+	offset := 0
+	if l := query.Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+	if o := query.Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	current, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		if err.Error() == "product not found" {
+			h.respondWithError(w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to retrieve revisions")
+		return
+	}
+
+	revisions, err := h.revisions.List(ctx, id, status, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list product revisions", "error", err, "product_id", id)
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to retrieve revisions")
+		return
+	}
+	total, err := h.revisions.Count(ctx, id, status)
+	if err != nil {
+		h.logger.Error("failed to count product revisions", "error", err, "product_id", id)
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to count revisions")
+		return
+	}
+
+	list := make([]RevisionResponse, 0, len(revisions))
+	for _, rev := range revisions {
+		list = append(list, revisionResponse(rev, current))
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Revisions retrieved successfully", list, pagination)
+	h.respondWithJSON(w, http.StatusOK, response)
+}
+
+// GetRevision handles GET /api/v1/revisions/{id}
+//
+//	@Summary		Get revision by ID
+//	@Description	Get a revision with, while it's pending, the changes it makes to the product as it is now
+//	@Tags			revisions
+//	@Produce		json
+//	@Param			id	path		int	true	"Revision ID"
+//	@Success		200	{object}	models.SuccessResponse{data=RevisionResponse}	"Revision and its changes"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid revision ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Revision not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/revisions/{id} [get]
+func (h *ProductHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid revision ID")
+		return
+	}
+
+	rev, err := h.revisions.GetByID(ctx, id)
+	var current *models.Product
+	if err == nil {
+		current, err = h.repo.GetByID(ctx, rev.ProductID)
+	}
+	if err != nil {
+		h.respondWithRevisionError(w, err, "get", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Revision retrieved successfully", revisionResponse(rev, current))
+	h.respondWithJSON(w, http.StatusOK, response)
+}
+
+// ApproveRevision handles POST /api/v1/revisions/{id}/approve
+//
+//	@Summary		Approve a revision
+//	@Description	Applies a pending revision to its product and marks it approved, in one transaction, publishing product.updated. Refused once the product was updated after the revision was proposed; reject it and propose again. Approvers and admins only.
+//	@Tags			revisions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Revision ID"
+//	@Param			review	body		ReviewRequest	false	"Optional comment"
+//	@Param			dry_run	query		bool			false	"Check the approval without applying it (also X-Dry-Run header)"
+//	@Success		200		{object}	models.SuccessResponse{data=models.ProductRevision}	"Approved revision, with the updated product"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		403		{object}	models.ErrorResponse	"Not an approver"
+//	@Failure		404		{object}	models.ErrorResponse	"Revision not found"
+//	@Failure		409		{object}	models.ErrorResponse	"Revision already reviewed, or product changed since"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/revisions/{id}/approve [post]
+func (h *ProductHandler) ApproveRevision(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, models.RevisionApproved)
+}
+
+// RejectRevision handles POST /api/v1/revisions/{id}/reject
+//
+//	@Summary		Reject a revision
+//	@Description	Marks a pending revision rejected, with a comment for its editor, leaving the product unchanged. Approvers and admins only.
+//	@Tags			revisions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Revision ID"
+//	@Param			review	body		ReviewRequest	true	"Why it's rejected"
+//	@Success		200		{object}	models.SuccessResponse{data=models.ProductRevision}	"Rejected revision"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid request or missing comment"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		403		{object}	models.ErrorResponse	"Not an approver"
+//	@Failure		404		{object}	models.ErrorResponse	"Revision not found"
+//	@Failure		409		{object}	models.ErrorResponse	"Revision already reviewed"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/revisions/{id}/reject [post]
+func (h *ProductHandler) RejectRevision(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, models.RevisionRejected)
+}
+
+// review approves or rejects the pending revision named by the path, applying
+// an approved one to its product in the same transaction
+func (h *ProductHandler) review(w http.ResponseWriter, r *http.Request, status string) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid revision ID")
+		return
+	}
+
+	var req ReviewRequest
+	if r.ContentLength != 0 {
+		dec := newDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if status == models.RevisionRejected && req.Comment == "" {
+		h.respondWithError(w, http.StatusBadRequest, "comment is required to reject a revision")
+		return
+	}
+
+	action := "approve"
+	if status == models.RevisionRejected {
+		action = "reject"
+	}
+
+	var rev *models.ProductRevision
+	err = h.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if rev, err = h.revisions.GetByIDForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if rev.Status != models.RevisionPending {
+			return errReviewed
+		}
+
+		if status == models.RevisionApproved {
+			before, err := h.repo.GetByIDForUpdate(ctx, rev.ProductID)
+			if err != nil {
+				return err
+			}
+			if !before.UpdatedAt.Equal(rev.BaseUpdatedAt) {
+				return errStaleRevision
+			}
+			rev.Product.ID = before.ID
+			if err := h.update(ctx, before, &rev.Product, "revision_approved"); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		rev.Status, rev.Reviewer, rev.Comment, rev.ReviewedAt = status, requestActor(r), req.Comment, &now
+		return h.revisions.Review(ctx, rev)
+	})
+	if err != nil {
+		h.respondWithRevisionError(w, err, action, id)
+		return
+	}
+
+	if database.IsDryRun(ctx) {
+		response := models.NewSuccessResponse(http.StatusOK, "Dry run: revision would be "+status, rev)
+		h.respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
+	h.logger.Info("product revision reviewed", "revision_id", id, "product_id", rev.ProductID, "status", status, "reviewer", rev.Reviewer)
+	response := models.NewSuccessResponse(http.StatusOK, "Revision "+status+" successfully", rev)
+	h.respondWithJSON(w, http.StatusOK, response)
+}
+
+// revisionResponse adds the changes rev makes to current while it's pending
+func revisionResponse(rev *models.ProductRevision, current *models.Product) RevisionResponse {
+	response := RevisionResponse{ProductRevision: rev}
+	if rev.Status == models.RevisionPending {
+		proposed := rev.Product
+		proposed.ID, proposed.UID, proposed.Regions = current.ID, current.UID, current.Regions
+		proposed.CreatedAt, proposed.UpdatedAt = current.CreatedAt, current.UpdatedAt
+		if proposed.Unit == "" {
+			proposed.Unit = current.Unit
+		}
+		if proposed.Slug == "" {
+			proposed.Slug = current.Slug
+		}
+		response.Changes = events.DiffProducts(current, &proposed)
+	}
+	return response
+}
+
+func (h *ProductHandler) respondWithRevisionError(w http.ResponseWriter, err error, action string, id int) {
+	switch {
+	case err.Error() == "product revision not found":
+		h.respondWithError(w, http.StatusNotFound, "Revision not found")
+	case err.Error() == "product not found":
+		h.respondWithError(w, http.StatusNotFound, "Product not found")
+	case errors.Is(err, errReviewed):
+		h.respondWithError(w, http.StatusConflict, "Revision was already reviewed")
+	case errors.Is(err, errSlugTaken):
+		h.respondWithError(w, http.StatusConflict, "Another product has or had this slug")
+	case errors.Is(err, errStaleRevision):
+		h.respondWithError(w, http.StatusConflict, "Product was updated after the revision was proposed; reject it and propose the change again")
+	default:
+		h.logger.Error("failed to "+action+" product revision", "error", err, "revision_id", id)
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to "+action+" revision")
+	}
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/cache"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type SavedSearchHandler struct {
+	repo   repository.SavedSearchRepository
+	cache  *cache.Cache
+	logger *slog.Logger
+}
+
+// NewSavedSearchHandler creates the saved search handler. Changes invalidate
+// the cached product listings of saved searches; responseCache may be nil.
+func NewSavedSearchHandler(repo repository.SavedSearchRepository, responseCache *cache.Cache, logger *slog.Logger) *SavedSearchHandler {
+	return &SavedSearchHandler{repo: repo, cache: responseCache, logger: logger}
+}
+
+// ListSavedSearches handles GET /api/v1/saved-searches
+// It returns a paginated list of saved searches
+//
+//	@Summary		List saved searches
+//	@Description	Get a paginated list of saved searches, by name
+//	@Tags			saved-searches
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int	false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.SavedSearch}	"List of saved searches with pagination metadata"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/saved-searches [get]
+func (h *SavedSearchHandler) ListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	list, err := h.repo.List(ctx, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list saved searches", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve saved searches")
+		return
+	}
+	if list == nil {
+		list = []*models.SavedSearch{}
+	}
+
+	total, err := h.repo.Count(ctx)
+	if err != nil {
+		h.logger.Error("failed to count saved searches", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count saved searches")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Saved searches retrieved successfully", list, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// GetSavedSearch handles GET /api/v1/saved-searches/{id}
+//
+//	@Summary		Get saved search by ID
+//	@Tags			saved-searches
+//	@Produce		json
+//	@Param			id	path		int	true	"Saved search ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.SavedSearch}	"Saved search"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid saved search ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Saved search not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/saved-searches/{id} [get]
+func (h *SavedSearchHandler) GetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.savedSearchID(w, r)
+	if !ok {
+		return
+	}
+
+	search, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		h.respondWithRepoError(w, err, "get", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Saved search retrieved successfully", search)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CreateSavedSearch handles POST /api/v1/saved-searches
+//
+//	@Summary		Create a saved search
+//	@Description	Saves a named product filter and sort, listed with GET /products?view={id}. Unknown filter fields are rejected.
+//	@Tags			saved-searches
+//	@Accept			json
+//	@Produce		json
+//	@Param			search	body		models.SavedSearch		true	"Saved search"
+//	@Success		201		{object}	models.SuccessResponse{data=models.SavedSearch}	"Created saved search, with its URL in the Location header"
+//	@Header			201		{string}	Location				"/api/v1/saved-searches/{id}"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid saved search"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/saved-searches [post]
+func (h *SavedSearchHandler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	var search models.SavedSearch
+	if !h.decode(w, r, &search) {
+		return
+	}
+
+	if err := h.repo.Create(r.Context(), &search); err != nil {
+		h.logger.Error("failed to create saved search", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to create saved search")
+		return
+	}
+
+	h.logger.Info("saved search created", "saved_search_id", search.ID)
+	response := models.NewSuccessResponse(http.StatusCreated, "Saved search created successfully", search)
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/saved-searches/%d", search.ID), response)
+}
+
+// UpdateSavedSearch handles PUT /api/v1/saved-searches/{id}
+//
+//	@Summary		Update saved search
+//	@Tags			saved-searches
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Saved search ID"
+//	@Param			search	body		models.SavedSearch	true	"Updated saved search"
+//	@Success		200		{object}	models.SuccessResponse{data=models.SavedSearch}	"Updated saved search"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid saved search"
+//	@Failure		404		{object}	models.ErrorResponse	"Saved search not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/saved-searches/{id} [put]
+func (h *SavedSearchHandler) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.savedSearchID(w, r)
+	if !ok {
+		return
+	}
+
+	var search models.SavedSearch
+	if !h.decode(w, r, &search) {
+		return
+	}
+	search.ID = id
+
+	ctx := r.Context()
+	existing, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		h.respondWithRepoError(w, err, "update", id)
+		return
+	}
+	search.CreatedAt = existing.CreatedAt
+
+	if err := h.repo.Update(ctx, &search); err != nil {
+		h.respondWithRepoError(w, err, "update", id)
+		return
+	}
+	h.cache.Invalidate(ctx, cache.SavedSearchesTag)
+
+	h.logger.Info("saved search updated", "saved_search_id", id)
+	response := models.NewSuccessResponse(http.StatusOK, "Saved search updated successfully", search)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// DeleteSavedSearch handles DELETE /api/v1/saved-searches/{id}
+//
+//	@Summary		Delete saved search
+//	@Tags			saved-searches
+//	@Param			id	path	int	true	"Saved search ID"
+//	@Success		204	"Saved search deleted successfully"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid saved search ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Saved search not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/saved-searches/{id} [delete]
+func (h *SavedSearchHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.savedSearchID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), id); err != nil {
+		h.respondWithRepoError(w, err, "delete", id)
+		return
+	}
+	h.cache.Invalidate(r.Context(), cache.SavedSearchesTag)
+
+	h.logger.Info("saved search deleted", "saved_search_id", id)
+	respondNoContent(w)
+}
+
+func (h *SavedSearchHandler) savedSearchID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid saved search ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// decode reads and validates a saved search from the request body. Unknown
+// fields are rejected, so a misspelled filter doesn't silently match more.
+func (h *SavedSearchHandler) decode(w http.ResponseWriter, r *http.Request, search *models.SavedSearch) bool {
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(search); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	search.Name = strings.TrimSpace(search.Name)
+	if search.Name == "" {
+		respondWithError(h.logger, w, http.StatusBadRequest, "name is required")
+		return false
+	}
+	if err := search.Filter.Validate(); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid filter: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func (h *SavedSearchHandler) respondWithRepoError(w http.ResponseWriter, err error, action string, id int) {
+	if err.Error() == "saved search not found" {
+		respondWithError(h.logger, w, http.StatusNotFound, "Saved search not found")
+		return
+	}
+	h.logger.Error("failed to "+action+" saved search", "error", err, "saved_search_id", id)
+	respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action+" saved search")
+}
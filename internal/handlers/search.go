@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/operations"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/search"
+)
+
+// SearchResponse is a page of search results. When the text matched
+// nothing, Postgres matches by spelling similarity instead and suggests the
+// text with misspelled words corrected.
+type SearchResponse struct {
+	models.PaginatedResponse
+	Fuzzy      bool          `json:"fuzzy,omitempty"`
+	DidYouMean string        `json:"did_you_mean,omitempty" example:"office chair"`
+	Facets     models.Facets `json:"facets,omitempty"` // Of ?facets=, counted over every match
+}
+
+type SearchHandler struct {
+	backend   search.Backend
+	facets    models.FacetSpec
+	indexer   *search.Indexer // nil when searches go to Postgres
+	runner    *operations.Runner
+	auditRepo repository.AuditRepository
+	logger    *slog.Logger
+}
+
+// NewSearchHandler creates the search handler. ?facets= buckets prices and
+// stock by the bounds of facets.
+func NewSearchHandler(backend search.Backend, facets models.FacetSpec, indexer *search.Indexer, runner *operations.Runner, auditRepo repository.AuditRepository, logger *slog.Logger) *SearchHandler {
+	return &SearchHandler{backend: backend, facets: facets, indexer: indexer, runner: runner, auditRepo: auditRepo, logger: logger}
+}
+
+// facetSpec returns spec with the facets ?facets= asks for, a
+// comma-separated list of models.FacetNames, and false after responding 400
+// to any other
+func facetSpec(logger *slog.Logger, w http.ResponseWriter, r *http.Request, spec models.FacetSpec) (models.FacetSpec, bool) {
+	f := r.URL.Query().Get("facets")
+	if f == "" {
+		return spec, true
+	}
+	spec.Names = nil
+	for _, name := range strings.Split(f, ",") {
+		name = strings.TrimSpace(name)
+		known := false
+		for _, n := range models.FacetNames {
+			known = known || n == name
+		}
+		if !known {
+			respondWithError(logger, w, http.StatusBadRequest, "facets must be among "+strings.Join(models.FacetNames, ", "))
+			return spec, false
+		}
+		if !spec.Has(name) {
+			spec.Names = append(spec.Names, name)
+		}
+	}
+	return spec, true
+}
+
+// SearchProducts handles GET /api/v1/products/search
+// It returns the products matching a text query, best match first
+//
+//	@Summary		Search products
+//	@Description	Full-text search over SKU, name, tags, and description of the products visible now, optionally filtered by category, tag, and sales region, with counts of the matches per facet on request. Served by Postgres full-text search or, with SEARCH_BACKEND=opensearch or meilisearch, an external index synced from the change feed, which may lag writes by a few seconds. When nothing matches on Postgres, names and SKUs are matched by spelling similarity (SEARCH_FUZZY_THRESHOLD): fuzzy is then true and did_you_mean holds the corrected text, if any word was corrected.
+//	@Tags			products
+//	@Produce		json
+//	@Param			q			query		string	false	"Search text; every word must match"
+//	@Param			category	query		string	false	"Only products in this category"
+//	@Param			tag			query		string	false	"Only products with this tag"
+//	@Param			region		query		string	false	"Only products sold in this region; a regional API key's own by default"
+//	@Param			facets		query		string	false	"Facets to count over the matches: category, price_range, and/or status, comma-separated"
+//	@Param			limit		query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset		query		int		false	"Number of items to skip"				default(0)
+//	@Success		200			{object}	SearchResponse{data=[]models.Product}	"Matching products with pagination metadata"
+//	@Failure		400			{object}	models.ErrorResponse	"Unknown facet or invalid region"
+//	@Failure		403			{object}	models.ErrorResponse	"Region outside the API key's"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/search [get]
+func (h *SearchHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := models.SearchQuery{
+		Text:     strings.TrimSpace(query.Get("q")),
+		Category: strings.TrimSpace(query.Get("category")),
+		Tag:      strings.TrimSpace(query.Get("tag")),
+		Limit:    50,
+	}
+	var ok bool
+	if q.Facets, ok = facetSpec(h.logger, w, r, h.facets); !ok {
+		return
+	}
+	if q.Region, ok = salesRegion(h.logger, w, r); !ok {
+		return
+	}
+	q.Visible = time.Now()
+
+	if l := query.Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			q.Limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := query.Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			q.Offset = parsedOffset
+		}
+	}
+
+	result, err := h.backend.Search(r.Context(), q)
+	if err != nil {
+		h.logger.Error("failed to search products", "backend", h.backend.Name(), "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to search products")
+		return
+	}
+	products := result.Products
+	if products == nil {
+		products = []*models.Product{}
+	}
+
+	pagination := &models.PaginationMeta{Limit: q.Limit, Offset: q.Offset, Total: result.Total}
+	response := SearchResponse{
+		PaginatedResponse: *models.NewPaginatedResponse(http.StatusOK, "Products retrieved successfully", products, pagination),
+		Fuzzy:             result.Fuzzy,
+		DidYouMean:        result.DidYouMean,
+		Facets:            result.Facets,
+	}
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// SuggestProducts handles GET /api/v1/products/suggest
+// It returns name and SKU completions of q for typeahead
+//
+//	@Summary		Suggest products
+//	@Description	Completes the names and SKUs of the products visible now as they are typed: SKUs and names starting with q first, then names with a word starting with or, on Postgres, similar to q. Only the ID, SKU, and name are returned. An empty q returns no suggestions.
+//	@Tags			products
+//	@Produce		json
+//	@Param			q		query		string	false	"Text typed so far"
+//	@Param			region	query		string	false	"Only products sold in this region; a regional API key's own by default"
+//	@Param			limit	query		int		false	"Number of suggestions to return (max 25)"	default(10)
+//	@Success		200		{object}	models.SuccessResponse{data=[]models.Suggestion}	"Suggestions, best first"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid region"
+//	@Failure		403		{object}	models.ErrorResponse	"Region outside the API key's"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/suggest [get]
+func (h *SearchHandler) SuggestProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix := strings.TrimSpace(query.Get("q"))
+	limit := 10
+	if l := query.Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 25)
+		}
+	}
+
+	region, ok := salesRegion(h.logger, w, r)
+	if !ok {
+		return
+	}
+
+	suggestions := []*models.Suggestion{}
+	if prefix != "" {
+		found, err := h.backend.Suggest(r.Context(), models.SuggestQuery{Prefix: prefix, Region: region, Visible: time.Now(), Limit: limit})
+		if err != nil {
+			h.logger.Error("failed to suggest products", "backend", h.backend.Name(), "error", err)
+			respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to suggest products")
+			return
+		}
+		if found != nil {
+			suggestions = found
+		}
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Suggestions retrieved successfully", suggestions)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// ReindexSearch handles POST /api/v1/admin/search/reindex
+// It rebuilds the search index as an operation
+//
+//	@Summary		Rebuild the search index
+//	@Description	Start rebuilding the OpenSearch or Meilisearch index from scratch, which searches switch to once it's built. Returns the operation running the rebuild; poll its Location for progress.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		202	{object}	models.SuccessResponse{data=models.Operation}	"Reindex started"
+//	@Failure		401	{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Failure		503	{object}	models.ErrorResponse	"Searches don't use an index"
+//	@Router			/admin/search/reindex [post]
+func (h *SearchHandler) ReindexSearch(w http.ResponseWriter, r *http.Request) {
+	if h.indexer == nil || h.runner == nil {
+		respondWithError(h.logger, w, http.StatusServiceUnavailable, "Searches don't use an index, set SEARCH_BACKEND to opensearch or meilisearch to rebuild one")
+		return
+	}
+
+	actor := r.RemoteAddr
+	op, err := h.runner.Run(r.Context(), models.OperationReindex, func(ctx context.Context, p *operations.Progress) (string, error) {
+		state, err := h.indexer.Reindex(ctx, p.Of("products indexed"))
+		if err != nil {
+			return "", err
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{"alias": state.Name, "index": state.IndexName})
+		entry := &models.AuditEntry{Action: "search.reindex", Actor: actor, EntityType: "search_index", EntityID: state.Name, Details: details}
+		if err := h.auditRepo.Create(ctx, entry); err != nil {
+			h.logger.Error("failed to record reindex in audit log", "error", err)
+		}
+		return "", nil
+	})
+	if err != nil {
+		h.logger.Error("failed to start reindex", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to start reindex")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusAccepted, "Reindex started", op)
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/operations/%d", op.ID))
+	respondWithJSON(h.logger, w, http.StatusAccepted, response)
+}
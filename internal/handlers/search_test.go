@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/quota"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/search"
+)
+
+// TestSuggestAndRelated_Region checks that suggestions and related products
+// leave out the products not sold in the caller's region, as lists do
+func TestSuggestAndRelated_Region(t *testing.T) {
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(t.TempDir(), "region.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	products := repository.NewProductRepository(db)
+	for _, p := range []*models.Product{
+		{SKU: "LMP-1", Name: "Lamp", Category: "lighting", UnitPrice: 20},
+		{SKU: "LMP-2", Name: "Lamp shade", Category: "lighting", UnitPrice: 21},
+		{SKU: "LMP-3", Name: "Lamp oil", Category: "lighting", UnitPrice: 22},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+	if err := repository.NewProductRegionRepository(db).Add(ctx, []int{2}, []string{"FR"}); err != nil {
+		t.Fatalf("failed to add region: %v", err)
+	}
+
+	searchHandler := NewSearchHandler(search.NewPostgres(repository.NewSearchRepository(db), 0), models.FacetSpec{}, nil, nil, nil, testLogger)
+	relatedHandler := NewRelatedHandler(products, RelatedLimits{DefaultLimit: 10, MaxLimit: 10, PriceBand: 0.2}, testLogger)
+	router := chi.NewRouter()
+	router.Get("/api/v1/products/suggest", searchHandler.SuggestProducts)
+	router.Get("/api/v1/products/{id}/related", relatedHandler.GetRelated)
+
+	tests := []struct {
+		name      string
+		path      string
+		keyRegion string // Of the request's API key, if any
+		want      int
+		skus      []string
+	}{
+		{"suggest", "/api/v1/products/suggest?q=lamp", "", http.StatusOK, []string{"LMP-1", "LMP-3", "LMP-2"}},
+		{"suggest in region", "/api/v1/products/suggest?q=lamp&region=de", "", http.StatusOK, []string{"LMP-1", "LMP-3"}},
+		{"suggest with regional key", "/api/v1/products/suggest?q=lamp", "DE", http.StatusOK, []string{"LMP-1", "LMP-3"}},
+		{"suggest outside the key's region", "/api/v1/products/suggest?q=lamp&region=fr", "DE", http.StatusForbidden, nil},
+		{"suggest in invalid region", "/api/v1/products/suggest?q=lamp&region=de%20fr", "", http.StatusBadRequest, nil},
+		{"related", "/api/v1/products/1/related", "", http.StatusOK, []string{"LMP-2", "LMP-3"}},
+		{"related in region", "/api/v1/products/1/related?region=de", "", http.StatusOK, []string{"LMP-3"}},
+		{"related with regional key", "/api/v1/products/1/related", "DE", http.StatusOK, []string{"LMP-3"}},
+		{"related of a product outside the region", "/api/v1/products/2/related", "DE", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.keyRegion != "" {
+				r = r.WithContext(quota.WithKey(r.Context(), &models.APIKey{ID: 1, Region: tt.keyRegion}))
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var body struct {
+				Data []struct {
+					SKU string `json:"sku"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			var skus []string
+			for _, p := range body.Data {
+				skus = append(skus, p.SKU)
+			}
+			if !reflect.DeepEqual(skus, tt.skus) {
+				t.Errorf("SKUs = %v, want %v", skus, tt.skus)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/serials"
+)
+
+type SerialHandler struct {
+	repo     repository.SerialRepository
+	products repository.ProductRepository
+	serials  *serials.Service
+	logger   *slog.Logger
+}
+
+func NewSerialHandler(repo repository.SerialRepository, products repository.ProductRepository, serialService *serials.Service, logger *slog.Logger) *SerialHandler {
+	return &SerialHandler{repo: repo, products: products, serials: serialService, logger: logger}
+}
+
+// RegisterSerialsRequest lists serial numbers of a product's units on hand
+type RegisterSerialsRequest struct {
+	Serials []string `json:"serials" example:"SN-4C2A-0091,SN-4C2A-0092"`
+}
+
+// RetireSerialRequest says why a serial's unit left stock
+type RetireSerialRequest struct {
+	Reason string `json:"reason" example:"sold"` // sold, scrapped, lost, or returned
+}
+
+// RegisterSerials handles POST /api/v1/products/{id}/serials
+// It records serial numbers of a product's units
+//
+//	@Summary		Register serial numbers
+//	@Description	Record serial numbers of units of a product on hand, all or none, making it serialized. Registering doesn't change the product's quantity, and is refused where it would leave more active serials than units. A serial the product retired before is made active again.
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Product ID"
+//	@Param			serials	body		RegisterSerialsRequest	true	"Serial numbers"
+//	@Success		201		{object}	models.SuccessResponse{data=[]models.SerialNumber}	"Registered serials"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid serials"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		409		{object}	models.ErrorResponse	"Serial in use, or more serials than units"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/serials [post]
+func (h *SerialHandler) RegisterSerials(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var req RegisterSerialsRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	registered, err := h.serials.Register(r.Context(), id, req.Serials)
+	if err != nil {
+		switch {
+		case errors.Is(err, serials.ErrInvalidSerial):
+			respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, serials.ErrSerialInUse), errors.Is(err, serials.ErrExceedsStock):
+			respondWithError(h.logger, w, http.StatusConflict, err.Error())
+		case err.Error() == "product not found":
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+		default:
+			h.logger.Error("failed to register serials", "error", err, "product_id", id)
+			respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to register serials")
+		}
+		return
+	}
+
+	h.logger.Info("serials registered", "product_id", id, "serials", len(registered))
+	response := models.NewSuccessResponse(http.StatusCreated, "Serials registered successfully", registered)
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/products/%d/serials", id), response)
+}
+
+// ListSerials handles GET /api/v1/products/{id}/serials
+// It returns a product's serial numbers
+//
+//	@Summary		List serial numbers
+//	@Description	Get a paginated list of a product's serial numbers in registration order
+//	@Tags			products
+//	@Produce		json
+//	@Param			id		path		int		true	"Product ID"
+//	@Param			status	query		string	false	"active or retired; both when omitted"
+//	@Param			limit	query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int		false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.SerialNumber}	"List of serials with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID or status"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/serials [get]
+func (h *SerialHandler) ListSerials(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != serials.StatusActive && status != serials.StatusRetired {
+		respondWithError(h.logger, w, http.StatusBadRequest, "status must be active or retired")
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	if _, err := h.products.GetByID(ctx, id); err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve serials")
+		return
+	}
+
+	list, err := h.repo.ListByProduct(ctx, id, status, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list serials", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve serials")
+		return
+	}
+
+	total, err := h.repo.CountByProduct(ctx, id, status)
+	if err != nil {
+		h.logger.Error("failed to count serials", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count serials")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Serials retrieved successfully", list, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// LookupSerial handles GET /api/v1/serials/{serial}
+// It finds a scanned serial number's unit
+//
+//	@Summary		Look up a serial number
+//	@Description	Get a serial number, active or retired, with the product it's a unit of, e.g. for a scanned serial
+//	@Tags			serials
+//	@Produce		json
+//	@Param			serial	path		string	true	"Serial number"
+//	@Success		200		{object}	models.SuccessResponse{data=models.SerialLookup}	"Serial with its product"
+//	@Failure		404		{object}	models.ErrorResponse	"Serial not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/serials/{serial} [get]
+func (h *SerialHandler) LookupSerial(w http.ResponseWriter, r *http.Request) {
+	serial := chi.URLParam(r, "serial")
+
+	found, err := h.serials.Lookup(r.Context(), serial)
+	if err != nil {
+		if err.Error() == "serial not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Serial not found")
+			return
+		}
+		h.logger.Error("failed to look up serial", "error", err, "serial", serial)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to look up serial")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Serial retrieved successfully", found)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// RetireSerial handles POST /api/v1/serials/{serial}/retire
+// It records that a serial's unit left stock
+//
+//	@Summary		Retire a serial number
+//	@Description	Record that an active serial's unit left stock, sold, scrapped, lost, or returned to the supplier. Retiring doesn't change the product's quantity: the sale or write-off does.
+//	@Tags			serials
+//	@Accept			json
+//	@Produce		json
+//	@Param			serial	path		string				true	"Serial number"
+//	@Param			retire	body		RetireSerialRequest	true	"Reason"
+//	@Success		200		{object}	models.SuccessResponse{data=models.SerialNumber}	"Retired serial"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid reason"
+//	@Failure		404		{object}	models.ErrorResponse	"Serial not found"
+//	@Failure		409		{object}	models.ErrorResponse	"Serial retired already"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/serials/{serial}/retire [post]
+func (h *SerialHandler) RetireSerial(w http.ResponseWriter, r *http.Request) {
+	serial := chi.URLParam(r, "serial")
+
+	var req RetireSerialRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	retired, err := h.serials.Retire(r.Context(), serial, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, serials.ErrInvalidSerial):
+			respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, serials.ErrRetired):
+			respondWithError(h.logger, w, http.StatusConflict, err.Error())
+		case err.Error() == "serial not found":
+			respondWithError(h.logger, w, http.StatusNotFound, "Serial not found")
+		default:
+			h.logger.Error("failed to retire serial", "error", err, "serial", serial)
+			respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retire serial")
+		}
+		return
+	}
+
+	h.logger.Info("serial retired", "serial", retired.Serial, "product_id", retired.ProductID, "reason", retired.RetiredReason)
+	response := models.NewSuccessResponse(http.StatusOK, "Serial retired successfully", retired)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
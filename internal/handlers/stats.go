@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/valuation"
+)
+
+type StatsHandler struct {
+	repo      repository.ProductStatsRepository
+	valuer    *valuation.Service
+	abcWindow time.Duration
+	logger    *slog.Logger
+}
+
+func NewStatsHandler(repo repository.ProductStatsRepository, valuer *valuation.Service, abcWindow time.Duration, logger *slog.Logger) *StatsHandler {
+	return &StatsHandler{repo: repo, valuer: valuer, abcWindow: abcWindow, logger: logger}
+}
+
+type InventorySummaryResponse struct {
+	Stats     *models.InventorySummary `json:"stats"`
+	Staleness *models.Staleness        `json:"staleness"`
+	Valuation *models.Valuation        `json:"valuation"` // What the stock on hand cost, at its valued_at
+}
+
+type ProductStatsResponse struct {
+	Stats     *models.ProductStats `json:"stats"`
+	Staleness *models.Staleness    `json:"staleness"`
+	Valuation *models.Valuation    `json:"valuation"` // What the stock on hand cost, now
+}
+
+// valuationMethod returns the ?valuation method asked for, the default when
+// none is, and false when it isn't one
+func (h *StatsHandler) valuationMethod(r *http.Request) (string, bool) {
+	method := r.URL.Query().Get("valuation")
+	if method == "" {
+		return h.valuer.Method(), true
+	}
+	return method, valuation.ValidMethod(method)
+}
+
+// Summary handles GET /api/v1/products/stats
+// It returns stock statistics over all products
+//
+//	@Summary		Get inventory statistics
+//	@Description	Product count, units, inventory value at unit prices, out-of-stock products, and units in and out, from a periodically refreshed materialized view. Units count products measured in each or boxes, in each; quantities has the total of every dimension in its base unit. staleness says when it was refreshed. valuation is the cost of the stock on hand from its receipts, as last recorded by the valuation job.
+//	@Tags			products
+//	@Produce		json
+//	@Param			valuation	query		string	false	"Valuation method, fifo or weighted_average (default VALUATION_METHOD)"
+//	@Success		200			{object}	models.SuccessResponse{data=InventorySummaryResponse}	"Inventory statistics"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid valuation method"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/stats [get]
+func (h *StatsHandler) Summary(w http.ResponseWriter, r *http.Request) {
+	method, ok := h.valuationMethod(r)
+	if !ok {
+		respondWithError(h.logger, w, http.StatusBadRequest, "valuation must be fifo or weighted_average")
+		return
+	}
+
+	summary, staleness, err := h.repo.Summary(r.Context())
+	if err != nil {
+		h.logger.Error("failed to get inventory summary", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve inventory statistics")
+		return
+	}
+
+	value, err := h.valuer.Latest(r.Context(), method)
+	if err != nil {
+		h.logger.Error("failed to get inventory valuation", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve inventory statistics")
+		return
+	}
+
+	data := InventorySummaryResponse{Stats: summary, Staleness: staleness, Valuation: value}
+	response := models.NewSuccessResponse(http.StatusOK, "Inventory statistics retrieved successfully", data)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// ProductStats handles GET /api/v1/products/{id}/stats
+// It returns a product's stock statistics
+//
+//	@Summary		Get product statistics
+//	@Description	Inventory value at the unit price, stock movements, and units in and out of one product, from a periodically refreshed materialized view. staleness says when it was refreshed. valuation is the cost of the stock on hand from its receipts, computed live.
+//	@Tags			products
+//	@Produce		json
+//	@Param			id			path		int		true	"Product ID"
+//	@Param			valuation	query		string	false	"Valuation method, fifo or weighted_average (default VALUATION_METHOD)"
+//	@Success		200			{object}	models.SuccessResponse{data=ProductStatsResponse}	"Product statistics"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid product ID or valuation method"
+//	@Failure		404	{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/stats [get]
+func (h *StatsHandler) ProductStats(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+	method, ok := h.valuationMethod(r)
+	if !ok {
+		respondWithError(h.logger, w, http.StatusBadRequest, "valuation must be fifo or weighted_average")
+		return
+	}
+
+	stats, staleness, err := h.repo.Get(r.Context(), id)
+	if err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product stats", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve product statistics")
+		return
+	}
+
+	value, err := h.valuer.Product(r.Context(), id, method)
+	if err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to value product stock", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve product statistics")
+		return
+	}
+
+	data := ProductStatsResponse{Stats: stats, Staleness: staleness, Valuation: value}
+	response := models.NewSuccessResponse(http.StatusOK, "Product statistics retrieved successfully", data)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// ABCAnalysis handles GET /api/v1/stats/abc-analysis
+// It classifies products into A, B, and C by consumption value
+//
+//	@Summary		ABC analysis
+//	@Description	Get a paginated list of products ranked by consumption value, the units stock movements took out of them over the window at their unit price, highest first. The products making up the first 80% of the total value are class A, the next ones up to 95% class B, and the rest, including those with no value, class C. Computed live from the stock movement ledger; window defaults to ABC_ANALYSIS_WINDOW.
+//	@Tags			stats
+//	@Produce		json
+//	@Param			window	query		string	false	"Go duration of history, e.g. 2160h"
+//	@Param			class	query		string	false	"Only products of this class"	Enums(A, B, C)
+//	@Param			limit	query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int		false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.ABCClassification}	"Classified products with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid window or class"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/stats/abc-analysis [get]
+func (h *StatsHandler) ABCAnalysis(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	window := h.abcWindow
+	if v := query.Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			respondWithError(h.logger, w, http.StatusBadRequest, "window must be a positive duration, e.g. 2160h")
+			return
+		}
+		window = d
+	}
+	class := query.Get("class")
+	switch class {
+	case "", models.ABCClassA, models.ABCClassB, models.ABCClassC:
+	default:
+		respondWithError(h.logger, w, http.StatusBadRequest, "class must be A, B, or C")
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if l := query.Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := query.Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	from := time.Now().Add(-window)
+	classifications, err := h.repo.ABC(ctx, from, class, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to classify products", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve ABC analysis")
+		return
+	}
+
+	total, err := h.repo.CountABC(ctx, from, class)
+	if err != nil {
+		h.logger.Error("failed to count classified products", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count classified products")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "ABC analysis retrieved successfully", classifications, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
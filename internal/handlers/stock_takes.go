@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/stocktake"
+)
+
+type StockTakeHandler struct {
+	repo    repository.StockTakeRepository
+	service *stocktake.Service
+	logger  *slog.Logger
+}
+
+func NewStockTakeHandler(repo repository.StockTakeRepository, service *stocktake.Service, logger *slog.Logger) *StockTakeHandler {
+	return &StockTakeHandler{repo: repo, service: service, logger: logger}
+}
+
+// OpenStockTakeRequest names a stock take
+type OpenStockTakeRequest struct {
+	Name string `json:"name" example:"Aisle 4, October"`
+}
+
+// StockCountsRequest is a batch of counts
+type StockCountsRequest struct {
+	Counts []models.StockCount `json:"counts"`
+}
+
+// ListStockTakes handles GET /api/v1/stock-takes
+// It returns a paginated list of stock takes
+//
+//	@Summary		List stock takes
+//	@Description	Get a paginated list of stock takes, most recently opened first
+//	@Tags			stock-takes
+//	@Produce		json
+//	@Param			status	query		string	false	"Only takes with this status"	Enums(open, completed, cancelled)
+//	@Param			limit	query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int		false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.StockTake}	"List of stock takes with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid status"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/stock-takes [get]
+func (h *StockTakeHandler) ListStockTakes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", stocktake.StatusOpen, stocktake.StatusCompleted, stocktake.StatusCancelled:
+	default:
+		respondWithError(h.logger, w, http.StatusBadRequest, "status must be open, completed, or cancelled")
+		return
+	}
+
+	takes, err := h.repo.List(ctx, status, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list stock takes", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve stock takes")
+		return
+	}
+
+	total, err := h.repo.Count(ctx, status)
+	if err != nil {
+		h.logger.Error("failed to count stock takes", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count stock takes")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Stock takes retrieved successfully", takes, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// OpenStockTake handles POST /api/v1/stock-takes
+//
+//	@Summary		Open stock take
+//	@Description	Open a stock take to record counts in. Audited as stock_take.open.
+//	@Tags			stock-takes
+//	@Accept			json
+//	@Produce		json
+//	@Param			stock_take	body		OpenStockTakeRequest	false	"Stock take"
+//	@Success		201			{object}	models.SuccessResponse{data=models.StockTake}	"Stock take opened"
+//	@Failure		400			{object}	models.ErrorResponse	"Invalid request body"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/stock-takes [post]
+func (h *StockTakeHandler) OpenStockTake(w http.ResponseWriter, r *http.Request) {
+	var req OpenStockTakeRequest
+	if r.ContentLength != 0 {
+		if err := newDecoder(r).Decode(&req); err != nil {
+			respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if len(req.Name) > 255 {
+		respondWithError(h.logger, w, http.StatusBadRequest, "name must be at most 255 characters")
+		return
+	}
+
+	take, err := h.service.Open(r.Context(), req.Name, r.RemoteAddr)
+	if err != nil {
+		h.logger.Error("failed to open stock take", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to open stock take")
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusCreated, "Stock take opened successfully", take)
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/stock-takes/%d", take.ID), response)
+}
+
+// GetStockTake handles GET /api/v1/stock-takes/{id}
+//
+//	@Summary		Get stock take
+//	@Description	Get a stock take with its counts, each with its discrepancy from the system quantity when it was counted, and their totals
+//	@Tags			stock-takes
+//	@Produce		json
+//	@Param			id	path		int	true	"Stock take ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.StockTakeDetail}	"Stock take with counts"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid stock take ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Stock take not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/stock-takes/{id} [get]
+func (h *StockTakeHandler) GetStockTake(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.stockTakeID(w, r)
+	if !ok {
+		return
+	}
+
+	detail, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		h.respondWithServiceError(w, err, "retrieve", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Stock take retrieved successfully", detail)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// RecordCounts handles POST /api/v1/stock-takes/{id}/counts
+//
+//	@Summary		Record counts
+//	@Description	Record counted quantities in an open stock take, as JSON or as CSV (Content-Type text/csv) with a header naming the sku, counted, and optionally reason columns. Each count keeps the product's quantity when it was recorded; counting a product again replaces its count. The counts are recorded all or none. reason is one of miscount (the default), damaged, expired, lost, theft, or found. Audited as stock_take.count.
+//	@Tags			stock-takes
+//	@Accept			json
+//	@Accept			text/csv
+//	@Produce		json
+//	@Param			id		path		int					true	"Stock take ID"
+//	@Param			counts	body		StockCountsRequest	true	"Counts"
+//	@Success		200		{object}	models.SuccessResponse{data=[]models.StockTakeCount}	"Recorded counts"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid counts"
+//	@Failure		404		{object}	models.ErrorResponse	"Stock take not found"
+//	@Failure		409		{object}	models.ErrorResponse	"Stock take isn't open, or a product is counted in another open take"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/stock-takes/{id}/counts [post]
+func (h *StockTakeHandler) RecordCounts(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.stockTakeID(w, r)
+	if !ok {
+		return
+	}
+
+	var counts []models.StockCount
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		var err error
+		if counts, err = stocktake.ReadCSV(r.Body); err != nil {
+			respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else {
+		var req StockCountsRequest
+		if err := newDecoder(r).Decode(&req); err != nil {
+			respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		counts = req.Counts
+	}
+
+	recorded, err := h.service.Record(r.Context(), id, counts, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "record counts of", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Counts recorded successfully", recorded)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CompleteStockTake handles POST /api/v1/stock-takes/{id}/complete
+//
+//	@Summary		Complete stock take
+//	@Description	Close an open stock take and adjust every counted product's stock by its discrepancy, in one transaction. Each adjustment is audited as stock.adjust with its reason code and publishes stock.adjusted with reason stock_take. With dry_run, returns the adjustments without posting them. Fails with 422, adjusting nothing, if an adjustment would take stock below zero.
+//	@Tags			stock-takes
+//	@Produce		json
+//	@Param			id	path		int	true	"Stock take ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.StockTakeDetail}	"Completed stock take with the posted adjustments"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid stock take ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Stock take not found"
+//	@Failure		409	{object}	models.ErrorResponse	"Stock take isn't open"
+//	@Failure		422	{object}	models.ErrorResponse	"Insufficient stock for an adjustment"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/stock-takes/{id}/complete [post]
+func (h *StockTakeHandler) CompleteStockTake(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.stockTakeID(w, r)
+	if !ok {
+		return
+	}
+
+	detail, err := h.service.Complete(r.Context(), id, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "complete", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Stock take completed successfully", detail)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CancelStockTake handles POST /api/v1/stock-takes/{id}/cancel
+//
+//	@Summary		Cancel stock take
+//	@Description	Close an open stock take without adjusting any stock. Audited as stock_take.cancel.
+//	@Tags			stock-takes
+//	@Produce		json
+//	@Param			id	path		int	true	"Stock take ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.StockTake}	"Cancelled stock take"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid stock take ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Stock take not found"
+//	@Failure		409	{object}	models.ErrorResponse	"Stock take isn't open"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/stock-takes/{id}/cancel [post]
+func (h *StockTakeHandler) CancelStockTake(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.stockTakeID(w, r)
+	if !ok {
+		return
+	}
+
+	take, err := h.service.Cancel(r.Context(), id, r.RemoteAddr)
+	if err != nil {
+		h.respondWithServiceError(w, err, "cancel", id)
+		return
+	}
+
+	response := models.NewSuccessResponse(http.StatusOK, "Stock take cancelled successfully", take)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+func (h *StockTakeHandler) stockTakeID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid stock take ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *StockTakeHandler) respondWithServiceError(w http.ResponseWriter, err error, action string, id int) {
+	switch {
+	case err.Error() == "stock take not found":
+		respondWithError(h.logger, w, http.StatusNotFound, "Stock take not found")
+	case errors.Is(err, stocktake.ErrInvalidCount):
+		respondWithError(h.logger, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, stocktake.ErrNotOpen), errors.Is(err, stocktake.ErrCountedElsewhere):
+		respondWithError(h.logger, w, http.StatusConflict, err.Error())
+	case errors.Is(err, repository.ErrInsufficientStock):
+		h.logger.Warn("stock take could not be completed", "stock_take_id", id, "error", err)
+		respondWithError(h.logger, w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		h.logger.Error("failed to "+action+" stock take", "error", err, "stock_take_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action+" stock take")
+	}
+}
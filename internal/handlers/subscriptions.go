@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type SubscriptionHandler struct {
+	repo     repository.SubscriptionRepository
+	products repository.ProductRepository
+	logger   *slog.Logger
+}
+
+func NewSubscriptionHandler(repo repository.SubscriptionRepository, products repository.ProductRepository, logger *slog.Logger) *SubscriptionHandler {
+	return &SubscriptionHandler{repo: repo, products: products, logger: logger}
+}
+
+// ListSubscriptions handles GET /api/v1/subscriptions
+// It returns a paginated list of subscriptions
+//
+//	@Summary		List subscriptions
+//	@Description	Get a paginated list of change subscriptions, oldest first. Secrets are left out.
+//	@Tags			subscriptions
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int	false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.Subscription}	"List of subscriptions with pagination metadata"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/subscriptions [get]
+func (h *SubscriptionHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	subs, err := h.repo.List(ctx, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list subscriptions", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve subscriptions")
+		return
+	}
+	if subs == nil {
+		subs = []*models.Subscription{}
+	}
+	for _, sub := range subs {
+		sub.Secret = ""
+	}
+
+	total, err := h.repo.Count(ctx)
+	if err != nil {
+		h.logger.Error("failed to count subscriptions", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count subscriptions")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Subscriptions retrieved successfully", subs, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// GetSubscription handles GET /api/v1/subscriptions/{id}
+//
+//	@Summary		Get subscription by ID
+//	@Description	Get a change subscription, without its secret
+//	@Tags			subscriptions
+//	@Produce		json
+//	@Param			id	path		int	true	"Subscription ID"
+//	@Success		200	{object}	models.SuccessResponse{data=models.Subscription}	"Subscription"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid subscription ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Subscription not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/subscriptions/{id} [get]
+func (h *SubscriptionHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	sub, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		h.respondWithRepoError(w, err, "get", id)
+		return
+	}
+	sub.Secret = ""
+
+	response := models.NewSuccessResponse(http.StatusOK, "Subscription retrieved successfully", sub)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// CreateSubscription handles POST /api/v1/subscriptions
+//
+//	@Summary		Subscribe to product changes
+//	@Description	Registers a callback URL notified when any of the listed fields of a product (by product_id or sku) change, or, watching expiry, when one of its lots comes close to expiring. With a secret, notifications are signed in X-Webhook-Signature.
+//	@Tags			subscriptions
+//	@Accept			json
+//	@Produce		json
+//	@Param			subscription	body		models.Subscription		true	"Subscription"
+//	@Success		201				{object}	models.SuccessResponse{data=models.Subscription}	"Created subscription, with its URL in the Location header"
+//	@Header			201				{string}	Location				"/api/v1/subscriptions/{id}"
+//	@Failure		400				{object}	models.ErrorResponse	"Invalid subscription"
+//	@Failure		404				{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/subscriptions [post]
+func (h *SubscriptionHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var sub models.Subscription
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sub); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if msg := validateSubscription(&sub); msg != "" {
+		respondWithError(h.logger, w, http.StatusBadRequest, msg)
+		return
+	}
+
+	var product *models.Product
+	var err error
+	if sub.SKU != "" {
+		product, err = h.products.GetBySKU(ctx, sub.SKU)
+	} else {
+		product, err = h.products.GetByID(ctx, sub.ProductID)
+	}
+	if err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to create subscription")
+		return
+	}
+	sub.ProductID, sub.SKU = product.ID, ""
+
+	if err := h.repo.Create(ctx, &sub); err != nil {
+		h.logger.Error("failed to create subscription", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to create subscription")
+		return
+	}
+	sub.Secret = ""
+
+	h.logger.Info("subscription created", "subscription_id", sub.ID, "product_id", sub.ProductID, "fields", sub.Fields)
+	response := models.NewSuccessResponse(http.StatusCreated, "Subscription created successfully", sub)
+	respondCreated(h.logger, w, fmt.Sprintf("/api/v1/subscriptions/%d", sub.ID), response)
+}
+
+// DeleteSubscription handles DELETE /api/v1/subscriptions/{id}
+//
+//	@Summary		Unsubscribe
+//	@Description	Deletes a subscription; notifications still queued for it are dropped
+//	@Tags			subscriptions
+//	@Param			id	path	int	true	"Subscription ID"
+//	@Success		204	"Subscription deleted successfully"
+//	@Failure		400	{object}	models.ErrorResponse	"Invalid subscription ID"
+//	@Failure		404	{object}	models.ErrorResponse	"Subscription not found"
+//	@Failure		500	{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/subscriptions/{id} [delete]
+func (h *SubscriptionHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), id); err != nil {
+		h.respondWithRepoError(w, err, "delete", id)
+		return
+	}
+
+	h.logger.Info("subscription deleted", "subscription_id", id)
+	respondNoContent(w)
+}
+
+// validateSubscription returns what's wrong with a new subscription, or ""
+func validateSubscription(sub *models.Subscription) string {
+	sub.SKU = strings.TrimSpace(sub.SKU)
+	if (sub.SKU == "") == (sub.ProductID == 0) {
+		return "Exactly one of product_id and sku is required"
+	}
+
+	if len(sub.Fields) == 0 {
+		return "fields is required, one or more of " + strings.Join(subscriptionFields, ", ")
+	}
+	seen := map[string]bool{}
+	for _, field := range sub.Fields {
+		if !subscriptionField(field) {
+			return fmt.Sprintf("Unknown field %q, expected one of %s", field, strings.Join(subscriptionFields, ", "))
+		}
+		if seen[field] {
+			return fmt.Sprintf("Field %q is listed twice", field)
+		}
+		seen[field] = true
+	}
+
+	u, err := url.Parse(sub.CallbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "callback_url must be an absolute http or https URL"
+	}
+	return ""
+}
+
+// subscriptionFields are the fields a subscription can watch
+var subscriptionFields = append(append([]string{}, events.ProductFields...), events.AlertFields...)
+
+func subscriptionField(field string) bool {
+	for _, f := range subscriptionFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *SubscriptionHandler) respondWithRepoError(w http.ResponseWriter, err error, action string, id int) {
+	if err.Error() == "subscription not found" {
+		respondWithError(h.logger, w, http.StatusNotFound, "Subscription not found")
+		return
+	}
+	h.logger.Error("failed to "+action+" subscription", "error", err, "subscription_id", id)
+	respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to "+action+" subscription")
+}
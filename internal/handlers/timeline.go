@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type TimelineHandler struct {
+	repo     repository.TimelineRepository
+	products repository.ProductRepository
+	logger   *slog.Logger
+}
+
+func NewTimelineHandler(repo repository.TimelineRepository, products repository.ProductRepository, logger *slog.Logger) *TimelineHandler {
+	return &TimelineHandler{repo: repo, products: products, logger: logger}
+}
+
+// GetTimeline handles GET /api/v1/products/{id}/timeline
+// It returns a product's activity, newest first
+//
+//	@Summary		Product timeline
+//	@Description	Get a paginated feed of what happened to a product, newest first: audit entries about it, stock movements, unit price changes, and webhook deliveries to its subscriptions. Each entry carries its record in the field named by its kind. ?kind= narrows it to a comma-separated list of kinds.
+//	@Tags			products
+//	@Produce		json
+//	@Param			id		path		int		true	"Product ID"
+//	@Param			kind	query		string	false	"audit, stock_movement, price_change, and/or webhook_delivery, comma-separated"
+//	@Param			limit	query		int		false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int		false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.TimelineEntry}	"Timeline entries with pagination metadata"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID or kind"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not found"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/products/{id}/timeline [get]
+func (h *TimelineHandler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var kinds []string
+	if k := r.URL.Query().Get("kind"); k != "" {
+		for _, kind := range strings.Split(k, ",") {
+			kind = strings.TrimSpace(kind)
+			if !containsKind(kind) {
+				respondWithError(h.logger, w, http.StatusBadRequest, "kind must be one of "+strings.Join(models.TimelineKinds, ", "))
+				return
+			}
+			kinds = append(kinds, kind)
+		}
+	}
+
+	limit, offset := lotPagination(r)
+
+	if _, err := h.products.GetByID(ctx, id); err != nil {
+		if err.Error() == "product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not found")
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve timeline")
+		return
+	}
+
+	entries, err := h.repo.ForProduct(ctx, id, kinds, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list timeline", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve timeline")
+		return
+	}
+
+	total, err := h.repo.Count(ctx, id, kinds)
+	if err != nil {
+		h.logger.Error("failed to count timeline", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count timeline")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Timeline retrieved successfully", entries, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+func containsKind(kind string) bool {
+	for _, k := range models.TimelineKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/policy"
+	"{{MODULE_NAME}}/internal/tokens"
+)
+
+// defaultTokenTTL is the lifetime of tokens minted without a ttl, capped at
+// ACCESS_TOKEN_MAX_TTL
+const defaultTokenTTL = time.Hour
+
+// TokenRequest mints a scoped access token. At least one of read_only,
+// product_id, and role is required; every one given applies. admin
+// is only granted to read-only tokens without other limits.
+type TokenRequest struct {
+	Subject   string `json:"subject" example:"support ticket 4711"`
+	ReadOnly  bool   `json:"read_only,omitempty" example:"true"`
+	ProductID int    `json:"product_id,omitempty" example:"42"`
+	Role      string `json:"role,omitempty" example:"editor" enums:"editor,approver"`
+	Admin     bool   `json:"admin,omitempty" example:"false"` // Also reach the admin API's GET endpoints
+	TTL       string `json:"ttl,omitempty" example:"30m"`     // Go duration, default 1h
+}
+
+type TokenResponse struct {
+	Token  string         `json:"token"`
+	Claims *tokens.Claims `json:"claims"`
+}
+
+// MintToken handles POST /api/v1/admin/tokens
+// It mints a short-lived scoped access token for a support session or
+// third-party debugging
+//
+//	@Summary		Mint a scoped access token
+//	@Description	Mints a bearer token limited to reads and/or one product, expiring after ttl (at most ACCESS_TOKEN_MAX_TTL). With a role, requests act as that policy role: an editor's product updates are held as revisions until an approver approves them. The mint is recorded in the audit log before the token is returned. Read-only tokens without other limits minted with admin also reach the admin API's GET endpoints; other tokens never do.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			token	body		TokenRequest	true	"Subject and scope"
+//	@Success		201		{object}	models.SuccessResponse{data=TokenResponse}	"Minted token and its claims"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		403		{object}	models.ErrorResponse	"Scoped tokens are disabled"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/admin/tokens [post]
+func (h *AdminHandler) MintToken(w http.ResponseWriter, r *http.Request) {
+	cfg := h.store.Current()
+	if cfg.AccessTokenKey == "" {
+		respondWithError(h.logger, w, http.StatusForbidden, "Scoped access tokens are disabled: ACCESS_TOKEN_KEY is not configured")
+		return
+	}
+	// Admin scoped tokens reach the admin API, but never mint more
+	if tokens.FromContext(r.Context()) != nil {
+		respondWithError(h.logger, w, http.StatusForbidden, "Scoped access tokens can't mint tokens")
+		return
+	}
+
+	var req TokenRequest
+	dec := newDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	req.Subject = strings.TrimSpace(req.Subject)
+	scope := tokens.Scope{ReadOnly: req.ReadOnly, ProductID: req.ProductID, Role: req.Role, Admin: req.Admin}
+	switch {
+	case req.Subject == "":
+		respondWithError(h.logger, w, http.StatusBadRequest, "subject is required")
+		return
+	case req.ProductID < 0:
+		respondWithError(h.logger, w, http.StatusBadRequest, "product_id must be positive")
+		return
+	case req.Role != "" && !containsString(policy.TokenRoles, req.Role):
+		respondWithError(h.logger, w, http.StatusBadRequest, "role must be one of "+strings.Join(policy.TokenRoles, ", "))
+		return
+	case req.Role != "" && req.ReadOnly:
+		respondWithError(h.logger, w, http.StatusBadRequest, "A role can't be combined with read_only")
+		return
+	case req.Admin && scope != (tokens.Scope{ReadOnly: true, Admin: true}):
+		respondWithError(h.logger, w, http.StatusBadRequest, "admin is only granted to read_only tokens without other limits")
+		return
+	case scope.Empty():
+		respondWithError(h.logger, w, http.StatusBadRequest, "At least one of read_only, product_id, and role is required")
+		return
+	}
+
+	ttl := defaultTokenTTL
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			respondWithError(h.logger, w, http.StatusBadRequest, "ttl must be a positive duration, e.g. 30m")
+			return
+		}
+	}
+	ttl = min(ttl, cfg.AccessTokenMaxTTL)
+
+	token, claims, err := tokens.Mint([]byte(cfg.AccessTokenKey), req.Subject, scope, ttl)
+	if err != nil {
+		h.logger.Error("failed to mint access token", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to mint access token")
+		return
+	}
+
+	// Unlike other admin actions, a mint that can't be audited is refused:
+	// every impersonation must be traceable to who started it. Scoped tokens
+	// can't mint, so that's the holder of ADMIN_TOKEN; as it's shared, the
+	// address they minted from is recorded too.
+	details, _ := json.Marshal(struct {
+		*tokens.Claims
+		RemoteAddr string `json:"remote_addr"`
+	}{claims, r.RemoteAddr})
+	entry := &models.AuditEntry{
+		Action:     "token.mint",
+		Actor:      policy.RoleAdmin,
+		EntityType: "access_token",
+		EntityID:   claims.ID,
+		Details:    details,
+	}
+	if err := h.auditRepo.Create(r.Context(), entry); err != nil {
+		h.logger.Error("failed to record access token in audit log", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to mint access token")
+		return
+	}
+
+	h.logger.Info("access token minted", "token_id", claims.ID, "subject", claims.Subject, "expires_at", claims.ExpiresAt)
+	response := models.NewSuccessResponse(http.StatusCreated, "Access token minted successfully", TokenResponse{Token: token, Claims: claims})
+	respondWithJSON(h.logger, w, http.StatusCreated, response)
+}
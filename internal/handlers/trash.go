@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// errSKUTaken is returned when restoring a product whose SKU a newer product
+// has taken
+var errSKUTaken = errors.New("sku taken")
+
+type TrashHandler struct {
+	trash     repository.TrashRepository
+	products  repository.ProductRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	retention time.Duration
+	logger    *slog.Logger
+}
+
+// NewTrashHandler creates the trash handler. Trashed products are purged
+// once retention has passed; 0 keeps them until purged by hand. Restores
+// publish ProductCreated in the restoring transaction.
+func NewTrashHandler(trash repository.TrashRepository, products repository.ProductRepository, tx repository.Transactor, publisher events.Publisher, retention time.Duration, logger *slog.Logger) *TrashHandler {
+	return &TrashHandler{
+		trash:     trash,
+		products:  products,
+		tx:        tx,
+		publisher: publisher,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// ListTrash handles GET /api/v1/trash
+// It returns a paginated list of trashed products
+//
+//	@Summary		List trashed products
+//	@Description	Get a paginated list of deleted products, most recently deleted first, with the time left until each is purged
+//	@Tags			trash
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of items to return (max 100)"	default(50)
+//	@Param			offset	query		int	false	"Number of items to skip"				default(0)
+//	@Success		200		{object}	models.PaginatedResponse{data=[]models.TrashedProduct}	"List of trashed products with pagination metadata"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/trash [get]
+func (h *TrashHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
+			limit = min(parsedLimit, 100)
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	list, err := h.trash.List(ctx, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list trash", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to retrieve trash")
+		return
+	}
+	if list == nil {
+		list = []*models.TrashedProduct{}
+	}
+	now := time.Now()
+	for _, trashed := range list {
+		h.setPurgeAt(trashed, now)
+	}
+
+	total, err := h.trash.Count(ctx)
+	if err != nil {
+		h.logger.Error("failed to count trash", "error", err)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to count trash")
+		return
+	}
+
+	pagination := &models.PaginationMeta{Limit: limit, Offset: offset, Total: total}
+	response := models.NewPaginatedResponse(http.StatusOK, "Trash retrieved successfully", list, pagination)
+	respondWithJSON(h.logger, w, http.StatusOK, response)
+}
+
+// RestoreProduct handles POST /api/v1/trash/{id}/restore
+// It moves a trashed product back into the catalog
+//
+//	@Summary		Restore a trashed product
+//	@Description	Restores a deleted product with its ID, tags, and stock. Bundle components and subscriptions removed with it aren't restored.
+//	@Tags			trash
+//	@Produce		json
+//	@Param			id		path		int		true	"Product ID"
+//	@Param			dry_run	query		bool	false	"Check the restore without performing it (also X-Dry-Run header)"
+//	@Success		200		{object}	models.SuccessResponse{data=models.Product}	"Restored product"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not in trash"
+//	@Failure		409		{object}	models.ErrorResponse	"Another product has the SKU"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/trash/{id}/restore [post]
+func (h *TrashHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var product models.Product
+	err = h.tx.WithTx(ctx, func(ctx context.Context) error {
+		trashed, err := h.trash.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		product = trashed.Product
+
+		if _, err := h.products.GetBySKU(ctx, product.SKU); err == nil {
+			return errSKUTaken
+		} else if err.Error() != "product not found" {
+			return err
+		}
+		if err := h.products.Restore(ctx, &product); err != nil {
+			return err
+		}
+		if err := h.trash.Remove(ctx, id); err != nil {
+			return err
+		}
+
+		return h.publisher.Publish(ctx, events.New(events.ProductCreated{Product: product}))
+	})
+	if err != nil {
+		if err.Error() == "trashed product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not in trash")
+			return
+		}
+		if errors.Is(err, errSKUTaken) {
+			respondWithError(h.logger, w, http.StatusConflict, "Another product has this SKU; change or delete it first")
+			return
+		}
+		h.logger.Error("failed to restore product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to restore product")
+		return
+	}
+
+	message := "Product restored successfully"
+	if database.IsDryRun(ctx) {
+		message = "Dry run: product would be restored"
+	} else {
+		h.logger.Info("product restored", "product_id", id)
+	}
+	respondWithJSON(h.logger, w, http.StatusOK, models.NewSuccessResponse(http.StatusOK, message, product))
+}
+
+// PurgeProduct handles DELETE /api/v1/trash/{id}
+// It deletes a trashed product for good
+//
+//	@Summary		Purge a trashed product
+//	@Description	Permanently deletes a product from trash, ahead of the trash retention
+//	@Tags			trash
+//	@Produce		json
+//	@Param			id		path		int		true	"Product ID"
+//	@Param			dry_run	query		bool	false	"Check the purge without performing it (also X-Dry-Run header)"
+//	@Success		200		{object}	models.SuccessResponse	"Dry run result: the trashed product that would be purged"
+//	@Success		204		"Product purged successfully"
+//	@Failure		400		{object}	models.ErrorResponse	"Invalid product ID"
+//	@Failure		404		{object}	models.ErrorResponse	"Product not in trash"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/trash/{id} [delete]
+func (h *TrashHandler) PurgeProduct(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(h.logger, w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var trashed *models.TrashedProduct
+	err = h.tx.WithTx(ctx, func(ctx context.Context) error {
+		if trashed, err = h.trash.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return h.trash.Remove(ctx, id)
+	})
+	if err != nil {
+		if err.Error() == "trashed product not found" {
+			respondWithError(h.logger, w, http.StatusNotFound, "Product not in trash")
+			return
+		}
+		h.logger.Error("failed to purge product", "error", err, "product_id", id)
+		respondWithError(h.logger, w, http.StatusInternalServerError, "Failed to purge product")
+		return
+	}
+
+	if database.IsDryRun(ctx) {
+		h.setPurgeAt(trashed, time.Now())
+		response := models.NewSuccessResponse(http.StatusOK, "Dry run: product would be purged", trashed)
+		respondWithJSON(h.logger, w, http.StatusOK, response)
+		return
+	}
+
+	h.logger.Info("product purged", "product_id", id)
+	respondNoContent(w)
+}
+
+// setPurgeAt fills in when the trashed product is due to be purged. Products
+// past it wait for the next purge with nothing remaining.
+func (h *TrashHandler) setPurgeAt(trashed *models.TrashedProduct, now time.Time) {
+	if h.retention <= 0 {
+		return
+	}
+	purgeAt := trashed.TrashedAt.Add(h.retention)
+	remaining := int64(max(purgeAt.Sub(now), 0) / time.Second)
+	trashed.PurgeAt, trashed.RemainingSeconds = &purgeAt, &remaining
+}
@@ -0,0 +1,190 @@
+// Package id generates the globally unique IDs of products, so catalogs
+// written in several regions can be merged without two records claiming one
+// ID. Serial integer keys stay the primary keys within a database; the
+// unique ID is the identity that survives a merge, and the API accepts it
+// wherever it accepts a product ID.
+//
+// IDs are time-ordered UUIDs (version 7) or ULIDs, optionally prefixed with
+// the region that created them, e.g. "eu1_01J9ZQ4V8X2M6T0K3R5N7P9B1C". API
+// responses add a type prefix, see ProductID.
+package id
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Strategies of ID_STRATEGY
+const (
+	Serial = "serial" // No unique IDs, only serial integer keys
+	UUID   = "uuid"
+	ULID   = "ulid"
+)
+
+var (
+	regionPattern = regexp.MustCompile(`^[a-z0-9-]{1,16}$`)
+	idPattern     = regexp.MustCompile(`^([a-z0-9-]{1,16}_)?([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|[0-7][0-9A-HJKMNP-TV-Z]{25})$`)
+)
+
+// Valid reports whether id is an ID of the uuid or ulid strategy, from any
+// region. IDs fit in 53 characters.
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+type Generator struct {
+	strategy string
+	prefix   string // Region and separator, if any
+
+	mu   sync.Mutex
+	last [16]byte // Last ULID, zero before the first
+}
+
+// New returns a generator for strategy, prefixing IDs with region unless
+// it's empty
+func New(strategy, region string) (*Generator, error) {
+	switch strategy {
+	case "":
+		strategy = Serial
+	case Serial, UUID, ULID:
+	default:
+		return nil, fmt.Errorf("unknown ID strategy %q, expected serial, uuid, or ulid", strategy)
+	}
+	g := &Generator{strategy: strategy}
+	if region != "" {
+		if !regionPattern.MatchString(region) {
+			return nil, fmt.Errorf("invalid region %q: up to 16 lowercase letters, digits, or dashes", region)
+		}
+		if region == ProductPrefix {
+			return nil, fmt.Errorf("invalid region %q: it's a type prefix", region)
+		}
+		g.prefix = region + "_"
+	}
+	return g, nil
+}
+
+// Strategy returns the strategy the generator was created with
+func (g *Generator) Strategy() string {
+	return g.strategy
+}
+
+// Next returns a new ID, or "" for the serial strategy
+func (g *Generator) Next() string {
+	switch g.strategy {
+	case UUID:
+		id, err := uuid.NewV7()
+		if err != nil {
+			panic(fmt.Sprintf("id: failed to read random bytes: %v", err))
+		}
+		return g.prefix + id.String()
+	case ULID:
+		return g.prefix + g.nextULID(time.Now())
+	}
+	return ""
+}
+
+// Crockford's base32, which ULIDs are written in
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// nextULID returns a ULID: 48 bits of Unix milliseconds and 80 random bits.
+// ULIDs of one generator strictly increase: within a millisecond, or when
+// the clock goes back, the last ULID's random bits are incremented instead.
+func (g *Generator) nextULID(t time.Time) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(t.UnixMilli())
+	if last := timestamp(g.last); ms <= last && last != 0 {
+		if !increment(g.last[6:]) {
+			// 2^80 IDs in one millisecond: borrow the next one
+			putTimestamp(&g.last, last+1)
+		}
+		return encodeULID(g.last)
+	}
+
+	putTimestamp(&g.last, ms)
+	if _, err := rand.Read(g.last[6:]); err != nil {
+		panic(fmt.Sprintf("id: failed to read random bytes: %v", err))
+	}
+	return encodeULID(g.last)
+}
+
+func timestamp(b [16]byte) uint64 {
+	var ms uint64
+	for _, v := range b[:6] {
+		ms = ms<<8 | uint64(v)
+	}
+	return ms
+}
+
+func putTimestamp(b *[16]byte, ms uint64) {
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// increment adds one to the big-endian number in b, reporting false when it
+// wrapped around to zero
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeULID writes 128 bits as 26 characters of 5 bits, the first holding 3
+func encodeULID(b [16]byte) string {
+	var sb strings.Builder
+	sb.Grow(26)
+	for i := 0; i < 26; i++ {
+		shift := 125 - 5*i
+		sb.WriteByte(crockford[bits(b, shift)])
+	}
+	return sb.String()
+}
+
+// bits returns the 5 bits of b starting shift bits from its least
+// significant end, with bits past the most significant end read as zero
+func bits(b [16]byte, shift int) byte {
+	var v byte
+	for j := 4; j >= 0; j-- {
+		v <<= 1
+		if bit := shift + j; bit < 128 {
+			v |= (b[15-bit/8] >> (bit % 8)) & 1
+		}
+	}
+	return v
+}
+
+// current holds the generator products are created with
+var current atomic.Pointer[Generator]
+
+func init() {
+	Use(&Generator{strategy: Serial})
+}
+
+// Use makes g the generator of Generate
+func Use(g *Generator) {
+	current.Store(g)
+}
+
+// Current returns the generator in use
+func Current() *Generator {
+	return current.Load()
+}
+
+// Generate returns a new ID from the generator in use, "" when it's serial
+func Generate() string {
+	return Current().Next()
+}
@@ -0,0 +1,104 @@
+package id
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestGenerator(t *testing.T) {
+	tests := []struct {
+		strategy string
+		region   string
+		pattern  string
+	}{
+		{Serial, "", `^$`},
+		{UUID, "", `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{ULID, "", `^[0-7][0-9A-HJKMNP-TV-Z]{25}$`},
+		{ULID, "eu-west-1", `^eu-west-1_[0-7][0-9A-HJKMNP-TV-Z]{25}$`},
+	}
+	for _, tt := range tests {
+		g, err := New(tt.strategy, tt.region)
+		if err != nil {
+			t.Fatalf("New(%q, %q): %v", tt.strategy, tt.region, err)
+		}
+		id := g.Next()
+		if !regexp.MustCompile(tt.pattern).MatchString(id) {
+			t.Errorf("%s/%s: %q doesn't match %s", tt.strategy, tt.region, id, tt.pattern)
+		}
+		if valid := Valid(id); valid != (tt.strategy != Serial) {
+			t.Errorf("Valid(%q) = %v", id, valid)
+		}
+	}
+
+	for _, id := range []string{"42", "stats", "01J9ZQ4V8X2M6T0K3R5N7P9B1", "EU_01J9ZQ4V8X2M6T0K3R5N7P9B1C", "01J9ZQ4V8X2M6T0K3R5N7P9B1U"} {
+		if Valid(id) {
+			t.Errorf("Valid(%q) = true", id)
+		}
+	}
+
+	for _, bad := range [][2]string{{"snowflake", ""}, {ULID, "EU"}, {UUID, "a_b"}} {
+		if _, err := New(bad[0], bad[1]); err == nil {
+			t.Errorf("New(%q, %q) succeeded", bad[0], bad[1])
+		}
+	}
+}
+
+func TestProductID(t *testing.T) {
+	var product struct {
+		UID ProductID `json:"uid,omitempty"`
+	}
+	for _, body := range []string{`{"uid":"prod_eu1_01J9ZQ4V8X2M6T0K3R5N7P9B1C"}`, `{"uid":"eu1_01J9ZQ4V8X2M6T0K3R5N7P9B1C"}`} {
+		if err := json.Unmarshal([]byte(body), &product); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", body, err)
+		}
+		if product.UID != "eu1_01J9ZQ4V8X2M6T0K3R5N7P9B1C" {
+			t.Errorf("Unmarshal(%s) = %q", body, product.UID)
+		}
+	}
+
+	if out, _ := json.Marshal(product); string(out) != `{"uid":"prod_eu1_01J9ZQ4V8X2M6T0K3R5N7P9B1C"}` {
+		t.Errorf("Marshal() = %s", out)
+	}
+	product.UID = ""
+	if out, _ := json.Marshal(product); string(out) != `{}` {
+		t.Errorf("Marshal() of no ID = %s", out)
+	}
+
+	if _, err := New(ULID, ProductPrefix); err == nil {
+		t.Errorf("New() accepted the region %q", ProductPrefix)
+	}
+}
+
+func TestULIDSortsByTime(t *testing.T) {
+	g, _ := New(ULID, "")
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	var generated []string
+	for i := 0; i < 50; i++ {
+		generated = append(generated, g.nextULID(start.Add(time.Duration(i/10)*time.Millisecond)))
+	}
+	// Clock going back
+	generated = append(generated, g.nextULID(start))
+	for i := 1; i < len(generated); i++ {
+		if generated[i] <= generated[i-1] {
+			t.Fatalf("ULID %d %s doesn't sort after %s", i, generated[i], generated[i-1])
+		}
+	}
+
+	// Random bits overflowing borrow the next millisecond
+	g.last = [16]byte{0, 0, 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if got := g.nextULID(time.UnixMilli(1)); got != "00000000020000000000000000" {
+		t.Errorf("after overflow = %s", got)
+	}
+
+	// The Unix epoch encodes as zeros, the maximum time as 7ZZZZZZZZZ
+	var b [16]byte
+	if got := encodeULID(b)[:10]; got != "0000000000" {
+		t.Errorf("epoch time part = %s", got)
+	}
+	putTimestamp(&b, 1<<48-1)
+	if got := encodeULID(b)[:10]; got != "7ZZZZZZZZZ" {
+		t.Errorf("max time part = %s", got)
+	}
+}
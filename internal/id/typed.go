@@ -0,0 +1,40 @@
+package id
+
+import "strings"
+
+// Type prefixes of unique IDs in API responses, telling what an ID names
+const (
+	ProductPrefix = "prod"
+)
+
+// ProductID is the unique ID of a product. It's stored as generated and
+// sent with its type prefix, "prod_01J9ZQ4V8X2M6T0K3R5N7P9B1C"; it's
+// accepted with or without the prefix.
+type ProductID string
+
+func (id ProductID) MarshalText() ([]byte, error) {
+	return withPrefix(ProductPrefix, string(id)), nil
+}
+
+func (id *ProductID) UnmarshalText(text []byte) error {
+	*id = ProductID(ParseProductID(string(text)))
+	return nil
+}
+
+// String returns the ID with its type prefix
+func (id ProductID) String() string {
+	return string(withPrefix(ProductPrefix, string(id)))
+}
+
+// ParseProductID returns s without the product type prefix, if it has one.
+// The result is a product ID if it's Valid.
+func ParseProductID(s string) string {
+	return strings.TrimPrefix(s, ProductPrefix+"_")
+}
+
+func withPrefix(prefix, id string) []byte {
+	if id == "" {
+		return nil
+	}
+	return []byte(prefix + "_" + id)
+}
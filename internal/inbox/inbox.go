@@ -0,0 +1,56 @@
+// Package inbox applies messages from other systems, such as order webhooks
+// and queue messages, at most once. Senders retry until they see success, so
+// the same message can arrive twice; its ID is recorded in inbox_messages
+// within the transaction that applies it, and a message whose ID was
+// recorded is discarded.
+package inbox
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+var duplicates = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "inbox_duplicates_total",
+	Help: "Messages discarded as already applied, by source.",
+}, []string{"source"})
+
+// ErrDuplicate is returned by Process for a message already applied
+var ErrDuplicate = errors.New("message already processed")
+
+// Inbox records the messages applied
+type Inbox struct {
+	repo repository.InboxRepository
+	tx   repository.Transactor
+}
+
+func New(repo repository.InboxRepository, tx repository.Transactor) *Inbox {
+	return &Inbox{repo: repo, tx: tx}
+}
+
+// Process runs apply for the message id from source in a transaction that
+// records it, unless it was recorded before, which returns ErrDuplicate. When
+// apply fails the record is rolled back with its changes, so a redelivery
+// applies the message again. Messages without an ID, whose sender didn't set
+// one, are always applied.
+func (i *Inbox) Process(ctx context.Context, source, id string, apply func(ctx context.Context) error) error {
+	if id == "" {
+		return apply(ctx)
+	}
+
+	return i.tx.WithTx(ctx, func(ctx context.Context) error {
+		first, err := i.repo.Receive(ctx, source, id)
+		if err != nil {
+			return err
+		}
+		if !first {
+			duplicates.WithLabelValues(source).Inc()
+			return ErrDuplicate
+		}
+		return apply(ctx)
+	})
+}
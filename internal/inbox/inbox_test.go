@@ -0,0 +1,76 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func setupSQLiteDB(t *testing.T) *database.DB {
+	t.Helper()
+
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(t.TempDir(), "inbox.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return db
+}
+
+func TestProcess(t *testing.T) {
+	db := setupSQLiteDB(t)
+	box := New(repository.NewInboxRepository(db), db)
+	ctx := context.Background()
+
+	applied := 0
+	apply := func(ctx context.Context) error {
+		applied++
+		return nil
+	}
+	for i, want := range []error{nil, ErrDuplicate} {
+		if err := box.Process(ctx, "nats", "evt-1", apply); !errors.Is(err, want) {
+			t.Errorf("Process #%d = %v, want %v", i+1, err, want)
+		}
+	}
+	if err := box.Process(ctx, "webhook", "evt-1", apply); err != nil {
+		t.Errorf("Process from another source = %v, want applied", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := box.Process(ctx, "nats", "", apply); err != nil {
+			t.Errorf("Process without an ID = %v, want applied", err)
+		}
+	}
+	if applied != 4 {
+		t.Errorf("applied %d times, want 4", applied)
+	}
+}
+
+func TestProcess_FailureRollsBack(t *testing.T) {
+	db := setupSQLiteDB(t)
+	box := New(repository.NewInboxRepository(db), db)
+	ctx := context.Background()
+
+	unavailable := errors.New("stock unavailable")
+	if err := box.Process(ctx, "nats", "evt-1", func(ctx context.Context) error { return unavailable }); !errors.Is(err, unavailable) {
+		t.Fatalf("Process = %v, want the failure", err)
+	}
+	// The redelivery applies it
+	applied := false
+	if err := box.Process(ctx, "nats", "evt-1", func(ctx context.Context) error {
+		applied = true
+		return nil
+	}); err != nil || !applied {
+		t.Errorf("Process of the redelivery = %v, applied %v; want applied", err, applied)
+	}
+}
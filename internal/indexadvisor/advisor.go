@@ -0,0 +1,136 @@
+// Package indexadvisor catches missing indexes in tests. It explains the
+// repository's query shapes against a seeded database and reports every
+// sequential scan that reads more rows than a threshold, which on a
+// production-sized table means a filter or sort no index covers.
+package indexadvisor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Case is one query shape to check, with parameters representative of
+// production traffic: a selective filter should select few seeded rows.
+type Case struct {
+	Query        string // Name in repository.ExplainableQueries
+	Params       map[string]json.RawMessage
+	AllowSeqScan bool // The query reads the whole table by design, e.g. a count
+}
+
+// Finding is a sequential scan over the threshold
+type Finding struct {
+	Query    string
+	Relation string
+	Rows     int64  // Rows read, including those the filter removed
+	Filter   string // Condition applied to every row read, if any
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("%s: sequential scan of %s read %d rows", f.Query, f.Relation, f.Rows)
+	if f.Filter != "" {
+		s += " filtering " + f.Filter
+	}
+	return s
+}
+
+// planNode is the part of an EXPLAIN (FORMAT JSON) node the advisor reads
+type planNode struct {
+	NodeType        string     `json:"Node Type"`
+	Relation        string     `json:"Relation Name"`
+	Alias           string     `json:"Alias"`
+	Filter          string     `json:"Filter"`
+	PlanRows        float64    `json:"Plan Rows"`
+	ActualRows      *float64   `json:"Actual Rows"` // Only with ANALYZE
+	ActualLoops     float64    `json:"Actual Loops"`
+	RemovedByFilter float64    `json:"Rows Removed by Filter"`
+	Plans           []planNode `json:"Plans"`
+}
+
+// SeqScans returns the sequential scans in plan, EXPLAIN (FORMAT JSON)
+// output, that read more than threshold rows. With ANALYZE the rows actually
+// read are counted, otherwise the planner's estimate.
+func SeqScans(query string, plan json.RawMessage, threshold int64) ([]Finding, error) {
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &plans); err != nil {
+		return nil, fmt.Errorf("failed to parse plan of %s: %w", query, err)
+	}
+
+	var findings []Finding
+	var walk func(node planNode)
+	walk = func(node planNode) {
+		if node.NodeType == "Seq Scan" {
+			rows := node.PlanRows
+			if node.ActualRows != nil {
+				// Per-loop averages, as EXPLAIN reports them
+				rows = (*node.ActualRows + node.RemovedByFilter) * max(node.ActualLoops, 1)
+			}
+			if int64(rows) > threshold {
+				relation := node.Relation
+				if relation == "" {
+					relation = node.Alias
+				}
+				findings = append(findings, Finding{Query: query, Relation: relation, Rows: int64(rows), Filter: node.Filter})
+			}
+		}
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+	for _, p := range plans {
+		walk(p.Plan)
+	}
+
+	return findings, nil
+}
+
+// Check explains every case and returns the sequential scans over threshold
+// in those that don't allow them
+func Check(ctx context.Context, explainer repository.QueryExplainer, cases []Case, threshold int64) ([]Finding, error) {
+	var findings []Finding
+	for _, c := range cases {
+		plan, err := explainer.Explain(ctx, c.Query, c.Params)
+		if err != nil {
+			return nil, err
+		}
+		if c.AllowSeqScan {
+			continue
+		}
+
+		scans, err := SeqScans(c.Query, plan, threshold)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, scans...)
+	}
+	return findings, nil
+}
+
+// Require fails t for every finding of Check, and for every explainable
+// query without a case, so a new query can't skip the check
+func Require(t testing.TB, explainer repository.QueryExplainer, cases []Case, threshold int64) {
+	t.Helper()
+
+	covered := make(map[string]bool, len(cases))
+	for _, c := range cases {
+		covered[c.Query] = true
+	}
+	for _, query := range repository.ExplainableQueries {
+		if !covered[query.Name] {
+			t.Errorf("%s has no index advisor case; add one with representative params", query.Name)
+		}
+	}
+
+	findings, err := Check(context.Background(), explainer, cases, threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, finding := range findings {
+		t.Errorf("%s; is an index missing?", finding)
+	}
+}
@@ -0,0 +1,231 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/lots"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+	"{{MODULE_NAME}}/internal/units"
+)
+
+var (
+	ErrInvalidOrder   = errors.New("invalid order")
+	ErrUnknownSKU     = errors.New("unknown sku")
+	ErrDuplicateOrder = errors.New("order already processed")
+)
+
+var lowStockAlerts = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "inventory_low_stock_alerts_total",
+	Help: "Products that dropped to or below the low stock threshold.",
+})
+
+// Service applies stock changes coming from outside the product API, such as
+// orders placed on sales channels
+type Service struct {
+	repo              repository.ProductRepository
+	bundles           repository.BundleRepository
+	orders            repository.OrderRepository
+	tx                repository.Transactor
+	lots              *lots.Service
+	units             *units.Table
+	publisher         events.Publisher
+	lowStockThreshold int
+	logger            *slog.Logger
+}
+
+// NewService creates the inventory service. A stock.low event is published
+// whenever an order takes a product's quantity from above lowStockThreshold to
+// at or below it; a threshold of 0 disables the alerts. Order lines are
+// converted to their product's unit with unitTable, and the units sold are
+// taken from the product's lots first-expired-first-out.
+func NewService(repo repository.ProductRepository, bundles repository.BundleRepository, orders repository.OrderRepository, tx repository.Transactor, lotService *lots.Service, unitTable *units.Table, publisher events.Publisher, lowStockThreshold int, logger *slog.Logger) *Service {
+	return &Service{
+		repo:              repo,
+		bundles:           bundles,
+		orders:            orders,
+		tx:                tx,
+		lots:              lotService,
+		units:             unitTable,
+		publisher:         publisher,
+		lowStockThreshold: lowStockThreshold,
+		logger:            logger,
+	}
+}
+
+// ApplyOrder decrements stock for every line of the order in one transaction.
+// Each line's quantity is converted from its unit to the product's first,
+// failing with ErrInvalidOrder for units that don't convert exactly, e.g. 6
+// each of a product stocked in box-of-12. A line for a bundle decrements its components instead, by the units each
+// bundle takes. Either all lines are applied or none: an unknown SKU or
+// insufficient stock on any line rolls back the whole order. Orders are idempotent per source;
+// an order ID seen before returns ErrDuplicateOrder without touching stock.
+func (s *Service) ApplyOrder(ctx context.Context, source string, order *models.Order) ([]*models.Product, error) {
+	if err := validateOrder(order); err != nil {
+		return nil, err
+	}
+
+	var updated []*models.Product
+	var alerts []*models.Product
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		updated = updated[:0]
+		alerts = alerts[:0]
+		var evts []events.Event
+
+		first, err := s.orders.MarkProcessed(ctx, source, order)
+		if err != nil {
+			return err
+		}
+		if !first {
+			return ErrDuplicateOrder
+		}
+
+		for i, line := range order.Lines {
+			product, err := s.repo.GetBySKU(ctx, line.SKU)
+			if err != nil {
+				if err.Error() == "product not found" {
+					return fmt.Errorf("%w: %s", ErrUnknownSKU, line.SKU)
+				}
+				return err
+			}
+
+			quantity, err := s.units.Convert(line.Quantity, line.Unit, product.Unit)
+			if err != nil {
+				return fmt.Errorf("%w: line %d: %w", ErrInvalidOrder, i, err)
+			}
+
+			changes, err := s.stockChanges(ctx, product, quantity)
+			if err != nil {
+				return err
+			}
+
+			for _, change := range changes {
+				after, err := s.repo.AdjustStock(ctx, change.productID, -change.quantity)
+				if err != nil {
+					if errors.Is(err, repository.ErrInsufficientStock) {
+						return fmt.Errorf("%w for %s: %d requested, %d available",
+							repository.ErrInsufficientStock, change.describe(line.SKU), change.quantity, change.available)
+					}
+					return err
+				}
+				if _, err := s.lots.Take(ctx, change.productID, change.quantity); err != nil {
+					return err
+				}
+
+				updated = append(updated, after)
+				evts = append(evts, events.New(events.StockAdjusted{
+					ProductID: after.ID,
+					SKU:       after.SKU,
+					Previous:  after.Quantity + change.quantity,
+					Current:   after.Quantity,
+					Delta:     -change.quantity,
+					Reason:    "order",
+				}))
+
+				if CrossedLowStock(s.lowStockThreshold, after.Quantity+change.quantity, after.Quantity) {
+					alerts = append(alerts, after)
+					evts = append(evts, events.New(events.StockLow{
+						ProductID: after.ID,
+						SKU:       after.SKU,
+						Quantity:  after.Quantity,
+						Threshold: s.lowStockThreshold,
+					}))
+				}
+			}
+		}
+
+		if err := s.publisher.Publish(ctx, evts...); err != nil {
+			return err
+		}
+
+		// Skipped when the transaction rolls back, including dry runs
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("order applied to stock", "source", source, "order_id", order.ID, "lines", len(order.Lines))
+			for _, product := range alerts {
+				lowStockAlerts.Inc()
+				s.logger.Warn("product stock is low",
+					"product_id", product.ID,
+					"sku", product.SKU,
+					"quantity", product.Quantity,
+					"threshold", s.lowStockThreshold,
+				)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// stockChange is a decrement of one product's stock for an order line
+type stockChange struct {
+	productID int
+	sku       string // Set for a bundle's component
+	quantity  int
+	available int
+}
+
+func (c stockChange) describe(lineSKU string) string {
+	if c.sku == "" {
+		return lineSKU
+	}
+	return fmt.Sprintf("%s (in bundle %s)", c.sku, lineSKU)
+}
+
+// stockChanges returns the decrements selling quantity of a product takes:
+// the product's own stock, or a bundle's components
+func (s *Service) stockChanges(ctx context.Context, product *models.Product, quantity int) ([]stockChange, error) {
+	components, err := s.bundles.Components(ctx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(components) == 0 {
+		return []stockChange{{productID: product.ID, quantity: quantity, available: product.Quantity}}, nil
+	}
+
+	changes := make([]stockChange, len(components))
+	for i, c := range components {
+		changes[i] = stockChange{productID: c.ProductID, sku: c.SKU, quantity: quantity * c.Quantity, available: c.InStock}
+	}
+	return changes, nil
+}
+
+// CrossedLowStock reports whether a change from previous to current took the
+// quantity from above the threshold to at or below it
+func CrossedLowStock(threshold, previous, current int) bool {
+	if threshold <= 0 {
+		return false
+	}
+	return previous > threshold && current <= threshold
+}
+
+func validateOrder(order *models.Order) error {
+	if order.ID == "" {
+		return fmt.Errorf("%w: order_id is required", ErrInvalidOrder)
+	}
+	if len(order.Lines) == 0 {
+		return fmt.Errorf("%w: at least one line is required", ErrInvalidOrder)
+	}
+	for i, line := range order.Lines {
+		if line.SKU == "" {
+			return fmt.Errorf("%w: line %d: sku is required", ErrInvalidOrder, i)
+		}
+		if line.Quantity <= 0 {
+			return fmt.Errorf("%w: line %d: quantity must be positive", ErrInvalidOrder, i)
+		}
+		if line.Unit == "" {
+			return fmt.Errorf("%w: line %d: unit is required", ErrInvalidOrder, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestCrossedLowStock(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		previous  int
+		current   int
+		want      bool
+	}{
+		{"drops below threshold", 10, 12, 8, true},
+		{"drops onto threshold", 10, 11, 10, true},
+		{"already low", 10, 9, 5, false},
+		{"stays above threshold", 10, 20, 15, false},
+		{"restock", 10, 5, 20, false},
+		{"disabled", 0, 5, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CrossedLowStock(tt.threshold, tt.previous, tt.current); got != tt.want {
+				t.Errorf("CrossedLowStock(%d, %d, %d) = %v, want %v",
+					tt.threshold, tt.previous, tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   models.Order
+		wantErr bool
+	}{
+		{"valid", models.Order{ID: "1001", Lines: []models.OrderLine{{SKU: "A", Quantity: 1, Unit: "each"}}}, false},
+		{"missing id", models.Order{Lines: []models.OrderLine{{SKU: "A", Quantity: 1, Unit: "each"}}}, true},
+		{"no lines", models.Order{ID: "1001"}, true},
+		{"missing sku", models.Order{ID: "1001", Lines: []models.OrderLine{{Quantity: 1, Unit: "each"}}}, true},
+		{"missing unit", models.Order{ID: "1001", Lines: []models.OrderLine{{SKU: "A", Quantity: 1}}}, true},
+		{"zero quantity", models.Order{ID: "1001", Lines: []models.OrderLine{{SKU: "A", Unit: "each"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOrder(&tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidOrder) {
+				t.Errorf("expected ErrInvalidOrder, got %v", err)
+			}
+		})
+	}
+}
+
+type fakeBundles struct {
+	repository.BundleRepository
+	components map[int][]models.BundleComponent
+}
+
+func (f *fakeBundles) Components(ctx context.Context, productID int) ([]models.BundleComponent, error) {
+	return f.components[productID], nil
+}
+
+func TestStockChanges(t *testing.T) {
+	s := &Service{bundles: &fakeBundles{components: map[int][]models.BundleComponent{
+		1: {{ProductID: 2, SKU: "A", Quantity: 1, InStock: 5}, {ProductID: 3, SKU: "B", Quantity: 3, InStock: 4}},
+	}}}
+
+	changes, err := s.stockChanges(context.Background(), &models.Product{ID: 1, SKU: "KIT"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []stockChange{{productID: 2, sku: "A", quantity: 2, available: 5}, {productID: 3, sku: "B", quantity: 6, available: 4}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("bundle changes = %+v, want %+v", changes, want)
+	}
+	if got := changes[1].describe("KIT"); got != "B (in bundle KIT)" {
+		t.Errorf("describe = %q", got)
+	}
+
+	changes, err = s.stockChanges(context.Background(), &models.Product{ID: 4, SKU: "PLAIN", Quantity: 9}, 2)
+	if err != nil || len(changes) != 1 || changes[0].productID != 4 || changes[0].quantity != 2 || changes[0].describe("PLAIN") != "PLAIN" {
+		t.Errorf("plain product changes = %+v, %v", changes, err)
+	}
+}
@@ -0,0 +1,289 @@
+// Package jsonenc encodes HTTP response bodies. The encoder is pluggable:
+// encoding/json, or segmentio/encoding, which produces the same bytes with
+// fewer allocations. Bodies are encoded into pooled buffers, so a response
+// costs no buffer allocation and a failed encoding can still become a 500.
+package jsonenc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	segmentjson "github.com/segmentio/encoding/json"
+)
+
+// Encoder appends JSON encodings to buffers
+type Encoder interface {
+	Name() string
+	// Append appends the JSON encoding of v followed by a newline to dst, the
+	// output of json.Encoder with its default settings
+	Append(dst []byte, v interface{}) ([]byte, error)
+}
+
+// Std encodes with encoding/json
+type Std struct{}
+
+func (Std) Name() string { return "std" }
+
+func (Std) Append(dst []byte, v interface{}) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return dst, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Segment encodes with segmentio/encoding, escaping HTML and sorting map keys
+// like encoding/json
+type Segment struct{}
+
+func (Segment) Name() string { return "segmentio" }
+
+func (Segment) Append(dst []byte, v interface{}) ([]byte, error) {
+	out, err := segmentjson.Append(dst, v, segmentjson.EscapeHTML|segmentjson.SortMapKeys)
+	if err != nil {
+		return dst, err
+	}
+	return append(out, '\n'), nil
+}
+
+// New returns the encoder called name
+func New(name string) (Encoder, error) {
+	switch name {
+	case "std":
+		return Std{}, nil
+	case "", "segmentio":
+		return Segment{}, nil
+	default:
+		return nil, fmt.Errorf("unknown JSON encoder %q", name)
+	}
+}
+
+// current holds the encoder in use; atomic.Value needs one concrete type
+var current atomic.Value // holder
+
+type holder struct{ Encoder }
+
+func init() {
+	Use(Segment{})
+}
+
+// Use makes enc the encoder for Write and new Timestamped responses
+func Use(enc Encoder) {
+	current.Store(holder{enc})
+}
+
+// Current returns the encoder in use
+func Current() Encoder {
+	return current.Load().(holder).Encoder
+}
+
+// maxPooled is the largest buffer returned to the pool; larger ones, from the
+// occasional big response, are left to the garbage collector
+const maxPooled = 64 << 10
+
+var buffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 4096)
+		return &b
+	},
+}
+
+// Write encodes v and writes it as the body of a response with status code.
+// If v can't be encoded nothing is written and the error is returned.
+func Write(w http.ResponseWriter, code int, v interface{}) error {
+	if warnings := responseWarnings(w); len(warnings) > 0 {
+		if body, ok := v.(Warnable); ok {
+			body.AddWarnings(warnings...)
+		}
+	}
+	// Problem details are JSON, so clients asking for another format get
+	// the error envelope in it
+	enc, contentType := Current(), "application/json"
+	varied, formatted := false, false
+	if formatter := responseFormatter(w); formatter != nil {
+		w.Header().Add("Vary", "Accept")
+		varied = true
+		if format, formatType := formatter.ResponseFormat(); format != nil {
+			enc, contentType, formatted = format, formatType, true
+		}
+	}
+	if problem, ok := v.(Problemer); ok && !formatted {
+		if negotiator := problemNegotiator(w); negotiator != nil {
+			if !varied {
+				w.Header().Add("Vary", "Accept")
+			}
+			if typeBase, instance, ok := negotiator.ProblemDetails(); ok {
+				v = problem.Problem(typeBase, instance)
+				contentType = ProblemContentType
+			}
+		}
+	}
+
+	bp := buffers.Get().(*[]byte)
+	defer func() {
+		if cap(*bp) <= maxPooled {
+			buffers.Put(bp)
+		}
+	}()
+
+	body, err := enc.Append((*bp)[:0], v)
+	*bp = body
+	if err != nil {
+		return err
+	}
+
+	writeBytes(w, code, contentType, body)
+	return nil
+}
+
+// Formatter is implemented by response writers that encode bodies in the
+// format the client negotiated, such as XML. ResponseFormat returns its
+// encoder and content type, or a nil encoder for JSON.
+type Formatter interface {
+	ResponseFormat() (Encoder, string)
+}
+
+// Warner is implemented by response writers whose bodies must carry
+// warnings, like those of deprecated routes
+type Warner interface {
+	ResponseWarnings() []string
+}
+
+// Warnable is implemented by bodies that carry warnings, like the response
+// envelopes of models
+type Warnable interface {
+	AddWarnings(warnings ...string)
+}
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// ProblemNegotiator is implemented by response writers that choose between
+// the error envelope and problem details for the client. ProblemDetails
+// reports whether the client asked for problem details, and the base their
+// type URIs are resolved against and the URI of the failed request.
+type ProblemNegotiator interface {
+	ProblemDetails() (typeBase, instance string, ok bool)
+}
+
+// Problemer is implemented by error bodies that can be written as problem
+// details instead, like the error envelope of models
+type Problemer interface {
+	Problem(typeBase, instance string) interface{}
+}
+
+// responseWarnings returns the warnings of the first Warner among w and the
+// writers it wraps
+func responseWarnings(w http.ResponseWriter) []string {
+	for ; w != nil; w = unwrap(w) {
+		if warner, ok := w.(Warner); ok {
+			return warner.ResponseWarnings()
+		}
+	}
+	return nil
+}
+
+// responseFormatter returns the first Formatter among w and the writers it
+// wraps, nil if there's none
+func responseFormatter(w http.ResponseWriter) Formatter {
+	for ; w != nil; w = unwrap(w) {
+		if formatter, ok := w.(Formatter); ok {
+			return formatter
+		}
+	}
+	return nil
+}
+
+// problemNegotiator returns the first ProblemNegotiator among w and the
+// writers it wraps, nil if there's none
+func problemNegotiator(w http.ResponseWriter) ProblemNegotiator {
+	for ; w != nil; w = unwrap(w) {
+		if negotiator, ok := w.(ProblemNegotiator); ok {
+			return negotiator
+		}
+	}
+	return nil
+}
+
+// unwrap returns the writer w wraps, nil if it wraps none
+func unwrap(w http.ResponseWriter) http.ResponseWriter {
+	if unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter }); ok {
+		return unwrapper.Unwrap()
+	}
+	return nil
+}
+
+// Timestamped is a response encoded once except for one timestamp, for bodies
+// that otherwise never change, such as the health check
+type Timestamped struct {
+	prefix, suffix []byte
+}
+
+// sentinel stands in for the timestamp while a Timestamped is encoded
+var sentinel = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// NewTimestamped encodes build(t) with a placeholder t and records where the
+// timestamp goes. The placeholder must appear exactly once in the encoding.
+func NewTimestamped(build func(t time.Time) interface{}) (*Timestamped, error) {
+	body, err := Current().Append(nil, build(sentinel))
+	if err != nil {
+		return nil, err
+	}
+
+	mark := sentinel.AppendFormat([]byte{'"'}, time.RFC3339Nano)
+	i := bytes.Index(body, mark)
+	if i < 0 || bytes.Count(body, mark) != 1 {
+		return nil, fmt.Errorf("timestamp must appear once in the response, found %d times", bytes.Count(body, mark))
+	}
+	return &Timestamped{prefix: body[:i+1], suffix: body[i+len(mark):]}, nil
+}
+
+// MustTimestamped is like NewTimestamped but panics on error, for responses
+// built at package initialisation
+func MustTimestamped(build func(t time.Time) interface{}) *Timestamped {
+	t, err := NewTimestamped(build)
+	if err != nil {
+		panic("jsonenc: " + err.Error())
+	}
+	return t
+}
+
+// Write writes the response with status code and timestamp now, in UTC as
+// encoding/json would write it. Clients that negotiated another format get
+// it re-encoded in that one.
+func (t *Timestamped) Write(w http.ResponseWriter, code int, now time.Time) {
+	bp := buffers.Get().(*[]byte)
+	defer buffers.Put(bp)
+
+	body := append((*bp)[:0], t.prefix...)
+	body = now.UTC().AppendFormat(body, time.RFC3339Nano)
+	body = append(body, t.suffix...)
+	*bp = body
+
+	if formatter := responseFormatter(w); formatter != nil {
+		if format, _ := formatter.ResponseFormat(); format != nil {
+			Write(w, code, json.RawMessage(body))
+			return
+		}
+	}
+	WriteBytes(w, code, body)
+}
+
+// WriteBytes writes body, already encoded JSON, as the body of a response
+// with status code
+func WriteBytes(w http.ResponseWriter, code int, body []byte) {
+	writeBytes(w, code, "application/json", body)
+}
+
+func writeBytes(w http.ResponseWriter, code int, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(code)
+	w.Write(body)
+}
@@ -0,0 +1,179 @@
+package jsonenc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func productPage(n int) *models.PaginatedResponse {
+	products := make([]models.Product, n)
+	for i := range products {
+		products[i] = models.Product{
+			ID:          i + 1,
+			SKU:         fmt.Sprintf("SKU-%06d", i),
+			Name:        fmt.Sprintf("Product <%d> & more", i),
+			Description: "Ships in 2–3 days",
+			Quantity:    i % 100,
+			UnitPrice:   float64(i%1000) / 100,
+			CreatedAt:   time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC),
+			UpdatedAt:   time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+		}
+	}
+	return &models.PaginatedResponse{
+		BaseResponse: models.BaseResponse{Status: "success", Code: 200, Message: "Products retrieved", Timestamp: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)},
+		Data:         products,
+		Pagination:   &models.PaginationMeta{Limit: n, Offset: 0, Total: 10 * n},
+	}
+}
+
+func TestSegmentMatchesStd(t *testing.T) {
+	values := map[string]interface{}{
+		"product page":   productPage(3),
+		"error response": models.NewErrorResponse(http.StatusBadRequest, `invalid "sku"`),
+		"map":            map[string]interface{}{"b": 1, "a": []string{"x", "<y>"}, "c": nil, "d": 0.1},
+		"raw message":    struct{ Details json.RawMessage }{json.RawMessage(`{"n": 1}`)},
+		"nil slice":      struct{ Items []int }{},
+		"floats":         []float64{0, 1e21, 1e-7, 12.3, -0.5},
+	}
+
+	for name, v := range values {
+		t.Run(name, func(t *testing.T) {
+			want, err := Std{}.Append(nil, v)
+			if err != nil {
+				t.Fatalf("std: %v", err)
+			}
+			got, err := Segment{}.Append(nil, v)
+			if err != nil {
+				t.Fatalf("segmentio: %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("segmentio encoded\n%s\nstd encoded\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := Write(w, http.StatusCreated, map[string]int{"id": 7}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if body := w.Body.String(); body != "{\"id\":7}\n" {
+		t.Errorf("body = %q", body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != strconv.Itoa(w.Body.Len()) {
+		t.Errorf("Content-Length = %q, body is %d bytes", cl, w.Body.Len())
+	}
+}
+
+func TestWrite_EncodingError(t *testing.T) {
+	for _, enc := range []Encoder{Std{}, Segment{}} {
+		t.Run(enc.Name(), func(t *testing.T) {
+			Use(enc)
+			defer Use(Segment{})
+
+			w := httptest.NewRecorder()
+			if err := Write(w, http.StatusOK, map[string]interface{}{"c": make(chan int)}); err == nil {
+				t.Fatal("expected an error encoding a channel")
+			}
+			if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+				t.Errorf("a failed encoding wrote a response: %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTimestamped(t *testing.T) {
+	build := func(ts time.Time) interface{} {
+		response := models.NewSuccessResponse(http.StatusOK, "Service is healthy", map[string]string{"version": "1.0.0"})
+		response.Timestamp = ts
+		return response
+	}
+	health := MustTimestamped(build)
+
+	now := time.Date(2024, 6, 1, 15, 4, 5, 600, time.FixedZone("CEST", 2*60*60))
+	w := httptest.NewRecorder()
+	health.Write(w, http.StatusOK, now)
+
+	want, _ := Std{}.Append(nil, build(now.UTC()))
+	if w.Body.String() != string(want) {
+		t.Errorf("body = %s, want %s", w.Body.String(), want)
+	}
+
+	if _, err := NewTimestamped(func(time.Time) interface{} { return map[string]int{} }); err == nil {
+		t.Error("expected an error for a response without the timestamp")
+	}
+}
+
+// discard is a ResponseWriter that throws the body away, so benchmarks
+// measure encoding rather than the recorder
+type discard struct{ header http.Header }
+
+func (d *discard) Header() http.Header         { return d.header }
+func (d *discard) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discard) WriteHeader(int)             {}
+
+func benchmarkWrite(b *testing.B, v interface{}, write func(w http.ResponseWriter)) {
+	b.Helper()
+	w := &discard{header: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		write(w)
+	}
+}
+
+// BenchmarkResponse compares encoding a page of products as the handlers did
+// before (a json.Encoder per response) with pooled buffers and each encoder
+func BenchmarkResponse(b *testing.B) {
+	for _, n := range []int{1, 50} {
+		page := productPage(n)
+		b.Run(fmt.Sprintf("products=%d/encoder-per-response", n), func(b *testing.B) {
+			benchmarkWrite(b, page, func(w http.ResponseWriter) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(page)
+			})
+		})
+		for _, enc := range []Encoder{Std{}, Segment{}} {
+			b.Run(fmt.Sprintf("products=%d/pooled-%s", n, enc.Name()), func(b *testing.B) {
+				Use(enc)
+				defer Use(Segment{})
+				benchmarkWrite(b, page, func(w http.ResponseWriter) { Write(w, http.StatusOK, page) })
+			})
+		}
+	}
+}
+
+func BenchmarkHealth(b *testing.B) {
+	build := func(ts time.Time) interface{} {
+		response := models.NewSuccessResponse(http.StatusOK, "Service is healthy", map[string]interface{}{"service": "api", "version": "1.0.0"})
+		response.Timestamp = ts
+		return response
+	}
+
+	b.Run("encoded-per-request", func(b *testing.B) {
+		benchmarkWrite(b, nil, func(w http.ResponseWriter) { Write(w, http.StatusOK, build(time.Now())) })
+	})
+	b.Run("pre-encoded", func(b *testing.B) {
+		health := MustTimestamped(build)
+		benchmarkWrite(b, nil, func(w http.ResponseWriter) { health.Write(w, http.StatusOK, time.Now()) })
+	})
+}
+
+var _ io.Writer = (*discard)(nil)
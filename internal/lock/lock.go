@@ -0,0 +1,290 @@
+// Package lock gives "only one instance runs this" semantics to jobs,
+// imports, and syncs, with Postgres session advisory locks. A lock is held
+// on a connection of its own, taken from the pool for as long as the lock is
+// held: if the connection drops, Postgres releases the lock, and the holder
+// learns of it from Lost within the check interval.
+//
+// SQLite deployments are a single process; there locks are held in memory.
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+)
+
+var (
+	acquisitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lock_acquisitions_total",
+		Help: "Advisory lock acquisitions, by lock and result (acquired, busy, error).",
+	}, []string{"lock", "result"})
+	waitDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "lock_wait_seconds",
+		Help:    "Time waited for an advisory lock by Acquire.",
+		Buckets: []float64{.001, .01, .1, 1, 5, 30, 120, 600},
+	}, []string{"lock"})
+	held = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lock_held",
+		Help: "Whether this instance holds the advisory lock.",
+	}, []string{"lock"})
+	lost = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lock_lost_total",
+		Help: "Advisory locks lost with their connection while held.",
+	}, []string{"lock"})
+)
+
+// ErrLocked is returned by TryAcquire when the lock is held elsewhere
+var ErrLocked = errors.New("lock is held elsewhere")
+
+// checkInterval is how often a held lock's connection is pinged
+const checkInterval = 5 * time.Second
+
+// Locker takes advisory locks by name
+type Locker struct {
+	db            *database.DB
+	checkInterval time.Duration
+
+	mu    sync.Mutex
+	local map[string]chan struct{} // SQLite: a full channel per held lock
+}
+
+func New(db *database.DB) *Locker {
+	return &Locker{db: db, checkInterval: checkInterval, local: make(map[string]chan struct{})}
+}
+
+// Acquire waits for the lock called name until ctx is done
+func (l *Locker) Acquire(ctx context.Context, name string) (*Lock, error) {
+	start := time.Now()
+	lk, err := l.acquire(ctx, name, true)
+	if err != nil {
+		acquisitions.WithLabelValues(name, "error").Inc()
+		return nil, err
+	}
+	waitDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	acquisitions.WithLabelValues(name, "acquired").Inc()
+	return lk, nil
+}
+
+// TryAcquire takes the lock called name if it's free, and fails with
+// ErrLocked otherwise
+func (l *Locker) TryAcquire(ctx context.Context, name string) (*Lock, error) {
+	lk, err := l.acquire(ctx, name, false)
+	switch {
+	case errors.Is(err, ErrLocked):
+		acquisitions.WithLabelValues(name, "busy").Inc()
+	case err != nil:
+		acquisitions.WithLabelValues(name, "error").Inc()
+	default:
+		acquisitions.WithLabelValues(name, "acquired").Inc()
+	}
+	return lk, err
+}
+
+// Exclusive wraps run, a scheduler job, so that one instance at a time runs
+// it: a run starts only if it gets the lock called name, and is otherwise
+// skipped without error. A run that loses the lock has its context
+// cancelled.
+func (l *Locker) Exclusive(name string, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		lk, err := l.TryAcquire(ctx, name)
+		if errors.Is(err, ErrLocked) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer lk.Release(context.WithoutCancel(ctx))
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-lk.Lost():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		return run(ctx)
+	}
+}
+
+func (l *Locker) acquire(ctx context.Context, name string, wait bool) (*Lock, error) {
+	if l.db.Dialect() == database.SQLite {
+		return l.acquireLocal(ctx, name, wait)
+	}
+
+	conn, err := l.db.DB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection for lock %s: %w", name, err)
+	}
+
+	key := Key(name)
+	ok := true
+	if wait {
+		_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, key)
+	} else {
+		err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok)
+	}
+	if err != nil {
+		// A cancelled wait may still have taken the lock; closing the
+		// session is the sure way to give it up
+		discard(conn)
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if !ok {
+		conn.Close()
+		return nil, ErrLocked
+	}
+
+	lk := newLock(name, conn)
+	lk.key = key
+	go lk.monitor(l.checkInterval)
+	return lk, nil
+}
+
+func (l *Locker) acquireLocal(ctx context.Context, name string, wait bool) (*Lock, error) {
+	l.mu.Lock()
+	sem, ok := l.local[name]
+	if !ok {
+		sem = make(chan struct{}, 1)
+		l.local[name] = sem
+	}
+	l.mu.Unlock()
+
+	if wait {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to acquire lock %s: %w", name, ctx.Err())
+		}
+	} else {
+		select {
+		case sem <- struct{}{}:
+		default:
+			return nil, ErrLocked
+		}
+	}
+
+	lk := newLock(name, nil)
+	lk.local = sem
+	return lk, nil
+}
+
+// Key returns the advisory lock key of name, for pg_locks queries: its
+// 64-bit FNV-1a hash
+func Key(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// Lock is a held advisory lock
+type Lock struct {
+	name  string
+	key   int64
+	conn  *sql.Conn     // Postgres: the session holding the lock
+	local chan struct{} // SQLite: the semaphore holding it
+
+	mu       sync.Mutex
+	released bool
+	lost     chan struct{}
+	stop     chan struct{}
+}
+
+func newLock(name string, conn *sql.Conn) *Lock {
+	held.WithLabelValues(name).Set(1)
+	return &Lock{name: name, conn: conn, lost: make(chan struct{}), stop: make(chan struct{})}
+}
+
+// Name returns the name the lock was acquired with
+func (lk *Lock) Name() string {
+	return lk.name
+}
+
+// Lost is closed once the lock is no longer held: after Release, or when
+// its connection was lost and with it the lock
+func (lk *Lock) Lost() <-chan struct{} {
+	return lk.lost
+}
+
+// Release gives the lock up. Releasing a lock twice, or one that was lost,
+// does nothing.
+func (lk *Lock) Release(ctx context.Context) error {
+	lk.mu.Lock()
+	defer lk.mu.Unlock()
+	if lk.released {
+		return nil
+	}
+	lk.end()
+	close(lk.stop)
+
+	if lk.local != nil {
+		<-lk.local
+		return nil
+	}
+
+	var unlocked bool
+	err := lk.conn.QueryRowContext(ctx, `SELECT pg_advisory_unlock($1)`, lk.key).Scan(&unlocked)
+	if err != nil || !unlocked {
+		discard(lk.conn)
+		if err != nil {
+			return fmt.Errorf("failed to release lock %s: %w", lk.name, err)
+		}
+		return fmt.Errorf("failed to release lock %s: not held by its session", lk.name)
+	}
+	return lk.conn.Close()
+}
+
+// monitor pings the lock's connection until the lock is released, and
+// marks the lock lost on the first failure
+func (lk *Lock) monitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lk.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := lk.conn.PingContext(ctx)
+		cancel()
+		if err == nil {
+			continue
+		}
+
+		lk.mu.Lock()
+		if !lk.released {
+			lk.end()
+			lost.WithLabelValues(lk.name).Inc()
+			discard(lk.conn)
+		}
+		lk.mu.Unlock()
+		return
+	}
+}
+
+// end marks the lock no longer held; lk.mu must be held
+func (lk *Lock) end() {
+	lk.released = true
+	close(lk.lost)
+	held.WithLabelValues(lk.name).Set(0)
+}
+
+// discard closes conn's session instead of returning it to the pool, which
+// releases every lock it holds
+func discard(conn *sql.Conn) {
+	conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	conn.Close()
+}
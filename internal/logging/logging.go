@@ -0,0 +1,228 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Components that can be given their own log level. Loggers for other
+// components inherit the base level.
+const (
+	ComponentHTTP       = "http"
+	ComponentRepository = "repository"
+	ComponentJobs       = "jobs"
+)
+
+var components = []string{ComponentHTTP, ComponentRepository, ComponentJobs}
+
+type Options struct {
+	Level           string            // Base level: debug, info, warn, error
+	Format          string            // "json" or "text"
+	ComponentLevels map[string]string // Per-component overrides of the base level
+	AddSource       bool
+}
+
+// Levels holds the base and per-component log levels. All loggers created
+// from it observe level changes immediately.
+type Levels struct {
+	handler slog.Handler // Root handler, filtering is done by levelHandler
+	format  string
+
+	base slog.LevelVar
+
+	mu        sync.RWMutex
+	overrides map[string]slog.Level
+}
+
+// New creates the root logger and the level controller backing it
+func New(w io.Writer, opts Options) (*slog.Logger, *Levels, error) {
+	base, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		Level:     slog.Level(-8), // Let everything through, levelHandler decides
+		AddSource: opts.AddSource,
+	}
+
+	format := opts.Format
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	case "text", "":
+		format = "text"
+		handler = slog.NewTextHandler(w, handlerOpts)
+	default:
+		return nil, nil, fmt.Errorf("invalid log format %q: must be json or text", opts.Format)
+	}
+
+	levels := &Levels{
+		handler:   handler,
+		format:    format,
+		overrides: make(map[string]slog.Level),
+	}
+	levels.base.Set(base)
+
+	if err := levels.SetComponents(opts.ComponentLevels); err != nil {
+		return nil, nil, err
+	}
+
+	return slog.New(&levelHandler{Handler: handler, level: &levels.base}), levels, nil
+}
+
+// Component returns a logger tagged with the component name whose level
+// follows the component's override, or the base level if none is set
+func (l *Levels) Component(name string) *slog.Logger {
+	handler := &levelHandler{
+		Handler: l.handler,
+		level:   componentLevel{levels: l, name: name},
+	}
+	return slog.New(handler).With("component", name)
+}
+
+func (l *Levels) Format() string {
+	return l.format
+}
+
+func (l *Levels) Base() slog.Level {
+	return l.base.Level()
+}
+
+func (l *Levels) SetBase(level slog.Level) {
+	l.base.Set(level)
+}
+
+// SetComponent overrides the level of one component, or resets it to the
+// base level when level is nil
+func (l *Levels) SetComponent(name string, level *slog.Level) error {
+	if !IsComponent(name) {
+		return fmt.Errorf("unknown log component %q: must be one of %s", name, strings.Join(components, ", "))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if level == nil {
+		delete(l.overrides, name)
+	} else {
+		l.overrides[name] = *level
+	}
+	return nil
+}
+
+// SetComponents replaces all component overrides. An empty level resets the
+// component to the base level.
+func (l *Levels) SetComponents(levels map[string]string) error {
+	overrides := make(map[string]slog.Level)
+	for name, value := range levels {
+		if !IsComponent(name) {
+			return fmt.Errorf("unknown log component %q: must be one of %s", name, strings.Join(components, ", "))
+		}
+		if value == "" {
+			continue
+		}
+		level, err := ParseLevel(value)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", name, err)
+		}
+		overrides[name] = level
+	}
+
+	l.mu.Lock()
+	l.overrides = overrides
+	l.mu.Unlock()
+	return nil
+}
+
+// Effective returns the level currently applied to a component
+func (l *Levels) Effective(name string) slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if level, ok := l.overrides[name]; ok {
+		return level
+	}
+	return l.base.Level()
+}
+
+// Overrides returns the components with an explicit level
+func (l *Levels) Overrides() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	overrides := make(map[string]string, len(l.overrides))
+	for name, level := range l.overrides {
+		overrides[name] = LevelName(level)
+	}
+	return overrides
+}
+
+// Components lists the components that accept a level override
+func Components() []string {
+	names := append([]string(nil), components...)
+	sort.Strings(names)
+	return names
+}
+
+func IsComponent(name string) bool {
+	for _, c := range components {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func ParseLevel(level string) (slog.Level, error) {
+	switch level {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q: must be debug, info, warn, or error", level)
+	}
+}
+
+func LevelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+type componentLevel struct {
+	levels *Levels
+	name   string
+}
+
+func (c componentLevel) Level() slog.Level {
+	return c.levels.Effective(c.name)
+}
+
+// levelHandler filters records against a dynamic level before handing them
+// to the wrapped handler
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
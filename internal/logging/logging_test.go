@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLevels_ComponentOverrides(t *testing.T) {
+	var buf bytes.Buffer
+	logger, levels, err := New(&buf, Options{
+		Level:           "warn",
+		Format:          "json",
+		ComponentLevels: map[string]string{ComponentHTTP: "debug"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	httpLogger := levels.Component(ComponentHTTP)
+	repoLogger := levels.Component(ComponentRepository)
+
+	logger.Info("base info")
+	httpLogger.Debug("http debug")
+	repoLogger.Info("repository info")
+
+	out := buf.String()
+	if strings.Contains(out, "base info") {
+		t.Error("expected base info to be filtered at warn level")
+	}
+	if !strings.Contains(out, "http debug") || !strings.Contains(out, `"component":"http"`) {
+		t.Errorf("expected tagged http debug record, got %q", out)
+	}
+	if strings.Contains(out, "repository info") {
+		t.Error("expected repository to inherit the warn base level")
+	}
+
+	// Runtime changes apply to loggers that already exist
+	buf.Reset()
+	levels.SetBase(slog.LevelInfo)
+	if err := levels.SetComponent(ComponentHTTP, nil); err != nil {
+		t.Fatalf("SetComponent() error = %v", err)
+	}
+
+	httpLogger.Debug("http debug after reset")
+	repoLogger.Info("repository info after change")
+
+	out = buf.String()
+	if strings.Contains(out, "http debug after reset") {
+		t.Error("expected http to fall back to the info base level")
+	}
+	if !strings.Contains(out, "repository info after change") {
+		t.Error("expected repository to follow the new base level")
+	}
+}
+
+func TestLevels_Validation(t *testing.T) {
+	if _, _, err := New(&bytes.Buffer{}, Options{Level: "info", Format: "xml"}); err == nil {
+		t.Error("expected error for invalid format")
+	}
+	if _, _, err := New(&bytes.Buffer{}, Options{Level: "trace"}); err == nil {
+		t.Error("expected error for invalid level")
+	}
+
+	_, levels, err := New(&bytes.Buffer{}, Options{Level: "info"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := levels.SetComponents(map[string]string{"cache": "debug"}); err == nil {
+		t.Error("expected error for unknown component")
+	}
+}
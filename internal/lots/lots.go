@@ -0,0 +1,289 @@
+// Package lots tracks the batches stock of perishable products comes in,
+// with their expiry, and consumes them first-expired-first-out (FEFO).
+//
+// A product's quantity stays the total of its stock: receiving a lot adds to
+// it and consuming takes from it, and lots say which units those are. Units
+// no lot accounts for, such as stock on hand before lots were tracked, are
+// untracked and consumed after the lots. Lots already past their expiry are
+// the first consumed, so expired stock should be written off from its lot
+// as soon as it expires.
+package lots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// alertBatchSize is the number of lots alerted per transaction
+const alertBatchSize = 500
+
+var (
+	ErrInvalidLot = errors.New("invalid lot")
+
+	// ErrExpiryMismatch is returned for a lot number received again with
+	// another expiry
+	ErrExpiryMismatch = errors.New("lot was received with another expiry")
+)
+
+var expiryAlerts = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "inventory_lot_expiry_alerts_total",
+	Help: "Lots with stock left that came within the expiry warning of their expiry.",
+})
+
+// Service receives and consumes lots and alerts on their expiry
+type Service struct {
+	repo      repository.LotRepository
+	products  repository.ProductRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	warning   time.Duration
+	logger    *slog.Logger
+}
+
+// NewService creates the lot service. A lot.expiring event is published for
+// every lot with stock left once it's within warning of its expiry.
+func NewService(repo repository.LotRepository, products repository.ProductRepository, tx repository.Transactor, publisher events.Publisher, warning time.Duration, logger *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		products:  products,
+		tx:        tx,
+		publisher: publisher,
+		warning:   warning,
+		logger:    logger,
+	}
+}
+
+// Warning returns how long before their expiry lots are alerted on
+func (s *Service) Warning() time.Duration {
+	return s.warning
+}
+
+// Receive adds a lot's units to its product's stock, in one transaction,
+// publishing StockAdjusted with reason "receipt". A lot number the product
+// already has takes the units into that lot, if its expiry is the same. It
+// returns the lot and the product as updated.
+func (s *Service) Receive(ctx context.Context, lot *models.Lot) (*models.Lot, *models.Product, error) {
+	lot.LotNumber = strings.TrimSpace(lot.LotNumber)
+	if lot.LotNumber == "" {
+		return nil, nil, fmt.Errorf("%w: lot_number is required", ErrInvalidLot)
+	}
+	if len(lot.LotNumber) > 100 {
+		return nil, nil, fmt.Errorf("%w: lot_number must be at most 100 characters", ErrInvalidLot)
+	}
+	if lot.Quantity <= 0 {
+		return nil, nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidLot)
+	}
+
+	var received *models.Lot
+	var product *models.Product
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if product, err = s.products.AdjustStock(ctx, lot.ProductID, lot.Quantity); err != nil {
+			return err
+		}
+
+		existing, err := s.repo.GetByNumber(ctx, lot.ProductID, lot.LotNumber)
+		switch {
+		case err != nil && err.Error() == "lot not found":
+			if err := s.repo.Create(ctx, lot); err != nil {
+				return err
+			}
+			received = lot
+		case err != nil:
+			return err
+		case !sameExpiry(existing.ExpiresAt, lot.ExpiresAt):
+			return fmt.Errorf("%w: %s expires %s", ErrExpiryMismatch, existing.LotNumber, describeExpiry(existing.ExpiresAt))
+		default:
+			if received, err = s.repo.AdjustQuantity(ctx, existing.ID, lot.Quantity); err != nil {
+				return err
+			}
+		}
+
+		return s.publisher.Publish(ctx, events.New(events.StockAdjusted{
+			ProductID: product.ID,
+			SKU:       product.SKU,
+			Previous:  product.Quantity - lot.Quantity,
+			Current:   product.Quantity,
+			Delta:     lot.Quantity,
+			Reason:    "receipt",
+		}))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return received, product, nil
+}
+
+// Consume takes quantity units of a product's stock, in one transaction,
+// publishing StockAdjusted with reason "consumption". With a lot number the
+// units come from that lot, failing with repository.ErrInsufficientStock if
+// it has fewer left; otherwise they're taken FEFO, as by Take. It returns
+// what was taken from each lot and the product as updated.
+func (s *Service) Consume(ctx context.Context, productID, quantity int, lotNumber string) ([]models.LotConsumption, *models.Product, error) {
+	if quantity <= 0 {
+		return nil, nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidLot)
+	}
+
+	var taken []models.LotConsumption
+	var product *models.Product
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if product, err = s.products.AdjustStock(ctx, productID, -quantity); err != nil {
+			return err
+		}
+
+		if lotNumber == "" {
+			if taken, err = s.Take(ctx, productID, quantity); err != nil {
+				return err
+			}
+		} else {
+			lot, err := s.repo.GetByNumber(ctx, productID, strings.TrimSpace(lotNumber))
+			if err != nil {
+				return err
+			}
+			after, err := s.repo.AdjustQuantity(ctx, lot.ID, -quantity)
+			if err != nil {
+				if errors.Is(err, repository.ErrInsufficientStock) {
+					return fmt.Errorf("%w in lot %s: %d requested, %d left", repository.ErrInsufficientStock, lot.LotNumber, quantity, lot.Quantity)
+				}
+				return err
+			}
+			taken = []models.LotConsumption{consumption(after, quantity)}
+		}
+
+		return s.publisher.Publish(ctx, events.New(events.StockAdjusted{
+			ProductID: product.ID,
+			SKU:       product.SKU,
+			Previous:  product.Quantity + quantity,
+			Current:   product.Quantity,
+			Delta:     -quantity,
+			Reason:    "consumption",
+		}))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return taken, product, nil
+}
+
+// Take takes up to quantity units from a product's lots, first expired
+// first, in the caller's transaction. It updates only the lots: the caller
+// takes the units from the product's stock. Units beyond what the lots have
+// left are untracked stock and taken from no lot.
+func (s *Service) Take(ctx context.Context, productID, quantity int) ([]models.LotConsumption, error) {
+	lots, err := s.repo.ForConsumption(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var taken []models.LotConsumption
+	for _, lot := range lots {
+		if quantity == 0 {
+			break
+		}
+		n := min(quantity, lot.Quantity)
+		after, err := s.repo.AdjustQuantity(ctx, lot.ID, -n)
+		if err != nil {
+			return nil, err
+		}
+		taken = append(taken, consumption(after, n))
+		quantity -= n
+	}
+	return taken, nil
+}
+
+func consumption(lot *models.Lot, quantity int) models.LotConsumption {
+	return models.LotConsumption{
+		LotID:     lot.ID,
+		LotNumber: lot.LotNumber,
+		Quantity:  quantity,
+		Remaining: lot.Quantity,
+		ExpiresAt: lot.ExpiresAt,
+	}
+}
+
+// Run publishes LotExpiring for every lot with stock left that's within
+// the expiry warning of its expiry and hasn't been alerted on, marking it
+// alerted in the same transaction, for use as a scheduled job
+func (s *Service) Run(ctx context.Context) error {
+	alerted := 0
+	for {
+		now := time.Now()
+		var batch []*models.ExpiringLot
+		err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+			var err error
+			if batch, err = s.repo.PendingAlerts(ctx, now.Add(s.warning), alertBatchSize); err != nil {
+				return err
+			}
+
+			evts := make([]events.Event, len(batch))
+			for i, lot := range batch {
+				evts[i] = events.New(events.LotExpiring{
+					ProductID: lot.ProductID,
+					SKU:       lot.SKU,
+					LotID:     lot.ID,
+					LotNumber: lot.LotNumber,
+					Quantity:  lot.Quantity,
+					ExpiresAt: *lot.ExpiresAt,
+					Expired:   !lot.ExpiresAt.After(now),
+				})
+				if err := s.repo.MarkAlerted(ctx, lot.ID, now); err != nil {
+					return err
+				}
+			}
+			if err := s.publisher.Publish(ctx, evts...); err != nil {
+				return err
+			}
+
+			database.AfterCommit(ctx, func() {
+				for _, lot := range batch {
+					expiryAlerts.Inc()
+					s.logger.Warn("lot is expiring",
+						"product_id", lot.ProductID,
+						"sku", lot.SKU,
+						"lot_number", lot.LotNumber,
+						"quantity", lot.Quantity,
+						"expires_at", *lot.ExpiresAt,
+					)
+				}
+			})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		alerted += len(batch)
+		if len(batch) < alertBatchSize {
+			if alerted > 0 {
+				s.logger.Info("lot expiry alerts sent", "lots", alerted)
+			}
+			return nil
+		}
+	}
+}
+
+func sameExpiry(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+func describeExpiry(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
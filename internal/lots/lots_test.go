@@ -0,0 +1,132 @@
+package lots
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestService_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "lots.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	bus := events.NewBus(logger)
+	var expiring []events.LotExpiring
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		expiring = append(expiring, event.Payload.(events.LotExpiring))
+		return nil
+	}, events.TypeLotExpiring)
+
+	products := repository.NewProductRepository(db)
+	repo := repository.NewLotRepository(db)
+	service := NewService(repo, products, db, bus, 7*24*time.Hour, logger)
+
+	// 2 units on hand before lots were tracked
+	milk := &models.Product{SKU: "LOT-1", Name: "Milk", Quantity: 2, UnitPrice: 1}
+	if err := products.Create(ctx, milk); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	day := 24 * time.Hour
+	at := func(d time.Duration) *time.Time { t := time.Now().Add(d).UTC().Truncate(time.Second); return &t }
+	for _, lot := range []*models.Lot{
+		{LotNumber: "LATE", Quantity: 5, ExpiresAt: at(30 * day)},
+		{LotNumber: "NEVER", Quantity: 5},
+		{LotNumber: "SOON", Quantity: 3, ExpiresAt: at(2 * day)},
+		{LotNumber: "GONE", Quantity: 1, ExpiresAt: at(-day)},
+	} {
+		lot.ProductID = milk.ID
+		if _, _, err := service.Receive(ctx, lot); err != nil {
+			t.Fatalf("Receive(%s) error = %v", lot.LotNumber, err)
+		}
+	}
+
+	if _, _, err := service.Receive(ctx, &models.Lot{ProductID: milk.ID, LotNumber: "SOON", Quantity: 1, ExpiresAt: at(3 * day)}); !errors.Is(err, ErrExpiryMismatch) {
+		t.Errorf("Receive(SOON, other expiry) error = %v, want ErrExpiryMismatch", err)
+	}
+	if _, _, err := service.Receive(ctx, &models.Lot{ProductID: milk.ID, LotNumber: " ", Quantity: 1}); !errors.Is(err, ErrInvalidLot) {
+		t.Errorf("Receive(no lot number) error = %v, want ErrInvalidLot", err)
+	}
+	lot, product, err := service.Receive(ctx, &models.Lot{ProductID: milk.ID, LotNumber: "NEVER", Quantity: 1})
+	if err != nil {
+		t.Fatalf("Receive(NEVER again) error = %v", err)
+	}
+	if lot.Quantity != 6 || product.Quantity != 17 {
+		t.Errorf("Receive(NEVER again) = lot of %d, product of %d; want 6, 17", lot.Quantity, product.Quantity)
+	}
+
+	// First expired first: all of GONE and SOON, then 2 of LATE
+	taken, product, err := service.Consume(ctx, milk.ID, 6, "")
+	if err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+	if len(taken) != 3 || taken[0].LotNumber != "GONE" || taken[1].LotNumber != "SOON" || taken[2].LotNumber != "LATE" || taken[2].Quantity != 2 || taken[2].Remaining != 3 {
+		t.Errorf("Consume() took %+v", taken)
+	}
+	if product.Quantity != 11 {
+		t.Errorf("product quantity = %d, want 11", product.Quantity)
+	}
+
+	if _, _, err := service.Consume(ctx, milk.ID, 4, "LATE"); !errors.Is(err, repository.ErrInsufficientStock) {
+		t.Errorf("Consume(LATE, 4) error = %v, want ErrInsufficientStock", err)
+	}
+	if p, _ := products.GetByID(ctx, milk.ID); p.Quantity != 11 {
+		t.Errorf("product quantity = %d after failed consume, want 11", p.Quantity)
+	}
+
+	// 9 in lots, so the last 2 are untracked
+	taken, product, err = service.Consume(ctx, milk.ID, 11, "")
+	if err != nil {
+		t.Fatalf("Consume(all) error = %v", err)
+	}
+	if len(taken) != 2 || taken[0].Quantity+taken[1].Quantity != 9 || product.Quantity != 0 {
+		t.Errorf("Consume(all) took %+v, product of %d", taken, product.Quantity)
+	}
+	if n, _ := repo.CountByProduct(ctx, milk.ID, false); n != 0 {
+		t.Errorf("lots with stock left = %d, want 0", n)
+	}
+	if n, _ := repo.CountByProduct(ctx, milk.ID, true); n != 4 {
+		t.Errorf("lots = %d, want 4", n)
+	}
+
+	// Only lots with stock left are alerted on, each once
+	if _, _, err := service.Receive(ctx, &models.Lot{ProductID: milk.ID, LotNumber: "NEXT", Quantity: 4, ExpiresAt: at(5 * day)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := service.Receive(ctx, &models.Lot{ProductID: milk.ID, LotNumber: "LATER", Quantity: 4, ExpiresAt: at(20 * day)}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := service.Run(ctx); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+	if len(expiring) != 1 || expiring[0].LotNumber != "NEXT" || expiring[0].Quantity != 4 || expiring[0].Expired {
+		t.Errorf("alerts = %+v, want NEXT only", expiring)
+	}
+
+	soon, err := repo.Expiring(ctx, time.Now().Add(10*day), 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(soon) != 1 || soon[0].SKU != "LOT-1" || soon[0].AlertedAt == nil {
+		t.Errorf("Expiring() = %+v", soon)
+	}
+}
@@ -0,0 +1,233 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+)
+
+var (
+	integrityViolations = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "integrity_violations",
+		Help: "Rows violating an integrity check at its last run, by check. Alert on any above zero.",
+	}, []string{"check"})
+	integrityRepaired = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "integrity_repaired_rows_total",
+		Help: "Rows repaired by integrity checks, by check.",
+	}, []string{"check"})
+)
+
+// Repair modes of the integrity checker
+const (
+	RepairOff    = "off"     // Report violations only
+	RepairDryRun = "dry-run" // Also run the repairs, rolled back, to report what they'd change
+	RepairOn     = "on"      // Repair the violations that can be
+)
+
+// integritySamples is how many violating rows a report names
+const integritySamples = 10
+
+// IntegrityCheck finds rows that break an invariant the schema can't
+// enforce, or that constraints should have prevented but didn't, as after a
+// restore or a manual fix
+type IntegrityCheck struct {
+	Name        string // Identifies the check in metrics and results
+	Description string
+	Query       string // Selects one integer per violating row, e.g. its product ID
+	Repair      string // Statement fixing every violation, "" when only a person can
+}
+
+// IntegrityChecks are the checks of the catalog's tables, on PostgreSQL and
+// SQLite alike
+var IntegrityChecks = []IntegrityCheck{
+	{
+		Name:        "orphaned_product_tags",
+		Description: "tags of products that don't exist",
+		Query:       `SELECT product_id FROM product_tags t WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = t.product_id)`,
+		Repair:      `DELETE FROM product_tags WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = product_tags.product_id)`,
+	},
+	{
+		Name:        "orphaned_product_regions",
+		Description: "region assignments of products that don't exist",
+		Query:       `SELECT product_id FROM product_regions r WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = r.product_id)`,
+		Repair:      `DELETE FROM product_regions WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = product_regions.product_id)`,
+	},
+	{
+		Name:        "orphaned_product_slugs",
+		Description: "former slugs of products that don't exist",
+		Query:       `SELECT product_id FROM product_slugs s WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = s.product_id)`,
+		Repair:      `DELETE FROM product_slugs WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = product_slugs.product_id)`,
+	},
+	{
+		Name:        "orphaned_product_notes",
+		Description: "notes on products that don't exist",
+		Query:       `SELECT product_id FROM product_notes n WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = n.product_id)`,
+		Repair:      `DELETE FROM product_notes WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = product_notes.product_id)`,
+	},
+	{
+		Name:        "orphaned_bundle_components",
+		Description: "bundle components whose bundle or component doesn't exist",
+		Query: `SELECT bundle_id FROM bundle_components c
+			WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = c.bundle_id)
+			   OR NOT EXISTS (SELECT 1 FROM products p WHERE p.id = c.component_id)`,
+		Repair: `DELETE FROM bundle_components
+			WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = bundle_components.bundle_id)
+			   OR NOT EXISTS (SELECT 1 FROM products p WHERE p.id = bundle_components.component_id)`,
+	},
+	{
+		Name:        "orphaned_subscriptions",
+		Description: "subscriptions to products that don't exist",
+		Query:       `SELECT id FROM subscriptions s WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = s.product_id)`,
+		Repair:      `DELETE FROM subscriptions WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = subscriptions.product_id)`,
+	},
+	{
+		// Restoring a product removes it from the trash in the same
+		// transaction; a leftover would be restored over the live product
+		Name:        "trashed_live_products",
+		Description: "trashed products that exist again",
+		Query:       `SELECT id FROM trashed_products t WHERE EXISTS (SELECT 1 FROM products p WHERE p.id = t.id)`,
+		Repair:      `DELETE FROM trashed_products WHERE EXISTS (SELECT 1 FROM products p WHERE p.id = trashed_products.id)`,
+	},
+	{
+		// Which of the two is right depends on what went wrong, so this one
+		// is for a person to look into
+		Name:        "stock_quantity_mismatch",
+		Description: "products whose quantity differs from their latest stock movement",
+		Query: `SELECT p.id FROM products p
+			JOIN stock_movements m ON m.product_id = p.id
+			WHERE m.id = (SELECT MAX(id) FROM stock_movements WHERE product_id = p.id)
+			  AND m.quantity_after <> p.quantity`,
+	},
+	{
+		// Serials don't move stock, so a sale or write-off whose serial
+		// wasn't retired, or a receipt whose wasn't registered, shows here
+		Name:        "serial_quantity_mismatch",
+		Description: "serialized products whose quantity differs from their active serials",
+		Query: `SELECT p.id FROM products p
+			WHERE EXISTS (SELECT 1 FROM serial_numbers s WHERE s.product_id = p.id)
+			  AND p.quantity <> (SELECT COUNT(*) FROM serial_numbers s WHERE s.product_id = p.id AND s.status = 'active')`,
+	},
+}
+
+// IntegrityResult is the outcome of one check
+type IntegrityResult struct {
+	Check      string  `json:"check"`
+	Violations int64   `json:"violations"`
+	Samples    []int64 `json:"samples,omitempty"`  // Up to 10 of the violating rows
+	Repaired   int64   `json:"repaired,omitempty"` // Rows the repair changed, or would have in a dry run
+	DryRun     bool    `json:"dry_run,omitempty"`  // The repair was rolled back
+	Error      string  `json:"error,omitempty"`
+}
+
+// IntegrityChecker runs integrity checks and, as a repair mode says, repairs
+// what they find
+type IntegrityChecker struct {
+	db     *database.DB
+	checks []IntegrityCheck
+	repair string // Of scheduled runs
+	logger *slog.Logger
+
+	mu sync.Mutex // Serialises runs
+}
+
+func NewIntegrityChecker(db *database.DB, checks []IntegrityCheck, repair string, logger *slog.Logger) *IntegrityChecker {
+	return &IntegrityChecker{db: db, checks: checks, repair: repair, logger: logger}
+}
+
+// Run checks once with the configured repair mode, for use as a scheduled
+// job
+func (c *IntegrityChecker) Run(ctx context.Context) error {
+	_, err := c.Check(ctx, c.repair)
+	return err
+}
+
+// Check runs every check once, repairing as repair says; in a dry run (see
+// database.WithDryRun) repairs are always rolled back. A failing check
+// doesn't stop the others; all errors are returned together.
+func (c *IntegrityChecker) Check(ctx context.Context, repair string) ([]IntegrityResult, error) {
+	if repair == RepairOn && database.IsDryRun(ctx) {
+		repair = RepairDryRun
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := make([]IntegrityResult, 0, len(c.checks))
+	var errs []error
+	for _, check := range c.checks {
+		result, err := c.run(ctx, check, repair)
+		if err != nil {
+			result.Error = err.Error()
+			errs = append(errs, fmt.Errorf("integrity check %s failed: %w", check.Name, err))
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+func (c *IntegrityChecker) run(ctx context.Context, check IntegrityCheck, repair string) (IntegrityResult, error) {
+	result := IntegrityResult{Check: check.Name}
+
+	conn := c.db.Conn(ctx)
+	if err := conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM (`+check.Query+`) v`).Scan(&result.Violations); err != nil {
+		return result, err
+	}
+	integrityViolations.WithLabelValues(check.Name).Set(float64(result.Violations))
+	if result.Violations == 0 {
+		return result, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, check.Query+` LIMIT `+fmt.Sprint(integritySamples))
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return result, err
+		}
+		result.Samples = append(result.Samples, id)
+	}
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	attrs := []interface{}{"check", check.Name, "description", check.Description, "violations", result.Violations, "samples", result.Samples}
+	if check.Repair == "" || repair == RepairOff {
+		c.logger.Warn("integrity violations found", attrs...)
+		return result, nil
+	}
+
+	repairCtx := ctx
+	if repair == RepairDryRun {
+		repairCtx = database.WithDryRun(ctx)
+		result.DryRun = true
+	}
+	err = c.db.WithTx(repairCtx, func(ctx context.Context) error {
+		res, err := c.db.Conn(ctx).ExecContext(ctx, check.Repair)
+		if err != nil {
+			return err
+		}
+		result.Repaired, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to repair: %w", err)
+	}
+
+	if result.DryRun {
+		c.logger.Warn("integrity violations found, repair would change rows", append(attrs, "would_repair", result.Repaired)...)
+		return result, nil
+	}
+	integrityRepaired.WithLabelValues(check.Name).Add(float64(result.Repaired))
+	integrityViolations.WithLabelValues(check.Name).Set(float64(max(result.Violations-result.Repaired, 0)))
+	c.logger.Warn("integrity violations repaired", append(attrs, "repaired", result.Repaired)...)
+	return result, nil
+}
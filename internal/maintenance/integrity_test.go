@@ -0,0 +1,115 @@
+package maintenance
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/database"
+)
+
+func TestIntegrityChecker_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "integrity.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	// Orphans are what foreign keys rule out, so turn them off on the only
+	// connection to make some
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO products (id, sku, name, quantity) VALUES (1, 'LIVE', 'Live', 5)`,
+		`INSERT INTO product_tags (product_id, tag) VALUES (1, 'kept'), (2, 'orphan'), (3, 'orphan')`,
+		`INSERT INTO subscriptions (product_id, fields, callback_url) VALUES (1, '[]', 'https://example.com'), (4, '[]', 'https://example.com')`,
+		`INSERT INTO trashed_products (id, sku, name, product) VALUES (1, 'LIVE', 'Live', '{}')`,
+		`UPDATE stock_movements SET quantity_after = 7 WHERE product_id = 1`,
+		`INSERT INTO serial_numbers (product_id, serial, status) VALUES (1, 'SN-1', 'active'), (1, 'SN-2', 'active'), (1, 'SN-3', 'retired')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	ctx := context.Background()
+	checker := NewIntegrityChecker(db, IntegrityChecks, RepairOff, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	check := func(repair string) map[string]IntegrityResult {
+		t.Helper()
+		results, err := checker.Check(ctx, repair)
+		if err != nil {
+			t.Fatalf("Check(%s) error = %v", repair, err)
+		}
+		byCheck := make(map[string]IntegrityResult, len(results))
+		for _, result := range results {
+			byCheck[result.Check] = result
+		}
+		return byCheck
+	}
+	want := map[string]int64{
+		"orphaned_product_tags":      2,
+		"orphaned_product_regions":   0,
+		"orphaned_bundle_components": 0,
+		"orphaned_subscriptions":     1,
+		"trashed_live_products":      1,
+		"stock_quantity_mismatch":    1,
+		"serial_quantity_mismatch":   1,
+	}
+
+	results := check(RepairOff)
+	for name, violations := range want {
+		if got := results[name]; got.Violations != violations || got.Repaired != 0 {
+			t.Errorf("%s = %+v, want %d violations and no repairs", name, got, violations)
+		}
+	}
+	if got := results["orphaned_product_tags"].Samples; len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("orphaned_product_tags samples = %v, want [2 3]", got)
+	}
+
+	// A dry run reports the repairs without keeping them
+	results = check(RepairDryRun)
+	if got := results["orphaned_product_tags"]; got.Repaired != 2 || !got.DryRun {
+		t.Errorf("dry-run orphaned_product_tags = %+v, want 2 rows repaired and rolled back", got)
+	}
+	results = check(RepairOff)
+	if got := results["orphaned_product_tags"].Violations; got != 2 {
+		t.Errorf("orphaned_product_tags has %d violations after a dry run, want 2", got)
+	}
+
+	// And so does a repair in a dry-run context
+	if dryRun, err := checker.Check(database.WithDryRun(ctx), RepairOn); err != nil || len(dryRun) == 0 || !dryRun[0].DryRun {
+		t.Errorf("Check(dry run, on) = %+v, %v, want repairs rolled back", dryRun, err)
+	}
+
+	results = check(RepairOn)
+	for name, violations := range want {
+		repaired := violations
+		if name == "stock_quantity_mismatch" || name == "serial_quantity_mismatch" {
+			// Left to a person
+			repaired = 0
+		}
+		if got := results[name]; got.Repaired != repaired {
+			t.Errorf("%s repaired %d rows, want %d", name, got.Repaired, repaired)
+		}
+	}
+	results = check(RepairOff)
+	for name := range want {
+		violations := int64(0)
+		if name == "stock_quantity_mismatch" || name == "serial_quantity_mismatch" {
+			violations = 1
+		}
+		if got := results[name].Violations; got != violations {
+			t.Errorf("%s has %d violations after repair, want %d", name, got, violations)
+		}
+	}
+
+	var tags int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM product_tags`).Scan(&tags); err != nil || tags != 1 {
+		t.Errorf("%d tags remain (%v), want the live product's", tags, err)
+	}
+}
@@ -0,0 +1,95 @@
+package maintenance
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrReadOnly is returned when maintenance mode is switched off on an
+// instance started read-only
+var ErrReadOnly = errors.New("instance is read-only")
+
+// ModeStatus is a snapshot of the maintenance mode
+type ModeStatus struct {
+	Enabled    bool       `json:"enabled"`
+	ReadOnly   bool       `json:"read_only"` // Started read-only, e.g. against a replica; can't be switched off
+	Message    string     `json:"message,omitempty"`
+	RetryAfter int        `json:"retry_after_seconds"`
+	Since      *time.Time `json:"since,omitempty"`
+}
+
+// Mode tracks whether writes are accepted. While enabled, write endpoints
+// answer 503 with Retry-After and reads continue to be served.
+type Mode struct {
+	mu         sync.RWMutex
+	enabled    bool
+	readOnly   bool
+	message    string
+	retryAfter time.Duration
+	since      time.Time
+}
+
+// NewMode creates the mode, enabled from the start when enabled or readOnly
+// is set. retryAfter is advertised to clients whose writes are refused.
+func NewMode(enabled, readOnly bool, retryAfter time.Duration) *Mode {
+	m := &Mode{
+		enabled:    enabled || readOnly,
+		readOnly:   readOnly,
+		retryAfter: retryAfter,
+	}
+	if m.enabled {
+		m.since = time.Now()
+	}
+	if readOnly {
+		m.message = "This instance is read-only"
+	}
+	return m
+}
+
+// Enable refuses writes from now on. A zero retryAfter keeps the current one.
+func (m *Mode) Enable(message string, retryAfter time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.enabled {
+		m.since = time.Now()
+	}
+	m.enabled = true
+	m.message = message
+	if retryAfter > 0 {
+		m.retryAfter = retryAfter
+	}
+}
+
+// Disable accepts writes again, unless the instance was started read-only
+func (m *Mode) Disable() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	m.enabled = false
+	m.message = ""
+	m.since = time.Time{}
+	return nil
+}
+
+// Status returns the current state
+func (m *Mode) Status() ModeStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := ModeStatus{
+		Enabled:    m.enabled,
+		ReadOnly:   m.readOnly,
+		Message:    m.message,
+		RetryAfter: int(m.retryAfter.Seconds()),
+	}
+	if m.enabled {
+		since := m.since
+		status.Since = &since
+	}
+	return status
+}
@@ -0,0 +1,136 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+)
+
+var partitionChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "partition_changes_total",
+	Help: "Monthly partitions changed by maintenance, by table and action (created, detached, dropped).",
+}, []string{"table", "action"})
+
+// PartitionPolicy is a table partitioned by month on created_at and how long
+// its partitions are kept
+type PartitionPolicy struct {
+	Table     string
+	Retention int // Months kept besides the current one, 0 keeps everything
+}
+
+// Partitioner keeps monthly partitions in line with their policies: it creates
+// partitions ahead of time, so inserts rarely find their month missing, moves
+// rows that did out of the DEFAULT partition, and drops or detaches
+// partitions that have aged out of retention
+type Partitioner struct {
+	db       *database.DB
+	policies []PartitionPolicy
+	premake  int  // Months created ahead of the current one
+	detach   bool // Detach expired partitions for archiving instead of dropping them
+	logger   *slog.Logger
+}
+
+func NewPartitioner(db *database.DB, policies []PartitionPolicy, premake int, detach bool, logger *slog.Logger) *Partitioner {
+	return &Partitioner{
+		db:       db,
+		policies: policies,
+		premake:  premake,
+		detach:   detach,
+		logger:   logger,
+	}
+}
+
+// Run maintains every table once. A failing table doesn't stop the others;
+// all errors are returned together.
+func (p *Partitioner) Run(ctx context.Context) error {
+	var errs []error
+	for _, policy := range p.policies {
+		if err := p.maintain(ctx, policy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Partitioner) maintain(ctx context.Context, policy PartitionPolicy) error {
+	current := database.MonthStart(time.Now())
+
+	// Rows in the DEFAULT partition had no partition for their month when
+	// inserted; creating it moves them there
+	stray, err := p.db.DefaultPartitionMonths(ctx, policy.Table)
+	if err != nil {
+		return err
+	}
+	for _, month := range stray {
+		created, err := p.db.CreateMonthlyPartition(ctx, policy.Table, month)
+		if err != nil {
+			return err
+		}
+		if created {
+			partitionChanges.WithLabelValues(policy.Table, "created").Inc()
+			p.logger.Info("created partition for rows in the default partition", "table", policy.Table, "partition", database.PartitionName(policy.Table, month))
+		}
+	}
+
+	for i := 0; i <= p.premake; i++ {
+		month := current.AddDate(0, i, 0)
+		created, err := p.db.CreateMonthlyPartition(ctx, policy.Table, month)
+		if err != nil {
+			return err
+		}
+		if created {
+			partitionChanges.WithLabelValues(policy.Table, "created").Inc()
+			p.logger.Info("created partition", "table", policy.Table, "partition", database.PartitionName(policy.Table, month))
+		}
+	}
+
+	if policy.Retention <= 0 {
+		return nil
+	}
+
+	partitions, err := p.db.MonthlyPartitions(ctx, policy.Table)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range Expired(partitions, current, policy.Retention) {
+		if p.detach {
+			if err := p.db.DetachPartition(ctx, policy.Table, name); err != nil {
+				return err
+			}
+			partitionChanges.WithLabelValues(policy.Table, "detached").Inc()
+			p.logger.Info("detached expired partition", "table", policy.Table, "partition", name)
+			continue
+		}
+
+		if err := p.db.DropPartition(ctx, name); err != nil {
+			return err
+		}
+		partitionChanges.WithLabelValues(policy.Table, "dropped").Inc()
+		p.logger.Info("dropped expired partition", "table", policy.Table, "partition", name)
+	}
+
+	return nil
+}
+
+// Expired returns the partitions, oldest first, whose month lies entirely
+// before the retention window of retention months preceding current
+func Expired(partitions map[string]time.Time, current time.Time, retention int) []string {
+	cutoff := database.MonthStart(current).AddDate(0, -retention, 0)
+
+	var expired []string
+	for name, month := range partitions {
+		if month.Before(cutoff) {
+			expired = append(expired, name)
+		}
+	}
+	sort.Strings(expired)
+
+	return expired
+}
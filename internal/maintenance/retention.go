@@ -0,0 +1,119 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+)
+
+var purgedRows = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "retention_purged_rows_total",
+	Help: "Rows deleted by retention purges, by policy.",
+}, []string{"policy"})
+
+// RetentionPolicy deletes rows of a table once they are older than Retention
+type RetentionPolicy struct {
+	Name      string // Identifies the policy in metrics and results
+	Table     string
+	Key       string        // Column(s) identifying a row, e.g. "id" or "source, order_id"
+	Column    string        // Timestamp column compared with the cutoff
+	Where     string        // Optional extra condition rows must match to be purged
+	Retention time.Duration // 0 keeps rows forever
+}
+
+// Purger enforces retention policies with batched deletes. Every batch is its
+// own statement, so locks are held briefly and concurrent writes aren't
+// blocked for the length of a large purge.
+type Purger struct {
+	db        *database.DB
+	policies  []RetentionPolicy
+	batchSize int
+	logger    *slog.Logger
+
+	mu sync.Mutex // Serialises purges, scheduled or triggered by an admin
+}
+
+func NewPurger(db *database.DB, policies []RetentionPolicy, batchSize int, logger *slog.Logger) *Purger {
+	return &Purger{
+		db:        db,
+		policies:  policies,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Run purges once, for use as a scheduled job
+func (p *Purger) Run(ctx context.Context) error {
+	_, err := p.Purge(ctx)
+	return err
+}
+
+// Purge applies every policy once and returns the rows deleted per policy.
+// A failing policy doesn't stop the others; all errors are returned together.
+func (p *Purger) Purge(ctx context.Context) (map[string]int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	purged := make(map[string]int64, len(p.policies))
+	var errs []error
+	for _, policy := range p.policies {
+		if policy.Retention <= 0 {
+			continue
+		}
+
+		n, err := p.purge(ctx, policy)
+		purged[policy.Name] = n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge %s: %w", policy.Name, err))
+			continue
+		}
+		if n > 0 {
+			p.logger.Info("purged expired rows", "policy", policy.Name, "rows", n, "retention", policy.Retention.String())
+		}
+	}
+
+	return purged, errors.Join(errs...)
+}
+
+func (p *Purger) purge(ctx context.Context, policy RetentionPolicy) (int64, error) {
+	cutoff := time.Now().Add(-policy.Retention)
+
+	condition := policy.Column + " < $1"
+	if policy.Where != "" {
+		condition += " AND (" + policy.Where + ")"
+	}
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE (%[2]s) IN (
+			SELECT %[2]s FROM %[1]s WHERE %[3]s LIMIT $2
+		)`, policy.Table, policy.Key, condition)
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		result, err := p.db.Conn(ctx).ExecContext(ctx, query, cutoff, p.batchSize)
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+		purgedRows.WithLabelValues(policy.Name).Add(float64(n))
+		if n < int64(p.batchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// APIKey identifies an external API consumer, sent in X-API-Key, and bounds
+// its requests per calendar month, see internal/quota
+type APIKey struct {
+	ID           int    `json:"id" db:"id"`
+	Name         string `json:"name" db:"name" example:"Acme storefront"`
+	Key          string `json:"key,omitempty" db:"-"` // Only returned when the key is created
+	KeyHash      string `json:"-" db:"key_hash"`      // Hex SHA-256 of the key
+	Prefix       string `json:"prefix" db:"prefix" example:"ak_3f9a1c"`
+	MonthlyQuota int64  `json:"monthly_quota" db:"monthly_quota" example:"100000"` // 0 for no limit
+	Region       string `json:"region,omitempty" db:"region" example:"DE"`         // Of a regional storefront, which only sees the products sold there
+
+	// Metadata
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// UsageRecord counts the requests of a key to one endpoint class in a month
+type UsageRecord struct {
+	APIKeyID      int    `json:"api_key_id" db:"api_key_id"`
+	APIKeyName    string `json:"api_key_name" db:"-"`
+	Month         string `json:"month" db:"month" example:"2026-10"`
+	EndpointClass string `json:"endpoint_class" db:"endpoint_class" example:"products:read"`
+	Requests      int64  `json:"requests" db:"requests"`
+	Bytes         int64  `json:"bytes" db:"bytes"` // Response bytes
+}
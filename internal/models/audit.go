@@ -0,0 +1,17 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEntry struct {
+	ID         int64           `json:"id" db:"id"`
+	Action     string          `json:"action" db:"action"`           // e.g. "config.reload"
+	Actor      string          `json:"actor" db:"actor"`             // Who triggered the action
+	EntityType string          `json:"entity_type" db:"entity_type"` // e.g. "config", "product"
+	EntityID   string          `json:"entity_id,omitempty" db:"entity_id"`
+	Details    json.RawMessage `json:"details,omitempty" db:"details"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
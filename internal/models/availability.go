@@ -0,0 +1,16 @@
+package models
+
+// Availability levels, from the quantity in stock
+const (
+	AvailabilityInStock    = "in_stock"
+	AvailabilityLow        = "low"
+	AvailabilityOutOfStock = "out_of_stock"
+)
+
+// Availability is the public view of a product's stock: whether it can be
+// ordered and a coarse level, never the exact quantity
+type Availability struct {
+	ProductID int    `json:"product_id"`
+	InStock   bool   `json:"in_stock"`
+	Level     string `json:"level" example:"low"` // in_stock, low, or out_of_stock
+}
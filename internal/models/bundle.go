@@ -0,0 +1,35 @@
+package models
+
+// BundleComponent is a product in a bundle, with the units of it each bundle
+// takes
+type BundleComponent struct {
+	ProductID int    `json:"product_id" db:"component_id"`
+	SKU       string `json:"sku,omitempty" db:"sku"`
+	Name      string `json:"name,omitempty" db:"name"`
+	Quantity  int    `json:"quantity" db:"quantity"`           // Units per bundle
+	InStock   int    `json:"in_stock,omitempty" db:"in_stock"` // The component's own quantity
+}
+
+// Bundle is a product sold as a kit of other products. The bundle's own
+// quantity isn't used: what's available follows from its components' stock.
+type Bundle struct {
+	ProductID  int               `json:"product_id"`
+	SKU        string            `json:"sku"`
+	Name       string            `json:"name"`
+	Components []BundleComponent `json:"components"`
+	Available  int               `json:"available"` // Bundles the components' stock makes up
+}
+
+// AvailableBundles returns how many whole bundles the components' stock
+// makes up: the minimum over components of their stock divided by the units
+// each bundle takes
+func AvailableBundles(components []BundleComponent) int {
+	available := 0
+	for i, c := range components {
+		n := max(c.InStock, 0) / c.Quantity
+		if i == 0 || n < available {
+			available = n
+		}
+	}
+	return available
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ChangeUpsert = "upsert" // The product was created or updated; Product holds its new state
+	ChangeStock  = "stock"  // Only the product's quantity changed; Quantity holds the new one
+	ChangeDelete = "delete" // The product was deleted; a tombstone without Product
+)
+
+// ChangeCursor is a position in the product change feed: the transaction and
+// outbox message of the last change read. The zero cursor is the start.
+type ChangeCursor struct {
+	TxID int64
+	ID   int64
+}
+
+// ProductChange is one entry of the product change feed. Applying the entries
+// in order, upserts as replacements, brings a copy of the catalog up to date;
+// applying one twice is harmless.
+type ProductChange struct {
+	Cursor     string    `json:"cursor" example:"MTIzNDU2LjQy"` // Resume after this change with ?since=
+	Op         string    `json:"op" example:"upsert"`
+	ProductID  int       `json:"product_id" example:"1"`
+	SKU        string    `json:"sku,omitempty" example:"BK-1"`
+	Product    *Product  `json:"product,omitempty"`
+	Quantity   *int      `json:"quantity,omitempty" example:"42"`
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type" example:"product.updated"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// ChangeFeed is a page of the product change feed
+type ChangeFeed struct {
+	Changes    []*ProductChange `json:"changes"`
+	NextCursor string           `json:"next_cursor" example:"MTIzNDU2LjQy"` // Pass as ?since= for the next page, even when Changes is empty
+	HasMore    bool             `json:"has_more"`                           // More changes are ready; fetch the next page right away
+}
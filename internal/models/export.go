@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ExportCSV     = "csv"     // gzip-compressed CSV
+	ExportParquet = "parquet" // Parquet with zstd-compressed columns
+
+	ExportScheduled = "scheduled"
+	ExportManual    = "manual"
+
+	ExportRunning   = "running"
+	ExportSucceeded = "succeeded"
+	ExportFailed    = "failed"
+)
+
+// ExportFilter narrows a catalog export; the zero filter exports every product
+type ExportFilter struct {
+	Category string `json:"category,omitempty" db:"filter_category" example:"books"` // Matched case-insensitively
+	Tag      string `json:"tag,omitempty" db:"filter_tag" example:"sale"`
+}
+
+// ExportRun is one catalog export to the blob store, as recorded in export_runs
+type ExportRun struct {
+	ID          int    `json:"id" db:"id"`
+	TriggeredBy string `json:"triggered_by" db:"triggered_by" example:"scheduled"`
+	Format      string `json:"format" db:"format" example:"csv"`
+
+	// Filter the run exported with
+	ExportFilter `json:"filter"`
+
+	Status string  `json:"status" db:"status" example:"succeeded"`
+	Key    string  `json:"key,omitempty" db:"object_key" example:"exports/products/dt=2026-10-14/products-20261014T020000Z-12.csv.gz"`
+	URL    string  `json:"url,omitempty" db:"url" example:"s3://catalog/exports/products/dt=2026-10-14/products-20261014T020000Z-12.csv.gz"`
+	Rows   int64   `json:"rows" db:"row_count"`   // Products exported
+	Bytes  int64   `json:"bytes" db:"size_bytes"` // Size of the stored object
+	Error  *string `json:"error,omitempty" db:"error"`
+
+	// When the snapshot the run read was taken: every exported row is as of
+	// then, whatever was written during the export
+	SnapshotAt *time.Time `json:"snapshot_at,omitempty" db:"snapshot_at"`
+
+	// Metadata
+	StartedAt  time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "strconv"
+
+// Facets of product lists and searches, asked for with ?facets=
+const (
+	FacetCategory   = "category"
+	FacetPriceRange = "price_range"
+	FacetStatus     = "status" // The availability level
+)
+
+// FacetNames are the facets that can be asked for
+var FacetNames = []string{FacetCategory, FacetPriceRange, FacetStatus}
+
+// MaxCategoryBuckets is how many categories a category facet lists at most,
+// the most common first
+const MaxCategoryBuckets = 100
+
+// FacetSpec selects the facets to count and how to bucket them
+type FacetSpec struct {
+	Names       []string  // Of FacetNames
+	PriceBounds []float64 // Ascending; the ranges are [0, b1), [b1, b2), ..., [bn, ∞)
+	LowStock    int       // Units left at or below which status is low, as for availability
+}
+
+// Has reports whether the facet was asked for
+func (s FacetSpec) Has(name string) bool {
+	for _, n := range s.Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FacetBucket is a value of a facet and how many of the matching products
+// have it
+type FacetBucket struct {
+	Value string   `json:"value" example:"10-25"`
+	Count int      `json:"count" example:"12"`
+	Min   *float64 `json:"min,omitempty" example:"10"` // Of a price range, inclusive
+	Max   *float64 `json:"max,omitempty" example:"25"` // Of a price range, exclusive; absent for the last
+}
+
+// Facets holds the buckets of each facet asked for. Categories are listed
+// by count, then name, without uncategorized products; price ranges and
+// statuses are all listed, in order, even when empty.
+type Facets map[string][]FacetBucket
+
+// PriceRanges returns the empty buckets of the price ranges between bounds
+func PriceRanges(bounds []float64) []FacetBucket {
+	buckets := make([]FacetBucket, 0, len(bounds)+1)
+	lower := 0.0
+	for i := 0; i <= len(bounds); i++ {
+		min := lower
+		bucket := FacetBucket{Min: &min}
+		if i < len(bounds) {
+			max := bounds[i]
+			bucket.Max = &max
+			bucket.Value = formatBound(min) + "-" + formatBound(max)
+			lower = max
+		} else {
+			bucket.Value = formatBound(min) + "+"
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// StatusBuckets returns the empty buckets of the availability levels
+func StatusBuckets() []FacetBucket {
+	return []FacetBucket{{Value: AvailabilityInStock}, {Value: AvailabilityLow}, {Value: AvailabilityOutOfStock}}
+}
+
+func formatBound(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Forecast projects a product's demand, the units stock movements took out
+// of it, from its moving average and trend over the last WindowDays. The
+// reorder point covers the demand forecast over the lead time plus the
+// safety stock; an order placed there should bring stock up to OrderUpTo,
+// covering the lead time and the horizon after it.
+type Forecast struct {
+	ProductID          int       `json:"product_id" db:"product_id"`
+	SKU                string    `json:"sku" db:"sku"`
+	WindowDays         int       `json:"window_days" db:"window_days" example:"28"`
+	Demand             int       `json:"demand" db:"demand" example:"84"`                            // Units out over the window
+	AverageDailyDemand float64   `json:"average_daily_demand" db:"average_daily_demand" example:"3"` // Moving average
+	Trend              float64   `json:"trend" db:"trend" example:"0.05"`                            // Change of daily demand per day
+	HorizonDays        int       `json:"horizon_days" db:"horizon_days" example:"30"`
+	ProjectedDemand    float64   `json:"projected_demand" db:"projected_demand" example:"113.25"` // Over the horizon
+	LeadTimeDays       int       `json:"lead_time_days" db:"lead_time_days" example:"7"`
+	LeadTimeDemand     float64   `json:"lead_time_demand" db:"lead_time_demand" example:"23.8"` // Over the lead time
+	ReorderPoint       int       `json:"reorder_point" db:"reorder_point" example:"34"`
+	OrderUpTo          int       `json:"order_up_to" db:"order_up_to" example:"151"`
+	ComputedAt         time.Time `json:"computed_at" db:"computed_at"`
+}
+
+// DemandHistory is what a product's forecast is computed from: its outbound
+// stock movements over the window, oldest first, and its lead time, nil
+// when it has none of its own
+type DemandHistory struct {
+	ProductID    int
+	SKU          string
+	LeadTimeDays *int
+	Outbound     []*StockMovement
+}
+
+// ReorderSuggestion is a product at or below its reorder point, with what to
+// order to bring it up to its forecast's order-up-to level
+type ReorderSuggestion struct {
+	ProductID          int       `json:"product_id" db:"product_id"`
+	SKU                string    `json:"sku" db:"sku"`
+	Name               string    `json:"name" db:"name"`
+	Quantity           int       `json:"quantity" db:"quantity"`
+	ReorderPoint       int       `json:"reorder_point" db:"reorder_point"`
+	OrderUpTo          int       `json:"order_up_to" db:"order_up_to"`
+	SuggestedQuantity  int       `json:"suggested_quantity" db:"suggested_quantity" example:"117"`
+	AverageDailyDemand float64   `json:"average_daily_demand" db:"average_daily_demand"`
+	LeadTimeDays       int       `json:"lead_time_days" db:"lead_time_days"`
+	ComputedAt         time.Time `json:"computed_at" db:"computed_at"` // Of the forecast
+}
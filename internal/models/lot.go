@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Lot is a batch of a product received together under a lot number.
+// Quantity is what's left of it in the product's unit; lots are consumed
+// first-expired-first-out, those that don't expire last.
+type Lot struct {
+	ID         int        `json:"id" db:"id"`
+	ProductID  int        `json:"product_id" db:"product_id"`
+	LotNumber  string     `json:"lot_number" db:"lot_number" example:"L2026-1014"`
+	Quantity   int        `json:"quantity" db:"quantity" example:"24"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"` // nil for lots that don't expire
+	ReceivedAt time.Time  `json:"received_at" db:"received_at"`
+	AlertedAt  *time.Time `json:"alerted_at,omitempty" db:"alerted_at"` // When the expiry alert was sent
+}
+
+// ExpiringLot is a lot with the SKU of its product, as listed by expiry
+type ExpiringLot struct {
+	Lot
+	SKU string `json:"sku" db:"sku"`
+}
+
+// LotConsumption is what consuming stock took from one lot
+type LotConsumption struct {
+	LotID     int        `json:"lot_id"`
+	LotNumber string     `json:"lot_number"`
+	Quantity  int        `json:"quantity"`
+	Remaining int        `json:"remaining"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxNoteLength bounds the body of a product note, in characters
+const MaxNoteLength = 4000
+
+// ProductNote is a note internal users leave on a product, e.g. where its
+// stock is kept, for each other. Pinned notes are listed first.
+type ProductNote struct {
+	ID        int       `json:"id" db:"id"`
+	ProductID int       `json:"product_id" db:"product_id"`
+	Author    string    `json:"author" db:"author"` // Subject of the writer's token, or admin
+	Body      string    `json:"body" db:"body" example:"Overflow stock is on shelf B7 @dana"`
+	Pinned    bool      `json:"pinned" db:"pinned"`
+	Mentions  []string  `json:"mentions,omitempty" db:"-" example:"dana"` // Handles @mentioned in the body
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NoteRequest writes a product note. Updates change only the fields given.
+type NoteRequest struct {
+	Body   *string `json:"body,omitempty" example:"Overflow stock is on shelf B7 @dana"`
+	Pinned *bool   `json:"pinned,omitempty"`
+}
+
+// AdminProduct is a product as admin lists return it: with its latest note,
+// if any, and its effective price on request
+type AdminProduct struct {
+	*Product
+	EffectivePrice *EffectivePrice `json:"effective_price,omitempty"`
+	LatestNote     *ProductNote    `json:"latest_note,omitempty"`
+}
+
+// mentionPattern matches @handle not preceded by a letter, digit, dot, or @,
+// so e-mail addresses aren't mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@([A-Za-z0-9][A-Za-z0-9._-]*)`)
+
+// Mentions returns the handles @mentioned in a note's body, lowercase, once
+// each, in order. Trailing dots, hyphens, and underscores end the sentence
+// rather than the handle.
+func Mentions(body string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		handle := strings.ToLower(strings.TrimRight(m[1], "._-"))
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	OperationExport  = "export"  // A catalog export to the blob store
+	OperationReindex = "reindex" // A rebuild of the search index
+
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+	OperationCancelled = "cancelled"
+)
+
+// Operation is a long-running operation an async endpoint started and
+// answered 202 with; poll it at /api/v1/operations/{id}
+type Operation struct {
+	ID       int     `json:"id" db:"id"`
+	Kind     string  `json:"kind" db:"kind" example:"export"`
+	Status   string  `json:"status" db:"status" example:"succeeded"`
+	Progress int     `json:"progress" db:"progress" example:"100"`                            // Percent done
+	Result   string  `json:"result,omitempty" db:"result" example:"/api/v1/admin/exports/12"` // Link to the outcome, partial when cancelled
+	Error    *string `json:"error,omitempty" db:"error"`                                      // Why it failed
+
+	Checkpoint        string     `json:"checkpoint,omitempty" db:"checkpoint" example:"1200 of 5000 products exported"` // How far it has got, kept when it stops early
+	CancelRequestedAt *time.Time `json:"cancel_requested_at,omitempty" db:"cancel_requested_at"`                        // A running operation stops at its next check
+
+	// Metadata
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// Done reports whether the operation has finished: succeeded, failed, or
+// cancelled
+func (o *Operation) Done() bool {
+	return o.Status != OperationRunning
+}
@@ -0,0 +1,16 @@
+package models
+
+// Order is an order placed on an external sales channel, as far as stock is
+// concerned
+type Order struct {
+	ID    string      `json:"order_id"`
+	Lines []OrderLine `json:"lines"`
+}
+
+// OrderLine is a quantity of a product sold, in Unit, which must convert
+// exactly to the product's unit
+type OrderLine struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+	Unit     string `json:"unit" example:"each"`
+}
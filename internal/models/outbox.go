@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxMessage is a domain event stored for guaranteed delivery to external
+// brokers. Payload holds the full versioned event envelope.
+type OutboxMessage struct {
+	ID            int64           `json:"id" db:"id"`
+	EventID       string          `json:"event_id" db:"event_id"`
+	EventType     string          `json:"event_type" db:"event_type"`
+	SchemaVersion int             `json:"schema_version" db:"schema_version"`
+	AggregateID   string          `json:"aggregate_id" db:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+
+	// TxID is the transaction that wrote the message, which orders the change
+	// feed; always 0 on SQLite
+	TxID int64 `json:"txid" db:"txid"`
+
+	Attempts  int     `json:"attempts" db:"attempts"`
+	LastError *string `json:"last_error,omitempty" db:"last_error"`
+
+	// Metadata
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	PublishedAt *time.Time `json:"published_at,omitempty" db:"published_at"`
+}
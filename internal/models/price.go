@@ -0,0 +1,17 @@
+package models
+
+// Price is what a product costs in a sales region, with the region's tax
+// applied and amounts rounded to the currency's minor unit
+type Price struct {
+	ProductID   int     `json:"product_id"`
+	Region      string  `json:"region" example:"DE"`
+	Category    string  `json:"category,omitempty" example:"books"` // The product's category, when a category rate applied
+	Currency    string  `json:"currency" example:"EUR"`
+	TaxRate     float64 `json:"tax_rate" example:"7"`   // Percent
+	TaxIncluded bool    `json:"tax_included"`           // Whether Amount includes tax
+	Amount      float64 `json:"amount" example:"10.69"` // The price to show: Gross with tax included, otherwise Net
+	Net         float64 `json:"net" example:"9.99"`
+	Tax         float64 `json:"tax" example:"0.70"`
+	Gross       float64 `json:"gross" example:"10.69"`
+	Rounding    string  `json:"rounding" example:"half_up"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxPriceUpdate is how many products one bulk price update changes at most
+const MaxPriceUpdate = 5000
+
+// PriceRule changes unit prices by a percentage, then optionally moves them
+// to the nearest price ending in the given cents, such as .99
+type PriceRule struct {
+	Percent float64  `json:"percent" example:"5"`               // -100 < percent <= 1000, up to two decimals
+	EndWith *float64 `json:"end_with,omitempty" example:"0.99"` // Cents new prices end in, 0 to 0.99
+}
+
+// Validate reports the first problem with r
+func (r PriceRule) Validate() error {
+	if r.Percent <= -100 || r.Percent > 1000 {
+		return fmt.Errorf("percent must be above -100 and at most 1000")
+	}
+	if !twoDecimals(r.Percent) {
+		return fmt.Errorf("percent must have at most two decimals")
+	}
+	if r.EndWith != nil && (*r.EndWith < 0 || *r.EndWith >= 1 || !twoDecimals(*r.EndWith)) {
+		return fmt.Errorf("end_with must be cents between 0 and 0.99")
+	}
+	if r.Percent == 0 && r.EndWith == nil {
+		return fmt.Errorf("percent or end_with is required")
+	}
+	return nil
+}
+
+func twoDecimals(f float64) bool {
+	return math.Abs(math.Round(f*100)-f*100) < 1e-6
+}
+
+// Modes of a bulk price update
+const (
+	PriceUpdateModePreview = "preview" // Report the changes without making them
+	PriceUpdateModeApply   = "apply"   // Make the changes of a preview
+)
+
+// PriceUpdateRequest changes the unit prices of the products matching a
+// filter by a rule
+type PriceUpdateRequest struct {
+	Filter       ProductFilter `json:"filter"`
+	Rule         PriceRule     `json:"rule"`
+	Mode         string        `json:"mode,omitempty" example:"preview" enums:"preview,apply"` // preview by default
+	PreviewToken string        `json:"preview_token,omitempty"`                                // From the preview, required to apply
+}
+
+// PriceUpdateRow is a product a bulk price update changes
+type PriceUpdateRow struct {
+	ProductID int     `json:"product_id" example:"1"`
+	SKU       string  `json:"sku" example:"WID-001"`
+	Name      string  `json:"name" example:"Widget"`
+	OldPrice  float64 `json:"old_price" example:"9.49"`
+	NewPrice  float64 `json:"new_price" example:"9.99"`
+}
+
+// PriceUpdatePreview is what a bulk price update would change. Applying it
+// takes its token, which no longer matches once the filter, the rule, or
+// the prices of the products change.
+type PriceUpdatePreview struct {
+	Affected     int               `json:"affected" example:"1"`
+	Products     []*PriceUpdateRow `json:"products"` // By product ID
+	PreviewToken string            `json:"preview_token" example:"3f79bb7b435b05321651daefd374cdc681dc06faa65e374e38337b88ca046dea"`
+}
+
+// PriceUpdateResult is what applying a bulk price update changed
+type PriceUpdateResult struct {
+	Updated  int               `json:"updated" example:"1"`
+	Batches  int               `json:"batches" example:"1"`
+	Products []*PriceUpdateRow `json:"products"` // As changed, prices read when each batch was locked
+}
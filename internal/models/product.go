@@ -1,18 +1,81 @@
 package models
 
 import (
+	"fmt"
 	"time"
+
+	"{{MODULE_NAME}}/internal/id"
 )
 
 type Product struct {
-	ID          int     `json:"id" db:"id"`
-	SKU         string  `json:"sku" db:"sku"`
-	Name        string  `json:"name" db:"name"`
-	Description string  `json:"description" db:"description"`
-	Quantity    int     `json:"quantity" db:"quantity"`
-	UnitPrice   float64 `json:"unit_price" db:"unit_price"`
+	ID          int          `json:"id" db:"id"`
+	UID         id.ProductID `json:"uid,omitempty" db:"uid" swaggertype:"string" example:"prod_01J9ZQ4V8X2M6T0K3R5N7P9B1C"` // Globally unique, see package id
+	SKU         string       `json:"sku" db:"sku"`
+	Name        string       `json:"name" db:"name"`
+	Slug        string       `json:"slug,omitempty" db:"slug" example:"widget"` // Unique, made from the name unless given, see slug
+	Description string       `json:"description" db:"description"`
+	Category    string       `json:"category" db:"category"`        // Empty when uncategorized
+	Unit        string       `json:"unit" db:"unit" example:"each"` // Unit of Quantity and UnitPrice, see Unit
+	Quantity    int          `json:"quantity" db:"quantity"`
+	UnitPrice   float64      `json:"unit_price" db:"unit_price"`
+
+	// Tags are kept in product_tags, lowercase and sorted
+	Tags []string `json:"tags,omitempty" db:"-"`
+
+	// Regions are the sales regions the product is limited to, kept in
+	// product_regions, uppercase and sorted; none when it's sold everywhere.
+	// They're assigned through /admin/product-regions, not product writes.
+	Regions []string `json:"regions,omitempty" db:"-" example:"DE"`
+
+	// VisibleFrom and VisibleUntil bound when public lists and searches show
+	// the product: from VisibleFrom, or always when nil, until VisibleUntil,
+	// or for good when nil
+	VisibleFrom  *time.Time `json:"visible_from,omitempty" db:"visible_from"`
+	VisibleUntil *time.Time `json:"visible_until,omitempty" db:"visible_until"`
+
+	// Listed is the visibility last announced by product.visibility_changed,
+	// which external search indexes filter on. The product-visibility job
+	// brings it in line with the window; writes leave it alone.
+	Listed bool `json:"listed" db:"listed"`
 
 	// Metadata
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
+
+// SoldIn reports whether the product may be sold in region; any product may
+// be when region is ""
+func (p *Product) SoldIn(region string) bool {
+	if region == "" || len(p.Regions) == 0 {
+		return true
+	}
+	for _, r := range p.Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// VisibleAt reports whether public lists and searches show the product at t
+func (p *Product) VisibleAt(t time.Time) bool {
+	return (p.VisibleFrom == nil || !p.VisibleFrom.After(t)) && (p.VisibleUntil == nil || p.VisibleUntil.After(t))
+}
+
+// ValidateVisibility reports a visibility window that ends before it starts
+func (p *Product) ValidateVisibility() error {
+	if p.VisibleFrom != nil && p.VisibleUntil != nil && !p.VisibleUntil.After(*p.VisibleFrom) {
+		return fmt.Errorf("visible_until must be after visible_from")
+	}
+	return nil
+}
+
+// ProductVersion is the state of a product during [ValidFrom, ValidTo), as
+// recorded in products_history. The current version has no ValidTo; a
+// deleted product's last version is closed at the time of deletion.
+type ProductVersion struct {
+	Product
+	Operation string     `json:"operation" db:"operation"` // INSERT or UPDATE that created the version
+	ValidFrom time.Time  `json:"valid_from" db:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to,omitempty" db:"valid_to"`
+}
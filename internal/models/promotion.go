@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	PromotionPercentage = "percentage" // Value is a percentage off the unit price
+	PromotionFixed      = "fixed"      // Value is an amount off the unit price
+
+	PromotionScopeAll      = "all"
+	PromotionScopeProduct  = "product"  // Target is a product ID
+	PromotionScopeCategory = "category" // Target is a category, matched case-insensitively
+	PromotionScopeTag      = "tag"      // Target is a tag
+)
+
+// Promotion discounts the unit price of the products in its scope while it's
+// in effect, during [StartsAt, EndsAt)
+type Promotion struct {
+	ID        int        `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	Kind      string     `json:"kind" db:"kind" example:"percentage"`
+	Value     float64    `json:"value" db:"value" example:"15"`
+	Scope     string     `json:"scope" db:"scope" example:"category"`
+	Target    string     `json:"target,omitempty" db:"target" example:"books"` // Empty for scope all
+	StartsAt  *time.Time `json:"starts_at,omitempty" db:"starts_at"`           // No start when empty
+	EndsAt    *time.Time `json:"ends_at,omitempty" db:"ends_at"`               // No end when empty
+	Stackable bool       `json:"stackable" db:"stackable"`                     // Combines with other stackable promotions
+	Priority  int        `json:"priority" db:"priority"`                       // Higher applies first
+
+	// Metadata
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EffectivePrice is a product's unit price after the promotions in effect at
+// a point in time
+type EffectivePrice struct {
+	UnitPrice  float64   `json:"unit_price" example:"20"`
+	Price      float64   `json:"price" example:"17"`
+	Discount   float64   `json:"discount" example:"3"`
+	Promotions []int     `json:"promotions"` // IDs of the applied promotions, in the order applied
+	At         time.Time `json:"at"`
+}
+
+// PricedProduct is a product with its effective price, returned when a
+// request asks for effective_price
+type PricedProduct struct {
+	*Product
+	EffectivePrice *EffectivePrice `json:"effective_price"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// ProductSupplier is who supplies a product. EconomicOrderQuantity, when
+// set, fixes how many units it's ordered in; otherwise it's computed from the
+// product's forecast demand and cost.
+type ProductSupplier struct {
+	ProductID             int       `json:"product_id" db:"product_id"`
+	Supplier              string    `json:"supplier" db:"supplier" example:"Acme Wholesale"`
+	EconomicOrderQuantity *int      `json:"economic_order_quantity,omitempty" db:"economic_order_quantity" example:"120"`
+	UpdatedAt             time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PurchaseOrder is an order of products from a supplier. It's drafted from
+// reorder suggestions and has to be approved before it's submitted.
+type PurchaseOrder struct {
+	ID          int                  `json:"id" db:"id"`
+	Supplier    string               `json:"supplier" db:"supplier" example:"Acme Wholesale"`
+	Status      string               `json:"status" db:"status" example:"draft"` // draft, approved, submitted, received, or cancelled
+	CreatedBy   string               `json:"created_by" db:"created_by"`
+	ApprovedBy  string               `json:"approved_by,omitempty" db:"approved_by"`
+	CreatedAt   time.Time            `json:"created_at" db:"created_at"`
+	ApprovedAt  *time.Time           `json:"approved_at,omitempty" db:"approved_at"`
+	SubmittedAt *time.Time           `json:"submitted_at,omitempty" db:"submitted_at"`
+	ClosedAt    *time.Time           `json:"closed_at,omitempty" db:"closed_at"` // Received or cancelled
+	Lines       []*PurchaseOrderLine `json:"lines" db:"-"`
+}
+
+// PurchaseOrderLine is a product on a purchase order. Quantity is the larger
+// of the suggested quantity and the economic order quantity unless edited.
+type PurchaseOrderLine struct {
+	PurchaseOrderID       int    `json:"purchase_order_id" db:"purchase_order_id"`
+	ProductID             int    `json:"product_id" db:"product_id"`
+	SKU                   string `json:"sku" db:"sku"`
+	Quantity              int    `json:"quantity" db:"quantity" example:"120"`
+	SuggestedQuantity     int    `json:"suggested_quantity" db:"suggested_quantity" example:"117"`
+	EconomicOrderQuantity int    `json:"economic_order_quantity" db:"economic_order_quantity" example:"120"` // 0 when unknown
+}
+
+// PurchaseOrderFilter narrows a list of purchase orders; zero fields match
+// every order
+type PurchaseOrderFilter struct {
+	Status   string
+	Supplier string
+}
+
+// ReorderCandidate is a product at or below its reorder point with a
+// supplier, and what its order quantity is computed from
+type ReorderCandidate struct {
+	ProductID             int     `db:"product_id"`
+	SKU                   string  `db:"sku"`
+	Supplier              string  `db:"supplier"`
+	SuggestedQuantity     int     `db:"suggested_quantity"`
+	AverageDailyDemand    float64 `db:"average_daily_demand"`
+	EconomicOrderQuantity *int    `db:"economic_order_quantity"` // Fixed for the product
+	UnitCost              float64 `db:"unit_cost"`               // Of its last receipt, else its unit price
+}
+
+// PurchaseOrderDrafts is what drafting purchase orders did: the drafts
+// created or added to, and how many products to reorder have no supplier
+type PurchaseOrderDrafts struct {
+	PurchaseOrders []*PurchaseOrder `json:"purchase_orders"`
+	Unassigned     int              `json:"unassigned"`
+}
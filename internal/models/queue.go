@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// QueuedTask is a queue task waiting in the database: deferred because its
+// destination was saturated, retrying after a failure, or queued in memory
+// when the service stopped. It runs again from RunAfter.
+type QueuedTask struct {
+	ID          int64           `json:"id" db:"id"`
+	Kind        string          `json:"kind" db:"kind"`
+	Destination string          `json:"destination" db:"destination"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+
+	Attempts  int       `json:"attempts" db:"attempts"`
+	LastError *string   `json:"last_error,omitempty" db:"last_error"`
+	RunAfter  time.Time `json:"run_after" db:"run_after"`
+
+	// Metadata
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// regionPattern matches sales region codes, such as DE or EU-WEST
+var regionPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{0,31}$`)
+
+// NormalizeRegion returns a sales region code uppercase, or false when it
+// isn't one
+func NormalizeRegion(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return code, regionPattern.MatchString(code)
+}
+
+// Actions of a bulk region assignment
+const (
+	RegionsAdd    = "add"    // Limit the products to these regions too
+	RegionsRemove = "remove" // No longer to these; a product left with none is sold everywhere
+	RegionsSet    = "set"    // To exactly these, or everywhere when none
+)
+
+// RegionActions are the actions of a bulk region assignment
+var RegionActions = []string{RegionsAdd, RegionsRemove, RegionsSet}
+
+// MaxRegionAssignment is how many products one bulk region assignment
+// changes at most
+const MaxRegionAssignment = 1000
+
+// RegionCount is how many products are limited to a region, among others
+type RegionCount struct {
+	Region   string `json:"region" db:"region" example:"DE"`
+	Products int    `json:"products" db:"products" example:"42"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// RelatedProduct is a product recommended alongside another, with what the
+// two have in common. Score is SharedTags plus one for each of SameCategory
+// and SamePriceBand.
+type RelatedProduct struct {
+	Product
+	SharedTags    int  `json:"shared_tags" db:"shared_tags"`
+	SameCategory  bool `json:"same_category" db:"same_category"`
+	SamePriceBand bool `json:"same_price_band" db:"same_price_band"`
+	Score         int  `json:"score" db:"score"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ReportParamTypes are the types report parameters are bound as
+var ReportParamTypes = []string{"int", "number", "text", "bool", "timestamp"}
+
+// Report is a named SQL template admins run with POST /reports/{name}/run,
+// its :name placeholders bound to typed parameters. The params are kept as
+// JSON in report_definitions.
+type Report struct {
+	Name        string        `json:"name" db:"name" example:"slow_movers"`
+	Description string        `json:"description" db:"description" example:"Products with stock that sold little"`
+	Query       string        `json:"query" db:"query" example:"SELECT sku, quantity FROM products WHERE quantity >= :min ORDER BY quantity DESC"`
+	Params      []ReportParam `json:"params" db:"-"`
+	CreatedBy   string        `json:"created_by" db:"created_by"`
+
+	// Metadata
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ReportParam is a parameter of a report, bound to its :name placeholders
+type ReportParam struct {
+	Name string `json:"name" example:"min"`
+	Type string `json:"type" example:"int"` // One of ReportParamTypes; timestamps are RFC 3339
+}
+
+// ReportResult is what a report run returned, its rows' values in the order
+// of Columns
+type ReportResult struct {
+	Report    string          `json:"report" example:"slow_movers"`
+	Columns   []string        `json:"columns" example:"sku,quantity"`
+	Rows      [][]interface{} `json:"rows"`
+	RowCount  int             `json:"row_count" example:"2"`
+	Truncated bool            `json:"truncated"` // More rows than REPORT_MAX_ROWS matched
+	Duration  string          `json:"duration" example:"12ms"`
+}
@@ -1,14 +1,25 @@
 package models
 
-import "time"
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type BaseResponse struct {
 	Status    string    `json:"status"`
 	Code      int       `json:"code"`
 	Message   string    `json:"message,omitempty"`
+	Warnings  []string  `json:"warnings,omitempty"` // e.g. that the route is deprecated
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// AddWarnings adds warnings for the client to the response
+func (b *BaseResponse) AddWarnings(warnings ...string) {
+	b.Warnings = append(b.Warnings, warnings...)
+}
+
 type SuccessResponse struct {
 	BaseResponse
 	Data interface{} `json:"data,omitempty"`
@@ -18,6 +29,46 @@ type ErrorResponse struct {
 	BaseResponse
 }
 
+// Problem is an error as RFC 7807 problem details, for clients that accept
+// application/problem+json
+type Problem struct {
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Status    int       `json:"status"`
+	Detail    string    `json:"detail,omitempty"`
+	Instance  string    `json:"instance,omitempty"`
+	Warnings  []string  `json:"warnings,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Problem returns the error as problem details, its type typeBase followed
+// by the name of its status, see ProblemType
+func (e *ErrorResponse) Problem(typeBase, instance string) interface{} {
+	return &Problem{
+		Type:      typeBase + ProblemType(e.Code),
+		Title:     http.StatusText(e.Code),
+		Status:    e.Code,
+		Detail:    e.Message,
+		Instance:  instance,
+		Warnings:  e.Warnings,
+		Timestamp: e.Timestamp,
+	}
+}
+
+// ProblemType returns the name of the problem type of errors with status
+// code: its status text in lowercase with dashes, e.g. "not-found" or
+// "precondition-failed"
+func ProblemType(code int) string {
+	text := http.StatusText(code)
+	if text == "" {
+		return strconv.Itoa(code)
+	}
+	text = strings.ReplaceAll(strings.ToLower(text), "'", "")
+	return strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	}), "-")
+}
+
 
 type PaginatedResponse struct {
 	BaseResponse
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Return is a return merchandise authorization (RMA): units of a product
+// sold that are to come back, inspected, and restocked, scrapped, or
+// repaired
+type Return struct {
+	ID              int        `json:"id" db:"id"`
+	ProductID       int        `json:"product_id" db:"product_id"`
+	SKU             string     `json:"sku" db:"sku"`
+	Quantity        int        `json:"quantity" db:"quantity" example:"1"`       // In the product's unit
+	OrderSource     string     `json:"order_source,omitempty" db:"order_source"` // Sales channel of the original order
+	OrderID         string     `json:"order_id,omitempty" db:"order_id"`         // As the channel sent it
+	Reason          string     `json:"reason,omitempty" db:"reason" example:"Arrived scratched"`
+	Status          string     `json:"status" db:"status" example:"authorized"`            // authorized, received, repairing, restocked, scrapped, or cancelled
+	Disposition     string     `json:"disposition,omitempty" db:"disposition"`             // restock, scrap, or repair, as last inspected
+	Notes           string     `json:"notes,omitempty" db:"notes"`                         // Of the inspections
+	StockMovementID *int64     `json:"stock_movement_id,omitempty" db:"stock_movement_id"` // Recorded by the restock
+	CreatedBy       string     `json:"created_by" db:"created_by"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	ReceivedAt      *time.Time `json:"received_at,omitempty" db:"received_at"`
+	ClosedAt        *time.Time `json:"closed_at,omitempty" db:"closed_at"` // Restocked, scrapped, or cancelled
+}
+
+// ReturnRequest authorizes a return of a product, by product_id or sku
+type ReturnRequest struct {
+	ProductID   int    `json:"product_id,omitempty"`
+	SKU         string `json:"sku,omitempty"`
+	Quantity    int    `json:"quantity" example:"1"`
+	OrderSource string `json:"order_source,omitempty" example:"shopify"`
+	OrderID     string `json:"order_id,omitempty" example:"1042"`
+	Reason      string `json:"reason,omitempty" example:"Arrived scratched"`
+}
+
+// ReturnFilter narrows the returns listed; zero values match all
+type ReturnFilter struct {
+	Status      string
+	ProductID   int
+	OrderSource string
+	OrderID     string
+}
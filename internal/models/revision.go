@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Statuses of a product revision
+const (
+	RevisionPending  = "pending"
+	RevisionApproved = "approved"
+	RevisionRejected = "rejected"
+)
+
+// RevisionStatuses are the statuses of a product revision
+var RevisionStatuses = []string{RevisionPending, RevisionApproved, RevisionRejected}
+
+// ProductRevision is an update of a product by an editor, held until an
+// approver approves it, which applies it, or rejects it
+type ProductRevision struct {
+	ID        int     `json:"id" db:"id"`
+	ProductID int     `json:"product_id" db:"product_id"`
+	Product   Product `json:"product" db:"-"` // As the editor sent it, validated
+
+	// When the product the editor saw was last updated; a revision can't be
+	// approved once the product has changed since
+	BaseUpdatedAt time.Time `json:"base_updated_at" db:"base_updated_at"`
+
+	Status     string     `json:"status" db:"status" example:"pending"` // pending, approved, or rejected
+	Author     string     `json:"author" db:"author"`                   // Subject of the editor's token
+	Reviewer   string     `json:"reviewer,omitempty" db:"reviewer"`
+	Comment    string     `json:"comment,omitempty" db:"comment" example:"Price needs sign-off"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+}
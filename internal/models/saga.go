@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	SagaRunning      = "running"      // Running its steps in order
+	SagaCompensating = "compensating" // A step failed; undoing the steps before it in reverse
+	SagaCompleted    = "completed"
+	SagaCompensated  = "compensated"
+)
+
+// Saga is the state of a multi-step workflow across systems, such as order
+// fulfillment, which internal/workflow runs and resumes after a crash
+type Saga struct {
+	ID     int             `json:"id" db:"id"`
+	Name   string          `json:"name" db:"name" example:"order-fulfillment"` // Of its definition
+	Key    string          `json:"key" db:"saga_key" example:"webhook:1001"`   // Unique per name, e.g. the order
+	Status string          `json:"status" db:"status" example:"completed"`
+	Step   int             `json:"step" db:"step"`             // Next to run, or steps left to compensate
+	Data   json.RawMessage `json:"data" db:"data"`             // Input, and what steps recorded
+	Error  *string         `json:"error,omitempty" db:"error"` // Of the step that failed
+
+	// The instance running the saga, and until when, unless it renews the
+	// claim; resumed elsewhere once the claim has lapsed
+	ClaimedBy    string     `json:"claimed_by,omitempty" db:"claimed_by"`
+	ClaimedUntil *time.Time `json:"claimed_until,omitempty" db:"claimed_until"`
+
+	// Metadata
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// Done reports whether the saga has finished, completed or compensated
+func (s *Saga) Done() bool {
+	return s.Status == SagaCompleted || s.Status == SagaCompensated
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fields products can be sorted by, see ProductFilter
+var ProductSortFields = []string{"created_at", "updated_at", "name", "sku", "unit_price", "quantity"}
+
+// Visibilities of products filters select, by their window as of the request
+const (
+	VisibilityVisible   = "visible"   // Shown by public lists and searches
+	VisibilityHidden    = "hidden"    // Not shown, before or after the window
+	VisibilityScheduled = "scheduled" // Not shown yet, before the window
+	VisibilityExpired   = "expired"   // Not shown anymore, after the window
+)
+
+// ProductVisibilities are the visibilities products can be filtered by
+var ProductVisibilities = []string{VisibilityVisible, VisibilityHidden, VisibilityScheduled, VisibilityExpired}
+
+// ProductFilter selects and orders the products GET /products lists. The zero
+// filter lists every product, newest first.
+type ProductFilter struct {
+	Category       string   `json:"category,omitempty" example:"books"`      // Matched case-insensitively
+	Tags           []string `json:"tags,omitempty" example:"sale"`           // Products with every one of these tags
+	MinPrice       *float64 `json:"min_price,omitempty" example:"5"`         // Unit price at least this
+	MaxPrice       *float64 `json:"max_price,omitempty" example:"20"`        // Unit price at most this
+	InStock        *bool    `json:"in_stock,omitempty"`                      // Quantity above zero, or zero when false
+	Region         string   `json:"region,omitempty" example:"DE"`           // Products sold in this region
+	Visibility     string   `json:"visibility,omitempty" example:"hidden"`   // One of ProductVisibilities, as of the request
+	ExpiringWithin string   `json:"expiring_within,omitempty" example:"72h"` // Visible, but hidden within this Go duration
+	Sort           string   `json:"sort,omitempty" example:"unit_price"`     // One of ProductSortFields, created_at by default
+	Order          string   `json:"order,omitempty" example:"asc"`           // asc or desc; timestamps sort desc by default, others asc
+}
+
+// Validate reports the first problem with f
+func (f ProductFilter) Validate() error {
+	for _, tag := range f.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("tags must not be empty")
+		}
+	}
+	if (f.MinPrice != nil && *f.MinPrice < 0) || (f.MaxPrice != nil && *f.MaxPrice < 0) {
+		return fmt.Errorf("min_price and max_price must not be negative")
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return fmt.Errorf("min_price must not exceed max_price")
+	}
+	if f.Region != "" {
+		if _, ok := NormalizeRegion(f.Region); !ok {
+			return fmt.Errorf("region must be a region code")
+		}
+	}
+	if f.Visibility != "" && !visibility(f.Visibility) {
+		return fmt.Errorf("visibility must be one of %s", strings.Join(ProductVisibilities, ", "))
+	}
+	if f.ExpiringWithin != "" {
+		if d, err := time.ParseDuration(f.ExpiringWithin); err != nil || d <= 0 {
+			return fmt.Errorf("expiring_within must be a positive duration, e.g. 72h")
+		}
+	}
+	if f.Sort != "" && !sortField(f.Sort) {
+		return fmt.Errorf("sort must be one of %s", strings.Join(ProductSortFields, ", "))
+	}
+	if f.Order != "" && f.Order != "asc" && f.Order != "desc" {
+		return fmt.Errorf("order must be asc or desc")
+	}
+	return nil
+}
+
+func visibility(v string) bool {
+	for _, w := range ProductVisibilities {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+func sortField(field string) bool {
+	for _, f := range ProductSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// SavedSearch is a named ProductFilter, listed by GET /products?view={id}.
+// The filter is kept as JSON in saved_searches.
+type SavedSearch struct {
+	ID     int           `json:"id" db:"id"`
+	Name   string        `json:"name" db:"name" example:"Books on sale"`
+	Filter ProductFilter `json:"filter" db:"-"`
+
+	// Metadata
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// SearchQuery is a product search. Text is matched against the SKU, name,
+// and description; Category and Tag filter the matches.
+type SearchQuery struct {
+	Text     string
+	Category string // Matched case-insensitively
+	Tag      string
+	Region   string    // Only products sold there, see Product.Regions
+	Visible  time.Time // Only products visible then (see Product.VisibleAt) when set; external indexes go by Product.Listed
+	Limit    int
+	Offset   int
+	Facets   FacetSpec // Counted over all the matches; none without names
+}
+
+// SearchResult is a page of search matches and how many match in total
+type SearchResult struct {
+	Products   []*Product
+	Total      int
+	Fuzzy      bool   // Matched by spelling similarity, as nothing matched the text as given
+	DidYouMean string // The text with misspelled words corrected, when fuzzy
+	Facets     Facets // Of q.Facets, nil without
+}
+
+// SuggestQuery asks for name or SKU completions of Prefix
+type SuggestQuery struct {
+	Prefix  string
+	Region  string    // Only products sold there, see Product.Regions
+	Visible time.Time // Only products visible then when set, like SearchQuery.Visible
+	Limit   int
+}
+
+// Suggestion is a product name or SKU completion, trimmed to what a
+// typeahead shows
+type Suggestion struct {
+	ID   int    `json:"id" db:"id"`
+	SKU  string `json:"sku" db:"sku"`
+	Name string `json:"name" db:"name"`
+}
+
+// SearchIndexState records how far a search index has been synced, as kept
+// in search_index_state
+type SearchIndexState struct {
+	Name        string     `json:"name" db:"name"`                 // Alias queries go through
+	IndexName   string     `json:"index_name" db:"index_name"`     // Index the alias points to
+	Cursor      string     `json:"cursor" db:"feed_cursor"`        // Change feed cursor applied through
+	ReindexedAt time.Time  `json:"reindexed_at" db:"reindexed_at"` // When IndexName was built
+	SyncedAt    *time.Time `json:"synced_at,omitempty" db:"synced_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SerialNumber identifies one unit of a serialized product. Active serials
+// are units on hand; a retired one left stock for RetiredReason.
+type SerialNumber struct {
+	ID            int        `json:"id" db:"id"`
+	ProductID     int        `json:"product_id" db:"product_id"`
+	Serial        string     `json:"serial" db:"serial" example:"SN-4C2A-0091"`
+	Status        string     `json:"status" db:"status" example:"active"`                         // active or retired
+	RetiredReason string     `json:"retired_reason,omitempty" db:"retired_reason" example:"sold"` // sold, scrapped, lost, or returned
+	RegisteredAt  time.Time  `json:"registered_at" db:"registered_at"`
+	RetiredAt     *time.Time `json:"retired_at,omitempty" db:"retired_at"`
+}
+
+// SerialLookup is a serial number with the product it's a unit of, as
+// found by scanning it
+type SerialLookup struct {
+	*SerialNumber
+	Product *Product `json:"product"`
+}
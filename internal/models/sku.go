@@ -0,0 +1,7 @@
+package models
+
+// SKUPreview is the SKU the next product created without one gets
+type SKUPreview struct {
+	SKU     string `json:"sku" example:"PRD-2026-00042"`
+	Pattern string `json:"pattern" example:"PRD-{YYYY}-{SEQ:5}"`
+}
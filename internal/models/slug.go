@@ -0,0 +1,10 @@
+package models
+
+// SlugRedirect answers a lookup by a slug the product had before: the
+// product moved to its current slug, at Location
+type SlugRedirect struct {
+	ProductID int    `json:"product_id" example:"42"`
+	Slug      string `json:"slug" example:"widget"`          // Current
+	OldSlug   string `json:"old_slug" example:"blue-widget"` // Looked up
+	Location  string `json:"location" example:"/api/v1/products/slug/widget"`
+}
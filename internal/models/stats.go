@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+)
+
+// ProductStats are a product's stock statistics, from the product_stats view
+type ProductStats struct {
+	ProductID      int     `json:"product_id" db:"product_id"`
+	SKU            string  `json:"sku" db:"sku"`
+	Unit           string  `json:"unit" db:"unit"`
+	Dimension      string  `json:"dimension" db:"dimension"`
+	Quantity       int     `json:"quantity" db:"quantity"`               // In Unit
+	BaseQuantity   int     `json:"base_quantity" db:"base_quantity"`     // In the base unit of Dimension
+	InventoryValue float64 `json:"inventory_value" db:"inventory_value"` // Quantity times unit price
+	Movements      int     `json:"movements" db:"movements"`             // Recorded stock movements
+	UnitsIn        int     `json:"units_in" db:"units_in"`               // In Unit
+	UnitsOut       int     `json:"units_out" db:"units_out"`             // In Unit
+}
+
+// InventorySummary adds up the stats of every product. Quantities only add
+// up within a dimension, so Units counts the count dimension in its base
+// unit (each), and Quantities has every dimension.
+type InventorySummary struct {
+	Products       int                  `json:"products" db:"products"`
+	Units          int                  `json:"units" db:"units"`
+	InventoryValue float64              `json:"inventory_value" db:"inventory_value"`
+	OutOfStock     int                  `json:"out_of_stock" db:"out_of_stock"`
+	UnitsIn        int                  `json:"units_in" db:"units_in"`
+	UnitsOut       int                  `json:"units_out" db:"units_out"`
+	Quantities     []*DimensionQuantity `json:"quantities" db:"-"`
+}
+
+// DimensionQuantity is the stock of one dimension's products, in its base
+// unit
+type DimensionQuantity struct {
+	Dimension string `json:"dimension" db:"dimension" example:"mass"`
+	BaseUnit  string `json:"base_unit" db:"base_unit" example:"g"`
+	Products  int    `json:"products" db:"products"`
+	Quantity  int    `json:"quantity" db:"quantity"`
+	UnitsIn   int    `json:"units_in" db:"units_in"`
+	UnitsOut  int    `json:"units_out" db:"units_out"`
+}
+
+// Staleness says how current data read from a materialized view is
+type Staleness struct {
+	Source      string     `json:"source" example:"materialized_view"` // "materialized_view", or "live" while the view holds no data
+	RefreshedAt *time.Time `json:"refreshed_at,omitempty"`
+	AgeSeconds  float64    `json:"age_seconds" example:"42.5"`
+}
+
+// ABC classes rank products by their consumption value: A products make up
+// the first ABCShareA of it, B products the next ones up to ABCShareB, and C
+// products the rest, including those with none
+const (
+	ABCClassA = "A"
+	ABCClassB = "B"
+	ABCClassC = "C"
+
+	ABCShareA = 0.8
+	ABCShareB = 0.95
+)
+
+// ABCClassification is a product's ABC class by the value of the units stock
+// movements took out of it over the analysis window, at its unit price
+type ABCClassification struct {
+	ProductID       int     `json:"product_id" db:"product_id"`
+	SKU             string  `json:"sku" db:"sku"`
+	Name            string  `json:"name" db:"name"`
+	Quantity        int     `json:"quantity" db:"quantity"`
+	UnitsOut        int     `json:"units_out" db:"units_out" example:"120"`
+	Value           float64 `json:"value" db:"value" example:"1198.8"`                     // Units out times unit price
+	Share           float64 `json:"share" db:"share" example:"0.12"`                       // Of the value of all products
+	CumulativeShare float64 `json:"cumulative_share" db:"cumulative_share" example:"0.64"` // Of this product and those ranked above it
+	Rank            int     `json:"rank" db:"rank" example:"3"`                            // 1 is the highest value
+	Class           string  `json:"class" db:"class" example:"A"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// StockMovement is one change of a product's quantity, recorded by the
+// database whenever the quantity changes
+type StockMovement struct {
+	ID            int64  `json:"id" db:"id"`
+	ProductID     int    `json:"product_id" db:"product_id"`
+	SKU           string `json:"sku" db:"sku"`
+	Delta         int    `json:"delta" db:"delta"`
+	QuantityAfter int    `json:"quantity_after" db:"quantity_after"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// StockTake is a counting session. Products counted while it's open are
+// adjusted to their counts when it's completed.
+type StockTake struct {
+	ID       int        `json:"id" db:"id"`
+	Name     string     `json:"name" db:"name" example:"Aisle 4, October"`
+	Status   string     `json:"status" db:"status" example:"open"` // open, completed, or cancelled
+	OpenedBy string     `json:"opened_by" db:"opened_by"`
+	OpenedAt time.Time  `json:"opened_at" db:"opened_at"`
+	ClosedAt *time.Time `json:"closed_at,omitempty" db:"closed_at"` // Completed or cancelled
+}
+
+// StockTakeCount is a product's count in a stock take. Discrepancy is the
+// count less Expected, the system quantity when the product was counted;
+// completing the take posts it as Adjustment.
+type StockTakeCount struct {
+	StockTakeID int       `json:"stock_take_id" db:"stock_take_id"`
+	ProductID   int       `json:"product_id" db:"product_id"`
+	SKU         string    `json:"sku" db:"sku"`
+	Counted     int       `json:"counted" db:"counted" example:"42"` // In the product's unit
+	Expected    int       `json:"expected" db:"expected" example:"45"`
+	Discrepancy int       `json:"discrepancy" db:"-" example:"-3"`
+	Reason      string    `json:"reason" db:"reason" example:"damaged"`
+	Adjustment  *int      `json:"adjustment,omitempty" db:"adjustment"` // Posted on completion
+	CountedAt   time.Time `json:"counted_at" db:"counted_at"`
+}
+
+// StockCount is a count as recorded by a counter, of a product by SKU.
+// Counted is required; Reason defaults to miscount.
+type StockCount struct {
+	SKU     string `json:"sku" example:"WID-001"`
+	Counted *int   `json:"counted" example:"42"`
+	Reason  string `json:"reason,omitempty" example:"damaged"`
+}
+
+// StockTakeDetail is a stock take with its counts and their totals
+type StockTakeDetail struct {
+	*StockTake
+	Counts        []*StockTakeCount `json:"counts"`
+	Discrepancies int               `json:"discrepancies"`  // Counts that differ from the system quantity
+	NetAdjustment int               `json:"net_adjustment"` // Sum of the discrepancies
+}
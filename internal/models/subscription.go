@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Subscription asks for a notification at CallbackURL whenever one of Fields
+// of a product changes, see internal/notify
+type Subscription struct {
+	ID          int      `json:"id" db:"id"`
+	ProductID   int      `json:"product_id" db:"product_id"`
+	SKU         string   `json:"sku,omitempty" db:"-"`               // Instead of product_id when subscribing
+	Fields      []string `json:"fields" db:"-" example:"unit_price"` // JSON names of product fields
+	CallbackURL string   `json:"callback_url" db:"callback_url" example:"https://example.com/hooks/prices"`
+	Secret      string   `json:"secret,omitempty" db:"secret"` // Signs notifications; never returned
+
+	// Metadata
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// WebhookDelivery is an attempt to deliver a notification to a
+// subscription's callback
+type WebhookDelivery struct {
+	ID             int64  `json:"id" db:"id"`
+	SubscriptionID int    `json:"subscription_id" db:"subscription_id"`
+	ProductID      int    `json:"product_id" db:"product_id"`
+	EventID        string `json:"event_id" db:"event_id"`
+	CallbackURL    string `json:"callback_url" db:"callback_url"`
+	Attempt        int    `json:"attempt" db:"attempt"`         // 1 for the first
+	StatusCode     int    `json:"status_code" db:"status_code"` // 0 when the callback didn't answer
+	Error          string `json:"error,omitempty" db:"error"`   // Why the attempt failed
+
+	// Metadata
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
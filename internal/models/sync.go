@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SyncState is the outcome of the last catalog sync run of a connector.
+// LastSuccessAt doubles as the watermark for incremental pulls and pushes.
+type SyncState struct {
+	Connector     string     `json:"connector" db:"connector"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty" db:"last_success_at"`
+	LastError     *string    `json:"last_error,omitempty" db:"last_error"`
+
+	// Counters of the last run
+	Pulled    int `json:"pulled" db:"pulled"`
+	Created   int `json:"created" db:"created"`
+	Updated   int `json:"updated" db:"updated"`
+	Pushed    int `json:"pushed" db:"pushed"`
+	Conflicts int `json:"conflicts" db:"conflicts"` // Changed on both sides since the last sync
+	Skipped   int `json:"skipped" db:"skipped"`     // Remote products without a mappable SKU
+}
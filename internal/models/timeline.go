@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Kinds of product timeline entries
+const (
+	TimelineAudit           = "audit"
+	TimelineStockMovement   = "stock_movement"
+	TimelinePriceChange     = "price_change"
+	TimelineWebhookDelivery = "webhook_delivery"
+)
+
+// TimelineKinds are the kinds of product timeline entries
+var TimelineKinds = []string{TimelineAudit, TimelineStockMovement, TimelinePriceChange, TimelineWebhookDelivery}
+
+// TimelineEntry is something that happened to a product, with the record it
+// comes from in the field of its kind
+type TimelineEntry struct {
+	Kind       string    `json:"kind" example:"price_change"`
+	ID         int64     `json:"id" example:"7"` // Of the record, unique within its kind
+	OccurredAt time.Time `json:"occurred_at"`
+
+	Audit           *AuditEntry      `json:"audit,omitempty"`
+	StockMovement   *StockMovement   `json:"stock_movement,omitempty"`
+	PriceChange     *PriceChange     `json:"price_change,omitempty"`
+	WebhookDelivery *WebhookDelivery `json:"webhook_delivery,omitempty"`
+}
+
+// PriceChange is a version of a product whose unit price differs from the
+// previous version's
+type PriceChange struct {
+	VersionID     int64     `json:"version_id" db:"id"` // Of the version in products_history
+	PreviousPrice float64   `json:"previous_price" db:"previous_price" example:"9.99"`
+	UnitPrice     float64   `json:"unit_price" db:"unit_price" example:"12.49"`
+	ChangedAt     time.Time `json:"changed_at" db:"valid_from"`
+}
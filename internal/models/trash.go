@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// TrashedProduct is a deleted product, kept until it's restored or its
+// retention runs out
+type TrashedProduct struct {
+	ID        int       `json:"id" db:"id"` // The product's ID
+	SKU       string    `json:"sku" db:"sku"`
+	Name      string    `json:"name" db:"name"`
+	Product   Product   `json:"product" db:"-"` // As it was when deleted, with its tags
+	TrashedAt time.Time `json:"trashed_at" db:"trashed_at"`
+
+	// When it's purged and how long until then; unset when trash is kept
+	// forever
+	PurgeAt          *time.Time `json:"purge_at,omitempty" db:"-"`
+	RemainingSeconds *int64     `json:"remaining_seconds,omitempty" db:"-"`
+}
@@ -0,0 +1,18 @@
+package models
+
+const (
+	// DefaultUnit is the unit of products created without one
+	DefaultUnit = "each"
+
+	// CountDimension is the dimension of DefaultUnit, whose quantities are
+	// reported as units
+	CountDimension = "count"
+)
+
+// Unit is a unit of measure, Factor times the base unit of its Dimension
+// (the unit with factor 1), e.g. a box-of-12 is 12 each and a kg is 1000 g
+type Unit struct {
+	Code      string `json:"code" db:"code" example:"box-of-12"`
+	Dimension string `json:"dimension" db:"dimension" example:"count"`
+	Factor    int    `json:"factor" db:"factor" example:"12"`
+}
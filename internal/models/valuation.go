@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// StockReceipt is a cost lot: units of a product received at a unit cost.
+// Receiving stock adds Quantity to the product's.
+type StockReceipt struct {
+	ID         int       `json:"id" db:"id"`
+	ProductID  int       `json:"product_id" db:"product_id"`
+	Quantity   int       `json:"quantity" db:"quantity" example:"24"` // In the product's unit
+	UnitCost   float64   `json:"unit_cost" db:"unit_cost" example:"4.25"`
+	Reference  string    `json:"reference" db:"reference" example:"PO-1042"`
+	ReceivedAt time.Time `json:"received_at" db:"received_at"` // Now unless given; orders the lots
+}
+
+// Valuation is what stock on hand cost, under a valuation method. Units on
+// hand that no receipt accounts for, like stock counted before receipts were
+// recorded, have no known cost and are left out of Value.
+type Valuation struct {
+	Method           string    `json:"method" db:"method" example:"fifo"`
+	Value            float64   `json:"value" db:"value"`
+	Quantity         int       `json:"quantity" db:"quantity"` // Units valued, in each product's unit
+	UncostedQuantity int       `json:"uncosted_quantity" db:"uncosted_quantity"`
+	ValuedAt         time.Time `json:"valued_at" db:"valued_at"`
+}
@@ -0,0 +1,229 @@
+// Package notify delivers change notifications to subscriptions. Each
+// subscription names a product and the fields it watches; a change to any
+// of those fields is POSTed to its callback URL, and changes to other fields
+// send nothing.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/queue"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the body, prefixed with
+// sha256=, for subscriptions with a secret
+const SignatureHeader = "X-Webhook-Signature"
+
+const taskKind = "subscription.notify"
+
+// Notification is the body POSTed to a callback URL. Changes holds only the
+// watched fields; an expiry notification also carries the lot, and a mentions
+// notification the note. Deliveries are retried, so a notification can arrive
+// more than once and out of order; EventID and OccurredAt tell them apart.
+type Notification struct {
+	SubscriptionID int                   `json:"subscription_id"`
+	EventID        string                `json:"event_id"`
+	OccurredAt     time.Time             `json:"occurred_at"`
+	ProductID      int                   `json:"product_id"`
+	SKU            string                `json:"sku"`
+	Changes        []events.FieldChange  `json:"changes"`
+	Lot            *events.LotExpiring   `json:"lot,omitempty"`
+	Note           *events.NoteMentioned `json:"note,omitempty"`
+}
+
+type Notifier struct {
+	repo   repository.SubscriptionRepository
+	pool   *queue.Pool
+	client *http.Client
+	logger *slog.Logger
+}
+
+// New creates a notifier delivering on pool, registering its handler there
+func New(repo repository.SubscriptionRepository, pool *queue.Pool, logger *slog.Logger) *Notifier {
+	n := &Notifier{
+		repo:   repo,
+		pool:   pool,
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+	}
+	pool.Handle(taskKind, n.deliver)
+	return n
+}
+
+// Handler returns an event handler queueing a notification for every
+// subscription watching a changed field. The notifications are stored in the
+// write's transaction and delivered once it commits, so a rolled-back write
+// (or dry run) notifies no one.
+func (n *Notifier) Handler() events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		var productID int
+		var sku string
+		var changes []events.FieldChange
+		var lot *events.LotExpiring
+		var note *events.NoteMentioned
+		switch payload := event.Payload.(type) {
+		case events.ProductUpdated:
+			productID, sku = payload.Product.ID, payload.Product.SKU
+			for _, change := range payload.Changes {
+				// Quantity changes come with StockAdjusted, which orders emit alone
+				if change.Field != "quantity" {
+					changes = append(changes, change)
+				}
+			}
+		case events.StockAdjusted:
+			productID, sku = payload.ProductID, payload.SKU
+			changes = []events.FieldChange{{Field: "quantity", Old: payload.Previous, New: payload.Current}}
+		case events.LotExpiring:
+			productID, sku, lot = payload.ProductID, payload.SKU, &payload
+			changes = []events.FieldChange{{Field: "expiry", New: payload.ExpiresAt}}
+		case events.NoteMentioned:
+			productID, sku, note = payload.ProductID, payload.SKU, &payload
+			changes = []events.FieldChange{{Field: "mentions", New: payload.Mentioned}}
+		default:
+			return nil
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+
+		subs, err := n.repo.ForProduct(ctx, productID)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subs {
+			watched := watchedChanges(sub.Fields, changes)
+			if len(watched) == 0 {
+				continue
+			}
+
+			payload, err := json.Marshal(Notification{
+				SubscriptionID: sub.ID,
+				EventID:        event.ID,
+				OccurredAt:     event.OccurredAt,
+				ProductID:      productID,
+				SKU:            sku,
+				Changes:        watched,
+				Lot:            lot,
+				Note:           note,
+			})
+			if err != nil {
+				return err
+			}
+			task := queue.Task{Kind: taskKind, Destination: destination(sub.CallbackURL), Payload: payload}
+			if err := n.pool.SubmitTx(ctx, task); err != nil {
+				return fmt.Errorf("failed to queue notification for subscription %d: %w", sub.ID, err)
+			}
+		}
+		return nil
+	}
+}
+
+func watchedChanges(fields []string, changes []events.FieldChange) []events.FieldChange {
+	var watched []events.FieldChange
+	for _, change := range changes {
+		for _, field := range fields {
+			if change.Field == field {
+				watched = append(watched, change)
+				break
+			}
+		}
+	}
+	return watched
+}
+
+// destination limits concurrent deliveries per callback host
+func destination(callbackURL string) string {
+	host := callbackURL
+	if u, err := url.Parse(callbackURL); err == nil {
+		host = u.Host
+	}
+	return "notify:" + host
+}
+
+// deliver POSTs a notification to the subscription's current callback URL.
+// Notifications of a subscription deleted since are dropped, as are those a
+// callback rejects with a client error other than 408 or 429.
+func (n *Notifier) deliver(ctx context.Context, task queue.Task) error {
+	var notification Notification
+	if err := json.Unmarshal(task.Payload, &notification); err != nil {
+		return queue.Permanent(fmt.Errorf("invalid notification payload: %w", err))
+	}
+
+	sub, err := n.repo.GetByID(ctx, notification.SubscriptionID)
+	if err != nil {
+		if err.Error() == "subscription not found" {
+			return nil
+		}
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.CallbackURL, bytes.NewReader(task.Payload))
+	if err != nil {
+		return queue.Permanent(fmt.Errorf("invalid callback URL of subscription %d: %w", sub.ID, err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sub.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign([]byte(sub.Secret), task.Payload))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		n.record(ctx, sub, notification, task, 0, err)
+		return fmt.Errorf("failed to notify subscription %d: %w", sub.ID, err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		n.record(ctx, sub, notification, task, resp.StatusCode, nil)
+		return nil
+	case resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		n.logger.Warn("notification rejected", "subscription_id", sub.ID, "status", resp.StatusCode)
+		err = queue.Permanent(fmt.Errorf("callback of subscription %d answered %d", sub.ID, resp.StatusCode))
+	default:
+		err = fmt.Errorf("callback of subscription %d answered %d", sub.ID, resp.StatusCode)
+	}
+	n.record(ctx, sub, notification, task, resp.StatusCode, err)
+	return err
+}
+
+// record logs a delivery attempt for the product's timeline. A failure to
+// log doesn't fail the delivery, which would send it again.
+func (n *Notifier) record(ctx context.Context, sub *models.Subscription, notification Notification, task queue.Task, status int, deliveryErr error) {
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: sub.ID,
+		ProductID:      notification.ProductID,
+		EventID:        notification.EventID,
+		CallbackURL:    sub.CallbackURL,
+		Attempt:        task.Attempts + 1,
+		StatusCode:     status,
+	}
+	if deliveryErr != nil {
+		delivery.Error = deliveryErr.Error()
+	}
+	if err := n.repo.RecordDelivery(ctx, delivery); err != nil {
+		n.logger.Error("failed to record webhook delivery", "error", err, "subscription_id", sub.ID)
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of body with secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
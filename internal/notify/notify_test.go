@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/queue"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+type received struct {
+	notification Notification
+	body         []byte
+	signature    string
+}
+
+func TestNotifier(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "notify.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	deliveries := make(chan received, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var n Notification
+		json.Unmarshal(body, &n)
+		deliveries <- received{notification: n, body: body, signature: r.Header.Get(SignatureHeader)}
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	pool := queue.NewPool(repository.NewQueueRepository(db), db, queue.Config{PollInterval: time.Hour}, logger)
+	subs := repository.NewSubscriptionRepository(db)
+	notifier := New(subs, pool, logger)
+	pool.Start(ctx)
+	defer pool.Stop(ctx)
+
+	product := &models.Product{SKU: "BK-1", Name: "Atlas", UnitPrice: 10, Quantity: 5}
+	if err := repository.NewProductRepository(db).Create(ctx, product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	prices := &models.Subscription{ProductID: product.ID, Fields: []string{"unit_price"}, CallbackURL: srv.URL, Secret: "s3cret"}
+	stock := &models.Subscription{ProductID: product.ID, Fields: []string{"quantity"}, CallbackURL: srv.URL}
+	for _, sub := range []*models.Subscription{prices, stock} {
+		if err := subs.Create(ctx, sub); err != nil {
+			t.Fatalf("failed to create subscription: %v", err)
+		}
+	}
+
+	// publish runs the handler in a transaction, as writes do
+	publish := func(event events.Event, commit bool) {
+		t.Helper()
+		rollback := errors.New("rollback")
+		err := db.WithTx(ctx, func(ctx context.Context) error {
+			if err := notifier.Handler()(ctx, event); err != nil {
+				return err
+			}
+			if !commit {
+				return rollback
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, rollback) {
+			t.Fatalf("handler: %v", err)
+		}
+	}
+	next := func() received {
+		t.Helper()
+		select {
+		case r := <-deliveries:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification delivered")
+			return received{}
+		}
+	}
+
+	after := *product
+	after.Name, after.UnitPrice, after.Quantity = "Atlas II", 12, 4
+	updates := events.ProductUpdates(product, &after, "manual_update")
+
+	publish(updates[0], false) // Rolled back
+	publish(events.New(events.ProductUpdated{Product: after, Changes: events.DiffProducts(product, &models.Product{ID: product.ID, SKU: "BK-1", Name: "Renamed", UnitPrice: 10, Quantity: 5})}), true)
+	publish(updates[0], true)
+	publish(updates[1], true)
+
+	got := map[int]received{}
+	for i := 0; i < 2; i++ {
+		r := next()
+		got[r.notification.SubscriptionID] = r
+	}
+	price := got[prices.ID]
+	if len(price.notification.Changes) != 1 || price.notification.Changes[0].Field != "unit_price" || price.notification.Changes[0].New != 12.0 {
+		t.Errorf("price notification = %+v, want only unit_price", price.notification)
+	}
+	if price.notification.EventID != updates[0].ID || price.notification.SKU != "BK-1" {
+		t.Errorf("price notification = %+v, want event %s of BK-1", price.notification, updates[0].ID)
+	}
+	if price.signature != "sha256="+Sign([]byte("s3cret"), price.body) {
+		t.Errorf("signature = %q, want the HMAC of the body", price.signature)
+	}
+	quantity := got[stock.ID]
+	if len(quantity.notification.Changes) != 1 || quantity.notification.Changes[0].New != 4.0 || quantity.signature != "" {
+		t.Errorf("stock notification = %+v (signature %q), want an unsigned quantity change", quantity.notification, quantity.signature)
+	}
+
+	select {
+	case r := <-deliveries:
+		t.Errorf("unexpected notification %+v", r.notification)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
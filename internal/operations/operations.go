@@ -0,0 +1,247 @@
+// Package operations runs the long-running work of async endpoints, such as
+// exports and reindexes, in the background. Each run is an operation,
+// recorded in the operations table, that the endpoint answers 202 with and
+// the client polls at /api/v1/operations/{id} for its progress, a link to
+// its result, or why it failed.
+//
+// Operations can be cancelled from any instance. Cancellation is
+// cooperative: the operation's context is cancelled, and its work stops at
+// its next check of the context, reporting what it got done.
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+var operationsFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "operations_total",
+	Help: "Long-running operations finished, by kind and status (succeeded, failed, cancelled).",
+}, []string{"kind", "status"})
+
+// ErrCancelled is the cause of the context of a cancelled operation
+var ErrCancelled = errors.New("operation cancelled")
+
+// cancelPollInterval is how often a running operation checks whether it was
+// cancelled on another instance
+const cancelPollInterval = 2 * time.Second
+
+// Func does the work of an operation, reporting its progress through p, and
+// returns a link to its result. When ctx is cancelled it should stop and
+// return ctx's error, with a link to what it got done, if anything.
+type Func func(ctx context.Context, p *Progress) (result string, err error)
+
+// Runner runs operations until its context ends, which cancels the running
+// ones
+type Runner struct {
+	repo   repository.OperationRepository
+	logger *slog.Logger
+
+	ctx context.Context // Of operations, set by Start
+	wg  sync.WaitGroup
+
+	mu           sync.Mutex
+	cancels      map[int]context.CancelCauseFunc // Of operations running here
+	pollInterval time.Duration
+}
+
+func NewRunner(repo repository.OperationRepository, logger *slog.Logger) *Runner {
+	return &Runner{
+		repo:         repo,
+		logger:       logger,
+		ctx:          context.Background(),
+		cancels:      make(map[int]context.CancelCauseFunc),
+		pollInterval: cancelPollInterval,
+	}
+}
+
+// Start fails the operations a restart interrupted and makes operations
+// stop when ctx is cancelled
+func (r *Runner) Start(ctx context.Context) error {
+	r.ctx = ctx
+	failed, err := r.repo.FailInterrupted(ctx)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		r.logger.Warn("failed operations interrupted by a restart", "count", failed)
+	}
+	return nil
+}
+
+// Wait blocks until running operations have returned
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// Run records an operation of kind and runs fn for it in the background,
+// then records its outcome. It returns the operation as it started.
+func (r *Runner) Run(ctx context.Context, kind string, fn Func) (*models.Operation, error) {
+	op := &models.Operation{Kind: kind}
+	if err := r.repo.Create(ctx, op); err != nil {
+		return nil, err
+	}
+
+	started := *op
+	ctx, cancel := context.WithCancelCause(r.ctx)
+	r.mu.Lock()
+	r.cancels[op.ID] = cancel
+	r.mu.Unlock()
+
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		r.watch(ctx, op.ID, cancel)
+	}()
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.mu.Lock()
+			delete(r.cancels, op.ID)
+			r.mu.Unlock()
+			cancel(nil)
+		}()
+		r.run(ctx, op, fn)
+	}()
+	return &started, nil
+}
+
+// Cancel asks the operation id to stop, wherever it runs, and returns it.
+// It fails with "operation has finished" once it has.
+func (r *Runner) Cancel(ctx context.Context, id int) (*models.Operation, error) {
+	op, err := r.repo.RequestCancel(ctx, id)
+	if err != nil {
+		return op, err
+	}
+
+	// At once when it runs here; other instances notice within
+	// cancelPollInterval
+	r.mu.Lock()
+	cancel, ok := r.cancels[id]
+	r.mu.Unlock()
+	if ok {
+		cancel(ErrCancelled)
+	}
+	return op, nil
+}
+
+// watch cancels the operation id once it's asked to stop, until ctx ends
+func (r *Runner) watch(ctx context.Context, id int, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			requested, err := r.repo.CancelRequested(ctx, id)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.logger.Warn("failed to check operation cancellation", "operation", id, "error", err)
+				}
+				continue
+			}
+			if requested {
+				cancel(ErrCancelled)
+				return
+			}
+		}
+	}
+}
+
+func (r *Runner) run(ctx context.Context, op *models.Operation, fn Func) {
+	logger := r.logger.With("operation", op.ID, "kind", op.Kind)
+	progress := &Progress{ctx: r.ctx, repo: r.repo, op: op, logger: logger}
+
+	result, err := runSafely(ctx, fn, progress)
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+	switch {
+	case err == nil:
+		op.Status, op.Progress, op.Result = models.OperationSucceeded, 100, result
+		logger.Info("operation succeeded", "result", result)
+	case errors.Is(context.Cause(ctx), ErrCancelled):
+		op.Status, op.Result = models.OperationCancelled, result
+		logger.Info("operation cancelled", "checkpoint", op.Checkpoint, "result", result)
+	default:
+		msg := err.Error()
+		op.Status, op.Result, op.Error = models.OperationFailed, result, &msg
+		logger.Error("operation failed", "checkpoint", op.Checkpoint, "error", err)
+	}
+	operationsFinished.WithLabelValues(op.Kind, op.Status).Inc()
+
+	// Recorded even when the runner's context ended the operation
+	if err := r.repo.Finish(context.WithoutCancel(r.ctx), op); err != nil {
+		logger.Error("failed to record operation", "error", err)
+	}
+}
+
+// runSafely runs fn, failing the operation if it panics rather than
+// leaving it running until the next restart
+func runSafely(ctx context.Context, fn Func, p *Progress) (result string, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = errors.New("operation panicked")
+			p.logger.Error("operation panicked", "panic", v)
+		}
+	}()
+	return fn(ctx, p)
+}
+
+// progressInterval is how often progress is recorded at most, so work that
+// reports every item doesn't write for each
+const progressInterval = time.Second
+
+// Progress records how far an operation has got, as a percentage and a
+// checkpoint describing the work done. The latest is recorded when the
+// operation finishes, too, so a cancelled or failed one tells how far it got.
+type Progress struct {
+	ctx    context.Context
+	repo   repository.OperationRepository
+	op     *models.Operation
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	recorded time.Time
+}
+
+// Checkpoint records that the operation is percent done, having done what
+// checkpoint describes, if a second has passed since it was last recorded.
+// Failing to record it doesn't fail the operation.
+func (p *Progress) Checkpoint(percent int, checkpoint string) {
+	percent = min(max(percent, 0), 99) // 100 is for succeeded operations
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.op.Progress, p.op.Checkpoint = max(percent, p.op.Progress), checkpoint
+	if time.Since(p.recorded) < progressInterval {
+		return
+	}
+	if err := p.repo.SetProgress(p.ctx, p.op.ID, p.op.Progress, p.op.Checkpoint); err != nil {
+		p.logger.Warn("failed to record operation progress", "error", err)
+		return
+	}
+	p.recorded = time.Now()
+}
+
+// Of returns the progress callback of exports and reindexes, recording the
+// checkpoint of done items out of total as "done of total items", where
+// items is what they are, such as "products exported", or "done items" when
+// the total isn't known
+func (p *Progress) Of(items string) func(done, total int64) {
+	return func(done, total int64) {
+		if total <= 0 { // Unknown
+			p.Checkpoint(0, fmt.Sprintf("%d %s", done, items))
+			return
+		}
+		p.Checkpoint(int(done*100/total), fmt.Sprintf("%d of %d %s", done, total, items))
+	}
+}
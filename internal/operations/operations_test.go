@@ -0,0 +1,239 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// memoryRepo records operations in a slice
+type memoryRepo struct {
+	repository.OperationRepository
+
+	mu       sync.Mutex
+	ops      []models.Operation
+	progress []string // Every progress recorded, as "percent checkpoint"
+}
+
+func (r *memoryRepo) Create(ctx context.Context, op *models.Operation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	op.ID = len(r.ops) + 1
+	op.Status, op.Progress = models.OperationRunning, 0
+	r.ops = append(r.ops, *op)
+	return nil
+}
+
+func (r *memoryRepo) SetProgress(ctx context.Context, id, progress int, checkpoint string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops[id-1].Progress, r.ops[id-1].Checkpoint = progress, checkpoint
+	r.progress = append(r.progress, fmt.Sprintf("%d %s", progress, checkpoint))
+	return nil
+}
+
+func (r *memoryRepo) RequestCancel(ctx context.Context, id int) (*models.Operation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	op := r.ops[id-1]
+	if op.Done() {
+		return &op, errors.New("operation has finished")
+	}
+	now := time.Now()
+	r.ops[id-1].CancelRequestedAt = &now
+	op = r.ops[id-1]
+	return &op, nil
+}
+
+func (r *memoryRepo) CancelRequested(ctx context.Context, id int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ops[id-1].CancelRequestedAt != nil, nil
+}
+
+func (r *memoryRepo) Finish(ctx context.Context, op *models.Operation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops[op.ID-1] = *op
+	return nil
+}
+
+func (r *memoryRepo) FailInterrupted(ctx context.Context) (int64, error) {
+	return 2, nil
+}
+
+func (r *memoryRepo) op(id int) models.Operation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ops[id-1]
+}
+
+func newTestRunner(repo *memoryRepo) *Runner {
+	return NewRunner(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRun(t *testing.T) {
+	repo := &memoryRepo{}
+	runner := newTestRunner(repo)
+	if err := runner.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	release := make(chan struct{})
+	succeeded, err := runner.Run(context.Background(), models.OperationExport, func(ctx context.Context, p *Progress) (string, error) {
+		<-release
+		exported := p.Of("products exported")
+		exported(1, 4)
+		exported(2, 4) // Within a second of the last, so not recorded
+		return "/api/v1/admin/exports/7", nil
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if succeeded.Status != models.OperationRunning || succeeded.Kind != models.OperationExport || succeeded.Done() {
+		t.Errorf("operation = %+v, want a running export", succeeded)
+	}
+	failed, err := runner.Run(context.Background(), models.OperationReindex, func(ctx context.Context, p *Progress) (string, error) {
+		return "", errors.New("index unreachable")
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	panicked, err := runner.Run(context.Background(), models.OperationReindex, func(ctx context.Context, p *Progress) (string, error) {
+		panic("bug")
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	close(release)
+	runner.Wait()
+
+	if got := repo.op(succeeded.ID); got.Status != models.OperationSucceeded || got.Progress != 100 || got.Result != "/api/v1/admin/exports/7" || got.Error != nil {
+		t.Errorf("operation = %+v, want succeeded with its result", got)
+	}
+	if want := []string{"25 1 of 4 products exported"}; !reflect.DeepEqual(repo.progress, want) {
+		t.Errorf("progress recorded = %q, want %q", repo.progress, want)
+	}
+	if got := repo.op(failed.ID); got.Status != models.OperationFailed || got.Error == nil || *got.Error != "index unreachable" || got.Result != "" {
+		t.Errorf("operation = %+v, want failed with its error", got)
+	}
+	if got := repo.op(panicked.ID); got.Status != models.OperationFailed || got.Error == nil || *got.Error != "operation panicked" {
+		t.Errorf("operation = %+v, want failed by the panic", got)
+	}
+}
+
+func TestRun_CancelledByStop(t *testing.T) {
+	repo := &memoryRepo{}
+	runner := newTestRunner(repo)
+	ctx, stop := context.WithCancel(context.Background())
+	if err := runner.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	op, err := runner.Run(context.Background(), models.OperationReindex, func(ctx context.Context, p *Progress) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	stop()
+	runner.Wait()
+
+	if got := repo.op(op.ID); got.Status != models.OperationFailed || got.Error == nil || *got.Error != context.Canceled.Error() {
+		t.Errorf("operation = %+v, want failed as cancelled", got)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	repo := &memoryRepo{}
+	runner := newTestRunner(repo)
+	runner.pollInterval = time.Millisecond
+	if err := runner.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// Stops at its next check of ctx, with what it got done
+	work := func(ctx context.Context, p *Progress) (string, error) {
+		indexed := p.Of("products indexed")
+		indexed(3, 10)
+		<-ctx.Done()
+		return "/partial", ctx.Err()
+	}
+	here, err := runner.Run(context.Background(), models.OperationReindex, work)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	elsewhere, err := runner.Run(context.Background(), models.OperationReindex, work)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if op, err := runner.Cancel(context.Background(), here.ID); err != nil || op.CancelRequestedAt == nil {
+		t.Fatalf("Cancel = %+v, %v", op, err)
+	}
+	// As another instance would, noticed by polling
+	if _, err := repo.RequestCancel(context.Background(), elsewhere.ID); err != nil {
+		t.Fatal(err)
+	}
+	runner.Wait()
+
+	for _, op := range []*models.Operation{here, elsewhere} {
+		got := repo.op(op.ID)
+		if got.Status != models.OperationCancelled || got.Progress != 30 || got.Checkpoint != "3 of 10 products indexed" || got.Result != "/partial" || got.Error != nil {
+			t.Errorf("operation = %+v, want cancelled at its checkpoint with its partial result", got)
+		}
+	}
+	if _, err := runner.Cancel(context.Background(), here.ID); err == nil || err.Error() != "operation has finished" {
+		t.Errorf("Cancel of a cancelled operation = %v, want operation has finished", err)
+	}
+}
+
+func TestCancel_AfterSuccess(t *testing.T) {
+	repo := &memoryRepo{}
+	runner := newTestRunner(repo)
+	cancelled := make(chan struct{})
+	op, err := runner.Run(context.Background(), models.OperationExport, func(ctx context.Context, p *Progress) (string, error) {
+		<-cancelled
+		return "/api/v1/admin/exports/1", nil // Done regardless
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, err := runner.Cancel(context.Background(), op.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	close(cancelled)
+	runner.Wait()
+
+	if got := repo.op(op.ID); got.Status != models.OperationSucceeded {
+		t.Errorf("status = %s, want succeeded: work that finished isn't cancelled", got.Status)
+	}
+}
+
+func TestProgress_Checkpoint(t *testing.T) {
+	repo := &memoryRepo{}
+	op := &models.Operation{Kind: models.OperationExport}
+	if err := repo.Create(context.Background(), op); err != nil {
+		t.Fatal(err)
+	}
+	p := &Progress{ctx: context.Background(), repo: repo, op: op, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	p.Checkpoint(150, "all of it") // Capped, 100 is for succeeded operations
+	p.Checkpoint(50, "half")       // Within a second, only kept for Finish
+	p.recorded = p.recorded.Add(-progressInterval)
+	p.Of("products exported")(5, 0) // No total, no further
+	if want := []string{"99 all of it", "99 5 products exported"}; !reflect.DeepEqual(repo.progress, want) {
+		t.Errorf("progress recorded = %q, want %q", repo.progress, want)
+	}
+}
@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+var (
+	pendingMessages = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "outbox_pending_messages",
+		Help: "Outbox messages waiting to be relayed.",
+	})
+	relayedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "outbox_relayed_messages_total",
+		Help: "Outbox messages handed to a sink, by sink and result.",
+	}, []string{"sink", "result"})
+)
+
+// Sink delivers a batch of outbox messages to an external broker. Send must
+// only return nil once every message in the batch has been acknowledged.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, messages []*models.OutboxMessage) error
+	Close() error
+}
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// Relay moves committed outbox messages to a sink. Delivery is at-least-once:
+// a message is marked published only after the sink acknowledged it, so a
+// crash in between causes a redelivery, never a loss. Multiple relays can run
+// against the same table; rows are claimed with SKIP LOCKED.
+type Relay struct {
+	repo   repository.OutboxRepository
+	tx     repository.Transactor
+	sink   Sink
+	cfg    Config
+	notify chan struct{}
+	logger *slog.Logger
+}
+
+func NewRelay(repo repository.OutboxRepository, tx repository.Transactor, sink Sink, cfg Config, logger *slog.Logger) *Relay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Relay{
+		repo:   repo,
+		tx:     tx,
+		sink:   sink,
+		cfg:    cfg,
+		notify: make(chan struct{}, 1),
+		logger: logger.With("sink", sink.Name()),
+	}
+}
+
+// Writer returns an event handler that stores events in the outbox. Subscribe
+// it to the bus; because the bus runs inside the publishing transaction the
+// outbox rows commit or roll back together with the change itself.
+func (r *Relay) Writer() events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		msg, err := NewMessage(event)
+		if err != nil {
+			return err
+		}
+		if err := r.repo.Add(ctx, msg); err != nil {
+			return err
+		}
+
+		database.AfterCommit(ctx, r.Notify)
+		return nil
+	}
+}
+
+// Recorder returns an event handler that stores events in the outbox as
+// already published. Subscribe it instead of a relay's Writer when the change
+// feed is enabled without a broker: the feed reads the messages, and the
+// retention purge removes them.
+func Recorder(repo repository.OutboxRepository) events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		msg, err := NewMessage(event)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		msg.PublishedAt = &now
+		return repo.Add(ctx, msg)
+	}
+}
+
+// Notify wakes the relay so freshly committed messages don't wait for the
+// next poll
+func (r *Relay) Notify() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays messages until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	r.logger.Info("outbox relay started", "poll_interval", r.cfg.PollInterval.String())
+
+	for {
+		r.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("outbox relay stopped")
+			return
+		case <-ticker.C:
+		case <-r.notify:
+		}
+	}
+}
+
+// drain relays full batches until the outbox is empty or a batch fails
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := r.relayBatch(ctx)
+		if err != nil {
+			r.logger.Warn("outbox relay batch failed", "error", err)
+			break
+		}
+		if n < r.cfg.BatchSize {
+			break
+		}
+	}
+
+	if count, err := r.repo.CountPending(ctx); err == nil {
+		pendingMessages.Set(float64(count))
+	}
+}
+
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	var relayed int
+	var sendErr error
+
+	err := r.tx.WithTx(ctx, func(ctx context.Context) error {
+		messages, err := r.repo.FetchPending(ctx, r.cfg.BatchSize)
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(messages))
+		for i, msg := range messages {
+			ids[i] = msg.ID
+		}
+
+		// The failure is recorded and committed; the batch is retried on the next run
+		if sendErr = r.sink.Send(ctx, messages); sendErr != nil {
+			relayedMessages.WithLabelValues(r.sink.Name(), "error").Add(float64(len(messages)))
+			return r.repo.MarkFailed(ctx, ids, sendErr)
+		}
+
+		relayedMessages.WithLabelValues(r.sink.Name(), "success").Add(float64(len(messages)))
+		relayed = len(messages)
+		return r.repo.MarkPublished(ctx, ids)
+	})
+	if err == nil && sendErr != nil {
+		err = fmt.Errorf("sink %s: %w", r.sink.Name(), sendErr)
+	}
+
+	return relayed, err
+}
+
+// NewMessage converts an event into its outbox representation
+func NewMessage(event events.Event) (*models.OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OutboxMessage{
+		EventID:       event.ID,
+		EventType:     string(event.Type),
+		SchemaVersion: event.Version,
+		AggregateID:   event.Payload.AggregateID(),
+		Payload:       payload,
+	}, nil
+}
@@ -0,0 +1,267 @@
+// Package policy decides which roles may call which routes. Rules map a
+// method and path pattern to the permissions a request needs; roles are
+// granted permissions. Both come from configuration (POLICY_RULES and
+// POLICY_ROLES), so access changes with a config reload instead of edits to
+// handlers.
+//
+// Permissions are "resource:action" names. Routes no rule matches need
+// "<resource>:read" for GET, HEAD, and OPTIONS and "<resource>:write"
+// otherwise, where the resource is the path segment after /api/v1/. Grants
+// may use "*" for the resource, the action, or both.
+package policy
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Roles a request is made as
+const (
+	RoleAnonymous = "anonymous" // No credentials, or ones that aren't checked
+	RoleAdmin     = "admin"     // ADMIN_TOKEN
+	RoleToken     = "token"     // A scoped access token, still limited by its scope
+	RoleEditor    = "editor"    // A scoped token minted for an editor, whose product updates await approval
+	RoleApprover  = "approver"  // A scoped token minted for an approver, who reviews editors' updates
+)
+
+// Roles lists every role, in the order they are reported
+var Roles = []string{RoleAnonymous, RoleAdmin, RoleToken, RoleEditor, RoleApprover}
+
+// TokenRoles are the roles a scoped token can be minted for
+var TokenRoles = []string{RoleEditor, RoleApprover}
+
+// DefaultRule names the rule of a decision no configured rule matched
+const DefaultRule = "default"
+
+// Rule requires every one of Permissions for requests matching Method and
+// Pattern. In patterns, "*" or "{name}" matches one path segment and a
+// trailing "*" matches the rest of the path, if any.
+type Rule struct {
+	Method      string   `json:"method"` // "*" for any method
+	Pattern     string   `json:"pattern"`
+	Permissions []string `json:"permissions"` // Empty lets everyone through
+
+	segments []string
+}
+
+// String returns the rule as it is written in POLICY_RULES
+func (r Rule) String() string {
+	return r.Method + " " + r.Pattern
+}
+
+// Decision is the outcome of evaluating a request
+type Decision struct {
+	Role     string   `json:"role"`
+	Allowed  bool     `json:"allowed"`
+	Rule     string   `json:"rule"` // The matched rule, or DefaultRule
+	Required []string `json:"required"`
+	Missing  []string `json:"missing,omitempty"`
+}
+
+type Policy struct {
+	rules []Rule              // In evaluation order, most specific first
+	roles map[string][]string // Granted permissions of every role
+}
+
+// New parses rules ("METHOD /pattern" to space-separated permissions) and
+// role grants (role to space-separated permissions). Roles without a grant
+// get "*", so an empty policy allows what the routes allowed before it.
+func New(rules, roles map[string]string) (*Policy, error) {
+	p := &Policy{roles: make(map[string][]string, len(Roles))}
+	for _, role := range Roles {
+		p.roles[role] = []string{"*"}
+	}
+
+	for role, grants := range roles {
+		if !isRole(role) {
+			return nil, fmt.Errorf("unknown role %q, expected one of %s", role, strings.Join(Roles, ", "))
+		}
+		permissions, err := parsePermissions(grants)
+		if err != nil {
+			return nil, fmt.Errorf("role %s: %w", role, err)
+		}
+		p.roles[role] = permissions
+	}
+
+	for key, required := range rules {
+		method, pattern, ok := strings.Cut(strings.TrimSpace(key), " ")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("rule %q: expected \"METHOD /path\"", key)
+		}
+		method = strings.ToUpper(method)
+		if method != "*" && !isMethod(method) {
+			return nil, fmt.Errorf("rule %q: unknown method %s", key, method)
+		}
+		permissions, err := parsePermissions(required)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", key, err)
+		}
+		p.rules = append(p.rules, Rule{Method: method, Pattern: pattern, Permissions: permissions, segments: split(pattern)})
+	}
+	sort.Slice(p.rules, func(i, j int) bool { return moreSpecific(p.rules[i], p.rules[j]) })
+
+	return p, nil
+}
+
+// Rules returns the configured rules in evaluation order
+func (p *Policy) Rules() []Rule {
+	return p.rules
+}
+
+// Grants returns the permissions granted to role
+func (p *Policy) Grants(role string) []string {
+	return p.roles[role]
+}
+
+// Decide evaluates a request to path with method by role
+func (p *Policy) Decide(role, method, path string) Decision {
+	rule, required := p.required(method, path)
+	decision := Decision{Role: role, Rule: rule, Required: required}
+	for _, permission := range required {
+		if !granted(p.roles[role], permission) {
+			decision.Missing = append(decision.Missing, permission)
+		}
+	}
+	decision.Allowed = len(decision.Missing) == 0
+	return decision
+}
+
+// required returns the rule matching a request and the permissions it needs
+func (p *Policy) required(method, path string) (string, []string) {
+	segments := split(path)
+	for _, rule := range p.rules {
+		if (rule.Method == "*" || rule.Method == method) && match(rule.segments, segments) {
+			return rule.String(), rule.Permissions
+		}
+	}
+
+	if permission := DefaultPermission(method, path); permission != "" {
+		return DefaultRule, []string{permission}
+	}
+	return DefaultRule, []string{}
+}
+
+// DefaultPermission returns the permission a request needs when no rule
+// matches it: "<resource>:read" or "<resource>:write" under /api/v1/, and ""
+// for other paths
+func DefaultPermission(method, path string) string {
+	segments := split(path)
+	if len(segments) < 3 || segments[0] != "api" || segments[1] != "v1" {
+		return ""
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return segments[2] + ":read"
+	}
+	return segments[2] + ":write"
+}
+
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func match(pattern, segments []string) bool {
+	for i, segment := range pattern {
+		if segment == "*" && i == len(pattern)-1 {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if segment != "*" && !isParam(segment) && segment != segments[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(segments)
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// moreSpecific orders rules with more literal segments first, then without a
+// trailing catch-all, then with a method, and by pattern to stay stable
+func moreSpecific(a, b Rule) bool {
+	if la, lb := literals(a.segments), literals(b.segments); la != lb {
+		return la > lb
+	}
+	if ta, tb := catchAll(a.segments), catchAll(b.segments); ta != tb {
+		return !ta
+	}
+	if (a.Method == "*") != (b.Method == "*") {
+		return b.Method == "*"
+	}
+	if a.Pattern != b.Pattern {
+		return a.Pattern < b.Pattern
+	}
+	return a.Method < b.Method
+}
+
+func literals(segments []string) int {
+	n := 0
+	for _, segment := range segments {
+		if segment != "*" && !isParam(segment) {
+			n++
+		}
+	}
+	return n
+}
+
+func catchAll(segments []string) bool {
+	return len(segments) > 0 && segments[len(segments)-1] == "*"
+}
+
+func granted(grants []string, permission string) bool {
+	resource, action, _ := strings.Cut(permission, ":")
+	for _, grant := range grants {
+		if grant == "*" {
+			return true
+		}
+		r, a, _ := strings.Cut(grant, ":")
+		if (r == "*" || r == resource) && (a == "*" || a == action) {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePermissions(value string) ([]string, error) {
+	permissions := strings.Fields(value)
+	for _, permission := range permissions {
+		if permission == "*" {
+			continue
+		}
+		resource, action, ok := strings.Cut(permission, ":")
+		if !ok || resource == "" || action == "" || strings.Contains(action, ":") {
+			return nil, fmt.Errorf("invalid permission %q, expected resource:action or *", permission)
+		}
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return permissions, nil
+}
+
+func isRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func isMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
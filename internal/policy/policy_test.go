@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPolicy_Decide(t *testing.T) {
+	p, err := New(map[string]string{
+		"GET /api/v1/health":               "",
+		"* /api/v1/products/{id}/*":        "products:read",
+		"PUT /api/v1/products/{id}":        "products:write catalog:edit",
+		"DELETE /api/v1/products/*":        "products:delete",
+		"post /api/v1/integrations/orders": "orders:receive",
+	}, map[string]string{
+		RoleAnonymous: "*:read",
+		RoleToken:     "products:* catalog:edit",
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		role, method, path string
+		rule               string
+		missing            []string
+	}{
+		{RoleAnonymous, "GET", "/api/v1/products", DefaultRule, nil},
+		{RoleAnonymous, "POST", "/api/v1/products/", DefaultRule, []string{"products:write"}},
+		{RoleAnonymous, "GET", "/api/v1/health", "GET /api/v1/health", nil},
+		{RoleAnonymous, "GET", "/metrics", DefaultRule, nil},
+		{RoleAnonymous, "PUT", "/api/v1/products/7", "PUT /api/v1/products/{id}", []string{"products:write", "catalog:edit"}},
+		{RoleToken, "PUT", "/api/v1/products/7", "PUT /api/v1/products/{id}", nil},
+		{RoleAnonymous, "POST", "/api/v1/products/7/price", "* /api/v1/products/{id}/*", nil},
+		{RoleAnonymous, "GET", "/api/v1/products/7", "* /api/v1/products/{id}/*", nil},
+		{RoleToken, "DELETE", "/api/v1/products/7", "DELETE /api/v1/products/*", nil},
+		{RoleAnonymous, "POST", "/api/v1/integrations/orders", "POST /api/v1/integrations/orders", []string{"orders:receive"}},
+		{RoleAdmin, "POST", "/api/v1/admin/config/reload", DefaultRule, nil},
+		{RoleToken, "GET", "/api/v1/admin/log-level", DefaultRule, []string{"admin:read"}},
+	}
+	for _, tt := range tests {
+		d := p.Decide(tt.role, tt.method, tt.path)
+		if d.Rule != tt.rule || !reflect.DeepEqual(d.Missing, tt.missing) || d.Allowed != (len(tt.missing) == 0) {
+			t.Errorf("%s %s as %s = %+v, want rule %q missing %v", tt.method, tt.path, tt.role, d, tt.rule, tt.missing)
+		}
+	}
+
+	if got := p.Rules()[0].String(); got != "POST /api/v1/integrations/orders" {
+		t.Errorf("first rule = %s, want the one with the most literal segments", got)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	invalid := []struct {
+		rules, roles map[string]string
+	}{
+		{map[string]string{"/api/v1/products": "products:read"}, nil},
+		{map[string]string{"FETCH /api/v1/products": "products:read"}, nil},
+		{map[string]string{"GET api/v1/products": "products:read"}, nil},
+		{map[string]string{"GET /api/v1/products": "products"}, nil},
+		{nil, map[string]string{"support": "*"}},
+		{nil, map[string]string{RoleToken: "products:read:all"}},
+	}
+	for _, tt := range invalid {
+		if _, err := New(tt.rules, tt.roles); err == nil {
+			t.Errorf("New(%v, %v) succeeded, want an error", tt.rules, tt.roles)
+		}
+	}
+}
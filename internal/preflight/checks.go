@@ -0,0 +1,115 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/config"
+	"{{MODULE_NAME}}/internal/database"
+)
+
+// Config reports settings that are valid but likely wrong for the environment
+func Config(cfg *config.Config) Check {
+	return Check{Name: "config", Run: func(ctx context.Context) (Status, string) {
+		var warnings []string
+		if cfg.AdminToken == "" && !cfg.IsDevelopment() {
+			warnings = append(warnings, "ADMIN_TOKEN is not set, the admin API is disabled")
+		}
+		if cfg.OrderWebhookSecret == "" {
+			warnings = append(warnings, "ORDER_WEBHOOK_SECRET is not set, order webhooks are rejected")
+		}
+		if cfg.MaintenanceMode || cfg.ReadOnly {
+			warnings = append(warnings, "writes are refused (MAINTENANCE_MODE or READ_ONLY)")
+		}
+		if cfg.EmbeddedPostgres && cfg.EmbeddedPostgresDataDir == "" {
+			warnings = append(warnings, "EMBEDDED_POSTGRES is on without a data directory, data is lost at shutdown")
+		}
+
+		if len(warnings) > 0 {
+			return StatusWarn, strings.Join(warnings, "; ")
+		}
+		return StatusOK, fmt.Sprintf("environment %s", cfg.Environment)
+	}}
+}
+
+// Database checks that the database answers queries
+func Database(db *database.DB) Check {
+	return Check{Name: "database", Run: func(ctx context.Context) (Status, string) {
+		var version string
+		if err := db.QueryRowContext(ctx, db.Dialect().VersionQuery()).Scan(&version); err != nil {
+			return StatusFail, err.Error()
+		}
+		return StatusOK, version
+	}}
+}
+
+// Extensions checks that the named extensions are installed
+func Extensions(db *database.DB, names []string) Check {
+	return Check{Name: "extensions", Run: func(ctx context.Context) (Status, string) {
+		if len(names) == 0 {
+			return StatusOK, "none required"
+		}
+		if db.Dialect() != database.Postgres {
+			return StatusFail, "extensions are only available on PostgreSQL"
+		}
+
+		rows, err := db.QueryContext(ctx, "SELECT extname FROM pg_extension")
+		if err != nil {
+			return StatusFail, err.Error()
+		}
+		defer rows.Close()
+
+		installed := make(map[string]bool)
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return StatusFail, err.Error()
+			}
+			installed[name] = true
+		}
+		if err := rows.Err(); err != nil {
+			return StatusFail, err.Error()
+		}
+
+		var missing []string
+		for _, name := range names {
+			if !installed[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return StatusFail, "not installed: " + strings.Join(missing, ", ")
+		}
+		return StatusOK, strings.Join(names, ", ")
+	}}
+}
+
+// Migrations compares applied migrations with the files in migrationsPath.
+// Pending migrations are a warning, as they are applied on start-up;
+// migrations unknown to this build fail the check.
+func Migrations(db *database.DB, migrationsPath string) Check {
+	return Check{Name: "migrations", Run: func(ctx context.Context) (Status, string) {
+		pending, unknown, err := database.MigrationStatus(db, migrationsPath)
+		if err != nil {
+			return StatusFail, err.Error()
+		}
+		if len(unknown) > 0 {
+			return StatusFail, "applied but not in this build: " + strings.Join(unknown, ", ")
+		}
+		if len(pending) > 0 {
+			return StatusWarn, "pending, applied on start-up: " + strings.Join(pending, ", ")
+		}
+		return StatusOK, "up to date"
+	}}
+}
+
+// Schema checks the scanned tables against their models, see VerifySchema
+func Schema(db *database.DB, tables map[string]interface{}) Check {
+	return Check{Name: "schema", Run: func(ctx context.Context) (Status, string) {
+		if err := db.VerifySchema(ctx, tables); err != nil {
+			return StatusFail, err.Error()
+		}
+		return StatusOK, fmt.Sprintf("%d tables match their models", len(tables))
+	}}
+}
@@ -0,0 +1,89 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn" // Worth a look, but doesn't fail the report
+	StatusFail Status = "fail"
+)
+
+// Check verifies one precondition for running the service
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (Status, string)
+}
+
+type Result struct {
+	Name     string `json:"name"`
+	Status   Status `json:"status"`
+	Message  string `json:"message,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	OK      bool     `json:"ok"` // No check failed
+	Results []Result `json:"results"`
+}
+
+// Run runs the checks in order, each with its own timeout, and collects
+// their results. Every check runs even if an earlier one failed.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) Report {
+	report := Report{OK: true}
+
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		status, message := check.Run(checkCtx)
+		cancel()
+
+		if status == StatusFail {
+			report.OK = false
+		}
+		report.Results = append(report.Results, Result{
+			Name:     check.Name,
+			Status:   status,
+			Message:  message,
+			Duration: time.Since(start).Round(time.Millisecond).String(),
+		})
+	}
+
+	return report
+}
+
+// WriteText writes the report as an aligned table for people
+func (r Report) WriteText(w io.Writer) error {
+	width := 0
+	for _, result := range r.Results {
+		width = max(width, len(result.Name))
+	}
+
+	var b strings.Builder
+	for _, result := range r.Results {
+		fmt.Fprintf(&b, "[%-4s] %-*s  %s (%s)\n", result.Status, width, result.Name, result.Message, result.Duration)
+	}
+	if r.OK {
+		b.WriteString("preflight passed\n")
+	} else {
+		b.WriteString("preflight FAILED\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the report as JSON for CI pipelines
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
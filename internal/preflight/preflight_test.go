@@ -0,0 +1,85 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func check(name string, status Status, message string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) (Status, string) {
+		return status, message
+	}}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		wantOK bool
+	}{
+		{"all ok", []Check{check("a", StatusOK, ""), check("b", StatusOK, "")}, true},
+		{"warnings pass", []Check{check("a", StatusOK, ""), check("b", StatusWarn, "hmm")}, true},
+		{"failure fails", []Check{check("a", StatusFail, "down"), check("b", StatusOK, "")}, false},
+		{"no checks", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Run(context.Background(), tt.checks, time.Second)
+			if report.OK != tt.wantOK {
+				t.Errorf("OK = %v, want %v", report.OK, tt.wantOK)
+			}
+			if len(report.Results) != len(tt.checks) {
+				t.Errorf("got %d results, want %d", len(report.Results), len(tt.checks))
+			}
+		})
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	slow := Check{Name: "slow", Run: func(ctx context.Context) (Status, string) {
+		<-ctx.Done()
+		return StatusFail, ctx.Err().Error()
+	}}
+
+	report := Run(context.Background(), []Check{slow}, 10*time.Millisecond)
+	if report.OK {
+		t.Fatal("expected the timed out check to fail the report")
+	}
+	if report.Results[0].Message != context.DeadlineExceeded.Error() {
+		t.Errorf("Message = %q", report.Results[0].Message)
+	}
+}
+
+func TestReport_Write(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		check("database", StatusOK, "PostgreSQL 16"),
+		check("migrations", StatusFail, "unknown: 099_future"),
+	}, time.Second)
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	for _, want := range []string{"[ok  ] database", "[fail] migrations", "unknown: 099_future", "preflight FAILED"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text report missing %q:\n%s", want, text.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded.OK || len(decoded.Results) != 2 || decoded.Results[1].Status != StatusFail {
+		t.Errorf("decoded = %+v", decoded)
+	}
+}
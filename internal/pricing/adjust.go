@@ -0,0 +1,24 @@
+package pricing
+
+import (
+	"math"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// Adjust applies a price rule to a unit price, a valid rule being assumed.
+// The percentage is rounded to the cent by the rules' rounding mode; an
+// ending then moves the price to the nearest one ending in those cents,
+// halfway prices going up, and never below them.
+func (r *Rules) Adjust(unitPrice float64, rule models.PriceRule) float64 {
+	cents := int64(math.Round(unitPrice * 100))
+	percent := int64(math.Round(rule.Percent * 100)) // Hundredths of a percent
+	cents = r.round(cents*(10000+percent), 10000)
+
+	if rule.EndWith != nil {
+		ending := int64(math.Round(*rule.EndWith * 100))
+		// Above -100, so prices under the ending truncate to it
+		cents = (cents-ending+50)/100*100 + ending
+	}
+	return float64(cents) / 100
+}
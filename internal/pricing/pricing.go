@@ -0,0 +1,242 @@
+// Package pricing computes what a product costs in a sales region: its net
+// unit price plus the region's tax, rounded to the catalog currency. Amounts
+// are computed in integer minor units, so rounding is exact rather than
+// subject to float error.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// Rounding says how amounts between two minor units are rounded
+type Rounding string
+
+const (
+	HalfUp   Rounding = "half_up"   // Halves away from zero, the usual commercial rounding
+	HalfEven Rounding = "half_even" // Halves to the even neighbour (banker's rounding)
+	Down     Rounding = "down"      // Towards zero
+	Up       Rounding = "up"        // Away from zero
+)
+
+var ErrUnknownRegion = errors.New("unknown region")
+
+// minorUnits are the decimals of currencies that don't have two (ISO 4217)
+var minorUnits = map[string]int{
+	"BHD": 3, "CLP": 0, "ISK": 0, "JOD": 3, "JPY": 0,
+	"KRW": 0, "KWD": 3, "OMR": 3, "TND": 3, "UGX": 0, "VND": 0,
+}
+
+// Config describes the tax rules, as set through the TAX_* and PRICE_*
+// environment variables
+type Config struct {
+	Currency string   // ISO 4217 code of unit prices, "USD" when empty
+	Rounding Rounding // HalfUp when empty
+
+	// Rates are tax percentages keyed by region ("DE") or by region and
+	// category ("DE/books"). A category rate overrides its region's rate;
+	// every region needs a rate of its own.
+	Rates map[string]string
+
+	InclusiveRegions []string // Regions whose prices are shown with tax included
+	DefaultRegion    string   // Used when a request names none, empty requires one
+}
+
+// Rules price products under a Config
+type Rules struct {
+	currency      string
+	exponent      int
+	rounding      Rounding
+	regions       map[string]*region
+	defaultRegion string
+}
+
+type region struct {
+	rate       int64            // Hundredths of a percent
+	categories map[string]int64 // Keyed by lowercase category
+	inclusive  bool
+}
+
+// New validates cfg and returns its rules
+func New(cfg Config) (*Rules, error) {
+	r := &Rules{
+		currency: strings.ToUpper(cfg.Currency),
+		rounding: cfg.Rounding,
+		regions:  make(map[string]*region),
+	}
+	if r.currency == "" {
+		r.currency = "USD"
+	}
+	if len(r.currency) != 3 || strings.Trim(r.currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return nil, fmt.Errorf("invalid currency %q: must be a three-letter ISO 4217 code", cfg.Currency)
+	}
+	r.exponent = 2
+	if n, ok := minorUnits[r.currency]; ok {
+		r.exponent = n
+	}
+
+	switch r.rounding {
+	case "":
+		r.rounding = HalfUp
+	case HalfUp, HalfEven, Down, Up:
+	default:
+		return nil, fmt.Errorf("invalid rounding %q: must be half_up, half_even, down, or up", cfg.Rounding)
+	}
+
+	categoryRates := make(map[string]int64)
+	for key, value := range cfg.Rates {
+		rate, err := parseRate(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tax rate for %s: %w", key, err)
+		}
+		code, category, isCategory := strings.Cut(key, "/")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || (isCategory && strings.TrimSpace(category) == "") {
+			return nil, fmt.Errorf("invalid tax rate key %q: must be REGION or REGION/category", key)
+		}
+		if isCategory {
+			categoryRates[code+"/"+strings.ToLower(strings.TrimSpace(category))] = rate
+			continue
+		}
+		r.regions[code] = &region{rate: rate, categories: make(map[string]int64)}
+	}
+	for key, rate := range categoryRates {
+		code, category, _ := strings.Cut(key, "/")
+		reg, ok := r.regions[code]
+		if !ok {
+			return nil, fmt.Errorf("invalid tax rate for %s: region %s has no rate of its own", key, code)
+		}
+		reg.categories[category] = rate
+	}
+
+	for _, code := range cfg.InclusiveRegions {
+		reg, ok := r.regions[strings.ToUpper(code)]
+		if !ok {
+			return nil, fmt.Errorf("tax inclusive region %s has no tax rate", code)
+		}
+		reg.inclusive = true
+	}
+
+	if cfg.DefaultRegion != "" {
+		r.defaultRegion = strings.ToUpper(cfg.DefaultRegion)
+		if _, ok := r.regions[r.defaultRegion]; !ok {
+			return nil, fmt.Errorf("default tax region %s has no tax rate", cfg.DefaultRegion)
+		}
+	}
+
+	return r, nil
+}
+
+// parseRate parses a percentage with up to two decimals into hundredths of
+// a percent
+func parseRate(value string) (int64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("%q must be a percentage between 0 and 100", value)
+	}
+	hundredths := math.Round(percent * 100)
+	if math.Abs(hundredths-percent*100) > 1e-6 {
+		return 0, fmt.Errorf("%q has more than two decimals", value)
+	}
+	return int64(hundredths), nil
+}
+
+// Regions returns the configured region codes, sorted
+func (r *Rules) Regions() []string {
+	codes := make([]string, 0, len(r.regions))
+	for code := range r.regions {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// DefaultRegion returns the region used when none is named, or ""
+func (r *Rules) DefaultRegion() string {
+	return r.defaultRegion
+}
+
+// Price computes the product's price in a region, "" for the default one.
+// inclusive overrides the region's presentation when not nil. An unknown
+// region fails with ErrUnknownRegion.
+func (r *Rules) Price(product *models.Product, regionCode string, inclusive *bool) (*models.Price, error) {
+	code := strings.ToUpper(regionCode)
+	if code == "" {
+		code = r.defaultRegion
+	}
+	reg, ok := r.regions[code]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownRegion, regionCode)
+	}
+
+	rate := reg.rate
+	category := ""
+	if categoryRate, ok := reg.categories[strings.ToLower(product.Category)]; ok && product.Category != "" {
+		rate = categoryRate
+		category = product.Category
+	}
+
+	// Unit prices are stored with two decimals; cents is exact
+	cents := int64(math.Round(product.UnitPrice * 100))
+	scale := int64(math.Pow10(r.exponent))
+	net := r.round(cents*scale, 100)
+	tax := r.round(net*rate, 10000)
+	gross := net + tax
+
+	included := reg.inclusive
+	if inclusive != nil {
+		included = *inclusive
+	}
+	amount := net
+	if included {
+		amount = gross
+	}
+
+	major := func(minor int64) float64 { return float64(minor) / float64(scale) }
+	return &models.Price{
+		ProductID:   product.ID,
+		Region:      code,
+		Category:    category,
+		Currency:    r.currency,
+		TaxRate:     float64(rate) / 100,
+		TaxIncluded: included,
+		Amount:      major(amount),
+		Net:         major(net),
+		Tax:         major(tax),
+		Gross:       major(gross),
+		Rounding:    string(r.rounding),
+	}, nil
+}
+
+// round divides n by d, d positive, rounding the quotient by the rules'
+// rounding mode. Negative amounts round symmetrically to positive ones.
+func (r *Rules) round(n, d int64) int64 {
+	if n < 0 {
+		return -r.round(-n, d)
+	}
+
+	q, rem := n/d, n%d
+	if rem == 0 {
+		return q
+	}
+	switch r.rounding {
+	case Down:
+	case Up:
+		q++
+	case HalfEven:
+		if 2*rem > d || (2*rem == d && q%2 == 1) {
+			q++
+		}
+	default:
+		if 2*rem >= d {
+			q++
+		}
+	}
+	return q
+}
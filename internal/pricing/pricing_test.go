@@ -0,0 +1,179 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func mustRules(t *testing.T, cfg Config) *Rules {
+	t.Helper()
+	rules, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return rules
+}
+
+func TestRules_Price(t *testing.T) {
+	rules := mustRules(t, Config{
+		Currency:         "EUR",
+		Rates:            map[string]string{"DE": "19", "DE/Books": "7", "US": "0", "FR": "5.5"},
+		InclusiveRegions: []string{"de"},
+		DefaultRegion:    "US",
+	})
+	yes, no := true, false
+
+	tests := []struct {
+		name      string
+		product   models.Product
+		region    string
+		inclusive *bool
+		want      models.Price
+	}{
+		{
+			name:    "region rate, inclusive by default",
+			product: models.Product{ID: 1, UnitPrice: 10},
+			region:  "de",
+			want:    models.Price{Region: "DE", TaxRate: 19, TaxIncluded: true, Amount: 11.9, Net: 10, Tax: 1.9, Gross: 11.9},
+		},
+		{
+			name:    "category rate, matched case-insensitively",
+			product: models.Product{ID: 1, UnitPrice: 9.99, Category: "books"},
+			region:  "DE",
+			want:    models.Price{Region: "DE", Category: "books", TaxRate: 7, TaxIncluded: true, Amount: 10.69, Net: 9.99, Tax: 0.7, Gross: 10.69},
+		},
+		{
+			name:      "presentation overridden",
+			product:   models.Product{ID: 1, UnitPrice: 10},
+			region:    "DE",
+			inclusive: &no,
+			want:      models.Price{Region: "DE", TaxRate: 19, Amount: 10, Net: 10, Tax: 1.9, Gross: 11.9},
+		},
+		{
+			name:    "default region",
+			product: models.Product{ID: 1, UnitPrice: 10, Category: "books"},
+			want:    models.Price{Region: "US", Amount: 10, Net: 10, Gross: 10},
+		},
+		{
+			name:      "fractional rate",
+			product:   models.Product{ID: 1, UnitPrice: 0.99},
+			region:    "FR",
+			inclusive: &yes,
+			want:      models.Price{Region: "FR", TaxRate: 5.5, TaxIncluded: true, Amount: 1.04, Net: 0.99, Tax: 0.05, Gross: 1.04},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rules.Price(&tt.product, tt.region, tt.inclusive)
+			if err != nil {
+				t.Fatalf("Price: %v", err)
+			}
+			tt.want.ProductID = 1
+			tt.want.Currency = "EUR"
+			tt.want.Rounding = "half_up"
+			if *got != tt.want {
+				t.Errorf("Price = %+v\nwant    %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := rules.Price(&models.Product{}, "XX", nil); !errors.Is(err, ErrUnknownRegion) {
+		t.Errorf("unknown region error = %v, want ErrUnknownRegion", err)
+	}
+}
+
+func TestRules_Rounding(t *testing.T) {
+	// 0.50 at 5% is 0.025 of tax, exactly half a cent; 0.70 at 5% is 0.035
+	tests := []struct {
+		rounding Rounding
+		price    float64
+		wantTax  float64
+	}{
+		{HalfUp, 0.5, 0.03},
+		{HalfUp, 0.7, 0.04},
+		{HalfEven, 0.5, 0.02},
+		{HalfEven, 0.7, 0.04},
+		{Down, 0.7, 0.03},
+		{Up, 0.61, 0.04}, // 0.0305
+	}
+
+	for _, tt := range tests {
+		rules := mustRules(t, Config{Rounding: tt.rounding, Rates: map[string]string{"NL": "5"}})
+		got, err := rules.Price(&models.Product{UnitPrice: tt.price}, "NL", nil)
+		if err != nil {
+			t.Fatalf("Price: %v", err)
+		}
+		if got.Tax != tt.wantTax {
+			t.Errorf("%s: tax on %.2f = %v, want %v", tt.rounding, tt.price, got.Tax, tt.wantTax)
+		}
+	}
+}
+
+func TestRules_CurrencyMinorUnits(t *testing.T) {
+	rules := mustRules(t, Config{Currency: "jpy", Rates: map[string]string{"JP": "10"}})
+
+	got, err := rules.Price(&models.Product{UnitPrice: 1234.5}, "JP", nil)
+	if err != nil {
+		t.Fatalf("Price: %v", err)
+	}
+	// 1234.50 rounds to 1235 yen before tax is applied
+	if got.Currency != "JPY" || got.Net != 1235 || got.Tax != 124 || got.Gross != 1359 {
+		t.Errorf("Price = %+v, want 1235 + 124 = 1359 JPY", got)
+	}
+}
+
+func TestNew_RejectsInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"currency":              {Currency: "EURO"},
+		"rounding":              {Rounding: "nearest"},
+		"rate":                  {Rates: map[string]string{"DE": "nineteen"}},
+		"rate over 100":         {Rates: map[string]string{"DE": "119"}},
+		"rate decimals":         {Rates: map[string]string{"DE": "19.125"}},
+		"category key":          {Rates: map[string]string{"DE/": "7"}},
+		"category alone":        {Rates: map[string]string{"DE/books": "7"}},
+		"inclusive region rate": {Rates: map[string]string{"DE": "19"}, InclusiveRegions: []string{"FR"}},
+		"default region rate":   {Rates: map[string]string{"DE": "19"}, DefaultRegion: "FR"},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%s: New accepted %+v", name, cfg)
+		}
+	}
+
+	if _, err := New(Config{}); err != nil {
+		t.Errorf("New rejected the zero config: %v", err)
+	}
+}
+
+func TestRules_Adjust(t *testing.T) {
+	rules := mustRules(t, Config{})
+	ending := func(cents float64) *float64 { return &cents }
+
+	for _, tt := range []struct {
+		price float64
+		rule  models.PriceRule
+		want  float64
+	}{
+		{10, models.PriceRule{Percent: 5}, 10.5},
+		{9.99, models.PriceRule{Percent: 5}, 10.49},    // 10.4895
+		{9.99, models.PriceRule{Percent: -10}, 8.99},   // 8.991
+		{9.99, models.PriceRule{Percent: 12.5}, 11.24}, // 11.23875
+		{10, models.PriceRule{Percent: 5, EndWith: ending(0.99)}, 10.99},
+		{10.48, models.PriceRule{EndWith: ending(0.99)}, 9.99},
+		{10.5, models.PriceRule{EndWith: ending(0.99)}, 10.99}, // Halfway goes up
+		{12.34, models.PriceRule{EndWith: ending(0)}, 12},
+		{0.2, models.PriceRule{EndWith: ending(0.99)}, 0.99}, // Never below the ending
+		{0, models.PriceRule{Percent: 50}, 0},
+	} {
+		if got := rules.Adjust(tt.price, tt.rule); got != tt.want {
+			t.Errorf("Adjust(%v, %+v) = %v, want %v", tt.price, tt.rule, got, tt.want)
+		}
+	}
+
+	down := mustRules(t, Config{Rounding: Down})
+	if got := down.Adjust(9.99, models.PriceRule{Percent: 5}); got != 10.48 {
+		t.Errorf("Adjust rounding down = %v, want 10.48", got)
+	}
+}
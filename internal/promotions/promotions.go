@@ -0,0 +1,207 @@
+// Package promotions computes effective prices: a product's unit price after
+// the promotions in effect at a point in time.
+//
+// Stacking rules: promotions apply in order of descending priority, then
+// ascending ID. Stackable promotions combine, each discounting the price the
+// previous ones left. A promotion that isn't stackable never combines with
+// another; the product gets either the best single non-stackable promotion or
+// all stackable ones together, whichever makes it cheaper (the stackable ones
+// on a tie). Prices never go below zero.
+package promotions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// ValidationError describes a promotion that can't be saved
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Validate checks a promotion before it's saved, and normalizes its target
+// and times (to UTC)
+func Validate(p *models.Promotion) error {
+	invalid := func(format string, args ...interface{}) error {
+		return &ValidationError{Message: fmt.Sprintf(format, args...)}
+	}
+
+	if strings.TrimSpace(p.Name) == "" {
+		return invalid("Promotion name is required")
+	}
+
+	switch p.Kind {
+	case models.PromotionPercentage:
+		if p.Value <= 0 || p.Value > 100 {
+			return invalid("A percentage promotion's value must be above 0 and at most 100")
+		}
+	case models.PromotionFixed:
+		if p.Value <= 0 {
+			return invalid("A fixed promotion's value must be positive")
+		}
+	default:
+		return invalid("Invalid kind: must be percentage or fixed")
+	}
+	if cents := p.Value * 100; math.Abs(cents-math.Round(cents)) > 1e-6 {
+		return invalid("A promotion's value has at most two decimals")
+	}
+
+	p.Target = strings.TrimSpace(p.Target)
+	switch p.Scope {
+	case models.PromotionScopeAll:
+		if p.Target != "" {
+			return invalid("A promotion with scope all has no target")
+		}
+	case models.PromotionScopeProduct:
+		if id, err := strconv.Atoi(p.Target); err != nil || id < 1 {
+			return invalid("A promotion with scope product needs a product ID as its target")
+		}
+	case models.PromotionScopeCategory, models.PromotionScopeTag:
+		if p.Target == "" {
+			return invalid("A promotion with scope %s needs a target", p.Scope)
+		}
+		p.Target = strings.ToLower(p.Target)
+	default:
+		return invalid("Invalid scope: must be all, product, category, or tag")
+	}
+
+	if p.StartsAt != nil {
+		startsAt := p.StartsAt.UTC()
+		p.StartsAt = &startsAt
+	}
+	if p.EndsAt != nil {
+		endsAt := p.EndsAt.UTC()
+		p.EndsAt = &endsAt
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return invalid("A promotion must end after it starts")
+	}
+
+	return nil
+}
+
+// IsValidationError reports whether err came from Validate
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
+
+// Active reports whether a promotion is in effect at
+func Active(p *models.Promotion, at time.Time) bool {
+	return (p.StartsAt == nil || !p.StartsAt.After(at)) && (p.EndsAt == nil || p.EndsAt.After(at))
+}
+
+// Covers reports whether a promotion's scope includes the product
+func Covers(p *models.Promotion, product *models.Product) bool {
+	switch p.Scope {
+	case models.PromotionScopeAll:
+		return true
+	case models.PromotionScopeProduct:
+		return p.Target == strconv.Itoa(product.ID)
+	case models.PromotionScopeCategory:
+		return product.Category != "" && strings.EqualFold(p.Target, product.Category)
+	case models.PromotionScopeTag:
+		for _, tag := range product.Tags {
+			if strings.EqualFold(p.Target, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Evaluate returns the product's effective price at a point in time, under
+// the promotions given. Promotions not in effect then, or not covering the
+// product, are ignored.
+func Evaluate(product *models.Product, promotions []*models.Promotion, at time.Time) *models.EffectivePrice {
+	var applicable []*models.Promotion
+	for _, p := range promotions {
+		if Active(p, at) && Covers(p, product) {
+			applicable = append(applicable, p)
+		}
+	}
+	sort.SliceStable(applicable, func(i, j int) bool {
+		if applicable[i].Priority != applicable[j].Priority {
+			return applicable[i].Priority > applicable[j].Priority
+		}
+		return applicable[i].ID < applicable[j].ID
+	})
+
+	// Unit prices have two decimals, so cents are exact
+	unit := int64(math.Round(product.UnitPrice * 100))
+
+	price := unit
+	applied := []int{}
+	var bestExclusive *models.Promotion
+	bestExclusivePrice := unit
+	for _, p := range applicable {
+		if p.Stackable {
+			price = discount(price, p)
+			applied = append(applied, p.ID)
+			continue
+		}
+		if discounted := discount(unit, p); discounted < bestExclusivePrice {
+			bestExclusive, bestExclusivePrice = p, discounted
+		}
+	}
+	if bestExclusive != nil && bestExclusivePrice < price {
+		price = bestExclusivePrice
+		applied = []int{bestExclusive.ID}
+	}
+
+	return &models.EffectivePrice{
+		UnitPrice:  float64(unit) / 100,
+		Price:      float64(price) / 100,
+		Discount:   float64(unit-price) / 100,
+		Promotions: applied,
+		At:         at,
+	}
+}
+
+// discount returns the price in cents after a promotion, rounding
+// percentage discounts half up to the cent
+func discount(cents int64, p *models.Promotion) int64 {
+	value := int64(math.Round(p.Value * 100))
+	off := value
+	if p.Kind == models.PromotionPercentage {
+		off = (cents*value + 5000) / 10000
+	}
+	return max(cents-off, 0)
+}
+
+// Service evaluates the promotions stored in the repository
+type Service struct {
+	repo repository.PromotionRepository
+}
+
+func NewService(repo repository.PromotionRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// EffectivePrices returns the effective price of each product at a point in
+// time, in the order of products. The promotions in effect are read once.
+func (s *Service) EffectivePrices(ctx context.Context, at time.Time, products ...*models.Product) ([]*models.EffectivePrice, error) {
+	active, err := s.repo.ListActive(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make([]*models.EffectivePrice, len(products))
+	for i, product := range products {
+		prices[i] = Evaluate(product, active, at)
+	}
+	return prices, nil
+}
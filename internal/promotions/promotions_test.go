@@ -0,0 +1,134 @@
+package promotions
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	product := &models.Product{ID: 7, UnitPrice: 20, Category: "Books", Tags: []string{"sale"}}
+
+	percent := func(id int, value float64, stackable bool, priority int) *models.Promotion {
+		return &models.Promotion{ID: id, Kind: models.PromotionPercentage, Value: value, Scope: models.PromotionScopeAll, Stackable: stackable, Priority: priority}
+	}
+	fixed := func(id int, value float64, stackable bool, priority int) *models.Promotion {
+		return &models.Promotion{ID: id, Kind: models.PromotionFixed, Value: value, Scope: models.PromotionScopeAll, Stackable: stackable, Priority: priority}
+	}
+	scoped := func(p *models.Promotion, scope, target string) *models.Promotion {
+		p.Scope, p.Target = scope, target
+		return p
+	}
+	window := func(p *models.Promotion, starts, ends *time.Time) *models.Promotion {
+		p.StartsAt, p.EndsAt = starts, ends
+		return p
+	}
+
+	tests := []struct {
+		name       string
+		promotions []*models.Promotion
+		wantPrice  float64
+		wantIDs    []int
+	}{
+		{"none", nil, 20, []int{}},
+		{"percentage", []*models.Promotion{percent(1, 15, false, 0)}, 17, []int{1}},
+		{"fixed above the price", []*models.Promotion{fixed(1, 25, false, 0)}, 0, []int{1}},
+		{
+			"stackable ones combine by priority",
+			[]*models.Promotion{fixed(1, 2, true, 0), percent(2, 10, true, 5)},
+			16, []int{2, 1}, // 20 - 10% = 18, then - 2
+		},
+		{
+			"best exclusive wins over weaker ones",
+			[]*models.Promotion{percent(1, 10, false, 9), percent(2, 25, false, 0)},
+			15, []int{2},
+		},
+		{
+			"exclusive beats a weaker stack",
+			[]*models.Promotion{percent(1, 5, true, 0), fixed(2, 1, true, 0), percent(3, 30, false, 0)},
+			14, []int{3},
+		},
+		{
+			"stack beats a weaker exclusive",
+			[]*models.Promotion{percent(1, 20, true, 0), fixed(2, 2, true, 0), percent(3, 25, false, 0)},
+			14, []int{1, 2},
+		},
+		{
+			"scopes",
+			[]*models.Promotion{
+				scoped(fixed(1, 1, true, 0), models.PromotionScopeProduct, "7"),
+				scoped(fixed(2, 1, true, 0), models.PromotionScopeProduct, "8"),
+				scoped(fixed(3, 1, true, 0), models.PromotionScopeCategory, "books"),
+				scoped(fixed(4, 1, true, 0), models.PromotionScopeTag, "sale"),
+				scoped(fixed(5, 1, true, 0), models.PromotionScopeTag, "clearance"),
+			},
+			17, []int{1, 3, 4},
+		},
+		{
+			"date ranges",
+			[]*models.Promotion{
+				window(fixed(1, 1, true, 0), &past, &future),
+				window(fixed(2, 1, true, 0), &future, nil),
+				window(fixed(3, 1, true, 0), nil, &now), // Ends are exclusive
+				window(fixed(4, 1, true, 0), &now, nil),
+			},
+			18, []int{1, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Evaluate(product, tt.promotions, now)
+			if got.Price != tt.wantPrice || !reflect.DeepEqual(got.Promotions, tt.wantIDs) {
+				t.Errorf("Evaluate = %v with %v, want %v with %v", got.Price, got.Promotions, tt.wantPrice, tt.wantIDs)
+			}
+			if got.UnitPrice != 20 || got.Discount != 20-got.Price {
+				t.Errorf("UnitPrice %v, Discount %v for price %v", got.UnitPrice, got.Discount, got.Price)
+			}
+		})
+	}
+}
+
+func TestEvaluate_RoundsPercentagesToCents(t *testing.T) {
+	// 12.5% of 9.99 is 1.24875
+	got := Evaluate(&models.Product{UnitPrice: 9.99}, []*models.Promotion{
+		{ID: 1, Kind: models.PromotionPercentage, Value: 12.5, Scope: models.PromotionScopeAll},
+	}, time.Now())
+	if got.Price != 8.74 {
+		t.Errorf("Price = %v, want 8.74", got.Price)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.FixedZone("CET", 3600))
+	end := start.Add(-time.Minute)
+
+	valid := &models.Promotion{Name: "Spring", Kind: models.PromotionPercentage, Value: 10, Scope: models.PromotionScopeTag, Target: " Sale ", StartsAt: &start}
+	if err := Validate(valid); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if valid.Target != "sale" || valid.StartsAt.Location() != time.UTC {
+		t.Errorf("Validate didn't normalize: target %q, starts %v", valid.Target, valid.StartsAt)
+	}
+
+	for name, p := range map[string]models.Promotion{
+		"name":              {Kind: models.PromotionFixed, Value: 1, Scope: models.PromotionScopeAll},
+		"kind":              {Name: "x", Kind: "bogo", Value: 1, Scope: models.PromotionScopeAll},
+		"percentage":        {Name: "x", Kind: models.PromotionPercentage, Value: 120, Scope: models.PromotionScopeAll},
+		"value":             {Name: "x", Kind: models.PromotionFixed, Value: 0, Scope: models.PromotionScopeAll},
+		"decimals":          {Name: "x", Kind: models.PromotionFixed, Value: 1.005, Scope: models.PromotionScopeAll},
+		"scope":             {Name: "x", Kind: models.PromotionFixed, Value: 1, Scope: "store"},
+		"target for all":    {Name: "x", Kind: models.PromotionFixed, Value: 1, Scope: models.PromotionScopeAll, Target: "7"},
+		"product target":    {Name: "x", Kind: models.PromotionFixed, Value: 1, Scope: models.PromotionScopeProduct, Target: "SKU-1"},
+		"missing target":    {Name: "x", Kind: models.PromotionFixed, Value: 1, Scope: models.PromotionScopeCategory},
+		"ends before start": {Name: "x", Kind: models.PromotionFixed, Value: 1, Scope: models.PromotionScopeAll, StartsAt: &start, EndsAt: &end},
+	} {
+		if err := Validate(&p); !IsValidationError(err) {
+			t.Errorf("%s: Validate = %v, want a validation error", name, err)
+		}
+	}
+}
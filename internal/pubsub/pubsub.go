@@ -0,0 +1,212 @@
+// Package pubsub sends messages to every instance of the service over
+// Postgres LISTEN/NOTIFY, for cluster-wide signals like cache invalidations
+// that don't justify Redis or a broker. Delivery is at most once: messages
+// sent while an instance is disconnected are lost, and its subscribers are
+// told to resync once it's back.
+//
+// SQLite deployments are a single process, so there's no one to tell.
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+)
+
+var (
+	published = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pubsub_published_total",
+		Help: "Messages published over NOTIFY, by channel.",
+	}, []string{"channel"})
+	received = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pubsub_received_total",
+		Help: "Messages received from other instances, by channel.",
+	}, []string{"channel"})
+	reconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pubsub_reconnects_total",
+		Help: "Reconnections of the LISTEN connection.",
+	})
+)
+
+// maxPayload is the largest NOTIFY payload Postgres accepts, less one
+const maxPayload = 7999
+
+// Message is a message from another instance
+type Message struct {
+	Channel string
+	Data    json.RawMessage
+
+	// Resync is set, without Data, on the message delivered after the LISTEN
+	// connection was re-established: messages may have been missed
+	Resync bool
+}
+
+// Decode unmarshals the message's data into v
+func (m Message) Decode(v interface{}) error {
+	return json.Unmarshal(m.Data, v)
+}
+
+// Handler handles the messages of a channel. Handlers of all channels run
+// one at a time, so they must be quick.
+type Handler func(ctx context.Context, msg Message) error
+
+// envelope is a NOTIFY payload
+type envelope struct {
+	Origin string          `json:"origin"` // Instance that published it
+	Data   json.RawMessage `json:"data"`
+}
+
+// PubSub publishes and receives messages. Subscribe before Start.
+type PubSub struct {
+	db     *database.DB
+	url    string
+	origin string
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	handlers map[string][]Handler
+}
+
+// New returns a PubSub publishing through db and listening on a connection
+// of its own to url, the database db is connected to
+func New(db *database.DB, url string, logger *slog.Logger) *PubSub {
+	origin := make([]byte, 8)
+	rand.Read(origin)
+	return &PubSub{
+		db:       db,
+		url:      url,
+		origin:   hex.EncodeToString(origin),
+		logger:   logger,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe makes h receive the messages other instances publish on channel
+func (p *PubSub) Subscribe(channel string, h Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[channel] = append(p.handlers[channel], h)
+}
+
+// Publish sends data, encoded as JSON, to the other instances listening on
+// channel. It's sent at once, outside any transaction ctx carries, so
+// publish what a transaction changed once it has committed. The encoding
+// must fit in 8000 bytes.
+func (p *PubSub) Publish(ctx context.Context, channel string, data interface{}) error {
+	if p.db.Dialect() == database.SQLite {
+		return nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode message for %s: %w", channel, err)
+	}
+	payload, err := json.Marshal(envelope{Origin: p.origin, Data: encoded})
+	if err != nil {
+		return fmt.Errorf("failed to encode message for %s: %w", channel, err)
+	}
+	if len(payload) > maxPayload {
+		return fmt.Errorf("message for %s is %d bytes, NOTIFY takes at most %d", channel, len(payload), maxPayload)
+	}
+
+	if _, err := p.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, channel, string(payload)); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", channel, err)
+	}
+	published.WithLabelValues(channel).Inc()
+	return nil
+}
+
+// Start listens on the subscribed channels until ctx is cancelled. The
+// connection is re-established after failures, waiting up to a minute
+// between attempts.
+func (p *PubSub) Start(ctx context.Context) error {
+	if p.db.Dialect() == database.SQLite {
+		return nil
+	}
+
+	listener := pq.NewListener(p.url, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			p.logger.Warn("pubsub connection lost", "error", err)
+		case pq.ListenerEventReconnected:
+			reconnects.Inc()
+			p.logger.Info("pubsub connection re-established")
+		case pq.ListenerEventConnectionAttemptFailed:
+			p.logger.Warn("pubsub connection attempt failed", "error", err)
+		}
+	})
+
+	p.mu.Lock()
+	channels := make([]string, 0, len(p.handlers))
+	for channel := range p.handlers {
+		channels = append(channels, channel)
+	}
+	p.mu.Unlock()
+	for _, channel := range channels {
+		if err := listener.Listen(channel); err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to listen on %s: %w", channel, err)
+		}
+	}
+
+	go func() {
+		defer listener.Close()
+		// A connection that dropped silently is only noticed by pinging it
+		ping := time.NewTicker(90 * time.Second)
+		defer ping.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				p.dispatch(ctx, n)
+			case <-ping.C:
+				go listener.Ping()
+			}
+		}
+	}()
+	return nil
+}
+
+// dispatch hands a notification to the handlers of its channel, or a
+// resync to every handler for the nil notification sent after a reconnect
+func (p *PubSub) dispatch(ctx context.Context, n *pq.Notification) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if n == nil {
+		for channel, handlers := range p.handlers {
+			p.deliver(ctx, handlers, Message{Channel: channel, Resync: true})
+		}
+		return
+	}
+
+	var env envelope
+	if err := json.Unmarshal([]byte(n.Extra), &env); err != nil {
+		p.logger.Warn("ignoring malformed pubsub message", "channel", n.Channel, "error", err)
+		return
+	}
+	if env.Origin == p.origin {
+		return
+	}
+	received.WithLabelValues(n.Channel).Inc()
+	p.deliver(ctx, p.handlers[n.Channel], Message{Channel: n.Channel, Data: env.Data})
+}
+
+func (p *PubSub) deliver(ctx context.Context, handlers []Handler, msg Message) {
+	for _, h := range handlers {
+		if err := h(ctx, msg); err != nil {
+			p.logger.Error("pubsub handler failed", "channel", msg.Channel, "error", err)
+		}
+	}
+}
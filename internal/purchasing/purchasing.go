@@ -0,0 +1,376 @@
+// Package purchasing drafts purchase orders from reorder suggestions and
+// takes them through approval to submission.
+//
+// Drafting groups the products at or below the reorder point of their
+// forecast by supplier, adding each to its supplier's draft or a new one,
+// and orders the larger of the suggested quantity and the economic order
+// quantity. Products with no supplier, or on an open purchase order already,
+// are left out. Nothing is sent until someone approves a draft and submits
+// it, which publishes PurchaseOrderSubmitted; goods arriving are recorded as
+// stock receipts, and the order marked received.
+package purchasing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Purchase order statuses
+const (
+	StatusDraft     = "draft"
+	StatusApproved  = "approved"
+	StatusSubmitted = "submitted"
+	StatusReceived  = "received"
+	StatusCancelled = "cancelled"
+)
+
+// Statuses are the statuses of purchase orders, in the order they're reached
+var Statuses = []string{StatusDraft, StatusApproved, StatusSubmitted, StatusReceived, StatusCancelled}
+
+// maxSupplierLength is the length of product_suppliers.supplier
+const maxSupplierLength = 255
+
+var (
+	ErrInvalidOrder = errors.New("invalid purchase order")
+
+	// ErrInvalidStep is returned for a step the order's status doesn't
+	// allow, e.g. submitting a draft that wasn't approved
+	ErrInvalidStep = errors.New("step not allowed")
+)
+
+// ValidStatus reports whether status is one of Statuses
+func ValidStatus(status string) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Config prices the economic order quantity
+type Config struct {
+	OrderCost   float64 // Of placing an order, 0 disables computed economic order quantities
+	HoldingRate float64 // Yearly cost of holding a unit, as a fraction of its cost
+}
+
+// EconomicOrderQuantity is the order size minimizing the yearly cost of
+// ordering and holding stock, sqrt(2DS/H): D is the yearly demand, S the
+// cost of an order, and H the yearly cost of holding a unit. It returns 0
+// when any of them is unknown.
+func EconomicOrderQuantity(yearlyDemand, orderCost, holdingCost float64) int {
+	if yearlyDemand <= 0 || orderCost <= 0 || holdingCost <= 0 {
+		return 0
+	}
+	return int(math.Ceil(math.Sqrt(2 * yearlyDemand * orderCost / holdingCost)))
+}
+
+// Service drafts purchase orders and takes them through approval, auditing
+// every step
+type Service struct {
+	repo      repository.PurchaseOrderRepository
+	products  repository.ProductRepository
+	audit     repository.AuditRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	cfg       Config
+	logger    *slog.Logger
+}
+
+func NewService(repo repository.PurchaseOrderRepository, products repository.ProductRepository, audit repository.AuditRepository, tx repository.Transactor, publisher events.Publisher, cfg Config, logger *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		products:  products,
+		audit:     audit,
+		tx:        tx,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+// SetSupplier records who supplies a product and, when eoq isn't nil, fixes
+// its economic order quantity
+func (s *Service) SetSupplier(ctx context.Context, productID int, supplier string, eoq *int) (*models.ProductSupplier, error) {
+	supplier = strings.TrimSpace(supplier)
+	switch {
+	case supplier == "":
+		return nil, fmt.Errorf("%w: supplier is required", ErrInvalidOrder)
+	case len(supplier) > maxSupplierLength:
+		return nil, fmt.Errorf("%w: supplier must be at most %d characters", ErrInvalidOrder, maxSupplierLength)
+	case eoq != nil && *eoq <= 0:
+		return nil, fmt.Errorf("%w: economic_order_quantity must be positive", ErrInvalidOrder)
+	}
+	if _, err := s.products.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	ps := &models.ProductSupplier{ProductID: productID, Supplier: supplier, EconomicOrderQuantity: eoq}
+	if err := s.repo.SetSupplier(ctx, ps); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// quantity returns the economic order quantity of a candidate, fixed or
+// computed, and what to order
+func (s *Service) quantity(c *models.ReorderCandidate) (eoq, quantity int) {
+	if c.EconomicOrderQuantity != nil {
+		eoq = *c.EconomicOrderQuantity
+	} else {
+		eoq = EconomicOrderQuantity(c.AverageDailyDemand*365, s.cfg.OrderCost, s.cfg.HoldingRate*c.UnitCost)
+	}
+	return eoq, max(c.SuggestedQuantity, eoq)
+}
+
+// Draft adds the products to reorder to their supplier's draft, creating
+// drafts for the suppliers with none, in one transaction
+func (s *Service) Draft(ctx context.Context, actor string) (*models.PurchaseOrderDrafts, error) {
+	result := &models.PurchaseOrderDrafts{}
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		result.PurchaseOrders = []*models.PurchaseOrder{}
+
+		candidates, err := s.repo.ListCandidates(ctx)
+		if err != nil {
+			return err
+		}
+		if result.Unassigned, err = s.repo.CountUnassigned(ctx); err != nil {
+			return err
+		}
+
+		// Candidates come by supplier
+		var order *models.PurchaseOrder
+		var added []*models.PurchaseOrderLine
+		for i, c := range candidates {
+			if order == nil || order.Supplier != c.Supplier {
+				if order, err = s.draft(ctx, c.Supplier, actor); err != nil {
+					return err
+				}
+				added = nil
+			}
+
+			eoq, quantity := s.quantity(c)
+			line := &models.PurchaseOrderLine{
+				PurchaseOrderID:       order.ID,
+				ProductID:             c.ProductID,
+				SKU:                   c.SKU,
+				Quantity:              quantity,
+				SuggestedQuantity:     c.SuggestedQuantity,
+				EconomicOrderQuantity: eoq,
+			}
+			if err := s.repo.AddLine(ctx, line); err != nil {
+				return err
+			}
+			order.Lines = append(order.Lines, line)
+			added = append(added, line)
+
+			if i == len(candidates)-1 || candidates[i+1].Supplier != c.Supplier {
+				if err := s.record(ctx, "purchase_order.draft", actor, order, map[string]interface{}{"added": added}); err != nil {
+					return err
+				}
+				result.PurchaseOrders = append(result.PurchaseOrders, order)
+			}
+		}
+
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("purchase orders drafted", "orders", len(result.PurchaseOrders), "products", len(candidates), "unassigned", result.Unassigned)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// draft returns a supplier's draft, locked, creating it when it has none
+func (s *Service) draft(ctx context.Context, supplier, actor string) (*models.PurchaseOrder, error) {
+	order, err := s.repo.GetDraft(ctx, supplier)
+	if err != nil || order != nil {
+		return order, err
+	}
+
+	order = &models.PurchaseOrder{Supplier: supplier, Status: StatusDraft, CreatedBy: actor, Lines: []*models.PurchaseOrderLine{}}
+	if err := s.repo.Create(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Run drafts purchase orders, for use as a scheduled job
+func (s *Service) Run(ctx context.Context) error {
+	_, err := s.Draft(ctx, "purchase-order-drafts")
+	return err
+}
+
+// SetLines changes the quantities of a draft's lines by product ID, a
+// quantity of 0 removing the line
+func (s *Service) SetLines(ctx context.Context, id int, quantities map[int]int, actor string) (*models.PurchaseOrder, error) {
+	if len(quantities) == 0 {
+		return nil, fmt.Errorf("%w: lines is required", ErrInvalidOrder)
+	}
+	for productID, quantity := range quantities {
+		if quantity < 0 {
+			return nil, fmt.Errorf("%w: quantity of product %d must not be negative", ErrInvalidOrder, productID)
+		}
+	}
+
+	return s.step(ctx, id, "update", actor, []string{StatusDraft}, func(ctx context.Context, order *models.PurchaseOrder) (map[string]interface{}, error) {
+		onOrder := make(map[int]bool, len(order.Lines))
+		for _, line := range order.Lines {
+			onOrder[line.ProductID] = true
+		}
+		for productID := range quantities {
+			if !onOrder[productID] {
+				return nil, fmt.Errorf("%w: product %d isn't on the order", ErrInvalidOrder, productID)
+			}
+		}
+
+		lines := []*models.PurchaseOrderLine{}
+		for _, line := range order.Lines {
+			quantity, ok := quantities[line.ProductID]
+			if !ok {
+				lines = append(lines, line)
+				continue
+			}
+			if err := s.repo.SetLineQuantity(ctx, order.ID, line.ProductID, quantity); err != nil {
+				return nil, err
+			}
+			if quantity > 0 {
+				line.Quantity = quantity
+				lines = append(lines, line)
+			}
+		}
+		order.Lines = lines
+
+		changes := make(map[string]int, len(quantities))
+		for productID, quantity := range quantities {
+			changes[strconv.Itoa(productID)] = quantity
+		}
+		return map[string]interface{}{"quantities": changes}, nil
+	})
+}
+
+// Approve approves a draft with lines for submission, as actor
+func (s *Service) Approve(ctx context.Context, id int, actor string) (*models.PurchaseOrder, error) {
+	return s.step(ctx, id, "approve", actor, []string{StatusDraft}, func(ctx context.Context, order *models.PurchaseOrder) (map[string]interface{}, error) {
+		if len(order.Lines) == 0 {
+			return nil, fmt.Errorf("%w: can't approve an order without lines", ErrInvalidStep)
+		}
+		now := time.Now()
+		order.Status, order.ApprovedBy, order.ApprovedAt = StatusApproved, actor, &now
+		return nil, nil
+	})
+}
+
+// Submit submits an approved order to its supplier, publishing
+// PurchaseOrderSubmitted in the same transaction
+func (s *Service) Submit(ctx context.Context, id int, actor string) (*models.PurchaseOrder, error) {
+	return s.step(ctx, id, "submit", actor, []string{StatusApproved}, func(ctx context.Context, order *models.PurchaseOrder) (map[string]interface{}, error) {
+		now := time.Now()
+		order.Status, order.SubmittedAt = StatusSubmitted, &now
+
+		lines := make([]events.OrderedProduct, len(order.Lines))
+		for i, line := range order.Lines {
+			lines[i] = events.OrderedProduct{ProductID: line.ProductID, SKU: line.SKU, Quantity: line.Quantity}
+		}
+		return nil, s.publisher.Publish(ctx, events.New(events.PurchaseOrderSubmitted{
+			PurchaseOrderID: order.ID,
+			Supplier:        order.Supplier,
+			Lines:           lines,
+		}))
+	})
+}
+
+// Receive closes a submitted order whose goods arrived. It doesn't add them
+// to stock: receipts do, with their costs.
+func (s *Service) Receive(ctx context.Context, id int, actor string) (*models.PurchaseOrder, error) {
+	return s.step(ctx, id, "receive", actor, []string{StatusSubmitted}, func(ctx context.Context, order *models.PurchaseOrder) (map[string]interface{}, error) {
+		now := time.Now()
+		order.Status, order.ClosedAt = StatusReceived, &now
+		return nil, nil
+	})
+}
+
+// Cancel closes an order that wasn't submitted
+func (s *Service) Cancel(ctx context.Context, id int, actor string) (*models.PurchaseOrder, error) {
+	return s.step(ctx, id, "cancel", actor, []string{StatusDraft, StatusApproved}, func(ctx context.Context, order *models.PurchaseOrder) (map[string]interface{}, error) {
+		now := time.Now()
+		order.Status, order.ClosedAt = StatusCancelled, &now
+		return nil, nil
+	})
+}
+
+// step runs a step of an order in one transaction: it locks the order,
+// checks its status is one of allowed, applies the step, stores the order,
+// and audits the step as "purchase_order.<name>"
+func (s *Service) step(ctx context.Context, id int, name, actor string, allowed []string, apply func(context.Context, *models.PurchaseOrder) (map[string]interface{}, error)) (*models.PurchaseOrder, error) {
+	var order *models.PurchaseOrder
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if order, err = s.repo.GetByIDForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if !contains(allowed, order.Status) {
+			return fmt.Errorf("%w: can't %s a purchase order that's %s", ErrInvalidStep, name, order.Status)
+		}
+
+		from := order.Status
+		details, err := apply(ctx, order)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.Update(ctx, order); err != nil {
+			return err
+		}
+
+		if details == nil {
+			details = map[string]interface{}{}
+		}
+		details["from"], details["status"] = from, order.Status
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("purchase order updated", "purchase_order_id", order.ID, "step", name, "status", order.Status)
+		})
+		return s.record(ctx, "purchase_order."+name, actor, order, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// record audits a step of an order, in its transaction
+func (s *Service) record(ctx context.Context, action, actor string, order *models.PurchaseOrder, details map[string]interface{}) error {
+	details["supplier"] = order.Supplier
+	entry := &models.AuditEntry{
+		Action:     action,
+		Actor:      actor,
+		EntityType: "purchase_order",
+		EntityID:   strconv.Itoa(order.ID),
+	}
+	entry.Details, _ = json.Marshal(details)
+	return s.audit.Create(ctx, entry)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
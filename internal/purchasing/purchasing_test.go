@@ -0,0 +1,169 @@
+package purchasing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestEconomicOrderQuantity(t *testing.T) {
+	for _, tt := range []struct {
+		demand, orderCost, holdingCost float64
+		want                           int
+	}{
+		{1000, 50, 2.5, 200},
+		{365, 10, 1, 86}, // 85.4 rounds up
+		{0, 50, 2.5, 0},
+		{1000, 0, 2.5, 0},
+		{1000, 50, 0, 0},
+	} {
+		if got := EconomicOrderQuantity(tt.demand, tt.orderCost, tt.holdingCost); got != tt.want {
+			t.Errorf("EconomicOrderQuantity(%v, %v, %v) = %d, want %d", tt.demand, tt.orderCost, tt.holdingCost, got, tt.want)
+		}
+	}
+}
+
+func TestService_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "purchasing.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	products := repository.NewProductRepository(db)
+	forecasts := repository.NewForecastRepository(db)
+	audit := repository.NewAuditRepository(db)
+	repo := repository.NewPurchaseOrderRepository(db)
+	bus := events.NewBus(logger)
+	var submitted []events.PurchaseOrderSubmitted
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		submitted = append(submitted, event.Payload.(events.PurchaseOrderSubmitted))
+		return nil
+	}, events.TypePurchaseOrderSubmitted)
+	service := NewService(repo, products, audit, db, bus, Config{OrderCost: 50, HoldingRate: 0.25}, logger)
+
+	// Widget's yearly demand of 3650 at a unit cost of 10 is ordered in 383s
+	widget := &models.Product{SKU: "PO-1", Name: "Widget", Quantity: 5, UnitPrice: 10}
+	gadget := &models.Product{SKU: "PO-2", Name: "Gadget", Quantity: 1, UnitPrice: 10}
+	orphan := &models.Product{SKU: "PO-3", Name: "Orphan", Quantity: 0, UnitPrice: 10}
+	for _, p := range []*models.Product{widget, gadget, orphan} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+		if err := forecasts.Upsert(ctx, &models.Forecast{
+			ProductID: p.ID, SKU: p.SKU, WindowDays: 28, AverageDailyDemand: 10,
+			HorizonDays: 30, LeadTimeDays: 7, ReorderPoint: 70, OrderUpTo: 370, ComputedAt: time.Now(),
+		}); err != nil {
+			t.Fatalf("failed to store forecast: %v", err)
+		}
+	}
+
+	if _, err := service.SetSupplier(ctx, widget.ID, " ", nil); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("SetSupplier(blank) error = %v, want ErrInvalidOrder", err)
+	}
+	if _, err := service.SetSupplier(ctx, 999, "Acme", nil); err == nil || err.Error() != "product not found" {
+		t.Errorf("SetSupplier(999) error = %v, want product not found", err)
+	}
+	caseSize := 500
+	if _, err := service.SetSupplier(ctx, widget.ID, "Acme", nil); err != nil {
+		t.Fatalf("SetSupplier() error = %v", err)
+	}
+	if _, err := service.SetSupplier(ctx, gadget.ID, "Acme", &caseSize); err != nil {
+		t.Fatalf("SetSupplier() error = %v", err)
+	}
+
+	drafts, err := service.Draft(ctx, "buyer")
+	if err != nil {
+		t.Fatalf("Draft() error = %v", err)
+	}
+	if len(drafts.PurchaseOrders) != 1 || drafts.Unassigned != 1 {
+		t.Fatalf("Draft() = %+v", drafts)
+	}
+	order := drafts.PurchaseOrders[0]
+	if order.Supplier != "Acme" || order.Status != StatusDraft || len(order.Lines) != 2 {
+		t.Fatalf("draft = %+v", order)
+	}
+	quantities := map[int]int{}
+	for _, line := range order.Lines {
+		quantities[line.ProductID] = line.Quantity
+	}
+	if quantities[widget.ID] != 383 || quantities[gadget.ID] != 500 {
+		t.Errorf("draft quantities = %v, want 383 and 500", quantities)
+	}
+
+	// Products on an open order aren't drafted again
+	if again, err := service.Draft(ctx, "buyer"); err != nil || len(again.PurchaseOrders) != 0 {
+		t.Errorf("Draft() again = %+v, %v", again, err)
+	}
+
+	if _, err := service.Submit(ctx, order.ID, "buyer"); !errors.Is(err, ErrInvalidStep) {
+		t.Errorf("Submit(draft) error = %v, want ErrInvalidStep", err)
+	}
+	if _, err := service.SetLines(ctx, order.ID, map[int]int{orphan.ID: 10}, "buyer"); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("SetLines(not on order) error = %v, want ErrInvalidOrder", err)
+	}
+	order, err = service.SetLines(ctx, order.ID, map[int]int{widget.ID: 400, gadget.ID: 0}, "buyer")
+	if err != nil {
+		t.Fatalf("SetLines() error = %v", err)
+	}
+	if len(order.Lines) != 1 || order.Lines[0].ProductID != widget.ID || order.Lines[0].Quantity != 400 {
+		t.Errorf("SetLines() lines = %+v", order.Lines)
+	}
+
+	if order, err = service.Approve(ctx, order.ID, "manager"); err != nil || order.Status != StatusApproved || order.ApprovedBy != "manager" {
+		t.Fatalf("Approve() = %+v, %v", order, err)
+	}
+	if _, err := service.SetLines(ctx, order.ID, map[int]int{widget.ID: 1}, "buyer"); !errors.Is(err, ErrInvalidStep) {
+		t.Errorf("SetLines(approved) error = %v, want ErrInvalidStep", err)
+	}
+	if order, err = service.Submit(ctx, order.ID, "buyer"); err != nil || order.Status != StatusSubmitted {
+		t.Fatalf("Submit() = %+v, %v", order, err)
+	}
+	if len(submitted) != 1 || submitted[0].Supplier != "Acme" || len(submitted[0].Lines) != 1 || submitted[0].Lines[0].Quantity != 400 {
+		t.Errorf("published %+v", submitted)
+	}
+	if _, err := service.Cancel(ctx, order.ID, "buyer"); !errors.Is(err, ErrInvalidStep) {
+		t.Errorf("Cancel(submitted) error = %v, want ErrInvalidStep", err)
+	}
+	if order, err = service.Receive(ctx, order.ID, "buyer"); err != nil || order.Status != StatusReceived || order.ClosedAt == nil {
+		t.Fatalf("Receive() = %+v, %v", order, err)
+	}
+
+	stored, err := repo.GetByID(ctx, order.ID)
+	if err != nil || stored.Status != StatusReceived || len(stored.Lines) != 1 {
+		t.Errorf("GetByID() = %+v, %v", stored, err)
+	}
+	if n, err := repo.Count(ctx, models.PurchaseOrderFilter{Supplier: "Acme", Status: StatusReceived}); err != nil || n != 1 {
+		t.Errorf("Count() = %d, %v, want 1", n, err)
+	}
+
+	entries, err := audit.List(ctx, time.Time{}, time.Now().Add(time.Minute), 100)
+	if err != nil {
+		t.Fatalf("failed to list audit entries: %v", err)
+	}
+	actions := map[string]int{}
+	for _, e := range entries {
+		if e.EntityType == "purchase_order" {
+			actions[e.Action]++
+		}
+	}
+	for _, action := range []string{"purchase_order.draft", "purchase_order.update", "purchase_order.approve", "purchase_order.submit", "purchase_order.receive"} {
+		if actions[action] != 1 {
+			t.Errorf("audited %s %d times, want once", action, actions[action])
+		}
+	}
+}
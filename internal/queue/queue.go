@@ -0,0 +1,409 @@
+// Package queue runs deliveries to external destinations (catalog pushes,
+// webhooks, notifications) on a bounded worker pool. Each destination has
+// its own queue and concurrency limit, so one slow endpoint can't hold every
+// worker. When a destination's queue is full, tasks are persisted and run
+// later instead of blocking the caller or growing without bound.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+var (
+	queueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "queue_depth",
+		Help: "Tasks waiting in memory for a worker, by destination.",
+	}, []string{"destination"})
+	queueRunning = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "queue_running_tasks",
+		Help: "Tasks being run, by destination.",
+	}, []string{"destination"})
+	queueTasks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "queue_tasks_total",
+		Help: "Task runs, by kind and result (success, retry, failed).",
+	}, []string{"kind", "result"})
+	queueDeferred = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "queue_deferred_tasks_total",
+		Help: "Tasks persisted to run later, by destination and reason (saturated, retry, shutdown).",
+	}, []string{"destination", "reason"})
+	queuePersisted = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "queue_persisted_tasks",
+		Help: "Tasks waiting in the database.",
+	})
+)
+
+// Task is one delivery. Payload must hold everything the handler needs, as
+// tasks may be persisted and run by another instance.
+type Task struct {
+	Kind        string // Selects the handler
+	Destination string // Concurrency is limited per destination
+	Payload     json.RawMessage
+	Attempts    int // Failed runs so far
+}
+
+// Handler runs a task. Returning nil completes it, a Permanent error drops
+// it, and any other error schedules a retry. Tasks can run more than once,
+// so handlers must be idempotent.
+type Handler func(ctx context.Context, task Task) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+type Config struct {
+	Workers        int           // Tasks run at once across destinations, default 8
+	PerDestination int           // Tasks run at once per destination, default 2
+	QueueSize      int           // Tasks waiting per destination before new ones are persisted, default 100
+	MaxAttempts    int           // Runs before a failing task is dropped, default 5
+	RetryDelay     time.Duration // Delay before the first retry, doubling per attempt up to an hour, default 30s
+	PollInterval   time.Duration // How often persisted tasks are checked, default 5s
+	Timeout        time.Duration // Per-run timeout, default 30s
+}
+
+// Pool runs tasks until stopped. Stop lets running tasks finish and persists
+// the ones still waiting, so no task is lost across a restart.
+type Pool struct {
+	repo   repository.QueueRepository
+	tx     repository.Transactor
+	cfg    Config
+	logger *slog.Logger
+
+	workers chan struct{} // Semaphore bounding running tasks
+	wg      sync.WaitGroup
+	poll    context.CancelFunc
+	wakeup  chan struct{} // Checks persisted tasks before the next poll
+
+	mu           sync.Mutex
+	handlers     map[string]Handler
+	destinations map[string]chan Task
+	stopping     bool
+}
+
+func NewPool(repo repository.QueueRepository, tx repository.Transactor, cfg Config, logger *slog.Logger) *Pool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 8
+	}
+	if cfg.PerDestination <= 0 {
+		cfg.PerDestination = 2
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = 30 * time.Second
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	return &Pool{
+		repo:         repo,
+		tx:           tx,
+		cfg:          cfg,
+		logger:       logger,
+		workers:      make(chan struct{}, cfg.Workers),
+		wakeup:       make(chan struct{}, 1),
+		handlers:     make(map[string]Handler),
+		destinations: make(map[string]chan Task),
+	}
+}
+
+// Handle registers the handler for tasks of kind. Register handlers before
+// Start, so persisted tasks find theirs.
+func (p *Pool) Handle(kind string, handler Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[kind] = handler
+}
+
+// Start begins moving persisted tasks back into the pool as they fall due
+func (p *Pool) Start(ctx context.Context) {
+	ctx, p.poll = context.WithCancel(ctx)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.pollPersisted(ctx)
+	}()
+	p.logger.Info("worker pool started", "workers", p.cfg.Workers, "per_destination", p.cfg.PerDestination)
+}
+
+// Submit queues a task for its destination. When the destination's queue is
+// full the task is persisted and runs once there is room, so Submit never
+// blocks on a slow destination; it only fails if the task can't be stored.
+func (p *Pool) Submit(ctx context.Context, task Task) error {
+	p.mu.Lock()
+	if _, ok := p.handlers[task.Kind]; !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("no handler for %s tasks", task.Kind)
+	}
+	queued, stopping := p.enqueue(task)
+	p.mu.Unlock()
+
+	if queued {
+		return nil
+	}
+	reason := "saturated"
+	if stopping {
+		reason = "shutdown"
+	}
+	return p.persist(ctx, task, reason, time.Now(), nil)
+}
+
+// SubmitTx stores task in the transaction carried by ctx, so it runs only
+// if that commits: a rolled-back write (or dry run) delivers nothing, and a
+// committed one is delivered even if the process stops first. Once the
+// transaction commits the pool picks the task up without waiting for a poll.
+func (p *Pool) SubmitTx(ctx context.Context, task Task) error {
+	p.mu.Lock()
+	_, ok := p.handlers[task.Kind]
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no handler for %s tasks", task.Kind)
+	}
+
+	if task.Payload == nil {
+		task.Payload = json.RawMessage("null")
+	}
+	queued := &models.QueuedTask{
+		Kind:        task.Kind,
+		Destination: task.Destination,
+		Payload:     task.Payload,
+		Attempts:    task.Attempts,
+		RunAfter:    time.Now(),
+	}
+	if err := p.repo.Add(ctx, queued); err != nil {
+		return fmt.Errorf("failed to store %s task: %w", task.Kind, err)
+	}
+	database.AfterCommit(ctx, p.wake)
+	return nil
+}
+
+// wake makes the poller check persisted tasks now
+func (p *Pool) wake() {
+	select {
+	case p.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue adds task to its destination's queue without blocking, starting
+// the destination's workers on first use. It must be called with mu held.
+func (p *Pool) enqueue(task Task) (queued, stopping bool) {
+	if p.stopping {
+		return false, true
+	}
+
+	tasks, ok := p.destinations[task.Destination]
+	if !ok {
+		tasks = make(chan Task, p.cfg.QueueSize)
+		p.destinations[task.Destination] = tasks
+		for i := 0; i < p.cfg.PerDestination; i++ {
+			p.wg.Add(1)
+			go p.work(task.Destination, tasks)
+		}
+	}
+
+	select {
+	case tasks <- task:
+		queueDepth.WithLabelValues(task.Destination).Inc()
+		return true, false
+	default:
+		return false, false
+	}
+}
+
+// work runs one destination's tasks, one at a time, whenever a pool worker
+// is free
+func (p *Pool) work(destination string, tasks chan Task) {
+	defer p.wg.Done()
+
+	for task := range tasks {
+		queueDepth.WithLabelValues(destination).Dec()
+		p.workers <- struct{}{}
+
+		p.mu.Lock()
+		stopping := p.stopping
+		handler := p.handlers[task.Kind]
+		p.mu.Unlock()
+
+		if stopping {
+			p.persist(context.Background(), task, "shutdown", time.Now(), nil)
+		} else {
+			p.run(handler, task)
+		}
+		<-p.workers
+	}
+}
+
+func (p *Pool) run(handler Handler, task Task) {
+	queueRunning.WithLabelValues(task.Destination).Inc()
+	defer queueRunning.WithLabelValues(task.Destination).Dec()
+
+	// Tasks get their own context so Stop lets them finish
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+	defer cancel()
+
+	err := handler(ctx, task)
+	logger := p.logger.With("kind", task.Kind, "destination", task.Destination)
+	switch {
+	case err == nil:
+		queueTasks.WithLabelValues(task.Kind, "success").Inc()
+
+	case IsPermanent(err) || task.Attempts+1 >= p.cfg.MaxAttempts:
+		queueTasks.WithLabelValues(task.Kind, "failed").Inc()
+		logger.Error("task failed, giving up", "attempts", task.Attempts+1, "error", err)
+
+	default:
+		queueTasks.WithLabelValues(task.Kind, "retry").Inc()
+		task.Attempts++
+		delay := p.retryDelay(task.Attempts)
+		logger.Warn("task failed, will retry", "attempt", task.Attempts, "retry_in", delay.String(), "error", err)
+		p.persist(context.Background(), task, "retry", time.Now().Add(delay), err)
+	}
+}
+
+// retryDelay backs off exponentially from RetryDelay, capped at an hour
+func (p *Pool) retryDelay(attempts int) time.Duration {
+	delay := p.cfg.RetryDelay << (attempts - 1)
+	if delay > time.Hour || delay <= 0 {
+		return time.Hour
+	}
+	return delay
+}
+
+func (p *Pool) persist(ctx context.Context, task Task, reason string, runAfter time.Time, cause error) error {
+	if task.Payload == nil {
+		task.Payload = json.RawMessage("null")
+	}
+	queued := &models.QueuedTask{
+		Kind:        task.Kind,
+		Destination: task.Destination,
+		Payload:     task.Payload,
+		Attempts:    task.Attempts,
+		RunAfter:    runAfter,
+	}
+	if cause != nil {
+		msg := cause.Error()
+		queued.LastError = &msg
+	}
+
+	if err := p.repo.Add(ctx, queued); err != nil {
+		p.logger.Error("failed to persist task, it is lost", "kind", task.Kind, "destination", task.Destination, "error", err)
+		return fmt.Errorf("failed to persist %s task: %w", task.Kind, err)
+	}
+	queueDeferred.WithLabelValues(task.Destination, reason).Inc()
+	return nil
+}
+
+// pollPersisted moves due persisted tasks into the pool until ctx is
+// cancelled
+func (p *Pool) pollPersisted(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.requeue(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Warn("failed to requeue persisted tasks", "error", err)
+		}
+		if count, err := p.repo.Count(ctx); err == nil {
+			queuePersisted.Set(float64(count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.wakeup:
+		}
+	}
+}
+
+// requeue moves due persisted tasks into their destinations' queues while
+// there is room. Tasks are removed from the table when the transaction
+// commits; should that fail after they were queued, they run twice.
+func (p *Pool) requeue(ctx context.Context) error {
+	return p.tx.WithTx(ctx, func(ctx context.Context) error {
+		due, err := p.repo.FetchDue(ctx, time.Now(), p.cfg.QueueSize)
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		var ids []int64
+		p.mu.Lock()
+		for _, queued := range due {
+			if _, ok := p.handlers[queued.Kind]; !ok {
+				p.logger.Warn("persisted task has no handler", "kind", queued.Kind, "id", queued.ID)
+				continue
+			}
+			task := Task{Kind: queued.Kind, Destination: queued.Destination, Payload: queued.Payload, Attempts: queued.Attempts}
+			if ok, _ := p.enqueue(task); ok {
+				ids = append(ids, queued.ID)
+			}
+		}
+		p.mu.Unlock()
+
+		if len(ids) == 0 {
+			return nil
+		}
+		return p.repo.Delete(ctx, ids)
+	})
+}
+
+// Stop stops taking tasks, waits for running ones to finish, and persists
+// those still waiting. It returns early when ctx expires.
+func (p *Pool) Stop(ctx context.Context) {
+	if p.poll != nil {
+		p.poll()
+	}
+
+	p.mu.Lock()
+	p.stopping = true
+	for _, tasks := range p.destinations {
+		close(tasks)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.logger.Info("worker pool stopped")
+	case <-ctx.Done():
+		p.logger.Warn("worker pool did not stop before shutdown deadline")
+	}
+}
@@ -0,0 +1,246 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func setupPool(t *testing.T, cfg Config) (*Pool, repository.QueueRepository) {
+	t.Helper()
+
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(t.TempDir(), "queue.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	repo := repository.NewQueueRepository(db)
+	return NewPool(repo, db, cfg, slog.New(slog.NewTextHandler(io.Discard, nil))), repo
+}
+
+// waitFor polls cond until it holds or five seconds have passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func persisted(t *testing.T, repo repository.QueueRepository) int {
+	t.Helper()
+	count, err := repo.Count(context.Background())
+	if err != nil {
+		t.Fatalf("failed to count persisted tasks: %v", err)
+	}
+	return count
+}
+
+func TestPool_SaturatedDestinationIsDeferred(t *testing.T) {
+	pool, repo := setupPool(t, Config{Workers: 4, PerDestination: 1, QueueSize: 1, PollInterval: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	ran := map[string]int{}
+	pool.Handle("test", func(ctx context.Context, task Task) error {
+		if task.Destination == "slow" {
+			<-release
+		}
+		mu.Lock()
+		ran[task.Destination]++
+		mu.Unlock()
+		return nil
+	})
+	count := func(destination string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return ran[destination]
+	}
+	pool.Start(ctx)
+	defer pool.Stop(ctx)
+
+	// One runs, one waits, and the third finds the queue full
+	for i := 0; i < 3; i++ {
+		if err := pool.Submit(ctx, Task{Kind: "test", Destination: "slow"}); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	waitFor(t, "the full queue to defer a task", func() bool { return persisted(t, repo) == 1 })
+
+	// Other destinations aren't held up
+	if err := pool.Submit(ctx, Task{Kind: "test", Destination: "fast"}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	waitFor(t, "the fast destination", func() bool { return count("fast") == 1 })
+
+	close(release)
+	waitFor(t, "the deferred task to run", func() bool { return count("slow") == 3 })
+	if n := persisted(t, repo); n != 0 {
+		t.Errorf("%d tasks still persisted after running", n)
+	}
+}
+
+func TestPool_Retries(t *testing.T) {
+	pool, repo := setupPool(t, Config{MaxAttempts: 3, RetryDelay: time.Millisecond, PollInterval: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	attempts := map[string][]int{}
+	pool.Handle("test", func(ctx context.Context, task Task) error {
+		mu.Lock()
+		attempts[task.Destination] = append(attempts[task.Destination], task.Attempts)
+		mu.Unlock()
+
+		switch task.Destination {
+		case "flaky":
+			if task.Attempts == 0 {
+				return errors.New("temporarily down")
+			}
+			return nil
+		case "broken":
+			return Permanent(errors.New("rejected"))
+		default:
+			return errors.New("always down")
+		}
+	})
+	runs := func(destination string) []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int(nil), attempts[destination]...)
+	}
+	pool.Start(ctx)
+	defer pool.Stop(ctx)
+
+	for _, destination := range []string{"flaky", "broken", "down"} {
+		if err := pool.Submit(ctx, Task{Kind: "test", Destination: destination}); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+
+	waitFor(t, "the flaky task to succeed", func() bool { return len(runs("flaky")) == 2 })
+	waitFor(t, "the failing task to give up", func() bool { return len(runs("down")) == 3 && persisted(t, repo) == 0 })
+	if got := runs("flaky"); got[1] != 1 {
+		t.Errorf("retry ran with Attempts = %d, want 1", got[1])
+	}
+	if got := runs("broken"); len(got) != 1 {
+		t.Errorf("permanent failure ran %d times, want 1", len(got))
+	}
+}
+
+func TestPool_StopPersistsWaitingTasks(t *testing.T) {
+	pool, repo := setupPool(t, Config{Workers: 1, PerDestination: 1})
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Handle("test", func(ctx context.Context, task Task) error {
+		if string(task.Payload) == `"first"` {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+	if err := pool.Submit(ctx, Task{Kind: "test", Destination: "d", Payload: []byte(`"first"`)}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	<-started
+	for i := 0; i < 2; i++ {
+		if err := pool.Submit(ctx, Task{Kind: "test", Destination: "d", Payload: []byte(`"later"`)}); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	pool.Stop(ctx)
+
+	if n := persisted(t, repo); n != 2 {
+		t.Errorf("%d tasks persisted on stop, want the 2 waiting", n)
+	}
+	if err := pool.Submit(ctx, Task{Kind: "test", Destination: "d"}); err != nil {
+		t.Fatalf("Submit after Stop: %v", err)
+	}
+	if n := persisted(t, repo); n != 3 {
+		t.Errorf("task submitted after stop wasn't persisted")
+	}
+	if err := pool.Submit(ctx, Task{Kind: "unknown", Destination: "d"}); err == nil {
+		t.Error("expected a task without a handler to be rejected")
+	}
+}
+
+func TestPool_SubmitTx(t *testing.T) {
+	// A poll interval longer than the test, so only the commit wakes the pool
+	pool, repo := setupPool(t, Config{PollInterval: time.Hour})
+	ctx := context.Background()
+
+	ran := make(chan string, 2)
+	pool.Handle("test", func(ctx context.Context, task Task) error {
+		ran <- string(task.Payload)
+		return nil
+	})
+	pool.Start(ctx)
+	defer pool.Stop(ctx)
+	time.Sleep(20 * time.Millisecond) // Past the first poll
+
+	rollback := errors.New("rollback")
+	err := pool.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := pool.SubmitTx(ctx, Task{Kind: "test", Destination: "d", Payload: []byte(`"rolled back"`)}); err != nil {
+			t.Fatalf("SubmitTx: %v", err)
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("WithTx = %v", err)
+	}
+
+	err = pool.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := pool.SubmitTx(ctx, Task{Kind: "test", Destination: "d", Payload: []byte(`"committed"`)}); err != nil {
+			t.Fatalf("SubmitTx: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	select {
+	case payload := <-ran:
+		if payload != `"committed"` {
+			t.Errorf("ran %s, want the committed task", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("committed task didn't run")
+	}
+	waitFor(t, "the task to be removed", func() bool { return persisted(t, repo) == 0 })
+	select {
+	case payload := <-ran:
+		t.Errorf("ran %s as well", payload)
+	default:
+	}
+
+	if err := pool.SubmitTx(ctx, Task{Kind: "unknown", Destination: "d"}); err == nil {
+		t.Error("expected a task without a handler to be rejected")
+	}
+}
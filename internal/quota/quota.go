@@ -0,0 +1,312 @@
+// Package quota meters external API consumers. Requests with an API key in
+// X-API-Key are counted per calendar month (UTC) and endpoint class, with the
+// response bytes, and refused once the key's monthly quota is used up.
+//
+// Counts are kept in memory and added to api_usage by Flush, so metering
+// costs no query per request. Each instance enforces quotas with the usage
+// flushed by every instance plus its own unflushed requests, so a key can
+// overshoot its quota by what other instances served since their last flush.
+package quota
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Header carries the API key of a request
+const Header = "X-API-Key"
+
+// keyPrefix starts every API key; the prefix stored for listings adds the
+// first characters after it
+const keyPrefix = "ak_"
+
+// keyCacheTTL bounds how long a revoked key, or a change of a key's quota or
+// region, takes to apply
+const keyCacheTTL = time.Minute
+
+// keyCacheSize bounds the cached lookups, most of them of unknown keys when
+// someone is guessing
+const keyCacheSize = 10000
+
+var (
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrRevokedKey = errors.New("API key revoked")
+)
+
+// Status reports a key's quota after a request was admitted or refused
+type Status struct {
+	Limit     int64 // 0 for no limit
+	Remaining int64
+	Reset     time.Time // Start of the next month
+}
+
+type Meter struct {
+	keys  repository.APIKeyRepository
+	usage repository.UsageRepository
+
+	flushMu   sync.Mutex // Serializes flushes
+	mu        sync.Mutex
+	cache     map[string]cachedKey // By key hash
+	cacheSize int
+	swept     time.Time           // Last removal of expired lookups
+	used      map[int]*monthUsage // By key ID
+	pending   map[usageKey]*models.UsageRecord
+	flushing  map[usageKey]*models.UsageRecord // Taken by a Flush in progress
+}
+
+type cachedKey struct {
+	key     *models.APIKey
+	expires time.Time
+}
+
+// monthUsage is a key's requests this month: flushed by every instance, as
+// of the last refresh, plus those of this instance since
+type monthUsage struct {
+	month string
+	count int64
+}
+
+type usageKey struct {
+	keyID int
+	month string
+	class string
+}
+
+func New(keys repository.APIKeyRepository, usage repository.UsageRepository) *Meter {
+	return &Meter{
+		keys:      keys,
+		usage:     usage,
+		cache:     make(map[string]cachedKey),
+		cacheSize: keyCacheSize,
+		used:      make(map[int]*monthUsage),
+		pending:   make(map[usageKey]*models.UsageRecord),
+	}
+}
+
+// GenerateKey returns a new API key and the hash and prefix stored for it
+func GenerateKey() (key, hash, prefix string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	key = keyPrefix + hex.EncodeToString(b)
+	return key, HashKey(key), key[:len(keyPrefix)+6], nil
+}
+
+// HashKey returns the hex SHA-256 stored for a key
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate returns the API key matching key, ErrInvalidKey, or
+// ErrRevokedKey. Lookups are cached for a minute, up to keyCacheSize.
+func (m *Meter) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	hash := HashKey(key)
+	now := time.Now()
+
+	m.mu.Lock()
+	cached, ok := m.cache[hash]
+	m.mu.Unlock()
+	if !ok || now.After(cached.expires) {
+		apiKey, err := m.keys.GetByHash(ctx, hash)
+		if err != nil && err.Error() != "API key not found" {
+			return nil, err
+		}
+		// Unknown keys are cached too, so guessing costs no query
+		cached = cachedKey{key: apiKey, expires: now.Add(keyCacheTTL)}
+		m.mu.Lock()
+		m.cacheKey(hash, cached, now)
+		m.mu.Unlock()
+	}
+
+	switch {
+	case cached.key == nil:
+		return nil, ErrInvalidKey
+	case cached.key.RevokedAt != nil:
+		return nil, ErrRevokedKey
+	}
+	return cached.key, nil
+}
+
+// cacheKey caches a lookup. Expired lookups are removed once a TTL, and
+// when the cache is full; if it's still full, arbitrary lookups make room,
+// which at worst costs their keys a query. m.mu must be held.
+func (m *Meter) cacheKey(hash string, cached cachedKey, now time.Time) {
+	_, replaces := m.cache[hash]
+	if full := !replaces && len(m.cache) >= m.cacheSize; full || now.Sub(m.swept) >= keyCacheTTL {
+		for h, c := range m.cache {
+			if now.After(c.expires) {
+				delete(m.cache, h)
+			}
+		}
+		m.swept = now
+	}
+	if !replaces {
+		for h := range m.cache {
+			if len(m.cache) < m.cacheSize {
+				break
+			}
+			delete(m.cache, h)
+		}
+	}
+	m.cache[hash] = cached
+}
+
+// Admit counts a request of key against its quota, reporting false without
+// counting it once the quota is used up
+func (m *Meter) Admit(ctx context.Context, key *models.APIKey, now time.Time) (Status, bool, error) {
+	month := Month(now)
+	status := Status{Limit: key.MonthlyQuota, Reset: nextMonth(now)}
+
+	m.mu.Lock()
+	used, ok := m.used[key.ID]
+	m.mu.Unlock()
+	if !ok || used.month != month {
+		total, err := m.usage.MonthTotal(ctx, key.ID, month)
+		if err != nil {
+			return status, false, err
+		}
+		m.mu.Lock()
+		if current, ok := m.used[key.ID]; ok && current.month == month {
+			used = current // Loaded concurrently
+		} else {
+			used = &monthUsage{month: month, count: total + m.pendingRequests(key.ID, month)}
+			m.used[key.ID] = used
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if key.MonthlyQuota > 0 && used.count >= key.MonthlyQuota {
+		return status, false, nil
+	}
+	used.count++
+	if key.MonthlyQuota > 0 {
+		status.Remaining = key.MonthlyQuota - used.count
+	}
+	return status, true, nil
+}
+
+// Record adds an admitted request and its response bytes to the usage of
+// key in class, to be stored by the next Flush
+func (m *Meter) Record(key *models.APIKey, class string, bytes int64, now time.Time) {
+	k := usageKey{keyID: key.ID, month: Month(now), class: class}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.pending[k]
+	if !ok {
+		rec = &models.UsageRecord{APIKeyID: key.ID, Month: k.month, EndpointClass: class}
+		m.pending[k] = rec
+	}
+	rec.Requests++
+	rec.Bytes += bytes
+}
+
+// pendingRequests sums the unflushed requests of a key in month, including
+// those a flush is storing. m.mu must be held.
+func (m *Meter) pendingRequests(keyID int, month string) int64 {
+	var n int64
+	for _, records := range []map[usageKey]*models.UsageRecord{m.pending, m.flushing} {
+		for k, rec := range records {
+			if k.keyID == keyID && k.month == month {
+				n += rec.Requests
+			}
+		}
+	}
+	return n
+}
+
+// Flush stores the counted usage, then refreshes the month totals with what
+// other instances flushed. Counts that fail to store are kept for the next
+// flush. Run it as a scheduled job, and once more at shutdown.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+
+	m.mu.Lock()
+	pending := m.pending
+	m.pending, m.flushing = make(map[usageKey]*models.UsageRecord), pending
+	m.mu.Unlock()
+
+	if len(pending) > 0 {
+		records := make([]models.UsageRecord, 0, len(pending))
+		for _, rec := range pending {
+			records = append(records, *rec)
+		}
+		if err := m.usage.Add(ctx, records); err != nil {
+			m.restore(pending)
+			return err
+		}
+	}
+
+	// Stored totals now include this instance's counts; reload them on
+	// next use to pick up other instances' too
+	m.mu.Lock()
+	m.flushing = nil
+	for id, used := range m.used {
+		if m.pendingRequests(id, used.month) == 0 {
+			delete(m.used, id)
+		}
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// restore puts back counts a flush failed to store, merging them with
+// those recorded since
+func (m *Meter) restore(pending map[usageKey]*models.UsageRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flushing = nil
+	for k, rec := range pending {
+		if current, ok := m.pending[k]; ok {
+			current.Requests += rec.Requests
+			current.Bytes += rec.Bytes
+			continue
+		}
+		m.pending[k] = rec
+	}
+}
+
+// Forget drops the cached lookup of a key, so a revocation or quota change
+// made on this instance applies to its next request
+func (m *Meter) Forget(hash string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cache, hash)
+}
+
+type contextKey struct{}
+
+// WithKey marks a request as made with an API key
+func WithKey(ctx context.Context, key *models.APIKey) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the API key of the request, or nil when it was made
+// without one
+func FromContext(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(contextKey{}).(*models.APIKey)
+	return key
+}
+
+// Month returns the billing month of t, e.g. "2026-10"
+func Month(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+func nextMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
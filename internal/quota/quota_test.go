@@ -0,0 +1,145 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestMeter(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "quota.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	keys := repository.NewAPIKeyRepository(db)
+	usage := repository.NewUsageRepository(db)
+	raw, hash, prefix, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	apiKey := &models.APIKey{Name: "partner", KeyHash: hash, Prefix: prefix, MonthlyQuota: 3}
+	if err := keys.Create(ctx, apiKey); err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+
+	meter := New(keys, usage)
+	if _, err := meter.Authenticate(ctx, "ak_unknown"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("unknown key: got %v, want ErrInvalidKey", err)
+	}
+	key, err := meter.Authenticate(ctx, raw)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		status, ok, err := meter.Admit(ctx, key, now)
+		if err != nil || !ok {
+			t.Fatalf("request %d: admitted %v, error %v", i+1, ok, err)
+		}
+		if want := int64(2 - i); status.Remaining != want {
+			t.Errorf("request %d: remaining %d, want %d", i+1, status.Remaining, want)
+		}
+		meter.Record(key, "products:read", 100, now)
+	}
+	if err := meter.Flush(ctx); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	// A fresh meter sees the flushed usage, like another instance would
+	other := New(keys, usage)
+	if _, ok, _ := other.Admit(ctx, key, now); !ok {
+		t.Fatal("third request refused")
+	}
+	other.Record(key, "products:write", 50, now)
+	status, ok, err := other.Admit(ctx, key, now)
+	if err != nil || ok {
+		t.Fatalf("fourth request: admitted %v, error %v", ok, err)
+	}
+	if want := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC); !status.Reset.Equal(want) {
+		t.Errorf("reset %v, want %v", status.Reset, want)
+	}
+	if _, ok, _ := other.Admit(ctx, key, now.AddDate(0, 1, 0)); !ok {
+		t.Error("request next month refused")
+	}
+	if err := other.Flush(ctx); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	records, err := usage.ForMonth(ctx, "2026-10")
+	if err != nil {
+		t.Fatalf("failed to list usage: %v", err)
+	}
+	want := []models.UsageRecord{
+		{APIKeyID: key.ID, APIKeyName: "partner", Month: "2026-10", EndpointClass: "products:read", Requests: 2, Bytes: 200},
+		{APIKeyID: key.ID, APIKeyName: "partner", Month: "2026-10", EndpointClass: "products:write", Requests: 1, Bytes: 50},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d usage records, want %d: %+v", len(records), len(want), records)
+	}
+	for i := range want {
+		if records[i] != want[i] {
+			t.Errorf("record %d: got %+v, want %+v", i, records[i], want[i])
+		}
+	}
+
+	if err := keys.Revoke(ctx, key.ID); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if _, err := meter.Authenticate(ctx, raw); err != nil {
+		t.Errorf("cached key: got %v, want it still valid until forgotten", err)
+	}
+	meter.Forget(hash)
+	if _, err := meter.Authenticate(ctx, raw); !errors.Is(err, ErrRevokedKey) {
+		t.Errorf("revoked key: got %v, want ErrRevokedKey", err)
+	}
+}
+
+func TestMeter_CacheBounded(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "quota.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	meter := New(repository.NewAPIKeyRepository(db), repository.NewUsageRepository(db))
+	meter.cacheSize = 5
+	for i := 0; i < 20; i++ {
+		if _, err := meter.Authenticate(ctx, fmt.Sprintf("ak_guess%d", i)); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("guess %d: got %v, want ErrInvalidKey", i, err)
+		}
+		if len(meter.cache) > meter.cacheSize {
+			t.Fatalf("after %d guesses %d lookups are cached, want at most %d", i+1, len(meter.cache), meter.cacheSize)
+		}
+	}
+
+	// Expired lookups, negative ones included, go with the next sweep
+	for hash, cached := range meter.cache {
+		cached.expires = time.Now().Add(-time.Second)
+		meter.cache[hash] = cached
+	}
+	meter.swept = time.Time{}
+	if _, err := meter.Authenticate(ctx, "ak_another"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("got %v, want ErrInvalidKey", err)
+	}
+	if len(meter.cache) != 1 {
+		t.Errorf("%d lookups cached after expiry, want 1", len(meter.cache))
+	}
+}
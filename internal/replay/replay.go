@@ -0,0 +1,310 @@
+// Package replay sends recorded requests again, against another
+// environment, to reproduce production issues on staging. Requests are read
+// from HAR files, as browsers' developer tools and most proxies export them,
+// or from NDJSON with one request per line in vegeta's JSON target format,
+// which cmd/bench writes too.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Request is a recorded request. It marshals to vegeta's JSON target format,
+// which expects the body base64-encoded.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"` // Absolute, or a path and query
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// ReadNDJSON reads requests in vegeta's JSON target format, one per line.
+// Blank lines are skipped.
+func ReadNDJSON(r io.Reader) ([]Request, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20) // Bodies of bulk requests run long
+
+	var requests []Request
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var req Request
+		if err := json.Unmarshal([]byte(text), &req); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if req.Method == "" || req.URL == "" {
+			return nil, fmt.Errorf("line %d: method and url are required", line)
+		}
+		requests = append(requests, req)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read requests: %w", err)
+	}
+	return requests, nil
+}
+
+// har is the part of a HAR file replays use
+type har struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method  string `json:"method"`
+				URL     string `json:"url"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// ReadHAR reads the requests of a HAR file's entries, in order
+func ReadHAR(r io.Reader) ([]Request, error) {
+	var archive har
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("failed to decode HAR: %w", err)
+	}
+
+	requests := make([]Request, 0, len(archive.Log.Entries))
+	for _, entry := range archive.Log.Entries {
+		req := Request{Method: entry.Request.Method, URL: entry.Request.URL, Header: make(http.Header)}
+		for _, h := range entry.Request.Headers {
+			req.Header.Add(h.Name, h.Value)
+		}
+		if data := entry.Request.PostData; data != nil {
+			req.Body = []byte(data.Text)
+			if req.Header.Get("Content-Type") == "" && data.MimeType != "" {
+				req.Header.Set("Content-Type", data.MimeType)
+			}
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+// Rewrite changes a header of every request: sets it to Value, or removes it
+// when Value is empty
+type Rewrite struct {
+	Name  string
+	Value string
+}
+
+// ParseRewrite parses a rewrite of the form "Name: value", or "Name:" to
+// remove the header
+func ParseRewrite(s string) (Rewrite, error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return Rewrite{}, fmt.Errorf("invalid header rewrite %q, want \"Name: value\" or \"Name:\"", s)
+	}
+	return Rewrite{Name: http.CanonicalHeaderKey(name), Value: strings.TrimSpace(value)}, nil
+}
+
+// unsent are the headers of a recording that aren't replayed: the transport
+// sets them for the target's connection. HTTP/2 pseudo-headers, such as
+// :authority, are dropped too.
+var unsent = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"Accept-Encoding":   true, // Left to the transport, which decompresses what it asked for
+}
+
+// Config configures a replay
+type Config struct {
+	Target      string        // Base URL requests are sent to, replacing the recorded scheme and host
+	Rate        float64       // Requests per second at most, 0 for no limit
+	Concurrency int           // Requests in flight at most, default 1: in the recorded order
+	Methods     []string      // Only replay these methods when set, e.g. GET to stay read-only
+	Rewrites    []Rewrite     // Applied in order, after the recorded headers
+	Timeout     time.Duration // Of each request, default 30s
+}
+
+// Result is the outcome of one replayed request
+type Result struct {
+	Method   string
+	URL      string
+	Status   int // 0 when the request failed
+	Duration time.Duration
+	Err      error
+}
+
+// Summary counts a replay's outcomes
+type Summary struct {
+	Sent     int
+	Skipped  int         // By method
+	Failed   int         // Without a response
+	Statuses map[int]int // Of responses
+}
+
+// Replayer sends recorded requests to a target
+type Replayer struct {
+	cfg    Config
+	target *url.URL
+	client *http.Client
+}
+
+func New(cfg Config) (*Replayer, error) {
+	target, err := url.Parse(strings.TrimSuffix(cfg.Target, "/"))
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid target %q, want a base URL such as https://staging.example.com", cfg.Target)
+	}
+	if cfg.Rate < 0 {
+		return nil, fmt.Errorf("invalid rate %v, must not be negative", cfg.Rate)
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		// Redirects are replayed as recorded, if at all, not followed
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return &Replayer{cfg: cfg, target: target, client: client}, nil
+}
+
+// Replay sends requests to the target at the configured rate, passing each
+// outcome to report, which may be called concurrently, and returns the
+// counts. It stops early when ctx ends.
+func (r *Replayer) Replay(ctx context.Context, requests []Request, report func(Result)) (Summary, error) {
+	limit := rate.Inf
+	if r.cfg.Rate > 0 {
+		limit = rate.Limit(r.cfg.Rate)
+	}
+	limiter := rate.NewLimiter(limit, 1)
+
+	summary := Summary{Statuses: make(map[int]int)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, r.cfg.Concurrency)
+	send := func(req *http.Request) {
+		defer wg.Done()
+		defer func() { <-slots }()
+		result := r.send(req)
+
+		mu.Lock()
+		summary.Sent++
+		if result.Err != nil {
+			summary.Failed++
+		} else {
+			summary.Statuses[result.Status]++
+		}
+		mu.Unlock()
+		if report != nil {
+			report(result)
+		}
+	}
+
+	err := func() error {
+		for _, rec := range requests {
+			if !r.replayed(rec.Method) {
+				mu.Lock()
+				summary.Skipped++
+				mu.Unlock()
+				continue
+			}
+			req, err := r.build(ctx, rec)
+			if err != nil {
+				return err
+			}
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			wg.Add(1)
+			go send(req)
+		}
+		return nil
+	}()
+	wg.Wait() // For the requests in flight, also when stopping early
+	return summary, err
+}
+
+func (r *Replayer) replayed(method string) bool {
+	if len(r.cfg.Methods) == 0 {
+		return true
+	}
+	for _, m := range r.cfg.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// build returns the recorded request against the target: its path and query
+// under the target's base URL, its headers but those of its connection, and
+// then the rewrites
+func (r *Replayer) build(ctx context.Context, rec Request) (*http.Request, error) {
+	recorded, err := url.Parse(rec.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recorded URL %q: %w", rec.URL, err)
+	}
+	u := *r.target
+	u.Path = r.target.Path + recorded.Path
+	u.RawPath = ""
+	u.RawQuery = recorded.RawQuery
+
+	req, err := http.NewRequestWithContext(ctx, rec.Method, u.String(), bytes.NewReader(rec.Body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recorded request %s %s: %w", rec.Method, rec.URL, err)
+	}
+	for name, values := range rec.Header {
+		name = http.CanonicalHeaderKey(name)
+		if unsent[name] || strings.HasPrefix(name, ":") {
+			continue
+		}
+		req.Header[name] = append([]string(nil), values...)
+	}
+	for _, rw := range r.cfg.Rewrites {
+		if rw.Value == "" {
+			req.Header.Del(rw.Name)
+			continue
+		}
+		req.Header.Set(rw.Name, rw.Value)
+	}
+	return req, nil
+}
+
+func (r *Replayer) send(req *http.Request) Result {
+	result := Result{Method: req.Method, URL: req.URL.RequestURI()}
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	result.Status = resp.StatusCode
+	return result
+}
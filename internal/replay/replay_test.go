@@ -0,0 +1,152 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/bench"
+)
+
+const capture = `{"log":{"version":"1.2","entries":[
+	{"startedDateTime":"2026-05-01T12:00:00Z","request":{"method":"GET","url":"https://api.example.com/api/v1/products?limit=5&category=tools",
+		"headers":[{"name":":authority","value":"api.example.com"},{"name":"Authorization","value":"Bearer prod"},{"name":"Accept-Encoding","value":"gzip"},{"name":"X-Request-Id","value":"r1"}]}},
+	{"startedDateTime":"2026-05-01T12:00:01Z","request":{"method":"POST","url":"https://api.example.com/api/v1/products",
+		"headers":[{"name":"Authorization","value":"Bearer prod"},{"name":"Cookie","value":"session=1"},{"name":"Content-Length","value":"16"}],
+		"postData":{"mimeType":"application/json","text":"{\"name\":\"Drill\"}"}}}
+]}}`
+
+func TestReadHAR(t *testing.T) {
+	requests, err := ReadHAR(strings.NewReader(capture))
+	if err != nil {
+		t.Fatalf("ReadHAR: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("read %d requests, want 2", len(requests))
+	}
+	post := requests[1]
+	if post.Method != http.MethodPost || post.URL != "https://api.example.com/api/v1/products" || string(post.Body) != `{"name":"Drill"}` || post.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("request = %+v, want the POST with its body and type", post)
+	}
+}
+
+func TestReadNDJSON(t *testing.T) {
+	// cmd/bench's scenarios replay as they are
+	var buf bytes.Buffer
+	targets := []bench.Target{
+		{Method: http.MethodGet, URL: "http://localhost:8080/api/v1/products?limit=20"},
+		{Method: http.MethodPost, URL: "http://localhost:8080/api/v1/products", Header: http.Header{"Content-Type": {"application/json"}}, Body: []byte(`{"name":"Saw"}`)},
+	}
+	if err := bench.WriteVegeta(&buf, targets); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("\n")
+
+	requests, err := ReadNDJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadNDJSON: %v", err)
+	}
+	want := []Request{
+		{Method: http.MethodGet, URL: targets[0].URL},
+		{Method: http.MethodPost, URL: targets[1].URL, Header: targets[1].Header, Body: targets[1].Body},
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("ReadNDJSON = %+v, want %+v", requests, want)
+	}
+
+	if _, err := ReadNDJSON(strings.NewReader(`{"method":"GET","url":"/"}` + "\n{\"url\":\"/\"}\n")); err == nil || err.Error() != "line 2: method and url are required" {
+		t.Errorf("ReadNDJSON without a method = %v", err)
+	}
+}
+
+func TestParseRewrite(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rewrite
+		wantErr bool
+	}{
+		{in: "authorization: Bearer staging", want: Rewrite{Name: "Authorization", Value: "Bearer staging"}},
+		{in: "Cookie:", want: Rewrite{Name: "Cookie"}},
+		{in: "X-Trace: a:b", want: Rewrite{Name: "X-Trace", Value: "a:b"}},
+		{in: "Cookie", wantErr: true},
+		{in: ": value", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRewrite(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRewrite(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, strings.Join([]string{
+			r.Method, r.URL.RequestURI(), r.Header.Get("Authorization"), r.Header.Get("Cookie"), r.Header.Get("X-Request-Id"), r.Header.Get(":authority"), string(body),
+		}, "|"))
+		mu.Unlock()
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	requests, err := ReadHAR(strings.NewReader(capture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer, err := New(Config{
+		Target:   server.URL + "/staging/",
+		Rewrites: []Rewrite{{Name: "Authorization", Value: "Bearer staging"}, {Name: "Cookie"}},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var results []Result
+	summary, err := replayer.Replay(context.Background(), requests, func(r Result) { results = append(results, r) })
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	want := []string{
+		"GET|/staging/api/v1/products?limit=5&category=tools|Bearer staging||r1||",
+		`POST|/staging/api/v1/products|Bearer staging||||{"name":"Drill"}`,
+	}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("received %q, want %q", received, want)
+	}
+	if summary.Sent != 2 || summary.Failed != 0 || !reflect.DeepEqual(summary.Statuses, map[int]int{200: 1, 201: 1}) {
+		t.Errorf("summary = %+v", summary)
+	}
+	if len(results) != 2 || results[1].Status != http.StatusCreated || results[1].URL != "/staging/api/v1/products" {
+		t.Errorf("results = %+v", results)
+	}
+
+	// Read-only
+	readOnly, err := New(Config{Target: server.URL, Methods: []string{"get"}, Concurrency: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err = readOnly.Replay(context.Background(), requests, nil)
+	if err != nil || summary.Sent != 1 || summary.Skipped != 1 {
+		t.Errorf("Replay of GETs = %+v, %v; want 1 sent, 1 skipped", summary, err)
+	}
+}
+
+func TestNew_InvalidTarget(t *testing.T) {
+	for _, target := range []string{"", "staging.example.com", "://"} {
+		if _, err := New(Config{Target: target}); err == nil {
+			t.Errorf("New(%q) succeeded, want an invalid target", target)
+		}
+	}
+}
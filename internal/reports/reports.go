@@ -0,0 +1,286 @@
+// Package reports stores named SQL report templates and runs them with typed
+// parameters, within time and row limits, auditing every change and run
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+var (
+	ErrInvalidReport = errors.New("invalid report")
+	ErrInvalidParam  = errors.New("invalid report param")
+)
+
+const maxDescriptionLength = 1000
+
+var (
+	namePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,99}$`)
+	paramPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// Config limits report runs
+type Config struct {
+	Timeout time.Duration // Of a run's query
+	MaxRows int           // Returned by a run; more set Truncated
+}
+
+type Service struct {
+	repo   repository.ReportRepository
+	audit  repository.AuditRepository
+	tx     repository.Transactor
+	cfg    Config
+	logger *slog.Logger
+}
+
+func NewService(repo repository.ReportRepository, audit repository.AuditRepository, tx repository.Transactor, cfg Config, logger *slog.Logger) *Service {
+	return &Service{repo: repo, audit: audit, tx: tx, cfg: cfg, logger: logger}
+}
+
+// Save validates a report and stores it as actor, replacing the report of
+// the same name; created says there was none. The query is planned by the
+// database first, so one it rejects isn't stored.
+func (s *Service) Save(ctx context.Context, report *models.Report, actor string) (created bool, err error) {
+	tmpl, err := validate(report)
+	if err != nil {
+		return false, err
+	}
+	args := make([]interface{}, len(tmpl.Params))
+	for i, name := range tmpl.Params {
+		args[i] = zero(paramType(report.Params, name))
+	}
+	if err := s.repo.Check(ctx, tmpl.SQL, args); err != nil {
+		return false, err
+	}
+
+	err = s.tx.WithTx(ctx, func(ctx context.Context) error {
+		existing, err := s.repo.GetByName(ctx, report.Name)
+		switch {
+		case err != nil && err.Error() == "report not found":
+			created = true
+			report.CreatedBy = actor
+			err = s.repo.Create(ctx, report)
+		case err == nil:
+			report.CreatedBy, report.CreatedAt = existing.CreatedBy, existing.CreatedAt
+			err = s.repo.Update(ctx, report)
+		}
+		if err != nil {
+			return err
+		}
+
+		action := "report.update"
+		if created {
+			action = "report.create"
+		}
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("report saved", "report", report.Name, "created", created)
+		})
+		return s.record(ctx, action, actor, report.Name, map[string]interface{}{"query": report.Query, "params": report.Params})
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return created, nil
+}
+
+// Delete removes a report, as actor
+func (s *Service) Delete(ctx context.Context, name, actor string) error {
+	return s.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Delete(ctx, name); err != nil {
+			return err
+		}
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("report deleted", "report", name)
+		})
+		return s.record(ctx, "report.delete", actor, name, map[string]interface{}{})
+	})
+}
+
+// Run runs a report with params bound by name, as actor. Runs that reached
+// the database are audited, without the params: they may identify customers.
+func (s *Service) Run(ctx context.Context, name string, params map[string]json.RawMessage, actor string) (*models.ReportResult, error) {
+	report, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := Compile(report.Query)
+	if err != nil {
+		return nil, fmt.Errorf("stored report %s no longer compiles: %w", name, err)
+	}
+	args, err := bind(report.Params, tmpl.Params, params)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err := s.repo.Run(ctx, tmpl.SQL, args, s.cfg.MaxRows, s.cfg.Timeout)
+	duration := time.Since(start).Round(time.Millisecond)
+
+	details := map[string]interface{}{"duration": duration.String()}
+	if err != nil {
+		details["error"] = err.Error()
+	} else {
+		details["rows"], details["truncated"] = result.RowCount, result.Truncated
+	}
+	if auditErr := s.record(ctx, "report.run", actor, name, details); auditErr != nil {
+		s.logger.Error("failed to record report run in audit log", "error", auditErr, "report", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Report, result.Duration = name, duration.String()
+	s.logger.Info("report run", "report", name, "rows", result.RowCount, "truncated", result.Truncated, "duration", duration)
+	return result, nil
+}
+
+// validate checks a report's fields and compiles its query, whose
+// placeholders must be exactly its params
+func validate(report *models.Report) (*Template, error) {
+	switch {
+	case !namePattern.MatchString(report.Name):
+		return nil, fmt.Errorf("%w: name must be 1 to 100 lowercase letters, digits, - and _", ErrInvalidReport)
+	case len(report.Description) > maxDescriptionLength:
+		return nil, fmt.Errorf("%w: description must be at most %d characters", ErrInvalidReport, maxDescriptionLength)
+	}
+	if report.Params == nil {
+		report.Params = []models.ReportParam{}
+	}
+
+	declared := map[string]bool{}
+	for _, p := range report.Params {
+		switch {
+		case !paramPattern.MatchString(p.Name):
+			return nil, fmt.Errorf("%w: param name %q must be lowercase letters, digits, and _", ErrInvalidReport, p.Name)
+		case declared[p.Name]:
+			return nil, fmt.Errorf("%w: param %s is declared twice", ErrInvalidReport, p.Name)
+		case !contains(models.ReportParamTypes, p.Type):
+			return nil, fmt.Errorf("%w: type of param %s must be one of %s", ErrInvalidReport, p.Name, strings.Join(models.ReportParamTypes, ", "))
+		}
+		declared[p.Name] = true
+	}
+
+	tmpl, err := Compile(report.Query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+	for _, name := range tmpl.Params {
+		if !declared[name] {
+			return nil, fmt.Errorf("%w: placeholder :%s isn't a declared param", ErrInvalidReport, name)
+		}
+		delete(declared, name)
+	}
+	for _, p := range report.Params {
+		if declared[p.Name] {
+			return nil, fmt.Errorf("%w: param %s isn't used in the query", ErrInvalidReport, p.Name)
+		}
+	}
+
+	return tmpl, nil
+}
+
+// bind converts params to the arguments of a template's placeholders,
+// rejecting unknown ones so a typo isn't silently ignored
+func bind(declared []models.ReportParam, placeholders []string, params map[string]json.RawMessage) ([]interface{}, error) {
+	for name := range params {
+		if paramType(declared, name) == "" {
+			return nil, fmt.Errorf("%w: %s isn't a param of the report", ErrInvalidParam, name)
+		}
+	}
+
+	args := make([]interface{}, len(placeholders))
+	for i, name := range placeholders {
+		raw, ok := params[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is missing", ErrInvalidParam, name)
+		}
+
+		var err error
+		typ := paramType(declared, name)
+		switch typ {
+		case "int":
+			var n int64
+			err = json.Unmarshal(raw, &n)
+			args[i] = n
+		case "number":
+			var f float64
+			err = json.Unmarshal(raw, &f)
+			args[i] = f
+		case "text":
+			var s string
+			err = json.Unmarshal(raw, &s)
+			args[i] = s
+		case "bool":
+			var b bool
+			err = json.Unmarshal(raw, &b)
+			args[i] = b
+		case "timestamp":
+			var t time.Time
+			err = json.Unmarshal(raw, &t)
+			args[i] = t.UTC()
+		}
+		if err != nil || string(raw) == "null" {
+			return nil, fmt.Errorf("%w: %s must be a %s", ErrInvalidParam, name, typ)
+		}
+	}
+
+	return args, nil
+}
+
+func paramType(params []models.ReportParam, name string) string {
+	for _, p := range params {
+		if p.Name == name {
+			return p.Type
+		}
+	}
+	return ""
+}
+
+// zero is a value of a param type to plan a query with
+func zero(typ string) interface{} {
+	switch typ {
+	case "int":
+		return int64(0)
+	case "number":
+		return float64(0)
+	case "bool":
+		return false
+	case "timestamp":
+		return time.Unix(0, 0).UTC()
+	default:
+		return ""
+	}
+}
+
+// record audits a change or run of a report, in the transaction ctx carries
+// if any
+func (s *Service) record(ctx context.Context, action, actor, name string, details map[string]interface{}) error {
+	entry := &models.AuditEntry{
+		Action:     action,
+		Actor:      actor,
+		EntityType: "report",
+		EntityID:   name,
+	}
+	entry.Details, _ = json.Marshal(details)
+	return s.audit.Create(ctx, entry)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
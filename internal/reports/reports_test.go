@@ -0,0 +1,120 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestService_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "reports.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	products := repository.NewProductRepository(db)
+	audit := repository.NewAuditRepository(db)
+	repo := repository.NewReportRepository(db)
+	service := NewService(repo, audit, db, Config{Timeout: 5 * time.Second, MaxRows: 2}, logger)
+
+	for i, sku := range []string{"R-1", "R-2", "R-3"} {
+		if err := products.Create(ctx, &models.Product{SKU: sku, Name: sku, Quantity: (i + 1) * 10, UnitPrice: 1}); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	report := &models.Report{
+		Name:   "stocked",
+		Query:  "SELECT sku, quantity FROM products WHERE quantity >= :min ORDER BY quantity DESC",
+		Params: []models.ReportParam{{Name: "min", Type: "int"}},
+	}
+	if created, err := service.Save(ctx, report, "admin"); err != nil || !created {
+		t.Fatalf("Save() = %v, %v, want true", created, err)
+	}
+	report.Description = "Products in stock"
+	if created, err := service.Save(ctx, report, "admin"); err != nil || created {
+		t.Fatalf("Save() = %v, %v, want false", created, err)
+	}
+
+	for _, invalid := range []*models.Report{
+		{Name: "Bad Name", Query: "SELECT 1"},
+		{Name: "undeclared", Query: "SELECT sku FROM products WHERE quantity > :min"},
+		{Name: "unused", Query: "SELECT sku FROM products", Params: []models.ReportParam{{Name: "min", Type: "int"}}},
+		{Name: "untyped", Query: "SELECT sku FROM products WHERE quantity > :min", Params: []models.ReportParam{{Name: "min", Type: "money"}}},
+		{Name: "secrets", Query: "SELECT * FROM api_keys"},
+	} {
+		if _, err := service.Save(ctx, invalid, "admin"); !errors.Is(err, ErrInvalidReport) {
+			t.Errorf("Save(%s) error = %v, want ErrInvalidReport", invalid.Name, err)
+		}
+	}
+
+	// Valid, yet the database rejects it: nothing is stored
+	var queryErr *repository.QueryError
+	if _, err := service.Save(ctx, &models.Report{Name: "missing", Query: "SELECT no_such_column FROM products"}, "admin"); !errors.As(err, &queryErr) {
+		t.Errorf("Save() error = %v, want QueryError", err)
+	}
+	if _, err := repo.GetByName(ctx, "missing"); err == nil {
+		t.Error("rejected report was stored")
+	}
+
+	result, err := service.Run(ctx, "stocked", map[string]json.RawMessage{"min": json.RawMessage("10")}, "admin")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.RowCount != 2 || !result.Truncated || result.Rows[0][0] != "R-3" {
+		t.Errorf("Run() = %+v, want R-3 first of 2 rows, truncated", result)
+	}
+	result, err = service.Run(ctx, "stocked", map[string]json.RawMessage{"min": json.RawMessage("25")}, "admin")
+	if err != nil || result.RowCount != 1 || result.Truncated {
+		t.Errorf("Run() = %+v, %v, want 1 row", result, err)
+	}
+
+	for _, params := range []map[string]json.RawMessage{
+		{},
+		{"min": json.RawMessage(`"ten"`)},
+		{"min": json.RawMessage("null")},
+		{"min": json.RawMessage("1"), "max": json.RawMessage("2")},
+	} {
+		if _, err := service.Run(ctx, "stocked", params, "admin"); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("Run(%s) error = %v, want ErrInvalidParam", params, err)
+		}
+	}
+
+	if err := service.Delete(ctx, "stocked", "admin"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := service.Run(ctx, "stocked", nil, "admin"); err == nil || err.Error() != "report not found" {
+		t.Errorf("Run() error = %v, want report not found", err)
+	}
+
+	entries, err := audit.List(ctx, time.Time{}, time.Now().Add(time.Minute), 100)
+	if err != nil {
+		t.Fatalf("failed to list audit entries: %v", err)
+	}
+	actions := map[string]int{}
+	for _, e := range entries {
+		if e.EntityType == "report" {
+			actions[e.Action]++
+		}
+	}
+	for action, want := range map[string]int{"report.create": 1, "report.update": 1, "report.run": 2, "report.delete": 1} {
+		if actions[action] != want {
+			t.Errorf("audited %s %d times, want %d", action, actions[action], want)
+		}
+	}
+}
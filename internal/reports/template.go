@@ -0,0 +1,323 @@
+package reports
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tables are what report queries may read: the catalog, stock, and
+// purchasing. Credentials, subscriptions, audit and outbox rows, and report
+// definitions themselves are left out.
+var Tables = []string{
+	"units",
+	"products",
+	"product_tags",
+	"product_regions",
+	"product_slugs",
+	"products_history",
+	"stock_movements",
+	"stock_receipts",
+	"inventory_valuations",
+	"product_lots",
+	"serial_numbers",
+	"stock_takes",
+	"stock_take_counts",
+	"product_returns",
+	"product_forecasts",
+	"product_lead_times",
+	"product_suppliers",
+	"purchase_orders",
+	"purchase_order_lines",
+	"bundle_components",
+	"promotions",
+}
+
+// Functions are what report queries may call: aggregates, window functions,
+// and scalar functions of both dialects that only compute on their arguments
+var Functions = []string{
+	"COUNT", "SUM", "AVG", "MIN", "MAX",
+	"ROW_NUMBER", "RANK", "DENSE_RANK", "NTILE", "PERCENT_RANK", "CUME_DIST",
+	"LAG", "LEAD", "FIRST_VALUE", "LAST_VALUE",
+	"COALESCE", "NULLIF", "CAST", "GREATEST", "LEAST",
+	"ABS", "ROUND", "FLOOR", "CEIL", "CEILING",
+	"LOWER", "UPPER", "LENGTH", "TRIM", "LTRIM", "RTRIM", "SUBSTR", "SUBSTRING", "REPLACE", "CONCAT",
+	"DATE", "DATE_TRUNC", "EXTRACT", "STRFTIME", "NOW",
+}
+
+// keywords are the SQL words a select statement is made of. They may come
+// before a parenthesis and aren't table aliases.
+var keywords = setOf(
+	"SELECT", "DISTINCT", "FROM", "WHERE", "AND", "OR", "NOT", "IN", "EXISTS", "ANY", "ALL", "AS",
+	"JOIN", "LEFT", "RIGHT", "INNER", "OUTER", "FULL", "CROSS", "LATERAL", "ON", "USING",
+	"GROUP", "BY", "HAVING", "ORDER", "ASC", "DESC", "NULLS", "FIRST", "LAST", "LIMIT", "OFFSET",
+	"UNION", "INTERSECT", "EXCEPT", "WITH", "RECURSIVE", "WINDOW", "OVER", "PARTITION", "FILTER",
+	"ROWS", "RANGE", "CASE", "WHEN", "THEN", "ELSE", "END", "IS", "NULL", "LIKE", "ILIKE",
+	"BETWEEN", "TRUE", "FALSE", "VALUES", "INTERVAL",
+)
+
+// forbidden are words of statements that write or change the session. A
+// read-only transaction would reject most; SQLite has none.
+var forbidden = setOf(
+	"INSERT", "UPDATE", "DELETE", "MERGE", "UPSERT", "INTO", "RETURNING",
+	"CREATE", "ALTER", "DROP", "TRUNCATE", "GRANT", "REVOKE", "COPY", "CALL", "EXECUTE",
+	"LOCK", "SET", "VACUUM", "ANALYZE", "ATTACH", "DETACH", "PRAGMA", "LISTEN", "NOTIFY",
+	"TABLE", // TABLE x is SELECT * FROM x, without a FROM to check
+)
+
+// fromEnds are the words ending a FROM list
+var fromEnds = setOf("WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET", "UNION", "INTERSECT", "EXCEPT", "WINDOW")
+
+var functions = setOf(Functions...)
+
+func setOf(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenParam
+	tokenString
+	tokenNumber
+	tokenPunct
+)
+
+type token struct {
+	kind       tokenKind
+	text       string // Upper-cased for identifiers, the name for params
+	start, end int    // In the query
+}
+
+// Template is a validated report query, its :name placeholders numbered
+type Template struct {
+	SQL    string   // With $1, $2, ... for the placeholders
+	Params []string // Names of the placeholders, $1 first
+}
+
+// Compile validates a report query and numbers its placeholders. The query
+// must be one SELECT, possibly with a WITH clause, reading only Tables and
+// calling only Functions; comments, quoted identifiers, and positional
+// placeholders are rejected, so what's checked is what runs.
+func Compile(query string) (*Template, error) {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 || tokens[0].kind != tokenIdent || (tokens[0].text != "SELECT" && tokens[0].text != "WITH") {
+		return nil, fmt.Errorf("query must start with SELECT or WITH")
+	}
+	if err := check(tokens); err != nil {
+		return nil, err
+	}
+
+	// Replace placeholders back to front, so earlier offsets hold
+	tmpl := &Template{}
+	positions := map[string]int{}
+	for _, t := range tokens {
+		if t.kind == tokenParam && positions[t.text] == 0 {
+			tmpl.Params = append(tmpl.Params, t.text)
+			positions[t.text] = len(tmpl.Params)
+		}
+	}
+	sql := query
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if t := tokens[i]; t.kind == tokenParam {
+			sql = sql[:t.start] + "$" + strconv.Itoa(positions[t.text]) + sql[t.end:]
+		}
+	}
+	tmpl.SQL = sql
+	return tmpl, nil
+}
+
+// check walks the tokens of a query, tracking for every level of
+// parentheses whether it's a select (so FROM names tables, not the FROM of
+// EXTRACT) and whether its FROM list is open (so a comma names a table). A
+// parenthesis where a table is expected opens a FROM item, a subquery or a
+// join list, whose names are checked like those of the FROM around it.
+//
+// A CTE's name is only trusted where Postgres resolves it to the CTE: after
+// its definition closes, up to the end of the level of its WITH, and inside
+// its own body under WITH RECURSIVE. Anywhere else the name is a table.
+func check(tokens []token) error {
+	type level struct {
+		selects, from bool
+		cte           string   // Of the CTE this level is the body of
+		recursive     bool     // Whether that CTE is visible in its own body
+		ctes          []string // Made visible by this level's WITH, until it closes
+		withRecursive bool     // Whether this level's WITH is a WITH RECURSIVE
+	}
+	levels := []level{{}}
+	visible := map[string]int{} // CTE names in scope, by how many levels define them
+	expectTable := false
+	cte := "" // Of a CTE whose body opens at the next parenthesis
+	for i, t := range tokens {
+		top := &levels[len(levels)-1]
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1].text
+		}
+
+		fromItem := false
+		if expectTable {
+			expectTable = t.text == "LATERAL"
+			fromItem = t.text == "("
+			if t.kind == tokenIdent && next != "(" && !keywords[t.text] && !forbidden[t.text] {
+				if err := checkTable(t, visible); err != nil {
+					return err
+				}
+			}
+		}
+
+		switch t.kind {
+		case tokenPunct:
+			switch t.text {
+			case "(":
+				recursive := cte != "" && top.withRecursive
+				levels = append(levels, level{from: fromItem, cte: cte, recursive: recursive})
+				if recursive {
+					visible[cte]++
+				}
+				cte = ""
+				expectTable = fromItem
+			case ")":
+				if len(levels) == 1 {
+					return fmt.Errorf("unbalanced parentheses")
+				}
+				closed := levels[len(levels)-1]
+				for _, name := range closed.ctes {
+					visible[name]--
+				}
+				levels = levels[:len(levels)-1]
+				if closed.cte != "" {
+					parent := &levels[len(levels)-1]
+					parent.ctes = append(parent.ctes, closed.cte)
+					if !closed.recursive {
+						visible[closed.cte]++
+					}
+				}
+			case ",":
+				expectTable = top.from
+			}
+		case tokenIdent:
+			switch {
+			case forbidden[t.text]:
+				return fmt.Errorf("%s isn't allowed", t.text)
+			case t.text == "FOR" && (next == "UPDATE" || next == "SHARE" || next == "NO" || next == "KEY"):
+				return fmt.Errorf("locking clauses aren't allowed")
+			case next == "(" && !keywords[t.text] && !functions[t.text]:
+				return fmt.Errorf("function %s isn't allowed, only %s", strings.ToLower(t.text), strings.ToLower(strings.Join(Functions, ", ")))
+			case t.text == "WITH":
+				top.withRecursive = next == "RECURSIVE"
+			case next == "AS" && i > 0 && i+2 < len(tokens) && tokens[i+2].text == "(" &&
+				(tokens[i-1].text == "WITH" || tokens[i-1].text == "RECURSIVE" || tokens[i-1].text == ","):
+				cte = t.text
+			case t.text == "SELECT":
+				top.selects, top.from = true, false
+			case t.text == "FROM" && top.selects:
+				top.from, expectTable = true, true
+			case t.text == "JOIN":
+				expectTable = true
+			case fromEnds[t.text]:
+				top.from = false
+			}
+		}
+	}
+	if len(levels) != 1 {
+		return fmt.Errorf("unbalanced parentheses")
+	}
+	return nil
+}
+
+func checkTable(t token, ctes map[string]int) error {
+	if strings.Contains(t.text, ".") {
+		return fmt.Errorf("table %s must not be schema-qualified", strings.ToLower(t.text))
+	}
+	if ctes[t.text] > 0 {
+		return nil
+	}
+	for _, table := range Tables {
+		if strings.EqualFold(table, t.text) {
+			return nil
+		}
+	}
+	return fmt.Errorf("table %s isn't allowed, only %s", strings.ToLower(t.text), strings.Join(Tables, ", "))
+}
+
+// tokenize splits a query into tokens, dropping the contents of string
+// literals
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '\'':
+			for i++; ; i++ {
+				if i >= len(query) {
+					return nil, fmt.Errorf("unterminated string literal")
+				}
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, start: start, end: i})
+		case c == '"' || c == '`' || c == '[':
+			return nil, fmt.Errorf("quoted identifiers aren't allowed")
+		case c == '-' && i+1 < len(query) && query[i+1] == '-', c == '/' && i+1 < len(query) && query[i+1] == '*':
+			return nil, fmt.Errorf("comments aren't allowed")
+		case c == ';':
+			return nil, fmt.Errorf("only one statement is allowed")
+		case c == '$' || c == '?' || c == '@':
+			return nil, fmt.Errorf("use :name placeholders")
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			i += 2
+			tokens = append(tokens, token{kind: tokenPunct, text: "::", start: start, end: i})
+		case c == ':':
+			i++
+			for i < len(query) && identChar(query[i]) {
+				i++
+			}
+			if i == start+1 {
+				return nil, fmt.Errorf("placeholder at %d has no name", start)
+			}
+			tokens = append(tokens, token{kind: tokenParam, text: strings.ToLower(query[start+1 : i]), start: start, end: i})
+		case identStart(c):
+			for i < len(query) && (identChar(query[i]) || query[i] == '.' && i+1 < len(query) && identStart(query[i+1])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: strings.ToUpper(query[start:i]), start: start, end: i})
+		case c >= '0' && c <= '9':
+			for i < len(query) && (identChar(query[i]) || query[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, start: start, end: i})
+		default:
+			i++
+			tokens = append(tokens, token{kind: tokenPunct, text: string(c), start: start, end: i})
+		}
+	}
+	return tokens, nil
+}
+
+func identStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func identChar(c byte) bool {
+	return identStart(c) || c >= '0' && c <= '9'
+}
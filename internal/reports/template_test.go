@@ -0,0 +1,81 @@
+package reports
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tmpl, err := Compile("SELECT sku FROM products WHERE quantity >= :min AND name ILIKE :name OR quantity < :min;")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if want := "SELECT sku FROM products WHERE quantity >= $1 AND name ILIKE $2 OR quantity < $1"; tmpl.SQL != want {
+		t.Errorf("SQL = %q, want %q", tmpl.SQL, want)
+	}
+	if want := []string{"min", "name"}; !reflect.DeepEqual(tmpl.Params, want) {
+		t.Errorf("Params = %v, want %v", tmpl.Params, want)
+	}
+
+	for _, query := range []string{
+		"SELECT p.sku, SUM(m.delta) FROM products p JOIN stock_movements m ON m.product_id = p.id GROUP BY p.sku",
+		"SELECT sku FROM products, product_tags WHERE product_tags.product_id = products.id",
+		"WITH recent AS (SELECT product_id FROM stock_movements) SELECT COUNT(*) FROM recent",
+		"SELECT EXTRACT(YEAR FROM created_at) FROM products",
+		"SELECT sku, RANK() OVER (ORDER BY quantity DESC) FROM products",
+		"SELECT 'drop; -- not a comment' AS note FROM units",
+		"SELECT quantity::text FROM products WHERE id IN (SELECT product_id FROM product_lots)",
+		"SELECT product_id, unit_price FROM products_history WHERE valid_to IS NULL",
+		"SELECT * FROM (products p JOIN product_tags t ON t.product_id = p.id)",
+		"SELECT * FROM (SELECT sku FROM products) p JOIN units u USING (unit)",
+		"WITH a AS (SELECT id FROM products), b AS (SELECT id FROM a) SELECT * FROM b",
+		"WITH RECURSIVE n AS (SELECT 1 AS i UNION ALL SELECT i + 1 FROM n WHERE i < 5) SELECT * FROM n",
+		"SELECT * FROM products WHERE id IN (WITH t AS (SELECT product_id FROM product_tags) SELECT product_id FROM t)",
+	} {
+		if _, err := Compile(query); err != nil {
+			t.Errorf("Compile(%q) error = %v, want nil", query, err)
+		}
+	}
+}
+
+func TestCompile_Rejects(t *testing.T) {
+	for _, tt := range []struct {
+		query, want string
+	}{
+		{"", "must start with SELECT"},
+		{"DELETE FROM products", "must start with SELECT"},
+		{"SELECT * FROM products; DROP TABLE products", "one statement"},
+		{"SELECT * FROM api_keys", "table api_keys isn't allowed"},
+		{"SELECT * FROM products JOIN audit_log ON true", "table audit_log isn't allowed"},
+		{"SELECT * FROM products, report_definitions", "table report_definitions isn't allowed"},
+		{"SELECT * FROM (SELECT * FROM webhook_subscriptions) w", "table webhook_subscriptions isn't allowed"},
+		{"SELECT * FROM (TABLE api_keys) t", "TABLE isn't allowed"},
+		{"SELECT * FROM (api_keys CROSS JOIN products)", "table api_keys isn't allowed"},
+		{"SELECT * FROM products JOIN ((products_history p CROSS JOIN audit_log a)) ON true", "table audit_log isn't allowed"},
+		{"SELECT * FROM products WHERE EXISTS (TABLE access_tokens)", "TABLE isn't allowed"},
+		{"SELECT * FROM products, LATERAL (SELECT * FROM api_keys) k", "table api_keys isn't allowed"},
+		{"WITH api_keys AS (SELECT * FROM api_keys) SELECT * FROM api_keys", "table api_keys isn't allowed"},
+		{"WITH x AS (SELECT * FROM access_tokens), access_tokens AS (SELECT 1) SELECT * FROM x", "table access_tokens isn't allowed"},
+		{"WITH a AS (SELECT * FROM (WITH RECURSIVE r AS (SELECT 1) SELECT * FROM r) z), api_keys AS (SELECT * FROM api_keys) SELECT * FROM a", "table api_keys isn't allowed"},
+		{"SELECT * FROM (WITH k AS (SELECT 1) SELECT * FROM k) a, k", "table k isn't allowed"},
+		{"WITH pg_read_file AS (SELECT 1) SELECT pg_read_file('/etc/passwd')", "function pg_read_file isn't allowed"},
+		{"SELECT * FROM public.products", "schema-qualified"},
+		{`SELECT * FROM "api_keys"`, "quoted identifiers"},
+		{"SELECT * FROM products -- x", "comments"},
+		{"SELECT * FROM products /* x */", "comments"},
+		{"SELECT * FROM products WHERE id = $1", ":name placeholders"},
+		{"SELECT * FROM products WHERE id = ?", ":name placeholders"},
+		{"SELECT pg_sleep(10)", "function pg_sleep isn't allowed"},
+		{"SELECT * FROM products FOR UPDATE", "locking clauses"},
+		{"WITH x AS (DELETE FROM products RETURNING *) SELECT * FROM x", "DELETE isn't allowed"},
+		{"SELECT * INTO copy FROM products", "INTO isn't allowed"},
+		{"SELECT (1", "unbalanced"},
+		{"SELECT 'open", "unterminated"},
+	} {
+		_, err := Compile(tt.query)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%q) error = %v, want %q", tt.query, err, tt.want)
+		}
+	}
+}
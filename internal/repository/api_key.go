@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type APIKeyRepository interface {
+	// Create stores a key with its hash and prefix already set
+	Create(ctx context.Context, key *models.APIKey) error
+
+	GetByID(ctx context.Context, id int) (*models.APIKey, error)
+
+	// GetByHash returns the key, revoked or not, whose key hashes to hash
+	GetByHash(ctx context.Context, hash string) (*models.APIKey, error)
+
+	// Update changes the name and quota of a key
+	Update(ctx context.Context, key *models.APIKey) error
+
+	// Revoke marks a key revoked now; revoked keys are kept for billing
+	Revoke(ctx context.Context, id int) error
+
+	// List returns keys, oldest first
+	List(ctx context.Context, limit, offset int) ([]*models.APIKey, error)
+
+	Count(ctx context.Context) (int, error)
+}
+
+type apiKeyRepo struct {
+	db *database.DB
+}
+
+func NewAPIKeyRepository(db *database.DB) APIKeyRepository {
+	return &apiKeyRepo{db: db}
+}
+
+const apiKeyColumns = `id, name, key_hash, prefix, monthly_quota, region, created_at, revoked_at`
+
+func (r *apiKeyRepo) Create(ctx context.Context, key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (name, key_hash, prefix, monthly_quota, region, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	key.CreatedAt = time.Now()
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, key.Name, key.KeyHash, key.Prefix, key.MonthlyQuota, key.Region, key.CreatedAt).Scan(&key.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepo) GetByID(ctx context.Context, id int) (*models.APIKey, error) {
+	return r.get(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id)
+}
+
+func (r *apiKeyRepo) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	return r.get(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, hash)
+}
+
+func (r *apiKeyRepo) get(ctx context.Context, query string, arg interface{}) (*models.APIKey, error) {
+	key, err := scanAPIKey(r.db.Conn(ctx).QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("API key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepo) Update(ctx context.Context, key *models.APIKey) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `UPDATE api_keys SET name = $1, monthly_quota = $2, region = $3 WHERE id = $4`, key.Name, key.MonthlyQuota, key.Region, key.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+
+	return requireRow(result)
+}
+
+func (r *apiKeyRepo) Revoke(ctx context.Context, id int) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	return requireRow(result)
+}
+
+// requireRow returns "API key not found" when result affected no row
+func requireRow(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("API key not found")
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepo) List(ctx context.Context, limit, offset int) ([]*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM api_keys`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+
+	return count, nil
+}
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.KeyHash, &key.Prefix, &key.MonthlyQuota, &key.Region, &key.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
+}
+
+// UsageRepository accumulates the requests of API keys per month and
+// endpoint class
+type UsageRepository interface {
+	// Add adds the counts of records to the stored ones, in one transaction
+	Add(ctx context.Context, records []models.UsageRecord) error
+
+	// MonthTotal returns the requests of a key in month, across classes
+	MonthTotal(ctx context.Context, apiKeyID int, month string) (int64, error)
+
+	// ForMonth returns the usage of every key in month, by key and class
+	ForMonth(ctx context.Context, month string) ([]models.UsageRecord, error)
+}
+
+type usageRepo struct {
+	db *database.DB
+}
+
+func NewUsageRepository(db *database.DB) UsageRepository {
+	return &usageRepo{db: db}
+}
+
+func (r *usageRepo) Add(ctx context.Context, records []models.UsageRecord) error {
+	query := `
+		INSERT INTO api_usage (api_key_id, month, endpoint_class, requests, bytes)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (api_key_id, month, endpoint_class) DO UPDATE SET
+			requests = api_usage.requests + EXCLUDED.requests,
+			bytes = api_usage.bytes + EXCLUDED.bytes
+	`
+
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		for _, rec := range records {
+			if _, err := r.db.Conn(ctx).ExecContext(ctx, query, rec.APIKeyID, rec.Month, rec.EndpointClass, rec.Requests, rec.Bytes); err != nil {
+				return fmt.Errorf("failed to record API usage: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *usageRepo) MonthTotal(ctx context.Context, apiKeyID int, month string) (int64, error) {
+	var total int64
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(requests), 0) FROM api_usage WHERE api_key_id = $1 AND month = $2
+	`, apiKeyID, month).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum API usage: %w", err)
+	}
+
+	return total, nil
+}
+
+func (r *usageRepo) ForMonth(ctx context.Context, month string) ([]models.UsageRecord, error) {
+	query := `
+		SELECT u.api_key_id, k.name, u.month, u.endpoint_class, u.requests, u.bytes
+		FROM api_usage u
+		JOIN api_keys k ON k.id = u.api_key_id
+		WHERE u.month = $1
+		ORDER BY u.api_key_id, u.endpoint_class
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API usage: %w", err)
+	}
+	defer rows.Close()
+
+	var records []models.UsageRecord
+	for rows.Next() {
+		var rec models.UsageRecord
+		if err := rows.Scan(&rec.APIKeyID, &rec.APIKeyName, &rec.Month, &rec.EndpointClass, &rec.Requests, &rec.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan API usage: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list API usage: %w", err)
+	}
+
+	return records, nil
+}
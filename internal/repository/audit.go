@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry *models.AuditEntry) error
+
+	// List returns entries created in [from, to), newest first. The time range
+	// is required so only the partitions covering it are read.
+	List(ctx context.Context, from, to time.Time, limit int) ([]*models.AuditEntry, error)
+}
+
+type auditRepo struct {
+	db *database.DB
+}
+
+func NewAuditRepository(db *database.DB) AuditRepository {
+	return &auditRepo{db: db}
+}
+
+const auditListQuery = `
+	SELECT id, action, actor, entity_type, COALESCE(entity_id, '') AS entity_id, details, created_at
+	FROM audit_log
+	WHERE created_at >= $1 AND created_at < $2
+	ORDER BY created_at DESC, id DESC
+	LIMIT $3
+`
+
+func (r *auditRepo) Create(ctx context.Context, entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (
+			action, actor, entity_type, entity_id, details, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		) RETURNING id
+	`
+
+	entry.CreatedAt = time.Now()
+
+	var details interface{}
+	if len(entry.Details) > 0 {
+		details = []byte(entry.Details)
+	}
+
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query,
+		entry.Action,
+		entry.Actor,
+		entry.EntityType,
+		entry.EntityID,
+		details,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+
+	return nil
+}
+
+func (r *auditRepo) List(ctx context.Context, from, to time.Time, limit int) ([]*models.AuditEntry, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, auditListQuery, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+
+	var entries []*models.AuditEntry
+	if err := database.ScanAll(&entries, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan audit entries: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/id"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// bulkChunkSize is the number of products per COPY or INSERT, which keeps a
+// multi-row INSERT well below Postgres' limit of 65535 parameters
+var bulkChunkSize = 1000
+
+// bulkColumns are the columns written by BulkCreate, in productRow order
+var bulkColumns = []string{"uid", "sku", "name", "slug", "description", "category", "unit", "quantity", "unit_price", "visible_from", "visible_until", "listed", "created_at", "updated_at"}
+
+// ChunkError is a chunk of a bulk insert that failed and was not inserted
+type ChunkError struct {
+	Offset int // Index of the chunk's first product
+	Count  int
+	Err    error
+}
+
+// BulkError reports the failed chunks of a bulk insert. Chunks not listed
+// were inserted.
+type BulkError struct {
+	Chunks []ChunkError
+}
+
+func (e *BulkError) Error() string {
+	failed := 0
+	for _, c := range e.Chunks {
+		failed += c.Count
+	}
+	first := e.Chunks[0]
+	return fmt.Sprintf("failed to insert %d products in %d chunks, first at offset %d: %v",
+		failed, len(e.Chunks), first.Offset, first.Err)
+}
+
+func (e *BulkError) Unwrap() []error {
+	errs := make([]error, len(e.Chunks))
+	for i, c := range e.Chunks {
+		errs[i] = c.Err
+	}
+	return errs
+}
+
+// BulkCreate copies products with COPY when the pool uses the pgx driver, and
+// falls back to multi-row INSERTs otherwise. COPY can't join a transaction, so
+// inside WithTx or a dry run INSERTs are used as well; there the first failed
+// chunk stops the load, as the transaction is aborted. IDs are set on every
+// inserted product.
+func (r *productRepo) BulkCreate(ctx context.Context, products []*models.Product) (int, error) {
+	now := time.Now()
+	inserted := 0
+	var failed []ChunkError
+
+	for offset := 0; offset < len(products); offset += bulkChunkSize {
+		chunk := products[offset:min(offset+bulkChunkSize, len(products))]
+		for _, p := range chunk {
+			if p.Unit == "" {
+				p.Unit = models.DefaultUnit
+			}
+			if p.UID == "" {
+				p.UID = id.ProductID(id.Generate())
+			}
+			p.CreatedAt = now
+			p.UpdatedAt = now
+			p.Listed = p.VisibleAt(now)
+		}
+
+		err := uniqueSlugs(ctx, r.db, chunk)
+		if err == nil {
+			err = r.createTaggedChunk(ctx, chunk)
+		}
+		if err != nil {
+			failed = append(failed, ChunkError{Offset: offset, Count: len(chunk), Err: err})
+			if database.InTx(ctx) {
+				break
+			}
+			continue
+		}
+		inserted += len(chunk)
+	}
+
+	if len(failed) > 0 {
+		return inserted, &BulkError{Chunks: failed}
+	}
+	return inserted, nil
+}
+
+// createTaggedChunk inserts a chunk and its products' tags. A chunk with tags
+// is inserted in a transaction, so with INSERTs rather than COPY.
+func (r *productRepo) createTaggedChunk(ctx context.Context, chunk []*models.Product) error {
+	tagged := false
+	for _, p := range chunk {
+		tagged = tagged || len(p.Tags) > 0
+	}
+	if !tagged {
+		return r.createChunk(ctx, chunk)
+	}
+
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := r.createChunk(ctx, chunk); err != nil {
+			return err
+		}
+		return r.insertTags(ctx, chunk...)
+	})
+}
+
+func (r *productRepo) createChunk(ctx context.Context, chunk []*models.Product) error {
+	rows := make([][]interface{}, len(chunk))
+	for i, p := range chunk {
+		rows[i] = productRow(p)
+	}
+
+	_, err := r.db.CopyFrom(ctx, "products", bulkColumns, rows)
+	if errors.Is(err, database.ErrCopyUnsupported) {
+		return r.insertChunk(ctx, chunk, rows)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy products: %w", err)
+	}
+
+	// COPY doesn't return generated columns, look the IDs up by SKU
+	skus := make([]string, len(chunk))
+	for i, p := range chunk {
+		skus[i] = p.SKU
+	}
+	query := `SELECT id, sku FROM products WHERE ` + r.db.Dialect().AnyOf("sku", 1)
+
+	return r.assignIDs(ctx, chunk, query, r.db.Dialect().Array(skus))
+}
+
+func (r *productRepo) insertChunk(ctx context.Context, chunk []*models.Product, rows [][]interface{}) error {
+	var query strings.Builder
+	query.WriteString(`INSERT INTO products (` + strings.Join(bulkColumns, ", ") + `) VALUES `)
+
+	args := make([]interface{}, 0, len(rows)*len(bulkColumns))
+	for i, row := range rows {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for j := range row {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString("$" + strconv.Itoa(len(args)+j+1))
+		}
+		query.WriteString(")")
+		args = append(args, row...)
+	}
+	query.WriteString(` RETURNING id, sku`)
+
+	if err := r.assignIDs(ctx, chunk, query.String(), args...); err != nil {
+		return fmt.Errorf("failed to insert products: %w", err)
+	}
+	return nil
+}
+
+// assignIDs runs a query returning (id, sku) rows and sets the IDs on chunk
+func (r *productRepo) assignIDs(ctx context.Context, chunk []*models.Product, query string, args ...interface{}) error {
+	bySKU := make(map[string]*models.Product, len(chunk))
+	for _, p := range chunk {
+		bySKU[p.SKU] = p
+	}
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var sku string
+		if err := rows.Scan(&id, &sku); err != nil {
+			return err
+		}
+		if p, ok := bySKU[sku]; ok {
+			p.ID = id
+		}
+	}
+
+	return rows.Err()
+}
+
+func productRow(p *models.Product) []interface{} {
+	return []interface{}{string(p.UID), p.SKU, p.Name, p.Slug, p.Description, p.Category, p.Unit, p.Quantity, p.UnitPrice, p.VisibleFrom, p.VisibleUntil, p.Listed, p.CreatedAt, p.UpdatedAt}
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+var (
+	// ErrNestedBundle is returned for a bundle containing a bundle, or
+	// becoming one while it's a component of another
+	ErrNestedBundle = errors.New("bundles can't contain bundles")
+
+	// ErrUnknownComponent is returned for a component that isn't a product
+	ErrUnknownComponent = errors.New("unknown component")
+
+	// ErrInBundle is returned when deleting a product that is a component
+	ErrInBundle = errors.New("product is a component of a bundle")
+)
+
+type BundleRepository interface {
+	// Get returns the bundle made of the product, with its components'
+	// stock, if the product is sold in region; "" finds any
+	Get(ctx context.Context, productID int, region string) (*models.Bundle, error)
+
+	// List returns the bundles sold in region by product ID; "" lists all
+	List(ctx context.Context, region string, limit, offset int) ([]*models.Bundle, error)
+
+	Count(ctx context.Context, region string) (int, error)
+
+	// Components returns the components of a bundle with their stock, or none
+	// for a product that isn't a bundle
+	Components(ctx context.Context, productID int) ([]models.BundleComponent, error)
+
+	// Set makes the product a bundle of components, replacing any it had.
+	// Components must be products that aren't bundles, and the product
+	// mustn't be a component itself.
+	Set(ctx context.Context, productID int, components []models.BundleComponent) error
+
+	// Delete removes a bundle's components, leaving a plain product
+	Delete(ctx context.Context, productID int) error
+}
+
+type bundleRepo struct {
+	db *database.DB
+}
+
+func NewBundleRepository(db *database.DB) BundleRepository {
+	return &bundleRepo{db: db}
+}
+
+// bundleRow is a component row along with its bundle
+type bundleRow struct {
+	BundleID   int    `db:"bundle_id"`
+	BundleSKU  string `db:"bundle_sku"`
+	BundleName string `db:"bundle_name"`
+	models.BundleComponent
+}
+
+const bundleSelect = `
+	SELECT bc.bundle_id, b.sku AS bundle_sku, b.name AS bundle_name,
+		bc.component_id, p.sku, p.name, bc.quantity, p.quantity AS in_stock
+	FROM bundle_components bc
+	JOIN products b ON b.id = bc.bundle_id
+	JOIN products p ON p.id = bc.component_id
+`
+
+// bundleSoldIn matches the bundles whose product is sold in the region bound
+// at placeholder, or all when it's empty
+func bundleSoldIn(placeholder string) string {
+	return `bc.bundle_id IN (SELECT id FROM products WHERE ` + placeholder + ` = '' OR ` + soldInCondition(placeholder) + `)`
+}
+
+func (r *bundleRepo) Get(ctx context.Context, productID int, region string) (*models.Bundle, error) {
+	bundles, err := r.query(ctx, bundleSelect+` WHERE bc.bundle_id = $1 AND `+bundleSoldIn("$2")+` ORDER BY bc.component_id`, productID, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle: %w", err)
+	}
+	if len(bundles) == 0 {
+		return nil, fmt.Errorf("bundle not found")
+	}
+	return bundles[0], nil
+}
+
+func (r *bundleRepo) List(ctx context.Context, region string, limit, offset int) ([]*models.Bundle, error) {
+	query := bundleSelect + `
+		WHERE bc.bundle_id IN (
+			SELECT DISTINCT bundle_id FROM bundle_components bc
+			WHERE ` + bundleSoldIn("$3") + `
+			ORDER BY bundle_id LIMIT $1 OFFSET $2
+		)
+		ORDER BY bc.bundle_id, bc.component_id
+	`
+	bundles, err := r.query(ctx, query, limit, offset, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bundles: %w", err)
+	}
+	return bundles, nil
+}
+
+func (r *bundleRepo) Count(ctx context.Context, region string) (int, error) {
+	var count int
+	query := `SELECT COUNT(DISTINCT bundle_id) FROM bundle_components bc WHERE ` + bundleSoldIn("$1")
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, region).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count bundles: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *bundleRepo) Components(ctx context.Context, productID int) ([]models.BundleComponent, error) {
+	bundles, err := r.query(ctx, bundleSelect+` WHERE bc.bundle_id = $1 ORDER BY bc.component_id`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle components: %w", err)
+	}
+	if len(bundles) == 0 {
+		return nil, nil
+	}
+	return bundles[0].Components, nil
+}
+
+// query groups the component rows of bundleSelect, ordered by bundle, into
+// bundles
+func (r *bundleRepo) query(ctx context.Context, query string, args ...interface{}) ([]*models.Bundle, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var components []*bundleRow
+	if err := database.ScanAll(&components, rows); err != nil {
+		return nil, err
+	}
+
+	var bundles []*models.Bundle
+	for _, row := range components {
+		if len(bundles) == 0 || bundles[len(bundles)-1].ProductID != row.BundleID {
+			bundles = append(bundles, &models.Bundle{ProductID: row.BundleID, SKU: row.BundleSKU, Name: row.BundleName})
+		}
+		bundle := bundles[len(bundles)-1]
+		bundle.Components = append(bundle.Components, row.BundleComponent)
+	}
+	for _, bundle := range bundles {
+		bundle.Available = models.AvailableBundles(bundle.Components)
+	}
+	return bundles, nil
+}
+
+func (r *bundleRepo) Set(ctx context.Context, productID int, components []models.BundleComponent) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+
+		var exists, isComponent bool
+		err := conn.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM products WHERE id = $1),
+				EXISTS (SELECT 1 FROM bundle_components WHERE component_id = $1)
+		`, productID).Scan(&exists, &isComponent)
+		if err != nil {
+			return fmt.Errorf("failed to check bundle product: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("product not found")
+		}
+		if isComponent {
+			return fmt.Errorf("%w: product %d is a component of another bundle", ErrNestedBundle, productID)
+		}
+
+		ids := make([]int, len(components))
+		for i, c := range components {
+			ids[i] = c.ProductID
+		}
+		found, err := r.components(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, c := range components {
+			if !found[c.ProductID] {
+				return fmt.Errorf("%w: product %d", ErrUnknownComponent, c.ProductID)
+			}
+		}
+
+		if _, err := conn.ExecContext(ctx, `DELETE FROM bundle_components WHERE bundle_id = $1`, productID); err != nil {
+			return fmt.Errorf("failed to replace bundle components: %w", err)
+		}
+
+		values := make([]string, len(components))
+		args := make([]interface{}, 0, 1+2*len(components))
+		args = append(args, productID)
+		for i, c := range components {
+			values[i] = "($1, $" + strconv.Itoa(2+2*i) + ", $" + strconv.Itoa(3+2*i) + ")"
+			args = append(args, c.ProductID, c.Quantity)
+		}
+		query := `INSERT INTO bundle_components (bundle_id, component_id, quantity) VALUES ` + strings.Join(values, ", ")
+		if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to insert bundle components: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// components returns which of the products exist, failing with
+// ErrNestedBundle if one is a bundle
+func (r *bundleRepo) components(ctx context.Context, ids []int) (map[int]bool, error) {
+	query := `
+		SELECT id, EXISTS (SELECT 1 FROM bundle_components WHERE bundle_id = products.id)
+		FROM products
+		WHERE ` + r.db.Dialect().AnyOf("id", 1)
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, r.db.Dialect().Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bundle components: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[int]bool, len(ids))
+	for rows.Next() {
+		var id int
+		var isBundle bool
+		if err := rows.Scan(&id, &isBundle); err != nil {
+			return nil, fmt.Errorf("failed to scan bundle components: %w", err)
+		}
+		if isBundle {
+			return nil, fmt.Errorf("%w: component %d is a bundle", ErrNestedBundle, id)
+		}
+		found[id] = true
+	}
+
+	return found, rows.Err()
+}
+
+func (r *bundleRepo) Delete(ctx context.Context, productID int) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM bundle_components WHERE bundle_id = $1`, productID)
+	if err != nil {
+		return fmt.Errorf("failed to delete bundle: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("bundle not found")
+	}
+
+	return nil
+}
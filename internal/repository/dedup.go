@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+var dedupedCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "repository_deduplicated_calls_total",
+	Help: "Repository reads answered by a concurrent identical call instead of a query, by repository and method.",
+}, []string{"repository", "method"})
+
+type dedupedProductRepo struct {
+	ProductRepository // Writes and uncommon reads pass through
+	group             singleflight.Group
+}
+
+// NewDedupedProductRepository wraps next so concurrent identical reads
+// (GetByID, GetBySKU, List, Count) share one call, as when a burst of cache
+// misses asks for the same product. Each caller gets its own copy of the
+// result. Reads inside a transaction always run on it, since they may see
+// uncommitted changes.
+func NewDedupedProductRepository(next ProductRepository) ProductRepository {
+	return &dedupedProductRepo{ProductRepository: next}
+}
+
+// do runs fn once for all concurrent callers with the same key. The shared
+// call isn't cancelled with the caller that started it, so the others still
+// get its result; each caller stops waiting when its own context ends.
+func (r *dedupedProductRepo) do(ctx context.Context, method, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	ran := false
+	results := r.group.DoChan(method+":"+key, func() (interface{}, error) {
+		ran = true
+		return fn(context.WithoutCancel(ctx))
+	})
+
+	select {
+	case res := <-results:
+		if !ran {
+			dedupedCalls.WithLabelValues("product", method).Inc()
+		}
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *dedupedProductRepo) GetByID(ctx context.Context, id int) (*models.Product, error) {
+	if database.InTx(ctx) {
+		return r.ProductRepository.GetByID(ctx, id)
+	}
+	product, err := r.do(ctx, "GetByID", fmt.Sprint(id), func(ctx context.Context) (interface{}, error) {
+		return r.ProductRepository.GetByID(ctx, id)
+	})
+	return copyProduct(product), err
+}
+
+func (r *dedupedProductRepo) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	if database.InTx(ctx) {
+		return r.ProductRepository.GetBySKU(ctx, sku)
+	}
+	product, err := r.do(ctx, "GetBySKU", sku, func(ctx context.Context) (interface{}, error) {
+		return r.ProductRepository.GetBySKU(ctx, sku)
+	})
+	return copyProduct(product), err
+}
+
+func (r *dedupedProductRepo) List(ctx context.Context, limit, offset int) ([]*models.Product, error) {
+	if database.InTx(ctx) {
+		return r.ProductRepository.List(ctx, limit, offset)
+	}
+	list, err := r.do(ctx, "List", fmt.Sprintf("%d,%d", limit, offset), func(ctx context.Context) (interface{}, error) {
+		return r.ProductRepository.List(ctx, limit, offset)
+	})
+	products, _ := list.([]*models.Product)
+	if products == nil {
+		return nil, err
+	}
+	copied := make([]*models.Product, len(products))
+	for i, p := range products {
+		copied[i] = copyProduct(p)
+	}
+	return copied, err
+}
+
+func (r *dedupedProductRepo) Count(ctx context.Context) (int, error) {
+	if database.InTx(ctx) {
+		return r.ProductRepository.Count(ctx)
+	}
+	count, err := r.do(ctx, "Count", "", func(ctx context.Context) (interface{}, error) {
+		return r.ProductRepository.Count(ctx)
+	})
+	n, _ := count.(int)
+	return n, err
+}
+
+// copyProduct returns a copy of a shared result, or nil
+func copyProduct(v interface{}) *models.Product {
+	product, _ := v.(*models.Product)
+	if product == nil {
+		return nil
+	}
+	copied := *product
+	return &copied
+}
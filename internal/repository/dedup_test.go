@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// blockingProductRepo counts GetByID calls, each blocking until release is
+// closed
+type blockingProductRepo struct {
+	ProductRepository
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingProductRepo) GetByID(ctx context.Context, id int) (*models.Product, error) {
+	if r.calls.Add(1) == 1 {
+		close(r.started)
+	}
+	<-r.release
+	return &models.Product{ID: id, Name: "Shared"}, nil
+}
+
+func newBlockingProductRepo() *blockingProductRepo {
+	return &blockingProductRepo{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func TestDedupedProductRepository_SharesConcurrentReads(t *testing.T) {
+	next := newBlockingProductRepo()
+	repo := NewDedupedProductRepository(next)
+
+	const callers = 20
+	results := make([]*models.Product, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			product, err := repo.GetByID(context.Background(), 7)
+			if err != nil {
+				t.Errorf("GetByID: %v", err)
+			}
+			results[i] = product
+		}(i)
+	}
+	<-next.started
+	time.Sleep(20 * time.Millisecond) // Let the other callers join
+	close(next.release)
+	wg.Wait()
+
+	if calls := next.calls.Load(); calls != 1 {
+		t.Errorf("%d calls reached the repository, want 1", calls)
+	}
+	// Callers can't see each other's changes to their result
+	results[0].Name = "Changed"
+	for _, product := range results[1:] {
+		if product.ID != 7 || product.Name != "Shared" {
+			t.Fatalf("product = %+v", product)
+		}
+	}
+}
+
+func TestDedupedProductRepository_CallerCancellation(t *testing.T) {
+	next := newBlockingProductRepo()
+	repo := NewDedupedProductRepository(next)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := repo.GetByID(ctx, 1)
+		leader <- err
+	}()
+	<-next.started
+
+	follower := make(chan *models.Product, 1)
+	go func() {
+		product, _ := repo.GetByID(context.Background(), 1)
+		follower <- product
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// The caller that started the query gives up; the query carries on for
+	// the other
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller got %v, want context.Canceled", err)
+	}
+	close(next.release)
+	if product := <-follower; product == nil || product.ID != 1 {
+		t.Errorf("waiting caller got %+v", product)
+	}
+}
+
+func TestDedupedProductRepository_BypassesTransactions(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "dedup.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+
+	next := newBlockingProductRepo()
+	close(next.release)
+	repo := NewDedupedProductRepository(next)
+
+	err = db.WithTx(context.Background(), func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				repo.GetByID(ctx, 1)
+			}()
+		}
+		wg.Wait()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if calls := next.calls.Load(); calls != 3 {
+		t.Errorf("%d calls inside a transaction, want each of the 3 to run", calls)
+	}
+}
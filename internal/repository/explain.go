@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+)
+
+// ExplainableQuery is a repository read that admins may run under EXPLAIN
+// ANALYZE. Only reads are listed: ANALYZE executes the query.
+type ExplainableQuery struct {
+	Name   string       `json:"name" example:"products.list"`
+	Params []QueryParam `json:"params"` // In placeholder order, $1 first
+	Query  string       `json:"-"`
+}
+
+type QueryParam struct {
+	Name string `json:"name" example:"limit"`
+	Type string `json:"type" example:"int"` // "int", "text", or "timestamp" (RFC 3339)
+}
+
+// ExplainableQueries lists the queries QueryExplainer runs, using the same
+// SQL as the repository methods named after them
+var ExplainableQueries = []ExplainableQuery{
+	{Name: "products.get_by_id", Query: productByIDQuery, Params: []QueryParam{{"id", "int"}}},
+	{Name: "products.get_by_sku", Query: productBySKUQuery, Params: []QueryParam{{"sku", "text"}}},
+	{Name: "products.get_by_id_as_of", Query: productAsOfQuery, Params: []QueryParam{{"id", "int"}, {"as_of", "timestamp"}}},
+	{Name: "products.history", Query: productHistoryQuery, Params: []QueryParam{{"id", "int"}, {"limit", "int"}}},
+	{Name: "products.list", Query: productListQuery, Params: []QueryParam{{"limit", "int"}, {"offset", "int"}}},
+	{Name: "products.count", Query: productCountQuery},
+	{Name: "products.list_with_count", Query: productListWithCountQuery, Params: []QueryParam{{"limit", "int"}, {"offset", "int"}}},
+	{Name: "products.list_updated_since", Query: productsUpdatedSinceQuery, Params: []QueryParam{{"since", "timestamp"}}},
+	{Name: "stock_movements.list_by_product", Query: stockMovementsByProductQuery, Params: []QueryParam{{"product_id", "int"}, {"from", "timestamp"}, {"to", "timestamp"}, {"limit", "int"}}},
+	{Name: "audit_log.list", Query: auditListQuery, Params: []QueryParam{{"from", "timestamp"}, {"to", "timestamp"}, {"limit", "int"}}},
+}
+
+var (
+	ErrUnknownQuery       = errors.New("unknown query")
+	ErrExplainUnsupported = errors.New("EXPLAIN ANALYZE needs PostgreSQL")
+)
+
+// ExplainTimeout bounds how long an explained query may run
+const ExplainTimeout = 30 * time.Second
+
+type QueryExplainer interface {
+	// Explain runs the named query under EXPLAIN (ANALYZE, BUFFERS, FORMAT
+	// JSON) with params bound by name, in a read-only transaction, and returns
+	// the plan. Invalid params are reported in a *ParamError.
+	Explain(ctx context.Context, name string, params map[string]json.RawMessage) (json.RawMessage, error)
+}
+
+// ParamError reports a missing or malformed query parameter
+type ParamError struct {
+	Param string
+	Err   error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("param %s: %v", e.Param, e.Err)
+}
+
+func (e *ParamError) Unwrap() error { return e.Err }
+
+type queryExplainer struct {
+	db *database.DB
+}
+
+func NewQueryExplainer(db *database.DB) QueryExplainer {
+	return &queryExplainer{db: db}
+}
+
+func (e *queryExplainer) Explain(ctx context.Context, name string, params map[string]json.RawMessage) (json.RawMessage, error) {
+	query, ok := lookupExplainable(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownQuery, name)
+	}
+	args, err := query.bind(params)
+	if err != nil {
+		return nil, err
+	}
+	if e.db.Dialect() != database.Postgres {
+		return nil, ErrExplainUnsupported
+	}
+
+	var plan []byte
+	// Read-only, so nothing the query could do is kept
+	err = e.db.WithTxOptions(ctx, &sql.TxOptions{ReadOnly: true}, func(ctx context.Context) error {
+		conn := e.db.Conn(ctx)
+		timeout := fmt.Sprintf("SET LOCAL statement_timeout = %d", ExplainTimeout.Milliseconds())
+		if _, err := conn.ExecContext(ctx, timeout); err != nil {
+			return err
+		}
+		return conn.QueryRowContext(ctx, `EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) `+query.Query, args...).Scan(&plan)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain %s: %w", name, err)
+	}
+
+	return plan, nil
+}
+
+func lookupExplainable(name string) (ExplainableQuery, bool) {
+	for _, query := range ExplainableQueries {
+		if query.Name == name {
+			return query, true
+		}
+	}
+	return ExplainableQuery{}, false
+}
+
+// bind converts params to the query's arguments, rejecting unknown ones so a
+// typo isn't silently ignored
+func (q ExplainableQuery) bind(params map[string]json.RawMessage) ([]interface{}, error) {
+	args := make([]interface{}, len(q.Params))
+	for i, param := range q.Params {
+		raw, ok := params[param.Name]
+		if !ok {
+			return nil, &ParamError{Param: param.Name, Err: errors.New("missing")}
+		}
+
+		var err error
+		switch param.Type {
+		case "int":
+			var n int
+			err = json.Unmarshal(raw, &n)
+			args[i] = n
+		case "text":
+			var s string
+			err = json.Unmarshal(raw, &s)
+			args[i] = s
+		case "timestamp":
+			var t time.Time
+			err = json.Unmarshal(raw, &t)
+			args[i] = t.UTC()
+		}
+		if err != nil {
+			return nil, &ParamError{Param: param.Name, Err: fmt.Errorf("want %s", param.Type)}
+		}
+	}
+
+	if len(params) > len(q.Params) {
+		for name := range params {
+			if !q.hasParam(name) {
+				return nil, &ParamError{Param: name, Err: errors.New("not a parameter of " + q.Name)}
+			}
+		}
+	}
+
+	return args, nil
+}
+
+func (q ExplainableQuery) hasParam(name string) bool {
+	for _, param := range q.Params {
+		if param.Name == name {
+			return true
+		}
+	}
+	return false
+}
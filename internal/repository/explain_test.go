@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplainableQuery_Bind(t *testing.T) {
+	query, ok := lookupExplainable("stock_movements.list_by_product")
+	if !ok {
+		t.Fatal("stock_movements.list_by_product isn't explainable")
+	}
+
+	args, err := query.bind(map[string]json.RawMessage{
+		"product_id": json.RawMessage(`7`),
+		"from":       json.RawMessage(`"2026-01-01T00:00:00+02:00"`),
+		"to":         json.RawMessage(`"2026-02-01T00:00:00Z"`),
+		"limit":      json.RawMessage(`50`),
+	})
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	from := time.Date(2025, 12, 31, 22, 0, 0, 0, time.UTC)
+	if args[0] != 7 || args[1] != from || args[3] != 50 {
+		t.Errorf("args = %v", args)
+	}
+
+	for name, params := range map[string]map[string]json.RawMessage{
+		"missing":   {"product_id": json.RawMessage(`7`)},
+		"malformed": {"product_id": json.RawMessage(`"seven"`), "from": nil, "to": nil, "limit": nil},
+		"unknown":   {"product_id": json.RawMessage(`7`), "from": json.RawMessage(`"2026-01-01T00:00:00Z"`), "to": json.RawMessage(`"2026-01-01T00:00:00Z"`), "limit": json.RawMessage(`1`), "offset": json.RawMessage(`0`)},
+	} {
+		var paramErr *ParamError
+		if _, err := query.bind(params); !errors.As(err, &paramErr) {
+			t.Errorf("%s params: error = %v, want a *ParamError", name, err)
+		}
+	}
+}
+
+func TestExplainableQueries_AreReads(t *testing.T) {
+	for _, query := range ExplainableQueries {
+		if !strings.HasPrefix(strings.TrimSpace(query.Query), "SELECT") {
+			t.Errorf("%s isn't a SELECT; EXPLAIN ANALYZE would run it", query.Name)
+		}
+		if n := strings.Count(query.Query, "$"); n < len(query.Params) {
+			t.Errorf("%s has %d params but %d placeholders", query.Name, len(query.Params), n)
+		}
+	}
+}
+
+func TestQueryExplainer_SQLite(t *testing.T) {
+	explainer := NewQueryExplainer(setupSQLiteDB(t))
+	ctx := context.Background()
+
+	if _, err := explainer.Explain(ctx, "products.drop_all", nil); !errors.Is(err, ErrUnknownQuery) {
+		t.Errorf("unknown query: error = %v, want ErrUnknownQuery", err)
+	}
+	if _, err := explainer.Explain(ctx, "products.count", nil); !errors.Is(err, ErrExplainUnsupported) {
+		t.Errorf("SQLite: error = %v, want ErrExplainUnsupported", err)
+	}
+}
+
+func TestQueryExplainer_Postgres(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	plan, err := NewQueryExplainer(db).Explain(context.Background(), "products.get_by_sku", map[string]json.RawMessage{"sku": json.RawMessage(`"EXPLAIN-1"`)})
+	if err != nil {
+		t.Fatalf("Explain: %v", err)
+	}
+
+	var plans []struct {
+		Plan          map[string]interface{} `json:"Plan"`
+		ExecutionTime float64                `json:"Execution Time"`
+	}
+	if err := json.Unmarshal(plan, &plans); err != nil || len(plans) != 1 || plans[0].Plan["Node Type"] == nil {
+		t.Errorf("plan = %s, %v", plan, err)
+	}
+}
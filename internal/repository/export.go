@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// exportBatchSize is the number of products read per query while exporting
+const exportBatchSize = 1000
+
+type ExportRepository interface {
+	// EachProduct calls fn with every product matching filter, in ID order,
+	// read from one snapshot, and returns when the snapshot was taken.
+	// Products are read in batches, so exporting the full catalog doesn't
+	// hold it in memory.
+	EachProduct(ctx context.Context, filter models.ExportFilter, fn func(*models.Product) error) (time.Time, error)
+
+	// CountProducts returns how many products match filter
+	CountProducts(ctx context.Context, filter models.ExportFilter) (int, error)
+
+	// Create records a run as it starts, before its key is known
+	Create(ctx context.Context, run *models.ExportRun) error
+
+	// Finish records the outcome of a run and where it was stored
+	Finish(ctx context.Context, run *models.ExportRun) error
+
+	GetByID(ctx context.Context, id int) (*models.ExportRun, error)
+
+	// List returns runs, most recent first
+	List(ctx context.Context, limit, offset int) ([]*models.ExportRun, error)
+
+	Count(ctx context.Context) (int, error)
+
+	// FailInterrupted fails the runs still running, which a restart cut
+	// short, and returns how many there were
+	FailInterrupted(ctx context.Context) (int64, error)
+}
+
+type exportRepo struct {
+	db *database.DB
+}
+
+func NewExportRepository(db *database.DB) ExportRepository {
+	return &exportRepo{db: db}
+}
+
+var exportRunColumns = database.ColumnList(models.ExportRun{})
+
+func (r *exportRepo) EachProduct(ctx context.Context, filter models.ExportFilter, fn func(*models.Product) error) (time.Time, error) {
+	query, args := exportProductsQuery(filter)
+	products := &productRepo{db: r.db}
+
+	var snapshotAt time.Time
+	err := r.db.WithTxOptions(ctx, r.db.Dialect().SnapshotTxOptions(), func(ctx context.Context) error {
+		var err error
+		if snapshotAt, err = r.db.SnapshotTime(ctx); err != nil {
+			return err
+		}
+
+		for afterID := 0; ; {
+			rows, err := r.db.Conn(ctx).QueryContext(ctx, query, append([]interface{}{afterID}, args...)...)
+			if err != nil {
+				return fmt.Errorf("failed to list products to export: %w", err)
+			}
+
+			var batch []*models.Product
+			if err := database.ScanAll(&batch, rows); err != nil {
+				return fmt.Errorf("failed to scan products: %w", err)
+			}
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := products.loadTags(ctx, batch...); err != nil {
+				return err
+			}
+
+			for _, p := range batch {
+				if err := fn(p); err != nil {
+					return err
+				}
+			}
+			afterID = batch[len(batch)-1].ID
+		}
+	})
+	return snapshotAt, err
+}
+
+func (r *exportRepo) CountProducts(ctx context.Context, filter models.ExportFilter) (int, error) {
+	conditions, args := exportFilterConditions(filter, 1)
+	query := `SELECT COUNT(*) FROM products`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	var count int
+	if err := r.db.Conn(ctx).QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count products to export: %w", err)
+	}
+	return count, nil
+}
+
+// exportProductsQuery pages through the products matching filter after the
+// ID bound at $1
+func exportProductsQuery(filter models.ExportFilter) (string, []interface{}) {
+	conditions, args := exportFilterConditions(filter, 2)
+
+	query := `
+		SELECT ` + productColumns + `
+		FROM products
+		WHERE ` + strings.Join(append([]string{"id > $1"}, conditions...), " AND ") + `
+		ORDER BY id
+		LIMIT ` + strconv.Itoa(exportBatchSize)
+	return query, args
+}
+
+// exportFilterConditions returns the conditions of filter, with their
+// arguments bound from $first
+func exportFilterConditions(filter models.ExportFilter, first int) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		conditions = append(conditions, "LOWER(category) = LOWER($"+strconv.Itoa(len(args)+first-1)+")")
+	}
+	if filter.Tag != "" {
+		args = append(args, strings.ToLower(filter.Tag))
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM product_tags t WHERE t.product_id = products.id AND t.tag = $"+strconv.Itoa(len(args)+first-1)+")")
+	}
+	return conditions, args
+}
+
+func (r *exportRepo) Create(ctx context.Context, run *models.ExportRun) error {
+	query := `
+		INSERT INTO export_runs (
+			triggered_by, format, filter_category, filter_tag, status, started_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		) RETURNING id
+	`
+
+	run.Status = models.ExportRunning
+	run.StartedAt = time.Now()
+
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query,
+		run.TriggeredBy,
+		run.Format,
+		run.Category,
+		run.Tag,
+		run.Status,
+		run.StartedAt,
+	).Scan(&run.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create export run: %w", err)
+	}
+
+	return nil
+}
+
+func (r *exportRepo) Finish(ctx context.Context, run *models.ExportRun) error {
+	query := `
+		UPDATE export_runs SET
+			status = $2,
+			object_key = $3,
+			url = $4,
+			row_count = $5,
+			size_bytes = $6,
+			error = $7,
+			snapshot_at = $8,
+			finished_at = $9
+		WHERE id = $1
+	`
+
+	now := time.Now()
+	run.FinishedAt = &now
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query,
+		run.ID,
+		run.Status,
+		run.Key,
+		run.URL,
+		run.Rows,
+		run.Bytes,
+		run.Error,
+		run.SnapshotAt,
+		run.FinishedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish export run: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("export run not found")
+	}
+
+	return nil
+}
+
+func (r *exportRepo) GetByID(ctx context.Context, id int) (*models.ExportRun, error) {
+	query := `SELECT ` + exportRunColumns + ` FROM export_runs WHERE id = $1`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export run: %w", err)
+	}
+
+	run := &models.ExportRun{}
+	err = database.ScanOne(run, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("export run not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export run: %w", err)
+	}
+
+	return run, nil
+}
+
+func (r *exportRepo) List(ctx context.Context, limit, offset int) ([]*models.ExportRun, error) {
+	query := `
+		SELECT ` + exportRunColumns + `
+		FROM export_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export runs: %w", err)
+	}
+
+	var runs []*models.ExportRun
+	if err := database.ScanAll(&runs, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan export runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+func (r *exportRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM export_runs`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count export runs: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *exportRepo) FailInterrupted(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE export_runs SET
+			status = $1,
+			error = $2,
+			finished_at = $3
+		WHERE status = $4
+	`
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query,
+		models.ExportFailed,
+		"interrupted by a restart",
+		time.Now(),
+		models.ExportRunning,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted export runs: %w", err)
+	}
+
+	return result.RowsAffected()
+}
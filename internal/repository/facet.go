@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// facetCounts counts the products in from matching where, which binds args,
+// by each facet of spec, one GROUP BY per facet
+func facetCounts(ctx context.Context, conn database.Querier, from, where string, args []interface{}, spec models.FacetSpec) (models.Facets, error) {
+	facets := models.Facets{}
+
+	if spec.Has(models.FacetCategory) {
+		query := `
+			SELECT MIN(category) AS value, COUNT(*) AS count
+			FROM ` + from + andWhere(where, "category <> ''") + `
+			GROUP BY LOWER(category)
+			ORDER BY count DESC, value
+			LIMIT ` + strconv.Itoa(models.MaxCategoryBuckets)
+		buckets := []models.FacetBucket{}
+		err := eachBucket(ctx, conn, query, args, func(value string, count int) {
+			buckets = append(buckets, models.FacetBucket{Value: value, Count: count})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count categories: %w", err)
+		}
+		facets[models.FacetCategory] = buckets
+	}
+
+	if spec.Has(models.FacetPriceRange) {
+		buckets := models.PriceRanges(spec.PriceBounds)
+		cases := make([]string, len(spec.PriceBounds))
+		for i, bound := range spec.PriceBounds {
+			cases[i] = "WHEN unit_price < " + strconv.FormatFloat(bound, 'f', -1, 64) + " THEN '" + strconv.Itoa(i) + "'"
+		}
+		bucket := "'" + strconv.Itoa(len(spec.PriceBounds)) + "'"
+		if len(cases) > 0 {
+			bucket = "CASE " + strings.Join(cases, " ") + " ELSE " + bucket + " END"
+		}
+		query := `SELECT ` + bucket + ` AS value, COUNT(*) AS count FROM ` + from + where + ` GROUP BY 1`
+		err := eachBucket(ctx, conn, query, args, func(value string, count int) {
+			if i, err := strconv.Atoi(value); err == nil && i < len(buckets) {
+				buckets[i].Count = count
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count price ranges: %w", err)
+		}
+		facets[models.FacetPriceRange] = buckets
+	}
+
+	if spec.Has(models.FacetStatus) {
+		buckets := models.StatusBuckets()
+		query := `
+			SELECT CASE
+				WHEN quantity <= 0 THEN '` + models.AvailabilityOutOfStock + `'
+				WHEN quantity <= ` + strconv.Itoa(spec.LowStock) + ` THEN '` + models.AvailabilityLow + `'
+				ELSE '` + models.AvailabilityInStock + `'
+			END AS value, COUNT(*) AS count
+			FROM ` + from + where + `
+			GROUP BY 1`
+		err := eachBucket(ctx, conn, query, args, func(value string, count int) {
+			for i := range buckets {
+				if buckets[i].Value == value {
+					buckets[i].Count = count
+				}
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count statuses: %w", err)
+		}
+		facets[models.FacetStatus] = buckets
+	}
+
+	return facets, nil
+}
+
+// andWhere adds condition to a WHERE clause, which may be empty
+func andWhere(where, condition string) string {
+	if where == "" {
+		return " WHERE " + condition
+	}
+	return where + " AND " + condition
+}
+
+func eachBucket(ctx context.Context, conn database.Querier, query string, args []interface{}, fn func(value string, count int)) error {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+		var count int
+		if err := rows.Scan(&value, &count); err != nil {
+			return err
+		}
+		fn(value, count)
+	}
+	return rows.Err()
+}
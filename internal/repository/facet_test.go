@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// bucketCounts maps the values of a facet's buckets to their counts
+func bucketCounts(buckets []models.FacetBucket) map[string]int {
+	counts := map[string]int{}
+	for _, b := range buckets {
+		counts[b.Value] = b.Count
+	}
+	return counts
+}
+
+func TestSQLite_Facets(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	ctx := context.Background()
+
+	for _, p := range []*models.Product{
+		{SKU: "FC-1", Name: "Atlas", Category: "Books", Quantity: 10, UnitPrice: 5},
+		{SKU: "FC-2", Name: "Almanac", Category: "books", Quantity: 2, UnitPrice: 12},
+		{SKU: "FC-3", Name: "Globe", Category: "Maps", Quantity: 0, UnitPrice: 60},
+		{SKU: "FC-4", Name: "Chair", Quantity: 7, UnitPrice: 300},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+	spec := models.FacetSpec{Names: models.FacetNames, PriceBounds: []float64{10, 50}, LowStock: 5}
+
+	facets, err := products.Facets(ctx, models.ProductFilter{}, spec)
+	if err != nil {
+		t.Fatalf("Facets: %v", err)
+	}
+	// Categories are grouped case-insensitively, the uncategorized left out
+	categories := facets[models.FacetCategory]
+	if len(categories) != 2 || categories[0].Value != "Books" || categories[0].Count != 2 || categories[1].Value != "Maps" {
+		t.Errorf("categories = %+v, want Books 2, Maps 1", categories)
+	}
+	if got, want := bucketCounts(facets[models.FacetPriceRange]), map[string]int{"0-10": 1, "10-50": 1, "50+": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("price ranges = %v, want %v", got, want)
+	}
+	if got, want := bucketCounts(facets[models.FacetStatus]), map[string]int{"in_stock": 2, "low": 1, "out_of_stock": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+
+	// A filter narrows the counts, which still list every range
+	min := 10.0
+	facets, err = products.Facets(ctx, models.ProductFilter{MinPrice: &min}, models.FacetSpec{Names: []string{models.FacetPriceRange}, PriceBounds: []float64{10, 50}})
+	if err != nil {
+		t.Fatalf("Facets with a filter: %v", err)
+	}
+	if got, want := bucketCounts(facets[models.FacetPriceRange]), map[string]int{"0-10": 0, "10-50": 1, "50+": 2}; !reflect.DeepEqual(got, want) || len(facets) != 1 {
+		t.Errorf("facets = %v, want price ranges %v only", facets, want)
+	}
+
+	// Search facets count every match, not the page
+	search := NewSearchRepository(db)
+	facets, err = search.Facets(ctx, models.SearchQuery{Text: "a", Category: "books", Limit: 1, Facets: spec}, 0)
+	if err != nil {
+		t.Fatalf("search Facets: %v", err)
+	}
+	if got, want := bucketCounts(facets[models.FacetStatus]), map[string]int{"in_stock": 1, "low": 1, "out_of_stock": 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search statuses = %v, want %v", got, want)
+	}
+}
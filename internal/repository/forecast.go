@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type ForecastRepository interface {
+	// EachDemand calls fn with every product, or only productID when it's
+	// not 0, in ID order, with its lead time and its outbound stock movements
+	// (negative deltas) in [from, to)
+	EachDemand(ctx context.Context, productID int, from, to time.Time, fn func(history *models.DemandHistory) error) error
+
+	// Upsert stores a product's forecast, replacing its previous one
+	Upsert(ctx context.Context, forecast *models.Forecast) error
+
+	GetByProduct(ctx context.Context, productID int) (*models.Forecast, error)
+
+	// SetLeadTime records a product's supplier lead time, in days
+	SetLeadTime(ctx context.Context, productID, days int) error
+
+	// DeleteLeadTime removes a product's lead time, so the default applies
+	DeleteLeadTime(ctx context.Context, productID int) error
+
+	// ListReorder returns the products at or below the reorder point of their
+	// forecast with something to order, largest shortfall first
+	ListReorder(ctx context.Context, limit, offset int) ([]*models.ReorderSuggestion, error)
+
+	CountReorder(ctx context.Context) (int, error)
+}
+
+type forecastRepo struct {
+	db *database.DB
+}
+
+func NewForecastRepository(db *database.DB) ForecastRepository {
+	return &forecastRepo{db: db}
+}
+
+var forecastColumns = database.ColumnList(models.Forecast{})
+
+func (r *forecastRepo) EachDemand(ctx context.Context, productID int, from, to time.Time, fn func(history *models.DemandHistory) error) error {
+	query := `
+		SELECT p.id, p.sku, l.lead_time_days, m.id, m.delta, m.quantity_after, m.created_at
+		FROM products p
+		LEFT JOIN product_lead_times l ON l.product_id = p.id
+		LEFT JOIN stock_movements m ON m.product_id = p.id AND m.delta < 0 AND m.created_at >= $1 AND m.created_at < $2
+		WHERE $3 = 0 OR p.id = $3
+		ORDER BY p.id, m.created_at, m.id
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, from, to, productID)
+	if err != nil {
+		return fmt.Errorf("failed to read demand: %w", err)
+	}
+	defer rows.Close()
+
+	var history *models.DemandHistory
+	for rows.Next() {
+		var id int
+		var sku string
+		var leadTime, movementID, delta, quantityAfter sql.NullInt64
+		var createdAt sql.NullTime
+		if err := rows.Scan(&id, &sku, &leadTime, &movementID, &delta, &quantityAfter, &createdAt); err != nil {
+			return fmt.Errorf("failed to scan demand: %w", err)
+		}
+
+		if history == nil || history.ProductID != id {
+			if history != nil {
+				if err := fn(history); err != nil {
+					return err
+				}
+			}
+			history = &models.DemandHistory{ProductID: id, SKU: sku}
+			if leadTime.Valid {
+				days := int(leadTime.Int64)
+				history.LeadTimeDays = &days
+			}
+		}
+		if movementID.Valid {
+			history.Outbound = append(history.Outbound, &models.StockMovement{
+				ID:            movementID.Int64,
+				ProductID:     id,
+				SKU:           sku,
+				Delta:         int(delta.Int64),
+				QuantityAfter: int(quantityAfter.Int64),
+				CreatedAt:     createdAt.Time,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read demand: %w", err)
+	}
+	if history != nil {
+		return fn(history)
+	}
+	return nil
+}
+
+func (r *forecastRepo) Upsert(ctx context.Context, f *models.Forecast) error {
+	query := `
+		INSERT INTO product_forecasts (product_id, sku, window_days, demand, average_daily_demand, trend, horizon_days,
+			projected_demand, lead_time_days, lead_time_demand, reorder_point, order_up_to, computed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (product_id) DO UPDATE
+		SET sku = excluded.sku, window_days = excluded.window_days, demand = excluded.demand,
+			average_daily_demand = excluded.average_daily_demand, trend = excluded.trend,
+			horizon_days = excluded.horizon_days, projected_demand = excluded.projected_demand,
+			lead_time_days = excluded.lead_time_days, lead_time_demand = excluded.lead_time_demand,
+			reorder_point = excluded.reorder_point, order_up_to = excluded.order_up_to, computed_at = excluded.computed_at
+	`
+
+	_, err := r.db.Conn(ctx).ExecContext(ctx, query,
+		f.ProductID, f.SKU, f.WindowDays, f.Demand, f.AverageDailyDemand, f.Trend, f.HorizonDays,
+		f.ProjectedDemand, f.LeadTimeDays, f.LeadTimeDemand, f.ReorderPoint, f.OrderUpTo, f.ComputedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store forecast: %w", err)
+	}
+
+	return nil
+}
+
+func (r *forecastRepo) GetByProduct(ctx context.Context, productID int) (*models.Forecast, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, `SELECT `+forecastColumns+` FROM product_forecasts WHERE product_id = $1`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	forecast := &models.Forecast{}
+	err = database.ScanOne(forecast, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("forecast not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	return forecast, nil
+}
+
+func (r *forecastRepo) SetLeadTime(ctx context.Context, productID, days int) error {
+	query := `
+		INSERT INTO product_lead_times (product_id, lead_time_days, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id) DO UPDATE
+		SET lead_time_days = excluded.lead_time_days, updated_at = excluded.updated_at
+	`
+
+	if _, err := r.db.Conn(ctx).ExecContext(ctx, query, productID, days, time.Now()); err != nil {
+		return fmt.Errorf("failed to set lead time: %w", err)
+	}
+
+	return nil
+}
+
+func (r *forecastRepo) DeleteLeadTime(ctx context.Context, productID int) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM product_lead_times WHERE product_id = $1`, productID)
+	if err != nil {
+		return fmt.Errorf("failed to delete lead time: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("lead time not found")
+	}
+
+	return nil
+}
+
+// reorderCondition selects the products at or below their reorder point
+// with something to order
+const reorderCondition = `p.quantity <= f.reorder_point AND f.order_up_to > p.quantity`
+
+func (r *forecastRepo) ListReorder(ctx context.Context, limit, offset int) ([]*models.ReorderSuggestion, error) {
+	query := `
+		SELECT p.id AS product_id, p.sku, p.name, p.quantity, f.reorder_point, f.order_up_to,
+			f.order_up_to - p.quantity AS suggested_quantity, f.average_daily_demand, f.lead_time_days, f.computed_at
+		FROM product_forecasts f
+		JOIN products p ON p.id = f.product_id
+		WHERE ` + reorderCondition + `
+		ORDER BY f.reorder_point - p.quantity DESC, p.id
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reorder suggestions: %w", err)
+	}
+
+	suggestions := []*models.ReorderSuggestion{}
+	if err := database.ScanAll(&suggestions, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan reorder suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+func (r *forecastRepo) CountReorder(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM product_forecasts f JOIN products p ON p.id = f.product_id WHERE ` + reorderCondition
+
+	var count int
+	if err := r.db.Conn(ctx).QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count reorder suggestions: %w", err)
+	}
+	return count, nil
+}
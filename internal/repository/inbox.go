@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"{{MODULE_NAME}}/internal/database"
+)
+
+type InboxRepository interface {
+	// Receive records the message as applied and reports false if it had
+	// already been recorded for the same source. Call it inside the
+	// transaction that applies the message so the record and its changes
+	// commit together.
+	Receive(ctx context.Context, source, messageID string) (bool, error)
+}
+
+type inboxRepo struct {
+	db *database.DB
+}
+
+func NewInboxRepository(db *database.DB) InboxRepository {
+	return &inboxRepo{db: db}
+}
+
+func (r *inboxRepo) Receive(ctx context.Context, source, messageID string) (bool, error) {
+	query := `
+		INSERT INTO inbox_messages (source, message_id)
+		VALUES ($1, $2)
+		ON CONFLICT (source, message_id) DO NOTHING
+	`
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, source, messageID)
+	if err != nil {
+		return false, fmt.Errorf("failed to record inbox message: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows == 1, nil
+}
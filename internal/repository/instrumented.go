@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+var (
+	callDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_call_duration_seconds",
+		Help:    "Duration of repository calls, by repository and method.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"repository", "method"})
+	callErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "repository_call_errors_total",
+		Help: "Failed repository calls, by repository, method, and error kind.",
+	}, []string{"repository", "method", "kind"})
+)
+
+var tracer = otel.Tracer("{{MODULE_NAME}}/internal/repository")
+
+// instrumenter records metrics, spans, and slow-call logs around repository
+// calls. Decorators embed it so implementations stay free of instrumentation.
+type instrumenter struct {
+	repository string
+	slow       time.Duration // Calls slower than this are logged, 0 disables
+	logger     *slog.Logger
+}
+
+// start begins an instrumented call. The returned function must be called with
+// the call's error when it completes.
+func (in *instrumenter) start(ctx context.Context, method string) (context.Context, func(error)) {
+	ctx, span := tracer.Start(ctx, in.repository+"."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", method),
+		),
+	)
+	begin := time.Now()
+	ctx = tagQueries(ctx, in.repository, method)
+
+	return ctx, func(err error) {
+		duration := time.Since(begin)
+		callDuration.WithLabelValues(in.repository, method).Observe(duration.Seconds())
+
+		if err != nil {
+			kind := errorKind(err)
+			callErrors.WithLabelValues(in.repository, method, kind).Inc()
+			span.SetAttributes(attribute.String("error.kind", kind))
+			// Expected outcomes aren't failures of the call itself
+			if kind != "not_found" && kind != "insufficient_stock" {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+		}
+		span.End()
+
+		if in.slow > 0 && duration >= in.slow {
+			in.logger.WarnContext(ctx, "slow repository call",
+				"repository", in.repository,
+				"method", method,
+				"duration", duration.String(),
+				"error", err,
+			)
+		}
+	}
+}
+
+// tagQueries tags the call's queries with the repository, the method, and
+// the call's span, for the DB to comment them with (see
+// database.SetQueryTags)
+func tagQueries(ctx context.Context, repository, method string) context.Context {
+	ctx = database.WithQueryTag(ctx, database.TagController, func() string { return repository })
+	ctx = database.WithQueryTag(ctx, database.TagAction, func() string { return method })
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ctx
+	}
+	traceparent := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sc.TraceFlags().String()
+	return database.WithQueryTag(ctx, database.TagTraceparent, func() string { return traceparent })
+}
+
+// errorKind classifies an error into a small, fixed set of metric labels
+func errorKind(err error) string {
+	var pqErr *pq.Error
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrInsufficientStock):
+		return "insufficient_stock"
+	case err.Error() == "product not found":
+		return "not_found"
+	case errors.As(err, &pqErr) && pqErr.Code.Class() == "23":
+		return "constraint"
+	case errors.As(err, &pqErr):
+		return "database"
+	case errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, "23"):
+		return "constraint"
+	case errors.As(err, &pgErr):
+		return "database"
+	default:
+		return "other"
+	}
+}
+
+type instrumentedProductRepo struct {
+	instrumenter
+	next ProductRepository
+}
+
+// NewInstrumentedProductRepository wraps next so every call records
+// repository_call_duration_seconds, counts errors by kind, runs in a tracing
+// span, and is logged when it takes longer than slow
+func NewInstrumentedProductRepository(next ProductRepository, slow time.Duration, logger *slog.Logger) ProductRepository {
+	return &instrumentedProductRepo{
+		instrumenter: instrumenter{repository: "product", slow: slow, logger: logger},
+		next:         next,
+	}
+}
+
+func (r *instrumentedProductRepo) Create(ctx context.Context, product *models.Product) (err error) {
+	ctx, done := r.start(ctx, "Create")
+	defer func() { done(err) }()
+	return r.next.Create(ctx, product)
+}
+
+func (r *instrumentedProductRepo) GetByID(ctx context.Context, id int) (_ *models.Product, err error) {
+	ctx, done := r.start(ctx, "GetByID")
+	defer func() { done(err) }()
+	return r.next.GetByID(ctx, id)
+}
+
+func (r *instrumentedProductRepo) GetByIDForUpdate(ctx context.Context, id int) (_ *models.Product, err error) {
+	ctx, done := r.start(ctx, "GetByIDForUpdate")
+	defer func() { done(err) }()
+	return r.next.GetByIDForUpdate(ctx, id)
+}
+
+func (r *instrumentedProductRepo) GetBySKU(ctx context.Context, sku string) (_ *models.Product, err error) {
+	ctx, done := r.start(ctx, "GetBySKU")
+	defer func() { done(err) }()
+	return r.next.GetBySKU(ctx, sku)
+}
+
+func (r *instrumentedProductRepo) ResolveUID(ctx context.Context, uid string) (_ int, err error) {
+	ctx, done := r.start(ctx, "ResolveUID")
+	defer func() { done(err) }()
+	return r.next.ResolveUID(ctx, uid)
+}
+
+func (r *instrumentedProductRepo) AssignUIDs(ctx context.Context, next func() string, limit int) (_ int, err error) {
+	ctx, done := r.start(ctx, "AssignUIDs")
+	defer func() { done(err) }()
+	return r.next.AssignUIDs(ctx, next, limit)
+}
+
+func (r *instrumentedProductRepo) GetByIDAsOf(ctx context.Context, id int, asOf time.Time) (_ *models.Product, err error) {
+	ctx, done := r.start(ctx, "GetByIDAsOf")
+	defer func() { done(err) }()
+	return r.next.GetByIDAsOf(ctx, id, asOf)
+}
+
+func (r *instrumentedProductRepo) History(ctx context.Context, id int, limit int) (_ []*models.ProductVersion, err error) {
+	ctx, done := r.start(ctx, "History")
+	defer func() { done(err) }()
+	return r.next.History(ctx, id, limit)
+}
+
+func (r *instrumentedProductRepo) Update(ctx context.Context, product *models.Product) (err error) {
+	ctx, done := r.start(ctx, "Update")
+	defer func() { done(err) }()
+	return r.next.Update(ctx, product)
+}
+
+func (r *instrumentedProductRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := r.start(ctx, "Delete")
+	defer func() { done(err) }()
+	return r.next.Delete(ctx, id)
+}
+
+func (r *instrumentedProductRepo) Restore(ctx context.Context, product *models.Product) (err error) {
+	ctx, done := r.start(ctx, "Restore")
+	defer func() { done(err) }()
+	return r.next.Restore(ctx, product)
+}
+
+func (r *instrumentedProductRepo) List(ctx context.Context, limit, offset int) (_ []*models.Product, err error) {
+	ctx, done := r.start(ctx, "List")
+	defer func() { done(err) }()
+	return r.next.List(ctx, limit, offset)
+}
+
+func (r *instrumentedProductRepo) Count(ctx context.Context) (_ int, err error) {
+	ctx, done := r.start(ctx, "Count")
+	defer func() { done(err) }()
+	return r.next.Count(ctx)
+}
+
+func (r *instrumentedProductRepo) ListWithCount(ctx context.Context, limit, offset int) (_ []*models.Product, _ int, err error) {
+	ctx, done := r.start(ctx, "ListWithCount")
+	defer func() { done(err) }()
+	return r.next.ListWithCount(ctx, limit, offset)
+}
+
+func (r *instrumentedProductRepo) ListFiltered(ctx context.Context, filter models.ProductFilter, limit, offset int) (_ []*models.Product, err error) {
+	ctx, done := r.start(ctx, "ListFiltered")
+	defer func() { done(err) }()
+	return r.next.ListFiltered(ctx, filter, limit, offset)
+}
+
+func (r *instrumentedProductRepo) CountFiltered(ctx context.Context, filter models.ProductFilter) (_ int, err error) {
+	ctx, done := r.start(ctx, "CountFiltered")
+	defer func() { done(err) }()
+	return r.next.CountFiltered(ctx, filter)
+}
+
+func (r *instrumentedProductRepo) Facets(ctx context.Context, filter models.ProductFilter, spec models.FacetSpec) (_ models.Facets, err error) {
+	ctx, done := r.start(ctx, "Facets")
+	defer func() { done(err) }()
+	return r.next.Facets(ctx, filter, spec)
+}
+
+func (r *instrumentedProductRepo) Sample(ctx context.Context, filter models.ProductFilter, n int) (_ []*models.Product, err error) {
+	ctx, done := r.start(ctx, "Sample")
+	defer func() { done(err) }()
+	return r.next.Sample(ctx, filter, n)
+}
+
+func (r *instrumentedProductRepo) ListFilteredWithCount(ctx context.Context, filter models.ProductFilter, limit, offset int) (_ []*models.Product, _ int, err error) {
+	ctx, done := r.start(ctx, "ListFilteredWithCount")
+	defer func() { done(err) }()
+	return r.next.ListFilteredWithCount(ctx, filter, limit, offset)
+}
+
+func (r *instrumentedProductRepo) ListUpdatedSince(ctx context.Context, since time.Time) (_ []*models.Product, err error) {
+	ctx, done := r.start(ctx, "ListUpdatedSince")
+	defer func() { done(err) }()
+	return r.next.ListUpdatedSince(ctx, since)
+}
+
+func (r *instrumentedProductRepo) Related(ctx context.Context, id int, limit int, priceBand float64, region string, visible time.Time) (_ []*models.RelatedProduct, err error) {
+	ctx, done := r.start(ctx, "Related")
+	defer func() { done(err) }()
+	return r.next.Related(ctx, id, limit, priceBand, region, visible)
+}
+
+func (r *instrumentedProductRepo) AdjustStock(ctx context.Context, id int, delta int) (_ *models.Product, err error) {
+	ctx, done := r.start(ctx, "AdjustStock")
+	defer func() { done(err) }()
+	return r.next.AdjustStock(ctx, id, delta)
+}
+
+func (r *instrumentedProductRepo) BulkCreate(ctx context.Context, products []*models.Product) (_ int, err error) {
+	ctx, done := r.start(ctx, "BulkCreate")
+	defer func() { done(err) }()
+	return r.next.BulkCreate(ctx, products)
+}
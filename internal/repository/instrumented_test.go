@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// slowProductRepo sleeps before failing every GetByID
+type slowProductRepo struct {
+	ProductRepository
+	delay time.Duration
+	err   error
+}
+
+func (r *slowProductRepo) GetByID(ctx context.Context, id int) (*models.Product, error) {
+	time.Sleep(r.delay)
+	return nil, r.err
+}
+
+func TestInstrumentedProductRepository_SlowCalls(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	next := &slowProductRepo{delay: 5 * time.Millisecond, err: fmt.Errorf("product not found")}
+	repo := NewInstrumentedProductRepository(next, time.Millisecond, logger)
+
+	_, err := repo.GetByID(context.Background(), 1)
+	if err == nil || err.Error() != "product not found" {
+		t.Fatalf("expected the wrapped error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "slow repository call") || !strings.Contains(buf.String(), "method=GetByID") {
+		t.Errorf("expected a slow call log, got %q", buf.String())
+	}
+
+	buf.Reset()
+	next.delay = 0
+	repo = NewInstrumentedProductRepository(next, time.Second, logger)
+	_, _ = repo.GetByID(context.Background(), 1)
+	if buf.Len() != 0 {
+		t.Errorf("expected no log for a fast call, got %q", buf.String())
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("product not found"), "not_found"},
+		{fmt.Errorf("wrapped: %w", ErrInsufficientStock), "insufficient_stock"},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), "timeout"},
+		{context.Canceled, "canceled"},
+		{fmt.Errorf("failed to create product: %w", &pq.Error{Code: "23505"}), "constraint"},
+		{&pq.Error{Code: "40001"}, "database"},
+		{&BulkError{Chunks: []ChunkError{{Err: &pgconn.PgError{Code: "23505"}}}}, "constraint"},
+		{&pgconn.PgError{Code: "57014"}, "database"},
+		{fmt.Errorf("boom"), "other"},
+	}
+
+	for _, tt := range tests {
+		if got := errorKind(tt.err); got != tt.want {
+			t.Errorf("errorKind(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type LotRepository interface {
+	// GetByNumber returns a product's lot by its lot number
+	GetByNumber(ctx context.Context, productID int, lotNumber string) (*models.Lot, error)
+
+	// Create records a lot as received now; it doesn't touch the product's
+	// stock
+	Create(ctx context.Context, lot *models.Lot) error
+
+	// AdjustQuantity adds delta to what's left of a lot, returning
+	// ErrInsufficientStock instead of letting it go negative
+	AdjustQuantity(ctx context.Context, id int, delta int) (*models.Lot, error)
+
+	// ListByProduct returns a product's lots in the order they're consumed,
+	// only those with stock left unless all
+	ListByProduct(ctx context.Context, productID int, all bool, limit, offset int) ([]*models.Lot, error)
+
+	CountByProduct(ctx context.Context, productID int, all bool) (int, error)
+
+	// ForConsumption returns a product's lots with stock left in the order
+	// they're consumed, locking them for the rest of the transaction
+	ForConsumption(ctx context.Context, productID int) ([]*models.Lot, error)
+
+	// Expiring returns lots with stock left that expire before the given
+	// time, including those already expired, soonest first
+	Expiring(ctx context.Context, before time.Time, limit, offset int) ([]*models.ExpiringLot, error)
+
+	CountExpiring(ctx context.Context, before time.Time) (int, error)
+
+	// PendingAlerts is Expiring for the lots whose expiry alert hasn't been
+	// sent, up to limit
+	PendingAlerts(ctx context.Context, before time.Time, limit int) ([]*models.ExpiringLot, error)
+
+	// MarkAlerted records that a lot's expiry alert was sent
+	MarkAlerted(ctx context.Context, id int, at time.Time) error
+}
+
+type lotRepo struct {
+	db *database.DB
+}
+
+func NewLotRepository(db *database.DB) LotRepository {
+	return &lotRepo{db: db}
+}
+
+// lotOrder is first-expired-first-out, lots that don't expire last
+const lotOrder = `ORDER BY expires_at IS NULL, expires_at, received_at, id`
+
+var lotColumns = database.ColumnList(models.Lot{})
+
+const expiringLotsQuery = `
+	SELECT l.id, l.product_id, l.lot_number, l.quantity, l.expires_at, l.received_at, l.alerted_at, p.sku
+	FROM product_lots l
+	JOIN products p ON p.id = l.product_id
+	WHERE l.quantity > 0 AND l.expires_at < $1
+`
+
+func (r *lotRepo) GetByNumber(ctx context.Context, productID int, lotNumber string) (*models.Lot, error) {
+	query := `SELECT ` + lotColumns + ` FROM product_lots WHERE product_id = $1 AND lot_number = $2`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, productID, lotNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lot: %w", err)
+	}
+
+	lot := &models.Lot{}
+	err = database.ScanOne(lot, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("lot not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lot: %w", err)
+	}
+
+	return lot, nil
+}
+
+func (r *lotRepo) Create(ctx context.Context, lot *models.Lot) error {
+	query := `
+		INSERT INTO product_lots (product_id, lot_number, quantity, expires_at, received_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	lot.ReceivedAt = time.Now()
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, lot.ProductID, lot.LotNumber, lot.Quantity, lot.ExpiresAt, lot.ReceivedAt).Scan(&lot.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create lot: %w", err)
+	}
+
+	return nil
+}
+
+func (r *lotRepo) AdjustQuantity(ctx context.Context, id int, delta int) (*models.Lot, error) {
+	query := `
+		UPDATE product_lots SET quantity = quantity + $2
+		WHERE id = $1 AND quantity + $2 >= 0
+		RETURNING ` + lotColumns
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, id, delta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adjust lot: %w", err)
+	}
+
+	lot := &models.Lot{}
+	err = database.ScanOne(lot, rows)
+	if err == sql.ErrNoRows {
+		return nil, ErrInsufficientStock
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to adjust lot: %w", err)
+	}
+
+	return lot, nil
+}
+
+func (r *lotRepo) ListByProduct(ctx context.Context, productID int, all bool, limit, offset int) ([]*models.Lot, error) {
+	query := `
+		SELECT ` + lotColumns + `
+		FROM product_lots
+		WHERE product_id = $1 AND ($2 OR quantity > 0)
+		` + lotOrder + `
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, productID, all, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lots: %w", err)
+	}
+
+	lots := []*models.Lot{}
+	if err := database.ScanAll(&lots, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan lots: %w", err)
+	}
+
+	return lots, nil
+}
+
+func (r *lotRepo) CountByProduct(ctx context.Context, productID int, all bool) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM product_lots WHERE product_id = $1 AND ($2 OR quantity > 0)`, productID, all).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count lots: %w", err)
+	}
+	return count, nil
+}
+
+func (r *lotRepo) ForConsumption(ctx context.Context, productID int) ([]*models.Lot, error) {
+	query := `
+		SELECT ` + lotColumns + `
+		FROM product_lots
+		WHERE product_id = $1 AND quantity > 0
+		` + lotOrder + `
+		` + r.db.Dialect().ForUpdate()
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lots: %w", err)
+	}
+
+	var lots []*models.Lot
+	if err := database.ScanAll(&lots, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan lots: %w", err)
+	}
+
+	return lots, nil
+}
+
+func (r *lotRepo) Expiring(ctx context.Context, before time.Time, limit, offset int) ([]*models.ExpiringLot, error) {
+	query := expiringLotsQuery + `
+		ORDER BY l.expires_at, l.id
+		LIMIT $2 OFFSET $3
+	`
+
+	return r.expiring(ctx, query, before, limit, offset)
+}
+
+func (r *lotRepo) CountExpiring(ctx context.Context, before time.Time) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM product_lots WHERE quantity > 0 AND expires_at < $1`, before).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count expiring lots: %w", err)
+	}
+	return count, nil
+}
+
+func (r *lotRepo) PendingAlerts(ctx context.Context, before time.Time, limit int) ([]*models.ExpiringLot, error) {
+	query := expiringLotsQuery + ` AND l.alerted_at IS NULL
+		ORDER BY l.expires_at, l.id
+		LIMIT $2
+	`
+
+	return r.expiring(ctx, query, before, limit)
+}
+
+func (r *lotRepo) expiring(ctx context.Context, query string, args ...interface{}) ([]*models.ExpiringLot, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiring lots: %w", err)
+	}
+
+	lots := []*models.ExpiringLot{}
+	if err := database.ScanAll(&lots, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan expiring lots: %w", err)
+	}
+
+	return lots, nil
+}
+
+func (r *lotRepo) MarkAlerted(ctx context.Context, id int, at time.Time) error {
+	if _, err := r.db.Conn(ctx).ExecContext(ctx, `UPDATE product_lots SET alerted_at = $1 WHERE id = $2`, at, id); err != nil {
+		return fmt.Errorf("failed to mark lot alerted: %w", err)
+	}
+	return nil
+}
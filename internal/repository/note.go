@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// ProductNoteRepository stores the notes internal users leave on products
+type ProductNoteRepository interface {
+	// Create stores a note, created and updated now
+	Create(ctx context.Context, note *models.ProductNote) error
+
+	// GetByID returns a note of a product, "product note not found" for one
+	// of another product
+	GetByID(ctx context.Context, productID, id int) (*models.ProductNote, error)
+
+	// List returns the notes of a product, pinned ones first, newest first
+	List(ctx context.Context, productID int, limit, offset int) ([]*models.ProductNote, error)
+
+	Count(ctx context.Context, productID int) (int, error)
+
+	// Latest returns the newest note of each of the products that have one,
+	// by product ID
+	Latest(ctx context.Context, productIDs []int) (map[int]*models.ProductNote, error)
+
+	// Update stores a note's body and pinned flag, updated now
+	Update(ctx context.Context, note *models.ProductNote) error
+
+	Delete(ctx context.Context, productID, id int) error
+}
+
+type productNoteRepo struct {
+	db *database.DB
+}
+
+func NewProductNoteRepository(db *database.DB) ProductNoteRepository {
+	return &productNoteRepo{db: db}
+}
+
+var noteColumns = database.ColumnList(models.ProductNote{})
+
+func (r *productNoteRepo) Create(ctx context.Context, note *models.ProductNote) error {
+	query := `
+		INSERT INTO product_notes (product_id, author, body, pinned, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, note.ProductID, note.Author, note.Body, note.Pinned, now).Scan(&note.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create product note: %w", err)
+	}
+	note.CreatedAt, note.UpdatedAt = now, now
+
+	return nil
+}
+
+func (r *productNoteRepo) GetByID(ctx context.Context, productID, id int) (*models.ProductNote, error) {
+	query := `SELECT ` + noteColumns + ` FROM product_notes WHERE id = $1 AND product_id = $2`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, id, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product note: %w", err)
+	}
+
+	note := &models.ProductNote{}
+	err = database.ScanOne(note, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("product note not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product note: %w", err)
+	}
+
+	return note, nil
+}
+
+func (r *productNoteRepo) List(ctx context.Context, productID int, limit, offset int) ([]*models.ProductNote, error) {
+	query := `
+		SELECT ` + noteColumns + `
+		FROM product_notes
+		WHERE product_id = $1
+		ORDER BY pinned DESC, created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, productID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list product notes: %w", err)
+	}
+
+	notes := []*models.ProductNote{}
+	if err := database.ScanAll(&notes, rows); err != nil {
+		return nil, fmt.Errorf("failed to list product notes: %w", err)
+	}
+
+	return notes, nil
+}
+
+func (r *productNoteRepo) Count(ctx context.Context, productID int) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM product_notes WHERE product_id = $1`, productID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count product notes: %w", err)
+	}
+	return count, nil
+}
+
+func (r *productNoteRepo) Latest(ctx context.Context, productIDs []int) (map[int]*models.ProductNote, error) {
+	latest := make(map[int]*models.ProductNote)
+	if len(productIDs) == 0 {
+		return latest, nil
+	}
+
+	// Each product's notes newest first; the first one seen is kept
+	dialect := r.db.Dialect()
+	query := `
+		SELECT ` + noteColumns + `
+		FROM product_notes
+		WHERE ` + dialect.AnyOf("product_id", 1) + `
+		ORDER BY product_id, created_at DESC, id DESC
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, dialect.Array(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest product notes: %w", err)
+	}
+
+	var notes []*models.ProductNote
+	if err := database.ScanAll(&notes, rows); err != nil {
+		return nil, fmt.Errorf("failed to get latest product notes: %w", err)
+	}
+	for _, note := range notes {
+		if _, ok := latest[note.ProductID]; !ok {
+			latest[note.ProductID] = note
+		}
+	}
+
+	return latest, nil
+}
+
+func (r *productNoteRepo) Update(ctx context.Context, note *models.ProductNote) error {
+	now := time.Now()
+	result, err := r.db.Conn(ctx).ExecContext(ctx,
+		`UPDATE product_notes SET body = $3, pinned = $4, updated_at = $5 WHERE id = $1 AND product_id = $2`,
+		note.ID, note.ProductID, note.Body, note.Pinned, now)
+	if err != nil {
+		return fmt.Errorf("failed to update product note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("product note not found")
+	}
+	note.UpdatedAt = now
+
+	return nil
+}
+
+func (r *productNoteRepo) Delete(ctx context.Context, productID, id int) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM product_notes WHERE id = $1 AND product_id = $2`, id, productID)
+	if err != nil {
+		return fmt.Errorf("failed to delete product note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("product note not found")
+	}
+
+	return nil
+}
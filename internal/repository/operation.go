@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// OperationRepository records the long-running operations of async endpoints
+type OperationRepository interface {
+	// Create records an operation as it starts, running with no progress
+	Create(ctx context.Context, op *models.Operation) error
+
+	GetByID(ctx context.Context, id int) (*models.Operation, error)
+
+	// SetProgress records how far a running operation has got, in percent
+	// and as a checkpoint describing the work done
+	SetProgress(ctx context.Context, id, progress int, checkpoint string) error
+
+	// RequestCancel asks a running operation to stop and returns it. It
+	// fails with "operation has finished" once it has.
+	RequestCancel(ctx context.Context, id int) (*models.Operation, error)
+
+	// CancelRequested reports whether an operation was asked to stop
+	CancelRequested(ctx context.Context, id int) (bool, error)
+
+	// Finish records the outcome of an operation: its status, progress,
+	// checkpoint, result, and error
+	Finish(ctx context.Context, op *models.Operation) error
+
+	// FailInterrupted fails the operations still running, which a restart
+	// cut short, and returns how many there were
+	FailInterrupted(ctx context.Context) (int64, error)
+}
+
+type operationRepo struct {
+	db *database.DB
+}
+
+func NewOperationRepository(db *database.DB) OperationRepository {
+	return &operationRepo{db: db}
+}
+
+var operationColumns = database.ColumnList(models.Operation{})
+
+func (r *operationRepo) Create(ctx context.Context, op *models.Operation) error {
+	query := `
+		INSERT INTO operations (kind, status, progress, created_at, updated_at)
+		VALUES ($1, $2, 0, $3, $3)
+		RETURNING id
+	`
+
+	now := time.Now()
+	op.Status, op.Progress = models.OperationRunning, 0
+	if err := r.db.Conn(ctx).QueryRowContext(ctx, query, op.Kind, op.Status, now).Scan(&op.ID); err != nil {
+		return fmt.Errorf("failed to create operation: %w", err)
+	}
+	op.CreatedAt, op.UpdatedAt = now, now
+
+	return nil
+}
+
+func (r *operationRepo) GetByID(ctx context.Context, id int) (*models.Operation, error) {
+	query := `SELECT ` + operationColumns + ` FROM operations WHERE id = $1`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+
+	op := &models.Operation{}
+	err = database.ScanOne(op, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("operation not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+
+	return op, nil
+}
+
+func (r *operationRepo) SetProgress(ctx context.Context, id, progress int, checkpoint string) error {
+	_, err := r.db.Conn(ctx).ExecContext(ctx,
+		`UPDATE operations SET progress = $2, checkpoint = $3, updated_at = $4 WHERE id = $1 AND status = $5`,
+		id, progress, checkpoint, time.Now(), models.OperationRunning)
+	if err != nil {
+		return fmt.Errorf("failed to record operation progress: %w", err)
+	}
+	return nil
+}
+
+func (r *operationRepo) RequestCancel(ctx context.Context, id int) (*models.Operation, error) {
+	query := `
+		UPDATE operations SET
+			cancel_requested_at = COALESCE(cancel_requested_at, $2),
+			updated_at = $2
+		WHERE id = $1 AND status = $3
+	`
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, id, time.Now(), models.OperationRunning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel operation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	op, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return op, fmt.Errorf("operation has finished")
+	}
+
+	return op, nil
+}
+
+func (r *operationRepo) CancelRequested(ctx context.Context, id int) (bool, error) {
+	var requested bool
+	err := r.db.Conn(ctx).QueryRowContext(ctx,
+		`SELECT cancel_requested_at IS NOT NULL FROM operations WHERE id = $1`, id).Scan(&requested)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("operation not found")
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check operation cancellation: %w", err)
+	}
+	return requested, nil
+}
+
+func (r *operationRepo) Finish(ctx context.Context, op *models.Operation) error {
+	query := `
+		UPDATE operations SET
+			status = $2,
+			progress = $3,
+			checkpoint = $4,
+			result = $5,
+			error = $6,
+			updated_at = $7,
+			finished_at = $7
+		WHERE id = $1
+	`
+
+	now := time.Now()
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, op.ID, op.Status, op.Progress, op.Checkpoint, op.Result, op.Error, now)
+	if err != nil {
+		return fmt.Errorf("failed to finish operation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("operation not found")
+	}
+	op.UpdatedAt, op.FinishedAt = now, &now
+
+	return nil
+}
+
+func (r *operationRepo) FailInterrupted(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE operations SET
+			status = $1,
+			error = $2,
+			updated_at = $3,
+			finished_at = $3
+		WHERE status = $4
+	`
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query,
+		models.OperationFailed,
+		"interrupted by a restart",
+		time.Now(),
+		models.OperationRunning,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted operations: %w", err)
+	}
+
+	return result.RowsAffected()
+}
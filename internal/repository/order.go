@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type OrderRepository interface {
+	// MarkProcessed records the order as applied and reports false if it had
+	// already been recorded for the same source. Call it inside the transaction
+	// that applies the order so the record and the stock changes commit together.
+	MarkProcessed(ctx context.Context, source string, order *models.Order) (bool, error)
+}
+
+type orderRepo struct {
+	db *database.DB
+}
+
+func NewOrderRepository(db *database.DB) OrderRepository {
+	return &orderRepo{db: db}
+}
+
+func (r *orderRepo) MarkProcessed(ctx context.Context, source string, order *models.Order) (bool, error) {
+	query := `
+		INSERT INTO processed_orders (source, order_id, line_count)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (source, order_id) DO NOTHING
+	`
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, source, order.ID, len(order.Lines))
+	if err != nil {
+		return false, fmt.Errorf("failed to record processed order: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows == 1, nil
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type OutboxRepository interface {
+	// Add stores messages, joining the caller's transaction if ctx has one
+	Add(ctx context.Context, messages ...*models.OutboxMessage) error
+
+	// FetchPending locks up to limit undelivered messages in id order. Must be
+	// called inside a transaction; rows locked by other relays are skipped.
+	FetchPending(ctx context.Context, limit int) ([]*models.OutboxMessage, error)
+
+	MarkPublished(ctx context.Context, ids []int64) error
+
+	MarkFailed(ctx context.Context, ids []int64, cause error) error
+
+	CountPending(ctx context.Context) (int, error)
+
+	// Changes returns up to limit committed messages of the given types after
+	// cursor, in commit order. Messages of transactions that may still commit
+	// are held back, so a message never appears behind one already returned.
+	Changes(ctx context.Context, types []string, after models.ChangeCursor, limit int) ([]*models.OutboxMessage, error)
+
+	// Head returns the cursor of the last committed message of the given
+	// types, or the zero cursor when there is none
+	Head(ctx context.Context, types []string) (models.ChangeCursor, error)
+
+	// Has reports whether the message at cursor is still stored
+	Has(ctx context.Context, cursor models.ChangeCursor) (bool, error)
+}
+
+type outboxRepo struct {
+	db *database.DB
+}
+
+func NewOutboxRepository(db *database.DB) OutboxRepository {
+	return &outboxRepo{db: db}
+}
+
+var outboxColumns = database.ColumnList(models.OutboxMessage{})
+
+func (r *outboxRepo) Add(ctx context.Context, messages ...*models.OutboxMessage) error {
+	query := `
+		INSERT INTO event_outbox (
+			event_id, event_type, schema_version, aggregate_id, payload, created_at, published_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		) RETURNING id, txid
+	`
+
+	for _, msg := range messages {
+		msg.CreatedAt = time.Now()
+
+		err := r.db.Conn(ctx).QueryRowContext(ctx, query,
+			msg.EventID,
+			msg.EventType,
+			msg.SchemaVersion,
+			msg.AggregateID,
+			[]byte(msg.Payload),
+			msg.CreatedAt,
+			msg.PublishedAt,
+		).Scan(&msg.ID, &msg.TxID)
+
+		if err != nil {
+			return fmt.Errorf("failed to add outbox message %s: %w", msg.EventID, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *outboxRepo) FetchPending(ctx context.Context, limit int) ([]*models.OutboxMessage, error) {
+	query := `
+		SELECT ` + outboxColumns + `
+		FROM event_outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		` + r.db.Dialect().SkipLocked()
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending outbox messages: %w", err)
+	}
+
+	var messages []*models.OutboxMessage
+	if err := database.ScanAll(&messages, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan outbox messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *outboxRepo) MarkPublished(ctx context.Context, ids []int64) error {
+	query := `UPDATE event_outbox SET published_at = $2, attempts = attempts + 1, last_error = NULL WHERE ` + r.db.Dialect().AnyOf("id", 1)
+
+	if _, err := r.db.Conn(ctx).ExecContext(ctx, query, r.db.Dialect().Array(ids), time.Now()); err != nil {
+		return fmt.Errorf("failed to mark outbox messages published: %w", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepo) MarkFailed(ctx context.Context, ids []int64, cause error) error {
+	query := `UPDATE event_outbox SET attempts = attempts + 1, last_error = $2 WHERE ` + r.db.Dialect().AnyOf("id", 1)
+
+	if _, err := r.db.Conn(ctx).ExecContext(ctx, query, r.db.Dialect().Array(ids), cause.Error()); err != nil {
+		return fmt.Errorf("failed to mark outbox messages failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepo) CountPending(ctx context.Context) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM event_outbox WHERE published_at IS NULL`
+
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending outbox messages: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *outboxRepo) Changes(ctx context.Context, types []string, after models.ChangeCursor, limit int) ([]*models.OutboxMessage, error) {
+	dialect := r.db.Dialect()
+	query := `
+		SELECT ` + outboxColumns + `
+		FROM event_outbox
+		WHERE (txid, id) > ($1, $2)
+			AND txid < ` + dialect.CommitHorizon() + `
+			AND ` + dialect.AnyOf("event_type", 3) + `
+		ORDER BY txid, id
+		LIMIT $4
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, after.TxID, after.ID, dialect.Array(types), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+
+	var messages []*models.OutboxMessage
+	if err := database.ScanAll(&messages, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan changes: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *outboxRepo) Head(ctx context.Context, types []string) (models.ChangeCursor, error) {
+	dialect := r.db.Dialect()
+	query := `
+		SELECT txid, id
+		FROM event_outbox
+		WHERE txid < ` + dialect.CommitHorizon() + `
+			AND ` + dialect.AnyOf("event_type", 1) + `
+		ORDER BY txid DESC, id DESC
+		LIMIT 1
+	`
+
+	var head models.ChangeCursor
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, dialect.Array(types)).Scan(&head.TxID, &head.ID)
+	if err == sql.ErrNoRows {
+		return models.ChangeCursor{}, nil
+	}
+	if err != nil {
+		return models.ChangeCursor{}, fmt.Errorf("failed to get change feed head: %w", err)
+	}
+
+	return head, nil
+}
+
+func (r *outboxRepo) Has(ctx context.Context, cursor models.ChangeCursor) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM event_outbox WHERE id = $1 AND txid = $2)`
+
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, cursor.ID, cursor.TxID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up change cursor: %w", err)
+	}
+
+	return exists, nil
+}
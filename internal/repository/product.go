@@ -3,10 +3,14 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/id"
 	"{{MODULE_NAME}}/internal/models"
 )
 
@@ -17,15 +21,82 @@ type ProductRepository interface {
 
 	GetBySKU(ctx context.Context, sku string) (*models.Product, error)
 
+	// GetByIDForUpdate is GetByID locking the product's row for the rest of
+	// the transaction, so it can't change before the caller writes it
+	GetByIDForUpdate(ctx context.Context, id int) (*models.Product, error)
+
+	// ResolveUID returns the ID of the product with the unique ID uid
+	ResolveUID(ctx context.Context, uid string) (int, error)
+
+	// AssignUIDs gives up to limit products without a unique ID one from
+	// next, oldest first, and returns how many it assigned
+	AssignUIDs(ctx context.Context, next func() string, limit int) (int, error)
+
+	// GetByIDAsOf returns the product as it was at asOf, from products_history.
+	// A product that didn't exist yet or was already deleted is not found.
+	GetByIDAsOf(ctx context.Context, id int, asOf time.Time) (*models.Product, error)
+
+	// History returns up to limit versions of a product, newest first. It
+	// includes the versions of deleted products.
+	History(ctx context.Context, id int, limit int) ([]*models.ProductVersion, error)
+
 	Update(ctx context.Context, product *models.Product) error
 
+	// Delete fails with ErrInBundle for a component of a bundle
 	Delete(ctx context.Context, id int) error
 
+	// Restore inserts a deleted product again, with its ID, tags, and
+	// creation time
+	Restore(ctx context.Context, product *models.Product) error
+
 	List(ctx context.Context, limit, offset int) ([]*models.Product, error)
 
 	Count(ctx context.Context) (int, error)
+
+	// ListWithCount returns a page of List and the Count of all products, in
+	// one query
+	ListWithCount(ctx context.Context, limit, offset int) ([]*models.Product, int, error)
+
+	// ListFiltered returns the products matching filter, in its order
+	ListFiltered(ctx context.Context, filter models.ProductFilter, limit, offset int) ([]*models.Product, error)
+
+	CountFiltered(ctx context.Context, filter models.ProductFilter) (int, error)
+
+	// ListFilteredWithCount returns a page of ListFiltered and the count of
+	// all products matching filter, in one query
+	ListFilteredWithCount(ctx context.Context, filter models.ProductFilter, limit, offset int) ([]*models.Product, int, error)
+
+	// Facets counts the products matching filter by the facets of spec
+	Facets(ctx context.Context, filter models.ProductFilter, spec models.FacetSpec) (models.Facets, error)
+
+	// Sample returns up to n products matching filter, chosen uniformly at
+	// random, in random order
+	Sample(ctx context.Context, filter models.ProductFilter, n int) ([]*models.Product, error)
+
+	// ListUpdatedSince returns products changed after since, oldest change first
+	ListUpdatedSince(ctx context.Context, since time.Time) ([]*models.Product, error)
+
+	// AdjustStock atomically adds delta (negative to decrement) to a product's
+	// quantity and returns the updated product. It fails with
+	// ErrInsufficientStock instead of letting the quantity go negative.
+	AdjustStock(ctx context.Context, id int, delta int) (*models.Product, error)
+
+	// Related returns up to limit products sharing tags or the category with
+	// the product, best match first: the more shared tags the better, plus
+	// one for the same category and one for a unit price within priceBand
+	// (a fraction, 0.2 is ±20%) of the product's. Only products visible at
+	// visible, and sold in region unless it's empty, are related; their tags
+	// aren't loaded.
+	Related(ctx context.Context, id int, limit int, priceBand float64, region string, visible time.Time) ([]*models.RelatedProduct, error)
+
+	// BulkCreate inserts products in chunks, with COPY when the connection
+	// supports it, and returns how many were inserted. Each chunk is atomic,
+	// tags included; failed chunks are reported in a *BulkError.
+	BulkCreate(ctx context.Context, products []*models.Product) (int, error)
 }
 
+var ErrInsufficientStock = errors.New("insufficient stock")
+
 type productRepo struct {
 	db *database.DB
 }
@@ -34,87 +105,214 @@ func NewProductRepository(db *database.DB) ProductRepository {
 	return &productRepo{db: db}
 }
 
+// productColumns is derived from the db tags of models.Product
+var productColumns = database.ColumnList(models.Product{})
+
+// productVersionColumns selects products_history rows as models.ProductVersion,
+// whose ID is the product's rather than the version's
+const productVersionColumns = `product_id AS id, sku, name, description, category, unit, quantity, unit_price,
+	created_at, updated_at, operation, valid_from, valid_to`
+
+// Reads are declared here so the explain endpoint runs exactly what the
+// methods do, see ExplainableQueries
+var (
+	productByIDQuery  = `SELECT ` + productColumns + ` FROM products WHERE id = $1`
+	productBySKUQuery = `SELECT ` + productColumns + ` FROM products WHERE sku = $1`
+
+	productAsOfQuery = `
+		SELECT product_id AS id, sku, name, description, category, unit, quantity, unit_price, created_at, updated_at
+		FROM products_history
+		WHERE product_id = $1 AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2)
+		ORDER BY valid_from DESC, id DESC
+		LIMIT 1
+	`
+
+	productHistoryQuery = `
+		SELECT ` + productVersionColumns + `
+		FROM products_history
+		WHERE product_id = $1
+		ORDER BY valid_from DESC, id DESC
+		LIMIT $2
+	`
+
+	productListQuery = `
+		SELECT ` + productColumns + `
+		FROM products
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	productCountQuery = `SELECT COUNT(*) FROM products`
+
+	// The window counts the rows before LIMIT applies. Ties, such as a bulk
+	// insert's, go by ID, so pages don't overlap.
+	productListWithCountQuery = `
+		SELECT ` + productColumns + `, COUNT(*) OVER () AS total_count
+		FROM products
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	productsUpdatedSinceQuery = `
+		SELECT ` + productColumns + `
+		FROM products
+		WHERE updated_at > $1
+		ORDER BY updated_at ASC
+	`
+)
+
 
 func (r *productRepo) Create(ctx context.Context, product *models.Product) error {
 	query := `
 		INSERT INTO products (
-			sku, name, description, quantity, unit_price, created_at, updated_at
+			uid, sku, name, slug, description, category, unit, quantity, unit_price, visible_from, visible_until, listed, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
 		) RETURNING id
 	`
 
+	if product.Unit == "" {
+		product.Unit = models.DefaultUnit
+	}
+	if product.UID == "" {
+		product.UID = id.ProductID(id.Generate())
+	}
 	now := time.Now()
 	product.CreatedAt = now
 	product.UpdatedAt = now
+	product.Listed = product.VisibleAt(now) // Nothing to announce yet
+
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := uniqueSlugs(ctx, r.db, []*models.Product{product}); err != nil {
+			return err
+		}
 
-	err := r.db.QueryRowContext(ctx, query,
-		product.SKU,
-		product.Name,
-		product.Description,
-		product.Quantity,
-		product.UnitPrice,
-		product.CreatedAt,
-		product.UpdatedAt,
-	).Scan(&product.ID)
+		err := r.db.Conn(ctx).QueryRowContext(ctx, query,
+			string(product.UID),
+			product.SKU,
+			product.Name,
+			product.Slug,
+			product.Description,
+			product.Category,
+			product.Unit,
+			product.Quantity,
+			product.UnitPrice,
+			product.VisibleFrom,
+			product.VisibleUntil,
+			product.Listed,
+			product.CreatedAt,
+			product.UpdatedAt,
+		).Scan(&product.ID)
 
-	if err != nil {
-		return fmt.Errorf("failed to create product: %w", err)
-	}
+		if err != nil {
+			return fmt.Errorf("failed to create product: %w", err)
+		}
 
-	return nil
+		return r.insertTags(ctx, product)
+	})
 }
 
 func (r *productRepo) GetByID(ctx context.Context, id int) (*models.Product, error) {
-	query := `
-		SELECT 
-			id, sku, name, description, quantity, unit_price, created_at, updated_at
-		FROM products
-		WHERE id = $1
-	`
+	return r.getTagged(ctx, productByIDQuery, id)
+}
 
-	product := &models.Product{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&product.ID,
-		&product.SKU,
-		&product.Name,
-		&product.Description,
-		&product.Quantity,
-		&product.UnitPrice,
-		&product.CreatedAt,
-		&product.UpdatedAt,
-	)
+func (r *productRepo) GetByIDForUpdate(ctx context.Context, id int) (*models.Product, error) {
+	return r.getTagged(ctx, productByIDQuery+" "+r.db.Dialect().ForUpdate(), id)
+}
 
+func (r *productRepo) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	return r.getTagged(ctx, productBySKUQuery, sku)
+}
+
+func (r *productRepo) ResolveUID(ctx context.Context, uid string) (int, error) {
+	var id int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT id FROM products WHERE uid = $1 AND uid <> ''`, uid).Scan(&id)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("product not found")
+		return 0, fmt.Errorf("product not found")
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get product: %w", err)
+		return 0, fmt.Errorf("failed to resolve product: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *productRepo) AssignUIDs(ctx context.Context, next func() string, limit int) (int, error) {
+	assigned := 0
+	err := r.db.WithTx(ctx, func(ctx context.Context) error {
+		rows, err := r.db.Conn(ctx).QueryContext(ctx, `SELECT id FROM products WHERE uid = '' ORDER BY id LIMIT $1`, limit)
+		if err != nil {
+			return fmt.Errorf("failed to list products without unique IDs: %w", err)
+		}
+		var pending []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan product ID: %w", err)
+			}
+			pending = append(pending, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to list products without unique IDs: %w", err)
+		}
+
+		// updated_at is left alone: the product didn't change, so neither
+		// the change feed nor the search index should see it
+		for _, id := range pending {
+			if _, err := r.db.Conn(ctx).ExecContext(ctx, `UPDATE products SET uid = $1 WHERE id = $2 AND uid = ''`, next(), id); err != nil {
+				return fmt.Errorf("failed to assign unique ID: %w", err)
+			}
+			assigned++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return assigned, nil
+}
+
+func (r *productRepo) GetByIDAsOf(ctx context.Context, id int, asOf time.Time) (*models.Product, error) {
+	return r.getOne(ctx, productAsOfQuery, id, asOf.UTC())
+}
+
+func (r *productRepo) History(ctx context.Context, id int, limit int) ([]*models.ProductVersion, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, productHistoryQuery, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product history: %w", err)
 	}
 
+	var versions []*models.ProductVersion
+	if err := database.ScanAll(&versions, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan product history: %w", err)
+	}
+
+	return versions, nil
+}
+
+// getTagged is getOne with the product's tags
+func (r *productRepo) getTagged(ctx context.Context, query string, args ...interface{}) (*models.Product, error) {
+	product, err := r.getOne(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadTags(ctx, product); err != nil {
+		return nil, err
+	}
 	return product, nil
 }
 
-func (r *productRepo) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
-	query := `
-		SELECT 
-			id, sku, name, description, quantity, unit_price, created_at, updated_at
-		FROM products
-		WHERE sku = $1
-	`
+func (r *productRepo) getOne(ctx context.Context, query string, args ...interface{}) (*models.Product, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
 
 	product := &models.Product{}
-	err := r.db.QueryRowContext(ctx, query, sku).Scan(
-		&product.ID,
-		&product.SKU,
-		&product.Name,
-		&product.Description,
-		&product.Quantity,
-		&product.UnitPrice,
-		&product.CreatedAt,
-		&product.UpdatedAt,
-	)
-
+	err = database.ScanOne(product, rows)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("product not found")
 	}
@@ -130,45 +328,91 @@ func (r *productRepo) Update(ctx context.Context, product *models.Product) error
 		UPDATE products SET
 			sku = $2,
 			name = $3,
-			description = $4,
-			quantity = $5,
-			unit_price = $6,
-			updated_at = $7
+			slug = $4,
+			description = $5,
+			category = $6,
+			unit = $7,
+			quantity = $8,
+			unit_price = $9,
+			visible_from = $10,
+			visible_until = $11,
+			updated_at = $12
 		WHERE id = $1
 	`
 
+	if product.Unit == "" {
+		product.Unit = models.DefaultUnit
+	}
 	product.UpdatedAt = time.Now()
 
-	result, err := r.db.ExecContext(ctx, query,
-		product.ID,
-		product.SKU,
-		product.Name,
-		product.Description,
-		product.Quantity,
-		product.UnitPrice,
-		product.UpdatedAt,
-	)
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		// A product updated without a slug keeps its own
+		var current string
+		err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT slug FROM products WHERE id = $1 `+r.db.Dialect().ForUpdate(), product.ID).Scan(&current)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("product not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update product: %w", err)
+		}
+		if product.Slug == "" {
+			product.Slug = current
+		}
 
-	if err != nil {
-		return fmt.Errorf("failed to update product: %w", err)
-	}
+		result, err := r.db.Conn(ctx).ExecContext(ctx, query,
+			product.ID,
+			product.SKU,
+			product.Name,
+			product.Slug,
+			product.Description,
+			product.Category,
+			product.Unit,
+			product.Quantity,
+			product.UnitPrice,
+			product.VisibleFrom,
+			product.VisibleUntil,
+			product.UpdatedAt,
+		)
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
+		if err != nil {
+			return fmt.Errorf("failed to update product: %w", err)
+		}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("product not found")
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
 
-	return nil
+		if rowsAffected == 0 {
+			return fmt.Errorf("product not found")
+		}
+		if product.Slug != current {
+			if err := replaceSlug(ctx, r.db, product.ID, current, product.Slug); err != nil {
+				return err
+			}
+		}
+
+		// Tags are replaced along with the rest of the product
+		if _, err := r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM product_tags WHERE product_id = $1`, product.ID); err != nil {
+			return fmt.Errorf("failed to replace product tags: %w", err)
+		}
+		return r.insertTags(ctx, product)
+	})
 }
 
 func (r *productRepo) Delete(ctx context.Context, id int) error {
+	var inBundle bool
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM bundle_components WHERE component_id = $1)`, id).Scan(&inBundle)
+	if err != nil {
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+	if inBundle {
+		return ErrInBundle
+	}
+
 	query := `DELETE FROM products WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete product: %w", err)
 	}
@@ -185,55 +429,465 @@ func (r *productRepo) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-func (r *productRepo) List(ctx context.Context, limit, offset int) ([]*models.Product, error) {
+func (r *productRepo) Restore(ctx context.Context, product *models.Product) error {
 	query := `
-		SELECT 
-			id, sku, name, description, quantity, unit_price, created_at, updated_at
-		FROM products
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
+		INSERT INTO products (
+			id, uid, sku, name, slug, description, category, unit, quantity, unit_price, visible_from, visible_until, listed, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+		)
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list products: %w", err)
-	}
-	defer rows.Close()
+	product.UpdatedAt = time.Now()
+	product.Listed = product.VisibleAt(product.UpdatedAt)
+
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		// Its slug may have gone to another product while it was deleted
+		if product.Slug != "" {
+			available, err := NewProductSlugRepository(r.db).Available(ctx, product.Slug, product.ID)
+			if err != nil {
+				return err
+			}
+			if !available {
+				product.Slug = ""
+			}
+		}
+		if err := uniqueSlugs(ctx, r.db, []*models.Product{product}); err != nil {
+			return err
+		}
 
-	var products []*models.Product
-	for rows.Next() {
-		product := &models.Product{}
-		err := rows.Scan(
-			&product.ID,
-			&product.SKU,
-			&product.Name,
-			&product.Description,
-			&product.Quantity,
-			&product.UnitPrice,
-			&product.CreatedAt,
-			&product.UpdatedAt,
+		_, err := r.db.Conn(ctx).ExecContext(ctx, query,
+			product.ID,
+			string(product.UID),
+			product.SKU,
+			product.Name,
+			product.Slug,
+			product.Description,
+			product.Category,
+			product.Unit,
+			product.Quantity,
+			product.UnitPrice,
+			product.VisibleFrom,
+			product.VisibleUntil,
+			product.Listed,
+			product.CreatedAt,
+			product.UpdatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan product: %w", err)
+			return fmt.Errorf("failed to restore product: %w", err)
 		}
-		products = append(products, product)
+
+		if err := r.insertTags(ctx, product); err != nil {
+			return err
+		}
+		return NewProductRegionRepository(r.db).Add(ctx, []int{product.ID}, product.Regions)
+	})
+}
+
+func (r *productRepo) List(ctx context.Context, limit, offset int) ([]*models.Product, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, productListQuery, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+	var products []*models.Product
+	if err := database.ScanAll(&products, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan products: %w", err)
 	}
 
+	if err := r.loadTags(ctx, products...); err != nil {
+		return nil, err
+	}
 	return products, nil
 }
 
 func (r *productRepo) Count(ctx context.Context) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM products`
+	err := r.db.Conn(ctx).QueryRowContext(ctx, productCountQuery).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count products: %w", err)
+	}
 
-	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	return count, nil
+}
+
+func (r *productRepo) ListWithCount(ctx context.Context, limit, offset int) ([]*models.Product, int, error) {
+	if r.db.Pipelined(ctx) {
+		return r.listAndCount(ctx, productListQuery, []interface{}{limit, offset}, productCountQuery, nil)
+	}
+	return r.listWithCount(ctx, func() (int, error) { return r.Count(ctx) }, productListWithCountQuery, limit, offset)
+}
+
+func (r *productRepo) ListFiltered(ctx context.Context, filter models.ProductFilter, limit, offset int) ([]*models.Product, error) {
+	query, args := productFilterListQuery(filter, productColumns)
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	var products []*models.Product
+	if err := database.ScanAll(&products, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan products: %w", err)
+	}
+
+	if err := r.loadTags(ctx, products...); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *productRepo) CountFiltered(ctx context.Context, filter models.ProductFilter) (int, error) {
+	where, args := productFilterConditions(filter)
+
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM products`+where, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count products: %w", err)
 	}
 
 	return count, nil
 }
+
+func (r *productRepo) ListFilteredWithCount(ctx context.Context, filter models.ProductFilter, limit, offset int) ([]*models.Product, int, error) {
+	if r.db.Pipelined(ctx) {
+		list, args := productFilterListQuery(filter, productColumns)
+		where, countArgs := productFilterConditions(filter)
+		return r.listAndCount(ctx, list, append(args, limit, offset), `SELECT COUNT(*) FROM products`+where, countArgs)
+	}
+
+	query, args := productFilterListQuery(filter, productColumns+", COUNT(*) OVER () AS total_count")
+	count := func() (int, error) { return r.CountFiltered(ctx, filter) }
+	return r.listWithCount(ctx, count, query, append(args, limit, offset)...)
+}
+
+func (r *productRepo) Facets(ctx context.Context, filter models.ProductFilter, spec models.FacetSpec) (models.Facets, error) {
+	where, args := productFilterConditions(filter)
+	return facetCounts(ctx, r.db.Conn(ctx), "products", where, args, spec)
+}
+
+// sampleSortLimit is the estimated number of products up to which Sample
+// sorts them all at random; beyond it a table sample is sorted instead
+const sampleSortLimit = 10000
+
+// sampleOversampling is how many times n rows Sample aims to read from a
+// table sample, so that a filter rarely leaves fewer than n of them
+const sampleOversampling = 10
+
+func (r *productRepo) Sample(ctx context.Context, filter models.ProductFilter, n int) ([]*models.Product, error) {
+	dialect := r.db.Dialect()
+	where, args := productFilterConditions(filter)
+	args = append(args, n)
+	query := func(sample string) string {
+		return `SELECT ` + productColumns + ` FROM products ` + sample + where + `
+			ORDER BY random()
+			LIMIT $` + strconv.Itoa(len(args))
+	}
+
+	var estimate int64
+	if q := dialect.RowEstimateQuery("products"); q != "" {
+		if err := r.db.Conn(ctx).QueryRowContext(ctx, q).Scan(&estimate); err != nil {
+			return nil, fmt.Errorf("failed to estimate products: %w", err)
+		}
+	}
+	if estimate > sampleSortLimit {
+		percent := min(100, 100*float64(n*sampleOversampling)/float64(estimate))
+		products, err := r.sample(ctx, query(dialect.TableSample(percent)), args)
+		if err != nil || len(products) == n {
+			return products, err
+		}
+		// Too few of the sampled rows matched; sort every match instead
+	}
+	return r.sample(ctx, query(""), args)
+}
+
+func (r *productRepo) sample(ctx context.Context, query string, args []interface{}) ([]*models.Product, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample products: %w", err)
+	}
+
+	var products []*models.Product
+	if err := database.ScanAll(&products, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan products: %w", err)
+	}
+
+	if err := r.loadTags(ctx, products...); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// listAndCount sends a list query and its count query in one batch. The
+// window of listWithCount makes Postgres read every matching row before
+// LIMIT applies; separately, the list can stop at the page.
+func (r *productRepo) listAndCount(ctx context.Context, list string, listArgs []interface{}, count string, countArgs []interface{}) ([]*models.Product, int, error) {
+	var products []*models.Product
+	var total int
+	batch := &database.Batch{}
+	batch.Query(&products, list, listArgs...)
+	batch.QueryRow(count, countArgs, &total)
+	if err := r.db.SendBatch(ctx, batch); err != nil {
+		return nil, 0, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	if err := r.loadTags(ctx, products...); err != nil {
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+// countedProduct is a product row with the COUNT(*) OVER () of its query
+type countedProduct struct {
+	models.Product
+	Total int `db:"total_count"`
+}
+
+// listWithCount runs query, which selects the product columns and
+// total_count, and returns its products and total. A page past the end has
+// no rows to carry the total, so count is run for it instead.
+func (r *productRepo) listWithCount(ctx context.Context, count func() (int, error), query string, args ...interface{}) ([]*models.Product, int, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	var counted []*countedProduct
+	if err := database.ScanAll(&counted, rows); err != nil {
+		return nil, 0, fmt.Errorf("failed to scan products: %w", err)
+	}
+	if len(counted) == 0 {
+		total, err := count()
+		return nil, total, err
+	}
+
+	products := make([]*models.Product, len(counted))
+	for i, c := range counted {
+		products[i] = &c.Product
+	}
+	if err := r.loadTags(ctx, products...); err != nil {
+		return nil, 0, err
+	}
+	return products, counted[0].Total, nil
+}
+
+// productFilterListQuery selects columns of the products matching a
+// validated filter, in its order, with the arguments it binds. The LIMIT and
+// OFFSET follow them.
+func productFilterListQuery(filter models.ProductFilter, columns string) (string, []interface{}) {
+	where, args := productFilterConditions(filter)
+	return `
+		SELECT ` + columns + `
+		FROM products` + where + `
+		ORDER BY ` + productFilterOrder(filter) + `
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2), args
+}
+
+// productFilterConditions builds the WHERE clause of a validated filter,
+// with the arguments it binds
+func productFilterConditions(filter models.ProductFilter) (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	placeholder := func(arg interface{}) string {
+		args = append(args, arg)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Category != "" {
+		conditions = append(conditions, "LOWER(category) = LOWER("+placeholder(filter.Category)+")")
+	}
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM product_tags t WHERE t.product_id = products.id AND t.tag = "+placeholder(strings.ToLower(strings.TrimSpace(tag)))+")")
+	}
+	if filter.MinPrice != nil {
+		conditions = append(conditions, "unit_price >= "+placeholder(*filter.MinPrice))
+	}
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, "unit_price <= "+placeholder(*filter.MaxPrice))
+	}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			conditions = append(conditions, "quantity > 0")
+		} else {
+			conditions = append(conditions, "quantity = 0")
+		}
+	}
+	if filter.Region != "" {
+		region, _ := models.NormalizeRegion(filter.Region)
+		conditions = append(conditions, soldInCondition(placeholder(region)))
+	}
+	if filter.Visibility != "" || filter.ExpiringWithin != "" {
+		now := time.Now()
+		switch filter.Visibility {
+		case models.VisibilityVisible:
+			conditions = append(conditions, visibleCondition(placeholder(now)))
+		case models.VisibilityHidden:
+			conditions = append(conditions, "NOT "+visibleCondition(placeholder(now)))
+		case models.VisibilityScheduled:
+			conditions = append(conditions, "visible_from > "+placeholder(now))
+		case models.VisibilityExpired:
+			conditions = append(conditions, "visible_until <= "+placeholder(now))
+		}
+		if filter.ExpiringWithin != "" {
+			within, _ := time.ParseDuration(filter.ExpiringWithin)
+			conditions = append(conditions, visibleCondition(placeholder(now)), "visible_until <= "+placeholder(now.Add(within)))
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// visibleCondition matches the products visible at the time bound at
+// placeholder, see models.Product.VisibleAt
+func visibleCondition(placeholder string) string {
+	return "((visible_from IS NULL OR visible_from <= " + placeholder + ") AND (visible_until IS NULL OR visible_until > " + placeholder + "))"
+}
+
+// productFilterOrder returns the ORDER BY of a validated filter; ties go by
+// ID in the same direction
+func productFilterOrder(filter models.ProductFilter) string {
+	column, order := filter.Sort, filter.Order
+	if column == "" {
+		column = "created_at"
+	}
+	if order == "" {
+		order = "asc"
+		if column == "created_at" || column == "updated_at" {
+			order = "desc"
+		}
+	}
+	return column + " " + order + ", id " + order
+}
+
+func (r *productRepo) ListUpdatedSince(ctx context.Context, since time.Time) ([]*models.Product, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, productsUpdatedSinceQuery, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list updated products: %w", err)
+	}
+
+	var products []*models.Product
+	if err := database.ScanAll(&products, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan products: %w", err)
+	}
+
+	if err := r.loadTags(ctx, products...); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *productRepo) AdjustStock(ctx context.Context, id int, delta int) (*models.Product, error) {
+	query := `
+		UPDATE products SET
+			quantity = quantity + $2,
+			updated_at = $3
+		WHERE id = $1 AND quantity + $2 >= 0
+		RETURNING ` + productColumns
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, id, delta, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to adjust stock: %w", err)
+	}
+
+	product := &models.Product{}
+	err = database.ScanOne(product, rows)
+	if err == sql.ErrNoRows {
+		// Either the product doesn't exist or the adjustment would go negative
+		if _, getErr := r.GetByID(ctx, id); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrInsufficientStock
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to adjust stock: %w", err)
+	}
+
+	if err := r.loadTags(ctx, product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// loadTags sets the Tags of products from product_tags, and their Regions
+// from product_regions
+func (r *productRepo) loadTags(ctx context.Context, products ...*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*models.Product, len(products))
+	ids := make([]int, len(products))
+	for i, p := range products {
+		byID[p.ID] = p
+		ids[i] = p.ID
+	}
+
+	query := `SELECT product_id, tag FROM product_tags WHERE ` + r.db.Dialect().AnyOf("product_id", 1) + ` ORDER BY product_id, tag`
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, r.db.Dialect().Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to get product tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return fmt.Errorf("failed to scan product tags: %w", err)
+		}
+		if p, ok := byID[id]; ok {
+			p.Tags = append(p.Tags, tag)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get product tags: %w", err)
+	}
+
+	query = `SELECT product_id, region FROM product_regions WHERE ` + r.db.Dialect().AnyOf("product_id", 1) + ` ORDER BY product_id, region`
+	regionRows, err := r.db.Conn(ctx).QueryContext(ctx, query, r.db.Dialect().Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to get product regions: %w", err)
+	}
+	defer regionRows.Close()
+
+	for regionRows.Next() {
+		var id int
+		var region string
+		if err := regionRows.Scan(&id, &region); err != nil {
+			return fmt.Errorf("failed to scan product regions: %w", err)
+		}
+		if p, ok := byID[id]; ok {
+			p.Regions = append(p.Regions, region)
+		}
+	}
+
+	return regionRows.Err()
+}
+
+// insertTags adds the Tags of products, which must have IDs, to product_tags
+func (r *productRepo) insertTags(ctx context.Context, products ...*models.Product) error {
+	var query strings.Builder
+	var args []interface{}
+	for _, p := range products {
+		for _, tag := range p.Tags {
+			if len(args) > 0 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "($%d, $%d)", len(args)+1, len(args)+2)
+			args = append(args, p.ID, tag)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	if _, err := r.db.Conn(ctx).ExecContext(ctx, `INSERT INTO product_tags (product_id, tag) VALUES `+query.String(), args...); err != nil {
+		return fmt.Errorf("failed to add product tags: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type PromotionRepository interface {
+	Create(ctx context.Context, promotion *models.Promotion) error
+
+	GetByID(ctx context.Context, id int) (*models.Promotion, error)
+
+	Update(ctx context.Context, promotion *models.Promotion) error
+
+	Delete(ctx context.Context, id int) error
+
+	// List returns promotions, newest first
+	List(ctx context.Context, limit, offset int) ([]*models.Promotion, error)
+
+	Count(ctx context.Context) (int, error)
+
+	// ListActive returns the promotions in effect at, by descending priority
+	ListActive(ctx context.Context, at time.Time) ([]*models.Promotion, error)
+}
+
+type promotionRepo struct {
+	db *database.DB
+}
+
+func NewPromotionRepository(db *database.DB) PromotionRepository {
+	return &promotionRepo{db: db}
+}
+
+var promotionColumns = database.ColumnList(models.Promotion{})
+
+func (r *promotionRepo) Create(ctx context.Context, promotion *models.Promotion) error {
+	query := `
+		INSERT INTO promotions (
+			name, kind, value, scope, target, starts_at, ends_at, stackable, priority, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		) RETURNING id
+	`
+
+	now := time.Now()
+	promotion.CreatedAt = now
+	promotion.UpdatedAt = now
+
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query,
+		promotion.Name,
+		promotion.Kind,
+		promotion.Value,
+		promotion.Scope,
+		promotion.Target,
+		promotion.StartsAt,
+		promotion.EndsAt,
+		promotion.Stackable,
+		promotion.Priority,
+		promotion.CreatedAt,
+		promotion.UpdatedAt,
+	).Scan(&promotion.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create promotion: %w", err)
+	}
+
+	return nil
+}
+
+func (r *promotionRepo) GetByID(ctx context.Context, id int) (*models.Promotion, error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE id = $1`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promotion: %w", err)
+	}
+
+	promotion := &models.Promotion{}
+	err = database.ScanOne(promotion, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("promotion not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promotion: %w", err)
+	}
+
+	return promotion, nil
+}
+
+func (r *promotionRepo) Update(ctx context.Context, promotion *models.Promotion) error {
+	query := `
+		UPDATE promotions SET
+			name = $2,
+			kind = $3,
+			value = $4,
+			scope = $5,
+			target = $6,
+			starts_at = $7,
+			ends_at = $8,
+			stackable = $9,
+			priority = $10,
+			updated_at = $11
+		WHERE id = $1
+	`
+
+	promotion.UpdatedAt = time.Now()
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query,
+		promotion.ID,
+		promotion.Name,
+		promotion.Kind,
+		promotion.Value,
+		promotion.Scope,
+		promotion.Target,
+		promotion.StartsAt,
+		promotion.EndsAt,
+		promotion.Stackable,
+		promotion.Priority,
+		promotion.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update promotion: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("promotion not found")
+	}
+
+	return nil
+}
+
+func (r *promotionRepo) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM promotions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete promotion: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("promotion not found")
+	}
+
+	return nil
+}
+
+func (r *promotionRepo) List(ctx context.Context, limit, offset int) ([]*models.Promotion, error) {
+	query := `
+		SELECT ` + promotionColumns + `
+		FROM promotions
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	return r.list(ctx, query, limit, offset)
+}
+
+func (r *promotionRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM promotions`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count promotions: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *promotionRepo) ListActive(ctx context.Context, at time.Time) ([]*models.Promotion, error) {
+	query := `
+		SELECT ` + promotionColumns + `
+		FROM promotions
+		WHERE (starts_at IS NULL OR starts_at <= $1) AND (ends_at IS NULL OR ends_at > $1)
+		ORDER BY priority DESC, id ASC
+	`
+
+	return r.list(ctx, query, at.UTC())
+}
+
+func (r *promotionRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.Promotion, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list promotions: %w", err)
+	}
+
+	var promotions []*models.Promotion
+	if err := database.ScanAll(&promotions, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan promotions: %w", err)
+	}
+
+	return promotions, nil
+}
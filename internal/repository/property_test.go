@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// Property tests check invariants of the product repository over generated
+// inputs, with testing/quick, on SQLite and, when one is running, Postgres.
+// A failing check prints the input that broke the invariant.
+
+// propertyRuns is the number of inputs each property is checked with
+const propertyRuns = 25
+
+// propertyBackends are the databases the properties run against
+var propertyBackends = []struct {
+	name  string
+	setup func(t *testing.T) *database.DB
+}{
+	{"sqlite", setupSQLiteDB},
+	{"postgres", setupMigratedDB}, // Skipped without a test database
+}
+
+// skus hands out SKUs unique within a test run
+var skus struct {
+	sync.Mutex
+	n int
+}
+
+func nextSKU(prefix string) string {
+	skus.Lock()
+	defer skus.Unlock()
+	skus.n++
+	return fmt.Sprintf("%s-%d", prefix, skus.n)
+}
+
+const propertyAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 -_.,'&/äöüéñß€漢字"
+
+// randomText returns up to max runes of propertyAlphabet, at least min
+func randomText(rnd *rand.Rand, min, max int) string {
+	alphabet := []rune(propertyAlphabet)
+	var b strings.Builder
+	for i, n := 0, min+rnd.Intn(max-min+1); i < n; i++ {
+		b.WriteRune(alphabet[rnd.Intn(len(alphabet))])
+	}
+	return b.String()
+}
+
+// newProduct is a product as it's created: the fields a client sets, within
+// the limits the API and schema enforce
+type newProduct struct {
+	models.Product
+}
+
+func (newProduct) Generate(rnd *rand.Rand, size int) reflect.Value {
+	p := models.Product{
+		SKU:         nextSKU("PROP"),
+		Name:        randomText(rnd, 1, 60),
+		Description: randomText(rnd, 0, 200),
+		Quantity:    rnd.Intn(10000),
+		UnitPrice:   float64(rnd.Intn(100000000)) / 100, // DECIMAL(10,2)
+	}
+	if rnd.Intn(2) == 0 {
+		p.Category = randomText(rnd, 1, 30)
+	}
+	if rnd.Intn(4) == 0 {
+		p.Unit = []string{"each", "kg", "g", "liter"}[rnd.Intn(4)]
+	}
+	tags := make(map[string]bool)
+	for i := rnd.Intn(5); i > 0; i-- {
+		tags[fmt.Sprintf("tag%d", rnd.Intn(20))] = true // Lowercase, as handlers store them
+	}
+	for tag := range tags {
+		p.Tags = append(p.Tags, tag)
+	}
+	return reflect.ValueOf(newProduct{p})
+}
+
+// pageRun is a batch of products created at once, so they share a creation
+// time, and a page size to list them by
+type pageRun struct {
+	Products int
+	Limit    int
+}
+
+func (pageRun) Generate(rnd *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(pageRun{Products: 1 + rnd.Intn(25), Limit: 1 + rnd.Intn(10)})
+}
+
+// stockRun is a starting quantity and adjustments to apply to it
+type stockRun struct {
+	Quantity int
+	Deltas   []int
+}
+
+func (stockRun) Generate(rnd *rand.Rand, size int) reflect.Value {
+	run := stockRun{Quantity: rnd.Intn(20)}
+	for i := rnd.Intn(30); i > 0; i-- {
+		run.Deltas = append(run.Deltas, rnd.Intn(21)-12) // Decrements a little more likely
+	}
+	return reflect.ValueOf(run)
+}
+
+func TestProductRepository_Properties(t *testing.T) {
+	for _, backend := range propertyBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			db := backend.setup(t)
+			repo := NewProductRepository(db)
+			ctx := context.Background()
+			cfg := &quick.Config{MaxCount: propertyRuns}
+
+			t.Run("create then get returns the product", func(t *testing.T) {
+				property := func(in newProduct) bool {
+					created := in.Product
+					created.Tags = append([]string(nil), in.Tags...)
+					if err := repo.Create(ctx, &created); err != nil {
+						t.Logf("Create(%+v): %v", in.Product, err)
+						return false
+					}
+					got, err := repo.GetByID(ctx, created.ID)
+					if err != nil {
+						t.Logf("GetByID(%d): %v", created.ID, err)
+						return false
+					}
+					want := created
+					sort.Strings(want.Tags)
+					if diff := productDiff(got, &want); diff != "" {
+						t.Logf("GetByID after Create differs: %s", diff)
+						return false
+					}
+					return true
+				}
+				if err := quick.Check(property, cfg); err != nil {
+					t.Error(err)
+				}
+			})
+
+			t.Run("pages partition the products", func(t *testing.T) {
+				property := func(run pageRun) bool {
+					batch := make([]*models.Product, run.Products)
+					for i := range batch {
+						batch[i] = &models.Product{SKU: nextSKU("PAGE"), Name: "Paged"}
+					}
+					if _, err := repo.BulkCreate(ctx, batch); err != nil {
+						t.Logf("BulkCreate: %v", err)
+						return false
+					}
+
+					count, err := repo.Count(ctx)
+					if err != nil {
+						t.Logf("Count: %v", err)
+						return false
+					}
+					seen := make(map[int]bool, count)
+					for offset := 0; ; offset += run.Limit {
+						page, total, err := repo.ListWithCount(ctx, run.Limit, offset)
+						if err != nil {
+							t.Logf("ListWithCount(%d, %d): %v", run.Limit, offset, err)
+							return false
+						}
+						if len(page) > 0 && total != count {
+							t.Logf("ListWithCount(%d, %d) total = %d, want %d", run.Limit, offset, total, count)
+							return false
+						}
+						if want := min(run.Limit, max(count-offset, 0)); len(page) != want {
+							t.Logf("page at offset %d has %d products, want %d", offset, len(page), want)
+							return false
+						}
+						for _, p := range page {
+							if seen[p.ID] {
+								t.Logf("product %d is on two pages of %d", p.ID, run.Limit)
+								return false
+							}
+							seen[p.ID] = true
+						}
+						if len(page) == 0 {
+							break
+						}
+					}
+					if len(seen) != count {
+						t.Logf("pages of %d list %d of %d products", run.Limit, len(seen), count)
+						return false
+					}
+					return true
+				}
+				if err := quick.Check(property, cfg); err != nil {
+					t.Error(err)
+				}
+			})
+
+			t.Run("stock never goes negative", func(t *testing.T) {
+				property := func(run stockRun) bool {
+					product := &models.Product{SKU: nextSKU("STOCK"), Name: "Stocked", Quantity: run.Quantity}
+					if err := repo.Create(ctx, product); err != nil {
+						t.Logf("Create: %v", err)
+						return false
+					}
+
+					// In order, against a model of the quantity
+					want := run.Quantity
+					for _, delta := range run.Deltas {
+						adjusted, err := repo.AdjustStock(ctx, product.ID, delta)
+						switch {
+						case want+delta < 0:
+							if !errors.Is(err, ErrInsufficientStock) {
+								t.Logf("AdjustStock(%d) of %d = %v, want ErrInsufficientStock", delta, want, err)
+								return false
+							}
+						case err != nil:
+							t.Logf("AdjustStock(%d) of %d: %v", delta, want, err)
+							return false
+						default:
+							want += delta
+							if adjusted.Quantity != want {
+								t.Logf("AdjustStock(%d) = quantity %d, want %d", delta, adjusted.Quantity, want)
+								return false
+							}
+						}
+					}
+
+					// Concurrently: only the accepted adjustments apply
+					var mu sync.Mutex
+					var wg sync.WaitGroup
+					accepted := 0
+					failed := false
+					for _, delta := range run.Deltas {
+						wg.Add(1)
+						go func(delta int) {
+							defer wg.Done()
+							_, err := repo.AdjustStock(ctx, product.ID, delta)
+							mu.Lock()
+							defer mu.Unlock()
+							switch {
+							case err == nil:
+								accepted += delta
+							case !errors.Is(err, ErrInsufficientStock):
+								t.Logf("concurrent AdjustStock(%d): %v", delta, err)
+								failed = true
+							}
+						}(delta)
+					}
+					wg.Wait()
+					if failed {
+						return false
+					}
+
+					got, err := repo.GetByID(ctx, product.ID)
+					if err != nil {
+						t.Logf("GetByID: %v", err)
+						return false
+					}
+					if got.Quantity < 0 || got.Quantity != want+accepted {
+						t.Logf("quantity = %d, want %d and never negative", got.Quantity, want+accepted)
+						return false
+					}
+					return true
+				}
+				if err := quick.Check(property, cfg); err != nil {
+					t.Error(err)
+				}
+			})
+		})
+	}
+}
+
+// productDiff describes how got differs from want, empty when they're equal.
+// Timestamps may lose precision in storage, so they compare to the
+// microsecond; an empty and a nil list are equal.
+func productDiff(got, want *models.Product) string {
+	g, w := *got, *want
+	for _, p := range []*models.Product{&g, &w} {
+		p.CreatedAt, p.UpdatedAt = p.CreatedAt.Truncate(time.Microsecond), p.UpdatedAt.Truncate(time.Microsecond)
+		if len(p.Tags) == 0 {
+			p.Tags = nil
+		}
+		if len(p.Regions) == 0 {
+			p.Regions = nil
+		}
+	}
+	if !g.CreatedAt.Equal(w.CreatedAt) || !g.UpdatedAt.Equal(w.UpdatedAt) {
+		return fmt.Sprintf("created_at, updated_at = %v, %v; want %v, %v", g.CreatedAt, g.UpdatedAt, w.CreatedAt, w.UpdatedAt)
+	}
+	g.CreatedAt, g.UpdatedAt = w.CreatedAt, w.UpdatedAt
+	if !reflect.DeepEqual(g, w) {
+		return fmt.Sprintf("got %+v, want %+v", g, w)
+	}
+	return ""
+}
@@ -0,0 +1,363 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type PurchaseOrderRepository interface {
+	GetSupplier(ctx context.Context, productID int) (*models.ProductSupplier, error)
+
+	// SetSupplier records who supplies a product, replacing its supplier
+	SetSupplier(ctx context.Context, supplier *models.ProductSupplier) error
+
+	DeleteSupplier(ctx context.Context, productID int) error
+
+	// ListCandidates returns the products at or below the reorder point of
+	// their forecast with a supplier and no open purchase order, by supplier
+	ListCandidates(ctx context.Context) ([]*models.ReorderCandidate, error)
+
+	// CountUnassigned counts the products at or below their reorder point
+	// with no supplier and no open purchase order
+	CountUnassigned(ctx context.Context) (int, error)
+
+	// Create records a purchase order, without its lines
+	Create(ctx context.Context, order *models.PurchaseOrder) error
+
+	// GetByID returns a purchase order with its lines
+	GetByID(ctx context.Context, id int) (*models.PurchaseOrder, error)
+
+	// GetByIDForUpdate is GetByID locking the order's row for the rest of
+	// the transaction
+	GetByIDForUpdate(ctx context.Context, id int) (*models.PurchaseOrder, error)
+
+	// GetDraft returns a supplier's most recent draft, with its lines and
+	// locked for the rest of the transaction, nil when it has none
+	GetDraft(ctx context.Context, supplier string) (*models.PurchaseOrder, error)
+
+	// List returns the purchase orders matching filter, most recently created
+	// first, without their lines
+	List(ctx context.Context, filter models.PurchaseOrderFilter, limit, offset int) ([]*models.PurchaseOrder, error)
+
+	Count(ctx context.Context, filter models.PurchaseOrderFilter) (int, error)
+
+	AddLine(ctx context.Context, line *models.PurchaseOrderLine) error
+
+	// SetLineQuantity changes a line's quantity, removing the line when it's 0
+	SetLineQuantity(ctx context.Context, orderID, productID, quantity int) error
+
+	// Update stores an order's progress: its status, approver, and when it
+	// was approved, submitted, and closed
+	Update(ctx context.Context, order *models.PurchaseOrder) error
+}
+
+type purchaseOrderRepo struct {
+	db *database.DB
+}
+
+func NewPurchaseOrderRepository(db *database.DB) PurchaseOrderRepository {
+	return &purchaseOrderRepo{db: db}
+}
+
+var (
+	productSupplierColumns   = database.ColumnList(models.ProductSupplier{})
+	purchaseOrderColumns     = database.ColumnList(models.PurchaseOrder{})
+	purchaseOrderLineColumns = database.ColumnList(models.PurchaseOrderLine{})
+)
+
+// openPurchaseOrder matches the products on a purchase order not received
+// or cancelled yet
+const openPurchaseOrder = `
+	EXISTS (
+		SELECT 1 FROM purchase_order_lines l
+		JOIN purchase_orders o ON o.id = l.purchase_order_id
+		WHERE l.product_id = p.id AND o.status IN ('draft', 'approved', 'submitted')
+	)`
+
+func (r *purchaseOrderRepo) GetSupplier(ctx context.Context, productID int) (*models.ProductSupplier, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, `SELECT `+productSupplierColumns+` FROM product_suppliers WHERE product_id = $1`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier: %w", err)
+	}
+
+	supplier := &models.ProductSupplier{}
+	err = database.ScanOne(supplier, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("supplier not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier: %w", err)
+	}
+
+	return supplier, nil
+}
+
+func (r *purchaseOrderRepo) SetSupplier(ctx context.Context, supplier *models.ProductSupplier) error {
+	query := `
+		INSERT INTO product_suppliers (product_id, supplier, economic_order_quantity, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (product_id) DO UPDATE
+		SET supplier = excluded.supplier, economic_order_quantity = excluded.economic_order_quantity, updated_at = excluded.updated_at
+	`
+
+	supplier.UpdatedAt = time.Now()
+	_, err := r.db.Conn(ctx).ExecContext(ctx, query, supplier.ProductID, supplier.Supplier, supplier.EconomicOrderQuantity, supplier.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set supplier: %w", err)
+	}
+
+	return nil
+}
+
+func (r *purchaseOrderRepo) DeleteSupplier(ctx context.Context, productID int) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM product_suppliers WHERE product_id = $1`, productID)
+	if err != nil {
+		return fmt.Errorf("failed to delete supplier: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("supplier not found")
+	}
+
+	return nil
+}
+
+func (r *purchaseOrderRepo) ListCandidates(ctx context.Context) ([]*models.ReorderCandidate, error) {
+	query := `
+		SELECT p.id AS product_id, p.sku, s.supplier, f.order_up_to - p.quantity AS suggested_quantity,
+			f.average_daily_demand, s.economic_order_quantity,
+			COALESCE((
+				SELECT r.unit_cost FROM stock_receipts r
+				WHERE r.product_id = p.id
+				ORDER BY r.received_at DESC, r.id DESC
+				LIMIT 1
+			), p.unit_price) AS unit_cost
+		FROM product_forecasts f
+		JOIN products p ON p.id = f.product_id
+		JOIN product_suppliers s ON s.product_id = p.id
+		WHERE ` + reorderCondition + ` AND NOT ` + openPurchaseOrder + `
+		ORDER BY s.supplier, p.id
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reorder candidates: %w", err)
+	}
+
+	candidates := []*models.ReorderCandidate{}
+	if err := database.ScanAll(&candidates, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan reorder candidates: %w", err)
+	}
+
+	return candidates, nil
+}
+
+func (r *purchaseOrderRepo) CountUnassigned(ctx context.Context) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM product_forecasts f
+		JOIN products p ON p.id = f.product_id
+		WHERE ` + reorderCondition + ` AND NOT ` + openPurchaseOrder + `
+			AND NOT EXISTS (SELECT 1 FROM product_suppliers s WHERE s.product_id = p.id)
+	`
+
+	var count int
+	if err := r.db.Conn(ctx).QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unassigned reorders: %w", err)
+	}
+	return count, nil
+}
+
+func (r *purchaseOrderRepo) Create(ctx context.Context, order *models.PurchaseOrder) error {
+	query := `
+		INSERT INTO purchase_orders (supplier, status, created_by, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	order.CreatedAt = time.Now()
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, order.Supplier, order.Status, order.CreatedBy, order.CreatedAt).Scan(&order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create purchase order: %w", err)
+	}
+
+	return nil
+}
+
+func (r *purchaseOrderRepo) GetByID(ctx context.Context, id int) (*models.PurchaseOrder, error) {
+	return r.get(ctx, `SELECT `+purchaseOrderColumns+` FROM purchase_orders WHERE id = $1`, id)
+}
+
+func (r *purchaseOrderRepo) GetByIDForUpdate(ctx context.Context, id int) (*models.PurchaseOrder, error) {
+	return r.get(ctx, `SELECT `+purchaseOrderColumns+` FROM purchase_orders WHERE id = $1 `+r.db.Dialect().ForUpdate(), id)
+}
+
+func (r *purchaseOrderRepo) GetDraft(ctx context.Context, supplier string) (*models.PurchaseOrder, error) {
+	query := `
+		SELECT ` + purchaseOrderColumns + `
+		FROM purchase_orders
+		WHERE supplier = $1 AND status = 'draft'
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1 ` + r.db.Dialect().ForUpdate()
+
+	order, err := r.get(ctx, query, supplier)
+	if err != nil && err.Error() == "purchase order not found" {
+		return nil, nil
+	}
+	return order, err
+}
+
+// get returns the purchase order a query selects, with its lines
+func (r *purchaseOrderRepo) get(ctx context.Context, query string, arg interface{}) (*models.PurchaseOrder, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase order: %w", err)
+	}
+
+	order := &models.PurchaseOrder{}
+	err = database.ScanOne(order, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("purchase order not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase order: %w", err)
+	}
+
+	rows, err = r.db.Conn(ctx).QueryContext(ctx, `
+		SELECT `+purchaseOrderLineColumns+`
+		FROM purchase_order_lines
+		WHERE purchase_order_id = $1
+		ORDER BY sku, product_id
+	`, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchase order lines: %w", err)
+	}
+	order.Lines = []*models.PurchaseOrderLine{}
+	if err := database.ScanAll(&order.Lines, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan purchase order lines: %w", err)
+	}
+
+	return order, nil
+}
+
+// purchaseOrderFilter builds the WHERE clause of a filter
+func purchaseOrderFilter(filter models.PurchaseOrderFilter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conds = append(conds, "status = $"+strconv.Itoa(len(args)))
+	}
+	if filter.Supplier != "" {
+		args = append(args, filter.Supplier)
+		conds = append(conds, "supplier = $"+strconv.Itoa(len(args)))
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (r *purchaseOrderRepo) List(ctx context.Context, filter models.PurchaseOrderFilter, limit, offset int) ([]*models.PurchaseOrder, error) {
+	where, args := purchaseOrderFilter(filter)
+	query := `
+		SELECT ` + purchaseOrderColumns + `
+		FROM purchase_orders` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchase orders: %w", err)
+	}
+
+	orders := []*models.PurchaseOrder{}
+	if err := database.ScanAll(&orders, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan purchase orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+func (r *purchaseOrderRepo) Count(ctx context.Context, filter models.PurchaseOrderFilter) (int, error) {
+	where, args := purchaseOrderFilter(filter)
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM purchase_orders`+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count purchase orders: %w", err)
+	}
+	return count, nil
+}
+
+func (r *purchaseOrderRepo) AddLine(ctx context.Context, line *models.PurchaseOrderLine) error {
+	query := `
+		INSERT INTO purchase_order_lines (purchase_order_id, product_id, sku, quantity, suggested_quantity, economic_order_quantity)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.Conn(ctx).ExecContext(ctx, query,
+		line.PurchaseOrderID, line.ProductID, line.SKU, line.Quantity, line.SuggestedQuantity, line.EconomicOrderQuantity,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add purchase order line: %w", err)
+	}
+
+	return nil
+}
+
+func (r *purchaseOrderRepo) SetLineQuantity(ctx context.Context, orderID, productID, quantity int) error {
+	var result sql.Result
+	var err error
+	if quantity == 0 {
+		result, err = r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM purchase_order_lines WHERE purchase_order_id = $1 AND product_id = $2`, orderID, productID)
+	} else {
+		result, err = r.db.Conn(ctx).ExecContext(ctx, `UPDATE purchase_order_lines SET quantity = $1 WHERE purchase_order_id = $2 AND product_id = $3`, quantity, orderID, productID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update purchase order line: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("purchase order line not found")
+	}
+
+	return nil
+}
+
+func (r *purchaseOrderRepo) Update(ctx context.Context, order *models.PurchaseOrder) error {
+	query := `
+		UPDATE purchase_orders
+		SET status = $1, approved_by = $2, approved_at = $3, submitted_at = $4, closed_at = $5
+		WHERE id = $6
+	`
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, order.Status, order.ApprovedBy, order.ApprovedAt, order.SubmittedAt, order.ClosedAt, order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update purchase order: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("purchase order not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type QueueRepository interface {
+	// Add stores tasks to run from their RunAfter
+	Add(ctx context.Context, tasks ...*models.QueuedTask) error
+
+	// FetchDue locks up to limit tasks whose RunAfter has passed, oldest
+	// first. Must be called inside a transaction; rows locked by other
+	// instances are skipped.
+	FetchDue(ctx context.Context, now time.Time, limit int) ([]*models.QueuedTask, error)
+
+	Delete(ctx context.Context, ids []int64) error
+
+	Count(ctx context.Context) (int, error)
+}
+
+type queueRepo struct {
+	db *database.DB
+}
+
+func NewQueueRepository(db *database.DB) QueueRepository {
+	return &queueRepo{db: db}
+}
+
+var queueColumns = database.ColumnList(models.QueuedTask{})
+
+func (r *queueRepo) Add(ctx context.Context, tasks ...*models.QueuedTask) error {
+	query := `
+		INSERT INTO queue_tasks (
+			kind, destination, payload, attempts, last_error, run_after, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		) RETURNING id
+	`
+
+	for _, task := range tasks {
+		task.CreatedAt = time.Now()
+
+		err := r.db.Conn(ctx).QueryRowContext(ctx, query,
+			task.Kind,
+			task.Destination,
+			[]byte(task.Payload),
+			task.Attempts,
+			task.LastError,
+			task.RunAfter,
+			task.CreatedAt,
+		).Scan(&task.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed to add %s task for %s: %w", task.Kind, task.Destination, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *queueRepo) FetchDue(ctx context.Context, now time.Time, limit int) ([]*models.QueuedTask, error) {
+	query := `
+		SELECT ` + queueColumns + `
+		FROM queue_tasks
+		WHERE run_after <= $1
+		ORDER BY run_after, id
+		LIMIT $2
+		` + r.db.Dialect().SkipLocked()
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch due queue tasks: %w", err)
+	}
+
+	var tasks []*models.QueuedTask
+	if err := database.ScanAll(&tasks, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan queue tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
+func (r *queueRepo) Delete(ctx context.Context, ids []int64) error {
+	query := `DELETE FROM queue_tasks WHERE ` + r.db.Dialect().AnyOf("id", 1)
+
+	if _, err := r.db.Conn(ctx).ExecContext(ctx, query, r.db.Dialect().Array(ids)); err != nil {
+		return fmt.Errorf("failed to delete queue tasks: %w", err)
+	}
+
+	return nil
+}
+
+func (r *queueRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM queue_tasks`
+
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count queue tasks: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// ProductRegionRepository assigns products to the sales regions they're
+// limited to; see models.Product.Regions
+type ProductRegionRepository interface {
+	// Add limits the products to regions too; assignments they already have
+	// are kept
+	Add(ctx context.Context, productIDs []int, regions []string) error
+
+	// Remove takes the products out of regions
+	Remove(ctx context.Context, productIDs []int, regions []string) error
+
+	// Set limits the products to exactly regions, or none
+	Set(ctx context.Context, productIDs []int, regions []string) error
+
+	// Counts returns how many products each region has, by region
+	Counts(ctx context.Context) ([]*models.RegionCount, error)
+
+	// Of returns the regions of those of the products limited to any, by
+	// product ID
+	Of(ctx context.Context, productIDs []int) (map[int][]string, error)
+}
+
+type productRegionRepo struct {
+	db *database.DB
+}
+
+func NewProductRegionRepository(db *database.DB) ProductRegionRepository {
+	return &productRegionRepo{db: db}
+}
+
+// soldInCondition matches the products sold in the region bound at
+// placeholder: those limited to it, and those limited to none
+func soldInCondition(placeholder string) string {
+	return "(NOT EXISTS (SELECT 1 FROM product_regions pr WHERE pr.product_id = products.id)" +
+		" OR EXISTS (SELECT 1 FROM product_regions pr WHERE pr.product_id = products.id AND pr.region = " + placeholder + "))"
+}
+
+func (r *productRegionRepo) Add(ctx context.Context, productIDs []int, regions []string) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	// One statement per region, each binding every product
+	query := `
+		INSERT INTO product_regions (product_id, region)
+		SELECT id, $2 FROM products WHERE ` + r.db.Dialect().AnyOf("id", 1) + `
+		ON CONFLICT (product_id, region) DO NOTHING
+	`
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		for _, region := range regions {
+			if _, err := r.db.Conn(ctx).ExecContext(ctx, query, r.db.Dialect().Array(productIDs), region); err != nil {
+				return fmt.Errorf("failed to add product regions: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *productRegionRepo) Remove(ctx context.Context, productIDs []int, regions []string) error {
+	if len(productIDs) == 0 || len(regions) == 0 {
+		return nil
+	}
+
+	dialect := r.db.Dialect()
+	query := `DELETE FROM product_regions WHERE ` + dialect.AnyOf("product_id", 1) + ` AND ` + dialect.AnyOf("region", 2)
+	if _, err := r.db.Conn(ctx).ExecContext(ctx, query, dialect.Array(productIDs), dialect.Array(regions)); err != nil {
+		return fmt.Errorf("failed to remove product regions: %w", err)
+	}
+	return nil
+}
+
+func (r *productRegionRepo) Set(ctx context.Context, productIDs []int, regions []string) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	dialect := r.db.Dialect()
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `DELETE FROM product_regions WHERE ` + dialect.AnyOf("product_id", 1)
+		if _, err := r.db.Conn(ctx).ExecContext(ctx, query, dialect.Array(productIDs)); err != nil {
+			return fmt.Errorf("failed to clear product regions: %w", err)
+		}
+		return r.Add(ctx, productIDs, regions)
+	})
+}
+
+func (r *productRegionRepo) Counts(ctx context.Context) ([]*models.RegionCount, error) {
+	query := `
+		SELECT region, COUNT(*) AS products
+		FROM product_regions
+		GROUP BY region
+		ORDER BY region
+	`
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count product regions: %w", err)
+	}
+
+	counts := []*models.RegionCount{}
+	if err := database.ScanAll(&counts, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan product regions: %w", err)
+	}
+	return counts, nil
+}
+
+func (r *productRegionRepo) Of(ctx context.Context, productIDs []int) (map[int][]string, error) {
+	regions := make(map[int][]string)
+	if len(productIDs) == 0 {
+		return regions, nil
+	}
+
+	dialect := r.db.Dialect()
+	query := `SELECT product_id, region FROM product_regions WHERE ` + dialect.AnyOf("product_id", 1) + ` ORDER BY product_id, region`
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, dialect.Array(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product regions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var region string
+		if err := rows.Scan(&id, &region); err != nil {
+			return nil, fmt.Errorf("failed to scan product regions: %w", err)
+		}
+		regions[id] = append(regions[id], region)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get product regions: %w", err)
+	}
+	return regions, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// relatedQuery ranks the products sharing a tag or the category with $1.
+// Candidates come from the tag and category indexes rather than a scan of
+// products; the price band, $3 to $4 times the product's price, only adds to
+// the score. Only products visible at $5, and sold in $6 unless it's empty,
+// are related.
+var relatedQuery = `
+	WITH source AS (
+		SELECT id, category, unit_price FROM products WHERE id = $1
+	),
+	candidates AS (
+		SELECT t.product_id AS id, COUNT(*) AS shared_tags
+		FROM product_tags s
+		JOIN product_tags t ON t.tag = s.tag AND t.product_id <> s.product_id
+		WHERE s.product_id = $1
+		GROUP BY t.product_id
+		UNION ALL
+		SELECT p.id, 0
+		FROM products p
+		JOIN source ON p.category = source.category AND p.id <> source.id
+		WHERE source.category <> ''
+	),
+	scored AS (
+		SELECT id, CAST(SUM(shared_tags) AS INTEGER) AS shared_tags
+		FROM candidates
+		GROUP BY id
+	)
+	SELECT ` + productColumns + `, shared_tags, same_category, same_price_band,
+		shared_tags + same_category + same_price_band AS score
+	FROM (
+		SELECT products.*, scored.shared_tags,
+			CASE WHEN source.category <> '' AND products.category = source.category THEN 1 ELSE 0 END AS same_category,
+			CASE WHEN products.unit_price BETWEEN source.unit_price * $3 AND source.unit_price * $4 THEN 1 ELSE 0 END AS same_price_band
+		FROM scored
+		JOIN products ON products.id = scored.id
+		CROSS JOIN source
+		WHERE ` + visibleCondition("$5") + ` AND ($6 = '' OR ` + soldInCondition("$6") + `)
+	) related
+	ORDER BY score DESC, id ASC
+	LIMIT $2
+`
+
+func (r *productRepo) Related(ctx context.Context, id int, limit int, priceBand float64, region string, visible time.Time) ([]*models.RelatedProduct, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, relatedQuery, id, limit, 1-priceBand, 1+priceBand, visible, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list related products: %w", err)
+	}
+
+	var related []*models.RelatedProduct
+	if err := database.ScanAll(&related, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan related products: %w", err)
+	}
+
+	return related, nil
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// ErrQueryTimeout is returned by Run when a report outlives its timeout
+var ErrQueryTimeout = errors.New("report query timed out")
+
+// QueryError reports that the database rejected or failed a report's query,
+// as opposed to being unavailable
+type QueryError struct {
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return "report query failed: " + e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error { return e.Err }
+
+type ReportRepository interface {
+	Create(ctx context.Context, report *models.Report) error
+
+	// GetByName returns a report, locked in a transaction
+	GetByName(ctx context.Context, name string) (*models.Report, error)
+
+	Update(ctx context.Context, report *models.Report) error
+
+	Delete(ctx context.Context, name string) error
+
+	// List returns reports by name
+	List(ctx context.Context, limit, offset int) ([]*models.Report, error)
+
+	Count(ctx context.Context) (int, error)
+
+	// Check plans query with args without returning rows, so a query the
+	// database rejects isn't stored
+	Check(ctx context.Context, query string, args []interface{}) error
+
+	// Run runs query with args in a read-only transaction, within timeout,
+	// and returns up to maxRows rows. Extra rows set Truncated.
+	Run(ctx context.Context, query string, args []interface{}, maxRows int, timeout time.Duration) (*models.ReportResult, error)
+}
+
+type reportRepo struct {
+	db *database.DB
+}
+
+func NewReportRepository(db *database.DB) ReportRepository {
+	return &reportRepo{db: db}
+}
+
+// The params are scanned as JSON, so the columns are listed explicitly
+const reportColumns = `name, description, query, params, created_by, created_at, updated_at`
+
+func (r *reportRepo) Create(ctx context.Context, report *models.Report) error {
+	query := `
+		INSERT INTO report_definitions (name, description, query, params, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	params, err := json.Marshal(report.Params)
+	if err != nil {
+		return fmt.Errorf("failed to encode report params: %w", err)
+	}
+	now := time.Now()
+	report.CreatedAt = now
+	report.UpdatedAt = now
+
+	_, err = r.db.Conn(ctx).ExecContext(ctx, query, report.Name, report.Description, report.Query, params, report.CreatedBy, report.CreatedAt, report.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+
+	return nil
+}
+
+func (r *reportRepo) GetByName(ctx context.Context, name string) (*models.Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM report_definitions WHERE name = $1` + r.db.Dialect().ForUpdate()
+
+	report, err := scanReport(r.db.Conn(ctx).QueryRowContext(ctx, query, name))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("report not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+
+	return report, nil
+}
+
+func (r *reportRepo) Update(ctx context.Context, report *models.Report) error {
+	query := `UPDATE report_definitions SET description = $2, query = $3, params = $4, updated_at = $5 WHERE name = $1`
+
+	params, err := json.Marshal(report.Params)
+	if err != nil {
+		return fmt.Errorf("failed to encode report params: %w", err)
+	}
+	report.UpdatedAt = time.Now()
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, report.Name, report.Description, report.Query, params, report.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update report: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("report not found")
+	}
+
+	return nil
+}
+
+func (r *reportRepo) Delete(ctx context.Context, name string) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM report_definitions WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete report: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("report not found")
+	}
+
+	return nil
+}
+
+func (r *reportRepo) List(ctx context.Context, limit, offset int) ([]*models.Report, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM report_definitions
+		ORDER BY name
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []*models.Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+func (r *reportRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM report_definitions`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *reportRepo) Check(ctx context.Context, query string, args []interface{}) error {
+	err := r.db.WithTxOptions(ctx, r.db.Dialect().SnapshotTxOptions(), func(ctx context.Context) error {
+		rows, err := r.db.Conn(ctx).QueryContext(ctx, `SELECT * FROM (`+query+`) report LIMIT 0`, args...)
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	})
+	if err != nil {
+		return &QueryError{Err: err}
+	}
+
+	return nil
+}
+
+func (r *reportRepo) Run(ctx context.Context, query string, args []interface{}, maxRows int, timeout time.Duration) (*models.ReportResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := &models.ReportResult{Rows: [][]interface{}{}}
+	// Read-only on PostgreSQL, so nothing the query could do is kept; SQLite
+	// only runs what validation allowed
+	err := r.db.WithTxOptions(ctx, r.db.Dialect().SnapshotTxOptions(), func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		if r.db.Dialect() == database.Postgres {
+			if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+				return err
+			}
+		}
+
+		rows, err := conn.QueryContext(ctx, `SELECT * FROM (`+query+`) report LIMIT `+strconv.Itoa(maxRows+1), args...)
+		if err != nil {
+			return &QueryError{Err: err}
+		}
+		defer rows.Close()
+
+		if result.Columns, err = rows.Columns(); err != nil {
+			return err
+		}
+		for rows.Next() {
+			if len(result.Rows) == maxRows {
+				result.Truncated = true
+				break
+			}
+			row := make([]interface{}, len(result.Columns))
+			dest := make([]interface{}, len(row))
+			for i := range row {
+				dest[i] = &row[i]
+			}
+			if err := rows.Scan(dest...); err != nil {
+				return &QueryError{Err: err}
+			}
+			for i, v := range row {
+				switch v := v.(type) {
+				case []byte:
+					row[i] = string(v) // Numerics and text
+				case time.Time:
+					row[i] = v.UTC()
+				}
+			}
+			result.Rows = append(result.Rows, row)
+		}
+		if err := rows.Err(); err != nil {
+			return &QueryError{Err: err}
+		}
+		return nil
+	})
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || (err != nil && strings.Contains(err.Error(), "statement timeout")) {
+		return nil, ErrQueryTimeout
+	}
+	var queryErr *QueryError
+	if errors.As(err, &queryErr) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to run report: %w", err)
+	}
+
+	result.RowCount = len(result.Rows)
+	return result, nil
+}
+
+// scanReport scans a row of reportColumns, decoding the params
+func scanReport(row interface{ Scan(...interface{}) error }) (*models.Report, error) {
+	report := &models.Report{}
+	var params []byte
+	if err := row.Scan(&report.Name, &report.Description, &report.Query, &params, &report.CreatedBy, &report.CreatedAt, &report.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(params, &report.Params); err != nil {
+		return nil, fmt.Errorf("failed to decode params of report %s: %w", report.Name, err)
+	}
+	return report, nil
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type ReturnRepository interface {
+	// Create records a return as authorized now
+	Create(ctx context.Context, ret *models.Return) error
+
+	GetByID(ctx context.Context, id int) (*models.Return, error)
+
+	// GetByIDForUpdate is GetByID locking the return's row for the rest of
+	// the transaction
+	GetByIDForUpdate(ctx context.Context, id int) (*models.Return, error)
+
+	// List returns the returns matching filter, most recently created first
+	List(ctx context.Context, filter models.ReturnFilter, limit, offset int) ([]*models.Return, error)
+
+	Count(ctx context.Context, filter models.ReturnFilter) (int, error)
+
+	// Update stores a return's progress: its status, disposition, notes,
+	// stock movement, and when it was received and closed
+	Update(ctx context.Context, ret *models.Return) error
+}
+
+type returnRepo struct {
+	db *database.DB
+}
+
+func NewReturnRepository(db *database.DB) ReturnRepository {
+	return &returnRepo{db: db}
+}
+
+var returnColumns = database.ColumnList(models.Return{})
+
+var returnByIDQuery = `SELECT ` + returnColumns + ` FROM product_returns WHERE id = $1`
+
+func (r *returnRepo) Create(ctx context.Context, ret *models.Return) error {
+	query := `
+		INSERT INTO product_returns (product_id, sku, quantity, order_source, order_id, reason, status, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	ret.CreatedAt = time.Now()
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query,
+		ret.ProductID, ret.SKU, ret.Quantity, ret.OrderSource, ret.OrderID, ret.Reason, ret.Status, ret.CreatedBy, ret.CreatedAt,
+	).Scan(&ret.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create return: %w", err)
+	}
+
+	return nil
+}
+
+func (r *returnRepo) GetByID(ctx context.Context, id int) (*models.Return, error) {
+	return r.get(ctx, returnByIDQuery, id)
+}
+
+func (r *returnRepo) GetByIDForUpdate(ctx context.Context, id int) (*models.Return, error) {
+	return r.get(ctx, returnByIDQuery+" "+r.db.Dialect().ForUpdate(), id)
+}
+
+func (r *returnRepo) get(ctx context.Context, query string, id int) (*models.Return, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return: %w", err)
+	}
+
+	ret := &models.Return{}
+	err = database.ScanOne(ret, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("return not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return: %w", err)
+	}
+
+	return ret, nil
+}
+
+// returnFilter builds the WHERE clause of a filter
+func returnFilter(filter models.ReturnFilter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, cond+" = $"+strconv.Itoa(len(args)))
+	}
+	if filter.Status != "" {
+		add("status", filter.Status)
+	}
+	if filter.ProductID != 0 {
+		add("product_id", filter.ProductID)
+	}
+	if filter.OrderSource != "" {
+		add("order_source", filter.OrderSource)
+	}
+	if filter.OrderID != "" {
+		add("order_id", filter.OrderID)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (r *returnRepo) List(ctx context.Context, filter models.ReturnFilter, limit, offset int) ([]*models.Return, error) {
+	where, args := returnFilter(filter)
+	query := `
+		SELECT ` + returnColumns + `
+		FROM product_returns` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list returns: %w", err)
+	}
+
+	returns := []*models.Return{}
+	if err := database.ScanAll(&returns, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan returns: %w", err)
+	}
+
+	return returns, nil
+}
+
+func (r *returnRepo) Count(ctx context.Context, filter models.ReturnFilter) (int, error) {
+	where, args := returnFilter(filter)
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM product_returns`+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count returns: %w", err)
+	}
+	return count, nil
+}
+
+func (r *returnRepo) Update(ctx context.Context, ret *models.Return) error {
+	query := `
+		UPDATE product_returns
+		SET status = $1, disposition = $2, notes = $3, stock_movement_id = $4, received_at = $5, closed_at = $6
+		WHERE id = $7
+	`
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, ret.Status, ret.Disposition, ret.Notes, ret.StockMovementID, ret.ReceivedAt, ret.ClosedAt, ret.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update return: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("return not found")
+	}
+
+	return nil
+}
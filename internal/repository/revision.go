@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// ProductRevisionRepository keeps editors' updates of products until they
+// are approved or rejected
+type ProductRevisionRepository interface {
+	// Create stores a revision as pending now
+	Create(ctx context.Context, rev *models.ProductRevision) error
+
+	GetByID(ctx context.Context, id int) (*models.ProductRevision, error)
+
+	// GetByIDForUpdate is GetByID locking the revision's row for the rest of
+	// the transaction
+	GetByIDForUpdate(ctx context.Context, id int) (*models.ProductRevision, error)
+
+	// List returns the revisions of a product with status, or any status
+	// when "", oldest first
+	List(ctx context.Context, productID int, status string, limit, offset int) ([]*models.ProductRevision, error)
+
+	Count(ctx context.Context, productID int, status string) (int, error)
+
+	// Review stores the outcome of a pending revision: its status, reviewer,
+	// comment, and when it was reviewed
+	Review(ctx context.Context, rev *models.ProductRevision) error
+}
+
+type productRevisionRepo struct {
+	db *database.DB
+}
+
+func NewProductRevisionRepository(db *database.DB) ProductRevisionRepository {
+	return &productRevisionRepo{db: db}
+}
+
+// The product is scanned as JSON, so the columns are listed explicitly
+const revisionColumns = `id, product_id, product, base_updated_at, status, author, reviewer, comment, created_at, reviewed_at`
+
+var revisionByIDQuery = `SELECT ` + revisionColumns + ` FROM product_revisions WHERE id = $1`
+
+func (r *productRevisionRepo) Create(ctx context.Context, rev *models.ProductRevision) error {
+	query := `
+		INSERT INTO product_revisions (product_id, product, base_updated_at, status, author, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	proposed, err := json.Marshal(rev.Product)
+	if err != nil {
+		return fmt.Errorf("failed to encode product revision: %w", err)
+	}
+	rev.Status = models.RevisionPending
+	rev.CreatedAt = time.Now()
+
+	err = r.db.Conn(ctx).QueryRowContext(ctx, query, rev.ProductID, proposed, rev.BaseUpdatedAt, rev.Status, rev.Author, rev.CreatedAt).Scan(&rev.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create product revision: %w", err)
+	}
+
+	return nil
+}
+
+func (r *productRevisionRepo) GetByID(ctx context.Context, id int) (*models.ProductRevision, error) {
+	return r.get(ctx, revisionByIDQuery, id)
+}
+
+func (r *productRevisionRepo) GetByIDForUpdate(ctx context.Context, id int) (*models.ProductRevision, error) {
+	return r.get(ctx, revisionByIDQuery+" "+r.db.Dialect().ForUpdate(), id)
+}
+
+func (r *productRevisionRepo) get(ctx context.Context, query string, id int) (*models.ProductRevision, error) {
+	rev, err := scanRevision(r.db.Conn(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("product revision not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product revision: %w", err)
+	}
+
+	return rev, nil
+}
+
+// revisionFilter builds the WHERE clause of a product's revisions with
+// status
+func revisionFilter(productID int, status string) (string, []interface{}) {
+	if status == "" {
+		return ` WHERE product_id = $1`, []interface{}{productID}
+	}
+	return ` WHERE product_id = $1 AND status = $2`, []interface{}{productID, status}
+}
+
+func (r *productRevisionRepo) List(ctx context.Context, productID int, status string, limit, offset int) ([]*models.ProductRevision, error) {
+	where, args := revisionFilter(productID, status)
+	query := `
+		SELECT ` + revisionColumns + `
+		FROM product_revisions` + where + `
+		ORDER BY created_at, id
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list product revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*models.ProductRevision{}
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product revision: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list product revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+func (r *productRevisionRepo) Count(ctx context.Context, productID int, status string) (int, error) {
+	where, args := revisionFilter(productID, status)
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM product_revisions`+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count product revisions: %w", err)
+	}
+	return count, nil
+}
+
+func (r *productRevisionRepo) Review(ctx context.Context, rev *models.ProductRevision) error {
+	query := `
+		UPDATE product_revisions
+		SET status = $1, reviewer = $2, comment = $3, reviewed_at = $4
+		WHERE id = $5 AND status = $6
+	`
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, rev.Status, rev.Reviewer, rev.Comment, rev.ReviewedAt, rev.ID, models.RevisionPending)
+	if err != nil {
+		return fmt.Errorf("failed to review product revision: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("product revision not found")
+	}
+
+	return nil
+}
+
+// scanRevision scans a row of revisionColumns, decoding the product
+func scanRevision(row interface{ Scan(...interface{}) error }) (*models.ProductRevision, error) {
+	rev := &models.ProductRevision{}
+	var proposed []byte
+	var reviewedAt sql.NullTime
+	if err := row.Scan(&rev.ID, &rev.ProductID, &proposed, &rev.BaseUpdatedAt, &rev.Status, &rev.Author, &rev.Reviewer, &rev.Comment, &rev.CreatedAt, &reviewedAt); err != nil {
+		return nil, err
+	}
+	if reviewedAt.Valid {
+		rev.ReviewedAt = &reviewedAt.Time
+	}
+	if err := json.Unmarshal(proposed, &rev.Product); err != nil {
+		return nil, fmt.Errorf("failed to decode product revision %d: %w", rev.ID, err)
+	}
+	return rev, nil
+}
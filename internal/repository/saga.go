@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// SagaRepository records the state of sagas, multi-step workflows across
+// systems, so they can be resumed after a crash
+type SagaRepository interface {
+	// Create records a saga as it starts, claimed, and reports false,
+	// without recording it, when one of the same name and key exists
+	Create(ctx context.Context, saga *models.Saga) (bool, error)
+
+	GetByID(ctx context.Context, id int) (*models.Saga, error)
+
+	GetByKey(ctx context.Context, name, key string) (*models.Saga, error)
+
+	// Claim claims the unfinished saga id for owner until until, if it's
+	// owner's already or its claim has lapsed, and reports whether it did.
+	// Owners renew their claims with it.
+	Claim(ctx context.Context, id int, owner string, until time.Time) (bool, error)
+
+	// Save records a saga's progress: its status, step, data, and error,
+	// and when it finished once it's done. It fails with "saga claimed
+	// elsewhere" when the saga's claimant has changed.
+	Save(ctx context.Context, saga *models.Saga) error
+
+	// Release gives up the claim on a saga that didn't finish, so it's
+	// resumed without waiting for the claim to lapse
+	Release(ctx context.Context, saga *models.Saga) error
+
+	// ListInterrupted returns up to limit sagas still running or
+	// compensating whose claim has lapsed, oldest first
+	ListInterrupted(ctx context.Context, limit int) ([]*models.Saga, error)
+}
+
+type sagaRepo struct {
+	db *database.DB
+}
+
+func NewSagaRepository(db *database.DB) SagaRepository {
+	return &sagaRepo{db: db}
+}
+
+var sagaColumns = database.ColumnList(models.Saga{})
+
+func (r *sagaRepo) Create(ctx context.Context, saga *models.Saga) (bool, error) {
+	query := `
+		INSERT INTO sagas (name, saga_key, status, step, data, claimed_by, claimed_until, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (name, saga_key) DO NOTHING
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query,
+		saga.Name,
+		saga.Key,
+		saga.Status,
+		saga.Step,
+		[]byte(saga.Data),
+		saga.ClaimedBy,
+		saga.ClaimedUntil,
+		now,
+	).Scan(&saga.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create saga: %w", err)
+	}
+	saga.CreatedAt, saga.UpdatedAt = now, now
+
+	return true, nil
+}
+
+func (r *sagaRepo) GetByID(ctx context.Context, id int) (*models.Saga, error) {
+	return r.get(ctx, `SELECT `+sagaColumns+` FROM sagas WHERE id = $1`, id)
+}
+
+func (r *sagaRepo) GetByKey(ctx context.Context, name, key string) (*models.Saga, error) {
+	return r.get(ctx, `SELECT `+sagaColumns+` FROM sagas WHERE name = $1 AND saga_key = $2`, name, key)
+}
+
+func (r *sagaRepo) get(ctx context.Context, query string, args ...interface{}) (*models.Saga, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saga: %w", err)
+	}
+
+	saga := &models.Saga{}
+	err = database.ScanOne(saga, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("saga not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saga: %w", err)
+	}
+
+	return saga, nil
+}
+
+func (r *sagaRepo) Claim(ctx context.Context, id int, owner string, until time.Time) (bool, error) {
+	query := `
+		UPDATE sagas SET claimed_by = $2, claimed_until = $3
+		WHERE id = $1 AND finished_at IS NULL
+			AND (claimed_by = $2 OR claimed_until IS NULL OR claimed_until < $4)
+	`
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, id, owner, until, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim saga: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *sagaRepo) Save(ctx context.Context, saga *models.Saga) error {
+	query := `
+		UPDATE sagas SET
+			status = $2,
+			step = $3,
+			data = $4,
+			error = $5,
+			updated_at = $6,
+			finished_at = $7
+		WHERE id = $1 AND claimed_by = $8
+	`
+
+	now := time.Now()
+	var finishedAt *time.Time
+	if saga.Done() {
+		finishedAt = &now
+	}
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query,
+		saga.ID,
+		saga.Status,
+		saga.Step,
+		[]byte(saga.Data),
+		saga.Error,
+		now,
+		finishedAt,
+		saga.ClaimedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save saga: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("saga claimed elsewhere")
+	}
+	saga.UpdatedAt, saga.FinishedAt = now, finishedAt
+
+	return nil
+}
+
+func (r *sagaRepo) Release(ctx context.Context, saga *models.Saga) error {
+	query := `UPDATE sagas SET claimed_until = NULL WHERE id = $1 AND claimed_by = $2`
+
+	if _, err := r.db.Conn(ctx).ExecContext(ctx, query, saga.ID, saga.ClaimedBy); err != nil {
+		return fmt.Errorf("failed to release saga: %w", err)
+	}
+	saga.ClaimedUntil = nil
+
+	return nil
+}
+
+func (r *sagaRepo) ListInterrupted(ctx context.Context, limit int) ([]*models.Saga, error) {
+	query := `
+		SELECT ` + sagaColumns + `
+		FROM sagas
+		WHERE finished_at IS NULL AND (claimed_until IS NULL OR claimed_until < $1)
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, time.Now(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interrupted sagas: %w", err)
+	}
+
+	var sagas []*models.Saga
+	if err := database.ScanAll(&sagas, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan sagas: %w", err)
+	}
+
+	return sagas, nil
+}
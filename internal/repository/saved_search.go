@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type SavedSearchRepository interface {
+	Create(ctx context.Context, search *models.SavedSearch) error
+
+	GetByID(ctx context.Context, id int) (*models.SavedSearch, error)
+
+	Update(ctx context.Context, search *models.SavedSearch) error
+
+	Delete(ctx context.Context, id int) error
+
+	// List returns saved searches by name
+	List(ctx context.Context, limit, offset int) ([]*models.SavedSearch, error)
+
+	Count(ctx context.Context) (int, error)
+}
+
+type savedSearchRepo struct {
+	db *database.DB
+}
+
+func NewSavedSearchRepository(db *database.DB) SavedSearchRepository {
+	return &savedSearchRepo{db: db}
+}
+
+// The filter is scanned as JSON, so the columns are listed explicitly
+const savedSearchColumns = `id, name, filter, created_at, updated_at`
+
+func (r *savedSearchRepo) Create(ctx context.Context, search *models.SavedSearch) error {
+	query := `
+		INSERT INTO saved_searches (name, filter, created_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	filter, err := json.Marshal(search.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to encode saved search filter: %w", err)
+	}
+	now := time.Now()
+	search.CreatedAt = now
+	search.UpdatedAt = now
+
+	err = r.db.Conn(ctx).QueryRowContext(ctx, query, search.Name, filter, search.CreatedAt, search.UpdatedAt).Scan(&search.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create saved search: %w", err)
+	}
+
+	return nil
+}
+
+func (r *savedSearchRepo) GetByID(ctx context.Context, id int) (*models.SavedSearch, error) {
+	query := `SELECT ` + savedSearchColumns + ` FROM saved_searches WHERE id = $1`
+
+	search, err := scanSavedSearch(r.db.Conn(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("saved search not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+
+	return search, nil
+}
+
+func (r *savedSearchRepo) Update(ctx context.Context, search *models.SavedSearch) error {
+	query := `UPDATE saved_searches SET name = $2, filter = $3, updated_at = $4 WHERE id = $1`
+
+	filter, err := json.Marshal(search.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to encode saved search filter: %w", err)
+	}
+	search.UpdatedAt = time.Now()
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, search.ID, search.Name, filter, search.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update saved search: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("saved search not found")
+	}
+
+	return nil
+}
+
+func (r *savedSearchRepo) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM saved_searches WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("saved search not found")
+	}
+
+	return nil
+}
+
+func (r *savedSearchRepo) List(ctx context.Context, limit, offset int) ([]*models.SavedSearch, error) {
+	query := `
+		SELECT ` + savedSearchColumns + `
+		FROM saved_searches
+		ORDER BY name, id
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	var searches []*models.SavedSearch
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		searches = append(searches, search)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+
+	return searches, nil
+}
+
+func (r *savedSearchRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM saved_searches`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count saved searches: %w", err)
+	}
+
+	return count, nil
+}
+
+// scanSavedSearch scans a row of savedSearchColumns, decoding the filter
+func scanSavedSearch(row interface{ Scan(...interface{}) error }) (*models.SavedSearch, error) {
+	search := &models.SavedSearch{}
+	var filter []byte
+	if err := row.Scan(&search.ID, &search.Name, &filter, &search.CreatedAt, &search.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filter, &search.Filter); err != nil {
+		return nil, fmt.Errorf("failed to decode filter of saved search %d: %w", search.ID, err)
+	}
+	return search, nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import "{{MODULE_NAME}}/internal/models"
+
+// ScannedTables maps each table read by this package to the model its rows
+// are scanned into, for schema drift checks
+var ScannedTables = map[string]interface{}{
+	"products":             models.Product{},
+	"event_outbox":         models.OutboxMessage{},
+	"catalog_sync_state":   models.SyncState{},
+	"stock_movements":      models.StockMovement{},
+	"queue_tasks":          models.QueuedTask{},
+	"promotions":           models.Promotion{},
+	"units":                models.Unit{},
+	"export_runs":          models.ExportRun{},
+	"search_index_state":   models.SearchIndexState{},
+	"saved_searches":       models.SavedSearch{},
+	"subscriptions":        models.Subscription{},
+	"trashed_products":     models.TrashedProduct{},
+	"api_keys":             models.APIKey{},
+	"api_usage":            models.UsageRecord{},
+	"stock_receipts":       models.StockReceipt{},
+	"inventory_valuations": models.Valuation{},
+	"stock_takes":          models.StockTake{},
+	"stock_take_counts":    models.StockTakeCount{},
+	"product_lots":         models.Lot{},
+	"serial_numbers":       models.SerialNumber{},
+	"product_returns":      models.Return{},
+	"product_forecasts":    models.Forecast{},
+	"product_suppliers":    models.ProductSupplier{},
+	"purchase_orders":      models.PurchaseOrder{},
+	"purchase_order_lines": models.PurchaseOrderLine{},
+	"report_definitions":   models.Report{},
+	"webhook_deliveries":   models.WebhookDelivery{},
+	"product_revisions":    models.ProductRevision{},
+	"operations":           models.Operation{},
+	"sagas":                models.Saga{},
+}
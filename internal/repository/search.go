@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// productSearchDocument is the text search document of a product; it must
+// match the idx_products_search expression for the index to be used
+const productSearchDocument = `(setweight(to_tsvector('simple', sku), 'A') || setweight(to_tsvector('english', name), 'A') || setweight(to_tsvector('english', description), 'B'))`
+
+type SearchRepository interface {
+	// Search returns the products matching q, best match first, and how many
+	// match in total. Postgres ranks full-text matches; SQLite matches every
+	// word as a substring and orders by ID.
+	Search(ctx context.Context, q models.SearchQuery) ([]*models.Product, int, error)
+
+	// FuzzySearch returns the products with a name or SKU word similar to
+	// every word of q.Text, by pg_trgm word similarity of at least threshold,
+	// closest first, and how many match in total. Postgres only.
+	FuzzySearch(ctx context.Context, q models.SearchQuery, threshold float64) ([]*models.Product, int, error)
+
+	// Correct replaces each word of text with the most similar of words, by
+	// pg_trgm similarity of at least threshold, keeping words without one.
+	// Postgres only.
+	Correct(ctx context.Context, text string, words []string, threshold float64) (string, error)
+
+	// Facets counts the products matching q by the facets of q.Facets: those
+	// Search matches or, with a threshold, FuzzySearch
+	Facets(ctx context.Context, q models.SearchQuery, threshold float64) (models.Facets, error)
+
+	// Suggest returns up to q.Limit products whose name or SKU completes
+	// q.Prefix: names and SKUs starting with it first, then, on Postgres,
+	// names with a word similar to it, closest first.
+	Suggest(ctx context.Context, q models.SuggestQuery) ([]*models.Suggestion, error)
+
+	// IndexState returns the sync state of an external search index, or nil
+	// when it has never been built
+	IndexState(ctx context.Context, name string) (*models.SearchIndexState, error)
+
+	// SaveIndexState records a freshly built index, replacing any state
+	SaveIndexState(ctx context.Context, state *models.SearchIndexState) error
+
+	// AdvanceCursor moves the cursor of an index from one position to the
+	// next. It reports false, changing nothing, when the cursor is no longer
+	// at from, e.g. because the index was rebuilt meanwhile.
+	AdvanceCursor(ctx context.Context, name, from, to string) (bool, error)
+}
+
+type searchRepo struct {
+	db *database.DB
+}
+
+func NewSearchRepository(db *database.DB) SearchRepository {
+	return &searchRepo{db: db}
+}
+
+var (
+	searchIndexStateColumns = database.ColumnList(models.SearchIndexState{})
+	suggestionColumns       = database.ColumnList(models.Suggestion{})
+)
+
+func (r *searchRepo) Search(ctx context.Context, q models.SearchQuery) ([]*models.Product, int, error) {
+	return r.search(ctx, q, false)
+}
+
+func (r *searchRepo) FuzzySearch(ctx context.Context, q models.SearchQuery, threshold float64) ([]*models.Product, int, error) {
+	var (
+		products []*models.Product
+		total    int
+	)
+	err := r.withSimilarityThreshold(ctx, threshold, func(ctx context.Context) error {
+		var err error
+		products, total, err = r.search(ctx, q, true)
+		return err
+	})
+	return products, total, err
+}
+
+// withSimilarityThreshold runs fn in a transaction matching words of at
+// least threshold similarity. The <% operator, which the trigram indexes
+// serve, compares against the threshold setting, here set for the
+// transaction only.
+func (r *searchRepo) withSimilarityThreshold(ctx context.Context, threshold float64, fn func(ctx context.Context) error) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		setting := strconv.FormatFloat(threshold, 'f', -1, 64)
+		if _, err := r.db.Conn(ctx).ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, setting); err != nil {
+			return fmt.Errorf("failed to set similarity threshold: %w", err)
+		}
+		return fn(ctx)
+	})
+}
+
+func (r *searchRepo) Facets(ctx context.Context, q models.SearchQuery, threshold float64) (models.Facets, error) {
+	if threshold <= 0 {
+		from, where, _, args := r.searchConditions(q, false)
+		return facetCounts(ctx, r.db.Conn(ctx), from, where, args, q.Facets)
+	}
+
+	var facets models.Facets
+	err := r.withSimilarityThreshold(ctx, threshold, func(ctx context.Context) error {
+		from, where, _, args := r.searchConditions(q, true)
+		var err error
+		facets, err = facetCounts(ctx, r.db.Conn(ctx), from, where, args, q.Facets)
+		return err
+	})
+	return facets, err
+}
+
+func (r *searchRepo) search(ctx context.Context, q models.SearchQuery, fuzzy bool) ([]*models.Product, int, error) {
+	from, where, order, args := r.searchConditions(q, fuzzy)
+
+	var total int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM `+from+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	query := `
+		SELECT ` + productColumns + `
+		FROM ` + from + where + `
+		ORDER BY ` + order + `
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	var products []*models.Product
+	if err := database.ScanAll(&products, rows); err != nil {
+		return nil, 0, fmt.Errorf("failed to scan products: %w", err)
+	}
+	if err := (&productRepo{db: r.db}).loadTags(ctx, products...); err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// searchConditions builds the FROM list, WHERE clause, and ORDER BY of a
+// search, with the arguments they bind. Fuzzy searches match words by
+// trigram similarity to name and SKU words (idx_products_suggest_name,
+// idx_products_suggest_sku).
+func (r *searchRepo) searchConditions(q models.SearchQuery, fuzzy bool) (from, where, order string, args []interface{}) {
+	from, order = "products", "id"
+	var conditions []string
+	placeholder := func(arg interface{}) string {
+		args = append(args, arg)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if text := strings.TrimSpace(q.Text); text != "" {
+		if fuzzy {
+			var similarity []string
+			for _, word := range strings.Fields(strings.ToLower(text)) {
+				p := placeholder(word)
+				conditions = append(conditions, "("+p+" <% LOWER(name) OR "+p+" <% LOWER(sku))")
+				similarity = append(similarity, "GREATEST(word_similarity("+p+", LOWER(name)), word_similarity("+p+", LOWER(sku)))")
+			}
+			order = strings.Join(similarity, " + ") + " DESC, id"
+		} else if r.db.Dialect() == database.SQLite {
+			for _, word := range strings.Fields(strings.ToLower(text)) {
+				p := placeholder("%" + escapeLike(word) + "%")
+				conditions = append(conditions, "(LOWER(sku) LIKE "+p+" ESCAPE '\\' OR LOWER(name) LIKE "+p+" ESCAPE '\\' OR LOWER(description) LIKE "+p+" ESCAPE '\\')")
+			}
+		} else {
+			from = "products, websearch_to_tsquery('english', " + placeholder(text) + ") query"
+			conditions = append(conditions, productSearchDocument+" @@ query")
+			order = "ts_rank(" + productSearchDocument + ", query) DESC, id"
+		}
+	}
+	if q.Category != "" {
+		conditions = append(conditions, "LOWER(category) = LOWER("+placeholder(q.Category)+")")
+	}
+	if q.Tag != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM product_tags t WHERE t.product_id = products.id AND t.tag = "+placeholder(strings.ToLower(q.Tag))+")")
+	}
+	if q.Region != "" {
+		conditions = append(conditions, soldInCondition(placeholder(q.Region)))
+	}
+	if !q.Visible.IsZero() {
+		conditions = append(conditions, visibleCondition(placeholder(q.Visible)))
+	}
+
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return from, where, order, args
+}
+
+func (r *searchRepo) Correct(ctx context.Context, text string, words []string, threshold float64) (string, error) {
+	query := `
+		SELECT COALESCE(string_agg(COALESCE(best.word, q.word), ' ' ORDER BY q.ord), '')
+		FROM unnest($1::text[]) WITH ORDINALITY AS q(word, ord)
+		LEFT JOIN LATERAL (
+			SELECT w.word FROM unnest($2::text[]) AS w(word)
+			WHERE similarity(w.word, q.word) >= $3
+			ORDER BY similarity(w.word, q.word) DESC, w.word
+			LIMIT 1
+		) best ON true
+	`
+
+	var corrected string
+	dialect := r.db.Dialect()
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, dialect.Array(strings.Fields(strings.ToLower(text))), dialect.Array(words), threshold).Scan(&corrected)
+	if err != nil {
+		return "", fmt.Errorf("failed to correct search text: %w", err)
+	}
+
+	return corrected, nil
+}
+
+func (r *searchRepo) Suggest(ctx context.Context, q models.SuggestQuery) ([]*models.Suggestion, error) {
+	prefix := strings.ToLower(strings.TrimSpace(q.Prefix))
+	starts := `(LOWER(sku) LIKE $2 ESCAPE '\' OR LOWER(name) LIKE $2 ESCAPE '\')`
+	args := []interface{}{prefix, escapeLike(prefix) + "%", q.Limit}
+	filters := ""
+	if q.Region != "" {
+		args = append(args, q.Region)
+		filters += " AND " + soldInCondition("$"+strconv.Itoa(len(args)))
+	}
+	if !q.Visible.IsZero() {
+		args = append(args, q.Visible)
+		filters += " AND " + visibleCondition("$"+strconv.Itoa(len(args)))
+	}
+
+	// Misspelled or later words of a name match by trigram similarity
+	// (idx_products_suggest_name, idx_products_suggest_sku); SQLite matches
+	// the start of any word instead
+	var query string
+	if r.db.Dialect() == database.SQLite {
+		query = `
+			SELECT ` + suggestionColumns + ` FROM products
+			WHERE (` + starts + ` OR LOWER(name) LIKE '% ' || $2 ESCAPE '\')` + filters + `
+			ORDER BY ` + starts + ` DESC, name, id
+			LIMIT $3`
+	} else {
+		query = `
+			SELECT ` + suggestionColumns + ` FROM products
+			WHERE (` + starts + ` OR $1 <% LOWER(name))` + filters + `
+			ORDER BY ` + starts + ` DESC, word_similarity($1, LOWER(name)) DESC, name, id
+			LIMIT $3`
+	}
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest products: %w", err)
+	}
+
+	var suggestions []*models.Suggestion
+	if err := database.ScanAll(&suggestions, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s, for ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (r *searchRepo) IndexState(ctx context.Context, name string) (*models.SearchIndexState, error) {
+	query := `SELECT ` + searchIndexStateColumns + ` FROM search_index_state WHERE name = $1`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search index state: %w", err)
+	}
+
+	state := &models.SearchIndexState{}
+	err = database.ScanOne(state, rows)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search index state: %w", err)
+	}
+
+	return state, nil
+}
+
+func (r *searchRepo) SaveIndexState(ctx context.Context, state *models.SearchIndexState) error {
+	query := `
+		INSERT INTO search_index_state (` + searchIndexStateColumns + `)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE SET
+			index_name = EXCLUDED.index_name,
+			feed_cursor = EXCLUDED.feed_cursor,
+			reindexed_at = EXCLUDED.reindexed_at,
+			synced_at = EXCLUDED.synced_at
+	`
+
+	_, err := r.db.Conn(ctx).ExecContext(ctx, query,
+		state.Name,
+		state.IndexName,
+		state.Cursor,
+		state.ReindexedAt,
+		state.SyncedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save search index state: %w", err)
+	}
+
+	return nil
+}
+
+func (r *searchRepo) AdvanceCursor(ctx context.Context, name, from, to string) (bool, error) {
+	query := `UPDATE search_index_state SET feed_cursor = $3, synced_at = $4 WHERE name = $1 AND feed_cursor = $2`
+
+	result, err := r.db.Conn(ctx).ExecContext(ctx, query, name, from, to, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to advance search index cursor: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
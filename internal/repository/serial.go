@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type SerialRepository interface {
+	// GetBySerial returns a serial number, active or retired
+	GetBySerial(ctx context.Context, serial string) (*models.SerialNumber, error)
+
+	// Register records a serial as an active unit of a product. A serial the
+	// product had retired is made active again; it returns false, without
+	// changing anything, for a serial that's active or another product's.
+	Register(ctx context.Context, productID int, serial string) (*models.SerialNumber, bool, error)
+
+	// Retire records that an active serial's unit left stock, returning
+	// "serial not found" for a serial that isn't active
+	Retire(ctx context.Context, serial, reason string, at time.Time) (*models.SerialNumber, error)
+
+	// ListByProduct returns a product's serials in registration order, only
+	// those with the given status unless it's ""
+	ListByProduct(ctx context.Context, productID int, status string, limit, offset int) ([]*models.SerialNumber, error)
+
+	CountByProduct(ctx context.Context, productID int, status string) (int, error)
+}
+
+type serialRepo struct {
+	db *database.DB
+}
+
+func NewSerialRepository(db *database.DB) SerialRepository {
+	return &serialRepo{db: db}
+}
+
+var serialColumns = database.ColumnList(models.SerialNumber{})
+
+func (r *serialRepo) GetBySerial(ctx context.Context, serial string) (*models.SerialNumber, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, `SELECT `+serialColumns+` FROM serial_numbers WHERE serial = $1`, serial)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get serial: %w", err)
+	}
+
+	sn := &models.SerialNumber{}
+	err = database.ScanOne(sn, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("serial not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get serial: %w", err)
+	}
+
+	return sn, nil
+}
+
+func (r *serialRepo) Register(ctx context.Context, productID int, serial string) (*models.SerialNumber, bool, error) {
+	query := `
+		INSERT INTO serial_numbers (product_id, serial, status, registered_at)
+		VALUES ($1, $2, 'active', $3)
+		ON CONFLICT (serial) DO UPDATE
+		SET status = 'active', retired_reason = '', retired_at = NULL, registered_at = excluded.registered_at
+		WHERE serial_numbers.product_id = excluded.product_id AND serial_numbers.status = 'retired'
+		RETURNING ` + serialColumns
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, productID, serial, time.Now())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to register serial: %w", err)
+	}
+
+	sn := &models.SerialNumber{}
+	err = database.ScanOne(sn, rows)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to register serial: %w", err)
+	}
+
+	return sn, true, nil
+}
+
+func (r *serialRepo) Retire(ctx context.Context, serial, reason string, at time.Time) (*models.SerialNumber, error) {
+	query := `
+		UPDATE serial_numbers SET status = 'retired', retired_reason = $2, retired_at = $3
+		WHERE serial = $1 AND status = 'active'
+		RETURNING ` + serialColumns
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, serial, reason, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retire serial: %w", err)
+	}
+
+	sn := &models.SerialNumber{}
+	err = database.ScanOne(sn, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("serial not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retire serial: %w", err)
+	}
+
+	return sn, nil
+}
+
+// serialStatusFilter narrows a product's serials to a status, if one is given
+func serialStatusFilter(productID int, status string) (string, []interface{}) {
+	if status == "" {
+		return ` WHERE product_id = $1`, []interface{}{productID}
+	}
+	return ` WHERE product_id = $1 AND status = $2`, []interface{}{productID, status}
+}
+
+func (r *serialRepo) ListByProduct(ctx context.Context, productID int, status string, limit, offset int) ([]*models.SerialNumber, error) {
+	where, args := serialStatusFilter(productID, status)
+	query := `
+		SELECT ` + serialColumns + `
+		FROM serial_numbers` + where + `
+		ORDER BY registered_at, id
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list serials: %w", err)
+	}
+
+	serials := []*models.SerialNumber{}
+	if err := database.ScanAll(&serials, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan serials: %w", err)
+	}
+
+	return serials, nil
+}
+
+func (r *serialRepo) CountByProduct(ctx context.Context, productID int, status string) (int, error) {
+	where, args := serialStatusFilter(productID, status)
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM serial_numbers`+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count serials: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+var (
+	shadowCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "repository_shadow_calls_total",
+		Help: "Shadowed repository reads, by method and result (match, mismatch, error, dropped).",
+	}, []string{"method", "result"})
+	shadowDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_shadow_duration_seconds",
+		Help:    "Duration of shadowed reads, by method and side (primary, candidate).",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"method", "side"})
+)
+
+const (
+	shadowTimeout     = 10 * time.Second
+	shadowMaxInFlight = 4 // Candidate calls at once; more are dropped
+)
+
+type shadowProductRepo struct {
+	ProductRepository // The primary; writes and unshadowed reads pass through
+	candidate         ProductRepository
+	enabled           func(ctx context.Context) bool
+	slots             chan struct{}
+	logger            *slog.Logger
+}
+
+// NewShadowProductRepository wraps primary so that, while enabled reports
+// true, List, Count, and ListWithCount run on candidate as well, the
+// implementation meant to replace primary's. Callers only ever get primary's
+// results. The candidate runs after primary has answered, in the background,
+// and its results are compared with primary's: mismatches and errors are
+// logged with both durations. Reads inside a transaction aren't shadowed,
+// since they may see uncommitted changes the candidate can't.
+func NewShadowProductRepository(primary, candidate ProductRepository, enabled func(ctx context.Context) bool, logger *slog.Logger) ProductRepository {
+	return &shadowProductRepo{
+		ProductRepository: primary,
+		candidate:         candidate,
+		enabled:           enabled,
+		slots:             make(chan struct{}, shadowMaxInFlight),
+		logger:            logger,
+	}
+}
+
+// shadow returns what primary returns, running candidate in the background
+// as well when shadowing is on. Both return their results in the form
+// compareShadowed expects.
+func (r *shadowProductRepo) shadow(ctx context.Context, method string, args []interface{}, primary, candidate func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if database.InTx(ctx) || !r.enabled(ctx) {
+		return primary(ctx)
+	}
+
+	start := time.Now()
+	want, err := primary(ctx)
+	primaryDuration := time.Since(start)
+	if err != nil {
+		// Nothing to compare with
+		return want, err
+	}
+	shadowDuration.WithLabelValues(method, "primary").Observe(primaryDuration.Seconds())
+
+	select {
+	case r.slots <- struct{}{}:
+	default:
+		shadowCalls.WithLabelValues(method, "dropped").Inc()
+		return want, nil
+	}
+
+	go func() {
+		defer func() { <-r.slots }()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shadowTimeout)
+		defer cancel()
+
+		start := time.Now()
+		got, err := candidate(ctx)
+		candidateDuration := time.Since(start)
+		shadowDuration.WithLabelValues(method, "candidate").Observe(candidateDuration.Seconds())
+
+		attrs := []interface{}{
+			"method", method,
+			"args", args,
+			"primary_duration", primaryDuration.String(),
+			"candidate_duration", candidateDuration.String(),
+			"latency_diff", (candidateDuration - primaryDuration).String(),
+		}
+		if err != nil {
+			shadowCalls.WithLabelValues(method, "error").Inc()
+			r.logger.WarnContext(ctx, "shadow read failed", append(attrs, "error", err)...)
+			return
+		}
+		if diff := compareShadowed(want, got); diff != "" {
+			shadowCalls.WithLabelValues(method, "mismatch").Inc()
+			r.logger.WarnContext(ctx, "shadow read mismatch", append(attrs, "diff", diff)...)
+			return
+		}
+		shadowCalls.WithLabelValues(method, "match").Inc()
+	}()
+	return want, nil
+}
+
+// productsAndCount is what ListWithCount returns, for comparison
+type productsAndCount struct {
+	products []*models.Product
+	total    int
+}
+
+// compareShadowed describes the first difference between primary's want and
+// candidate's got, "" when they're equal
+func compareShadowed(want, got interface{}) string {
+	switch want := want.(type) {
+	case []*models.Product:
+		return compareProducts(want, got.([]*models.Product))
+	case productsAndCount:
+		got := got.(productsAndCount)
+		if want.total != got.total {
+			return fmt.Sprintf("total: primary %d, candidate %d", want.total, got.total)
+		}
+		return compareProducts(want.products, got.products)
+	default:
+		if !reflect.DeepEqual(want, got) {
+			return fmt.Sprintf("primary %v, candidate %v", want, got)
+		}
+		return ""
+	}
+}
+
+func compareProducts(want, got []*models.Product) string {
+	if len(want) != len(got) {
+		return fmt.Sprintf("length: primary %d, candidate %d", len(want), len(got))
+	}
+	for i := range want {
+		if want[i].ID != got[i].ID {
+			return fmt.Sprintf("product %d: primary ID %d, candidate ID %d", i, want[i].ID, got[i].ID)
+		}
+		if !reflect.DeepEqual(want[i], got[i]) {
+			return fmt.Sprintf("product %d (ID %d): fields differ", i, want[i].ID)
+		}
+	}
+	return ""
+}
+
+func (r *shadowProductRepo) List(ctx context.Context, limit, offset int) ([]*models.Product, error) {
+	res, err := r.shadow(ctx, "List", []interface{}{limit, offset},
+		func(ctx context.Context) (interface{}, error) { return r.ProductRepository.List(ctx, limit, offset) },
+		func(ctx context.Context) (interface{}, error) { return r.candidate.List(ctx, limit, offset) },
+	)
+	products, _ := res.([]*models.Product)
+	return products, err
+}
+
+func (r *shadowProductRepo) Count(ctx context.Context) (int, error) {
+	res, err := r.shadow(ctx, "Count", nil,
+		func(ctx context.Context) (interface{}, error) { return r.ProductRepository.Count(ctx) },
+		func(ctx context.Context) (interface{}, error) { return r.candidate.Count(ctx) },
+	)
+	count, _ := res.(int)
+	return count, err
+}
+
+func (r *shadowProductRepo) ListWithCount(ctx context.Context, limit, offset int) ([]*models.Product, int, error) {
+	listWithCount := func(repo ProductRepository) func(ctx context.Context) (interface{}, error) {
+		return func(ctx context.Context) (interface{}, error) {
+			products, total, err := repo.ListWithCount(ctx, limit, offset)
+			return productsAndCount{products, total}, err
+		}
+	}
+	res, err := r.shadow(ctx, "ListWithCount", []interface{}{limit, offset}, listWithCount(r.ProductRepository), listWithCount(r.candidate))
+	pc, _ := res.(productsAndCount)
+	return pc.products, pc.total, err
+}
+
+type filteredListRepo struct {
+	ProductRepository
+}
+
+// NewFilteredListProductRepository returns next with List, Count, and
+// ListWithCount answered by their filtered counterparts with an empty
+// filter: the filter-based implementation meant to replace them, for
+// NewShadowProductRepository to try
+func NewFilteredListProductRepository(next ProductRepository) ProductRepository {
+	return filteredListRepo{ProductRepository: next}
+}
+
+func (r filteredListRepo) List(ctx context.Context, limit, offset int) ([]*models.Product, error) {
+	return r.ListFiltered(ctx, models.ProductFilter{}, limit, offset)
+}
+
+func (r filteredListRepo) Count(ctx context.Context) (int, error) {
+	return r.CountFiltered(ctx, models.ProductFilter{})
+}
+
+func (r filteredListRepo) ListWithCount(ctx context.Context, limit, offset int) ([]*models.Product, int, error) {
+	return r.ListFilteredWithCount(ctx, models.ProductFilter{}, limit, offset)
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// listingProductRepo lists its products and counts them
+type listingProductRepo struct {
+	ProductRepository
+	products []*models.Product
+}
+
+func (r listingProductRepo) List(ctx context.Context, limit, offset int) ([]*models.Product, error) {
+	return r.products, nil
+}
+
+func (r listingProductRepo) Count(ctx context.Context) (int, error) {
+	return len(r.products), nil
+}
+
+// syncBuffer is a log buffer safe for the background candidate calls
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestShadowProductRepository(t *testing.T) {
+	primary := listingProductRepo{products: []*models.Product{{ID: 2, Name: "B"}, {ID: 1, Name: "A"}}}
+	candidate := listingProductRepo{products: []*models.Product{{ID: 1, Name: "A"}, {ID: 2, Name: "B"}}}
+
+	var enabled bool
+	var logs syncBuffer
+	repo := NewShadowProductRepository(primary, candidate, func(context.Context) bool { return enabled }, slog.New(slog.NewTextHandler(&logs, nil)))
+	waitForLog := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(logs.String(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("no %q in logs: %s", want, logs.String())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	products, err := repo.List(context.Background(), 10, 0)
+	if err != nil || len(products) != 2 || products[0].ID != 2 {
+		t.Fatalf("List = %v, %v, want the primary's products", products, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if logs.String() != "" {
+		t.Errorf("shadowed while disabled: %s", logs.String())
+	}
+
+	enabled = true
+	products, err = repo.List(context.Background(), 10, 0)
+	if err != nil || len(products) != 2 || products[0].ID != 2 {
+		t.Fatalf("List = %v, %v, want the primary's products", products, err)
+	}
+	waitForLog("shadow read mismatch")
+	if !strings.Contains(logs.String(), `diff="product 0: primary ID 2, candidate ID 1"`) || !strings.Contains(logs.String(), "latency_diff=") {
+		t.Errorf("mismatch logged as %s", logs.String())
+	}
+
+	// Equal results log nothing
+	before := logs.String()
+	if count, err := repo.Count(context.Background()); err != nil || count != 2 {
+		t.Fatalf("Count = %d, %v", count, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if logs.String() != before {
+		t.Errorf("matching counts logged: %s", strings.TrimPrefix(logs.String(), before))
+	}
+}
+
+func TestCompareShadowed(t *testing.T) {
+	a := []*models.Product{{ID: 1, Name: "A"}}
+	for _, tt := range []struct {
+		want, got interface{}
+		diff      string
+	}{
+		{a, []*models.Product{{ID: 1, Name: "A"}}, ""},
+		{a, []*models.Product{{ID: 1, Name: "Z"}}, "product 0 (ID 1): fields differ"},
+		{a, []*models.Product{}, "length: primary 1, candidate 0"},
+		{productsAndCount{a, 3}, productsAndCount{a, 4}, "total: primary 3, candidate 4"},
+		{3, 3, ""},
+		{3, 4, "primary 3, candidate 4"},
+	} {
+		if diff := compareShadowed(tt.want, tt.got); diff != tt.diff {
+			t.Errorf("compareShadowed(%v, %v) = %q, want %q", tt.want, tt.got, diff, tt.diff)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"{{MODULE_NAME}}/internal/database"
+)
+
+// SKUSequence numbers generated SKUs. Numbers are per scope, the SKU
+// pattern rendered without its number, so patterns with date parts restart
+// every period.
+type SKUSequence interface {
+	// Next takes the next number of scope
+	Next(ctx context.Context, scope string) (int64, error)
+
+	// Peek returns the number Next would take, without taking it
+	Peek(ctx context.Context, scope string) (int64, error)
+}
+
+type skuCounterRepo struct {
+	db *database.DB
+}
+
+// NewSKUCounterRepository numbers SKUs with a row per scope in sku_counters.
+// Next locks the row until the transaction ends, so concurrent creates wait
+// for each other, and a rolled back create gives its number back: committed
+// SKUs have neither duplicate nor missing numbers. Next must run in the
+// transaction creating the product.
+func NewSKUCounterRepository(db *database.DB) SKUSequence {
+	return &skuCounterRepo{db: db}
+}
+
+func (r *skuCounterRepo) Next(ctx context.Context, scope string) (int64, error) {
+	var n int64
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `
+		INSERT INTO sku_counters (scope, value) VALUES ($1, 1)
+		ON CONFLICT (scope) DO UPDATE SET value = sku_counters.value + 1
+		RETURNING value
+	`, scope).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to take SKU number: %w", err)
+	}
+
+	return n, nil
+}
+
+func (r *skuCounterRepo) Peek(ctx context.Context, scope string) (int64, error) {
+	var n int64
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(value), 0) + 1 FROM sku_counters WHERE scope = $1
+	`, scope).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read SKU counter: %w", err)
+	}
+
+	return n, nil
+}
+
+type skuSequenceRepo struct {
+	db *database.DB
+}
+
+// NewSKUSequenceRepository numbers SKUs with the product_sku_seq Postgres
+// sequence, shared by every scope. Creates don't wait for each other, but a
+// number taken by a create that rolls back (including dry runs) is skipped.
+func NewSKUSequenceRepository(db *database.DB) SKUSequence {
+	return &skuSequenceRepo{db: db}
+}
+
+func (r *skuSequenceRepo) Next(ctx context.Context, scope string) (int64, error) {
+	var n int64
+	if err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT nextval('product_sku_seq')`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to take SKU number: %w", err)
+	}
+
+	return n, nil
+}
+
+func (r *skuSequenceRepo) Peek(ctx context.Context, scope string) (int64, error) {
+	var n int64
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `
+		SELECT CASE WHEN is_called THEN last_value + 1 ELSE last_value END FROM product_sku_seq
+	`).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read SKU sequence: %w", err)
+	}
+
+	return n, nil
+}
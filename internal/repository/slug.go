@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/slug"
+)
+
+// ProductSlugRepository finds products by their slugs, current and former;
+// see models.Product.Slug. Product writes assign and change slugs.
+type ProductSlugRepository interface {
+	// Lookup returns the ID of the product whose slug is, or was, slug, and
+	// its current slug
+	Lookup(ctx context.Context, slug string) (int, string, error)
+
+	// Available reports whether slug is free for product id: no other
+	// product has it now, or had it before
+	Available(ctx context.Context, slug string, id int) (bool, error)
+
+	// Regenerate gives up to limit products after afterID, in order, the
+	// slug made from their name: those without a slug, or every one with
+	// all. It returns how many slugs it changed and the last ID it looked
+	// at, 0 once there are no products left.
+	Regenerate(ctx context.Context, afterID, limit int, all bool) (int, int, error)
+}
+
+type productSlugRepo struct {
+	db *database.DB
+}
+
+func NewProductSlugRepository(db *database.DB) ProductSlugRepository {
+	return &productSlugRepo{db: db}
+}
+
+func (r *productSlugRepo) Lookup(ctx context.Context, s string) (int, string, error) {
+	query := `
+		SELECT id, slug FROM products WHERE slug = $1 AND slug <> ''
+		UNION ALL
+		SELECT p.id, p.slug FROM product_slugs s JOIN products p ON p.id = s.product_id WHERE s.slug = $1
+	`
+
+	var id int
+	var current string
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, s).Scan(&id, &current)
+	if err == sql.ErrNoRows {
+		return 0, "", fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to look up product slug: %w", err)
+	}
+
+	return id, current, nil
+}
+
+func (r *productSlugRepo) Available(ctx context.Context, s string, id int) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM products WHERE slug = $1 AND id <> $2)
+			OR EXISTS (SELECT 1 FROM product_slugs WHERE slug = $1 AND product_id <> $2)
+	`
+
+	var taken bool
+	if err := r.db.Conn(ctx).QueryRowContext(ctx, query, s, id).Scan(&taken); err != nil {
+		return false, fmt.Errorf("failed to check product slug: %w", err)
+	}
+
+	return !taken, nil
+}
+
+func (r *productSlugRepo) Regenerate(ctx context.Context, afterID, limit int, all bool) (int, int, error) {
+	query := `
+		SELECT id, name, slug FROM products
+		WHERE id > $1 AND (slug = '' OR $3)
+		ORDER BY id
+		LIMIT $2
+	`
+
+	changed, lastID := 0, 0
+	err := r.db.WithTx(ctx, func(ctx context.Context) error {
+		rows, err := r.db.Conn(ctx).QueryContext(ctx, query, afterID, limit, all)
+		if err != nil {
+			return fmt.Errorf("failed to list products to slug: %w", err)
+		}
+		var products []*models.Product
+		var old []string
+		for rows.Next() {
+			p := &models.Product{}
+			if err := rows.Scan(&p.ID, &p.Name, &p.Slug); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan product: %w", err)
+			}
+			old = append(old, p.Slug)
+			p.Slug = ""
+			products = append(products, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to list products to slug: %w", err)
+		}
+
+		if err := uniqueSlugs(ctx, r.db, products); err != nil {
+			return err
+		}
+		// updated_at is left alone, as for unique IDs: the product's data
+		// didn't change
+		for i, p := range products {
+			lastID = p.ID
+			if p.Slug == old[i] {
+				continue
+			}
+			if _, err := r.db.Conn(ctx).ExecContext(ctx, `UPDATE products SET slug = $2 WHERE id = $1`, p.ID, p.Slug); err != nil {
+				return fmt.Errorf("failed to update product slug: %w", err)
+			}
+			if err := replaceSlug(ctx, r.db, p.ID, old[i], p.Slug); err != nil {
+				return err
+			}
+			changed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return changed, lastID, nil
+}
+
+// uniqueSlugs gives the products without a slug one made from their name,
+// suffixed -2, -3, ... while another product has or had it, or one earlier
+// in products took it
+func uniqueSlugs(ctx context.Context, db *database.DB, products []*models.Product) error {
+	var pending []*models.Product
+	var bases []string
+	for _, p := range products {
+		if p.Slug == "" {
+			pending = append(pending, p)
+			bases = append(bases, slug.Make(p.Name))
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	dialect := db.Dialect()
+	owners := make(map[string]int)
+	if err := slugOwners(ctx, db, owners, dialect.AnyOf("slug", 1), dialect.Array(bases)); err != nil {
+		return err
+	}
+
+	claimed := make(map[string]bool, len(pending))
+	searched := make(map[string]bool)
+	for i, p := range pending {
+		base, n := bases[i], 1
+		for {
+			candidate := slug.Suffixed(base, n)
+			owner, taken := owners[candidate]
+			if !claimed[candidate] && (!taken || owner == p.ID) {
+				break
+			}
+			// Taken, so look up the suffixed slugs of base too, once
+			if !searched[base] {
+				if err := slugOwners(ctx, db, owners, "slug LIKE $1", base+"-%"); err != nil {
+					return err
+				}
+				searched[base] = true
+			}
+			n++
+		}
+		p.Slug = slug.Suffixed(base, n)
+		claimed[p.Slug] = true
+	}
+
+	return nil
+}
+
+// slugOwners adds the slugs matching condition on arg, of products now and
+// before, to owners with the IDs of their products
+func slugOwners(ctx context.Context, db *database.DB, owners map[string]int, condition string, arg interface{}) error {
+	query := `
+		SELECT slug, id FROM products WHERE ` + condition + `
+		UNION ALL
+		SELECT slug, product_id FROM product_slugs WHERE ` + condition
+
+	rows, err := db.Conn(ctx).QueryContext(ctx, query, arg)
+	if err != nil {
+		return fmt.Errorf("failed to look up product slugs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s string
+		var id int
+		if err := rows.Scan(&s, &id); err != nil {
+			return fmt.Errorf("failed to scan product slug: %w", err)
+		}
+		owners[s] = id
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to look up product slugs: %w", err)
+	}
+
+	return nil
+}
+
+// replaceSlug records that the slug of product id changed from old to s:
+// old keeps leading to the product, and s is no longer a former slug
+func replaceSlug(ctx context.Context, db *database.DB, id int, old, s string) error {
+	if _, err := db.Conn(ctx).ExecContext(ctx, `DELETE FROM product_slugs WHERE slug = $1 AND product_id = $2`, s, id); err != nil {
+		return fmt.Errorf("failed to update product slug history: %w", err)
+	}
+	if old == "" {
+		return nil
+	}
+
+	query := `
+		INSERT INTO product_slugs (slug, product_id, replaced_at) VALUES ($1, $2, $3)
+		ON CONFLICT (slug) DO UPDATE SET product_id = excluded.product_id, replaced_at = excluded.replaced_at
+	`
+	if _, err := db.Conn(ctx).ExecContext(ctx, query, old, id, time.Now()); err != nil {
+		return fmt.Errorf("failed to update product slug history: %w", err)
+	}
+	return nil
+}
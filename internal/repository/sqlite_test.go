@@ -0,0 +1,1275 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// setupSQLiteDB returns a migrated SQLite database in a temporary file. SQLite
+// is embedded, so these tests run without a database server.
+func setupSQLiteDB(t *testing.T) *database.DB {
+	t.Helper()
+
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(t.TempDir(), "test.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	return db
+}
+
+func TestScannedTablesMatchSQLiteMigrations(t *testing.T) {
+	db := setupSQLiteDB(t)
+
+	if err := db.VerifySchema(context.Background(), ScannedTables); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLite_ProductRepository(t *testing.T) {
+	db := setupSQLiteDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	product := &models.Product{SKU: "SQL-1", Name: "Embedded", Category: "books", Tags: []string{"new", "sale"}, Quantity: 5, UnitPrice: 9.99}
+	if err := repo.Create(ctx, product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	if err := repo.Create(ctx, &models.Product{SKU: "SQL-1", Name: "Duplicate"}); err == nil {
+		t.Error("expected a duplicate SKU to be rejected")
+	}
+
+	got, err := repo.GetBySKU(ctx, "SQL-1")
+	if err != nil {
+		t.Fatalf("failed to get product: %v", err)
+	}
+	if got.ID != product.ID || got.UnitPrice != 9.99 || got.Category != "books" || !got.CreatedAt.Equal(product.CreatedAt) {
+		t.Errorf("got %+v, want %+v", got, product)
+	}
+	if !reflect.DeepEqual(got.Tags, []string{"new", "sale"}) {
+		t.Errorf("Tags = %v, want [new sale]", got.Tags)
+	}
+
+	product.Name = "Renamed"
+	product.Tags = []string{"sale"}
+	if err := repo.Update(ctx, product); err != nil {
+		t.Fatalf("failed to update product: %v", err)
+	}
+	if got, _ := repo.GetByID(ctx, product.ID); got.Name != "Renamed" || !reflect.DeepEqual(got.Tags, []string{"sale"}) {
+		t.Errorf("Name = %q, Tags = %v after update", got.Name, got.Tags)
+	}
+
+	adjusted, err := repo.AdjustStock(ctx, product.ID, -3)
+	if err != nil || adjusted.Quantity != 2 {
+		t.Fatalf("AdjustStock = %+v, %v; want quantity 2", adjusted, err)
+	}
+	if _, err := repo.AdjustStock(ctx, product.ID, -3); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("AdjustStock below zero = %v, want ErrInsufficientStock", err)
+	}
+
+	inserted, err := repo.BulkCreate(ctx, []*models.Product{{SKU: "SQL-2", Name: "Two", Tags: []string{"sale"}}, {SKU: "SQL-3", Name: "Three"}})
+	if err != nil || inserted != 2 {
+		t.Fatalf("BulkCreate = %d, %v", inserted, err)
+	}
+	if got, _ := repo.GetBySKU(ctx, "SQL-2"); len(got.Tags) != 1 {
+		t.Errorf("bulk created product has tags %v, want [sale]", got.Tags)
+	}
+
+	count, err := repo.Count(ctx)
+	if err != nil || count != 3 {
+		t.Errorf("Count = %d, %v; want 3", count, err)
+	}
+	list, err := repo.List(ctx, 2, 0)
+	if err != nil || len(list) != 2 {
+		t.Errorf("List = %d products, %v; want 2", len(list), err)
+	}
+	list, total, err := repo.ListWithCount(ctx, 2, 0)
+	if err != nil || len(list) != 2 || total != 3 {
+		t.Errorf("ListWithCount = %d products of %d, %v; want 2 of 3", len(list), total, err)
+	}
+	filter := models.ProductFilter{Tags: []string{"sale"}}
+	if list, total, err := repo.ListFilteredWithCount(ctx, filter, 10, 5); err != nil || len(list) != 0 || total != 2 {
+		t.Errorf("ListFilteredWithCount past the end = %d products of %d, %v; want 0 of 2", len(list), total, err)
+	}
+	since, err := repo.ListUpdatedSince(ctx, product.CreatedAt.Add(-time.Second))
+	if err != nil || len(since) != 3 || since[2].SKU == "SQL-1" {
+		t.Errorf("ListUpdatedSince = %d products, %v; want 3, the adjusted one last", len(since), err)
+	}
+
+	if err := repo.Delete(ctx, product.ID); err != nil {
+		t.Fatalf("failed to delete product: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, product.ID); err == nil || err.Error() != "product not found" {
+		t.Errorf("GetByID after delete = %v, want not found", err)
+	}
+}
+
+func TestSQLite_Related(t *testing.T) {
+	db := setupSQLiteDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	later := time.Now().Add(time.Hour)
+	products := []*models.Product{
+		{SKU: "REL-0", Name: "Source", Category: "books", Tags: []string{"fantasy", "sale", "new"}, UnitPrice: 10},
+		{SKU: "REL-6", Name: "Not yet visible", Category: "books", Tags: []string{"fantasy", "sale", "new"}, UnitPrice: 10, VisibleFrom: &later},
+		{SKU: "REL-1", Name: "Two tags", Category: "games", Tags: []string{"fantasy", "sale"}, UnitPrice: 50},
+		{SKU: "REL-2", Name: "Category and price", Category: "books", UnitPrice: 11},
+		{SKU: "REL-3", Name: "Category only", Category: "books", UnitPrice: 30},
+		{SKU: "REL-4", Name: "One tag", Tags: []string{"new"}, UnitPrice: 99},
+		{SKU: "REL-5", Name: "Unrelated", Category: "garden", Tags: []string{"outdoor"}, UnitPrice: 10},
+	}
+	for _, p := range products {
+		if err := repo.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	related, err := repo.Related(ctx, products[0].ID, 10, 0.2, "", time.Now())
+	if err != nil {
+		t.Fatalf("Related: %v", err)
+	}
+	var skus []string
+	for _, r := range related {
+		skus = append(skus, r.SKU)
+	}
+	// REL-1 and REL-2 tie on 2, and REL-1 was created first
+	if want := []string{"REL-1", "REL-2", "REL-3", "REL-4"}; !reflect.DeepEqual(skus, want) {
+		t.Fatalf("Related = %v, want %v", skus, want)
+	}
+	if r := related[1]; r.SharedTags != 0 || !r.SameCategory || !r.SamePriceBand || r.Score != 2 {
+		t.Errorf("REL-2 = %+v, want same category and price band", r)
+	}
+	if r := related[0]; r.SharedTags != 2 || r.SameCategory || r.Score != 2 {
+		t.Errorf("REL-1 = %+v, want two shared tags", r)
+	}
+
+	if limited, err := repo.Related(ctx, products[0].ID, 1, 0.2, "", time.Now()); err != nil || len(limited) != 1 {
+		t.Errorf("Related with limit 1 = %d products, %v", len(limited), err)
+	}
+
+	// Products limited to other regions aren't related in a region
+	if err := NewProductRegionRepository(db).Add(ctx, []int{products[3].ID}, []string{"FR"}); err != nil {
+		t.Fatalf("failed to add region: %v", err)
+	}
+	related, err = repo.Related(ctx, products[0].ID, 10, 0.2, "DE", time.Now())
+	if err != nil {
+		t.Fatalf("Related in DE: %v", err)
+	}
+	skus = nil
+	for _, r := range related {
+		skus = append(skus, r.SKU)
+	}
+	if want := []string{"REL-1", "REL-3", "REL-4"}; !reflect.DeepEqual(skus, want) {
+		t.Errorf("Related in DE = %v, want %v", skus, want)
+	}
+}
+
+func TestSQLite_Sample(t *testing.T) {
+	db := setupSQLiteDB(t)
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		p := &models.Product{SKU: fmt.Sprintf("SMP-%d", i), Name: "Sampled", Category: "even", Tags: []string{"qa"}, Quantity: i}
+		if i%2 == 1 {
+			p.Category = "odd"
+		}
+		if err := repo.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		sample, err := repo.Sample(ctx, models.ProductFilter{Category: "odd"}, 5)
+		if err != nil || len(sample) != 5 {
+			t.Fatalf("Sample = %d products, %v; want 5", len(sample), err)
+		}
+		for _, p := range sample {
+			if p.Category != "odd" || len(p.Tags) != 1 {
+				t.Fatalf("sampled %+v, want an odd product with its tags", p)
+			}
+			seen[p.SKU] = true
+		}
+	}
+	// Ten samples of 5 of 10 products all being the same 5 is one in 252^9
+	if len(seen) <= 5 {
+		t.Errorf("samples covered %d products, want them to vary", len(seen))
+	}
+
+	if sample, err := repo.Sample(ctx, models.ProductFilter{}, 50); err != nil || len(sample) != 20 {
+		t.Errorf("Sample of more than there are = %d products, %v; want all 20", len(sample), err)
+	}
+}
+
+func TestSQLite_Bundles(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	bundles := NewBundleRepository(db)
+	ctx := context.Background()
+
+	var ids []int
+	for _, p := range []*models.Product{
+		{SKU: "KIT", Name: "Kit"},
+		{SKU: "PART-A", Name: "A", Quantity: 10},
+		{SKU: "PART-B", Name: "B", Quantity: 7},
+		{SKU: "KIT-2", Name: "Kit of kits"},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+		ids = append(ids, p.ID)
+	}
+	kit, a, b, kit2 := ids[0], ids[1], ids[2], ids[3]
+
+	if err := bundles.Set(ctx, kit, []models.BundleComponent{{ProductID: a, Quantity: 1}, {ProductID: b, Quantity: 2}}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	bundle, err := bundles.Get(ctx, kit, "")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	// B limits it: 7 units make 3 kits
+	if bundle.SKU != "KIT" || len(bundle.Components) != 2 || bundle.Components[1].SKU != "PART-B" || bundle.Available != 3 {
+		t.Errorf("Get = %+v, want KIT of A and B with 3 available", bundle)
+	}
+
+	if err := bundles.Set(ctx, kit2, []models.BundleComponent{{ProductID: kit, Quantity: 1}}); !errors.Is(err, ErrNestedBundle) {
+		t.Errorf("Set with a bundle as component = %v, want ErrNestedBundle", err)
+	}
+	if err := bundles.Set(ctx, a, []models.BundleComponent{{ProductID: b, Quantity: 1}}); !errors.Is(err, ErrNestedBundle) {
+		t.Errorf("Set on a component = %v, want ErrNestedBundle", err)
+	}
+	if err := bundles.Set(ctx, kit2, []models.BundleComponent{{ProductID: 999, Quantity: 1}}); !errors.Is(err, ErrUnknownComponent) {
+		t.Errorf("Set with an unknown component = %v, want ErrUnknownComponent", err)
+	}
+	if err := products.Delete(ctx, b); !errors.Is(err, ErrInBundle) {
+		t.Errorf("Delete of a component = %v, want ErrInBundle", err)
+	}
+
+	if list, err := bundles.List(ctx, "", 10, 0); err != nil || len(list) != 1 {
+		t.Errorf("List = %d bundles, %v; want 1", len(list), err)
+	}
+	if err := NewProductRegionRepository(db).Add(ctx, []int{kit}, []string{"FR"}); err != nil {
+		t.Fatalf("failed to limit the kit to FR: %v", err)
+	}
+	if list, err := bundles.List(ctx, "DE", 10, 0); err != nil || len(list) != 0 {
+		t.Errorf("List(DE) = %d bundles, %v; want none sold in DE", len(list), err)
+	}
+	if count, err := bundles.Count(ctx, "FR"); err != nil || count != 1 {
+		t.Errorf("Count(FR) = %d, %v; want 1", count, err)
+	}
+	if _, err := bundles.Get(ctx, kit, "DE"); err == nil || err.Error() != "bundle not found" {
+		t.Errorf("Get(DE) of a kit sold in FR = %v, want not found", err)
+	}
+	if components, err := bundles.Components(ctx, a); err != nil || len(components) != 0 {
+		t.Errorf("Components of a plain product = %v, %v", components, err)
+	}
+
+	if err := bundles.Delete(ctx, kit); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := bundles.Get(ctx, kit, ""); err == nil || err.Error() != "bundle not found" {
+		t.Errorf("Get after delete = %v, want not found", err)
+	}
+	if err := products.Delete(ctx, b); err != nil {
+		t.Errorf("Delete of a former component: %v", err)
+	}
+}
+
+func TestSQLite_ProductHistory(t *testing.T) {
+	db := setupSQLiteDB(t)
+	repo := NewProductRepository(db)
+	movements := NewStockMovementRepository(db)
+	ctx := context.Background()
+
+	product := &models.Product{SKU: "HIST-1", Name: "Versioned", Category: "tools", Quantity: 5}
+	if err := repo.Create(ctx, product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	afterCreate := time.Now()
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := repo.AdjustStock(ctx, product.ID, -2); err != nil {
+		t.Fatalf("failed to adjust stock: %v", err)
+	}
+
+	past, err := repo.GetByIDAsOf(ctx, product.ID, afterCreate)
+	if err != nil || past.Quantity != 5 || past.Category != "tools" {
+		t.Errorf("GetByIDAsOf = %+v, %v; want quantity 5 in tools", past, err)
+	}
+
+	versions, err := repo.History(ctx, product.ID, 10)
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("History = %d versions, %v; want 2", len(versions), err)
+	}
+	if versions[0].Operation != "UPDATE" || versions[0].ValidTo != nil || versions[1].ValidTo == nil {
+		t.Errorf("versions = %+v, %+v", versions[0], versions[1])
+	}
+
+	from := database.MonthStart(time.Now())
+	list, err := movements.ListByProduct(ctx, product.ID, from, from.AddDate(0, 1, 0), 10)
+	if err != nil || len(list) != 2 || list[0].Delta != -2 {
+		t.Errorf("ListByProduct = %+v, %v; want 2 movements, latest -2", list, err)
+	}
+}
+
+func TestSQLite_SKUCounter(t *testing.T) {
+	db := setupSQLiteDB(t)
+	counter := NewSKUCounterRepository(db)
+	ctx := context.Background()
+
+	if n, err := counter.Peek(ctx, "A-"); err != nil || n != 1 {
+		t.Errorf("Peek of a new scope = %d, %v; want 1", n, err)
+	}
+	for want := int64(1); want <= 2; want++ {
+		if n, err := counter.Next(ctx, "A-"); err != nil || n != want {
+			t.Errorf("Next = %d, %v; want %d", n, err, want)
+		}
+	}
+	if n, err := counter.Next(ctx, "B-"); err != nil || n != 1 {
+		t.Errorf("Next of another scope = %d, %v; want 1", n, err)
+	}
+
+	// A rolled back create gives its number back
+	errRollback := errors.New("rollback")
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		if n, err := counter.Next(ctx, "A-"); err != nil || n != 3 {
+			t.Errorf("Next in a transaction = %d, %v; want 3", n, err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx = %v", err)
+	}
+	if n, err := counter.Peek(ctx, "A-"); err != nil || n != 3 {
+		t.Errorf("Peek after a rollback = %d, %v; want 3", n, err)
+	}
+}
+
+func TestSQLite_ExportRepository(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	repo := NewExportRepository(db)
+	ctx := context.Background()
+
+	for _, p := range []*models.Product{
+		{SKU: "EX-1", Name: "Atlas", Category: "Books", Tags: []string{"sale"}},
+		{SKU: "EX-2", Name: "Hammer", Category: "tools", Tags: []string{"sale"}},
+		{SKU: "EX-3", Name: "Novel", Category: "books"},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	tests := []struct {
+		filter models.ExportFilter
+		want   []string
+	}{
+		{models.ExportFilter{}, []string{"EX-1", "EX-2", "EX-3"}},
+		{models.ExportFilter{Category: "BOOKS"}, []string{"EX-1", "EX-3"}},
+		{models.ExportFilter{Category: "books", Tag: "Sale"}, []string{"EX-1"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		before := time.Now()
+		snapshotAt, err := repo.EachProduct(ctx, tt.filter, func(p *models.Product) error {
+			got = append(got, p.SKU)
+			return nil
+		})
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EachProduct(%+v) = %v, %v; want %v", tt.filter, got, err, tt.want)
+		}
+		if snapshotAt.Before(before) || snapshotAt.After(time.Now()) {
+			t.Errorf("EachProduct(%+v) snapshot at %v, not during the call", tt.filter, snapshotAt)
+		}
+		if n, err := repo.CountProducts(ctx, tt.filter); err != nil || n != len(tt.want) {
+			t.Errorf("CountProducts(%+v) = %d, %v; want %d", tt.filter, n, err, len(tt.want))
+		}
+	}
+
+	run := &models.ExportRun{TriggeredBy: models.ExportManual, Format: models.ExportCSV, ExportFilter: models.ExportFilter{Tag: "sale"}}
+	if err := repo.Create(ctx, run); err != nil {
+		t.Fatalf("failed to create run: %v", err)
+	}
+	run.Status, run.Key, run.URL, run.Rows, run.Bytes = models.ExportSucceeded, "exports/a.csv.gz", "file:///exports/a.csv.gz", 2, 120
+	snapshotAt := time.Date(2026, 10, 14, 2, 0, 1, 0, time.UTC)
+	run.SnapshotAt = &snapshotAt
+	if err := repo.Finish(ctx, run); err != nil {
+		t.Fatalf("failed to finish run: %v", err)
+	}
+	interrupted := &models.ExportRun{TriggeredBy: models.ExportScheduled, Format: models.ExportParquet}
+	if err := repo.Create(ctx, interrupted); err != nil {
+		t.Fatalf("failed to create run: %v", err)
+	}
+
+	if n, err := repo.FailInterrupted(ctx); err != nil || n != 1 {
+		t.Errorf("FailInterrupted = %d, %v; want 1", n, err)
+	}
+	got, err := repo.GetByID(ctx, run.ID)
+	if err != nil || got.Status != models.ExportSucceeded || got.Key != run.Key || got.Rows != 2 || got.Tag != "sale" || got.FinishedAt == nil || got.SnapshotAt == nil || !got.SnapshotAt.Equal(snapshotAt) {
+		t.Errorf("GetByID = %+v, %v", got, err)
+	}
+	runs, err := repo.List(ctx, 10, 0)
+	if err != nil || len(runs) != 2 || runs[0].ID != interrupted.ID || runs[0].Status != models.ExportFailed || runs[0].Error == nil {
+		t.Errorf("List = %+v, %v; want the interrupted run first, failed", runs, err)
+	}
+	if _, err := repo.GetByID(ctx, 999); err == nil || err.Error() != "export run not found" {
+		t.Errorf("GetByID of a missing run = %v", err)
+	}
+}
+
+func TestSQLite_OperationRepository(t *testing.T) {
+	db := setupSQLiteDB(t)
+	repo := NewOperationRepository(db)
+	ctx := context.Background()
+
+	op := &models.Operation{Kind: models.OperationExport}
+	if err := repo.Create(ctx, op); err != nil {
+		t.Fatalf("failed to create operation: %v", err)
+	}
+	if err := repo.SetProgress(ctx, op.ID, 40, "2 of 5 products exported"); err != nil {
+		t.Fatalf("SetProgress: %v", err)
+	}
+	got, err := repo.GetByID(ctx, op.ID)
+	if err != nil || got.Status != models.OperationRunning || got.Progress != 40 || got.Checkpoint != "2 of 5 products exported" || got.Done() {
+		t.Errorf("GetByID = %+v, %v; want running at 40%%", got, err)
+	}
+	if requested, err := repo.CancelRequested(ctx, op.ID); err != nil || requested {
+		t.Errorf("CancelRequested = %v, %v; want false", requested, err)
+	}
+
+	op.Status, op.Progress, op.Result = models.OperationSucceeded, 100, "/api/v1/admin/exports/1"
+	if err := repo.Finish(ctx, op); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if err := repo.SetProgress(ctx, op.ID, 50, ""); err != nil {
+		t.Fatalf("SetProgress: %v", err)
+	}
+	if got, err := repo.RequestCancel(ctx, op.ID); err == nil || err.Error() != "operation has finished" || got.Status != models.OperationSucceeded {
+		t.Errorf("RequestCancel of a finished operation = %+v, %v", got, err)
+	}
+
+	cancelled := &models.Operation{Kind: models.OperationReindex}
+	if err := repo.Create(ctx, cancelled); err != nil {
+		t.Fatalf("failed to create operation: %v", err)
+	}
+	requested, err := repo.RequestCancel(ctx, cancelled.ID)
+	if err != nil || requested.CancelRequestedAt == nil || requested.Status != models.OperationRunning {
+		t.Fatalf("RequestCancel = %+v, %v; want running, asked to stop", requested, err)
+	}
+	if again, err := repo.RequestCancel(ctx, cancelled.ID); err != nil || !again.CancelRequestedAt.Equal(*requested.CancelRequestedAt) {
+		t.Errorf("RequestCancel again = %+v, %v; want the first request kept", again, err)
+	}
+	if requested, err := repo.CancelRequested(ctx, cancelled.ID); err != nil || !requested {
+		t.Errorf("CancelRequested = %v, %v; want true", requested, err)
+	}
+	cancelled.Status, cancelled.Checkpoint = models.OperationCancelled, "500 of 900 products indexed"
+	if err := repo.Finish(ctx, cancelled); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if _, err := repo.RequestCancel(ctx, 999); err == nil || err.Error() != "operation not found" {
+		t.Errorf("RequestCancel of a missing operation = %v", err)
+	}
+
+	interrupted := &models.Operation{Kind: models.OperationReindex}
+	if err := repo.Create(ctx, interrupted); err != nil {
+		t.Fatalf("failed to create operation: %v", err)
+	}
+
+	if n, err := repo.FailInterrupted(ctx); err != nil || n != 1 {
+		t.Errorf("FailInterrupted = %d, %v; want 1", n, err)
+	}
+	got, err = repo.GetByID(ctx, op.ID)
+	if err != nil || got.Status != models.OperationSucceeded || got.Progress != 100 || got.Result != op.Result || got.FinishedAt == nil {
+		t.Errorf("GetByID = %+v, %v; want succeeded, unchanged by later progress", got, err)
+	}
+	got, err = repo.GetByID(ctx, interrupted.ID)
+	if err != nil || got.Status != models.OperationFailed || got.Error == nil || *got.Error != "interrupted by a restart" {
+		t.Errorf("GetByID = %+v, %v; want failed by the restart", got, err)
+	}
+	got, err = repo.GetByID(ctx, cancelled.ID)
+	if err != nil || got.Status != models.OperationCancelled || got.Checkpoint != cancelled.Checkpoint || got.FinishedAt == nil {
+		t.Errorf("GetByID = %+v, %v; want cancelled at its checkpoint", got, err)
+	}
+	if _, err := repo.GetByID(ctx, 999); err == nil || err.Error() != "operation not found" {
+		t.Errorf("GetByID of a missing operation = %v", err)
+	}
+}
+
+func TestSQLite_SagaRepository(t *testing.T) {
+	db := setupSQLiteDB(t)
+	repo := NewSagaRepository(db)
+	ctx := context.Background()
+
+	until := time.Now().Add(time.Minute)
+	saga := &models.Saga{Name: "order-fulfillment", Key: "1001", Status: models.SagaRunning, Data: json.RawMessage(`{"input":{"sku":"A-1"}}`), ClaimedBy: "a", ClaimedUntil: &until}
+	if created, err := repo.Create(ctx, saga); err != nil || !created {
+		t.Fatalf("Create = %v, %v; want created", created, err)
+	}
+	duplicate := &models.Saga{Name: "order-fulfillment", Key: "1001", Status: models.SagaRunning, Data: json.RawMessage(`{}`)}
+	if created, err := repo.Create(ctx, duplicate); err != nil || created {
+		t.Errorf("Create of a duplicate = %v, %v; want not created", created, err)
+	}
+
+	// Claimed by a: b can't claim it or save it
+	if claimed, err := repo.Claim(ctx, saga.ID, "b", until); err != nil || claimed {
+		t.Errorf("Claim by b = %v, %v; want false", claimed, err)
+	}
+	if sagas, err := repo.ListInterrupted(ctx, 10); err != nil || len(sagas) != 0 {
+		t.Errorf("ListInterrupted = %v, %v; want none while claimed", sagas, err)
+	}
+	saga.Step, saga.Data = 1, json.RawMessage(`{"input":{"sku":"A-1"},"reservation":7}`)
+	if err := repo.Save(ctx, saga); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	stolen := *saga
+	stolen.ClaimedBy = "b"
+	if err := repo.Save(ctx, &stolen); err == nil || err.Error() != "saga claimed elsewhere" {
+		t.Errorf("Save by b = %v, want saga claimed elsewhere", err)
+	}
+
+	// Released, as on shutdown: b resumes it
+	if err := repo.Release(ctx, saga); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	sagas, err := repo.ListInterrupted(ctx, 10)
+	if err != nil || len(sagas) != 1 || sagas[0].Step != 1 || sagas[0].ClaimedUntil != nil {
+		t.Fatalf("ListInterrupted = %+v, %v; want the released saga", sagas, err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(sagas[0].Data, &data); err != nil || data["reservation"] != float64(7) {
+		t.Errorf("data = %s, %v; want the reservation recorded", sagas[0].Data, err)
+	}
+	if claimed, err := repo.Claim(ctx, saga.ID, "b", until); err != nil || !claimed {
+		t.Fatalf("Claim by b = %v, %v; want true", claimed, err)
+	}
+	if claimed, err := repo.Claim(ctx, saga.ID, "b", until.Add(time.Minute)); err != nil || !claimed {
+		t.Errorf("Claim renewed by b = %v, %v; want true", claimed, err)
+	}
+	if err := repo.Save(ctx, saga); err == nil || err.Error() != "saga claimed elsewhere" {
+		t.Errorf("Save by a = %v, want saga claimed elsewhere", err)
+	}
+
+	// A lapsed claim is listed and claimable
+	lapsed := time.Now().Add(-time.Second)
+	if claimed, err := repo.Claim(ctx, saga.ID, "b", lapsed); err != nil || !claimed {
+		t.Fatalf("Claim by b = %v, %v; want true", claimed, err)
+	}
+	if sagas, err := repo.ListInterrupted(ctx, 10); err != nil || len(sagas) != 1 {
+		t.Errorf("ListInterrupted = %v, %v; want the saga with a lapsed claim", sagas, err)
+	}
+	if claimed, err := repo.Claim(ctx, saga.ID, "a", until); err != nil || !claimed {
+		t.Fatalf("Claim by a = %v, %v; want true once b's claim lapsed", claimed, err)
+	}
+
+	msg := "push: connector unavailable"
+	saga.Status, saga.Step, saga.Error = models.SagaCompensated, 0, &msg
+	if err := repo.Save(ctx, saga); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := repo.GetByKey(ctx, "order-fulfillment", "1001")
+	if err != nil || got.ID != saga.ID || got.Status != models.SagaCompensated || got.Error == nil || *got.Error != msg || got.FinishedAt == nil || !got.Done() {
+		t.Errorf("GetByKey = %+v, %v; want compensated with its error", got, err)
+	}
+	if claimed, err := repo.Claim(ctx, saga.ID, "a", until); err != nil || claimed {
+		t.Errorf("Claim of a finished saga = %v, %v; want false", claimed, err)
+	}
+	if sagas, err := repo.ListInterrupted(ctx, 10); err != nil || len(sagas) != 0 {
+		t.Errorf("ListInterrupted = %v, %v; want none once finished", sagas, err)
+	}
+	if _, err := repo.GetByID(ctx, 999); err == nil || err.Error() != "saga not found" {
+		t.Errorf("GetByID of a missing saga = %v", err)
+	}
+}
+
+func TestSQLite_SearchRepository(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	repo := NewSearchRepository(db)
+	ctx := context.Background()
+
+	for _, p := range []*models.Product{
+		{SKU: "BK-1", Name: "Atlas of the World", Description: "Maps, 100% detailed", Category: "books", Tags: []string{"maps"}},
+		{SKU: "BK-2", Name: "Road Atlas", Category: "books"},
+		{SKU: "TL-1", Name: "Hammer", Description: "For the world's nails", Category: "tools"},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		query models.SearchQuery
+		want  []string
+	}{
+		{models.SearchQuery{Text: "atlas"}, []string{"BK-1", "BK-2"}},
+		{models.SearchQuery{Text: "ATLAS world"}, []string{"BK-1"}},
+		{models.SearchQuery{Text: "world"}, []string{"BK-1", "TL-1"}},
+		{models.SearchQuery{Text: "100%"}, []string{"BK-1"}},
+		{models.SearchQuery{Text: "0_"}, nil},
+		{models.SearchQuery{Category: "Books", Tag: "MAPS"}, []string{"BK-1"}},
+		{models.SearchQuery{Text: "atlas", Limit: 1, Offset: 1}, []string{"BK-2"}},
+	} {
+		if tc.query.Limit == 0 {
+			tc.query.Limit = 10
+		}
+		found, total, err := repo.Search(ctx, tc.query)
+		if err != nil {
+			t.Fatalf("Search(%+v): %v", tc.query, err)
+		}
+		var skus []string
+		for _, p := range found {
+			skus = append(skus, p.SKU)
+		}
+		if !reflect.DeepEqual(skus, tc.want) || (tc.query.Offset == 0 && total != len(tc.want)) {
+			t.Errorf("Search(%+v) = %v of %d, want %v", tc.query, skus, total, tc.want)
+		}
+		if len(found) > 0 && found[0].SKU == "BK-1" && !reflect.DeepEqual(found[0].Tags, []string{"maps"}) {
+			t.Errorf("Search(%+v) tags = %v, want loaded", tc.query, found[0].Tags)
+		}
+	}
+
+	if state, err := repo.IndexState(ctx, "products"); err != nil || state != nil {
+		t.Errorf("IndexState before a build = %+v, %v; want nil", state, err)
+	}
+	state := &models.SearchIndexState{Name: "products", IndexName: "products-1", Cursor: "c1", ReindexedAt: time.Now()}
+	if err := repo.SaveIndexState(ctx, state); err != nil {
+		t.Fatalf("failed to save index state: %v", err)
+	}
+	if ok, err := repo.AdvanceCursor(ctx, "products", "c1", "c2"); err != nil || !ok {
+		t.Errorf("AdvanceCursor(c1, c2) = %v, %v", ok, err)
+	}
+	if ok, _ := repo.AdvanceCursor(ctx, "products", "c1", "c3"); ok {
+		t.Error("AdvanceCursor from a stale cursor succeeded")
+	}
+	got, err := repo.IndexState(ctx, "products")
+	if err != nil || got.IndexName != "products-1" || got.Cursor != "c2" || got.SyncedAt == nil {
+		t.Errorf("IndexState = %+v, %v; want cursor c2, synced", got, err)
+	}
+}
+
+func TestSQLite_Suggest(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	repo := NewSearchRepository(db)
+	ctx := context.Background()
+
+	later := time.Now().Add(time.Hour)
+	for _, p := range []*models.Product{
+		{SKU: "CH-2", Name: "Office chair"},
+		{SKU: "TB-1", Name: "Chart table"},
+		{SKU: "CH-1", Name: "Armchair"},
+		{SKU: "LP-1", Name: "100% lamp"},
+		{SKU: "CH-3", Name: "Chaise longue", VisibleFrom: &later},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	if err := NewProductRegionRepository(db).Add(ctx, []int{1}, []string{"FR"}); err != nil {
+		t.Fatalf("failed to add region: %v", err)
+	}
+
+	for _, tc := range []struct {
+		prefix string
+		region string
+		limit  int
+		want   []string
+	}{
+		// Starts of SKUs and names first, then starts of later words; CH-3
+		// isn't visible yet
+		{"CH", "", 10, []string{"CH-1", "TB-1", "CH-2"}},
+		{" cha ", "", 10, []string{"TB-1", "CH-2"}},
+		{"ch", "", 1, []string{"CH-1"}},
+		{"100%", "", 10, []string{"LP-1"}},
+		{"1_", "", 10, nil},
+		// CH-2 is only sold in FR
+		{"CH", "DE", 10, []string{"CH-1", "TB-1"}},
+		{"CH", "FR", 10, []string{"CH-1", "TB-1", "CH-2"}},
+	} {
+		found, err := repo.Suggest(ctx, models.SuggestQuery{Prefix: tc.prefix, Region: tc.region, Visible: time.Now(), Limit: tc.limit})
+		if err != nil {
+			t.Fatalf("Suggest(%q): %v", tc.prefix, err)
+		}
+		var skus []string
+		for _, s := range found {
+			skus = append(skus, s.SKU)
+		}
+		if !reflect.DeepEqual(skus, tc.want) {
+			t.Errorf("Suggest(%q) = %v, want %v", tc.prefix, skus, tc.want)
+		}
+		if len(found) > 0 && (found[0].ID == 0 || found[0].Name == "") {
+			t.Errorf("Suggest(%q) = %+v, want the ID and name", tc.prefix, found[0])
+		}
+	}
+}
+
+func TestSQLite_ListFiltered(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	ctx := context.Background()
+
+	for _, p := range []*models.Product{
+		{SKU: "BK-1", Name: "Atlas", Category: "books", UnitPrice: 20, Quantity: 3, Tags: []string{"maps", "sale"}},
+		{SKU: "BK-2", Name: "Cookbook", Category: "Books", UnitPrice: 8, Tags: []string{"sale"}},
+		{SKU: "TL-1", Name: "Hammer", Category: "tools", UnitPrice: 8, Quantity: 1},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	price := func(v float64) *float64 { return &v }
+	inStock := true
+	for _, tc := range []struct {
+		filter models.ProductFilter
+		want   []string
+	}{
+		{models.ProductFilter{}, []string{"TL-1", "BK-2", "BK-1"}},
+		{models.ProductFilter{Category: "BOOKS", Tags: []string{"Sale"}, Sort: "name"}, []string{"BK-1", "BK-2"}},
+		{models.ProductFilter{Tags: []string{"sale", "maps"}}, []string{"BK-1"}},
+		{models.ProductFilter{MinPrice: price(5), MaxPrice: price(10), Sort: "unit_price", Order: "desc"}, []string{"TL-1", "BK-2"}},
+		{models.ProductFilter{InStock: &inStock, Sort: "quantity"}, []string{"TL-1", "BK-1"}},
+	} {
+		found, err := products.ListFiltered(ctx, tc.filter, 10, 0)
+		if err != nil {
+			t.Fatalf("ListFiltered(%+v): %v", tc.filter, err)
+		}
+		var skus []string
+		for _, p := range found {
+			skus = append(skus, p.SKU)
+		}
+		total, err := products.CountFiltered(ctx, tc.filter)
+		if err != nil || !reflect.DeepEqual(skus, tc.want) || total != len(tc.want) {
+			t.Errorf("ListFiltered(%+v) = %v of %d (%v), want %v", tc.filter, skus, total, err, tc.want)
+		}
+	}
+}
+
+func TestSQLite_ProductRegions(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	regions := NewProductRegionRepository(db)
+	ctx := context.Background()
+
+	var ids []int
+	for _, sku := range []string{"DE-1", "AT-1", "ANY-1"} {
+		p := &models.Product{SKU: sku, Name: sku, UnitPrice: 1}
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+		ids = append(ids, p.ID)
+	}
+	if err := regions.Add(ctx, ids[:2], []string{"DE"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := regions.Set(ctx, ids[1:2], []string{"CH", "AT"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	got, err := products.GetByID(ctx, ids[1])
+	if err != nil || !reflect.DeepEqual(got.Regions, []string{"AT", "CH"}) {
+		t.Errorf("Regions = %v (%v), want [AT CH]", got.Regions, err)
+	}
+
+	of, err := regions.Of(ctx, ids)
+	if want := map[int][]string{ids[0]: {"DE"}, ids[1]: {"AT", "CH"}}; err != nil || !reflect.DeepEqual(of, want) {
+		t.Errorf("Of = %v (%v), want %v", of, err, want)
+	}
+
+	found, err := products.ListFiltered(ctx, models.ProductFilter{Region: "de", Sort: "sku"}, 10, 0)
+	if err != nil || len(found) != 2 || found[0].SKU != "ANY-1" || found[1].SKU != "DE-1" {
+		t.Errorf("ListFiltered(region DE) = %v (%v), want ANY-1 and DE-1", found, err)
+	}
+
+	if err := regions.Remove(ctx, ids[:1], []string{"DE"}); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	counts, err := regions.Counts(ctx)
+	if err != nil || len(counts) != 2 || counts[0].Region != "AT" || counts[1].Region != "CH" || counts[0].Products != 1 {
+		t.Errorf("Counts = %v (%v), want AT and CH with 1 product each", counts, err)
+	}
+}
+
+func TestSQLite_ProductVisibility(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	visibility := NewProductVisibilityRepository(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	past, soon, later := now.Add(-time.Hour), now.Add(time.Hour), now.Add(48*time.Hour)
+	windows := map[string][2]*time.Time{
+		"ALWAYS":   {nil, nil},
+		"OPEN":     {&past, &later},
+		"CLOSING":  {nil, &soon},
+		"UPCOMING": {&soon, nil},
+		"ENDED":    {nil, &past},
+	}
+	ids := make(map[string]int)
+	for _, sku := range []string{"ALWAYS", "OPEN", "CLOSING", "UPCOMING", "ENDED"} {
+		p := &models.Product{SKU: sku, Name: sku, UnitPrice: 1, VisibleFrom: windows[sku][0], VisibleUntil: windows[sku][1]}
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+		ids[sku] = p.ID
+	}
+
+	for _, tt := range []struct {
+		filter models.ProductFilter
+		want   []string
+	}{
+		{models.ProductFilter{Visibility: models.VisibilityVisible}, []string{"ALWAYS", "CLOSING", "OPEN"}},
+		{models.ProductFilter{Visibility: models.VisibilityHidden}, []string{"ENDED", "UPCOMING"}},
+		{models.ProductFilter{Visibility: models.VisibilityScheduled}, []string{"UPCOMING"}},
+		{models.ProductFilter{Visibility: models.VisibilityExpired}, []string{"ENDED"}},
+		{models.ProductFilter{ExpiringWithin: "2h"}, []string{"CLOSING"}},
+	} {
+		tt.filter.Sort = "sku"
+		found, err := products.ListFiltered(ctx, tt.filter, 10, 0)
+		if err != nil {
+			t.Fatalf("ListFiltered(%+v): %v", tt.filter, err)
+		}
+		var skus []string
+		for _, p := range found {
+			skus = append(skus, p.SKU)
+		}
+		if !reflect.DeepEqual(skus, tt.want) {
+			t.Errorf("ListFiltered(%+v) = %v, want %v", tt.filter, skus, tt.want)
+		}
+	}
+
+	// Created outside their window, they were never announced as listed
+	pending, err := visibility.Pending(ctx, now, 10)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("Pending(now) = %v (%v), want none", pending, err)
+	}
+	pending, err = visibility.Pending(ctx, now.Add(2*time.Hour), 10)
+	if err != nil || !reflect.DeepEqual(pending, []int{ids["CLOSING"], ids["UPCOMING"]}) {
+		t.Fatalf("Pending(in 2h) = %v (%v), want CLOSING and UPCOMING", pending, err)
+	}
+	if err := visibility.SetListed(ctx, ids["CLOSING"], false); err != nil {
+		t.Fatalf("SetListed: %v", err)
+	}
+	if p, _ := products.GetByID(ctx, ids["CLOSING"]); p.Listed {
+		t.Errorf("Listed = true after SetListed(false)")
+	}
+	if err := visibility.SetListed(ctx, 9999, true); err == nil || err.Error() != "product not found" {
+		t.Errorf("SetListed(missing) error = %v, want product not found", err)
+	}
+}
+
+func TestSQLite_ProductSlugs(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	slugs := NewProductSlugRepository(db)
+	ctx := context.Background()
+
+	first := &models.Product{SKU: "SL-1", Name: "Blue Widget", UnitPrice: 1}
+	second := &models.Product{SKU: "SL-2", Name: "Blue  widget!", UnitPrice: 1}
+	for _, p := range []*models.Product{first, second} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+	bulk := []*models.Product{{SKU: "SL-3", Name: "Blue Widget", UnitPrice: 1}, {SKU: "SL-4", Name: "Blue Widget", UnitPrice: 1}}
+	if _, err := products.BulkCreate(ctx, bulk); err != nil {
+		t.Fatalf("BulkCreate: %v", err)
+	}
+	if first.Slug != "blue-widget" || second.Slug != "blue-widget-2" || bulk[0].Slug != "blue-widget-3" || bulk[1].Slug != "blue-widget-4" {
+		t.Fatalf("slugs = %q, %q, %q, %q; want blue-widget to blue-widget-4", first.Slug, second.Slug, bulk[0].Slug, bulk[1].Slug)
+	}
+
+	// Renaming keeps the slug, changing it keeps the old one leading to it
+	first.Name = "Navy Widget"
+	first.Slug = ""
+	if err := products.Update(ctx, first); err != nil || first.Slug != "blue-widget" {
+		t.Fatalf("Update(no slug) = %v, slug %q; want blue-widget kept", err, first.Slug)
+	}
+	first.Slug = "navy-widget"
+	if err := products.Update(ctx, first); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if id, current, err := slugs.Lookup(ctx, "blue-widget"); err != nil || id != first.ID || current != "navy-widget" {
+		t.Errorf("Lookup(old) = %d, %q, %v; want %d, navy-widget", id, current, err, first.ID)
+	}
+	if _, _, err := slugs.Lookup(ctx, "red-widget"); err == nil || err.Error() != "product not found" {
+		t.Errorf("Lookup(unknown) error = %v, want product not found", err)
+	}
+	if available, err := slugs.Available(ctx, "blue-widget", second.ID); err != nil || available {
+		t.Errorf("Available(former slug of another) = %v, %v; want false", available, err)
+	}
+	if available, err := slugs.Available(ctx, "blue-widget", first.ID); err != nil || !available {
+		t.Errorf("Available(own former slug) = %v, %v; want true", available, err)
+	}
+
+	// Taken while it was deleted, a product's slug is made anew on restore
+	deleted, _ := products.GetByID(ctx, bulk[1].ID)
+	if err := products.Delete(ctx, bulk[1].ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	third := &models.Product{SKU: "SL-5", Name: "Blue Widget 4", UnitPrice: 1}
+	if err := products.Create(ctx, third); err != nil || third.Slug != "blue-widget-4" {
+		t.Fatalf("Create = %v, slug %q; want blue-widget-4", err, third.Slug)
+	}
+	if err := products.Restore(ctx, deleted); err != nil || deleted.Slug != "blue-widget-5" {
+		t.Errorf("Restore = %v, slug %q; want blue-widget-5", err, deleted.Slug)
+	}
+
+	// Regenerating from names only changes the slugs of renamed products
+	second.Name = "Green Widget"
+	if err := products.Update(ctx, second); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	changed, last, err := slugs.Regenerate(ctx, 0, 10, true)
+	if err != nil || changed != 1 || last != third.ID {
+		t.Fatalf("Regenerate(all) = %d, %d, %v; want 1 changed, last %d", changed, last, err, third.ID)
+	}
+	if id, current, err := slugs.Lookup(ctx, "blue-widget-2"); err != nil || id != second.ID || current != "green-widget" {
+		t.Errorf("Lookup(regenerated) = %d, %q, %v; want %d, green-widget", id, current, err, second.ID)
+	}
+	if _, last, err = slugs.Regenerate(ctx, last, 10, true); err != nil || last != 0 {
+		t.Errorf("Regenerate(past the last) = %d, %v; want 0", last, err)
+	}
+}
+
+func TestSQLite_SavedSearchRepository(t *testing.T) {
+	db := setupSQLiteDB(t)
+	repo := NewSavedSearchRepository(db)
+	ctx := context.Background()
+
+	min := 5.0
+	search := &models.SavedSearch{Name: "Cheap books", Filter: models.ProductFilter{Category: "books", MinPrice: &min, Sort: "unit_price"}}
+	if err := repo.Create(ctx, search); err != nil {
+		t.Fatalf("failed to create saved search: %v", err)
+	}
+	got, err := repo.GetByID(ctx, search.ID)
+	if err != nil || !reflect.DeepEqual(got.Filter, search.Filter) {
+		t.Fatalf("GetByID = %+v, %v; want the filter %+v", got, err, search.Filter)
+	}
+
+	search.Name = "Books"
+	search.Filter = models.ProductFilter{Tags: []string{"sale"}}
+	if err := repo.Update(ctx, search); err != nil {
+		t.Fatalf("failed to update saved search: %v", err)
+	}
+	list, err := repo.List(ctx, 10, 0)
+	if err != nil || len(list) != 1 || list[0].Name != "Books" || !reflect.DeepEqual(list[0].Filter, search.Filter) {
+		t.Errorf("List = %+v, %v; want the updated search", list, err)
+	}
+	if count, err := repo.Count(ctx); err != nil || count != 1 {
+		t.Errorf("Count = %d, %v; want 1", count, err)
+	}
+
+	if err := repo.Delete(ctx, search.ID); err != nil {
+		t.Fatalf("failed to delete saved search: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, search.ID); err == nil || err.Error() != "saved search not found" {
+		t.Errorf("GetByID after Delete = %v, want not found", err)
+	}
+	if err := repo.Update(ctx, search); err == nil || err.Error() != "saved search not found" {
+		t.Errorf("Update after Delete = %v, want not found", err)
+	}
+}
+
+func TestSQLite_ProductRevisionRepository(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	revisions := NewProductRevisionRepository(db)
+	ctx := context.Background()
+
+	product := &models.Product{SKU: "BK-1", Name: "Atlas", UnitPrice: 12.5}
+	if err := products.Create(ctx, product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	proposed := *product
+	proposed.UnitPrice, proposed.Tags = 15, []string{"maps"}
+	rev := &models.ProductRevision{ProductID: product.ID, Product: proposed, BaseUpdatedAt: product.UpdatedAt, Author: "editor"}
+	if err := revisions.Create(ctx, rev); err != nil {
+		t.Fatalf("failed to create revision: %v", err)
+	}
+	got, err := revisions.GetByID(ctx, rev.ID)
+	if err != nil || got.Status != models.RevisionPending || got.Product.UnitPrice != 15 || !reflect.DeepEqual(got.Product.Tags, proposed.Tags) || !got.BaseUpdatedAt.Equal(product.UpdatedAt) {
+		t.Fatalf("GetByID = %+v, %v; want the pending revision", got, err)
+	}
+
+	now := time.Now()
+	got.Status, got.Reviewer, got.Comment, got.ReviewedAt = models.RevisionRejected, "approver", "Too expensive", &now
+	if err := revisions.Review(ctx, got); err != nil {
+		t.Fatalf("failed to review revision: %v", err)
+	}
+	if err := revisions.Review(ctx, got); err == nil || err.Error() != "product revision not found" {
+		t.Errorf("Review of a reviewed revision = %v, want not found", err)
+	}
+
+	if list, err := revisions.List(ctx, product.ID, models.RevisionPending, 10, 0); err != nil || len(list) != 0 {
+		t.Errorf("List(pending) = %+v, %v; want none", list, err)
+	}
+	list, err := revisions.List(ctx, product.ID, "", 10, 0)
+	if err != nil || len(list) != 1 || list[0].Comment != "Too expensive" || list[0].ReviewedAt == nil {
+		t.Errorf("List = %+v, %v; want the rejected revision", list, err)
+	}
+	if count, err := revisions.Count(ctx, product.ID, models.RevisionRejected); err != nil || count != 1 {
+		t.Errorf("Count(rejected) = %d, %v; want 1", count, err)
+	}
+}
+
+func TestSQLite_TrashAndRestore(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	trash := NewTrashRepository(db)
+	ctx := context.Background()
+
+	product := &models.Product{SKU: "BK-1", Name: "Atlas", Quantity: 3, UnitPrice: 12.5, Tags: []string{"maps", "sale"}}
+	if err := products.Create(ctx, product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	if err := products.Delete(ctx, product.ID); err != nil {
+		t.Fatalf("failed to delete product: %v", err)
+	}
+	if _, err := trash.Add(ctx, product); err != nil {
+		t.Fatalf("failed to trash product: %v", err)
+	}
+
+	list, err := trash.List(ctx, 10, 0)
+	if err != nil || len(list) != 1 || list[0].SKU != "BK-1" || !reflect.DeepEqual(list[0].Product.Tags, product.Tags) {
+		t.Fatalf("List = %+v, %v; want the trashed product with its tags", list, err)
+	}
+	if count, err := trash.Count(ctx); err != nil || count != 1 {
+		t.Errorf("Count = %d, %v; want 1", count, err)
+	}
+
+	restored := list[0].Product
+	if err := products.Restore(ctx, &restored); err != nil {
+		t.Fatalf("failed to restore product: %v", err)
+	}
+	if err := trash.Remove(ctx, product.ID); err != nil {
+		t.Fatalf("failed to remove trashed product: %v", err)
+	}
+	got, err := products.GetByID(ctx, product.ID)
+	if err != nil || got.SKU != "BK-1" || got.Quantity != 3 || !reflect.DeepEqual(got.Tags, product.Tags) || !got.CreatedAt.Equal(product.CreatedAt) {
+		t.Errorf("restored product = %+v, %v; want %+v", got, err, product)
+	}
+	if _, err := trash.GetByID(ctx, product.ID); err == nil || err.Error() != "trashed product not found" {
+		t.Errorf("GetByID after Remove = %v, want not found", err)
+	}
+	if err := trash.Remove(ctx, product.ID); err == nil || err.Error() != "trashed product not found" {
+		t.Errorf("second Remove = %v, want not found", err)
+	}
+
+	// New products keep getting IDs past the restored one
+	next := &models.Product{SKU: "BK-2", Name: "Globe"}
+	if err := products.Create(ctx, next); err != nil || next.ID <= product.ID {
+		t.Errorf("Create after Restore = ID %d, %v; want past %d", next.ID, err, product.ID)
+	}
+}
+
+func TestSQLite_OutboxRepository(t *testing.T) {
+	db := setupSQLiteDB(t)
+	repo := NewOutboxRepository(db)
+	ctx := context.Background()
+
+	messages := []*models.OutboxMessage{
+		{EventID: "e1", EventType: "product.created", SchemaVersion: 1, AggregateID: "1", Payload: []byte(`{"id":1}`)},
+		{EventID: "e2", EventType: "product.created", SchemaVersion: 1, AggregateID: "2", Payload: []byte(`{"id":2}`)},
+	}
+	if err := repo.Add(ctx, messages...); err != nil {
+		t.Fatalf("failed to add messages: %v", err)
+	}
+
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		pending, err := repo.FetchPending(ctx, 10)
+		if err != nil {
+			return err
+		}
+		if len(pending) != 2 || string(pending[0].Payload) != `{"id":1}` {
+			t.Errorf("FetchPending = %+v", pending)
+		}
+		return repo.MarkPublished(ctx, []int64{pending[0].ID})
+	})
+	if err != nil {
+		t.Fatalf("relay transaction failed: %v", err)
+	}
+
+	if err := repo.MarkFailed(ctx, []int64{messages[1].ID}, errors.New("broker down")); err != nil {
+		t.Fatalf("failed to mark message failed: %v", err)
+	}
+	count, err := repo.CountPending(ctx)
+	if err != nil || count != 1 {
+		t.Errorf("CountPending = %d, %v; want 1", count, err)
+	}
+
+	published := time.Now()
+	low := &models.OutboxMessage{EventID: "e3", EventType: "stock.low", SchemaVersion: 1, AggregateID: "2", Payload: []byte(`{}`), PublishedAt: &published}
+	deleted := &models.OutboxMessage{EventID: "e4", EventType: "product.deleted", SchemaVersion: 1, AggregateID: "1", Payload: []byte(`{}`), PublishedAt: &published}
+	if err := repo.Add(ctx, low, deleted); err != nil {
+		t.Fatalf("failed to add published messages: %v", err)
+	}
+	if count, _ := repo.CountPending(ctx); count != 1 {
+		t.Errorf("CountPending = %d after adding published messages, want 1", count)
+	}
+
+	types := []string{"product.created", "product.deleted"}
+	changes, err := repo.Changes(ctx, types, models.ChangeCursor{}, 2)
+	if err != nil || len(changes) != 2 || changes[0].EventID != "e1" || changes[1].EventID != "e2" {
+		t.Fatalf("Changes from the start = %+v, %v; want e1, e2", changes, err)
+	}
+	changes, err = repo.Changes(ctx, types, models.ChangeCursor{TxID: changes[1].TxID, ID: changes[1].ID}, 10)
+	if err != nil || len(changes) != 1 || changes[0].EventID != "e4" {
+		t.Errorf("Changes after e2 = %+v, %v; want e4 without stock.low", changes, err)
+	}
+
+	head, err := repo.Head(ctx, types)
+	if err != nil || head != (models.ChangeCursor{TxID: deleted.TxID, ID: deleted.ID}) {
+		t.Errorf("Head = %+v, %v; want e4", head, err)
+	}
+	if ok, err := repo.Has(ctx, head); err != nil || !ok {
+		t.Errorf("Has(head) = %v, %v", ok, err)
+	}
+	if ok, _ := repo.Has(ctx, models.ChangeCursor{ID: 999}); ok {
+		t.Error("Has reported a missing message")
+	}
+}
+
+func TestSQLite_SmallRepositories(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	orders := NewOrderRepository(db)
+	order := &models.Order{ID: "order-1"}
+	for i, want := range []bool{true, false} {
+		first, err := orders.MarkProcessed(ctx, "shop", order)
+		if err != nil || first != want {
+			t.Errorf("MarkProcessed #%d = %v, %v; want %v", i+1, first, err, want)
+		}
+	}
+
+	inbox := NewInboxRepository(db)
+	for i, want := range []bool{true, false} {
+		first, err := inbox.Receive(ctx, "nats", "evt-1")
+		if err != nil || first != want {
+			t.Errorf("Receive #%d = %v, %v; want %v", i+1, first, err, want)
+		}
+	}
+	if first, err := inbox.Receive(ctx, "webhook", "evt-1"); err != nil || !first {
+		t.Errorf("Receive from another source = %v, %v; want true", first, err)
+	}
+
+	syncs := NewSyncStateRepository(db)
+	now := time.Now()
+	if err := syncs.Save(ctx, &models.SyncState{Connector: "shopify", LastRunAt: &now, Pulled: 3}); err != nil {
+		t.Fatalf("failed to save sync state: %v", err)
+	}
+	if err := syncs.Save(ctx, &models.SyncState{Connector: "shopify", LastRunAt: &now, Pulled: 4}); err != nil {
+		t.Fatalf("failed to update sync state: %v", err)
+	}
+	state, err := syncs.Get(ctx, "shopify")
+	if err != nil || state.Pulled != 4 || state.LastRunAt == nil || !state.LastRunAt.Equal(now) {
+		t.Errorf("Get = %+v, %v", state, err)
+	}
+
+	promotions := NewPromotionRepository(db)
+	ended := now.Add(-time.Hour)
+	for _, p := range []*models.Promotion{
+		{Name: "Always", Kind: models.PromotionPercentage, Value: 10, Scope: models.PromotionScopeAll},
+		{Name: "Over", Kind: models.PromotionFixed, Value: 1, Scope: models.PromotionScopeAll, EndsAt: &ended},
+		{Name: "First", Kind: models.PromotionFixed, Value: 2.5, Scope: models.PromotionScopeTag, Target: "sale", Stackable: true, Priority: 5},
+	} {
+		if err := promotions.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create promotion: %v", err)
+		}
+	}
+	active, err := promotions.ListActive(ctx, now)
+	if err != nil || len(active) != 2 || active[0].Name != "First" || !active[0].Stackable || active[0].Value != 2.5 {
+		t.Errorf("ListActive = %+v, %v; want First then Always", active, err)
+	}
+
+	audit := NewAuditRepository(db)
+	if err := audit.Create(ctx, &models.AuditEntry{Action: "config.reload", Actor: "test", EntityType: "config", Details: []byte(`{"changed":[]}`)}); err != nil {
+		t.Fatalf("failed to create audit entry: %v", err)
+	}
+	entries, err := audit.List(ctx, now.Add(-time.Minute), now.Add(time.Minute), 10)
+	if err != nil || len(entries) != 1 || entries[0].EntityID != "" {
+		t.Errorf("List = %+v, %v", entries, err)
+	}
+}
+
+func TestSQLite_ProductNotes(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	notes := NewProductNoteRepository(db)
+	ctx := context.Background()
+
+	first := &models.Product{SKU: "NT-1", Name: "Widget", UnitPrice: 1}
+	second := &models.Product{SKU: "NT-2", Name: "Gadget", UnitPrice: 1}
+	for _, p := range []*models.Product{first, second} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	pinned := &models.ProductNote{ProductID: first.ID, Author: "dana", Body: "Fragile", Pinned: true}
+	older := &models.ProductNote{ProductID: first.ID, Author: "sam", Body: "On shelf B7"}
+	newer := &models.ProductNote{ProductID: first.ID, Author: "admin", Body: "Moved to B9 @sam"}
+	for _, n := range []*models.ProductNote{pinned, older, newer} {
+		if err := notes.Create(ctx, n); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	list, err := notes.List(ctx, first.ID, 10, 0)
+	if err != nil || len(list) != 3 || list[0].ID != pinned.ID || list[1].ID != newer.ID || list[2].ID != older.ID {
+		t.Fatalf("List = %v, %v; want pinned, newer, older", list, err)
+	}
+	if count, err := notes.Count(ctx, first.ID); err != nil || count != 3 {
+		t.Errorf("Count = %d, %v; want 3", count, err)
+	}
+	if _, err := notes.GetByID(ctx, second.ID, older.ID); err == nil || err.Error() != "product note not found" {
+		t.Errorf("GetByID(other product) = %v, want product note not found", err)
+	}
+
+	latest, err := notes.Latest(ctx, []int{first.ID, second.ID})
+	if err != nil || len(latest) != 1 || latest[first.ID].ID != newer.ID {
+		t.Fatalf("Latest = %v, %v; want only the newer note of the first product", latest, err)
+	}
+
+	older.Body, older.Pinned = "On shelf B8", true
+	if err := notes.Update(ctx, older); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ := notes.GetByID(ctx, first.ID, older.ID); got.Body != "On shelf B8" || !got.Pinned {
+		t.Errorf("after Update = %+v, want B8, pinned", got)
+	}
+
+	if err := notes.Delete(ctx, first.ID, newer.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := notes.Delete(ctx, first.ID, newer.ID); err == nil || err.Error() != "product note not found" {
+		t.Errorf("Delete again = %v, want product note not found", err)
+	}
+	if latest, _ := notes.Latest(ctx, []int{first.ID}); latest[first.ID].ID != older.ID {
+		t.Errorf("Latest after delete = %+v, want the older note", latest[first.ID])
+	}
+}
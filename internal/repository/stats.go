@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// ProductStatsView is the materialized view behind ProductStatsRepository
+const ProductStatsView = "product_stats"
+
+type ProductStatsRepository interface {
+	// Get returns a product's stats and how stale they are. Stats come from
+	// the materialized view, or are computed live while it holds no data or
+	// for a product created since the last refresh.
+	Get(ctx context.Context, productID int) (*models.ProductStats, *models.Staleness, error)
+
+	// Summary adds up the stats of every product, read like Get
+	Summary(ctx context.Context) (*models.InventorySummary, *models.Staleness, error)
+
+	// Refresh recomputes the materialized view
+	Refresh(ctx context.Context) error
+
+	// ABC classifies every product by the value of its stock movements out
+	// since from, and returns those of class, or all when it's empty, highest
+	// value first. It reads the live tables, not the view.
+	ABC(ctx context.Context, from time.Time, class string, limit, offset int) ([]*models.ABCClassification, error)
+
+	CountABC(ctx context.Context, from time.Time, class string) (int, error)
+}
+
+type productStatsRepo struct {
+	db *database.DB
+}
+
+func NewProductStatsRepository(db *database.DB) ProductStatsRepository {
+	return &productStatsRepo{db: db}
+}
+
+var productStatsColumns = database.ColumnList(models.ProductStats{})
+
+// Quantities are added up in base units, see models.InventorySummary
+const inventorySummaryColumns = `
+	COUNT(*) AS products,
+	COALESCE(SUM(CASE WHEN s.dimension = '` + models.CountDimension + `' THEN s.base_quantity END), 0) AS units,
+	COALESCE(SUM(s.inventory_value), 0) AS inventory_value,
+	COUNT(CASE WHEN s.quantity = 0 THEN 1 END) AS out_of_stock,
+	COALESCE(SUM(CASE WHEN s.dimension = '` + models.CountDimension + `' THEN s.units_in * u.factor END), 0) AS units_in,
+	COALESCE(SUM(CASE WHEN s.dimension = '` + models.CountDimension + `' THEN s.units_out * u.factor END), 0) AS units_out
+`
+
+const dimensionQuantityColumns = `
+	s.dimension, b.code AS base_unit,
+	COUNT(*) AS products,
+	SUM(s.base_quantity) AS quantity,
+	SUM(s.units_in * u.factor) AS units_in,
+	SUM(s.units_out * u.factor) AS units_out
+`
+
+// abcClassified ranks every product by consumption value, the units its
+// movements took out since $1 at its unit price, and classes it by the share
+// of the total value before it: under $2 is A, under $3 B. Ties rank by ID.
+// Shares multiply by 1.0 as SQLite stores whole prices as integers.
+const abcClassified = `
+	WITH consumption AS (
+		SELECT p.id AS product_id, p.sku, p.name, p.quantity,
+			COALESCE(SUM(-m.delta), 0) AS units_out,
+			COALESCE(SUM(-m.delta), 0) * p.unit_price AS value
+		FROM products p
+		LEFT JOIN stock_movements m ON m.product_id = p.id AND m.delta < 0 AND m.created_at >= $1
+		GROUP BY p.id, p.sku, p.name, p.quantity, p.unit_price
+	), ranked AS (
+		SELECT c.*,
+			ROW_NUMBER() OVER (ORDER BY c.value DESC, c.product_id) AS rank,
+			SUM(c.value) OVER (ORDER BY c.value DESC, c.product_id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS cumulative_value,
+			SUM(c.value) OVER () AS total_value
+		FROM consumption c
+	), classified AS (
+		SELECT product_id, sku, name, quantity, units_out, value, rank,
+			CASE WHEN total_value > 0 THEN value * 1.0 / total_value ELSE 0 END AS share,
+			CASE WHEN total_value > 0 THEN cumulative_value * 1.0 / total_value ELSE 0 END AS cumulative_share,
+			CASE
+				WHEN value <= 0 THEN '` + models.ABCClassC + `'
+				WHEN cumulative_value - value < $2 * total_value THEN '` + models.ABCClassA + `'
+				WHEN cumulative_value - value < $3 * total_value THEN '` + models.ABCClassB + `'
+				ELSE '` + models.ABCClassC + `'
+			END AS class
+		FROM ranked
+	)
+`
+
+var abcColumns = database.ColumnList(models.ABCClassification{})
+
+func (r *productStatsRepo) ABC(ctx context.Context, from time.Time, class string, limit, offset int) ([]*models.ABCClassification, error) {
+	query := abcClassified + `
+		SELECT ` + abcColumns + ` FROM classified
+		WHERE $4 = '' OR class = $4
+		ORDER BY rank
+		LIMIT $5 OFFSET $6
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, from, models.ABCShareA, models.ABCShareB, class, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify products: %w", err)
+	}
+
+	classifications := []*models.ABCClassification{}
+	if err := database.ScanAll(&classifications, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan classifications: %w", err)
+	}
+
+	return classifications, nil
+}
+
+func (r *productStatsRepo) CountABC(ctx context.Context, from time.Time, class string) (int, error) {
+	query := abcClassified + `SELECT COUNT(*) FROM classified WHERE $4 = '' OR class = $4`
+
+	var count int
+	if err := r.db.Conn(ctx).QueryRowContext(ctx, query, from, models.ABCShareA, models.ABCShareB, class).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count classifications: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *productStatsRepo) Get(ctx context.Context, productID int) (*models.ProductStats, *models.Staleness, error) {
+	stats := &models.ProductStats{}
+	get := func(ctx context.Context, from string) error {
+		query := `SELECT ` + productStatsColumns + ` FROM ` + from + ` WHERE product_id = $1`
+		rows, err := r.db.Conn(ctx).QueryContext(ctx, query, productID)
+		if err != nil {
+			return err
+		}
+		return database.ScanOne(stats, rows)
+	}
+
+	staleness, err := r.read(ctx, get)
+	// Created since the last refresh; one product is cheap to compute
+	if err == sql.ErrNoRows && staleness.Source != "live" {
+		staleness = &models.Staleness{Source: "live"}
+		err = get(ctx, ProductStatsView+"_source")
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get product stats: %w", err)
+	}
+
+	return stats, staleness, nil
+}
+
+func (r *productStatsRepo) Summary(ctx context.Context) (*models.InventorySummary, *models.Staleness, error) {
+	summary := &models.InventorySummary{}
+	staleness, err := r.read(ctx, func(ctx context.Context, from string) error {
+		conn := r.db.Conn(ctx)
+		from += ` s JOIN units u ON u.code = s.unit`
+
+		rows, err := conn.QueryContext(ctx, `SELECT `+inventorySummaryColumns+` FROM `+from)
+		if err != nil {
+			return err
+		}
+		if err := database.ScanOne(summary, rows); err != nil {
+			return err
+		}
+
+		rows, err = conn.QueryContext(ctx, `
+			SELECT `+dimensionQuantityColumns+`
+			FROM `+from+`
+			JOIN units b ON b.dimension = s.dimension AND b.factor = 1
+			GROUP BY s.dimension, b.code
+			ORDER BY s.dimension
+		`)
+		if err != nil {
+			return err
+		}
+		return database.ScanAll(&summary.Quantities, rows)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get inventory summary: %w", err)
+	}
+	if summary.Quantities == nil {
+		summary.Quantities = []*models.DimensionQuantity{}
+	}
+
+	return summary, staleness, nil
+}
+
+// read runs query against the view, or its live source while the view holds
+// no data. The refresh time is read in the same snapshot as the data, so it
+// describes what query saw.
+func (r *productStatsRepo) read(ctx context.Context, query func(ctx context.Context, from string) error) (*models.Staleness, error) {
+	var staleness *models.Staleness
+	err := r.db.WithTxOptions(ctx, r.db.Dialect().SnapshotTxOptions(), func(ctx context.Context) error {
+		refreshedAt, err := r.db.MaterializedViewRefreshedAt(ctx, ProductStatsView)
+		if err != nil {
+			return err
+		}
+
+		if refreshedAt == nil {
+			staleness = &models.Staleness{Source: "live"}
+			return query(ctx, ProductStatsView+"_source")
+		}
+		staleness = &models.Staleness{
+			Source:      "materialized_view",
+			RefreshedAt: refreshedAt,
+			AgeSeconds:  time.Since(*refreshedAt).Round(time.Millisecond).Seconds(),
+		}
+		return query(ctx, ProductStatsView)
+	})
+	return staleness, err
+}
+
+func (r *productStatsRepo) Refresh(ctx context.Context) error {
+	_, err := r.db.RefreshMaterializedView(ctx, ProductStatsView)
+	return err
+}
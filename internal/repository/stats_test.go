@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func TestSQLite_ProductStatsRepository(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	repo := NewProductStatsRepository(db)
+	ctx := context.Background()
+
+	// Migrations populated the view while the catalog was empty
+	summary, staleness, err := repo.Summary(ctx)
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	if staleness.Source != "materialized_view" || staleness.RefreshedAt == nil {
+		t.Errorf("staleness after migrations = %+v, want a refreshed view", staleness)
+	}
+
+	product := &models.Product{SKU: "STATS-1", Name: "Counted", Quantity: 10, UnitPrice: 2.5}
+	if err := products.Create(ctx, product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	if _, err := products.AdjustStock(ctx, product.ID, -4); err != nil {
+		t.Fatalf("failed to adjust stock: %v", err)
+	}
+	for _, p := range []*models.Product{
+		{SKU: "STATS-2", Name: "Empty"},
+		{SKU: "STATS-3", Name: "Boxed", Unit: "box-of-12", Quantity: 2, UnitPrice: 10},
+		{SKU: "STATS-4", Name: "Weighed", Unit: "kg", Quantity: 3, UnitPrice: 1},
+	} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	// Until the next refresh the summary is stale, but a new product is computed live
+	if summary, _, _ = repo.Summary(ctx); summary.Products != 0 {
+		t.Errorf("summary before refresh counts %d products, want the stale 0", summary.Products)
+	}
+	want := models.ProductStats{ProductID: product.ID, SKU: "STATS-1", Unit: "each", Dimension: "count", Quantity: 6, BaseQuantity: 6, InventoryValue: 15, Movements: 2, UnitsIn: 10, UnitsOut: 4}
+	stats, staleness, err := repo.Get(ctx, product.ID)
+	if err != nil || *stats != want || staleness.Source != "live" {
+		t.Errorf("Get before refresh = %+v, %+v, %v; want %+v computed live", stats, staleness, err, want)
+	}
+	if _, _, err := repo.Get(ctx, 999); err == nil || err.Error() != "product not found" {
+		t.Errorf("Get of a missing product = %v, want not found", err)
+	}
+
+	if err := repo.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	summary, staleness, err = repo.Summary(ctx)
+	// Boxes count as the units in them; kilograms are reported separately
+	wantSummary := models.InventorySummary{Products: 4, Units: 30, InventoryValue: 38, OutOfStock: 1, UnitsIn: 34, UnitsOut: 4, Quantities: []*models.DimensionQuantity{
+		{Dimension: "count", BaseUnit: "each", Products: 3, Quantity: 30, UnitsIn: 34, UnitsOut: 4},
+		{Dimension: "mass", BaseUnit: "g", Products: 1, Quantity: 3000, UnitsIn: 3000},
+	}}
+	if err != nil || !reflect.DeepEqual(summary, &wantSummary) || staleness.Source != "materialized_view" {
+		t.Errorf("Summary after refresh = %+v, %+v, %v; want %+v", summary, staleness, err, wantSummary)
+	}
+	if stats, staleness, _ := repo.Get(ctx, product.ID); *stats != want || staleness.Source != "materialized_view" {
+		t.Errorf("Get after refresh = %+v, %+v", stats, staleness)
+	}
+
+	// A view that holds no data, e.g. recreated by a migration, is bypassed
+	if _, err := db.Exec(`UPDATE materialized_views SET refreshed_at = NULL`); err != nil {
+		t.Fatalf("failed to reset refresh time: %v", err)
+	}
+	if _, staleness, _ := repo.Summary(ctx); staleness.Source != "live" || staleness.RefreshedAt != nil {
+		t.Errorf("staleness of an unpopulated view = %+v, want live", staleness)
+	}
+}
+
+func TestSQLite_ABCAnalysis(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	repo := NewProductStatsRepository(db)
+	ctx := context.Background()
+
+	// Out values of 700, 200, 60, 40, and 0 are 70%, 20%, 6%, 4%, and none
+	for _, p := range []struct {
+		sku   string
+		price float64
+		out   int
+	}{
+		{"ABC-1", 10, 70},
+		{"ABC-2", 2, 100},
+		{"ABC-3", 3, 20},
+		{"ABC-4", 1, 40},
+		{"ABC-5", 50, 0},
+	} {
+		product := &models.Product{SKU: p.sku, Name: p.sku, Quantity: 100, UnitPrice: p.price}
+		if err := products.Create(ctx, product); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+		if p.out > 0 {
+			if _, err := products.AdjustStock(ctx, product.ID, -p.out); err != nil {
+				t.Fatalf("failed to adjust stock: %v", err)
+			}
+		}
+	}
+
+	from := time.Now().Add(-time.Hour)
+	all, err := repo.ABC(ctx, from, "", 10, 0)
+	if err != nil {
+		t.Fatalf("ABC: %v", err)
+	}
+	var got []string
+	for _, c := range all {
+		got = append(got, c.SKU+":"+c.Class)
+	}
+	// ABC-2 starts at 70%, under 80%, so it's A; ABC-4 starts at 96%
+	want := []string{"ABC-1:A", "ABC-2:A", "ABC-3:B", "ABC-4:C", "ABC-5:C"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ABC classes = %v, want %v", got, want)
+	}
+	if first := all[0]; first.Rank != 1 || first.UnitsOut != 70 || first.Value != 700 || first.Share != 0.7 || first.CumulativeShare != 0.7 {
+		t.Errorf("ABC first = %+v", first)
+	}
+
+	classB, err := repo.ABC(ctx, from, models.ABCClassB, 10, 0)
+	if err != nil || len(classB) != 1 || classB[0].SKU != "ABC-3" || classB[0].Rank != 3 {
+		t.Errorf("ABC of class B = %+v, %v", classB, err)
+	}
+	if n, err := repo.CountABC(ctx, from, models.ABCClassC); err != nil || n != 2 {
+		t.Errorf("CountABC(C) = %d, %v, want 2", n, err)
+	}
+
+	// Movements before the window don't count, so every product is C
+	later, err := repo.ABC(ctx, time.Now().Add(time.Hour), models.ABCClassC, 10, 0)
+	if err != nil || len(later) != 5 || later[0].Share != 0 {
+		t.Errorf("ABC after the movements = %+v, %v", later, err)
+	}
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type StockMovementRepository interface {
+	// ListByProduct returns a product's movements in [from, to), newest first.
+	// The time range is required so only the partitions covering it are read.
+	ListByProduct(ctx context.Context, productID int, from, to time.Time, limit int) ([]*models.StockMovement, error)
+
+	// Latest returns the movement of a product's last quantity change, as
+	// seen by the caller's transaction, e.g. to reference the one a write
+	// just recorded
+	Latest(ctx context.Context, productID int) (*models.StockMovement, error)
+}
+
+type stockMovementRepo struct {
+	db *database.DB
+}
+
+func NewStockMovementRepository(db *database.DB) StockMovementRepository {
+	return &stockMovementRepo{db: db}
+}
+
+// stockMovementColumns is derived from the db tags of models.StockMovement
+var stockMovementColumns = database.ColumnList(models.StockMovement{})
+
+var stockMovementsByProductQuery = `
+	SELECT ` + stockMovementColumns + `
+	FROM stock_movements
+	WHERE product_id = $1 AND created_at >= $2 AND created_at < $3
+	ORDER BY created_at DESC, id DESC
+	LIMIT $4
+`
+
+func (r *stockMovementRepo) ListByProduct(ctx context.Context, productID int, from, to time.Time, limit int) ([]*models.StockMovement, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, stockMovementsByProductQuery, productID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock movements: %w", err)
+	}
+
+	var movements []*models.StockMovement
+	if err := database.ScanAll(&movements, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan stock movements: %w", err)
+	}
+
+	return movements, nil
+}
+
+func (r *stockMovementRepo) Latest(ctx context.Context, productID int) (*models.StockMovement, error) {
+	query := `SELECT ` + stockMovementColumns + ` FROM stock_movements WHERE product_id = $1 ORDER BY id DESC LIMIT 1`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock movement: %w", err)
+	}
+
+	movement := &models.StockMovement{}
+	err = database.ScanOne(movement, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("stock movement not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock movement: %w", err)
+	}
+
+	return movement, nil
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type StockTakeRepository interface {
+	// Create records a stock take as opened now
+	Create(ctx context.Context, take *models.StockTake) error
+
+	GetByID(ctx context.Context, id int) (*models.StockTake, error)
+
+	// GetByIDForUpdate is GetByID locking the take's row for the rest of the
+	// transaction, so counts and completion of one take don't interleave
+	GetByIDForUpdate(ctx context.Context, id int) (*models.StockTake, error)
+
+	// List returns stock takes with status, or all of them for "", most
+	// recently opened first
+	List(ctx context.Context, status string, limit, offset int) ([]*models.StockTake, error)
+
+	Count(ctx context.Context, status string) (int, error)
+
+	// Close sets a take's status and closed_at
+	Close(ctx context.Context, take *models.StockTake) error
+
+	// RecordCount records a product's count, replacing an earlier count of it
+	// in the same take
+	RecordCount(ctx context.Context, count *models.StockTakeCount) error
+
+	// ListCounts returns a take's counts in SKU order
+	ListCounts(ctx context.Context, takeID int) ([]*models.StockTakeCount, error)
+
+	// OpenTakeCounting returns the ID of an open take other than takeID that
+	// has counted the product, 0 when there is none
+	OpenTakeCounting(ctx context.Context, productID, takeID int) (int, error)
+
+	// PostAdjustment records the adjustment a completed take made to a
+	// product's stock
+	PostAdjustment(ctx context.Context, takeID, productID, adjustment int) error
+}
+
+type stockTakeRepo struct {
+	db *database.DB
+}
+
+func NewStockTakeRepository(db *database.DB) StockTakeRepository {
+	return &stockTakeRepo{db: db}
+}
+
+var (
+	stockTakeColumns      = database.ColumnList(models.StockTake{})
+	stockTakeCountColumns = database.ColumnList(models.StockTakeCount{})
+)
+
+var stockTakeByIDQuery = `SELECT ` + stockTakeColumns + ` FROM stock_takes WHERE id = $1`
+
+func (r *stockTakeRepo) Create(ctx context.Context, take *models.StockTake) error {
+	query := `
+		INSERT INTO stock_takes (name, status, opened_by, opened_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	take.OpenedAt = time.Now()
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, take.Name, take.Status, take.OpenedBy, take.OpenedAt).Scan(&take.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create stock take: %w", err)
+	}
+
+	return nil
+}
+
+func (r *stockTakeRepo) GetByID(ctx context.Context, id int) (*models.StockTake, error) {
+	return r.get(ctx, stockTakeByIDQuery, id)
+}
+
+func (r *stockTakeRepo) GetByIDForUpdate(ctx context.Context, id int) (*models.StockTake, error) {
+	return r.get(ctx, stockTakeByIDQuery+" "+r.db.Dialect().ForUpdate(), id)
+}
+
+func (r *stockTakeRepo) get(ctx context.Context, query string, id int) (*models.StockTake, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock take: %w", err)
+	}
+
+	take := &models.StockTake{}
+	err = database.ScanOne(take, rows)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("stock take not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock take: %w", err)
+	}
+
+	return take, nil
+}
+
+func (r *stockTakeRepo) List(ctx context.Context, status string, limit, offset int) ([]*models.StockTake, error) {
+	where, args := stockTakeStatusFilter(status)
+	query := `
+		SELECT ` + stockTakeColumns + `
+		FROM stock_takes` + where + `
+		ORDER BY opened_at DESC, id DESC
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock takes: %w", err)
+	}
+
+	takes := []*models.StockTake{}
+	if err := database.ScanAll(&takes, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan stock takes: %w", err)
+	}
+
+	return takes, nil
+}
+
+func (r *stockTakeRepo) Count(ctx context.Context, status string) (int, error) {
+	where, args := stockTakeStatusFilter(status)
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM stock_takes`+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count stock takes: %w", err)
+	}
+	return count, nil
+}
+
+// stockTakeStatusFilter returns the WHERE clause for takes with status, none
+// for ""
+func stockTakeStatusFilter(status string) (string, []interface{}) {
+	if status == "" {
+		return "", nil
+	}
+	return " WHERE status = $1", []interface{}{status}
+}
+
+func (r *stockTakeRepo) Close(ctx context.Context, take *models.StockTake) error {
+	closedAt := time.Now()
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `UPDATE stock_takes SET status = $1, closed_at = $2 WHERE id = $3`, take.Status, closedAt, take.ID)
+	if err != nil {
+		return fmt.Errorf("failed to close stock take: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("stock take not found")
+	}
+
+	take.ClosedAt = &closedAt
+	return nil
+}
+
+func (r *stockTakeRepo) RecordCount(ctx context.Context, count *models.StockTakeCount) error {
+	query := `
+		INSERT INTO stock_take_counts (stock_take_id, product_id, sku, counted, expected, reason, counted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (stock_take_id, product_id) DO UPDATE SET
+			sku = EXCLUDED.sku,
+			counted = EXCLUDED.counted,
+			expected = EXCLUDED.expected,
+			reason = EXCLUDED.reason,
+			counted_at = EXCLUDED.counted_at
+	`
+
+	count.CountedAt = time.Now()
+	_, err := r.db.Conn(ctx).ExecContext(ctx, query,
+		count.StockTakeID,
+		count.ProductID,
+		count.SKU,
+		count.Counted,
+		count.Expected,
+		count.Reason,
+		count.CountedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record stock count: %w", err)
+	}
+
+	count.Discrepancy = count.Counted - count.Expected
+	return nil
+}
+
+func (r *stockTakeRepo) ListCounts(ctx context.Context, takeID int) ([]*models.StockTakeCount, error) {
+	query := `
+		SELECT ` + stockTakeCountColumns + `
+		FROM stock_take_counts
+		WHERE stock_take_id = $1
+		ORDER BY sku
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, takeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock counts: %w", err)
+	}
+
+	counts := []*models.StockTakeCount{}
+	if err := database.ScanAll(&counts, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan stock counts: %w", err)
+	}
+	for _, c := range counts {
+		c.Discrepancy = c.Counted - c.Expected
+	}
+
+	return counts, nil
+}
+
+func (r *stockTakeRepo) OpenTakeCounting(ctx context.Context, productID, takeID int) (int, error) {
+	query := `
+		SELECT t.id
+		FROM stock_take_counts c
+		JOIN stock_takes t ON t.id = c.stock_take_id
+		WHERE c.product_id = $1 AND c.stock_take_id <> $2 AND t.status = 'open'
+		ORDER BY t.id
+		LIMIT 1
+	`
+
+	var id int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, productID, takeID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to check open stock takes: %w", err)
+	}
+	return id, nil
+}
+
+func (r *stockTakeRepo) PostAdjustment(ctx context.Context, takeID, productID, adjustment int) error {
+	_, err := r.db.Conn(ctx).ExecContext(ctx, `UPDATE stock_take_counts SET adjustment = $1 WHERE stock_take_id = $2 AND product_id = $3`, adjustment, takeID, productID)
+	if err != nil {
+		return fmt.Errorf("failed to post stock adjustment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func TestStockMovementRepository_ListByProduct(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	products := NewProductRepository(db)
+	movements := NewStockMovementRepository(db)
+	ctx := context.Background()
+
+	product := &models.Product{SKU: "MOVE-1", Name: "Moving", Quantity: 5}
+	if err := products.Create(ctx, product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	if _, err := products.AdjustStock(ctx, product.ID, -2); err != nil {
+		t.Fatalf("failed to adjust stock: %v", err)
+	}
+
+	from := database.MonthStart(time.Now())
+	list, err := movements.ListByProduct(ctx, product.ID, from, from.AddDate(0, 1, 0), 10)
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+
+	if len(list) != 2 {
+		t.Fatalf("got %d movements, want 2", len(list))
+	}
+	if list[0].Delta != -2 || list[0].QuantityAfter != 3 {
+		t.Errorf("latest movement = %+v, want delta -2, quantity 3", list[0])
+	}
+	if list[1].Delta != 5 || list[1].QuantityAfter != 5 {
+		t.Errorf("first movement = %+v, want delta 5, quantity 5", list[1])
+	}
+
+	// Months outside the range aren't read
+	last := from.AddDate(0, -1, 0)
+	list, err = movements.ListByProduct(ctx, product.ID, last, from, 10)
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("got %d movements for last month, want 0", len(list))
+	}
+}
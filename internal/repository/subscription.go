@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type SubscriptionRepository interface {
+	Create(ctx context.Context, sub *models.Subscription) error
+
+	GetByID(ctx context.Context, id int) (*models.Subscription, error)
+
+	Delete(ctx context.Context, id int) error
+
+	// List returns subscriptions, oldest first
+	List(ctx context.Context, limit, offset int) ([]*models.Subscription, error)
+
+	Count(ctx context.Context) (int, error)
+
+	// ForProduct returns every subscription to the product
+	ForProduct(ctx context.Context, productID int) ([]*models.Subscription, error)
+
+	// RecordDelivery logs an attempt to deliver a notification
+	RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+type subscriptionRepo struct {
+	db *database.DB
+}
+
+func NewSubscriptionRepository(db *database.DB) SubscriptionRepository {
+	return &subscriptionRepo{db: db}
+}
+
+// The fields are scanned as JSON, so the columns are listed explicitly
+const subscriptionColumns = `id, product_id, fields, callback_url, secret, created_at`
+
+func (r *subscriptionRepo) Create(ctx context.Context, sub *models.Subscription) error {
+	query := `
+		INSERT INTO subscriptions (product_id, fields, callback_url, secret, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	fields, err := json.Marshal(sub.Fields)
+	if err != nil {
+		return fmt.Errorf("failed to encode subscription fields: %w", err)
+	}
+	sub.CreatedAt = time.Now()
+
+	err = r.db.Conn(ctx).QueryRowContext(ctx, query, sub.ProductID, fields, sub.CallbackURL, sub.Secret, sub.CreatedAt).Scan(&sub.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (r *subscriptionRepo) GetByID(ctx context.Context, id int) (*models.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = $1`
+
+	sub, err := scanSubscription(r.db.Conn(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("subscription not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	return sub, nil
+}
+
+func (r *subscriptionRepo) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("subscription not found")
+	}
+
+	return nil
+}
+
+func (r *subscriptionRepo) List(ctx context.Context, limit, offset int) ([]*models.Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		ORDER BY id
+		LIMIT $1 OFFSET $2
+	`
+
+	return r.query(ctx, query, limit, offset)
+}
+
+func (r *subscriptionRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM subscriptions`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count subscriptions: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *subscriptionRepo) ForProduct(ctx context.Context, productID int) ([]*models.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE product_id = $1 ORDER BY id`
+
+	return r.query(ctx, query, productID)
+}
+
+func (r *subscriptionRepo) query(ctx context.Context, query string, args ...interface{}) ([]*models.Subscription, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []*models.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// scanSubscription scans a row of subscriptionColumns, decoding the fields
+func scanSubscription(row interface{ Scan(...interface{}) error }) (*models.Subscription, error) {
+	sub := &models.Subscription{}
+	var fields []byte
+	if err := row.Scan(&sub.ID, &sub.ProductID, &fields, &sub.CallbackURL, &sub.Secret, &sub.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(fields, &sub.Fields); err != nil {
+		return nil, fmt.Errorf("failed to decode fields of subscription %d: %w", sub.ID, err)
+	}
+	return sub, nil
+}
+
+func (r *subscriptionRepo) RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, product_id, event_id, callback_url, attempt, status_code, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	delivery.CreatedAt = time.Now()
+
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query,
+		delivery.SubscriptionID,
+		delivery.ProductID,
+		delivery.EventID,
+		delivery.CallbackURL,
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Error,
+		delivery.CreatedAt,
+	).Scan(&delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+
+	return nil
+}
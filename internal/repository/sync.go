@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type SyncStateRepository interface {
+	// Get returns the state of a connector, or an empty state if it never ran
+	Get(ctx context.Context, connector string) (*models.SyncState, error)
+
+	Save(ctx context.Context, state *models.SyncState) error
+
+	List(ctx context.Context) ([]*models.SyncState, error)
+}
+
+type syncStateRepo struct {
+	db *database.DB
+}
+
+func NewSyncStateRepository(db *database.DB) SyncStateRepository {
+	return &syncStateRepo{db: db}
+}
+
+var syncStateColumns = database.ColumnList(models.SyncState{})
+
+func (r *syncStateRepo) Get(ctx context.Context, connector string) (*models.SyncState, error) {
+	query := `SELECT ` + syncStateColumns + ` FROM catalog_sync_state WHERE connector = $1`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, connector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync state: %w", err)
+	}
+
+	state := &models.SyncState{}
+	err = database.ScanOne(state, rows)
+	if err == sql.ErrNoRows {
+		return &models.SyncState{Connector: connector}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync state: %w", err)
+	}
+
+	return state, nil
+}
+
+func (r *syncStateRepo) Save(ctx context.Context, state *models.SyncState) error {
+	query := `
+		INSERT INTO catalog_sync_state (` + syncStateColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (connector) DO UPDATE SET
+			last_run_at = EXCLUDED.last_run_at,
+			last_success_at = EXCLUDED.last_success_at,
+			last_error = EXCLUDED.last_error,
+			pulled = EXCLUDED.pulled,
+			created = EXCLUDED.created,
+			updated = EXCLUDED.updated,
+			pushed = EXCLUDED.pushed,
+			conflicts = EXCLUDED.conflicts,
+			skipped = EXCLUDED.skipped
+	`
+
+	_, err := r.db.Conn(ctx).ExecContext(ctx, query,
+		state.Connector,
+		state.LastRunAt,
+		state.LastSuccessAt,
+		state.LastError,
+		state.Pulled,
+		state.Created,
+		state.Updated,
+		state.Pushed,
+		state.Conflicts,
+		state.Skipped,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save sync state: %w", err)
+	}
+
+	return nil
+}
+
+func (r *syncStateRepo) List(ctx context.Context) ([]*models.SyncState, error) {
+	query := `SELECT ` + syncStateColumns + ` FROM catalog_sync_state ORDER BY connector`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync states: %w", err)
+	}
+
+	states := []*models.SyncState{}
+	if err := database.ScanAll(&states, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan sync states: %w", err)
+	}
+
+	return states, nil
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type TimelineRepository interface {
+	// ForProduct returns the entries of a product's timeline of the given
+	// kinds (all when none), newest first, from one snapshot
+	ForProduct(ctx context.Context, productID int, kinds []string, limit, offset int) ([]*models.TimelineEntry, error)
+
+	Count(ctx context.Context, productID int, kinds []string) (int, error)
+}
+
+type timelineRepo struct {
+	db *database.DB
+}
+
+func NewTimelineRepository(db *database.DB) TimelineRepository {
+	return &timelineRepo{db: db}
+}
+
+// priceChangesQuery lists the versions of the product bound at $%d, with the
+// unit price of the version before; the first version has none
+const priceChangesQuery = `
+	SELECT id, valid_from, unit_price, LAG(unit_price) OVER (ORDER BY valid_from, id) AS previous_price
+	FROM products_history
+	WHERE product_id = $%d
+`
+
+// timelineSources select the kind, ID, and time of a product's records, the
+// product bound at $%d. Audit entries name it by its ID as text.
+var timelineSources = map[string]string{
+	models.TimelineAudit: `SELECT 'audit' AS kind, id, created_at AS occurred_at FROM audit_log
+		WHERE entity_type = 'product' AND entity_id = $%d`,
+	models.TimelineStockMovement: `SELECT 'stock_movement' AS kind, id, created_at AS occurred_at FROM stock_movements
+		WHERE product_id = $%d`,
+	models.TimelinePriceChange: `SELECT 'price_change' AS kind, id, valid_from AS occurred_at FROM (` + priceChangesQuery + `) versions
+		WHERE previous_price <> unit_price`,
+	models.TimelineWebhookDelivery: `SELECT 'webhook_delivery' AS kind, id, created_at AS occurred_at FROM webhook_deliveries
+		WHERE product_id = $%d`,
+}
+
+// timeline returns the union of the sources of kinds, all when none, and its
+// arguments: each source binds the product itself, so one left out leaves no
+// placeholder unused
+func timeline(productID int, kinds []string) (string, []interface{}) {
+	if len(kinds) == 0 {
+		kinds = models.TimelineKinds
+	}
+
+	var branches []string
+	var args []interface{}
+	for _, kind := range models.TimelineKinds {
+		if !containsString(kinds, kind) {
+			continue
+		}
+		if kind == models.TimelineAudit {
+			args = append(args, strconv.Itoa(productID))
+		} else {
+			args = append(args, productID)
+		}
+		branches = append(branches, fmt.Sprintf(timelineSources[kind], len(args)))
+	}
+	return strings.Join(branches, "\nUNION ALL\n"), args
+}
+
+func (r *timelineRepo) ForProduct(ctx context.Context, productID int, kinds []string, limit, offset int) ([]*models.TimelineEntry, error) {
+	union, args := timeline(productID, kinds)
+	query := `SELECT kind, id FROM (` + union + `) timeline
+		ORDER BY occurred_at DESC, kind, id DESC
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+	args = append(args, limit, offset)
+
+	entries := []*models.TimelineEntry{}
+	err := r.db.WithTxOptions(ctx, r.db.Dialect().SnapshotTxOptions(), func(ctx context.Context) error {
+		rows, err := r.db.Conn(ctx).QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list timeline: %w", err)
+		}
+		ids := map[string][]int64{}
+		for rows.Next() {
+			entry := &models.TimelineEntry{}
+			if err := rows.Scan(&entry.Kind, &entry.ID); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan timeline: %w", err)
+			}
+			ids[entry.Kind] = append(ids[entry.Kind], entry.ID)
+			entries = append(entries, entry)
+		}
+		if err := rows.Close(); err != nil {
+			return fmt.Errorf("failed to list timeline: %w", err)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to list timeline: %w", err)
+		}
+
+		return r.load(ctx, productID, entries, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// load fills in the records of a page of entries, by kind
+func (r *timelineRepo) load(ctx context.Context, productID int, entries []*models.TimelineEntry, ids map[string][]int64) error {
+	dialect := r.db.Dialect()
+	conn := r.db.Conn(ctx)
+
+	audits := map[int64]*models.AuditEntry{}
+	if len(ids[models.TimelineAudit]) > 0 {
+		query := `SELECT id, action, actor, entity_type, COALESCE(entity_id, '') AS entity_id, details, created_at
+			FROM audit_log WHERE ` + dialect.AnyOf("id", 1)
+		rows, err := conn.QueryContext(ctx, query, dialect.Array(ids[models.TimelineAudit]))
+		if err != nil {
+			return fmt.Errorf("failed to load audit entries: %w", err)
+		}
+		var list []*models.AuditEntry
+		if err := database.ScanAll(&list, rows); err != nil {
+			return fmt.Errorf("failed to scan audit entries: %w", err)
+		}
+		for _, e := range list {
+			audits[e.ID] = e
+		}
+	}
+
+	movements := map[int64]*models.StockMovement{}
+	if len(ids[models.TimelineStockMovement]) > 0 {
+		query := `SELECT ` + stockMovementColumns + ` FROM stock_movements WHERE ` + dialect.AnyOf("id", 1)
+		rows, err := conn.QueryContext(ctx, query, dialect.Array(ids[models.TimelineStockMovement]))
+		if err != nil {
+			return fmt.Errorf("failed to load stock movements: %w", err)
+		}
+		var list []*models.StockMovement
+		if err := database.ScanAll(&list, rows); err != nil {
+			return fmt.Errorf("failed to scan stock movements: %w", err)
+		}
+		for _, m := range list {
+			movements[m.ID] = m
+		}
+	}
+
+	prices := map[int64]*models.PriceChange{}
+	if len(ids[models.TimelinePriceChange]) > 0 {
+		query := `SELECT id, previous_price, unit_price, valid_from FROM (` + fmt.Sprintf(priceChangesQuery, 1) + `) versions
+			WHERE ` + dialect.AnyOf("id", 2)
+		rows, err := conn.QueryContext(ctx, query, productID, dialect.Array(ids[models.TimelinePriceChange]))
+		if err != nil {
+			return fmt.Errorf("failed to load price changes: %w", err)
+		}
+		var list []*models.PriceChange
+		if err := database.ScanAll(&list, rows); err != nil {
+			return fmt.Errorf("failed to scan price changes: %w", err)
+		}
+		for _, c := range list {
+			prices[c.VersionID] = c
+		}
+	}
+
+	deliveries := map[int64]*models.WebhookDelivery{}
+	if len(ids[models.TimelineWebhookDelivery]) > 0 {
+		query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE ` + dialect.AnyOf("id", 1)
+		rows, err := conn.QueryContext(ctx, query, dialect.Array(ids[models.TimelineWebhookDelivery]))
+		if err != nil {
+			return fmt.Errorf("failed to load webhook deliveries: %w", err)
+		}
+		var list []*models.WebhookDelivery
+		if err := database.ScanAll(&list, rows); err != nil {
+			return fmt.Errorf("failed to scan webhook deliveries: %w", err)
+		}
+		for _, d := range list {
+			deliveries[d.ID] = d
+		}
+	}
+
+	for _, entry := range entries {
+		switch entry.Kind {
+		case models.TimelineAudit:
+			if entry.Audit = audits[entry.ID]; entry.Audit != nil {
+				entry.OccurredAt = entry.Audit.CreatedAt
+			}
+		case models.TimelineStockMovement:
+			if entry.StockMovement = movements[entry.ID]; entry.StockMovement != nil {
+				entry.OccurredAt = entry.StockMovement.CreatedAt
+			}
+		case models.TimelinePriceChange:
+			if entry.PriceChange = prices[entry.ID]; entry.PriceChange != nil {
+				entry.OccurredAt = entry.PriceChange.ChangedAt
+			}
+		case models.TimelineWebhookDelivery:
+			if entry.WebhookDelivery = deliveries[entry.ID]; entry.WebhookDelivery != nil {
+				entry.OccurredAt = entry.WebhookDelivery.CreatedAt
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *timelineRepo) Count(ctx context.Context, productID int, kinds []string) (int, error) {
+	union, args := timeline(productID, kinds)
+
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM (`+union+`) timeline`, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count timeline: %w", err)
+	}
+
+	return count, nil
+}
+
+// webhookDeliveryColumns is derived from the db tags of models.WebhookDelivery
+var webhookDeliveryColumns = database.ColumnList(models.WebhookDelivery{})
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
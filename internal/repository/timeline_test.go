@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func TestSQLite_TimelineRepository(t *testing.T) {
+	db := setupSQLiteDB(t)
+	products := NewProductRepository(db)
+	audit := NewAuditRepository(db)
+	subs := NewSubscriptionRepository(db)
+	repo := NewTimelineRepository(db)
+	ctx := context.Background()
+
+	product := &models.Product{SKU: "TL-1", Name: "Timed", Quantity: 10, UnitPrice: 10}
+	other := &models.Product{SKU: "TL-2", Name: "Other", Quantity: 1, UnitPrice: 1}
+	for _, p := range []*models.Product{product, other} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+	product.Name = "Renamed" // Not a price change
+	if err := products.Update(ctx, product); err != nil {
+		t.Fatalf("failed to update product: %v", err)
+	}
+	product.UnitPrice = 12.5
+	if err := products.Update(ctx, product); err != nil {
+		t.Fatalf("failed to update product: %v", err)
+	}
+	if _, err := products.AdjustStock(ctx, product.ID, -3); err != nil {
+		t.Fatalf("failed to adjust stock: %v", err)
+	}
+	for _, id := range []int{product.ID, other.ID} {
+		entry := &models.AuditEntry{Action: "product.restock", Actor: "admin", EntityType: "product", EntityID: strconv.Itoa(id), Details: []byte(`{}`)}
+		if err := audit.Create(ctx, entry); err != nil {
+			t.Fatalf("failed to create audit entry: %v", err)
+		}
+	}
+	if err := subs.RecordDelivery(ctx, &models.WebhookDelivery{SubscriptionID: 1, ProductID: product.ID, EventID: "e1", CallbackURL: "https://example.com/hook", Attempt: 1, StatusCode: 200}); err != nil {
+		t.Fatalf("failed to record delivery: %v", err)
+	}
+
+	entries, err := repo.ForProduct(ctx, product.ID, nil, 50, 0)
+	if err != nil {
+		t.Fatalf("ForProduct: %v", err)
+	}
+	kinds := map[string]int{}
+	for i, e := range entries {
+		kinds[e.Kind]++
+		if i > 0 && e.OccurredAt.After(entries[i-1].OccurredAt) {
+			t.Errorf("entry %d (%s) is newer than the one before", i, e.Kind)
+		}
+		switch {
+		case e.Kind == models.TimelineAudit && (e.Audit == nil || e.Audit.EntityID != strconv.Itoa(product.ID)),
+			e.Kind == models.TimelineStockMovement && (e.StockMovement == nil || e.StockMovement.ProductID != product.ID),
+			e.Kind == models.TimelineWebhookDelivery && (e.WebhookDelivery == nil || e.WebhookDelivery.StatusCode != 200):
+			t.Errorf("entry %+v doesn't carry the product's record", e)
+		case e.Kind == models.TimelinePriceChange && (e.PriceChange == nil || e.PriceChange.PreviousPrice != 10 || e.PriceChange.UnitPrice != 12.5):
+			t.Errorf("price change = %+v, want 10 to 12.5", e.PriceChange)
+		}
+		if e.OccurredAt.IsZero() {
+			t.Errorf("entry %+v has no time", e)
+		}
+	}
+	if kinds[models.TimelineAudit] != 1 || kinds[models.TimelinePriceChange] != 1 || kinds[models.TimelineWebhookDelivery] != 1 || kinds[models.TimelineStockMovement] == 0 {
+		t.Errorf("kinds = %v, want one audit entry, price change, and delivery, and movements", kinds)
+	}
+	if total, err := repo.Count(ctx, product.ID, nil); err != nil || total != len(entries) {
+		t.Errorf("Count = %d, %v, want %d", total, err, len(entries))
+	}
+
+	page, err := repo.ForProduct(ctx, product.ID, nil, 2, 1)
+	if err != nil || len(page) != 2 || page[0].Kind != entries[1].Kind || page[0].ID != entries[1].ID {
+		t.Errorf("ForProduct(limit 2, offset 1) = %v, %v, want entries 1 and 2", page, err)
+	}
+
+	kinded := []string{models.TimelinePriceChange, models.TimelineWebhookDelivery}
+	filtered, err := repo.ForProduct(ctx, product.ID, kinded, 50, 0)
+	if err != nil || len(filtered) != 2 {
+		t.Fatalf("ForProduct(%v) = %v, %v, want 2 entries", kinded, filtered, err)
+	}
+	if total, err := repo.Count(ctx, product.ID, kinded); err != nil || total != 2 {
+		t.Errorf("Count(%v) = %d, %v, want 2", kinded, total, err)
+	}
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// TrashRepository keeps deleted products until they are restored or purged.
+// Expired ones are purged by the trashed_products retention policy.
+type TrashRepository interface {
+	// Add stores product as trashed now
+	Add(ctx context.Context, product *models.Product) (*models.TrashedProduct, error)
+
+	GetByID(ctx context.Context, id int) (*models.TrashedProduct, error)
+
+	// Remove deletes a trashed product for good
+	Remove(ctx context.Context, id int) error
+
+	// List returns trashed products, most recently trashed first
+	List(ctx context.Context, limit, offset int) ([]*models.TrashedProduct, error)
+
+	Count(ctx context.Context) (int, error)
+}
+
+type trashRepo struct {
+	db *database.DB
+}
+
+func NewTrashRepository(db *database.DB) TrashRepository {
+	return &trashRepo{db: db}
+}
+
+// The product is scanned as JSON, so the columns are listed explicitly
+const trashColumns = `id, sku, name, product, trashed_at`
+
+func (r *trashRepo) Add(ctx context.Context, product *models.Product) (*models.TrashedProduct, error) {
+	query := `
+		INSERT INTO trashed_products (id, sku, name, product, trashed_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	snapshot, err := json.Marshal(product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode trashed product: %w", err)
+	}
+	trashed := &models.TrashedProduct{ID: product.ID, SKU: product.SKU, Name: product.Name, Product: *product, TrashedAt: time.Now()}
+
+	if _, err := r.db.Conn(ctx).ExecContext(ctx, query, trashed.ID, trashed.SKU, trashed.Name, snapshot, trashed.TrashedAt); err != nil {
+		return nil, fmt.Errorf("failed to trash product: %w", err)
+	}
+
+	return trashed, nil
+}
+
+func (r *trashRepo) GetByID(ctx context.Context, id int) (*models.TrashedProduct, error) {
+	query := `SELECT ` + trashColumns + ` FROM trashed_products WHERE id = $1`
+
+	trashed, err := scanTrashed(r.db.Conn(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("trashed product not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trashed product: %w", err)
+	}
+
+	return trashed, nil
+}
+
+func (r *trashRepo) Remove(ctx context.Context, id int) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `DELETE FROM trashed_products WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to remove trashed product: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("trashed product not found")
+	}
+
+	return nil
+}
+
+func (r *trashRepo) List(ctx context.Context, limit, offset int) ([]*models.TrashedProduct, error) {
+	query := `
+		SELECT ` + trashColumns + `
+		FROM trashed_products
+		ORDER BY trashed_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed products: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.TrashedProduct
+	for rows.Next() {
+		trashed, err := scanTrashed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trashed product: %w", err)
+		}
+		list = append(list, trashed)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list trashed products: %w", err)
+	}
+
+	return list, nil
+}
+
+func (r *trashRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM trashed_products`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count trashed products: %w", err)
+	}
+
+	return count, nil
+}
+
+// scanTrashed scans a row of trashColumns, decoding the product
+func scanTrashed(row interface{ Scan(...interface{}) error }) (*models.TrashedProduct, error) {
+	trashed := &models.TrashedProduct{}
+	var snapshot []byte
+	if err := row.Scan(&trashed.ID, &trashed.SKU, &trashed.Name, &snapshot, &trashed.TrashedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(snapshot, &trashed.Product); err != nil {
+		return nil, fmt.Errorf("failed to decode trashed product %d: %w", trashed.ID, err)
+	}
+	return trashed, nil
+}
@@ -0,0 +1,9 @@
+package repository
+
+import "context"
+
+// Transactor runs a function inside a database transaction. Repository calls
+// made with the ctx passed to fn take part in that transaction.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
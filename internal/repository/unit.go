@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type UnitRepository interface {
+	// List returns the units of measure by dimension, then factor
+	List(ctx context.Context) ([]*models.Unit, error)
+}
+
+type unitRepo struct {
+	db *database.DB
+}
+
+func NewUnitRepository(db *database.DB) UnitRepository {
+	return &unitRepo{db: db}
+}
+
+var unitColumns = database.ColumnList(models.Unit{})
+
+func (r *unitRepo) List(ctx context.Context) ([]*models.Unit, error) {
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, `SELECT `+unitColumns+` FROM units ORDER BY dimension, factor`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list units: %w", err)
+	}
+
+	var units []*models.Unit
+	if err := database.ScanAll(&units, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan units: %w", err)
+	}
+
+	return units, nil
+}
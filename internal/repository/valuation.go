@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+type ValuationRepository interface {
+	// CreateReceipt records a cost lot; it doesn't touch the product's stock
+	CreateReceipt(ctx context.Context, receipt *models.StockReceipt) error
+
+	// ListReceipts returns a product's receipts, most recently received first
+	ListReceipts(ctx context.Context, productID int, limit, offset int) ([]*models.StockReceipt, error)
+
+	CountReceipts(ctx context.Context, productID int) (int, error)
+
+	// EachStock calls fn with every product in stock, in ID order, and its
+	// receipts, most recently received first, read from one snapshot, and
+	// returns when the snapshot was taken. Only one product's receipts are
+	// held in memory at a time.
+	EachStock(ctx context.Context, fn func(productID, quantity int, receipts []*models.StockReceipt) error) (time.Time, error)
+
+	// RecordValuation keeps a valuation of the whole inventory
+	RecordValuation(ctx context.Context, valuation *models.Valuation) error
+
+	// LatestValuation returns the last valuation recorded with method, nil
+	// when there is none
+	LatestValuation(ctx context.Context, method string) (*models.Valuation, error)
+}
+
+type valuationRepo struct {
+	db *database.DB
+}
+
+func NewValuationRepository(db *database.DB) ValuationRepository {
+	return &valuationRepo{db: db}
+}
+
+var (
+	stockReceiptColumns = database.ColumnList(models.StockReceipt{})
+	valuationColumns    = database.ColumnList(models.Valuation{})
+)
+
+func (r *valuationRepo) CreateReceipt(ctx context.Context, receipt *models.StockReceipt) error {
+	query := `
+		INSERT INTO stock_receipts (product_id, quantity, unit_cost, reference, received_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	if receipt.ReceivedAt.IsZero() {
+		receipt.ReceivedAt = time.Now()
+	}
+	err := r.db.Conn(ctx).QueryRowContext(ctx, query, receipt.ProductID, receipt.Quantity, receipt.UnitCost, receipt.Reference, receipt.ReceivedAt).Scan(&receipt.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create stock receipt: %w", err)
+	}
+
+	return nil
+}
+
+func (r *valuationRepo) ListReceipts(ctx context.Context, productID int, limit, offset int) ([]*models.StockReceipt, error) {
+	query := `
+		SELECT ` + stockReceiptColumns + `
+		FROM stock_receipts
+		WHERE product_id = $1
+		ORDER BY received_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, productID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock receipts: %w", err)
+	}
+
+	receipts := []*models.StockReceipt{}
+	if err := database.ScanAll(&receipts, rows); err != nil {
+		return nil, fmt.Errorf("failed to scan stock receipts: %w", err)
+	}
+
+	return receipts, nil
+}
+
+func (r *valuationRepo) CountReceipts(ctx context.Context, productID int) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM stock_receipts WHERE product_id = $1`, productID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count stock receipts: %w", err)
+	}
+	return count, nil
+}
+
+func (r *valuationRepo) EachStock(ctx context.Context, fn func(productID, quantity int, receipts []*models.StockReceipt) error) (time.Time, error) {
+	query := `
+		SELECT p.id, p.quantity, r.id, r.quantity, r.unit_cost, r.reference, r.received_at
+		FROM products p
+		LEFT JOIN stock_receipts r ON r.product_id = p.id
+		WHERE p.quantity > 0
+		ORDER BY p.id, r.received_at DESC, r.id DESC
+	`
+
+	var snapshotAt time.Time
+	err := r.db.WithTxOptions(ctx, r.db.Dialect().SnapshotTxOptions(), func(ctx context.Context) error {
+		var err error
+		if snapshotAt, err = r.db.SnapshotTime(ctx); err != nil {
+			return err
+		}
+
+		rows, err := r.db.Conn(ctx).QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		productID, quantity := 0, 0
+		var receipts []*models.StockReceipt
+		for rows.Next() {
+			var id, onHand int
+			var receiptID, receiptQuantity sql.NullInt64
+			var unitCost sql.NullFloat64
+			var reference sql.NullString
+			var receivedAt sql.NullTime
+			if err := rows.Scan(&id, &onHand, &receiptID, &receiptQuantity, &unitCost, &reference, &receivedAt); err != nil {
+				return err
+			}
+
+			if id != productID {
+				if productID != 0 {
+					if err := fn(productID, quantity, receipts); err != nil {
+						return err
+					}
+				}
+				productID, quantity, receipts = id, onHand, nil
+			}
+			if receiptID.Valid {
+				receipts = append(receipts, &models.StockReceipt{
+					ID:         int(receiptID.Int64),
+					ProductID:  id,
+					Quantity:   int(receiptQuantity.Int64),
+					UnitCost:   unitCost.Float64,
+					Reference:  reference.String,
+					ReceivedAt: receivedAt.Time,
+				})
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if productID != 0 {
+			return fn(productID, quantity, receipts)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read stock: %w", err)
+	}
+
+	return snapshotAt, nil
+}
+
+func (r *valuationRepo) RecordValuation(ctx context.Context, valuation *models.Valuation) error {
+	query := `
+		INSERT INTO inventory_valuations (method, value, quantity, uncosted_quantity, valued_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Conn(ctx).ExecContext(ctx, query, valuation.Method, valuation.Value, valuation.Quantity, valuation.UncostedQuantity, valuation.ValuedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record inventory valuation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *valuationRepo) LatestValuation(ctx context.Context, method string) (*models.Valuation, error) {
+	query := `
+		SELECT ` + valuationColumns + `
+		FROM inventory_valuations
+		WHERE method = $1
+		ORDER BY valued_at DESC, id DESC
+		LIMIT 1
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory valuation: %w", err)
+	}
+
+	valuation := &models.Valuation{}
+	err = database.ScanOne(valuation, rows)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory valuation: %w", err)
+	}
+
+	return valuation, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+)
+
+// ProductVisibilityRepository tracks the visibility announced for products
+// with a visibility window; see models.Product.Listed
+type ProductVisibilityRepository interface {
+	// Pending returns the IDs of up to limit products, in order, whose
+	// visibility at differs from the one last announced
+	Pending(ctx context.Context, at time.Time, limit int) ([]int, error)
+
+	// SetListed records the visibility announced for a product. It leaves
+	// updated_at alone: the product itself didn't change.
+	SetListed(ctx context.Context, id int, listed bool) error
+}
+
+type productVisibilityRepo struct {
+	db *database.DB
+}
+
+func NewProductVisibilityRepository(db *database.DB) ProductVisibilityRepository {
+	return &productVisibilityRepo{db: db}
+}
+
+func (r *productVisibilityRepo) Pending(ctx context.Context, at time.Time, limit int) ([]int, error) {
+	// The first condition is that of idx_products_visibility
+	visible := visibleCondition("$1")
+	query := `
+		SELECT id FROM products
+		WHERE (visible_from IS NOT NULL OR visible_until IS NOT NULL OR NOT listed)
+			AND ((listed AND NOT ` + visible + `) OR (NOT listed AND ` + visible + `))
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Conn(ctx).QueryContext(ctx, query, at, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products changing visibility: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan product ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list products changing visibility: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (r *productVisibilityRepo) SetListed(ctx context.Context, id int, listed bool) error {
+	result, err := r.db.Conn(ctx).ExecContext(ctx, `UPDATE products SET listed = $2 WHERE id = $1`, id, listed)
+	if err != nil {
+		return fmt.Errorf("failed to update product visibility: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("product not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+// Package repricing changes the unit prices of the products matching a
+// filter by a percentage rule, in bulk.
+//
+// An update is previewed first: the preview lists the products it changes
+// with their old and new prices, and a token over the filter, the rule, and
+// those prices. Applying takes the token, and is refused when it no longer
+// matches what the update would change, so nothing is changed that wasn't
+// seen. It then runs in batches, each its own transaction locking its
+// products, pricing them anew from the locked price, and auditing the batch;
+// the history triggers record every price change as a product version.
+package repricing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/pricing"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// BatchSize is how many products each transaction of an update reprices
+const BatchSize = 100
+
+// pageSize is how many products are read at a time while previewing
+const pageSize = 1000
+
+var (
+	ErrInvalidUpdate = errors.New("invalid price update")
+
+	// ErrStalePreview is returned applying an update whose preview token
+	// doesn't match what it would change anymore
+	ErrStalePreview = errors.New("preview is out of date")
+)
+
+// Service previews and applies bulk price updates
+type Service struct {
+	products  repository.ProductRepository
+	audit     repository.AuditRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	rules     *pricing.Rules
+	logger    *slog.Logger
+}
+
+func NewService(products repository.ProductRepository, audit repository.AuditRepository, tx repository.Transactor, publisher events.Publisher, rules *pricing.Rules, logger *slog.Logger) *Service {
+	return &Service{
+		products:  products,
+		audit:     audit,
+		tx:        tx,
+		publisher: publisher,
+		rules:     rules,
+		logger:    logger,
+	}
+}
+
+// Preview returns the products matching filter whose price rule changes,
+// by ID, without changing them
+func (s *Service) Preview(ctx context.Context, filter models.ProductFilter, rule models.PriceRule) (*models.PriceUpdatePreview, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: filter: %v", ErrInvalidUpdate, err)
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: rule: %v", ErrInvalidUpdate, err)
+	}
+
+	// Pages may overlap when products change between them; IDs dedupe them
+	rows := make(map[int]*models.PriceUpdateRow)
+	for offset := 0; ; offset += pageSize {
+		page, err := s.products.ListFiltered(ctx, filter, pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page {
+			if price := s.rules.Adjust(p.UnitPrice, rule); price != p.UnitPrice {
+				rows[p.ID] = &models.PriceUpdateRow{ProductID: p.ID, SKU: p.SKU, Name: p.Name, OldPrice: p.UnitPrice, NewPrice: price}
+			}
+		}
+		if len(rows) > models.MaxPriceUpdate {
+			return nil, fmt.Errorf("%w: the filter matches more than %d products to reprice, narrow it", ErrInvalidUpdate, models.MaxPriceUpdate)
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+
+	preview := &models.PriceUpdatePreview{Affected: len(rows), Products: make([]*models.PriceUpdateRow, 0, len(rows))}
+	for _, row := range rows {
+		preview.Products = append(preview.Products, row)
+	}
+	sort.Slice(preview.Products, func(i, j int) bool { return preview.Products[i].ProductID < preview.Products[j].ProductID })
+	preview.PreviewToken = token(filter, rule, preview.Products)
+	return preview, nil
+}
+
+// token digests an update and the changes it previewed
+func token(filter models.ProductFilter, rule models.PriceRule, rows []*models.PriceUpdateRow) string {
+	changes := make([][3]int64, len(rows))
+	for i, row := range rows {
+		changes[i] = [3]int64{int64(row.ProductID), int64(math.Round(row.OldPrice * 100)), int64(math.Round(row.NewPrice * 100))}
+	}
+	b, _ := json.Marshal(struct {
+		Filter  models.ProductFilter `json:"filter"`
+		Rule    models.PriceRule     `json:"rule"`
+		Changes [][3]int64           `json:"changes"`
+	}{filter, rule, changes})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Apply makes the changes of a preview, given its token, in batches of
+// BatchSize by product ID. Batches done stay done when a later one fails:
+// the result then has the products changed so far.
+func (s *Service) Apply(ctx context.Context, filter models.ProductFilter, rule models.PriceRule, previewToken, actor string) (*models.PriceUpdateResult, error) {
+	if previewToken == "" {
+		return nil, fmt.Errorf("%w: preview_token is required, preview the update first", ErrInvalidUpdate)
+	}
+	preview, err := s.Preview(ctx, filter, rule)
+	if err != nil {
+		return nil, err
+	}
+	if preview.PreviewToken != previewToken {
+		return nil, ErrStalePreview
+	}
+
+	result := &models.PriceUpdateResult{Products: []*models.PriceUpdateRow{}}
+	for start := 0; start < len(preview.Products); start += BatchSize {
+		batch := preview.Products[start:min(start+BatchSize, len(preview.Products))]
+		changed, err := s.batch(ctx, batch, rule, result.Batches+1, actor)
+		if err != nil {
+			return result, fmt.Errorf("batch %d: %w", result.Batches+1, err)
+		}
+		result.Batches++
+		result.Updated += len(changed)
+		result.Products = append(result.Products, changed...)
+	}
+
+	s.logger.Info("prices updated", "products", result.Updated, "batches", result.Batches, "percent", rule.Percent)
+	return result, nil
+}
+
+// batch reprices products in one transaction, from their locked prices.
+// Products deleted since the preview, or whose price the rule no longer
+// changes, are skipped.
+func (s *Service) batch(ctx context.Context, rows []*models.PriceUpdateRow, rule models.PriceRule, n int, actor string) ([]*models.PriceUpdateRow, error) {
+	var changed []*models.PriceUpdateRow
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		changed = make([]*models.PriceUpdateRow, 0, len(rows))
+		var evts []events.Event
+		for _, row := range rows {
+			before, err := s.products.GetByIDForUpdate(ctx, row.ProductID)
+			if err != nil {
+				if err.Error() == "product not found" {
+					continue
+				}
+				return err
+			}
+			price := s.rules.Adjust(before.UnitPrice, rule)
+			if price == before.UnitPrice {
+				continue
+			}
+
+			after := *before
+			after.UnitPrice = price
+			if err := s.products.Update(ctx, &after); err != nil {
+				return err
+			}
+			evts = append(evts, events.ProductUpdates(before, &after, "price_update")...)
+			changed = append(changed, &models.PriceUpdateRow{ProductID: after.ID, SKU: after.SKU, Name: after.Name, OldPrice: before.UnitPrice, NewPrice: price})
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+		if err := s.publisher.Publish(ctx, evts...); err != nil {
+			return err
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{"rule": rule, "batch": n, "products": changed})
+		entry := &models.AuditEntry{
+			Action:     "product.price_update",
+			Actor:      actor,
+			EntityType: "product",
+			Details:    details,
+		}
+		database.AfterCommit(ctx, func() {
+			s.logger.Debug("price update batch committed", "batch", n, "products", len(changed))
+		})
+		return s.audit.Create(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
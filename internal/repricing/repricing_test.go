@@ -0,0 +1,108 @@
+package repricing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/pricing"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestService_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "repricing.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	products := repository.NewProductRepository(db)
+	rules, err := pricing.New(pricing.Config{})
+	if err != nil {
+		t.Fatalf("pricing.New() error = %v", err)
+	}
+	bus := events.NewBus(logger)
+	var updated []events.ProductUpdated
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		updated = append(updated, event.Payload.(events.ProductUpdated))
+		return nil
+	}, events.TypeProductUpdated)
+	service := NewService(products, repository.NewAuditRepository(db), db, bus, rules, logger)
+
+	widget := &models.Product{SKU: "RP-1", Name: "Widget", Category: "tools", UnitPrice: 10}
+	gadget := &models.Product{SKU: "RP-2", Name: "Gadget", Category: "tools", UnitPrice: 20.99}
+	book := &models.Product{SKU: "RP-3", Name: "Book", Category: "books", UnitPrice: 5}
+	for _, p := range []*models.Product{widget, gadget, book} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	filter := models.ProductFilter{Category: "tools"}
+	ending := 0.99
+	rule := models.PriceRule{Percent: 5, EndWith: &ending}
+
+	if _, err := service.Preview(ctx, filter, models.PriceRule{Percent: -100}); !errors.Is(err, ErrInvalidUpdate) {
+		t.Errorf("Preview(-100%%) error = %v, want ErrInvalidUpdate", err)
+	}
+
+	// 10.50 ends up at 10.99; 22.04 at 21.99, so both change
+	preview, err := service.Preview(ctx, filter, rule)
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if preview.Affected != 2 || preview.Products[0].ProductID != widget.ID || preview.Products[0].NewPrice != 10.99 || preview.Products[1].NewPrice != 21.99 {
+		t.Fatalf("Preview() = %+v", preview)
+	}
+	if p, _ := products.GetByID(ctx, widget.ID); p.UnitPrice != 10 {
+		t.Errorf("Preview() changed the price to %v", p.UnitPrice)
+	}
+
+	if _, err := service.Apply(ctx, filter, rule, "", "admin"); !errors.Is(err, ErrInvalidUpdate) {
+		t.Errorf("Apply(no token) error = %v, want ErrInvalidUpdate", err)
+	}
+	if _, err := service.Apply(ctx, filter, models.PriceRule{Percent: 6, EndWith: &ending}, preview.PreviewToken, "admin"); !errors.Is(err, ErrStalePreview) {
+		t.Errorf("Apply(other rule) error = %v, want ErrStalePreview", err)
+	}
+
+	// A price changed since the preview makes it stale
+	gadget.UnitPrice = 19.99
+	if err := products.Update(ctx, gadget); err != nil {
+		t.Fatalf("failed to update product: %v", err)
+	}
+	if _, err := service.Apply(ctx, filter, rule, preview.PreviewToken, "admin"); !errors.Is(err, ErrStalePreview) {
+		t.Errorf("Apply(stale) error = %v, want ErrStalePreview", err)
+	}
+
+	if preview, err = service.Preview(ctx, filter, rule); err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	result, err := service.Apply(ctx, filter, rule, preview.PreviewToken, "admin")
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if result.Updated != 2 || result.Batches != 1 || len(updated) != 2 {
+		t.Errorf("Apply() = %+v, published %d updates", result, len(updated))
+	}
+	for id, want := range map[int]float64{widget.ID: 10.99, gadget.ID: 20.99, book.ID: 5} {
+		if p, _ := products.GetByID(ctx, id); p.UnitPrice != want {
+			t.Errorf("product %d price = %v, want %v", id, p.UnitPrice, want)
+		}
+	}
+
+	// Applied again, the rule changes nothing it hasn't
+	if preview, err = service.Preview(ctx, filter, models.PriceRule{EndWith: &ending}); err != nil || preview.Affected != 0 {
+		t.Errorf("Preview(applied) = %+v, %v", preview, err)
+	}
+}
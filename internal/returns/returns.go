@@ -0,0 +1,314 @@
+// Package returns runs returns (RMAs) of sold units: a return is authorized
+// for a product, optionally naming the order it was sold on, received, and
+// inspected, which dispositions it.
+//
+// A return is restocked, adding its units back to the product's quantity;
+// scrapped, leaving stock as it is; or sent for repair, to be inspected
+// again and restocked or scrapped once repaired. Every step is audited, and
+// a restock is a stock movement like any other: it publishes StockAdjusted
+// with reason "return", and the return references the movement it recorded.
+package returns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Return statuses
+const (
+	StatusAuthorized = "authorized"
+	StatusReceived   = "received"
+	StatusRepairing  = "repairing"
+	StatusRestocked  = "restocked"
+	StatusScrapped   = "scrapped"
+	StatusCancelled  = "cancelled"
+)
+
+// Statuses are the statuses of returns, in the order they're reached
+var Statuses = []string{StatusAuthorized, StatusReceived, StatusRepairing, StatusRestocked, StatusScrapped, StatusCancelled}
+
+// Dispositions of an inspected return
+const (
+	DispositionRestock = "restock"
+	DispositionScrap   = "scrap"
+	DispositionRepair  = "repair"
+)
+
+// Dispositions are the outcomes an inspection can have
+var Dispositions = []string{DispositionRestock, DispositionScrap, DispositionRepair}
+
+var (
+	ErrInvalidReturn = errors.New("invalid return")
+
+	// ErrInvalidStep is returned for a step the return's status doesn't
+	// allow, e.g. inspecting a return not received yet
+	ErrInvalidStep = errors.New("step not allowed")
+)
+
+// ValidStatus reports whether status is one of Statuses
+func ValidStatus(status string) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Service authorizes, receives, and dispositions returns, auditing every
+// step
+type Service struct {
+	repo      repository.ReturnRepository
+	products  repository.ProductRepository
+	movements repository.StockMovementRepository
+	audit     repository.AuditRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	logger    *slog.Logger
+}
+
+func NewService(repo repository.ReturnRepository, products repository.ProductRepository, movements repository.StockMovementRepository, audit repository.AuditRepository, tx repository.Transactor, publisher events.Publisher, logger *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		products:  products,
+		movements: movements,
+		audit:     audit,
+		tx:        tx,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Create authorizes a return of a product, by ID or SKU. The order isn't
+// checked: order lines aren't kept, and the keys of processed orders are
+// purged after PROCESSED_ORDER_RETENTION, long before most returns.
+func (s *Service) Create(ctx context.Context, req models.ReturnRequest, actor string) (*models.Return, error) {
+	req.SKU = strings.TrimSpace(req.SKU)
+	req.OrderSource = strings.TrimSpace(req.OrderSource)
+	req.OrderID = strings.TrimSpace(req.OrderID)
+	switch {
+	case (req.SKU == "") == (req.ProductID == 0):
+		return nil, fmt.Errorf("%w: exactly one of product_id and sku is required", ErrInvalidReturn)
+	case req.Quantity <= 0:
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidReturn)
+	case len(req.OrderSource) > 100:
+		return nil, fmt.Errorf("%w: order_source must be at most 100 characters", ErrInvalidReturn)
+	case len(req.OrderID) > 255:
+		return nil, fmt.Errorf("%w: order_id must be at most 255 characters", ErrInvalidReturn)
+	case req.OrderSource != "" && req.OrderID == "":
+		return nil, fmt.Errorf("%w: order_source needs an order_id", ErrInvalidReturn)
+	}
+
+	var product *models.Product
+	var err error
+	if req.SKU != "" {
+		product, err = s.products.GetBySKU(ctx, req.SKU)
+	} else {
+		product, err = s.products.GetByID(ctx, req.ProductID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &models.Return{
+		ProductID:   product.ID,
+		SKU:         product.SKU,
+		Quantity:    req.Quantity,
+		OrderSource: req.OrderSource,
+		OrderID:     req.OrderID,
+		Reason:      strings.TrimSpace(req.Reason),
+		Status:      StatusAuthorized,
+		CreatedBy:   actor,
+	}
+	err = s.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, ret); err != nil {
+			return err
+		}
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("return authorized", "return_id", ret.ID, "product_id", ret.ProductID, "quantity", ret.Quantity)
+		})
+		return s.record(ctx, "return.create", actor, ret, map[string]interface{}{
+			"product_id":   ret.ProductID,
+			"sku":          ret.SKU,
+			"quantity":     ret.Quantity,
+			"order_source": ret.OrderSource,
+			"order_id":     ret.OrderID,
+			"reason":       ret.Reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Receive records an authorized return's units as arrived, for inspection
+func (s *Service) Receive(ctx context.Context, id int, actor string) (*models.Return, error) {
+	return s.step(ctx, id, "receive", actor, []string{StatusAuthorized}, func(ctx context.Context, ret *models.Return) (map[string]interface{}, error) {
+		now := time.Now()
+		ret.Status, ret.ReceivedAt = StatusReceived, &now
+		return nil, nil
+	})
+}
+
+// Inspect dispositions a received return, or one back from repair, with the
+// inspection's notes. Restocking adds its units to the product's quantity,
+// in the same transaction; a return back from repair can't go for repair
+// again.
+func (s *Service) Inspect(ctx context.Context, id int, disposition, notes, actor string) (*models.Return, error) {
+	allowed := []string{StatusReceived, StatusRepairing}
+	switch disposition {
+	case DispositionRestock, DispositionScrap:
+	case DispositionRepair:
+		allowed = []string{StatusReceived}
+	default:
+		return nil, fmt.Errorf("%w: disposition must be one of %s", ErrInvalidReturn, strings.Join(Dispositions, ", "))
+	}
+
+	return s.step(ctx, id, "inspect", actor, allowed, func(ctx context.Context, ret *models.Return) (map[string]interface{}, error) {
+		ret.Disposition = disposition
+		if notes = strings.TrimSpace(notes); notes != "" {
+			if ret.Notes != "" {
+				ret.Notes += "\n"
+			}
+			ret.Notes += notes
+		}
+
+		now := time.Now()
+		switch disposition {
+		case DispositionRepair:
+			ret.Status = StatusRepairing
+		case DispositionScrap:
+			ret.Status, ret.ClosedAt = StatusScrapped, &now
+		case DispositionRestock:
+			ret.Status, ret.ClosedAt = StatusRestocked, &now
+			if err := s.restock(ctx, ret, actor); err != nil {
+				return nil, err
+			}
+		}
+		return map[string]interface{}{"disposition": disposition, "notes": notes}, nil
+	})
+}
+
+// restock adds a return's units back to its product's stock
+func (s *Service) restock(ctx context.Context, ret *models.Return, actor string) error {
+	after, err := s.products.AdjustStock(ctx, ret.ProductID, ret.Quantity)
+	if err != nil {
+		return err
+	}
+	movement, err := s.movements.Latest(ctx, ret.ProductID)
+	if err != nil {
+		return err
+	}
+	ret.StockMovementID = &movement.ID
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"return_id":         ret.ID,
+		"sku":               after.SKU,
+		"previous":          after.Quantity - ret.Quantity,
+		"current":           after.Quantity,
+		"delta":             ret.Quantity,
+		"stock_movement_id": movement.ID,
+	})
+	entry := &models.AuditEntry{
+		Action:     "stock.adjust",
+		Actor:      actor,
+		EntityType: "product",
+		EntityID:   strconv.Itoa(after.ID),
+		Details:    details,
+	}
+	if err := s.audit.Create(ctx, entry); err != nil {
+		return err
+	}
+
+	return s.publisher.Publish(ctx, events.New(events.StockAdjusted{
+		ProductID: after.ID,
+		SKU:       after.SKU,
+		Previous:  after.Quantity - ret.Quantity,
+		Current:   after.Quantity,
+		Delta:     ret.Quantity,
+		Reason:    "return",
+	}))
+}
+
+// Cancel closes a return that hasn't been inspected, e.g. one the customer
+// never sent
+func (s *Service) Cancel(ctx context.Context, id int, actor string) (*models.Return, error) {
+	return s.step(ctx, id, "cancel", actor, []string{StatusAuthorized, StatusReceived}, func(ctx context.Context, ret *models.Return) (map[string]interface{}, error) {
+		now := time.Now()
+		ret.Status, ret.ClosedAt = StatusCancelled, &now
+		return nil, nil
+	})
+}
+
+// step runs a step of a return in one transaction: it locks the return,
+// checks its status is one of allowed, applies the step, stores the return,
+// and audits the step as "return.<name>"
+func (s *Service) step(ctx context.Context, id int, name, actor string, allowed []string, apply func(context.Context, *models.Return) (map[string]interface{}, error)) (*models.Return, error) {
+	var ret *models.Return
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if ret, err = s.repo.GetByIDForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if !contains(allowed, ret.Status) {
+			return fmt.Errorf("%w: can't %s a return that's %s", ErrInvalidStep, name, ret.Status)
+		}
+
+		from := ret.Status
+		details, err := apply(ctx, ret)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.Update(ctx, ret); err != nil {
+			return err
+		}
+
+		if details == nil {
+			details = map[string]interface{}{}
+		}
+		details["from"], details["status"] = from, ret.Status
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("return updated", "return_id", ret.ID, "step", name, "status", ret.Status)
+		})
+		return s.record(ctx, "return."+name, actor, ret, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// record audits a step of a return, in its transaction
+func (s *Service) record(ctx context.Context, action, actor string, ret *models.Return, details map[string]interface{}) error {
+	entry := &models.AuditEntry{
+		Action:     action,
+		Actor:      actor,
+		EntityType: "return",
+		EntityID:   strconv.Itoa(ret.ID),
+	}
+	entry.Details, _ = json.Marshal(details)
+	return s.audit.Create(ctx, entry)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package returns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestService_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "returns.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	products := repository.NewProductRepository(db)
+	audit := repository.NewAuditRepository(db)
+	repo := repository.NewReturnRepository(db)
+	service := NewService(repo, products, repository.NewStockMovementRepository(db), audit, db, events.NewBus(logger), logger)
+
+	widget := &models.Product{SKU: "RT-1", Name: "Widget", Quantity: 10, UnitPrice: 1}
+	if err := products.Create(ctx, widget); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	for _, req := range []models.ReturnRequest{
+		{Quantity: 1},
+		{ProductID: widget.ID, SKU: "RT-1", Quantity: 1},
+		{SKU: "RT-1", Quantity: 0},
+		{SKU: "RT-1", Quantity: 1, OrderSource: "shop"},
+	} {
+		if _, err := service.Create(ctx, req, "tester"); !errors.Is(err, ErrInvalidReturn) {
+			t.Errorf("Create(%+v) error = %v, want ErrInvalidReturn", req, err)
+		}
+	}
+	if _, err := service.Create(ctx, models.ReturnRequest{SKU: "NOPE", Quantity: 1}, "tester"); err == nil || err.Error() != "product not found" {
+		t.Errorf("Create(unknown SKU) error = %v, want product not found", err)
+	}
+
+	restocked, err := service.Create(ctx, models.ReturnRequest{SKU: "RT-1", Quantity: 3, OrderSource: "shop", OrderID: "A-1"}, "tester")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if restocked.Status != StatusAuthorized || restocked.ProductID != widget.ID {
+		t.Errorf("Create() = %+v", restocked)
+	}
+	if _, err := service.Inspect(ctx, restocked.ID, DispositionRestock, "", "tester"); !errors.Is(err, ErrInvalidStep) {
+		t.Errorf("Inspect(authorized) error = %v, want ErrInvalidStep", err)
+	}
+	if _, err := service.Receive(ctx, restocked.ID, "tester"); err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if _, err := service.Inspect(ctx, restocked.ID, "keep", "", "tester"); !errors.Is(err, ErrInvalidReturn) {
+		t.Errorf("Inspect(keep) error = %v, want ErrInvalidReturn", err)
+	}
+	if _, err := service.Inspect(ctx, restocked.ID, DispositionRepair, "Scratched", "tester"); err != nil {
+		t.Fatalf("Inspect(repair) error = %v", err)
+	}
+	if _, err := service.Inspect(ctx, restocked.ID, DispositionRepair, "", "tester"); !errors.Is(err, ErrInvalidStep) {
+		t.Errorf("Inspect(repair) twice error = %v, want ErrInvalidStep", err)
+	}
+	restocked, err = service.Inspect(ctx, restocked.ID, DispositionRestock, "Repaired", "tester")
+	if err != nil {
+		t.Fatalf("Inspect(restock) error = %v", err)
+	}
+	if restocked.Status != StatusRestocked || restocked.ClosedAt == nil || restocked.StockMovementID == nil || restocked.Notes != "Scratched\nRepaired" {
+		t.Errorf("Inspect(restock) = %+v", restocked)
+	}
+	if p, _ := products.GetByID(ctx, widget.ID); p.Quantity != 13 {
+		t.Errorf("quantity after restock = %d, want 13", p.Quantity)
+	}
+	if _, err := service.Cancel(ctx, restocked.ID, "tester"); !errors.Is(err, ErrInvalidStep) {
+		t.Errorf("Cancel(restocked) error = %v, want ErrInvalidStep", err)
+	}
+
+	scrapped, err := service.Create(ctx, models.ReturnRequest{ProductID: widget.ID, Quantity: 2}, "tester")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := service.Receive(ctx, scrapped.ID, "tester"); err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if scrapped, err = service.Inspect(ctx, scrapped.ID, DispositionScrap, "", "tester"); err != nil || scrapped.Status != StatusScrapped {
+		t.Fatalf("Inspect(scrap) = %+v, %v", scrapped, err)
+	}
+	if p, _ := products.GetByID(ctx, widget.ID); p.Quantity != 13 {
+		t.Errorf("quantity after scrap = %d, want 13", p.Quantity)
+	}
+
+	cancelled, err := service.Create(ctx, models.ReturnRequest{SKU: "RT-1", Quantity: 1}, "tester")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if cancelled, err = service.Cancel(ctx, cancelled.ID, "tester"); err != nil || cancelled.Status != StatusCancelled {
+		t.Fatalf("Cancel() = %+v, %v", cancelled, err)
+	}
+	if _, err := service.Receive(ctx, 999, "tester"); err == nil || err.Error() != "return not found" {
+		t.Errorf("Receive(999) error = %v, want return not found", err)
+	}
+
+	list, err := repo.List(ctx, models.ReturnFilter{OrderSource: "shop", OrderID: "A-1"}, 10, 0)
+	if err != nil || len(list) != 1 || list[0].ID != restocked.ID {
+		t.Errorf("List(order A-1) = %v, %v", list, err)
+	}
+	if n, err := repo.Count(ctx, models.ReturnFilter{ProductID: widget.ID}); err != nil || n != 3 {
+		t.Errorf("Count(product) = %d, %v, want 3", n, err)
+	}
+
+	entries, err := audit.List(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := map[string]int{}
+	for _, e := range entries {
+		actions[e.Action]++
+	}
+	if actions["return.create"] != 3 || actions["return.receive"] != 2 || actions["return.inspect"] != 3 || actions["return.cancel"] != 1 || actions["stock.adjust"] != 1 {
+		t.Errorf("audited actions = %v", actions)
+	}
+}
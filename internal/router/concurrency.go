@@ -0,0 +1,121 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/config"
+	"{{MODULE_NAME}}/internal/quota"
+	"{{MODULE_NAME}}/internal/tokens"
+)
+
+var (
+	concurrencyInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_concurrency_in_flight",
+		Help: "Requests being served, by route group.",
+	}, []string{"group"})
+	concurrencyLimit = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_concurrency_limit",
+		Help: "Requests a route group may serve at once, 0 when unlimited.",
+	}, []string{"group"})
+	concurrencyRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_concurrency_rejected_total",
+		Help: "Requests refused with 503, by route group and the limit hit (route or tenant).",
+	}, []string{"group", "limit"})
+)
+
+// ConcurrencyLimiter bounds the requests served at once per route group, and
+// per tenant within a group, so one noisy client can't take every database
+// connection. Requests over a limit are refused rather than queued, as
+// queued requests would hold their connections and time out anyway. Limits
+// are read from the request's configuration snapshot.
+type ConcurrencyLimiter struct {
+	mu       sync.Mutex
+	inFlight map[limitKey]int // Entries are removed when they reach zero
+}
+
+type limitKey struct {
+	group  string
+	tenant string // Empty for the group as a whole
+}
+
+func NewConcurrencyLimiter() *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{inFlight: make(map[limitKey]int)}
+}
+
+// Middleware limits the routes it's installed on as group. Must be installed
+// after ConfigMiddleware, RealIP, ScopedTokens, and Quota.
+func (cl *ConcurrencyLimiter) Middleware(group string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cfg := config.FromContext(r.Context())
+			if cfg == nil || (cfg.ConcurrencyLimitRoute <= 0 && cfg.ConcurrencyLimitTenant <= 0) {
+				concurrencyLimit.WithLabelValues(group).Set(0)
+				next.ServeHTTP(w, r)
+				return
+			}
+			concurrencyLimit.WithLabelValues(group).Set(float64(cfg.ConcurrencyLimitRoute))
+
+			tenant := limitTenant(r)
+			if limit := cl.acquire(group, tenant, cfg.ConcurrencyLimitRoute, cfg.ConcurrencyLimitTenant); limit != "" {
+				concurrencyRejected.WithLabelValues(group, limit).Inc()
+				w.Header().Set("Retry-After", "1")
+				writeError(w, http.StatusServiceUnavailable, "Too many concurrent requests, retry shortly")
+				return
+			}
+			defer cl.release(group, tenant)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitTenant returns who a request's tenant limit is counted for: its API
+// key, its scoped token's subject, or else its client IP. Clients choose
+// X-Tenant-ID, so it doesn't count: a fresh value per request would escape
+// the limit.
+func limitTenant(r *http.Request) string {
+	if key := quota.FromContext(r.Context()); key != nil {
+		return "key:" + strconv.Itoa(key.ID)
+	}
+	if claims := tokens.FromContext(r.Context()); claims != nil {
+		return "token:" + claims.Subject
+	}
+	return "ip:" + clientIP(r)
+}
+
+// acquire takes a slot for tenant in group, or returns the limit that is
+// full. A limit of zero or less is no limit.
+func (cl *ConcurrencyLimiter) acquire(group, tenant string, routeLimit, tenantLimit int) string {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	groupKey := limitKey{group: group}
+	tenantKey := limitKey{group: group, tenant: tenant}
+	if routeLimit > 0 && cl.inFlight[groupKey] >= routeLimit {
+		return "route"
+	}
+	if tenantLimit > 0 && cl.inFlight[tenantKey] >= tenantLimit {
+		return "tenant"
+	}
+
+	cl.inFlight[groupKey]++
+	cl.inFlight[tenantKey]++
+	concurrencyInFlight.WithLabelValues(group).Set(float64(cl.inFlight[groupKey]))
+	return ""
+}
+
+func (cl *ConcurrencyLimiter) release(group, tenant string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	for _, key := range []limitKey{{group: group}, {group: group, tenant: tenant}} {
+		if cl.inFlight[key]--; cl.inFlight[key] <= 0 {
+			delete(cl.inFlight, key)
+		}
+	}
+	concurrencyInFlight.WithLabelValues(group).Set(float64(cl.inFlight[limitKey{group: group}]))
+}
@@ -0,0 +1,102 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/config"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/quota"
+	"{{MODULE_NAME}}/internal/tokens"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	cfg := &config.Config{ConcurrencyLimitRoute: 3, ConcurrencyLimitTenant: 2}
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	handler := NewConcurrencyLimiter().Middleware("products")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Done()
+		<-release
+	}))
+
+	// Requests are made with API key 1 or 2, or by IP
+	serve := func(keyID int, remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/products", nil)
+		ctx := config.WithConfig(r.Context(), cfg)
+		if keyID != 0 {
+			ctx = quota.WithKey(ctx, &models.APIKey{ID: keyID})
+		}
+		r = r.WithContext(ctx)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// Fill key 1's slots and the last slot of the route
+	var done sync.WaitGroup
+	for _, keyID := range []int{1, 1, 2} {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			serve(keyID, "192.0.2.1:1234")
+		}()
+	}
+	started.Wait()
+
+	for name, keyID := range map[string]int{"tenant limit": 1, "route limit": 3, "without key": 0} {
+		w := serve(keyID, "192.0.2.1:1234")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: status = %d, want %d", name, w.Code, http.StatusServiceUnavailable)
+		}
+		if got := w.Header().Get("Retry-After"); got != "1" {
+			t.Errorf("%s: Retry-After = %q, want %q", name, got, "1")
+		}
+	}
+
+	close(release)
+	done.Wait()
+
+	// Slots are given back when requests finish
+	started.Add(1)
+	if w := serve(1, "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("after release: status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestLimitTenant(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    *models.APIKey
+		claims *tokens.Claims
+		header string
+		want   string
+	}{
+		{name: "API key", key: &models.APIKey{ID: 7}, header: "other", want: "key:7"},
+		{name: "token", claims: &tokens.Claims{Subject: "ticket 4711", Scope: tokens.Scope{ReadOnly: true}}, header: "other", want: "token:ticket 4711"},
+		{name: "header alone", header: "acme", want: "ip:192.0.2.1"},
+		{name: "anonymous", want: "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/products", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			if tt.header != "" {
+				r.Header.Set("X-Tenant-ID", tt.header)
+			}
+			ctx := r.Context()
+			if tt.key != nil {
+				ctx = quota.WithKey(ctx, tt.key)
+			}
+			if tt.claims != nil {
+				ctx = tokens.WithClaims(ctx, tt.claims)
+			}
+			if got := limitTenant(r.WithContext(ctx)); got != tt.want {
+				t.Errorf("limitTenant() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var deprecatedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_deprecated_requests_total",
+	Help: "Requests to deprecated routes, by route. A route nobody calls any more is safe to remove.",
+}, []string{"route"})
+
+// Deprecation describes a deprecated route
+type Deprecation struct {
+	Since     time.Time // When the route was deprecated
+	Sunset    time.Time // When it may be removed; zero while undecided
+	Successor string    // What to use instead, e.g. "GET /api/v1/admin/sync-status"
+	Link      string    // URL of the deprecation notice, if any
+}
+
+// Message returns the warning added to response envelopes
+func (d Deprecation) Message() string {
+	msg := "This route is deprecated since " + d.Since.UTC().Format(time.DateOnly)
+	if !d.Sunset.IsZero() {
+		msg += " and will be removed after " + d.Sunset.UTC().Format(time.DateOnly)
+	}
+	if d.Successor != "" {
+		msg += "; use " + d.Successor + " instead"
+	}
+	return msg
+}
+
+// Deprecated marks a route deprecated: responses get a Deprecation header
+// (RFC 9745), a Sunset header (RFC 8594) once a removal date is set, a Link
+// to the notice, and the warning in their envelope. Requests are counted in
+// http_deprecated_requests_total. Install it with r.With, ahead of the
+// response cache so cached responses carry the headers as well.
+func Deprecated(d Deprecation) func(next http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	var sunset string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	var link string
+	if d.Link != "" {
+		link = "<" + d.Link + `>; rel="deprecation"; type="text/html"`
+	}
+	warnings := []string{d.Message()}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			if sunset != "" {
+				h.Set("Sunset", sunset)
+			}
+			if link != "" {
+				h.Add("Link", link)
+			}
+
+			// By pattern, not path, to keep the label's values few
+			route := r.Method
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route += " " + rctx.RoutePattern()
+			}
+			deprecatedRequests.WithLabelValues(route).Inc()
+
+			next.ServeHTTP(&deprecationWriter{ResponseWriter: w, warnings: warnings}, r)
+		})
+	}
+}
+
+// deprecationWriter hands the deprecation warning to jsonenc.Write
+type deprecationWriter struct {
+	http.ResponseWriter
+	warnings []string
+}
+
+func (w *deprecationWriter) ResponseWarnings() []string {
+	return w.warnings
+}
+
+func (w *deprecationWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
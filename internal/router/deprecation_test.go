@@ -0,0 +1,54 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"{{MODULE_NAME}}/internal/jsonenc"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func TestDeprecated(t *testing.T) {
+	r := chi.NewRouter()
+	respond := func(w http.ResponseWriter, r *http.Request) {
+		jsonenc.Write(w, http.StatusOK, models.NewSuccessResponse(http.StatusOK, "", "ok"))
+	}
+	r.With(Deprecated(Deprecation{
+		Since:     time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		Successor: "GET /api/v1/new",
+		Link:      "https://example.com/deprecations/old",
+	})).Get("/api/v1/old", respond)
+	r.Get("/api/v1/new", respond)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/old", nil))
+	for header, want := range map[string]string{
+		"Deprecation": "@1790812800",
+		"Sunset":      "Fri, 01 Jan 2027 00:00:00 GMT",
+		"Link":        `<https://example.com/deprecations/old>; rel="deprecation"; type="text/html"`,
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	var body models.SuccessResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	want := "This route is deprecated since 2026-10-01 and will be removed after 2027-01-01; use GET /api/v1/new instead"
+	if len(body.Warnings) != 1 || body.Warnings[0] != want {
+		t.Errorf("warnings = %q, want [%q]", body.Warnings, want)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/new", nil))
+	if rec.Header().Get("Deprecation") != "" || strings.Contains(rec.Body.String(), "warnings") {
+		t.Errorf("route not deprecated answered with %v %s", rec.Header(), rec.Body)
+	}
+}
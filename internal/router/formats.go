@@ -0,0 +1,55 @@
+package router
+
+import (
+	"net/http"
+
+	"{{MODULE_NAME}}/internal/formats"
+	"{{MODULE_NAME}}/internal/jsonenc"
+)
+
+// Formats writes responses in the format Accept prefers, if a registered
+// one is preferred to JSON, see formats.Negotiate. The format is also in the
+// request's context, for the response cache to tell formats apart.
+func Formats(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		withFormat(next, w, r, formats.Negotiate(r.Header.Values("Accept")))
+	})
+}
+
+// DefaultFormat makes format the one of a route's responses unless Accept
+// names JSON or another format, for routes of clients that expect it, such
+// as JSON:API ones. Like Formats, it goes ahead of any response cache.
+func DefaultFormat(format formats.Format) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			withFormat(next, w, r, formats.NegotiateDefault(r.Header.Values("Accept"), format))
+		})
+	}
+}
+
+func withFormat(next http.Handler, w http.ResponseWriter, r *http.Request, format formats.Format) {
+	r = r.WithContext(formats.WithFormat(r.Context(), format))
+	next.ServeHTTP(&formatWriter{ResponseWriter: w, format: format, request: r}, r)
+}
+
+// formatWriter tells jsonenc.Write which format to write responses in
+type formatWriter struct {
+	http.ResponseWriter
+	format  formats.Format
+	request *http.Request
+}
+
+func (w *formatWriter) ResponseFormat() (jsonenc.Encoder, string) {
+	if w.format == nil {
+		return nil, ""
+	}
+	format := w.format
+	if f, ok := format.(formats.RequestFormat); ok {
+		format = f.ForRequest(w.request)
+	}
+	return format, format.MediaTypes()[0]
+}
+
+func (w *formatWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"{{MODULE_NAME}}/internal/formats"
+	"{{MODULE_NAME}}/internal/jsonenc"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func TestFormats(t *testing.T) {
+	handler := Problems("https://docs.example.com/problems/")(Formats(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "Product not found")
+	})))
+
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", "application/json", `"message":"Product not found"`},
+		{"application/xml", "application/xml", "<message>Product not found</message>"},
+		{"text/xml, application/json;q=0.5", "application/xml", "<status>error</status>"},
+		// Problem details are JSON, so XML takes the error envelope
+		{"application/problem+json, application/xml", "application/xml", "<code>404</code>"},
+		{"application/problem+json", "application/problem+json", `"title":"Not Found"`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/42", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("Accept %q: Content-Type = %q, want %q", tt.accept, ct, tt.contentType)
+		}
+		if vary := rec.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Accept" {
+			t.Errorf("Accept %q: Vary = %q", tt.accept, vary)
+		}
+		if !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("Accept %q: body %s doesn't contain %s", tt.accept, rec.Body, tt.body)
+		}
+	}
+}
+
+func TestDefaultFormat(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Formats)
+	r.With(DefaultFormat(formats.JSONAPI{})).Get("/api/v1/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		jsonenc.Write(w, http.StatusOK, models.NewSuccessResponse(http.StatusOK, "", models.Product{ID: 42, SKU: "BOLT-M6"}))
+	})
+
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", "application/vnd.api+json", `"type":"products"`},
+		{"*/*", "application/vnd.api+json", `"id":"42"`},
+		{"application/json", "application/json", `"status":"success"`},
+		{"application/xml", "application/xml", "<sku>BOLT-M6</sku>"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/42", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("Accept %q: Content-Type = %q, want %q", tt.accept, ct, tt.contentType)
+		}
+		if !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("Accept %q: body %s doesn't contain %s", tt.accept, rec.Body, tt.body)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// MethodOverrideHeader lets clients that can only send GET and POST tunnel
+// other methods through POST
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// routeMethods are the methods probed when listing what a route allows
+var routeMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// MethodOverride rewrites POST requests carrying X-HTTP-Method-Override to
+// PUT, PATCH, or DELETE. Must be installed before routing, on the root router.
+func MethodOverride(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		override := r.Header.Get(MethodOverrideHeader)
+		if override == "" || r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch method := strings.ToUpper(strings.TrimSpace(override)); method {
+		case http.MethodPut, http.MethodPatch, http.MethodDelete:
+			r.Method = method
+			r.Header.Del(MethodOverrideHeader)
+			next.ServeHTTP(w, r)
+		default:
+			writeError(w, http.StatusBadRequest, "Unsupported method override: "+override)
+		}
+	})
+}
+
+// MethodNotAllowed answers requests for a known path with an unsupported
+// method. OPTIONS gets a 204 listing the allowed methods; anything else gets a
+// 405 in the standard error envelope. Both set the Allow header.
+func MethodNotAllowed(mux *chi.Mux) http.HandlerFunc {
+	var once sync.Once
+	var probe *chi.Mux
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Routes are complete by the time requests arrive
+		once.Do(func() { probe = flatten(mux) })
+
+		w.Header().Set("Allow", strings.Join(allowedMethods(probe, r.URL.Path), ", "))
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		writeError(w, http.StatusMethodNotAllowed, "Method "+r.Method+" not allowed")
+	}
+}
+
+// flatten copies every route of mux onto a router without sub-routers, where
+// matching a path and method doesn't run into nested routing state
+func flatten(mux *chi.Mux) *chi.Mux {
+	probe := chi.NewRouter()
+	noop := func(http.ResponseWriter, *http.Request) {}
+
+	_ = chi.Walk(mux, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		probe.MethodFunc(method, route, noop)
+		// A sub-router's "/" also serves the path without the trailing slash
+		if trimmed := strings.TrimSuffix(route, "/"); trimmed != route && trimmed != "" {
+			probe.MethodFunc(method, trimmed, noop)
+		}
+		return nil
+	})
+
+	return probe
+}
+
+func allowedMethods(probe *chi.Mux, path string) []string {
+	var allowed []string
+	for _, method := range routeMethods {
+		if probe.Match(chi.NewRouteContext(), method, path) {
+			allowed = append(allowed, method)
+		}
+	}
+	return append(allowed, http.MethodOptions)
+}
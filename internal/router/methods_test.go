@@ -0,0 +1,87 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newMethodsRouter() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(MethodOverride)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method", r.Method)
+		w.WriteHeader(http.StatusOK)
+	}
+	r.Route("/items", func(r chi.Router) {
+		r.Get("/", ok)
+		r.Post("/", ok)
+		r.Put("/{id}", ok)
+		r.Delete("/{id}", ok)
+	})
+	r.MethodNotAllowed(MethodNotAllowed(r))
+	return r
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := newMethodsRouter()
+
+	tests := []struct {
+		method    string
+		path      string
+		wantCode  int
+		wantAllow string
+	}{
+		{http.MethodOptions, "/items/1", http.StatusNoContent, "PUT, DELETE, OPTIONS"},
+		{http.MethodOptions, "/items", http.StatusNoContent, "GET, POST, OPTIONS"},
+		{http.MethodPatch, "/items/1", http.StatusMethodNotAllowed, "PUT, DELETE, OPTIONS"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if w.Code != tt.wantCode {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, w.Code, tt.wantCode)
+		}
+		if allow := w.Header().Get("Allow"); allow != tt.wantAllow {
+			t.Errorf("%s %s: Allow = %q, want %q", tt.method, tt.path, allow, tt.wantAllow)
+		}
+		if tt.wantCode == http.StatusMethodNotAllowed && w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s %s: expected JSON error envelope", tt.method, tt.path)
+		}
+	}
+}
+
+func TestMethodOverride(t *testing.T) {
+	r := newMethodsRouter()
+
+	tests := []struct {
+		method     string
+		override   string
+		wantCode   int
+		wantMethod string
+	}{
+		{http.MethodPost, "DELETE", http.StatusOK, http.MethodDelete},
+		{http.MethodPost, "put", http.StatusOK, http.MethodPut},
+		{http.MethodPost, "GET", http.StatusBadRequest, ""},
+		{http.MethodGet, "DELETE", http.StatusMethodNotAllowed, ""}, // Only POST is tunnelled
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/items/1", nil)
+		req.Header.Set(MethodOverrideHeader, tt.override)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.wantCode {
+			t.Errorf("%s with override %s: status = %d, want %d", tt.method, tt.override, w.Code, tt.wantCode)
+		}
+		if got := w.Header().Get("X-Method"); got != tt.wantMethod {
+			t.Errorf("%s with override %s: handled as %q, want %q", tt.method, tt.override, got, tt.wantMethod)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package router
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"{{MODULE_NAME}}/internal/config"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/jsonenc"
+	"{{MODULE_NAME}}/internal/maintenance"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/policy"
+	"{{MODULE_NAME}}/internal/tokens"
+)
+
+// ConfigMiddleware attaches the current configuration snapshot to the request
+// context so a request sees one consistent configuration even if a reload
+// happens while it is in flight
+func ConfigMiddleware(store *config.Store) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := config.WithConfig(r.Context(), store.Current())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// CORSMiddleware allows cross-origin requests from CORS_ALLOWED_ORIGINS.
+// Must be installed after ConfigMiddleware.
+func CORSMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		cfg := config.FromContext(r.Context())
+		if origin == "" || cfg == nil || !originAllowed(cfg.CORSAllowedOrigins, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Request-ID, X-Dry-Run, X-HTTP-Method-Override, X-API-Key")
+			w.Header().Set("Access-Control-Max-Age", "300")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimiter limits requests per client IP using a token bucket. Limits are
+// read from the request's configuration snapshot, so a reload takes effect on
+// the next request. Each rate-limit class has its own limits and buckets, see
+// rateLimitClass.
+type RateLimiter struct {
+	mu        sync.Mutex
+	classes   map[string]*limitClass
+	lastPrune time.Time
+}
+
+type limitClass struct {
+	rps     float64
+	burst   int
+	clients map[string]*client
+}
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{classes: make(map[string]*limitClass)}
+}
+
+// Middleware must be installed after ConfigMiddleware and RealIP
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := config.FromContext(r.Context())
+		if cfg == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		class, rps, burst := rateLimitClass(r, cfg)
+		if rps <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !rl.allow(class, clientIP(r), rps, burst) {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitClass returns the class a request is limited in, and its limits.
+// Availability lookups are cheap and polled by storefronts, so they don't
+// use up the budget of the rest of the API, nor it theirs.
+func rateLimitClass(r *http.Request, cfg *config.Config) (string, float64, int) {
+	if strings.HasPrefix(r.URL.Path, "/api/v1/products/") && strings.HasSuffix(r.URL.Path, "/availability") {
+		return "availability", cfg.AvailabilityRateLimitRPS, cfg.AvailabilityRateLimitBurst
+	}
+	return "default", cfg.RateLimitRPS, cfg.RateLimitBurst
+}
+
+func (rl *RateLimiter) allow(name, ip string, rps float64, burst int) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+
+	// Limits changed through a reload, start every client with a fresh bucket
+	class, ok := rl.classes[name]
+	if !ok || class.rps != rps || class.burst != burst {
+		class = &limitClass{rps: rps, burst: burst, clients: make(map[string]*client)}
+		rl.classes[name] = class
+	}
+
+	if now.Sub(rl.lastPrune) > time.Minute {
+		for _, class := range rl.classes {
+			for key, c := range class.clients {
+				if now.Sub(c.lastSeen) > 3*time.Minute {
+					delete(class.clients, key)
+				}
+			}
+		}
+		rl.lastPrune = now
+	}
+
+	c, ok := class.clients[ip]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+		class.clients[ip] = c
+	}
+	c.lastSeen = now
+
+	return c.limiter.Allow()
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// AdminAuth protects admin routes with ADMIN_TOKEN. When no token is configured
+// admin routes are only reachable in development. Scoped tokens, verified by
+// ScopedTokens, reach them only when minted with admin, which is only granted
+// to read-only tokens limited to nothing else.
+func AdminAuth(store *config.Store) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cfg := store.Current()
+
+			if claims := tokens.FromContext(r.Context()); claims != nil {
+				if claims.Scope != (tokens.Scope{ReadOnly: true, Admin: true}) {
+					writeError(w, http.StatusForbidden, "Access token does not grant admin access")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if cfg.AdminToken == "" {
+				if cfg.IsDevelopment() {
+					next.ServeHTTP(w, r)
+					return
+				}
+				writeError(w, http.StatusForbidden, "Admin API is disabled: ADMIN_TOKEN is not configured")
+				return
+			}
+
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) != 1 {
+				writeError(w, http.StatusUnauthorized, "Invalid or missing admin token")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Approvers protects revision reviews: scoped tokens minted for the approver
+// role pass, and other requests need AdminAuth
+func Approvers(store *config.Store) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		admin := AdminAuth(store)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := tokens.FromContext(r.Context())
+			if claims != nil && claims.Scope.Role == policy.RoleApprover {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if claims != nil {
+				writeError(w, http.StatusForbidden, "Access token is not an approver's")
+				return
+			}
+			admin.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Identified admits internal users: requests with a scoped token, whose
+// subject names who made them, and otherwise those AdminAuth admits
+func Identified(store *config.Store) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		admin := AdminAuth(store)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tokens.FromContext(r.Context()) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			admin.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ScopedTokens enforces the scope of scoped access tokens, leaving requests
+// with any other bearer token (or none) alone. Expired or forged tokens get
+// 401, requests outside the scope 403. Must be installed after
+// ConfigMiddleware.
+func ScopedTokens(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !tokens.IsToken(token) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cfg := config.FromContext(r.Context())
+		if cfg == nil || cfg.AccessTokenKey == "" {
+			writeError(w, http.StatusUnauthorized, "Scoped access tokens are disabled: ACCESS_TOKEN_KEY is not configured")
+			return
+		}
+		claims, err := tokens.Verify([]byte(cfg.AccessTokenKey), token, time.Now())
+		if errors.Is(err, tokens.ErrExpired) {
+			writeError(w, http.StatusUnauthorized, "Access token expired")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "Invalid access token")
+			return
+		}
+
+		scope := claims.Scope
+		if scope.ReadOnly {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				writeError(w, http.StatusForbidden, "Access token is read-only")
+				return
+			}
+		}
+		if scope.ProductID != 0 && !productPath(r.URL.Path, scope.ProductID) {
+			writeError(w, http.StatusForbidden, "Access token is limited to product "+strconv.Itoa(scope.ProductID))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(tokens.WithClaims(r.Context(), claims)))
+	})
+}
+
+// productPath reports whether path is /api/v1/products/{id} or below it for
+// the product id, accepting IDs with leading zeros as routing does
+func productPath(path string, id int) bool {
+	rest, ok := strings.CutPrefix(path, "/api/v1/products/")
+	if !ok {
+		return false
+	}
+	segment, _, _ := strings.Cut(rest, "/")
+	n, err := strconv.Atoi(segment)
+	return err == nil && n == id
+}
+
+// PublicCache lets browsers and shared caches keep 200 responses for maxAge,
+// and serve them stale for as long again while revalidating. Install it
+// outside the response cache so cached responses carry the header too.
+func PublicCache(maxAge time.Duration) func(next http.Handler) http.Handler {
+	value := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds())) + ", stale-while-revalidate=" + strconv.Itoa(int(maxAge.Seconds()))
+	return func(next http.Handler) http.Handler {
+		if maxAge <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, value: value}, r)
+		})
+	}
+}
+
+// cacheControlWriter sets Cache-Control once the status is known, leaving
+// errors uncached
+type cacheControlWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if code == http.StatusOK {
+			w.Header().Set("Cache-Control", w.value)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// maxRequestBody bounds request bodies, in JSON or any other format
+const maxRequestBody = 10 << 20
+
+// LimitBody fails reads past limit bytes of request bodies with an
+// *http.MaxBytesError, so a decoder never buffers more of one than that
+func LimitBody(limit int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DryRunHeader requests a dry run, as does the dry_run=true query parameter
+const DryRunHeader = "X-Dry-Run"
+
+// DryRun marks requests asking for a dry run so their transactions are rolled
+// back, and echoes X-Dry-Run: true on the response. Only install it on routes
+// whose writes all go through a transaction.
+func DryRun(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isDryRun(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set(DryRunHeader, "true")
+		next.ServeHTTP(w, r.WithContext(database.WithDryRun(r.Context())))
+	})
+}
+
+func isDryRun(r *http.Request) bool {
+	for _, value := range []string{r.URL.Query().Get("dry_run"), r.Header.Get(DryRunHeader)} {
+		if dryRun, err := strconv.ParseBool(value); err == nil && dryRun {
+			return true
+		}
+	}
+	return false
+}
+
+// Maintenance refuses writes with 503 and Retry-After while maintenance mode
+// is enabled. GET, HEAD, and OPTIONS requests are always served.
+func Maintenance(mode *maintenance.Mode) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			status := mode.Status()
+			if !status.Enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			message := status.Message
+			if message == "" {
+				message = "Service is under maintenance, writes are temporarily disabled"
+			}
+			if status.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(status.RetryAfter))
+			}
+			writeError(w, http.StatusServiceUnavailable, message)
+		})
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	jsonenc.Write(w, code, models.NewErrorResponse(code, message))
+}
@@ -0,0 +1,203 @@
+package router
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/config"
+	"{{MODULE_NAME}}/internal/formats"
+	"{{MODULE_NAME}}/internal/maintenance"
+	"{{MODULE_NAME}}/internal/policy"
+	"{{MODULE_NAME}}/internal/tokens"
+)
+
+func TestMaintenance(t *testing.T) {
+	mode := maintenance.NewMode(false, false, time.Minute)
+	handler := Maintenance(mode)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, "/api/v1/products", nil))
+		return w
+	}
+
+	if w := serve(http.MethodPost); w.Code != http.StatusOK {
+		t.Fatalf("POST with maintenance off: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	mode.Enable("Upgrading", 30*time.Second)
+
+	w := serve(http.MethodPost)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("POST during maintenance: status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want %q", got, "30")
+	}
+	if w := serve(http.MethodGet); w.Code != http.StatusOK {
+		t.Errorf("GET during maintenance: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if err := mode.Disable(); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	if w := serve(http.MethodDelete); w.Code != http.StatusOK {
+		t.Errorf("DELETE after maintenance: status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestMaintenance_ReadOnly(t *testing.T) {
+	mode := maintenance.NewMode(false, true, time.Minute)
+
+	if err := mode.Disable(); err != maintenance.ErrReadOnly {
+		t.Errorf("Disable() on a read-only instance error = %v, want %v", err, maintenance.ErrReadOnly)
+	}
+	if status := mode.Status(); !status.Enabled || !status.ReadOnly {
+		t.Errorf("status = %+v, want enabled and read-only", status)
+	}
+}
+
+func TestRateLimiter_AvailabilityClass(t *testing.T) {
+	cfg := &config.Config{RateLimitRPS: 1, RateLimitBurst: 1, AvailabilityRateLimitRPS: 1, AvailabilityRateLimitBurst: 2}
+	handler := NewRateLimiter().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		handler.ServeHTTP(w, r.WithContext(config.WithConfig(r.Context(), cfg)))
+		return w.Code
+	}
+
+	if code := serve("/api/v1/products/7"); code != http.StatusOK {
+		t.Fatalf("first request: status = %d", code)
+	}
+	if code := serve("/api/v1/products/7"); code != http.StatusTooManyRequests {
+		t.Errorf("second request: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	// The exhausted default bucket doesn't limit availability lookups
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if code := serve("/api/v1/products/7/availability"); code != want {
+			t.Errorf("availability request %d: status = %d, want %d", i+1, code, want)
+		}
+	}
+}
+
+func TestPublicCache(t *testing.T) {
+	status := http.StatusOK
+	handler := PublicCache(30 * time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/products/7/availability", nil))
+	if got, want := w.Header().Get("Cache-Control"), "public, max-age=30, stale-while-revalidate=30"; got != want {
+		t.Errorf("Cache-Control = %q, want %q", got, want)
+	}
+
+	status = http.StatusNotFound
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/products/8/availability", nil))
+	if got := w.Header().Get("Cache-Control"); got != "" {
+		t.Errorf("Cache-Control on a 404 = %q, want none", got)
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	var err error
+	handler := LimitBody(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v interface{}
+		err = formats.NewDecoder(r.Body, r.Header.Get("Content-Type")).Decode(&v)
+	}))
+
+	for _, contentType := range []string{"application/json", "application/x-msgpack"} {
+		body, _ := formats.ForContentType("application/x-msgpack").Append(nil, strings.Repeat("x", 32))
+		if contentType == "application/json" {
+			body = []byte(`"` + strings.Repeat("x", 32) + `"`)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/products", bytes.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		var tooLarge *http.MaxBytesError
+		if !errors.As(err, &tooLarge) {
+			t.Errorf("decoding %s past the limit = %v, want an *http.MaxBytesError", contentType, err)
+		}
+	}
+}
+
+func TestScopedTokens(t *testing.T) {
+	cfg := &config.Config{AccessTokenKey: "0123456789abcdef0123456789abcdef", AdminToken: "admin"}
+	store := config.NewStore(cfg, nil)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/admin/", AdminAuth(store)(ok))
+	mux.Handle("/api/v1/revisions/", Approvers(store)(ok))
+	mux.Handle("/", ok)
+	handler := ScopedTokens(mux)
+
+	mint := func(scope tokens.Scope, ttl time.Duration) string {
+		token, _, err := tokens.Mint([]byte(cfg.AccessTokenKey), "test", scope, ttl)
+		if err != nil {
+			t.Fatalf("Mint: %v", err)
+		}
+		return token
+	}
+	readOnly := mint(tokens.Scope{ReadOnly: true}, time.Hour)
+	adminReader := mint(tokens.Scope{ReadOnly: true, Admin: true}, time.Hour)
+	adminWriter := mint(tokens.Scope{ProductID: 7, Admin: true}, time.Hour)
+	product := mint(tokens.Scope{ProductID: 7}, time.Hour)
+	editor := mint(tokens.Scope{Role: policy.RoleEditor}, time.Hour)
+	approver := mint(tokens.Scope{Role: policy.RoleApprover}, time.Hour)
+
+	tests := []struct {
+		name, method, path, token string
+		want                      int
+	}{
+		{"no token", http.MethodPost, "/api/v1/products", "", http.StatusOK},
+		{"admin token", http.MethodPut, "/api/v1/admin/log-level", "admin", http.StatusOK},
+		{"read-only read", http.MethodGet, "/api/v1/products/3", readOnly, http.StatusOK},
+		{"read-only write", http.MethodPost, "/api/v1/products", readOnly, http.StatusForbidden},
+		{"read-only admin read", http.MethodGet, "/api/v1/admin/log-level", readOnly, http.StatusForbidden},
+		{"read-only backup", http.MethodGet, "/api/v1/admin/backup", readOnly, http.StatusForbidden},
+		{"admin read", http.MethodGet, "/api/v1/admin/log-level", adminReader, http.StatusOK},
+		{"admin write", http.MethodPut, "/api/v1/admin/log-level", adminReader, http.StatusForbidden},
+		{"admin without read-only", http.MethodGet, "/api/v1/admin/log-level", adminWriter, http.StatusForbidden},
+		{"product", http.MethodPut, "/api/v1/products/7", product, http.StatusOK},
+		{"product below", http.MethodGet, "/api/v1/products/007/price", product, http.StatusOK},
+		{"other product", http.MethodGet, "/api/v1/products/8", product, http.StatusForbidden},
+		{"product listing", http.MethodGet, "/api/v1/products", product, http.StatusForbidden},
+		{"editor", http.MethodPut, "/api/v1/products/7", editor, http.StatusOK},
+		{"editor approval", http.MethodPost, "/api/v1/revisions/1/approve", editor, http.StatusForbidden},
+		{"approver approval", http.MethodPost, "/api/v1/revisions/1/approve", approver, http.StatusOK},
+		{"admin approval", http.MethodPost, "/api/v1/revisions/1/approve", "admin", http.StatusOK},
+		{"anonymous approval", http.MethodPost, "/api/v1/revisions/1/approve", "", http.StatusUnauthorized},
+		{"approver admin", http.MethodGet, "/api/v1/admin/log-level", approver, http.StatusForbidden},
+		{"expired", http.MethodGet, "/api/v1/products", mint(tokens.Scope{ReadOnly: true}, -time.Second), http.StatusUnauthorized},
+		{"forged", http.MethodGet, "/api/v1/products", readOnly + "x", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r.WithContext(config.WithConfig(r.Context(), cfg)))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package router
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/config"
+	"{{MODULE_NAME}}/internal/policy"
+	"{{MODULE_NAME}}/internal/tokens"
+)
+
+var policyDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_policy_decisions_total",
+	Help: "Route authorization decisions, by role and outcome (allowed or denied).",
+}, []string{"role", "decision"})
+
+// Authorizer enforces the route policy of POLICY_RULES and POLICY_ROLES,
+// compiled once per configuration snapshot so a reload applies it to the
+// next request
+type Authorizer struct {
+	compiled atomic.Pointer[compiledPolicy]
+	logger   *slog.Logger
+}
+
+type compiledPolicy struct {
+	cfg    *config.Config
+	policy *policy.Policy
+}
+
+func NewAuthorizer(logger *slog.Logger) *Authorizer {
+	return &Authorizer{logger: logger}
+}
+
+// Middleware refuses requests whose role lacks a permission the route needs,
+// with 401 for anonymous requests and 403 otherwise. Denials are logged at
+// warn and allowed requests at debug. It authorizes only: AdminAuth and
+// ScopedTokens still authenticate, and must already have run for scoped
+// tokens, as must ConfigMiddleware.
+func (a *Authorizer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := config.FromContext(r.Context())
+		if cfg == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p, err := a.policy(cfg)
+		if err != nil {
+			a.logger.Error("invalid route policy", "error", err)
+			writeError(w, http.StatusInternalServerError, "Invalid authorization policy")
+			return
+		}
+
+		decision := p.Decide(requestRole(r, cfg), r.Method, r.URL.Path)
+		attrs := []any{
+			"role", decision.Role,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"rule", decision.Rule,
+			"required", decision.Required,
+			"request_id", middleware.GetReqID(r.Context()),
+		}
+		if !decision.Allowed {
+			policyDecisions.WithLabelValues(decision.Role, "denied").Inc()
+			a.logger.Warn("request denied by policy", append(attrs, "missing", decision.Missing)...)
+			message := "missing permission " + strings.Join(decision.Missing, ", ")
+			if decision.Role == policy.RoleAnonymous {
+				writeError(w, http.StatusUnauthorized, "Authentication required: "+message)
+				return
+			}
+			writeError(w, http.StatusForbidden, "Forbidden: "+message)
+			return
+		}
+
+		policyDecisions.WithLabelValues(decision.Role, "allowed").Inc()
+		a.logger.Debug("request allowed by policy", attrs...)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authorizer) policy(cfg *config.Config) (*policy.Policy, error) {
+	if compiled := a.compiled.Load(); compiled != nil && compiled.cfg == cfg {
+		return compiled.policy, nil
+	}
+	p, err := policy.New(cfg.PolicyRules, cfg.PolicyRoles)
+	if err != nil {
+		return nil, err
+	}
+	a.compiled.Store(&compiledPolicy{cfg: cfg, policy: p})
+	return p, nil
+}
+
+// requestRole returns the role a request is made as: a verified scoped
+// token's role, or token when it has none, admin for ADMIN_TOKEN, and
+// anonymous otherwise
+func requestRole(r *http.Request, cfg *config.Config) string {
+	if claims := tokens.FromContext(r.Context()); claims != nil {
+		if claims.Scope.Role != "" {
+			return claims.Scope.Role
+		}
+		return policy.RoleToken
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if cfg.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) == 1 {
+		return policy.RoleAdmin
+	}
+	return policy.RoleAnonymous
+}
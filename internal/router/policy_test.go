@@ -0,0 +1,58 @@
+package router
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/config"
+)
+
+func TestAuthorizer(t *testing.T) {
+	cfg := &config.Config{
+		AdminToken:  "admin",
+		PolicyRules: map[string]string{"DELETE /api/v1/products/{id}": "products:delete"},
+		PolicyRoles: map[string]string{"anonymous": "*:read"},
+	}
+	handler := NewAuthorizer(slog.New(slog.NewTextHandler(io.Discard, nil))).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(cfg *config.Config, method, path, token string) int {
+		r := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r.WithContext(config.WithConfig(r.Context(), cfg)))
+		return w.Code
+	}
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodGet, "/api/v1/products", "", http.StatusOK},
+		{http.MethodPost, "/api/v1/products", "", http.StatusUnauthorized},
+		{http.MethodPost, "/api/v1/products", "wrong", http.StatusUnauthorized},
+		{http.MethodPost, "/api/v1/products", "admin", http.StatusOK},
+		{http.MethodDelete, "/api/v1/products/7", "admin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := serve(cfg, tt.method, tt.path, tt.token); code != tt.want {
+			t.Errorf("%s %s with %q: status = %d, want %d", tt.method, tt.path, tt.token, code, tt.want)
+		}
+	}
+
+	// A reloaded configuration applies to the next request
+	reloaded := *cfg
+	reloaded.PolicyRoles = map[string]string{"admin": "products:read products:write"}
+	if code := serve(&reloaded, http.MethodDelete, "/api/v1/products/7", "admin"); code != http.StatusForbidden {
+		t.Errorf("DELETE after reload: status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := serve(&reloaded, http.MethodPost, "/api/v1/products", ""); code != http.StatusOK {
+		t.Errorf("anonymous POST after reload: status = %d, want %d", code, http.StatusOK)
+	}
+}
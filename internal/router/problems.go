@@ -0,0 +1,69 @@
+package router
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/jsonenc"
+)
+
+// Problems writes errors as RFC 7807 problem details to clients that ask for
+// application/problem+json in Accept, at least as strongly as for
+// application/json; others keep the error envelope. Type URIs are typeBase
+// followed by the problem type of the status, e.g. "/problems/not-found".
+func Problems(typeBase string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&problemWriter{
+				ResponseWriter: w,
+				typeBase:       typeBase,
+				instance:       r.URL.Path,
+				accepted:       acceptsProblems(r.Header.Values("Accept")),
+			}, r)
+		})
+	}
+}
+
+// acceptsProblems reports whether Accept prefers problem details. Wildcards
+// don't count: problem details are only sent to clients that name them.
+func acceptsProblems(accept []string) bool {
+	var problemQ, jsonQ float64
+	for _, value := range accept {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(v, 64); err != nil {
+					continue
+				}
+			}
+			switch mediaType {
+			case jsonenc.ProblemContentType:
+				problemQ = q
+			case "application/json":
+				jsonQ = q
+			}
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
+
+// problemWriter tells jsonenc.Write how to write errors for the client
+type problemWriter struct {
+	http.ResponseWriter
+	typeBase, instance string
+	accepted           bool
+}
+
+func (w *problemWriter) ProblemDetails() (typeBase, instance string, ok bool) {
+	return w.typeBase, w.instance, w.accepted
+}
+
+func (w *problemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/jsonenc"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+func TestProblems(t *testing.T) {
+	handler := Problems("https://docs.example.com/problems/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusPreconditionFailed, "Product was modified after If-Unmodified-Since")
+	}))
+
+	tests := []struct {
+		accept  string
+		problem bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/problem+json", true},
+		{"application/json, application/problem+json", true},
+		{"application/problem+json;q=0.5, application/json", false},
+		{"application/problem+json;q=0", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/products/42", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("Accept %q: status = %d", tt.accept, rec.Code)
+		}
+		if rec.Header().Get("Vary") != "Accept" {
+			t.Errorf("Accept %q: Vary = %q", tt.accept, rec.Header().Get("Vary"))
+		}
+		if !tt.problem {
+			var body models.ErrorResponse
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Accept %q: Content-Type = %q", tt.accept, ct)
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Status != "error" {
+				t.Errorf("Accept %q: want the error envelope, got %s", tt.accept, rec.Body)
+			}
+			continue
+		}
+
+		var problem models.Problem
+		if ct := rec.Header().Get("Content-Type"); ct != jsonenc.ProblemContentType {
+			t.Errorf("Accept %q: Content-Type = %q", tt.accept, ct)
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+		want := models.Problem{
+			Type:      "https://docs.example.com/problems/precondition-failed",
+			Title:     "Precondition Failed",
+			Status:    http.StatusPreconditionFailed,
+			Detail:    "Product was modified after If-Unmodified-Since",
+			Instance:  "/api/v1/products/42",
+			Timestamp: problem.Timestamp,
+		}
+		if problem.Type != want.Type || problem.Title != want.Title || problem.Status != want.Status || problem.Detail != want.Detail || problem.Instance != want.Instance {
+			t.Errorf("Accept %q: problem = %+v, want %+v", tt.accept, problem, want)
+		}
+	}
+}
+
+func TestProblemType(t *testing.T) {
+	for code, want := range map[int]string{
+		http.StatusNotFound:              "not-found",
+		http.StatusTeapot:                "im-a-teapot",
+		http.StatusRequestEntityTooLarge: "request-entity-too-large",
+		599:                              "599",
+	} {
+		if got := models.ProblemType(code); got != want {
+			t.Errorf("ProblemType(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
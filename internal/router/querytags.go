@@ -0,0 +1,30 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"{{MODULE_NAME}}/internal/database"
+)
+
+// QueryTags tags the database queries of a request with its route and
+// request ID. The route is read when a query runs, once chi has matched the
+// whole pattern. Must be installed after middleware.RequestID.
+func QueryTags(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if id := middleware.GetReqID(ctx); id != "" {
+			ctx = database.WithQueryTag(ctx, database.TagRequestID, func() string { return id })
+		}
+		rctx := chi.RouteContext(ctx)
+		ctx = database.WithQueryTag(ctx, database.TagRoute, func() string {
+			if rctx == nil || rctx.RoutePattern() == "" {
+				return ""
+			}
+			// The method routed, after any X-HTTP-Method-Override
+			return rctx.RouteMethod + " " + rctx.RoutePattern()
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/config"
+	"{{MODULE_NAME}}/internal/policy"
+	"{{MODULE_NAME}}/internal/quota"
+)
+
+// Quota headers sent on responses to requests with a limited API key
+const (
+	QuotaLimitHeader     = "X-Quota-Limit"
+	QuotaRemainingHeader = "X-Quota-Remaining"
+	QuotaResetHeader     = "X-Quota-Reset" // Unix time the quota renews
+)
+
+// Quota meters requests with an API key in X-API-Key: unknown or revoked keys
+// get 401, and keys past their monthly quota 429 until the next month. Other
+// requests pass unmetered, unless API_KEYS_REQUIRED refuses them. Admin,
+// health, and integration routes, which authenticate on their own, are never
+// metered. Handlers find the key of a metered request with quota.FromContext.
+// A nil meter disables metering. Must be installed after ConfigMiddleware.
+func Quota(meter *quota.Meter, logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if meter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !metered(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			raw := r.Header.Get(quota.Header)
+			if raw == "" {
+				if cfg := config.FromContext(r.Context()); cfg != nil && cfg.APIKeysRequired {
+					writeError(w, http.StatusUnauthorized, "API key required in "+quota.Header)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := meter.Authenticate(r.Context(), raw)
+			if errors.Is(err, quota.ErrInvalidKey) || errors.Is(err, quota.ErrRevokedKey) {
+				writeError(w, http.StatusUnauthorized, "Invalid or revoked API key")
+				return
+			}
+			if err != nil {
+				logger.Error("failed to look up API key", "error", err)
+				writeError(w, http.StatusInternalServerError, "Failed to check API key")
+				return
+			}
+
+			now := time.Now()
+			status, admitted, err := meter.Admit(r.Context(), key, now)
+			if err != nil {
+				logger.Error("failed to check API quota", "error", err, "api_key_id", key.ID)
+				writeError(w, http.StatusInternalServerError, "Failed to check API quota")
+				return
+			}
+			if status.Limit > 0 {
+				w.Header().Set(QuotaLimitHeader, strconv.FormatInt(status.Limit, 10))
+				w.Header().Set(QuotaRemainingHeader, strconv.FormatInt(status.Remaining, 10))
+				w.Header().Set(QuotaResetHeader, strconv.FormatInt(status.Reset.Unix(), 10))
+			}
+			if !admitted {
+				w.Header().Set("Retry-After", strconv.Itoa(int(status.Reset.Sub(now).Seconds())+1))
+				writeError(w, http.StatusTooManyRequests, "Monthly quota of "+strconv.FormatInt(status.Limit, 10)+" requests exceeded")
+				return
+			}
+
+			counted := &countingWriter{ResponseWriter: w}
+			next.ServeHTTP(counted, r.WithContext(quota.WithKey(r.Context(), key)))
+			meter.Record(key, policy.DefaultPermission(r.Method, r.URL.Path), counted.bytes, now)
+		})
+	}
+}
+
+func metered(path string) bool {
+	if !strings.HasPrefix(path, "/api/v1/") || path == "/api/v1/health" || path == "/api/v1/ready" {
+		return false
+	}
+	for _, prefix := range []string{"/api/v1/admin", "/api/v1/integrations"} {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// countingWriter counts the response bytes billed to an API key
+type countingWriter struct {
+	http.ResponseWriter
+	bytes int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *countingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,50 +1,279 @@
 package router
 
 import (
-	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"{{MODULE_NAME}}/internal/cache"
+	"{{MODULE_NAME}}/internal/config"
 	"{{MODULE_NAME}}/internal/handlers"
-	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/maintenance"
+	"{{MODULE_NAME}}/internal/quota"
 
 	_ "{{MODULE_NAME}}/docs" // This is required for Swagger
 )
 
-func New(productHandler *handlers.ProductHandler, logger *slog.Logger) http.Handler {
+func New(productHandler *handlers.ProductHandler, statsHandler *handlers.StatsHandler, receiptHandler *handlers.ReceiptHandler, lotHandler *handlers.LotHandler, serialHandler *handlers.SerialHandler, stockTakeHandler *handlers.StockTakeHandler, returnHandler *handlers.ReturnHandler, forecastHandler *handlers.ForecastHandler, purchaseOrderHandler *handlers.PurchaseOrderHandler, timelineHandler *handlers.TimelineHandler, changeHandler *handlers.ChangeHandler, searchHandler *handlers.SearchHandler, pricingHandler *handlers.PricingHandler, availabilityHandler *handlers.AvailabilityHandler, relatedHandler *handlers.RelatedHandler, bundleHandler *handlers.BundleHandler, promotionHandler *handlers.PromotionHandler, savedSearchHandler *handlers.SavedSearchHandler, subscriptionHandler *handlers.SubscriptionHandler, trashHandler *handlers.TrashHandler, adminHandler *handlers.AdminHandler, exportHandler *handlers.ExportHandler, operationHandler *handlers.OperationHandler, reportHandler *handlers.ReportHandler, apiKeyHandler *handlers.APIKeyHandler, regionHandler *handlers.RegionHandler, noteHandler *handlers.NoteHandler, integrationHandler *handlers.IntegrationHandler, readinessHandler *handlers.ReadinessHandler, products UIDResolver, meter *quota.Meter, store *config.Store, mode *maintenance.Mode, responseCache *cache.Cache, logger *slog.Logger) http.Handler {
 	r := chi.NewRouter()
 
 	// Middleware stack
-	r.Use(middleware.RequestID)                 // Add request ID for tracing
-	r.Use(middleware.RealIP)                    // Get real IP from headers
-	r.Use(middleware.Recoverer)                 // Recover from panics
-	r.Use(LoggerMiddleware(logger))             // Custom logging middleware
-	r.Use(middleware.Timeout(60 * time.Second)) // Request timeout
+	r.Use(middleware.RequestID)                      // Add request ID for tracing
+	r.Use(QueryTags)                                 // Route and request ID in SQL comments
+	r.Use(middleware.RealIP)                         // Get real IP from headers
+	r.Use(MethodOverride)                            // X-HTTP-Method-Override for POST-only clients
+	r.Use(Problems(store.Current().ProblemTypeBase)) // Errors as problem details on request
+	r.Use(Formats)                                   // XML and other formats on request
+	r.Use(LimitBody(maxRequestBody))                 // Request bodies of at most 10 MiB, in any format
+	r.Use(ProductUIDs(products, logger))             // Products addressed by their unique IDs
+	r.Use(middleware.Recoverer)                      // Recover from panics
+	r.Use(LoggerMiddleware(logger))                  // Custom logging middleware
+	r.Use(middleware.Timeout(60 * time.Second))      // Request timeout
+	r.Use(ConfigMiddleware(store))                   // Per-request config snapshot
+	r.Use(CORSMiddleware)                            // CORS from runtime config
+	r.Use(NewRateLimiter().Middleware)               // Per-IP rate limiting from runtime config
+	r.Use(ScopedTokens)                              // Scope of admin-minted access tokens
+	r.Use(NewAuthorizer(logger).Middleware)          // Route permissions per role from runtime config
+	r.Use(Quota(meter, logger))                      // Monthly quotas of API keys
 
 	r.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"), // Use relative URL instead of absolute
 	))
 
+	r.Handle("/metrics", promhttp.Handler()) // Prometheus metrics
+
 	r.Get("/api/v1/health", productHandler.HealthCheck)
+	r.Get("/api/v1/ready", readinessHandler.Ready)
 
+	concurrency := NewConcurrencyLimiter()
+	cacheList := responseCache.Middleware("products.list", productListTags)
+	cacheProduct := responseCache.Middleware("products.get", productTags)
+	cachePrice := responseCache.Middleware("products.price", productTags)
+	cacheRelated := responseCache.Middleware("products.related", relatedTags)
+	cacheAvailability := responseCache.Middleware("products.availability", productTags)
+	publicAvailability := PublicCache(store.Current().AvailabilityMaxAge)
 	r.Route("/api/v1/products", func(r chi.Router) {
-		r.Get("/", productHandler.ListProducts)         // GET /api/v1/products
-		r.Post("/", productHandler.CreateProduct)       // POST /api/v1/products
-		r.Get("/{id}", productHandler.GetProduct)       // GET /api/v1/products/{id}
-		r.Put("/{id}", productHandler.UpdateProduct)    // PUT /api/v1/products/{id}
-		r.Delete("/{id}", productHandler.DeleteProduct) // DELETE /api/v1/products/{id}
+		r.Use(concurrency.Middleware("products"))                                   // 503 past the concurrent request limits
+		r.Use(Maintenance(mode))                                                    // 503 on writes during maintenance
+		r.Use(DryRun)                                                               // ?dry_run=true or X-Dry-Run: true on writes
+		r.With(cacheList).Get("/", productHandler.ListProducts)                     // GET /api/v1/products
+		r.Post("/", productHandler.CreateProduct)                                   // POST /api/v1/products
+		r.With(AdminAuth(store)).Post("/price-update", pricingHandler.UpdatePrices) // POST /api/v1/products/price-update (admin)
+		r.With(cacheProduct).Get("/{id}", productHandler.GetProduct)                // GET /api/v1/products/{id}
+		r.Get("/slug/{slug}", productHandler.GetProductBySlug)                      // GET /api/v1/products/slug/{slug}
+		r.Get("/stats", statsHandler.Summary)                                       // GET /api/v1/products/stats
+		r.Get("/next-sku", productHandler.NextSKU)                                  // GET /api/v1/products/next-sku
+		r.Get("/reorder-suggestions", forecastHandler.ListReorderSuggestions)       // GET /api/v1/products/reorder-suggestions
+		r.Get("/changes", changeHandler.ListChanges)                                // GET /api/v1/products/changes
+		r.Get("/search", searchHandler.SearchProducts)                              // GET /api/v1/products/search
+		r.Get("/suggest", searchHandler.SuggestProducts)                            // GET /api/v1/products/suggest
+		r.Get("/{id}/stats", statsHandler.ProductStats)                             // GET /api/v1/products/{id}/stats
+		r.Get("/{id}/receipts", receiptHandler.ListReceipts)                        // GET /api/v1/products/{id}/receipts
+		r.Post("/{id}/receipts", receiptHandler.ReceiveStock)                       // POST /api/v1/products/{id}/receipts
+		r.Get("/{id}/lots", lotHandler.ListLots)                                    // GET /api/v1/products/{id}/lots
+		r.Post("/{id}/lots", lotHandler.ReceiveLot)                                 // POST /api/v1/products/{id}/lots
+		r.Post("/{id}/lots/consume", lotHandler.ConsumeLots)                        // POST /api/v1/products/{id}/lots/consume
+		r.Get("/{id}/serials", serialHandler.ListSerials)                           // GET /api/v1/products/{id}/serials
+		r.Post("/{id}/serials", serialHandler.RegisterSerials)                      // POST /api/v1/products/{id}/serials
+		r.Get("/{id}/forecast", forecastHandler.GetForecast)                        // GET /api/v1/products/{id}/forecast
+		r.Put("/{id}/lead-time", forecastHandler.SetLeadTime)                       // PUT /api/v1/products/{id}/lead-time
+		r.Delete("/{id}/lead-time", forecastHandler.DeleteLeadTime)                 // DELETE /api/v1/products/{id}/lead-time
+		r.Get("/{id}/supplier", purchaseOrderHandler.GetSupplier)                   // GET /api/v1/products/{id}/supplier
+		r.Put("/{id}/supplier", purchaseOrderHandler.SetSupplier)                   // PUT /api/v1/products/{id}/supplier
+		r.Delete("/{id}/supplier", purchaseOrderHandler.DeleteSupplier)             // DELETE /api/v1/products/{id}/supplier
+		r.With(AdminAuth(store)).Get("/{id}/timeline", timelineHandler.GetTimeline) // GET /api/v1/products/{id}/timeline (admin)
+		r.With(cachePrice).Get("/{id}/price", pricingHandler.GetPrice)              // GET /api/v1/products/{id}/price
+		r.With(cacheRelated).Get("/{id}/related", relatedHandler.GetRelated)        // GET /api/v1/products/{id}/related
+		r.Get("/{id}/revisions", productHandler.ListRevisions)                      // GET /api/v1/products/{id}/revisions
+		r.Put("/{id}", productHandler.UpdateProduct)                                // PUT /api/v1/products/{id}
+		r.Delete("/{id}", productHandler.DeleteProduct)                             // DELETE /api/v1/products/{id}
+
+		// Notes of internal users
+		r.Route("/{id}/notes", func(r chi.Router) {
+			r.Use(Identified(store))
+			r.Get("/", noteHandler.ListNotes)             // GET /api/v1/products/{id}/notes
+			r.Post("/", noteHandler.CreateNote)           // POST /api/v1/products/{id}/notes
+			r.Get("/{noteID}", noteHandler.GetNote)       // GET /api/v1/products/{id}/notes/{noteID}
+			r.Put("/{noteID}", noteHandler.UpdateNote)    // PUT /api/v1/products/{id}/notes/{noteID}
+			r.Delete("/{noteID}", noteHandler.DeleteNote) // DELETE /api/v1/products/{id}/notes/{noteID}
+		})
+
+		// GET /api/v1/products/{id}/availability, public and cacheable by browsers and CDNs
+		r.With(publicAvailability, cacheAvailability).Get("/{id}/availability", availabilityHandler.GetAvailability)
+	})
+
+	r.Route("/api/v1/bundles", func(r chi.Router) {
+		r.Use(concurrency.Middleware("bundles"))
+		r.Use(Maintenance(mode))
+		r.Get("/", bundleHandler.ListBundles)         // GET /api/v1/bundles
+		r.Post("/", bundleHandler.CreateBundle)       // POST /api/v1/bundles
+		r.Get("/{id}", bundleHandler.GetBundle)       // GET /api/v1/bundles/{id}
+		r.Put("/{id}", bundleHandler.UpdateBundle)    // PUT /api/v1/bundles/{id}
+		r.Delete("/{id}", bundleHandler.DeleteBundle) // DELETE /api/v1/bundles/{id}
+	})
+
+	r.Route("/api/v1/revisions", func(r chi.Router) {
+		r.Use(concurrency.Middleware("revisions"))
+		r.Use(Maintenance(mode))
+		r.Use(DryRun)
+		r.Get("/{id}", productHandler.GetRevision)                                     // GET /api/v1/revisions/{id}
+		r.With(Approvers(store)).Post("/{id}/approve", productHandler.ApproveRevision) // POST /api/v1/revisions/{id}/approve (approver)
+		r.With(Approvers(store)).Post("/{id}/reject", productHandler.RejectRevision)   // POST /api/v1/revisions/{id}/reject (approver)
+	})
+
+	r.Route("/api/v1/lots", func(r chi.Router) {
+		r.Use(concurrency.Middleware("lots"))
+		r.Get("/expiring", lotHandler.ListExpiringLots) // GET /api/v1/lots/expiring
+	})
+
+	r.Route("/api/v1/serials", func(r chi.Router) {
+		r.Use(concurrency.Middleware("serials"))
+		r.Use(Maintenance(mode))
+		r.Use(DryRun)
+		r.Get("/{serial}", serialHandler.LookupSerial)         // GET /api/v1/serials/{serial}
+		r.Post("/{serial}/retire", serialHandler.RetireSerial) // POST /api/v1/serials/{serial}/retire
+	})
+
+	r.Route("/api/v1/stock-takes", func(r chi.Router) {
+		r.Use(concurrency.Middleware("stock_takes"))
+		r.Use(Maintenance(mode))
+		r.Use(DryRun)
+		r.Get("/", stockTakeHandler.ListStockTakes)                  // GET /api/v1/stock-takes
+		r.Post("/", stockTakeHandler.OpenStockTake)                  // POST /api/v1/stock-takes
+		r.Get("/{id}", stockTakeHandler.GetStockTake)                // GET /api/v1/stock-takes/{id}
+		r.Post("/{id}/counts", stockTakeHandler.RecordCounts)        // POST /api/v1/stock-takes/{id}/counts (JSON or CSV)
+		r.Post("/{id}/complete", stockTakeHandler.CompleteStockTake) // POST /api/v1/stock-takes/{id}/complete
+		r.Post("/{id}/cancel", stockTakeHandler.CancelStockTake)     // POST /api/v1/stock-takes/{id}/cancel
+	})
+
+	r.Route("/api/v1/stats", func(r chi.Router) {
+		r.Use(concurrency.Middleware("stats"))
+		r.Get("/abc-analysis", statsHandler.ABCAnalysis) // GET /api/v1/stats/abc-analysis
+	})
+
+	r.Route("/api/v1/returns", func(r chi.Router) {
+		r.Use(concurrency.Middleware("returns"))
+		r.Use(Maintenance(mode))
+		r.Use(DryRun)
+		r.Get("/", returnHandler.ListReturns)                // GET /api/v1/returns
+		r.Post("/", returnHandler.CreateReturn)              // POST /api/v1/returns
+		r.Get("/{id}", returnHandler.GetReturn)              // GET /api/v1/returns/{id}
+		r.Post("/{id}/receive", returnHandler.ReceiveReturn) // POST /api/v1/returns/{id}/receive
+		r.Post("/{id}/inspect", returnHandler.InspectReturn) // POST /api/v1/returns/{id}/inspect
+		r.Post("/{id}/cancel", returnHandler.CancelReturn)   // POST /api/v1/returns/{id}/cancel
+	})
+
+	r.Route("/api/v1/purchase-orders", func(r chi.Router) {
+		r.Use(concurrency.Middleware("purchase-orders"))
+		r.Use(Maintenance(mode))
+		r.Use(DryRun)
+		r.Get("/", purchaseOrderHandler.ListPurchaseOrders)                 // GET /api/v1/purchase-orders
+		r.Post("/drafts", purchaseOrderHandler.DraftPurchaseOrders)         // POST /api/v1/purchase-orders/drafts
+		r.Get("/{id}", purchaseOrderHandler.GetPurchaseOrder)               // GET /api/v1/purchase-orders/{id}
+		r.Put("/{id}/lines", purchaseOrderHandler.UpdatePurchaseOrderLines) // PUT /api/v1/purchase-orders/{id}/lines
+		r.Post("/{id}/approve", purchaseOrderHandler.ApprovePurchaseOrder)  // POST /api/v1/purchase-orders/{id}/approve
+		r.Post("/{id}/submit", purchaseOrderHandler.SubmitPurchaseOrder)    // POST /api/v1/purchase-orders/{id}/submit
+		r.Post("/{id}/receive", purchaseOrderHandler.ReceivePurchaseOrder)  // POST /api/v1/purchase-orders/{id}/receive
+		r.Post("/{id}/cancel", purchaseOrderHandler.CancelPurchaseOrder)    // POST /api/v1/purchase-orders/{id}/cancel
+	})
+
+	r.Route("/api/v1/promotions", func(r chi.Router) {
+		r.Use(concurrency.Middleware("promotions"))
+		r.Use(Maintenance(mode))
+		r.Get("/", promotionHandler.ListPromotions)         // GET /api/v1/promotions
+		r.Post("/", promotionHandler.CreatePromotion)       // POST /api/v1/promotions
+		r.Get("/{id}", promotionHandler.GetPromotion)       // GET /api/v1/promotions/{id}
+		r.Put("/{id}", promotionHandler.UpdatePromotion)    // PUT /api/v1/promotions/{id}
+		r.Delete("/{id}", promotionHandler.DeletePromotion) // DELETE /api/v1/promotions/{id}
+	})
+
+	r.Route("/api/v1/saved-searches", func(r chi.Router) {
+		r.Use(concurrency.Middleware("saved_searches"))
+		r.Use(Maintenance(mode))
+		r.Get("/", savedSearchHandler.ListSavedSearches)        // GET /api/v1/saved-searches
+		r.Post("/", savedSearchHandler.CreateSavedSearch)       // POST /api/v1/saved-searches
+		r.Get("/{id}", savedSearchHandler.GetSavedSearch)       // GET /api/v1/saved-searches/{id}
+		r.Put("/{id}", savedSearchHandler.UpdateSavedSearch)    // PUT /api/v1/saved-searches/{id}
+		r.Delete("/{id}", savedSearchHandler.DeleteSavedSearch) // DELETE /api/v1/saved-searches/{id}
+	})
+
+	r.Route("/api/v1/subscriptions", func(r chi.Router) {
+		r.Use(concurrency.Middleware("subscriptions"))
+		r.Use(Maintenance(mode))
+		r.Get("/", subscriptionHandler.ListSubscriptions)         // GET /api/v1/subscriptions
+		r.Post("/", subscriptionHandler.CreateSubscription)       // POST /api/v1/subscriptions
+		r.Get("/{id}", subscriptionHandler.GetSubscription)       // GET /api/v1/subscriptions/{id}
+		r.Delete("/{id}", subscriptionHandler.DeleteSubscription) // DELETE /api/v1/subscriptions/{id}
+	})
+
+	r.Route("/api/v1/trash", func(r chi.Router) {
+		r.Use(concurrency.Middleware("trash"))
+		r.Use(Maintenance(mode))
+		r.Use(DryRun)
+		r.Get("/", trashHandler.ListTrash)                   // GET /api/v1/trash
+		r.Post("/{id}/restore", trashHandler.RestoreProduct) // POST /api/v1/trash/{id}/restore
+		r.Delete("/{id}", trashHandler.PurgeProduct)         // DELETE /api/v1/trash/{id}
+	})
+
+	r.Route("/api/v1/integrations", func(r chi.Router) {
+		r.Use(concurrency.Middleware("integrations"))
+		r.With(Maintenance(mode), DryRun).Post("/orders", integrationHandler.ReceiveOrder) // POST /api/v1/integrations/orders (signed webhook)
+		r.With(AdminAuth(store)).Get("/sync-status", integrationHandler.SyncStatus)        // GET /api/v1/integrations/sync-status (admin)
+	})
+
+	r.Route("/api/v1/reports", func(r chi.Router) {
+		r.Use(AdminAuth(store))
+		r.Use(concurrency.Middleware("reports"))
+		r.Get("/", reportHandler.ListReports)                                   // GET /api/v1/reports
+		r.Get("/{name}", reportHandler.GetReport)                               // GET /api/v1/reports/{name}
+		r.With(Maintenance(mode)).Put("/{name}", reportHandler.PutReport)       // PUT /api/v1/reports/{name}
+		r.With(Maintenance(mode)).Delete("/{name}", reportHandler.DeleteReport) // DELETE /api/v1/reports/{name}
+		r.Post("/{name}/run", reportHandler.RunReport)                          // POST /api/v1/reports/{name}/run
+	})
+
+	r.Route("/api/v1/operations", func(r chi.Router) {
+		r.Use(AdminAuth(store))
+		r.Get("/{id}", operationHandler.GetOperation)            // GET /api/v1/operations/{id}
+		r.Post("/{id}/cancel", operationHandler.CancelOperation) // POST /api/v1/operations/{id}/cancel
+	})
+
+	r.Route("/api/v1/admin", func(r chi.Router) {
+		r.Use(AdminAuth(store))
+		r.Post("/config/reload", adminHandler.ReloadConfig)                                     // POST /api/v1/admin/config/reload
+		r.Get("/log-level", adminHandler.GetLogLevel)                                           // GET /api/v1/admin/log-level
+		r.Put("/log-level", adminHandler.UpdateLogLevel)                                        // PUT /api/v1/admin/log-level
+		r.With(Maintenance(mode)).Post("/retention/purge", adminHandler.PurgeExpired)           // POST /api/v1/admin/retention/purge
+		r.With(Maintenance(mode), DryRun).Post("/integrity/check", adminHandler.CheckIntegrity) // POST /api/v1/admin/integrity/check
+		r.Get("/maintenance", adminHandler.GetMaintenance)                                      // GET /api/v1/admin/maintenance
+		r.Put("/maintenance", adminHandler.UpdateMaintenance)                                   // PUT /api/v1/admin/maintenance
+		r.Get("/backup", adminHandler.Backup)                                                   // GET /api/v1/admin/backup
+		r.Post("/explain", adminHandler.Explain)                                                // POST /api/v1/admin/explain
+		r.Post("/exports", exportHandler.TriggerExport)                                         // POST /api/v1/admin/exports
+		r.Get("/exports", exportHandler.ListExports)                                            // GET /api/v1/admin/exports
+		r.Get("/exports/{id}", exportHandler.GetExport)                                         // GET /api/v1/admin/exports/{id}
+		r.Post("/search/reindex", searchHandler.ReindexSearch)                                  // POST /api/v1/admin/search/reindex
+		r.Post("/tokens", adminHandler.MintToken)                                               // POST /api/v1/admin/tokens
+		r.Get("/policies", adminHandler.GetPolicies)                                            // GET /api/v1/admin/policies
+		r.Get("/api-keys", apiKeyHandler.ListAPIKeys)                                           // GET /api/v1/admin/api-keys
+		r.Post("/api-keys", apiKeyHandler.CreateAPIKey)                                         // POST /api/v1/admin/api-keys
+		r.Get("/api-keys/{id}", apiKeyHandler.GetAPIKey)                                        // GET /api/v1/admin/api-keys/{id}
+		r.Put("/api-keys/{id}", apiKeyHandler.UpdateAPIKey)                                     // PUT /api/v1/admin/api-keys/{id}
+		r.Delete("/api-keys/{id}", apiKeyHandler.RevokeAPIKey)                                  // DELETE /api/v1/admin/api-keys/{id}
+		r.Get("/usage", apiKeyHandler.ExportUsage)                                              // GET /api/v1/admin/usage
+		r.Get("/products", productHandler.ListAdminProducts)                                    // GET /api/v1/admin/products
+		r.Get("/product-regions", regionHandler.ListRegions)                                    // GET /api/v1/admin/product-regions
+		r.With(Maintenance(mode)).Post("/product-regions", regionHandler.AssignRegions)         // POST /api/v1/admin/product-regions
 	})
 
 	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		response := models.NewErrorResponse(http.StatusNotFound, "Route not found")
-		json.NewEncoder(w).Encode(response)
+		writeError(w, http.StatusNotFound, "Route not found")
 	})
+	r.MethodNotAllowed(MethodNotAllowed(r)) // 405 envelope, and OPTIONS listing allowed methods
 
 	return r
 }
@@ -82,3 +311,43 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// productListTags adds the saved searches tag to listings through a saved
+// search, whose filter can change under the same URL
+func productListTags(r *http.Request) []string {
+	tags := []string{cache.ProductsTag}
+	if r.URL.Query().Get("view") != "" {
+		tags = append(tags, cache.SavedSearchesTag)
+	}
+	return withPromotionsTag(r, tags...)
+}
+
+// productTags tags a product response with the ID events carry, so
+// /products/007 is invalidated along with /products/7
+func productTags(r *http.Request) []string {
+	id := chi.URLParam(r, "id")
+	if n, err := strconv.Atoi(id); err == nil {
+		id = strconv.Itoa(n)
+	}
+	return withPromotionsTag(r, cache.ProductTag(id))
+}
+
+// relatedTags tags related products with the listing tag, since a change to
+// any product can add it to or drop it from the list
+func relatedTags(r *http.Request) []string {
+	return []string{cache.ProductsTag}
+}
+
+// withPromotionsTag adds the promotions tag to responses with effective
+// prices, which promotion changes invalidate
+func withPromotionsTag(r *http.Request, tags ...string) []string {
+	if include, _ := strconv.ParseBool(r.URL.Query().Get("effective_price")); include {
+		tags = append(tags, cache.PromotionsTag)
+	}
+	return tags
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
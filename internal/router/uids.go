@@ -0,0 +1,54 @@
+package router
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/id"
+)
+
+// UIDResolver finds the ID of a product by its globally unique ID
+type UIDResolver interface {
+	ResolveUID(ctx context.Context, uid string) (int, error)
+}
+
+// ProductUIDs lets every /api/v1/products/{id} route take a product's unique
+// ID (see package id), with or without its type prefix, in place of its ID,
+// by rewriting the path to the ID before routing. Unknown unique IDs get 404.
+// Must be installed before middleware that reads product IDs from paths,
+// like ScopedTokens.
+func ProductUIDs(products UIDResolver, logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rest, ok := strings.CutPrefix(r.URL.Path, "/api/v1/products/")
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			segment, _, _ := strings.Cut(rest, "/")
+			uid := id.ParseProductID(segment)
+			if !id.Valid(uid) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			productID, err := products.ResolveUID(r.Context(), uid)
+			if err != nil && err.Error() == "product not found" {
+				writeError(w, http.StatusNotFound, "Product not found")
+				return
+			}
+			if err != nil {
+				logger.Error("failed to resolve product uid", "error", err, "uid", uid)
+				writeError(w, http.StatusInternalServerError, "Failed to retrieve product")
+				return
+			}
+
+			r.URL.Path = "/api/v1/products/" + strconv.Itoa(productID) + rest[len(segment):]
+			r.URL.RawPath = ""
+			next.ServeHTTP(w, r)
+		})
+	}
+}
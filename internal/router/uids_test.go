@@ -0,0 +1,49 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeUIDs map[string]int
+
+func (f fakeUIDs) ResolveUID(ctx context.Context, uid string) (int, error) {
+	if id, ok := f[uid]; ok {
+		return id, nil
+	}
+	return 0, fmt.Errorf("product not found")
+}
+
+func TestProductUIDs(t *testing.T) {
+	const ulid = "01J9ZQ4V8X2M6T0K3R5N7P9B1C"
+	var path string
+	handler := ProductUIDs(fakeUIDs{ulid: 7, "eu1_" + ulid: 8}, slog.New(slog.NewTextHandler(io.Discard, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+	}))
+
+	tests := []struct {
+		path, want string
+		code       int
+	}{
+		{"/api/v1/products/" + ulid, "/api/v1/products/7", http.StatusOK},
+		{"/api/v1/products/eu1_" + ulid + "/price", "/api/v1/products/8/price", http.StatusOK},
+		{"/api/v1/products/prod_" + ulid + "/", "/api/v1/products/7/", http.StatusOK},
+		{"/api/v1/products/7", "/api/v1/products/7", http.StatusOK},
+		{"/api/v1/products/search", "/api/v1/products/search", http.StatusOK},
+		{"/api/v1/bundles/" + ulid, "/api/v1/bundles/" + ulid, http.StatusOK},
+		{"/api/v1/products/01J9ZQ4V8X2M6T0K3R5N7P9B1D", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		path = ""
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.code || path != tt.want {
+			t.Errorf("%s: got %d at %q, want %d at %q", tt.path, rec.Code, path, tt.code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+)
+
+var (
+	jobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_job_runs_total",
+		Help: "Scheduled job runs, by job and result (success, error).",
+	}, []string{"job", "result"})
+	jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scheduler_job_duration_seconds",
+		Help:    "Time taken by a scheduled job run.",
+		Buckets: []float64{.1, .5, 1, 5, 15, 30, 60, 300, 900},
+	}, []string{"job"})
+)
+
+// Job is a function run at a fixed interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Timeout  time.Duration // Per-run timeout, defaults to Interval
+	Run      func(ctx context.Context) error
+
+	// RunOnStart runs the job immediately instead of waiting one interval
+	RunOnStart bool
+}
+
+// Scheduler runs registered jobs in the background. A job never overlaps
+// with itself: if a run takes longer than the interval, the missed ticks are
+// skipped.
+type Scheduler struct {
+	jobs   []Job
+	wg     sync.WaitGroup
+	logger *slog.Logger
+}
+
+func New(logger *slog.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("scheduler: job name and run function are required")
+	}
+	if job.Interval <= 0 {
+		return fmt.Errorf("scheduler: job %s: interval must be positive", job.Name)
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = job.Interval
+	}
+
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// Start runs every registered job until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+}
+
+// Wait blocks until all jobs have returned after ctx was cancelled
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	logger := s.logger.With("job", job.Name)
+	logger.Info("job scheduled", "interval", job.Interval.String())
+
+	if job.RunOnStart {
+		s.run(ctx, job, logger)
+	}
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.run(ctx, job, logger)
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+	// The job stands in for the route in its queries' tags
+	ctx = database.WithQueryTag(ctx, database.TagRoute, func() string { return "job " + job.Name })
+
+	start := time.Now()
+	err := job.Run(ctx)
+	duration := time.Since(start)
+	jobDuration.WithLabelValues(job.Name).Observe(duration.Seconds())
+
+	if err != nil {
+		jobRuns.WithLabelValues(job.Name, "error").Inc()
+		logger.Error("job failed", "duration", duration.String(), "error", err)
+		return
+	}
+
+	jobRuns.WithLabelValues(job.Name, "success").Inc()
+	logger.Debug("job finished", "duration", duration.String())
+}
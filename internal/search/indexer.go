@@ -0,0 +1,217 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"{{MODULE_NAME}}/internal/changefeed"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// indexBatchSize is the number of changes, or products while reindexing, sent
+// per bulk request
+const indexBatchSize = 500
+
+// Index is an external search backend the Indexer keeps in sync. Searches go
+// through a fixed name; a rebuilt index is swapped in under it, so searches
+// keep working during a rebuild.
+type Index interface {
+	Backend
+
+	// Alias is the name searches and syncs go through
+	Alias() string
+
+	// Check reports whether the index behind the alias is built with the
+	// current mapping or settings, or returns ErrIndexMissing
+	Check(ctx context.Context) (bool, error)
+
+	// CreateIndex creates an empty index with the current mapping or settings
+	CreateIndex(ctx context.Context, name string) error
+
+	// Bulk applies changes to index in order: upserts replace documents,
+	// stock changes update the quantity, and deletes remove documents.
+	Bulk(ctx context.Context, index string, changes []*models.ProductChange) error
+
+	// SwapIn makes the fully built index name searchable under the alias. It
+	// returns the index now behind the alias and the indices to drop.
+	SwapIn(ctx context.Context, name string) (string, []string, error)
+
+	DeleteIndex(ctx context.Context, name string) error
+}
+
+// Indexer keeps a search index in sync with the catalog by applying the
+// change feed, recording its cursor in search_index_state. Changes can be
+// applied more than once, e.g. after a crash between a bulk request and
+// recording the cursor, which is harmless.
+type Indexer struct {
+	index    Index
+	feed     *changefeed.Feed
+	repo     repository.SearchRepository
+	products repository.ExportRepository // Reads the catalog from one snapshot
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex // Serializes syncs and reindexes
+	checked bool       // The mapping of the index behind the alias is current
+}
+
+func NewIndexer(index Index, feed *changefeed.Feed, repo repository.SearchRepository, products repository.ExportRepository, logger *slog.Logger) *Indexer {
+	return &Indexer{index: index, feed: feed, repo: repo, products: products, logger: logger, now: time.Now}
+}
+
+// Sync applies the changes since the last sync. It rebuilds the index instead
+// when there is none yet, its mapping is outdated, or the change feed no
+// longer reaches back to its cursor.
+func (ix *Indexer) Sync(ctx context.Context) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	state, err := ix.repo.IndexState(ctx, ix.index.Alias())
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		_, err := ix.reindex(ctx, nil, "no index yet")
+		return err
+	}
+	if !ix.checked {
+		current, err := ix.index.Check(ctx)
+		switch {
+		case errors.Is(err, ErrIndexMissing):
+			_, err := ix.reindex(ctx, nil, "index missing")
+			return err
+		case err != nil:
+			return err
+		case !current:
+			_, err := ix.reindex(ctx, nil, "mapping outdated")
+			return err
+		}
+		ix.checked = true
+	}
+
+	for cursor := state.Cursor; ; {
+		page, err := ix.feed.Read(ctx, cursor, indexBatchSize)
+		if errors.Is(err, changefeed.ErrCursorExpired) {
+			_, err := ix.reindex(ctx, nil, "change feed no longer reaches the cursor")
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if len(page.Changes) == 0 {
+			return nil
+		}
+
+		if err := ix.index.Bulk(ctx, ix.index.Alias(), page.Changes); err != nil {
+			return err
+		}
+		advanced, err := ix.repo.AdvanceCursor(ctx, ix.index.Alias(), cursor, page.NextCursor)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			// Rebuilt by another instance; the next sync starts from its cursor
+			ix.logger.Info("search index was rebuilt meanwhile", "alias", ix.index.Alias())
+			return nil
+		}
+		ix.logger.Debug("search index synced", "alias", ix.index.Alias(), "changes", len(page.Changes))
+
+		if !page.HasMore {
+			return nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// Reindex rebuilds the index from scratch and points the alias at it,
+// telling progress, if any, how many of how many products it has indexed
+func (ix *Indexer) Reindex(ctx context.Context, progress func(done, total int64)) (*models.SearchIndexState, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.reindex(ctx, progress, "requested")
+}
+
+// reindex copies the catalog into a new index, swaps it in, and drops the
+// old index. The cursor is taken before the copy, so changes
+// committed during it are applied again by the next sync.
+func (ix *Indexer) reindex(ctx context.Context, progress func(done, total int64), reason string) (*models.SearchIndexState, error) {
+	head, err := ix.feed.Read(ctx, changefeed.Now, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	started := ix.now().UTC()
+	// Letters, digits, and dashes only, which every backend accepts
+	name := fmt.Sprintf("%s-%s-%06d", ix.index.Alias(), started.Format("20060102-150405"), started.Nanosecond()/1000)
+	logger := ix.logger.With("alias", ix.index.Alias(), "index", name)
+	logger.Info("rebuilding search index", "reason", reason)
+
+	if err := ix.index.CreateIndex(ctx, name); err != nil {
+		return nil, err
+	}
+	documents, err := ix.copy(ctx, name, progress)
+	live, previous := name, []string(nil)
+	if err == nil {
+		live, previous, err = ix.index.SwapIn(ctx, name)
+	}
+	if err != nil {
+		if dropErr := ix.index.DeleteIndex(context.WithoutCancel(ctx), name); dropErr != nil {
+			logger.Warn("failed to drop incomplete search index", "error", dropErr)
+		}
+		return nil, fmt.Errorf("failed to rebuild search index: %w", err)
+	}
+
+	state := &models.SearchIndexState{Name: ix.index.Alias(), IndexName: live, Cursor: head.NextCursor, ReindexedAt: started}
+	if err := ix.repo.SaveIndexState(ctx, state); err != nil {
+		return nil, err
+	}
+	ix.checked = true
+
+	for _, old := range previous {
+		if err := ix.index.DeleteIndex(ctx, old); err != nil {
+			logger.Warn("failed to drop previous search index", "previous", old, "error", err)
+		}
+	}
+	logger.Info("search index rebuilt", "documents", documents, "duration", ix.now().Sub(started).String())
+	return state, nil
+}
+
+// copy indexes every product into index and returns how many there were
+func (ix *Indexer) copy(ctx context.Context, index string, progress func(done, total int64)) (int, error) {
+	total := 0
+	if progress != nil {
+		var err error
+		if total, err = ix.products.CountProducts(ctx, models.ExportFilter{}); err != nil {
+			return 0, err
+		}
+	}
+
+	batch := make([]*models.ProductChange, 0, indexBatchSize)
+	documents := 0
+	flush := func() error {
+		err := ix.index.Bulk(ctx, index, batch)
+		documents += len(batch)
+		batch = batch[:0]
+		if err == nil && progress != nil {
+			progress(int64(documents), int64(total))
+		}
+		return err
+	}
+
+	_, err := ix.products.EachProduct(ctx, models.ExportFilter{}, func(p *models.Product) error {
+		batch = append(batch, &models.ProductChange{Op: models.ChangeUpsert, ProductID: p.ID, Product: p})
+		if len(batch) == indexBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	return documents, err
+}
@@ -0,0 +1,495 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// meiliSettings are the index settings the Meilisearch backend manages.
+// Searchable attributes are listed by importance; ties in relevance are
+// broken by ID like the other backends.
+type meiliSettings struct {
+	SearchableAttributes []string           `json:"searchableAttributes"`
+	FilterableAttributes []string           `json:"filterableAttributes"`
+	RankingRules         []string           `json:"rankingRules"`
+	TypoTolerance        meiliTypoTolerance `json:"typoTolerance"`
+}
+
+type meiliTypoTolerance struct {
+	Enabled             bool `json:"enabled"`
+	MinWordSizeForTypos struct {
+		OneTypo  int `json:"oneTypo"`
+		TwoTypos int `json:"twoTypos"`
+	} `json:"minWordSizeForTypos"`
+	DisableOnAttributes []string `json:"disableOnAttributes"`
+}
+
+type MeilisearchConfig struct {
+	URL    string // e.g. http://localhost:7700
+	APIKey string // Optional; needs the documents, indexes, settings, and tasks actions
+	Index  string // Searches and indexing go through this index
+
+	// Typo tolerance; SKUs always match exactly
+	TypoTolerance       bool
+	MinWordSizeOneTypo  int // Shortest word that matches with one typo
+	MinWordSizeTwoTypos int // Shortest word that matches with two typos
+}
+
+// Meilisearch searches and maintains a Meilisearch index, a lighter
+// alternative to OpenSearch for smaller deployments. Searches go through a
+// fixed index; a rebuilt index is swapped with it atomically.
+//
+// Meilisearch applies writes asynchronously as tasks, in the order they were
+// enqueued per index; every write waits for its task to finish, so errors
+// surface to the indexer.
+type Meilisearch struct {
+	cfg      MeilisearchConfig
+	base     *url.URL
+	client   *http.Client
+	settings meiliSettings
+}
+
+func NewMeilisearch(cfg MeilisearchConfig) (*Meilisearch, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("meilisearch: invalid URL %q", cfg.URL)
+	}
+	if cfg.Index == "" {
+		return nil, fmt.Errorf("meilisearch: an index name is required")
+	}
+
+	settings := meiliSettings{
+		SearchableAttributes: []string{"sku", "name", "tags", "description"},
+		// sku filters out documents that hold nothing but a quantity, see Bulk;
+		// unit_price and quantity bucket the price and status facets
+		FilterableAttributes: []string{"category", "sku", "tags", "regions", "listed", "unit_price", "quantity"},
+		RankingRules:         []string{"words", "typo", "proximity", "attribute", "exactness", "id:asc"},
+	}
+	settings.TypoTolerance.Enabled = cfg.TypoTolerance
+	settings.TypoTolerance.MinWordSizeForTypos.OneTypo = cfg.MinWordSizeOneTypo
+	settings.TypoTolerance.MinWordSizeForTypos.TwoTypos = cfg.MinWordSizeTwoTypos
+	settings.TypoTolerance.DisableOnAttributes = []string{"sku"}
+
+	return &Meilisearch{cfg: cfg, base: base, client: &http.Client{Timeout: 30 * time.Second}, settings: settings}, nil
+}
+
+func (m *Meilisearch) Name() string { return "meilisearch" }
+
+// Alias is the index searches go through
+func (m *Meilisearch) Alias() string { return m.cfg.Index }
+
+func (m *Meilisearch) Search(ctx context.Context, q models.SearchQuery) (*models.SearchResult, error) {
+	var result struct {
+		Hits               []*models.Product         `json:"hits"`
+		TotalHits          *int                      `json:"totalHits"`
+		EstimatedTotalHits int                       `json:"estimatedTotalHits"`
+		FacetDistribution  map[string]map[string]int `json:"facetDistribution"`
+	}
+	if err := m.do(ctx, http.MethodPost, "/indexes/"+url.PathEscape(m.cfg.Index)+"/search", meiliSearchRequest(q), &result); err != nil {
+		return nil, err
+	}
+	total := result.EstimatedTotalHits
+	if result.TotalHits != nil {
+		total = *result.TotalHits
+	}
+	found := &models.SearchResult{Products: result.Hits, Total: total}
+	if len(q.Facets.Names) == 0 {
+		return found, nil
+	}
+
+	found.Facets = models.Facets{}
+	if q.Facets.Has(models.FacetCategory) {
+		buckets := []models.FacetBucket{}
+		for value, count := range result.FacetDistribution["category"] {
+			if value != "" {
+				buckets = append(buckets, models.FacetBucket{Value: value, Count: count})
+			}
+		}
+		sort.Slice(buckets, func(i, j int) bool {
+			if buckets[i].Count != buckets[j].Count {
+				return buckets[i].Count > buckets[j].Count
+			}
+			return buckets[i].Value < buckets[j].Value
+		})
+		if len(buckets) > models.MaxCategoryBuckets {
+			buckets = buckets[:models.MaxCategoryBuckets]
+		}
+		found.Facets[models.FacetCategory] = buckets
+	}
+	if err := m.countRanges(ctx, q, found.Facets); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// countRanges counts the buckets of the price and status facets of q, which
+// Meilisearch has no range aggregation for, with one search per bucket sent
+// together as a multi-search
+func (m *Meilisearch) countRanges(ctx context.Context, q models.SearchQuery, facets models.Facets) error {
+	type bucketRef struct {
+		facet string
+		index int
+	}
+	var refs []bucketRef
+	var queries []interface{}
+	add := func(facet string, buckets []models.FacetBucket, filters []string) {
+		facets[facet] = buckets
+		for i, filter := range filters {
+			req := meiliSearchRequest(models.SearchQuery{Text: q.Text, Category: q.Category, Tag: q.Tag, Limit: 1})
+			req["indexUid"] = m.cfg.Index
+			req["filter"] = append(req["filter"].([]string), filter)
+			req["attributesToRetrieve"] = []string{"id"}
+			queries = append(queries, req)
+			refs = append(refs, bucketRef{facet: facet, index: i})
+		}
+	}
+
+	if q.Facets.Has(models.FacetPriceRange) {
+		buckets := models.PriceRanges(q.Facets.PriceBounds)
+		filters := make([]string, len(buckets))
+		for i, b := range buckets {
+			filters[i] = "unit_price >= " + strconv.FormatFloat(*b.Min, 'f', -1, 64)
+			if b.Max != nil {
+				filters[i] += " AND unit_price < " + strconv.FormatFloat(*b.Max, 'f', -1, 64)
+			}
+		}
+		add(models.FacetPriceRange, buckets, filters)
+	}
+	if q.Facets.Has(models.FacetStatus) {
+		low := strconv.Itoa(q.Facets.LowStock)
+		add(models.FacetStatus, models.StatusBuckets(), []string{
+			"quantity > " + low,
+			"quantity > 0 AND quantity <= " + low,
+			"quantity <= 0",
+		})
+	}
+	if len(queries) == 0 {
+		return nil
+	}
+
+	var result struct {
+		Results []struct {
+			TotalHits int `json:"totalHits"`
+		} `json:"results"`
+	}
+	if err := m.do(ctx, http.MethodPost, "/multi-search", map[string]interface{}{"queries": queries}, &result); err != nil {
+		return err
+	}
+	if len(result.Results) != len(refs) {
+		return fmt.Errorf("meilisearch: multi-search returned %d results for %d queries", len(result.Results), len(refs))
+	}
+	for i, ref := range refs {
+		facets[ref.facet][ref.index].Count = result.Results[i].TotalHits
+	}
+	return nil
+}
+
+// Suggest relies on Meilisearch matching the last word as a prefix
+func (m *Meilisearch) Suggest(ctx context.Context, q models.SuggestQuery) ([]*models.Suggestion, error) {
+	var result struct {
+		Hits []*models.Suggestion `json:"hits"`
+	}
+	if err := m.do(ctx, http.MethodPost, "/indexes/"+url.PathEscape(m.cfg.Index)+"/search", meiliSuggestRequest(q), &result); err != nil {
+		return nil, err
+	}
+	return result.Hits, nil
+}
+
+// meiliSuggestRequest builds the completions of q, filtered like searches
+func meiliSuggestRequest(q models.SuggestQuery) map[string]interface{} {
+	filter := []string{"sku EXISTS"}
+	if q.Region != "" {
+		filter = append(filter, meiliRegionFilter(q.Region))
+	}
+	if !q.Visible.IsZero() {
+		filter = append(filter, "listed != false")
+	}
+	return map[string]interface{}{
+		"q":                    strings.TrimSpace(q.Prefix),
+		"limit":                q.Limit,
+		"filter":               filter,
+		"attributesToRetrieve": []string{"id", "sku", "name"},
+	}
+}
+
+// meiliSearchRequest builds the search for q: every word of the text must
+// match. Offsets on a page boundary ask for pages, which count the total
+// exactly; others only get an estimate.
+func meiliSearchRequest(q models.SearchQuery) map[string]interface{} {
+	filter := []string{"sku EXISTS"}
+	if q.Category != "" {
+		filter = append(filter, "category = "+meiliQuote(q.Category))
+	}
+	if q.Tag != "" {
+		filter = append(filter, "tags = "+meiliQuote(strings.ToLower(q.Tag)))
+	}
+	if q.Region != "" {
+		filter = append(filter, meiliRegionFilter(q.Region))
+	}
+	if !q.Visible.IsZero() {
+		// Documents indexed before products had windows have no listed
+		filter = append(filter, "listed != false")
+	}
+
+	req := map[string]interface{}{
+		"q":                strings.TrimSpace(q.Text),
+		"filter":           filter,
+		"matchingStrategy": "all",
+	}
+	if q.Facets.Has(models.FacetCategory) {
+		req["facets"] = []string{"category"}
+	}
+	if q.Limit > 0 && q.Offset%q.Limit == 0 {
+		req["hitsPerPage"] = q.Limit
+		req["page"] = q.Offset/q.Limit + 1
+	} else {
+		req["offset"] = q.Offset
+		req["limit"] = q.Limit
+	}
+	return req
+}
+
+// meiliRegionFilter matches the products limited to region, or to none
+func meiliRegionFilter(region string) string {
+	return "(regions = " + meiliQuote(region) + " OR regions NOT EXISTS)"
+}
+
+// meiliQuote quotes s as a filter value
+func meiliQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Check reports whether the index exists. Settings changed since it was
+// created are applied in place, which Meilisearch does without a rebuild.
+func (m *Meilisearch) Check(ctx context.Context) (bool, error) {
+	var current meiliSettings
+	path := "/indexes/" + url.PathEscape(m.cfg.Index) + "/settings"
+	if err := m.do(ctx, http.MethodGet, path, nil, &current); err != nil {
+		return false, err
+	}
+	if reflect.DeepEqual(current, m.settings) {
+		return true, nil
+	}
+	if err := m.write(ctx, http.MethodPatch, path, m.settings); err != nil {
+		return false, fmt.Errorf("failed to update index settings: %w", err)
+	}
+	return true, nil
+}
+
+// CreateIndex creates an empty index with the current settings
+func (m *Meilisearch) CreateIndex(ctx context.Context, name string) error {
+	if err := m.write(ctx, http.MethodPost, "/indexes", map[string]string{"uid": name, "primaryKey": "id"}); err != nil {
+		return err
+	}
+	return m.write(ctx, http.MethodPatch, "/indexes/"+url.PathEscape(name)+"/settings", m.settings)
+}
+
+func (m *Meilisearch) DeleteIndex(ctx context.Context, name string) error {
+	err := m.write(ctx, http.MethodDelete, "/indexes/"+url.PathEscape(name), nil)
+	if errors.Is(err, ErrIndexMissing) {
+		return nil
+	}
+	return err
+}
+
+// SwapIn swaps index with the searched index, which is created first if
+// missing. The index name then holds the previous documents and is dropped.
+func (m *Meilisearch) SwapIn(ctx context.Context, index string) (string, []string, error) {
+	err := m.write(ctx, http.MethodPost, "/indexes", map[string]string{"uid": m.cfg.Index, "primaryKey": "id"})
+	if err != nil && !errors.Is(err, errMeiliIndexExists) {
+		return "", nil, err
+	}
+	swap := []map[string][]string{{"indexes": {m.cfg.Index, index}}}
+	if err := m.write(ctx, http.MethodPost, "/swap-indexes", swap); err != nil {
+		return "", nil, err
+	}
+	return m.cfg.Index, []string{index}, nil
+}
+
+// Bulk applies changes to index in order, one task per run of changes of the
+// same kind. A stock change of a product that isn't indexed leaves a document
+// with only an ID and quantity, which searches filter out and a later upsert
+// or delete replaces.
+func (m *Meilisearch) Bulk(ctx context.Context, index string, changes []*models.ProductChange) error {
+	documents := "/indexes/" + url.PathEscape(index) + "/documents"
+	var tasks []int64
+	for start := 0; start < len(changes); {
+		op := changes[start].Op
+		end := start
+		for end < len(changes) && changes[end].Op == op {
+			end++
+		}
+		run := changes[start:end]
+		start = end
+
+		var (
+			method, path string
+			body         interface{}
+		)
+		switch op {
+		case models.ChangeUpsert:
+			docs := make([]*models.Product, len(run))
+			for i, c := range run {
+				docs[i] = c.Product
+			}
+			method, path, body = http.MethodPost, documents+"?primaryKey=id", docs
+		case models.ChangeStock:
+			docs := make([]map[string]interface{}, len(run))
+			for i, c := range run {
+				docs[i] = map[string]interface{}{"id": c.ProductID, "quantity": c.Quantity}
+			}
+			method, path, body = http.MethodPut, documents+"?primaryKey=id", docs
+		case models.ChangeDelete:
+			ids := make([]int, len(run))
+			for i, c := range run {
+				ids[i] = c.ProductID
+			}
+			method, path, body = http.MethodPost, documents+"/delete-batch", ids
+		default:
+			return fmt.Errorf("meilisearch: unknown change %q of product %d", op, run[0].ProductID)
+		}
+
+		task, err := m.enqueue(ctx, method, path, body)
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, task)
+	}
+
+	for _, task := range tasks {
+		if err := m.wait(ctx, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errMeiliIndexExists is returned when creating an index that exists
+var errMeiliIndexExists = errors.New("meilisearch: index already exists")
+
+// meiliError is the error body of failed requests and tasks
+type meiliError struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+func (e meiliError) err(op string) error {
+	switch e.Code {
+	case "index_not_found":
+		return fmt.Errorf("%w: %s: %s", ErrIndexMissing, op, e.Message)
+	case "index_already_exists":
+		return fmt.Errorf("%w: %s: %s", errMeiliIndexExists, op, e.Message)
+	}
+	return fmt.Errorf("meilisearch: %s: %s (%s)", op, e.Message, e.Code)
+}
+
+// write enqueues a task and waits for it to finish
+func (m *Meilisearch) write(ctx context.Context, method, path string, in interface{}) error {
+	task, err := m.enqueue(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	return m.wait(ctx, task)
+}
+
+func (m *Meilisearch) enqueue(ctx context.Context, method, path string, in interface{}) (int64, error) {
+	var task struct {
+		TaskUID int64 `json:"taskUid"`
+	}
+	if err := m.do(ctx, method, path, in, &task); err != nil {
+		return 0, err
+	}
+	return task.TaskUID, nil
+}
+
+// wait polls a task until it has finished, backing off up to half a second
+func (m *Meilisearch) wait(ctx context.Context, uid int64) error {
+	delay := 10 * time.Millisecond
+	for {
+		var task struct {
+			Status string      `json:"status"`
+			Type   string      `json:"type"`
+			Error  *meiliError `json:"error"`
+		}
+		if err := m.do(ctx, http.MethodGet, "/tasks/"+strconv.FormatInt(uid, 10), nil, &task); err != nil {
+			return err
+		}
+		switch task.Status {
+		case "succeeded":
+			return nil
+		case "failed":
+			if task.Error == nil {
+				return fmt.Errorf("meilisearch: task %d (%s) failed", uid, task.Type)
+			}
+			return task.Error.err(fmt.Sprintf("task %d (%s)", uid, task.Type))
+		case "canceled":
+			return fmt.Errorf("meilisearch: task %d (%s) was canceled", uid, task.Type)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, 500*time.Millisecond)
+	}
+}
+
+// do sends a JSON request and decodes the JSON response into out, if given
+func (m *Meilisearch) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, m.base.String()+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if m.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.cfg.APIKey)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("meilisearch: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		var e meiliError
+		if json.Unmarshal(msg, &e) == nil && e.Code != "" {
+			return e.err(method + " " + path)
+		}
+		return fmt.Errorf("meilisearch: %s %s: %s: %s", method, path, resp.Status, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("meilisearch: failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/changefeed"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// fakeMeilisearch implements the part of the Meilisearch API the package
+// uses. Tasks run when enqueued and report "processing" on their first poll.
+type fakeMeilisearch struct {
+	mu       sync.Mutex
+	indexes  map[string]map[string]map[string]interface{} // Documents by ID, by index
+	settings map[string]json.RawMessage
+	tasks    []map[string]interface{}
+	polled   map[int]bool
+	search   map[string]interface{}
+}
+
+func newFakeMeilisearch(t *testing.T) (*fakeMeilisearch, *Meilisearch) {
+	fake := &fakeMeilisearch{indexes: map[string]map[string]map[string]interface{}{}, settings: map[string]json.RawMessage{}, polled: map[int]bool{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client, err := NewMeilisearch(MeilisearchConfig{URL: srv.URL, Index: "products", TypoTolerance: true, MinWordSizeOneTypo: 5, MinWordSizeTwoTypos: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, client
+}
+
+func (f *fakeMeilisearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	var index string
+	if len(parts) > 1 && parts[0] == "indexes" {
+		index = parts[1]
+	}
+	docs, exists := f.indexes[index]
+	notFound := map[string]string{"code": "index_not_found", "message": "Index `" + index + "` not found."}
+
+	// Runs a write and responds with its task
+	enqueue := func(run func() map[string]string) {
+		task := map[string]interface{}{"uid": len(f.tasks), "type": r.Method + " " + r.URL.Path, "status": "succeeded"}
+		if failure := run(); failure != nil {
+			task["status"], task["error"] = "failed", failure
+		}
+		f.tasks = append(f.tasks, task)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{"taskUid": task["uid"], "status": "enqueued"})
+	}
+
+	switch {
+	case r.Method == http.MethodGet && parts[0] == "tasks":
+		uid, _ := strconv.Atoi(parts[1])
+		if !f.polled[uid] {
+			f.polled[uid] = true
+			json.NewEncoder(w).Encode(map[string]interface{}{"uid": uid, "status": "processing"})
+			return
+		}
+		json.NewEncoder(w).Encode(f.tasks[uid])
+
+	case r.Method == http.MethodPost && r.URL.Path == "/indexes":
+		var created struct {
+			UID string `json:"uid"`
+		}
+		json.Unmarshal(body, &created)
+		enqueue(func() map[string]string {
+			if f.indexes[created.UID] != nil {
+				return map[string]string{"code": "index_already_exists", "message": "Index already exists."}
+			}
+			f.indexes[created.UID] = map[string]map[string]interface{}{}
+			return nil
+		})
+
+	case r.Method == http.MethodDelete && len(parts) == 2:
+		enqueue(func() map[string]string {
+			if !exists {
+				return notFound
+			}
+			delete(f.indexes, index)
+			delete(f.settings, index)
+			return nil
+		})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/swap-indexes":
+		var swaps []struct {
+			Indexes []string `json:"indexes"`
+		}
+		json.Unmarshal(body, &swaps)
+		enqueue(func() map[string]string {
+			a, b := swaps[0].Indexes[0], swaps[0].Indexes[1]
+			if f.indexes[a] == nil || f.indexes[b] == nil {
+				return notFound
+			}
+			f.indexes[a], f.indexes[b] = f.indexes[b], f.indexes[a]
+			f.settings[a], f.settings[b] = f.settings[b], f.settings[a]
+			return nil
+		})
+
+	case !exists:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(notFound)
+
+	case len(parts) == 3 && parts[2] == "settings" && r.Method == http.MethodGet:
+		w.Write(f.settings[index])
+
+	case len(parts) == 3 && parts[2] == "settings" && r.Method == http.MethodPatch:
+		enqueue(func() map[string]string {
+			f.settings[index] = body
+			return nil
+		})
+
+	case len(parts) == 3 && parts[2] == "documents":
+		var batch []map[string]interface{}
+		json.Unmarshal(body, &batch)
+		enqueue(func() map[string]string {
+			for _, doc := range batch {
+				id := fmt.Sprint(doc["id"])
+				if r.Method == http.MethodPut && docs[id] != nil {
+					for k, v := range doc {
+						docs[id][k] = v
+					}
+					continue
+				}
+				docs[id] = doc
+			}
+			return nil
+		})
+
+	case len(parts) == 4 && parts[3] == "delete-batch":
+		var ids []int
+		json.Unmarshal(body, &ids)
+		enqueue(func() map[string]string {
+			for _, id := range ids {
+				delete(docs, strconv.Itoa(id))
+			}
+			return nil
+		})
+
+	case len(parts) == 3 && parts[2] == "search":
+		json.Unmarshal(body, &f.search)
+		// Ignores the query but applies the sku EXISTS filter
+		hits := []map[string]interface{}{}
+		for _, doc := range docs {
+			if _, ok := doc["sku"]; ok {
+				hits = append(hits, doc)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"hits": hits, "totalHits": len(hits)})
+
+	default:
+		http.Error(w, `{"code":"bad_request","message":"unexpected request"}`, http.StatusBadRequest)
+	}
+}
+
+// quantities returns the quantity of every document of the index
+func (f *fakeMeilisearch) quantities(index string) map[string]float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	quantities := map[string]float64{}
+	for id, doc := range f.indexes[index] {
+		quantities[id], _ = doc["quantity"].(float64)
+	}
+	return quantities
+}
+
+func TestIndexer_Meilisearch(t *testing.T) {
+	fake, index := newFakeMeilisearch(t)
+	feed := &memoryOutbox{}
+	state := &memoryState{states: map[string]models.SearchIndexState{}}
+	catalog := &memoryProducts{products: []*models.Product{{ID: 1, SKU: "BK-1", Name: "Atlas", Quantity: 3}, {ID: 2, SKU: "BK-2", Name: "Globe", Quantity: 5}}}
+	indexer := NewIndexer(index, changefeed.New(feed), state, catalog, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	// The first sync builds an index and swaps it in under the configured name
+	if err := indexer.Sync(ctx); err != nil {
+		t.Fatalf("first Sync: %v", err)
+	}
+	if docs := fake.quantities("products"); len(docs) != 2 || docs["1"] != 3 {
+		t.Fatalf("documents after the first sync = %v, want the catalog", docs)
+	}
+	if len(fake.indexes) != 1 || state.states["products"].IndexName != "products" {
+		t.Fatalf("indexes = %v, state = %+v; want only products", fake.indexes, state.states["products"])
+	}
+
+	feed.publish(t, events.ProductCreated{Product: models.Product{ID: 3, SKU: "TL-1", Name: "Hammer", Quantity: 1}})
+	feed.publish(t, events.StockAdjusted{ProductID: 1, SKU: "BK-1", Previous: 3, Current: 2, Delta: -1, Reason: "order"})
+	feed.publish(t, events.ProductDeleted{ProductID: 2, SKU: "BK-2"})
+	feed.publish(t, events.StockAdjusted{ProductID: 2, SKU: "BK-2", Previous: 5, Current: 0, Delta: -5, Reason: "order"}) // Of a deleted document
+	if err := indexer.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if docs := fake.quantities("products"); len(docs) != 3 || docs["1"] != 2 || docs["3"] != 1 {
+		t.Errorf("documents = %v, want 1 with quantity 2, 3, and the quantity of 2", docs)
+	}
+	// The leftover quantity of the deleted product isn't found
+	if result, err := index.Search(ctx, models.SearchQuery{Limit: 10}); err != nil || result.Total != 2 || len(result.Products) != 2 {
+		t.Errorf("Search = %+v, %v; want products 1 and 3", result, err)
+	}
+
+	// A reindex swaps a fresh copy in and drops the index it swapped out
+	indexer.now = func() time.Time { return time.Now().Add(time.Second) }
+	if _, err := indexer.Reindex(ctx, nil); err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	if docs := fake.quantities("products"); len(docs) != 2 || docs["1"] != 3 || len(fake.indexes) != 1 {
+		t.Errorf("after Reindex documents = %v, indexes = %d; want the catalog in products alone", docs, len(fake.indexes))
+	}
+}
+
+func TestMeilisearch_Check(t *testing.T) {
+	fake, index := newFakeMeilisearch(t)
+	ctx := context.Background()
+
+	if _, err := index.Check(ctx); !errors.Is(err, ErrIndexMissing) {
+		t.Fatalf("Check without an index = %v, want ErrIndexMissing", err)
+	}
+
+	// Settings from an earlier configuration are updated in place
+	fake.indexes["products"] = map[string]map[string]interface{}{}
+	fake.settings["products"] = json.RawMessage(`{"searchableAttributes":["*"],"typoTolerance":{"enabled":false}}`)
+	if current, err := index.Check(ctx); err != nil || !current {
+		t.Fatalf("Check = %v, %v; want true", current, err)
+	}
+	var settings meiliSettings
+	if err := json.Unmarshal(fake.settings["products"], &settings); err != nil || !reflect.DeepEqual(settings, index.settings) {
+		t.Errorf("settings = %s, want %+v", fake.settings["products"], index.settings)
+	}
+	if !settings.TypoTolerance.Enabled || settings.TypoTolerance.MinWordSizeForTypos.TwoTypos != 9 {
+		t.Errorf("typo tolerance = %+v", settings.TypoTolerance)
+	}
+}
+
+func TestMeiliSearchRequest(t *testing.T) {
+	req := meiliSearchRequest(models.SearchQuery{Text: " atlas ", Category: `Books "rare"`, Tag: "Maps", Region: "DE", Limit: 20, Offset: 40})
+	want := []string{"sku EXISTS", `category = "Books \"rare\""`, `tags = "maps"`, `(regions = "DE" OR regions NOT EXISTS)`}
+	if !reflect.DeepEqual(req["filter"], want) || req["q"] != "atlas" {
+		t.Errorf("filter = %v, q = %q; want %v", req["filter"], req["q"], want)
+	}
+	if req["page"] != 3 || req["hitsPerPage"] != 20 || req["offset"] != nil {
+		t.Errorf("paging = %v, want page 3 of 20", req)
+	}
+	if req["facets"] != nil {
+		t.Errorf("facets = %v, want none unless asked for", req["facets"])
+	}
+
+	// Only categories are Meilisearch facets; ranges are counted apart
+	req = meiliSearchRequest(models.SearchQuery{Limit: 20, Facets: models.FacetSpec{Names: models.FacetNames}})
+	if !reflect.DeepEqual(req["facets"], []string{"category"}) {
+		t.Errorf("facets = %v, want category", req["facets"])
+	}
+
+	// Offsets between pages only get an estimated total
+	req = meiliSearchRequest(models.SearchQuery{Limit: 20, Offset: 5})
+	if req["offset"] != 5 || req["limit"] != 20 || req["page"] != nil {
+		t.Errorf("paging = %v, want offset 5 and limit 20", req)
+	}
+}
+
+func TestMeiliSuggestRequest(t *testing.T) {
+	req := meiliSuggestRequest(models.SuggestQuery{Prefix: " atl ", Limit: 5})
+	if want := []string{"sku EXISTS"}; !reflect.DeepEqual(req["filter"], want) || req["q"] != "atl" || req["limit"] != 5 {
+		t.Errorf("request = %v, want q atl, limit 5, and filter %v", req, want)
+	}
+
+	// Products hidden now or not sold in the region are left out, like in
+	// searches
+	req = meiliSuggestRequest(models.SuggestQuery{Prefix: "atl", Region: "DE", Visible: time.Now(), Limit: 5})
+	if want := []string{"sku EXISTS", `(regions = "DE" OR regions NOT EXISTS)`, "listed != false"}; !reflect.DeepEqual(req["filter"], want) {
+		t.Errorf("filter = %v, want %v", req["filter"], want)
+	}
+}
@@ -0,0 +1,467 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// MappingVersion is bumped whenever indexMapping changes. The indexer
+// rebuilds an index created with another version.
+const MappingVersion = 3
+
+// indexMapping maps the searchable and filterable fields of products.
+// Documents are products as the API returns them; fields not listed here are
+// kept in _source without being indexed.
+var indexMapping = map[string]interface{}{
+	"settings": map[string]interface{}{
+		"analysis": map[string]interface{}{
+			"normalizer": map[string]interface{}{
+				"lowercase": map[string]interface{}{"type": "custom", "filter": []string{"lowercase"}},
+			},
+		},
+	},
+	"mappings": map[string]interface{}{
+		"dynamic": false,
+		"_meta":   map[string]interface{}{"mapping_version": MappingVersion},
+		"properties": map[string]interface{}{
+			"id":          map[string]interface{}{"type": "integer"},
+			"sku":         map[string]interface{}{"type": "keyword", "normalizer": "lowercase"},
+			"name":        map[string]interface{}{"type": "text", "analyzer": "english"},
+			"description": map[string]interface{}{"type": "text", "analyzer": "english"},
+			"category":    map[string]interface{}{"type": "keyword", "normalizer": "lowercase"},
+			"tags":        map[string]interface{}{"type": "keyword"},
+			"regions":     map[string]interface{}{"type": "keyword"},
+			"listed":      map[string]interface{}{"type": "boolean"},
+			"quantity":    map[string]interface{}{"type": "integer"},
+			"unit_price":  map[string]interface{}{"type": "scaled_float", "scaling_factor": 100},
+			"updated_at":  map[string]interface{}{"type": "date"},
+		},
+	},
+}
+
+// ErrIndexMissing is returned when an index, or the alias, doesn't exist
+var ErrIndexMissing = errors.New("search index missing")
+
+type OpenSearchConfig struct {
+	URL      string // e.g. http://localhost:9200
+	Username string // Basic auth, optional
+	Password string
+	Alias    string // Searches and indexing go through this alias
+}
+
+// OpenSearch searches and maintains an OpenSearch (or Elasticsearch) index.
+// The index is reached through an alias, so a rebuilt index can replace it
+// atomically.
+type OpenSearch struct {
+	cfg    OpenSearchConfig
+	base   *url.URL
+	client *http.Client
+}
+
+func NewOpenSearch(cfg OpenSearchConfig) (*OpenSearch, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("opensearch: invalid URL %q", cfg.URL)
+	}
+	if cfg.Alias == "" {
+		return nil, fmt.Errorf("opensearch: an index alias is required")
+	}
+	return &OpenSearch{cfg: cfg, base: base, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (o *OpenSearch) Name() string { return "opensearch" }
+
+// Alias is the name searches go through
+func (o *OpenSearch) Alias() string { return o.cfg.Alias }
+
+func (o *OpenSearch) Search(ctx context.Context, q models.SearchQuery) (*models.SearchResult, error) {
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source models.Product `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]struct {
+			Buckets []struct {
+				Key      interface{} `json:"key"` // A term, or a range's key
+				DocCount int         `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"aggregations"`
+	}
+	if err := o.do(ctx, http.MethodPost, "/"+o.cfg.Alias+"/_search", searchRequest(q), &result); err != nil {
+		return nil, err
+	}
+
+	products := make([]*models.Product, len(result.Hits.Hits))
+	for i := range result.Hits.Hits {
+		products[i] = &result.Hits.Hits[i].Source
+	}
+	found := &models.SearchResult{Products: products, Total: result.Hits.Total.Value}
+	if len(q.Facets.Names) == 0 {
+		return found, nil
+	}
+
+	found.Facets = models.Facets{}
+	for _, name := range q.Facets.Names {
+		var buckets []models.FacetBucket
+		switch name {
+		case models.FacetCategory:
+			buckets = []models.FacetBucket{}
+		case models.FacetPriceRange:
+			buckets = models.PriceRanges(q.Facets.PriceBounds)
+		case models.FacetStatus:
+			buckets = models.StatusBuckets()
+		}
+		for _, b := range result.Aggregations[name].Buckets {
+			key := fmt.Sprint(b.Key)
+			if name == models.FacetCategory {
+				buckets = append(buckets, models.FacetBucket{Value: key, Count: b.DocCount})
+				continue
+			}
+			for i := range buckets {
+				if buckets[i].Value == key {
+					buckets[i].Count = b.DocCount
+				}
+			}
+		}
+		found.Facets[name] = buckets
+	}
+	return found, nil
+}
+
+// searchRequest builds the query DSL for q: the text matched against the
+// SKU, name, tags, and description, ranked by relevance, then ID, with an
+// aggregation per facet
+func searchRequest(q models.SearchQuery) map[string]interface{} {
+	must := []interface{}{map[string]interface{}{"match_all": map[string]interface{}{}}}
+	if text := strings.TrimSpace(q.Text); text != "" {
+		must = []interface{}{map[string]interface{}{"multi_match": map[string]interface{}{
+			"query":    text,
+			"fields":   []string{"sku^4", "name^3", "tags^2", "description"},
+			"operator": "and",
+		}}}
+	}
+	filter := []interface{}{}
+	if q.Category != "" {
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"category": q.Category}})
+	}
+	if q.Tag != "" {
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"tags": strings.ToLower(q.Tag)}})
+	}
+	if q.Region != "" {
+		filter = append(filter, regionFilter(q.Region))
+	}
+	if !q.Visible.IsZero() {
+		filter = append(filter, map[string]interface{}{"bool": map[string]interface{}{
+			"must_not": map[string]interface{}{"term": map[string]interface{}{"listed": false}},
+		}})
+	}
+
+	req := map[string]interface{}{
+		"from":             q.Offset,
+		"size":             q.Limit,
+		"track_total_hits": true,
+		"query":            map[string]interface{}{"bool": map[string]interface{}{"must": must, "filter": filter}},
+		"sort":             []interface{}{"_score", map[string]interface{}{"id": "asc"}},
+	}
+	if aggs := facetAggregations(q.Facets); len(aggs) > 0 {
+		req["aggs"] = aggs
+	}
+	return req
+}
+
+// regionFilter matches the products limited to region, or to none
+func regionFilter(region string) map[string]interface{} {
+	return map[string]interface{}{"bool": map[string]interface{}{
+		"should": []interface{}{
+			map[string]interface{}{"term": map[string]interface{}{"regions": region}},
+			map[string]interface{}{"bool": map[string]interface{}{"must_not": map[string]interface{}{"exists": map[string]interface{}{"field": "regions"}}}},
+		},
+		"minimum_should_match": 1,
+	}}
+}
+
+// facetAggregations builds an aggregation per facet of spec, keyed like its
+// buckets. Categories are the lowercase terms the index normalizes them to;
+// the empty category isn't indexed.
+func facetAggregations(spec models.FacetSpec) map[string]interface{} {
+	aggs := map[string]interface{}{}
+	if spec.Has(models.FacetCategory) {
+		aggs[models.FacetCategory] = map[string]interface{}{"terms": map[string]interface{}{
+			"field": "category",
+			"size":  models.MaxCategoryBuckets,
+			"order": []interface{}{map[string]string{"_count": "desc"}, map[string]string{"_key": "asc"}},
+		}}
+	}
+	if spec.Has(models.FacetPriceRange) {
+		var ranges []interface{}
+		for _, b := range models.PriceRanges(spec.PriceBounds) {
+			r := map[string]interface{}{"key": b.Value, "from": *b.Min}
+			if b.Max != nil {
+				r["to"] = *b.Max
+			}
+			ranges = append(ranges, r)
+		}
+		aggs[models.FacetPriceRange] = map[string]interface{}{"range": map[string]interface{}{"field": "unit_price", "ranges": ranges}}
+	}
+	if spec.Has(models.FacetStatus) {
+		low := spec.LowStock + 1 // Range ends are exclusive
+		aggs[models.FacetStatus] = map[string]interface{}{"range": map[string]interface{}{"field": "quantity", "ranges": []interface{}{
+			map[string]interface{}{"key": models.AvailabilityOutOfStock, "to": 1},
+			map[string]interface{}{"key": models.AvailabilityLow, "from": 1, "to": low},
+			map[string]interface{}{"key": models.AvailabilityInStock, "from": low},
+		}}}
+	}
+	return aggs
+}
+
+// Suggest matches names word by word, the last word as a prefix, and SKUs by
+// prefix, which rank first
+func (o *OpenSearch) Suggest(ctx context.Context, q models.SuggestQuery) ([]*models.Suggestion, error) {
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source models.Suggestion `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := o.do(ctx, http.MethodPost, "/"+o.cfg.Alias+"/_search", suggestRequest(q), &result); err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]*models.Suggestion, len(result.Hits.Hits))
+	for i := range result.Hits.Hits {
+		suggestions[i] = &result.Hits.Hits[i].Source
+	}
+	return suggestions, nil
+}
+
+func suggestRequest(q models.SuggestQuery) map[string]interface{} {
+	prefix := strings.TrimSpace(q.Prefix)
+	filter := []interface{}{}
+	if q.Region != "" {
+		filter = append(filter, regionFilter(q.Region))
+	}
+	if !q.Visible.IsZero() {
+		filter = append(filter, map[string]interface{}{"bool": map[string]interface{}{
+			"must_not": map[string]interface{}{"term": map[string]interface{}{"listed": false}},
+		}})
+	}
+	return map[string]interface{}{
+		"size":    q.Limit,
+		"_source": []string{"id", "sku", "name"},
+		"query": map[string]interface{}{"bool": map[string]interface{}{
+			"should": []interface{}{
+				map[string]interface{}{"prefix": map[string]interface{}{"sku": map[string]interface{}{"value": strings.ToLower(prefix), "boost": 2}}},
+				map[string]interface{}{"multi_match": map[string]interface{}{"query": prefix, "type": "bool_prefix", "fields": []string{"name"}, "operator": "and"}},
+			},
+			"minimum_should_match": 1,
+			"filter":               filter,
+		}},
+		"sort": []interface{}{"_score", map[string]interface{}{"id": "asc"}},
+	}
+}
+
+// MappingVersion returns the mapping version of the index behind the alias,
+// or ErrIndexMissing when there is none
+func (o *OpenSearch) MappingVersion(ctx context.Context) (int, error) {
+	var mappings map[string]struct {
+		Mappings struct {
+			Meta struct {
+				MappingVersion int `json:"mapping_version"`
+			} `json:"_meta"`
+		} `json:"mappings"`
+	}
+	if err := o.do(ctx, http.MethodGet, "/"+o.cfg.Alias+"/_mapping", nil, &mappings); err != nil {
+		return 0, err
+	}
+	for _, index := range mappings {
+		return index.Mappings.Meta.MappingVersion, nil
+	}
+	return 0, ErrIndexMissing
+}
+
+// Check reports whether the index behind the alias has the current mapping
+func (o *OpenSearch) Check(ctx context.Context) (bool, error) {
+	version, err := o.MappingVersion(ctx)
+	return version == MappingVersion, err
+}
+
+// CreateIndex creates an index with the current mapping
+func (o *OpenSearch) CreateIndex(ctx context.Context, name string) error {
+	return o.do(ctx, http.MethodPut, "/"+name, indexMapping, nil)
+}
+
+func (o *OpenSearch) DeleteIndex(ctx context.Context, name string) error {
+	err := o.do(ctx, http.MethodDelete, "/"+name, nil, nil)
+	if errors.Is(err, ErrIndexMissing) {
+		return nil
+	}
+	return err
+}
+
+// SwapIn refreshes index and points the alias at it
+func (o *OpenSearch) SwapIn(ctx context.Context, index string) (string, []string, error) {
+	if err := o.Refresh(ctx, index); err != nil {
+		return "", nil, err
+	}
+	previous, err := o.SwapAlias(ctx, index)
+	return index, previous, err
+}
+
+// SwapAlias points the alias at index alone, in one atomic step, and returns
+// the indices it pointed to before
+func (o *OpenSearch) SwapAlias(ctx context.Context, index string) ([]string, error) {
+	var current map[string]json.RawMessage
+	err := o.do(ctx, http.MethodGet, "/_alias/"+o.cfg.Alias, nil, &current)
+	if err != nil && !errors.Is(err, ErrIndexMissing) {
+		return nil, err
+	}
+
+	actions := []interface{}{map[string]interface{}{"add": map[string]interface{}{"index": index, "alias": o.cfg.Alias}}}
+	var previous []string
+	for name := range current {
+		if name != index {
+			previous = append(previous, name)
+			actions = append(actions, map[string]interface{}{"remove": map[string]interface{}{"index": name, "alias": o.cfg.Alias}})
+		}
+	}
+	if err := o.do(ctx, http.MethodPost, "/_aliases", map[string]interface{}{"actions": actions}, nil); err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// Refresh makes everything indexed so far searchable
+func (o *OpenSearch) Refresh(ctx context.Context, index string) error {
+	return o.do(ctx, http.MethodPost, "/"+index+"/_refresh", nil, nil)
+}
+
+// Bulk applies changes to index in order. Stock changes and deletes of
+// documents that aren't indexed are ignored.
+func (o *OpenSearch) Bulk(ctx context.Context, index string, changes []*models.ProductChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	body, err := bulkBody(changes)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string          `json:"_id"`
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := o.request(ctx, http.MethodPost, "/"+index+"/_bulk", "application/x-ndjson", bytes.NewReader(body), &result); err != nil {
+		return err
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	for _, item := range result.Items {
+		for action, r := range item {
+			if r.Status/100 == 2 || (r.Status == http.StatusNotFound && action != "index") {
+				continue
+			}
+			return fmt.Errorf("opensearch: bulk %s of %s failed: %d %s", action, r.ID, r.Status, r.Error)
+		}
+	}
+	return nil
+}
+
+// bulkBody encodes changes as a _bulk request
+func bulkBody(changes []*models.ProductChange) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, c := range changes {
+		meta := map[string]interface{}{"_id": strconv.Itoa(c.ProductID)}
+		var err error
+		switch c.Op {
+		case models.ChangeUpsert:
+			if err = enc.Encode(map[string]interface{}{"index": meta}); err == nil {
+				err = enc.Encode(c.Product)
+			}
+		case models.ChangeStock:
+			if err = enc.Encode(map[string]interface{}{"update": meta}); err == nil {
+				err = enc.Encode(map[string]interface{}{"doc": map[string]interface{}{"quantity": c.Quantity}})
+			}
+		case models.ChangeDelete:
+			err = enc.Encode(map[string]interface{}{"delete": meta})
+		default:
+			err = fmt.Errorf("unknown change %q", c.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("opensearch: failed to encode change of product %d: %w", c.ProductID, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// do sends a JSON request and decodes the JSON response into out, if given
+func (o *OpenSearch) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	return o.request(ctx, method, path, "application/json", body, out)
+}
+
+func (o *OpenSearch) request(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, o.base.String()+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if o.cfg.Username != "" {
+		req.SetBasicAuth(o.cfg.Username, o.cfg.Password)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("opensearch: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode == http.StatusNotFound {
+			// A missing index or alias
+			return fmt.Errorf("%w: %s %s: %s", ErrIndexMissing, method, path, msg)
+		}
+		return fmt.Errorf("opensearch: %s %s: %s: %s", method, path, resp.Status, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("opensearch: failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
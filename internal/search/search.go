@@ -0,0 +1,112 @@
+// Package search serves product search and completions from Postgres or an
+// OpenSearch or Meilisearch index kept in sync from the change feed.
+package search
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Backend answers product searches
+type Backend interface {
+	Name() string
+
+	// Search returns a page of the products matching q, best match first,
+	// and how many match in total
+	Search(ctx context.Context, q models.SearchQuery) (*models.SearchResult, error)
+
+	// Suggest returns up to q.Limit name or SKU completions of q.Prefix,
+	// best first, for typeahead
+	Suggest(ctx context.Context, q models.SuggestQuery) ([]*models.Suggestion, error)
+}
+
+// Postgres searches the products table itself, so results are never stale.
+// When the text matches nothing, it falls back to matching words by trigram
+// similarity and suggests a corrected spelling.
+type Postgres struct {
+	repo      repository.SearchRepository
+	threshold float64 // Least pg_trgm similarity of fuzzy matches, 0 disables them
+}
+
+func NewPostgres(repo repository.SearchRepository, threshold float64) *Postgres {
+	return &Postgres{repo: repo, threshold: threshold}
+}
+
+func (p *Postgres) Name() string { return "postgres" }
+
+func (p *Postgres) Search(ctx context.Context, q models.SearchQuery) (*models.SearchResult, error) {
+	result, err := p.search(ctx, q)
+	if err != nil || len(q.Facets.Names) == 0 {
+		return result, err
+	}
+
+	// Facets count what the results are: fuzzy matches, when those were shown
+	threshold := 0.0
+	if result.Fuzzy {
+		threshold = p.threshold
+	}
+	if result.Facets, err = p.repo.Facets(ctx, q, threshold); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (p *Postgres) search(ctx context.Context, q models.SearchQuery) (*models.SearchResult, error) {
+	products, total, err := p.repo.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if total > 0 || p.threshold <= 0 || strings.TrimSpace(q.Text) == "" {
+		return &models.SearchResult{Products: products, Total: total}, nil
+	}
+
+	products, total, err = p.repo.FuzzySearch(ctx, q, p.threshold)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.SearchResult{Products: products, Total: total, Fuzzy: true}
+	if total == 0 {
+		return result, nil
+	}
+
+	// Corrections come from the words of the matches shown
+	corrected, err := p.repo.Correct(ctx, q.Text, matchWords(products), p.threshold)
+	if err != nil {
+		return nil, err
+	}
+	if corrected != strings.Join(strings.Fields(strings.ToLower(q.Text)), " ") {
+		result.DidYouMean = corrected
+	}
+	return result, nil
+}
+
+// matchWords returns the distinct lowercase words of the names, and the
+// SKUs, of products
+func matchWords(products []*models.Product) []string {
+	seen := map[string]bool{}
+	var words []string
+	add := func(word string) {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	for _, p := range products {
+		add(strings.ToLower(p.SKU))
+		for _, word := range strings.FieldsFunc(strings.ToLower(p.Name), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			add(word)
+		}
+	}
+	return words
+}
+
+// Suggest completes names and SKUs with trigram indexes
+func (p *Postgres) Suggest(ctx context.Context, q models.SuggestQuery) ([]*models.Suggestion, error) {
+	return p.repo.Suggest(ctx, q)
+}
@@ -0,0 +1,478 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/changefeed"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/outbox"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// fakeOpenSearch implements the part of the OpenSearch API the package uses
+type fakeOpenSearch struct {
+	mu       sync.Mutex
+	indices  map[string]map[string]json.RawMessage // Documents by ID, by index
+	mappings map[string]json.RawMessage
+	aliases  map[string]string // Index by alias
+	search   map[string]interface{}
+	aggs     map[string]interface{} // Returned by every search
+}
+
+func newFakeOpenSearch(t *testing.T) (*fakeOpenSearch, *OpenSearch) {
+	fake := &fakeOpenSearch{indices: map[string]map[string]json.RawMessage{}, mappings: map[string]json.RawMessage{}, aliases: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client, err := NewOpenSearch(OpenSearchConfig{URL: srv.URL, Alias: "products"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, client
+}
+
+func (f *fakeOpenSearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	target := parts[0]
+	if index, ok := f.aliases[target]; ok {
+		target = index
+	}
+	missing := func() {
+		http.Error(w, `{"error":{"type":"index_not_found_exception"},"status":404}`, http.StatusNotFound)
+	}
+
+	switch {
+	case r.Method == http.MethodPut && len(parts) == 1:
+		var created struct {
+			Mappings json.RawMessage `json:"mappings"`
+		}
+		json.Unmarshal(body, &created)
+		f.indices[target] = map[string]json.RawMessage{}
+		f.mappings[target] = created.Mappings
+		w.Write([]byte(`{"acknowledged":true}`))
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		if _, ok := f.indices[target]; !ok {
+			missing()
+			return
+		}
+		delete(f.indices, target)
+		w.Write([]byte(`{"acknowledged":true}`))
+	case r.Method == http.MethodGet && target == "_alias":
+		index, ok := f.aliases[parts[1]]
+		if !ok {
+			http.Error(w, `{"error":"alias [`+parts[1]+`] missing","status":404}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{index: map[string]interface{}{"aliases": map[string]interface{}{parts[1]: map[string]interface{}{}}}})
+	case r.Method == http.MethodPost && target == "_aliases":
+		var req struct {
+			Actions []map[string]struct{ Index, Alias string } `json:"actions"`
+		}
+		json.Unmarshal(body, &req)
+		for _, action := range req.Actions {
+			if add, ok := action["add"]; ok {
+				f.aliases[add.Alias] = add.Index
+			}
+		}
+		w.Write([]byte(`{"acknowledged":true}`))
+	case r.Method == http.MethodGet && parts[len(parts)-1] == "_mapping":
+		if _, ok := f.indices[target]; !ok {
+			missing()
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{target: map[string]interface{}{"mappings": f.mappings[target]}})
+	case parts[len(parts)-1] == "_refresh":
+		w.Write([]byte(`{}`))
+	case parts[len(parts)-1] == "_bulk":
+		docs, ok := f.indices[target]
+		if !ok {
+			missing()
+			return
+		}
+		f.bulk(w, docs, body)
+	case parts[len(parts)-1] == "_search":
+		docs, ok := f.indices[target]
+		if !ok {
+			missing()
+			return
+		}
+		json.Unmarshal(body, &f.search)
+		var hits []map[string]json.RawMessage
+		for _, doc := range docs {
+			hits = append(hits, map[string]json.RawMessage{"_source": doc})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"hits": map[string]interface{}{"total": map[string]int{"value": len(hits)}, "hits": hits}, "aggregations": f.aggs})
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+	}
+}
+
+func (f *fakeOpenSearch) bulk(w http.ResponseWriter, docs map[string]json.RawMessage, body []byte) {
+	var items []map[string]interface{}
+	failed := false
+	lines := bufio.NewScanner(strings.NewReader(string(body)))
+	for lines.Scan() {
+		var action map[string]struct {
+			ID string `json:"_id"`
+		}
+		json.Unmarshal(lines.Bytes(), &action)
+		for op, meta := range action {
+			status := http.StatusOK
+			switch op {
+			case "index":
+				lines.Scan()
+				docs[meta.ID] = json.RawMessage(append([]byte(nil), lines.Bytes()...))
+			case "update":
+				lines.Scan()
+				var update struct {
+					Doc map[string]interface{} `json:"doc"`
+				}
+				json.Unmarshal(lines.Bytes(), &update)
+				doc, ok := docs[meta.ID]
+				if !ok {
+					status = http.StatusNotFound
+					break
+				}
+				var merged map[string]interface{}
+				json.Unmarshal(doc, &merged)
+				for k, v := range update.Doc {
+					merged[k] = v
+				}
+				docs[meta.ID], _ = json.Marshal(merged)
+			case "delete":
+				if _, ok := docs[meta.ID]; !ok {
+					status = http.StatusNotFound
+				}
+				delete(docs, meta.ID)
+			}
+			failed = failed || status != http.StatusOK
+			items = append(items, map[string]interface{}{op: map[string]interface{}{"_id": meta.ID, "status": status}})
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"errors": failed, "items": items})
+}
+
+// documents returns the IDs and quantities of the documents behind the alias
+func (f *fakeOpenSearch) documents(t *testing.T) map[string]int {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	docs := map[string]int{}
+	for id, doc := range f.indices[f.aliases["products"]] {
+		var p models.Product
+		if err := json.Unmarshal(doc, &p); err != nil {
+			t.Fatal(err)
+		}
+		docs[id] = p.Quantity
+	}
+	return docs
+}
+
+// memoryOutbox keeps messages in order, as if every transaction committed
+type memoryOutbox struct {
+	repository.OutboxRepository
+	messages []*models.OutboxMessage
+}
+
+func (o *memoryOutbox) Changes(ctx context.Context, types []string, after models.ChangeCursor, limit int) ([]*models.OutboxMessage, error) {
+	var changes []*models.OutboxMessage
+	for _, msg := range o.messages {
+		if msg.ID > after.ID && len(changes) < limit {
+			changes = append(changes, msg)
+		}
+	}
+	return changes, nil
+}
+
+func (o *memoryOutbox) Head(ctx context.Context, types []string) (models.ChangeCursor, error) {
+	if len(o.messages) == 0 {
+		return models.ChangeCursor{}, nil
+	}
+	return models.ChangeCursor{ID: o.messages[len(o.messages)-1].ID}, nil
+}
+
+func (o *memoryOutbox) Has(ctx context.Context, cursor models.ChangeCursor) (bool, error) {
+	return cursor.ID <= int64(len(o.messages)), nil
+}
+
+func (o *memoryOutbox) publish(t *testing.T, payload events.Payload) {
+	t.Helper()
+	msg, err := outbox.NewMessage(events.New(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.ID = int64(len(o.messages) + 1)
+	o.messages = append(o.messages, msg)
+}
+
+// memoryState keeps index states in a map
+type memoryState struct {
+	repository.SearchRepository
+	states map[string]models.SearchIndexState
+}
+
+func (s *memoryState) IndexState(ctx context.Context, name string) (*models.SearchIndexState, error) {
+	state, ok := s.states[name]
+	if !ok {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func (s *memoryState) SaveIndexState(ctx context.Context, state *models.SearchIndexState) error {
+	s.states[state.Name] = *state
+	return nil
+}
+
+func (s *memoryState) AdvanceCursor(ctx context.Context, name, from, to string) (bool, error) {
+	state, ok := s.states[name]
+	if !ok || state.Cursor != from {
+		return false, nil
+	}
+	state.Cursor = to
+	s.states[name] = state
+	return true, nil
+}
+
+// memoryProducts serves the catalog for reindexing
+type memoryProducts struct {
+	repository.ExportRepository
+	products []*models.Product
+}
+
+func (p *memoryProducts) EachProduct(ctx context.Context, filter models.ExportFilter, fn func(*models.Product) error) (time.Time, error) {
+	for _, product := range p.products {
+		if err := fn(product); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Now(), nil
+}
+
+func TestIndexer_Sync(t *testing.T) {
+	fake, index := newFakeOpenSearch(t)
+	feed := &memoryOutbox{}
+	state := &memoryState{states: map[string]models.SearchIndexState{}}
+	catalog := &memoryProducts{products: []*models.Product{{ID: 1, SKU: "BK-1", Name: "Atlas", Quantity: 3}, {ID: 2, SKU: "BK-2", Name: "Globe", Quantity: 5}}}
+	indexer := NewIndexer(index, changefeed.New(feed), state, catalog, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	// The first sync builds the index from the catalog
+	if err := indexer.Sync(ctx); err != nil {
+		t.Fatalf("first Sync: %v", err)
+	}
+	if docs := fake.documents(t); len(docs) != 2 || docs["1"] != 3 {
+		t.Fatalf("documents after the first sync = %v, want the catalog", docs)
+	}
+	first := state.states["products"]
+	if !strings.HasPrefix(first.IndexName, "products-") || first.Cursor == "" {
+		t.Fatalf("state = %+v", first)
+	}
+
+	feed.publish(t, events.ProductCreated{Product: models.Product{ID: 3, SKU: "TL-1", Name: "Hammer", Quantity: 1}})
+	feed.publish(t, events.StockAdjusted{ProductID: 1, SKU: "BK-1", Previous: 3, Current: 2, Delta: -1, Reason: "order"})
+	feed.publish(t, events.ProductDeleted{ProductID: 2, SKU: "BK-2"})
+	feed.publish(t, events.StockAdjusted{ProductID: 2, SKU: "BK-2", Previous: 5, Current: 0, Delta: -5, Reason: "order"}) // Of a deleted document
+	if err := indexer.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if docs := fake.documents(t); len(docs) != 2 || docs["1"] != 2 || docs["3"] != 1 {
+		t.Errorf("documents = %v, want 1 with quantity 2 and 3", docs)
+	}
+
+	// A reindex replaces the index behind the alias and drops the old one
+	indexer.now = func() time.Time { return time.Now().Add(time.Second) }
+	rebuilt, err := indexer.Reindex(ctx, nil)
+	if err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	if rebuilt.IndexName == first.IndexName || fake.aliases["products"] != rebuilt.IndexName || fake.indices[first.IndexName] != nil {
+		t.Errorf("after Reindex alias = %s, indices = %v; want only %s", fake.aliases["products"], fake.indices, rebuilt.IndexName)
+	}
+	if next, _ := changefeed.New(feed).Read(ctx, changefeed.Now, 1); rebuilt.Cursor != next.NextCursor {
+		t.Errorf("cursor after Reindex = %s, want the head %s", rebuilt.Cursor, next.NextCursor)
+	}
+}
+
+func TestIndexer_OutdatedMapping(t *testing.T) {
+	fake, index := newFakeOpenSearch(t)
+	state := &memoryState{states: map[string]models.SearchIndexState{}}
+	catalog := &memoryProducts{products: []*models.Product{{ID: 1, SKU: "BK-1"}}}
+	ctx := context.Background()
+
+	// An index built with an earlier mapping
+	fake.indices["products-old"] = map[string]json.RawMessage{}
+	fake.mappings["products-old"] = json.RawMessage(`{"_meta":{"mapping_version":0}}`)
+	fake.aliases["products"] = "products-old"
+	state.states["products"] = models.SearchIndexState{Name: "products", IndexName: "products-old", Cursor: changefeed.EncodeCursor(models.ChangeCursor{})}
+
+	indexer := NewIndexer(index, changefeed.New(&memoryOutbox{}), state, catalog, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := indexer.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if fake.aliases["products"] == "products-old" || len(fake.documents(t)) != 1 {
+		t.Errorf("alias = %s with %v, want a rebuilt index", fake.aliases["products"], fake.documents(t))
+	}
+	if version, err := index.MappingVersion(ctx); err != nil || version != MappingVersion {
+		t.Errorf("MappingVersion = %d, %v; want %d", version, err, MappingVersion)
+	}
+}
+
+func TestOpenSearch_Search(t *testing.T) {
+	fake, index := newFakeOpenSearch(t)
+	ctx := context.Background()
+	if err := index.CreateIndex(ctx, "products-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := index.SwapAlias(ctx, "products-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Bulk(ctx, "products", []*models.ProductChange{{Op: models.ChangeUpsert, ProductID: 1, Product: &models.Product{ID: 1, SKU: "BK-1", Name: "Atlas of maps", Tags: []string{"maps"}}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := index.Search(ctx, models.SearchQuery{Text: "atlas", Tag: "MAPS", Region: "DE", Limit: 10})
+	if err != nil || result.Total != 1 || len(result.Products) != 1 || result.Products[0].Name != "Atlas of maps" || result.Products[0].Tags[0] != "maps" {
+		t.Fatalf("Search = %+v, %v", result, err)
+	}
+
+	query, _ := json.Marshal(fake.search["query"])
+	for _, want := range []string{`"multi_match":{"fields":["sku^4","name^3","tags^2","description"],"operator":"and","query":"atlas"}`, `"term":{"tags":"maps"}`, `"term":{"regions":"DE"}`, `"must_not":{"exists":{"field":"regions"}}`} {
+		if !strings.Contains(string(query), want) {
+			t.Errorf("query %s lacks %s", query, want)
+		}
+	}
+}
+
+func TestSuggestRequest(t *testing.T) {
+	query, _ := json.Marshal(suggestRequest(models.SuggestQuery{Prefix: " Atl", Region: "DE", Visible: time.Now(), Limit: 5})["query"])
+	for _, want := range []string{`"prefix":{"sku":{"boost":2,"value":"atl"}}`, `"term":{"regions":"DE"}`, `"must_not":{"exists":{"field":"regions"}}`, `"must_not":{"term":{"listed":false}}`} {
+		if !strings.Contains(string(query), want) {
+			t.Errorf("query %s lacks %s", query, want)
+		}
+	}
+}
+
+func TestOpenSearch_Facets(t *testing.T) {
+	fake, index := newFakeOpenSearch(t)
+	ctx := context.Background()
+	if err := index.CreateIndex(ctx, "products-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := index.SwapAlias(ctx, "products-1"); err != nil {
+		t.Fatal(err)
+	}
+	fake.aggs = map[string]interface{}{
+		"category":    map[string]interface{}{"buckets": []interface{}{map[string]interface{}{"key": "books", "doc_count": 3}}},
+		"price_range": map[string]interface{}{"buckets": []interface{}{map[string]interface{}{"key": "10+", "doc_count": 2}}},
+	}
+
+	spec := models.FacetSpec{Names: []string{models.FacetCategory, models.FacetPriceRange, models.FacetStatus}, PriceBounds: []float64{10}, LowStock: 5}
+	result, err := index.Search(ctx, models.SearchQuery{Text: "atlas", Limit: 10, Facets: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Facets[models.FacetCategory]; len(got) != 1 || got[0].Value != "books" || got[0].Count != 3 {
+		t.Errorf("categories = %+v", got)
+	}
+	// Buckets OpenSearch left out are listed empty
+	if got := result.Facets[models.FacetPriceRange]; len(got) != 2 || got[0].Count != 0 || got[1].Value != "10+" || got[1].Count != 2 {
+		t.Errorf("price ranges = %+v", got)
+	}
+	if got := result.Facets[models.FacetStatus]; len(got) != 3 {
+		t.Errorf("statuses = %+v, want every level", got)
+	}
+
+	aggs, _ := json.Marshal(fake.search["aggs"])
+	for _, want := range []string{
+		`"terms":{"field":"category"`,
+		`"ranges":[{"from":0,"key":"0-10","to":10},{"from":10,"key":"10+"}]`,
+		`{"from":1,"key":"low","to":6}`,
+	} {
+		if !strings.Contains(string(aggs), want) {
+			t.Errorf("aggs %s lack %s", aggs, want)
+		}
+	}
+}
+
+func TestBulkBody(t *testing.T) {
+	quantity := 4
+	body, err := bulkBody([]*models.ProductChange{
+		{Op: models.ChangeUpsert, ProductID: 1, Product: &models.Product{ID: 1, SKU: "BK-1"}},
+		{Op: models.ChangeStock, ProductID: 1, Quantity: &quantity},
+		{Op: models.ChangeDelete, ProductID: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[1], `{"id":1,"sku":"BK-1"`) {
+		t.Fatalf("body =\n%s", body)
+	}
+	for i, want := range map[int]string{0: `{"index":{"_id":"1"}}`, 2: `{"update":{"_id":"1"}}`, 3: `{"doc":{"quantity":4}}`, 4: `{"delete":{"_id":"2"}}`} {
+		if lines[i] != want {
+			t.Errorf("line %d = %s, want %s", i, lines[i], want)
+		}
+	}
+}
+
+// fuzzyRepo answers exact searches with nothing and fuzzy ones with matches
+type fuzzyRepo struct {
+	repository.SearchRepository
+	matches   []*models.Product
+	corrected string
+	words     []string
+}
+
+func (r *fuzzyRepo) Search(ctx context.Context, q models.SearchQuery) ([]*models.Product, int, error) {
+	return nil, 0, nil
+}
+
+func (r *fuzzyRepo) FuzzySearch(ctx context.Context, q models.SearchQuery, threshold float64) ([]*models.Product, int, error) {
+	return r.matches, len(r.matches), nil
+}
+
+func (r *fuzzyRepo) Correct(ctx context.Context, text string, words []string, threshold float64) (string, error) {
+	r.words = words
+	return r.corrected, nil
+}
+
+func TestPostgres_FuzzyFallback(t *testing.T) {
+	repo := &fuzzyRepo{matches: []*models.Product{{ID: 1, SKU: "CH-1", Name: "Office chair"}, {ID: 2, SKU: "CH-2", Name: "Chair, folding"}}, corrected: "office chair"}
+	ctx := context.Background()
+
+	result, err := NewPostgres(repo, 0.3).Search(ctx, models.SearchQuery{Text: "Offcie  chiar", Limit: 10})
+	if err != nil || !result.Fuzzy || result.Total != 2 || result.DidYouMean != "office chair" {
+		t.Fatalf("Search = %+v, %v; want fuzzy matches and a correction", result, err)
+	}
+	if want := []string{"ch-1", "office", "chair", "ch-2", "folding"}; !reflect.DeepEqual(repo.words, want) {
+		t.Errorf("correction words = %v, want %v", repo.words, want)
+	}
+
+	// Text that needs no correction isn't suggested
+	if result, _ := NewPostgres(repo, 0.3).Search(ctx, models.SearchQuery{Text: "Office Chair"}); result.DidYouMean != "" {
+		t.Errorf("DidYouMean = %q, want none", result.DidYouMean)
+	}
+	// A zero threshold disables the fallback
+	if result, _ := NewPostgres(repo, 0).Search(ctx, models.SearchQuery{Text: "chiar"}); result.Fuzzy || result.Total != 0 {
+		t.Errorf("Search without fuzzy matching = %+v", result)
+	}
+}
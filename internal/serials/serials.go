@@ -0,0 +1,179 @@
+// Package serials tracks the units of serialized products by their serial
+// numbers.
+//
+// Tracking is per product and optional: a product is serialized once a
+// serial is registered for it. Its active serials are then its units on
+// hand, so its quantity should equal their count. Serials don't move stock
+// themselves: units are received and sold as for any product, and their
+// serials registered and retired alongside. Registering is refused where it
+// would leave more active serials than units, and the serial_quantity_mismatch
+// integrity check reports serialized products whose counts have drifted.
+package serials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Serial statuses
+const (
+	StatusActive  = "active"
+	StatusRetired = "retired"
+)
+
+// Reasons a serial's unit left stock
+const (
+	ReasonSold     = "sold"
+	ReasonScrapped = "scrapped"
+	ReasonLost     = "lost"
+	ReasonReturned = "returned" // To the supplier
+)
+
+// Reasons are the accepted retirement reasons
+var Reasons = []string{ReasonSold, ReasonScrapped, ReasonLost, ReasonReturned}
+
+// maxSerialLength is the length of serial_numbers.serial
+const maxSerialLength = 100
+
+var (
+	ErrInvalidSerial = errors.New("invalid serial")
+
+	// ErrSerialInUse is returned for a serial that's active already or
+	// registered to another product
+	ErrSerialInUse = errors.New("serial is in use")
+
+	// ErrExceedsStock is returned for registrations that would leave a
+	// product more active serials than units on hand
+	ErrExceedsStock = errors.New("more serials than units on hand")
+
+	ErrRetired = errors.New("serial is retired")
+)
+
+type Service struct {
+	repo     repository.SerialRepository
+	products repository.ProductRepository
+	tx       repository.Transactor
+}
+
+func NewService(repo repository.SerialRepository, products repository.ProductRepository, tx repository.Transactor) *Service {
+	return &Service{repo: repo, products: products, tx: tx}
+}
+
+// Register records serials as active units of a product, all or none. A
+// serial the product retired before, e.g. for a unit sold and returned by
+// the customer, is made active again.
+func (s *Service) Register(ctx context.Context, productID int, serials []string) ([]*models.SerialNumber, error) {
+	if len(serials) == 0 {
+		return nil, fmt.Errorf("%w: serials is required", ErrInvalidSerial)
+	}
+	seen := make(map[string]bool, len(serials))
+	for i, serial := range serials {
+		serial = strings.TrimSpace(serial)
+		switch {
+		case serial == "":
+			return nil, fmt.Errorf("%w: serial %d is empty", ErrInvalidSerial, i)
+		case len(serial) > maxSerialLength:
+			return nil, fmt.Errorf("%w: serial %d must be at most %d characters", ErrInvalidSerial, i, maxSerialLength)
+		case seen[serial]:
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidSerial, serial)
+		}
+		seen[serial] = true
+		serials[i] = serial
+	}
+
+	var registered []*models.SerialNumber
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		registered = registered[:0]
+
+		// Locked so concurrent registrations count each other's serials
+		product, err := s.products.GetByIDForUpdate(ctx, productID)
+		if err != nil {
+			return err
+		}
+		active, err := s.repo.CountByProduct(ctx, productID, StatusActive)
+		if err != nil {
+			return err
+		}
+		if active+len(serials) > product.Quantity {
+			return fmt.Errorf("%w: %s has %d units and %d active serials, %d more can be registered",
+				ErrExceedsStock, product.SKU, product.Quantity, active, max(product.Quantity-active, 0))
+		}
+
+		for _, serial := range serials {
+			sn, ok, err := s.repo.Register(ctx, productID, serial)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return s.inUse(ctx, productID, serial)
+			}
+			registered = append(registered, sn)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return registered, nil
+}
+
+// inUse describes why a serial couldn't be registered to a product
+func (s *Service) inUse(ctx context.Context, productID int, serial string) error {
+	existing, err := s.repo.GetBySerial(ctx, serial)
+	if err != nil {
+		return err
+	}
+	if existing.ProductID != productID {
+		return fmt.Errorf("%w: %s is registered to product %d", ErrSerialInUse, serial, existing.ProductID)
+	}
+	return fmt.Errorf("%w: %s is active already", ErrSerialInUse, serial)
+}
+
+// Retire records that an active serial's unit left stock for reason. It
+// doesn't take the unit from the product's quantity: what sold or wrote off
+// the unit does.
+func (s *Service) Retire(ctx context.Context, serial, reason string) (*models.SerialNumber, error) {
+	if !validReason(reason) {
+		return nil, fmt.Errorf("%w: reason must be one of %s", ErrInvalidSerial, strings.Join(Reasons, ", "))
+	}
+
+	sn, err := s.repo.Retire(ctx, strings.TrimSpace(serial), reason, time.Now())
+	if err != nil {
+		if err.Error() == "serial not found" {
+			if existing, getErr := s.repo.GetBySerial(ctx, strings.TrimSpace(serial)); getErr == nil {
+				return nil, fmt.Errorf("%w: %s was retired as %s", ErrRetired, existing.Serial, existing.RetiredReason)
+			}
+		}
+		return nil, err
+	}
+	return sn, nil
+}
+
+// Lookup returns a serial, active or retired, with its product
+func (s *Service) Lookup(ctx context.Context, serial string) (*models.SerialLookup, error) {
+	sn, err := s.repo.GetBySerial(ctx, strings.TrimSpace(serial))
+	if err != nil {
+		return nil, err
+	}
+	product, err := s.products.GetByID(ctx, sn.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SerialLookup{SerialNumber: sn, Product: product}, nil
+}
+
+func validReason(reason string) bool {
+	for _, r := range Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package serials
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestService_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "serials.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	products := repository.NewProductRepository(db)
+	repo := repository.NewSerialRepository(db)
+	service := NewService(repo, products, db)
+
+	laptop := &models.Product{SKU: "SER-1", Name: "Laptop", Quantity: 3, UnitPrice: 900}
+	phone := &models.Product{SKU: "SER-2", Name: "Phone", Quantity: 2, UnitPrice: 400}
+	for _, p := range []*models.Product{laptop, phone} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	if _, err := service.Register(ctx, laptop.ID, []string{"A1", " A1 "}); !errors.Is(err, ErrInvalidSerial) {
+		t.Errorf("Register(duplicate) error = %v, want ErrInvalidSerial", err)
+	}
+	if _, err := service.Register(ctx, laptop.ID, []string{"A1", "A2", "A3", "A4"}); !errors.Is(err, ErrExceedsStock) {
+		t.Errorf("Register(4 of 3 units) error = %v, want ErrExceedsStock", err)
+	}
+	registered, err := service.Register(ctx, laptop.ID, []string{"A1", " A2"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if len(registered) != 2 || registered[1].Serial != "A2" || registered[1].Status != StatusActive {
+		t.Errorf("Register() = %+v", registered)
+	}
+
+	// All or none: B1 isn't kept when A1 is refused
+	if _, err := service.Register(ctx, phone.ID, []string{"B1", "A1"}); !errors.Is(err, ErrSerialInUse) {
+		t.Errorf("Register(another product's serial) error = %v, want ErrSerialInUse", err)
+	}
+	if n, _ := repo.CountByProduct(ctx, phone.ID, ""); n != 0 {
+		t.Errorf("phone has %d serials, want 0", n)
+	}
+
+	if _, err := service.Retire(ctx, "A1", "eaten"); !errors.Is(err, ErrInvalidSerial) {
+		t.Errorf("Retire(bad reason) error = %v, want ErrInvalidSerial", err)
+	}
+	retired, err := service.Retire(ctx, "A1", ReasonSold)
+	if err != nil {
+		t.Fatalf("Retire() error = %v", err)
+	}
+	if retired.Status != StatusRetired || retired.RetiredReason != ReasonSold || retired.RetiredAt == nil {
+		t.Errorf("Retire() = %+v", retired)
+	}
+	if _, err := service.Retire(ctx, "A1", ReasonSold); !errors.Is(err, ErrRetired) {
+		t.Errorf("Retire() again error = %v, want ErrRetired", err)
+	}
+	if _, err := service.Retire(ctx, "NOPE", ReasonLost); err == nil || err.Error() != "serial not found" {
+		t.Errorf("Retire(unknown) error = %v, want serial not found", err)
+	}
+
+	// A unit sold and returned by its customer is registered again
+	if _, err := service.Register(ctx, laptop.ID, []string{"A1"}); err != nil {
+		t.Fatalf("Register(retired) error = %v", err)
+	}
+	found, err := service.Lookup(ctx, "A1")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if found.Status != StatusActive || found.RetiredReason != "" || found.Product.SKU != "SER-1" {
+		t.Errorf("Lookup() = %+v, product %s", found.SerialNumber, found.Product.SKU)
+	}
+	if _, err := service.Register(ctx, laptop.ID, []string{"A1"}); !errors.Is(err, ErrSerialInUse) {
+		t.Errorf("Register(active) error = %v, want ErrSerialInUse", err)
+	}
+
+	if n, _ := repo.CountByProduct(ctx, laptop.ID, StatusActive); n != 2 {
+		t.Errorf("laptop has %d active serials, want 2", n)
+	}
+}
@@ -0,0 +1,199 @@
+// Package sku generates SKUs for products created without one, from a
+// pattern of literal text (such as a prefix), date parts, and a number:
+//
+//	PRD-{YYYY}{MM}-{SEQ:5}  ->  PRD-202610-00042
+//
+// Tokens are {YYYY}, {YY}, {MM}, and {DD} for the UTC date, and exactly one
+// {SEQ}, or {SEQ:n} zero-padded to n digits. Numbers are per scope, the
+// pattern rendered without its number, so a pattern with date parts restarts
+// at 1 every period.
+package sku
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// maxSKULength is the size of the products.sku column
+const maxSKULength = 255
+
+// maxSkips bounds the numbers skipped because their SKU was taken by hand
+const maxSkips = 100
+
+// Pattern is a parsed SKU pattern
+type Pattern struct {
+	source string
+	parts  []part
+}
+
+// part is literal text or a token
+type part struct {
+	literal string
+	token   string
+	width   int // Of {SEQ:n}
+}
+
+// Parse parses a SKU pattern
+func Parse(pattern string) (*Pattern, error) {
+	p := &Pattern{source: pattern}
+	numbers := 0
+	for rest := pattern; rest != ""; {
+		start := strings.IndexAny(rest, "{}")
+		if start < 0 {
+			p.parts = append(p.parts, part{literal: rest})
+			break
+		}
+		if rest[start] == '}' {
+			return nil, fmt.Errorf("invalid SKU pattern %q: unmatched }", pattern)
+		}
+		if start > 0 {
+			p.parts = append(p.parts, part{literal: rest[:start]})
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid SKU pattern %q: unmatched {", pattern)
+		}
+
+		token := rest[start+1 : start+end]
+		rest = rest[start+end+1:]
+		switch name, width, _ := strings.Cut(token, ":"); name {
+		case "YYYY", "YY", "MM", "DD":
+			if width != "" {
+				return nil, fmt.Errorf("invalid SKU pattern %q: {%s} takes no width", pattern, name)
+			}
+			p.parts = append(p.parts, part{token: name})
+		case "SEQ":
+			n := 0
+			if width != "" {
+				var err error
+				if n, err = strconv.Atoi(width); err != nil || n < 1 || n > 18 {
+					return nil, fmt.Errorf("invalid SKU pattern %q: {SEQ:n} needs a width from 1 to 18", pattern)
+				}
+			}
+			numbers++
+			p.parts = append(p.parts, part{token: name, width: n})
+		default:
+			return nil, fmt.Errorf("invalid SKU pattern %q: unknown token {%s}", pattern, token)
+		}
+	}
+
+	if numbers != 1 {
+		return nil, fmt.Errorf("invalid SKU pattern %q: needs exactly one {SEQ}", pattern)
+	}
+	// Dates render at a fixed length, and numbers at up to 19 digits
+	if len(p.Scope(time.Now()))+19 > maxSKULength {
+		return nil, fmt.Errorf("invalid SKU pattern %q: SKUs could exceed %d characters", pattern, maxSKULength)
+	}
+	return p, nil
+}
+
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Scope is the pattern at t without its number
+func (p *Pattern) Scope(t time.Time) string {
+	return p.render(t, 0, false)
+}
+
+// Render returns the SKU numbered n at t
+func (p *Pattern) Render(t time.Time, n int64) string {
+	return p.render(t, n, true)
+}
+
+func (p *Pattern) render(t time.Time, n int64, number bool) string {
+	t = t.UTC()
+	var b strings.Builder
+	for _, part := range p.parts {
+		switch part.token {
+		case "":
+			b.WriteString(part.literal)
+		case "YYYY":
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case "YY":
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case "MM":
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case "DD":
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case "SEQ":
+			if number {
+				fmt.Fprintf(&b, "%0*d", part.width, n)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Generator generates SKUs from a pattern, numbered by a sequence
+type Generator struct {
+	pattern  *Pattern
+	sequence repository.SKUSequence
+	products repository.ProductRepository
+	now      func() time.Time
+}
+
+// New creates a generator. Numbers whose SKU already exists, e.g. entered by
+// hand, are skipped, so generated SKUs never collide with existing ones.
+func New(pattern string, sequence repository.SKUSequence, products repository.ProductRepository) (*Generator, error) {
+	p, err := Parse(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &Generator{pattern: p, sequence: sequence, products: products, now: time.Now}, nil
+}
+
+// Pattern returns the pattern SKUs are generated from
+func (g *Generator) Pattern() string {
+	return g.pattern.String()
+}
+
+// Next takes the next SKU. It must run in the transaction creating the
+// product, so a rollback returns the number; see repository.SKUSequence.
+func (g *Generator) Next(ctx context.Context) (string, error) {
+	now := g.now()
+	scope := g.pattern.Scope(now)
+	for range maxSkips {
+		n, err := g.sequence.Next(ctx, scope)
+		if err != nil {
+			return "", err
+		}
+		sku := g.pattern.Render(now, n)
+		if taken, err := g.taken(ctx, sku); err != nil || !taken {
+			return sku, err
+		}
+	}
+	return "", fmt.Errorf("failed to generate a SKU: the next %d are taken", maxSkips)
+}
+
+// Preview returns the SKU Next would take now, without taking it. A
+// concurrent create may take it first.
+func (g *Generator) Preview(ctx context.Context) (string, error) {
+	now := g.now()
+	n, err := g.sequence.Peek(ctx, g.pattern.Scope(now))
+	if err != nil {
+		return "", err
+	}
+	for i := range int64(maxSkips) {
+		sku := g.pattern.Render(now, n+i)
+		if taken, err := g.taken(ctx, sku); err != nil || !taken {
+			return sku, err
+		}
+	}
+	return "", fmt.Errorf("failed to preview a SKU: the next %d are taken", maxSkips)
+}
+
+func (g *Generator) taken(ctx context.Context, sku string) (bool, error) {
+	if _, err := g.products.GetBySKU(ctx, sku); err != nil {
+		if err.Error() == "product not found" {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,128 @@
+package sku
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestParse(t *testing.T) {
+	at := time.Date(2026, time.March, 7, 23, 0, 0, 0, time.FixedZone("EST", -5*3600)) // March 8 in UTC
+
+	tests := []struct {
+		pattern   string
+		wantScope string
+		wantSKU   string // Numbered 42
+	}{
+		{"PRD-{YYYY}{MM}-{SEQ:5}", "PRD-202603-", "PRD-202603-00042"},
+		{"{YY}{MM}{DD}{SEQ}", "260308", "26030842"},
+		{"SKU{SEQ:1}", "SKU", "SKU42"},
+		{"{SEQ:3}-EU", "-EU", "042-EU"},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.pattern)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.pattern, err)
+			continue
+		}
+		if scope, sku := p.Scope(at), p.Render(at, 42); scope != tt.wantScope || sku != tt.wantSKU {
+			t.Errorf("%q: scope %q, SKU %q; want %q, %q", tt.pattern, scope, sku, tt.wantScope, tt.wantSKU)
+		}
+	}
+
+	for _, pattern := range []string{
+		"PRD-",
+		"{SEQ}-{SEQ}",
+		"{SEQ:0}",
+		"{SEQ:x}",
+		"{YYYY:2}{SEQ}",
+		"{HH}{SEQ}",
+		"{SEQ",
+		"SEQ}",
+		"{" + "SEQ}" + fmt.Sprintf("%0240d", 0),
+	} {
+		if _, err := Parse(pattern); err == nil {
+			t.Errorf("Parse(%q) succeeded", pattern)
+		}
+	}
+}
+
+// memorySequence is a counter per scope
+type memorySequence map[string]int64
+
+func (s memorySequence) Next(ctx context.Context, scope string) (int64, error) {
+	s[scope]++
+	return s[scope], nil
+}
+
+func (s memorySequence) Peek(ctx context.Context, scope string) (int64, error) {
+	return s[scope] + 1, nil
+}
+
+type skuProducts struct {
+	repository.ProductRepository
+	skus map[string]bool
+}
+
+func (r skuProducts) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	if !r.skus[sku] {
+		return nil, fmt.Errorf("product not found")
+	}
+	return &models.Product{SKU: sku}, nil
+}
+
+func TestGenerator_SkipsTakenSKUs(t *testing.T) {
+	ctx := context.Background()
+	sequence := memorySequence{}
+	g, err := New("A-{SEQ:2}", sequence, skuProducts{skus: map[string]bool{"A-02": true, "A-03": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for range 3 {
+		if preview, err := g.Preview(ctx); err != nil {
+			t.Fatal(err)
+		} else {
+			got = append(got, preview)
+		}
+		next, err := g.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, next)
+	}
+
+	want := []string{"A-01", "A-01", "A-04", "A-04", "A-05", "A-05"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("previews and SKUs = %v, want %v", got, want)
+	}
+	if sequence["A-"] != 5 {
+		t.Errorf("counter = %d, want 5: taken numbers are used up", sequence["A-"])
+	}
+}
+
+func TestGenerator_DateScopes(t *testing.T) {
+	sequence := memorySequence{}
+	g, err := New("{YYYY}-{SEQ}", sequence, skuProducts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, year := range []int{2026, 2026, 2027} {
+		g.now = func() time.Time { return time.Date(year, time.June, 1, 0, 0, 0, 0, time.UTC) }
+		sku, err := g.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, sku)
+	}
+	if fmt.Sprint(got) != "[2026-1 2026-2 2027-1]" {
+		t.Errorf("SKUs = %v, want numbering restarting each year", got)
+	}
+}
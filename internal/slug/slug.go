@@ -0,0 +1,119 @@
+// Package slug makes the URL slugs of products from their names: lowercase
+// ASCII letters and digits, words joined by single hyphens.
+//
+//	Crème Brûlée Torch (2-Pack)  ->  creme-brulee-torch-2-pack
+//
+// Accented Latin letters lose their accents, and other characters separate
+// words. Slugs taken by another product get a numeric suffix, -2, -3, ...
+package slug
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MaxLength bounds slugs made from names, leaving room in the products.slug
+// column for a suffix
+const MaxLength = 200
+
+// maxLength is the size of the products.slug column
+const maxLength = 255
+
+// Fallback is the slug of names without letters or digits
+const Fallback = "product"
+
+// folded are the letters spelled out in ASCII other than by dropping accents
+var folded = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i",
+}
+
+// accents are the accented Latin letters of Latin-1 and Latin Extended-A, by
+// their base letter
+var accents = []struct {
+	letters string
+	base    byte
+}{
+	{"àáâãäåāăą", 'a'}, {"çćĉċč", 'c'}, {"ď", 'd'}, {"èéêëēĕėęě", 'e'}, {"ĝğġģ", 'g'},
+	{"ĥħ", 'h'}, {"ìíîïĩīĭį", 'i'}, {"ĵ", 'j'}, {"ķ", 'k'}, {"ĺļľŀ", 'l'},
+	{"ñńņňŉ", 'n'}, {"òóôõöōŏő", 'o'}, {"ŕŗř", 'r'}, {"śŝşš", 's'}, {"ţťŧ", 't'},
+	{"ùúûüũūŭůűų", 'u'}, {"ŵ", 'w'}, {"ýÿŷ", 'y'}, {"źżž", 'z'},
+}
+
+// Make returns the slug of name, at most MaxLength long, cut at a hyphen
+// when it's longer
+func Make(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		s := fold(r)
+		if s == "" {
+			hyphen = b.Len() > 0
+			continue
+		}
+		if hyphen {
+			b.WriteByte('-')
+			hyphen = false
+		}
+		b.WriteString(s)
+	}
+
+	slug := b.String()
+	if len(slug) > MaxLength {
+		slug = slug[:MaxLength]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+		slug = strings.TrimSuffix(slug, "-")
+	}
+	if slug == "" {
+		return Fallback
+	}
+	return slug
+}
+
+// fold returns r, lowercase, in ASCII letters and digits, or "" when it
+// separates words
+func fold(r rune) string {
+	switch {
+	case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		return string(r)
+	case r < 0x80:
+		return ""
+	}
+	if s, ok := folded[r]; ok {
+		return s
+	}
+	for _, a := range accents {
+		if strings.ContainsRune(a.letters, r) {
+			return string(a.base)
+		}
+	}
+	return ""
+}
+
+// Suffixed returns the nth slug for base: base itself for n 1, and base-n
+// after that
+func Suffixed(base string, n int) string {
+	if n <= 1 {
+		return base
+	}
+	return base + "-" + strconv.Itoa(n)
+}
+
+// Valid reports whether s is a slug: lowercase ASCII letters and digits in
+// words joined by single hyphens, fitting the products.slug column
+func Valid(s string) bool {
+	if s == "" || len(s) > maxLength || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && s[i-1] != '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Widget", "widget"},
+		{"Crème Brûlée Torch (2-Pack)", "creme-brulee-torch-2-pack"},
+		{"  Große Straße -- Ölkanne!  ", "grosse-strasse-olkanne"},
+		{"USB-C ⚡ Charger, 65W", "usb-c-charger-65w"},
+		{"Łódź Æbleskiver", "lodz-aebleskiver"},
+		{"日本茶", Fallback},
+		{"", Fallback},
+	}
+	for _, tt := range tests {
+		if got := Make(tt.name); got != tt.want {
+			t.Errorf("Make(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	long := Make(strings.Repeat("word ", 100))
+	if len(long) > MaxLength || strings.HasSuffix(long, "-") || !strings.HasPrefix(long, "word-word") {
+		t.Errorf("Make(500 characters) = %q (%d), want whole words within %d", long, len(long), MaxLength)
+	}
+}
+
+func TestSuffixed(t *testing.T) {
+	if got := Suffixed("widget", 1); got != "widget" {
+		t.Errorf("Suffixed(widget, 1) = %q", got)
+	}
+	if got := Suffixed("widget", 3); got != "widget-3" {
+		t.Errorf("Suffixed(widget, 3) = %q", got)
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, s := range []string{"widget", "usb-c-charger-65w", "2026", Make("Crème Brûlée")} {
+		if !Valid(s) {
+			t.Errorf("Valid(%q) = false", s)
+		}
+	}
+	for _, s := range []string{"", "-widget", "widget-", "wid--get", "Widget", "wid get", "widgét", strings.Repeat("a", 256)} {
+		if Valid(s) {
+			t.Errorf("Valid(%q) = true", s)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package stocktake
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+// ReadCSV reads counts from CSV with a header row naming the columns: sku and
+// counted, and optionally reason. Header names are case-insensitive and other
+// columns are ignored, so a spreadsheet of the shelf can be uploaded as is.
+// Errors name the line of the CSV.
+func ReadCSV(r io.Reader) ([]models.StockCount, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: CSV is empty", ErrInvalidCount)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCount, err)
+	}
+	columns := map[string]int{"sku": -1, "counted": -1, "reason": -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) // Excel's byte order mark
+		if _, ok := columns[name]; ok {
+			columns[name] = i
+		}
+	}
+	if columns["sku"] < 0 || columns["counted"] < 0 {
+		return nil, fmt.Errorf("%w: CSV header must name the sku and counted columns", ErrInvalidCount)
+	}
+
+	var counts []models.StockCount
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return counts, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidCount, parseErr.Line, parseErr.Err)
+			}
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			if i := columns[name]; i >= 0 && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if field("sku") == "" && field("counted") == "" {
+			continue // Blank rows
+		}
+
+		count := models.StockCount{SKU: field("sku"), Reason: strings.ToLower(field("reason"))}
+		if field("counted") != "" {
+			counted, err := strconv.Atoi(field("counted"))
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: counted must be a whole number", ErrInvalidCount, line)
+			}
+			count.Counted = &counted
+		}
+		counts = append(counts, count)
+	}
+}
@@ -0,0 +1,369 @@
+// Package stocktake runs stock takes: sessions in which products are counted
+// and their stock is then adjusted to the counts.
+//
+// Stock keeps moving while a take is open, so a count records the system
+// quantity at the moment the product was counted, and completing the take
+// adjusts stock by the difference rather than setting it to the count. An
+// order applied between counting a product and completing the take stays
+// applied.
+package stocktake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Stock take statuses
+const (
+	StatusOpen      = "open"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+)
+
+// Reason codes of counts, explaining their discrepancies
+const (
+	ReasonMiscount = "miscount" // The default: the system quantity was wrong
+	ReasonDamaged  = "damaged"
+	ReasonExpired  = "expired"
+	ReasonLost     = "lost"
+	ReasonTheft    = "theft"
+	ReasonFound    = "found"
+)
+
+// Reasons are the reason codes counts can have
+var Reasons = []string{ReasonMiscount, ReasonDamaged, ReasonExpired, ReasonLost, ReasonTheft, ReasonFound}
+
+var (
+	ErrInvalidCount = errors.New("invalid count")
+	ErrNotOpen      = errors.New("stock take is not open")
+
+	// ErrCountedElsewhere is returned for a product another open take has
+	// counted, whose completion would adjust it again
+	ErrCountedElsewhere = errors.New("product is counted in another open stock take")
+)
+
+// ValidReason reports whether reason is one of Reasons
+func ValidReason(reason string) bool {
+	for _, r := range Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Service opens, counts, and completes stock takes, auditing every step
+type Service struct {
+	repo      repository.StockTakeRepository
+	products  repository.ProductRepository
+	audit     repository.AuditRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	logger    *slog.Logger
+}
+
+func NewService(repo repository.StockTakeRepository, products repository.ProductRepository, audit repository.AuditRepository, tx repository.Transactor, publisher events.Publisher, logger *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		products:  products,
+		audit:     audit,
+		tx:        tx,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Open opens a stock take
+func (s *Service) Open(ctx context.Context, name, actor string) (*models.StockTake, error) {
+	take := &models.StockTake{Name: name, Status: StatusOpen, OpenedBy: actor}
+
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, take); err != nil {
+			return err
+		}
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("stock take opened", "stock_take_id", take.ID, "name", take.Name)
+		})
+		return s.record(ctx, "stock_take.open", actor, take.ID, map[string]interface{}{"name": take.Name})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return take, nil
+}
+
+// Record records counts in an open take, each against the product's
+// quantity now. A product counted again is recounted: the new count replaces
+// the earlier one. Counts are recorded all or none, failing with
+// ErrInvalidCount, naming the SKU, for an unknown SKU or an invalid count.
+func (s *Service) Record(ctx context.Context, takeID int, counts []models.StockCount, actor string) ([]*models.StockTakeCount, error) {
+	if len(counts) == 0 {
+		return nil, fmt.Errorf("%w: at least one count is required", ErrInvalidCount)
+	}
+
+	var recorded []*models.StockTakeCount
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		recorded = recorded[:0]
+		if _, err := s.openTake(ctx, takeID); err != nil {
+			return err
+		}
+
+		for i, c := range counts {
+			count, err := s.count(ctx, takeID, i, c)
+			if err != nil {
+				return err
+			}
+			if err := s.repo.RecordCount(ctx, count); err != nil {
+				return err
+			}
+			recorded = append(recorded, count)
+		}
+
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("stock counted", "stock_take_id", takeID, "counts", len(recorded))
+		})
+		return s.record(ctx, "stock_take.count", actor, takeID, map[string]interface{}{"counts": recorded})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recorded, nil
+}
+
+// count validates c, the count at index i of those recorded in a take, and
+// resolves it to the product it counts
+func (s *Service) count(ctx context.Context, takeID, i int, c models.StockCount) (*models.StockTakeCount, error) {
+	sku := strings.TrimSpace(c.SKU)
+	if sku == "" {
+		return nil, fmt.Errorf("%w: count %d: sku is required", ErrInvalidCount, i)
+	}
+	if c.Counted == nil {
+		return nil, fmt.Errorf("%w: %s: counted is required", ErrInvalidCount, sku)
+	}
+	if *c.Counted < 0 {
+		return nil, fmt.Errorf("%w: %s: counted must not be negative", ErrInvalidCount, sku)
+	}
+	reason := c.Reason
+	if reason == "" {
+		reason = ReasonMiscount
+	}
+	if !ValidReason(reason) {
+		return nil, fmt.Errorf("%w: %s: reason must be one of %s", ErrInvalidCount, sku, strings.Join(Reasons, ", "))
+	}
+
+	product, err := s.products.GetBySKU(ctx, sku)
+	if err != nil {
+		if err.Error() == "product not found" {
+			return nil, fmt.Errorf("%w: unknown sku %s", ErrInvalidCount, sku)
+		}
+		return nil, err
+	}
+
+	other, err := s.repo.OpenTakeCounting(ctx, product.ID, takeID)
+	if err != nil {
+		return nil, err
+	}
+	if other != 0 {
+		return nil, fmt.Errorf("%w: %s in stock take %d", ErrCountedElsewhere, product.SKU, other)
+	}
+
+	return &models.StockTakeCount{
+		StockTakeID: takeID,
+		ProductID:   product.ID,
+		SKU:         product.SKU,
+		Counted:     *c.Counted,
+		Expected:    product.Quantity,
+		Reason:      reason,
+	}, nil
+}
+
+// Get returns a take with its counts and their discrepancies
+func (s *Service) Get(ctx context.Context, id int) (*models.StockTakeDetail, error) {
+	take, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.detail(ctx, take)
+}
+
+func (s *Service) detail(ctx context.Context, take *models.StockTake) (*models.StockTakeDetail, error) {
+	counts, err := s.repo.ListCounts(ctx, take.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &models.StockTakeDetail{StockTake: take, Counts: counts}
+	for _, c := range counts {
+		if c.Discrepancy != 0 {
+			detail.Discrepancies++
+			detail.NetAdjustment += c.Discrepancy
+		}
+	}
+	return detail, nil
+}
+
+// Complete closes an open take and adjusts the stock of every product it
+// counted by the count's discrepancy, in one transaction: either every
+// adjustment is posted or none is. Each adjustment is audited with its
+// reason code and publishes StockAdjusted with reason "stock_take". An
+// adjustment that would take stock below zero, because more was sold since
+// the product was counted than the count allows, fails the whole take with
+// repository.ErrInsufficientStock; recount the product and complete again.
+func (s *Service) Complete(ctx context.Context, id int, actor string) (*models.StockTakeDetail, error) {
+	var detail *models.StockTakeDetail
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		take, err := s.openTake(ctx, id)
+		if err != nil {
+			return err
+		}
+		if detail, err = s.detail(ctx, take); err != nil {
+			return err
+		}
+
+		var evts []events.Event
+		for _, c := range detail.Counts {
+			adjustment := c.Discrepancy
+			c.Adjustment = &adjustment
+			if err := s.repo.PostAdjustment(ctx, take.ID, c.ProductID, adjustment); err != nil {
+				return err
+			}
+			if adjustment == 0 {
+				continue
+			}
+
+			after, err := s.products.AdjustStock(ctx, c.ProductID, adjustment)
+			if err != nil {
+				if errors.Is(err, repository.ErrInsufficientStock) {
+					return fmt.Errorf("%w for %s: adjusting by %d", repository.ErrInsufficientStock, c.SKU, adjustment)
+				}
+				return err
+			}
+
+			evts = append(evts, events.New(events.StockAdjusted{
+				ProductID: after.ID,
+				SKU:       after.SKU,
+				Previous:  after.Quantity - adjustment,
+				Current:   after.Quantity,
+				Delta:     adjustment,
+				Reason:    "stock_take",
+			}))
+			details, _ := json.Marshal(map[string]interface{}{
+				"stock_take_id": take.ID,
+				"sku":           after.SKU,
+				"counted":       c.Counted,
+				"expected":      c.Expected,
+				"previous":      after.Quantity - adjustment,
+				"current":       after.Quantity,
+				"delta":         adjustment,
+				"reason":        c.Reason,
+			})
+			entry := &models.AuditEntry{
+				Action:     "stock.adjust",
+				Actor:      actor,
+				EntityType: "product",
+				EntityID:   strconv.Itoa(after.ID),
+				Details:    details,
+			}
+			if err := s.audit.Create(ctx, entry); err != nil {
+				return err
+			}
+		}
+
+		take.Status = StatusCompleted
+		if err := s.repo.Close(ctx, take); err != nil {
+			return err
+		}
+		if err := s.publisher.Publish(ctx, evts...); err != nil {
+			return err
+		}
+		if err := s.record(ctx, "stock_take.complete", actor, take.ID, map[string]interface{}{
+			"counts":         len(detail.Counts),
+			"adjusted":       detail.Discrepancies,
+			"net_adjustment": detail.NetAdjustment,
+		}); err != nil {
+			return err
+		}
+
+		// Skipped when the transaction rolls back, including dry runs
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("stock take completed",
+				"stock_take_id", take.ID,
+				"counts", len(detail.Counts),
+				"adjusted", detail.Discrepancies,
+				"net_adjustment", detail.NetAdjustment,
+			)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return detail, nil
+}
+
+// Cancel closes an open take without adjusting any stock; its counts are
+// kept for the record
+func (s *Service) Cancel(ctx context.Context, id int, actor string) (*models.StockTake, error) {
+	var take *models.StockTake
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if take, err = s.openTake(ctx, id); err != nil {
+			return err
+		}
+		counts, err := s.repo.ListCounts(ctx, take.ID)
+		if err != nil {
+			return err
+		}
+		take.Status = StatusCancelled
+		if err := s.repo.Close(ctx, take); err != nil {
+			return err
+		}
+		database.AfterCommit(ctx, func() {
+			s.logger.Info("stock take cancelled", "stock_take_id", id, "counts", len(counts))
+		})
+		return s.record(ctx, "stock_take.cancel", actor, take.ID, map[string]interface{}{"counts": len(counts)})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return take, nil
+}
+
+// openTake locks a take for the rest of the transaction, failing with
+// ErrNotOpen unless it's open
+func (s *Service) openTake(ctx context.Context, id int) (*models.StockTake, error) {
+	take, err := s.repo.GetByIDForUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if take.Status != StatusOpen {
+		return nil, fmt.Errorf("%w: it's %s", ErrNotOpen, take.Status)
+	}
+	return take, nil
+}
+
+// record audits a step of a take, in its transaction
+func (s *Service) record(ctx context.Context, action, actor string, takeID int, details map[string]interface{}) error {
+	entry := &models.AuditEntry{
+		Action:     action,
+		Actor:      actor,
+		EntityType: "stock_take",
+		EntityID:   strconv.Itoa(takeID),
+	}
+	entry.Details, _ = json.Marshal(details)
+	return s.audit.Create(ctx, entry)
+}
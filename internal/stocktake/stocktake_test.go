@@ -0,0 +1,228 @@
+package stocktake
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestReadCSV(t *testing.T) {
+	counts, err := ReadCSV(strings.NewReader("\ufeffSKU,Name,Counted,Reason\nA-1,Widget,12,\n\n,,,\nB-2,Gadget, 0 ,Damaged\n"))
+	if err != nil {
+		t.Fatalf("ReadCSV() error = %v", err)
+	}
+	if len(counts) != 2 {
+		t.Fatalf("ReadCSV() = %d counts, want 2", len(counts))
+	}
+	if c := counts[0]; c.SKU != "A-1" || c.Counted == nil || *c.Counted != 12 || c.Reason != "" {
+		t.Errorf("counts[0] = %+v", c)
+	}
+	if c := counts[1]; c.SKU != "B-2" || c.Counted == nil || *c.Counted != 0 || c.Reason != ReasonDamaged {
+		t.Errorf("counts[1] = %+v", c)
+	}
+
+	for _, tt := range []struct {
+		input string
+		want  string
+	}{
+		{"", "CSV is empty"},
+		{"sku,quantity\nA-1,3\n", "must name the sku and counted columns"},
+		{"sku,counted\nA-1,3\nB-2,three\n", "line 3: counted must be a whole number"},
+		{"sku,counted\nA-1,\"3\n", "line 2"},
+	} {
+		_, err := ReadCSV(strings.NewReader(tt.input))
+		if !errors.Is(err, ErrInvalidCount) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ReadCSV(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestService_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "stocktake.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	products := repository.NewProductRepository(db)
+	audit := repository.NewAuditRepository(db)
+	repo := repository.NewStockTakeRepository(db)
+	service := NewService(repo, products, audit, db, events.NewBus(logger), logger)
+
+	widget := &models.Product{SKU: "ST-1", Name: "Widget", Quantity: 10, UnitPrice: 1}
+	gadget := &models.Product{SKU: "ST-2", Name: "Gadget", Quantity: 5, UnitPrice: 1}
+	for _, p := range []*models.Product{widget, gadget} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	take, err := service.Open(ctx, "Aisle 4", "tester")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	n := func(i int) *int { return &i }
+	if _, err := service.Record(ctx, take.ID, []models.StockCount{{SKU: "ST-1", Counted: n(7)}, {SKU: "NOPE", Counted: n(1)}}, "tester"); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("Record(unknown sku) error = %v, want ErrInvalidCount", err)
+	}
+	if _, err := service.Record(ctx, take.ID, []models.StockCount{{SKU: "ST-1", Counted: n(7), Reason: "eaten"}}, "tester"); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("Record(bad reason) error = %v, want ErrInvalidCount", err)
+	}
+
+	// Widget counted twice, the recount replacing the first count
+	counts := []models.StockCount{{SKU: "ST-1", Counted: n(9)}, {SKU: "ST-2", Counted: n(5)}}
+	if _, err := service.Record(ctx, take.ID, counts, "tester"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if _, err := service.Record(ctx, take.ID, []models.StockCount{{SKU: "ST-1", Counted: n(7), Reason: ReasonDamaged}}, "tester"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	// Another open take can't count the widget again
+	other, err := service.Open(ctx, "", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Record(ctx, other.ID, []models.StockCount{{SKU: "ST-1", Counted: n(1)}}, "tester"); !errors.Is(err, ErrCountedElsewhere) {
+		t.Errorf("Record(counted elsewhere) error = %v, want ErrCountedElsewhere", err)
+	}
+	if _, err := service.Cancel(ctx, other.ID, "tester"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	detail, err := service.Get(ctx, take.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(detail.Counts) != 2 || detail.Discrepancies != 1 || detail.NetAdjustment != -3 {
+		t.Fatalf("Get() = %d counts, %d discrepancies, net %d; want 2, 1, -3", len(detail.Counts), detail.Discrepancies, detail.NetAdjustment)
+	}
+	if c := detail.Counts[0]; c.SKU != "ST-1" || c.Counted != 7 || c.Expected != 10 || c.Reason != ReasonDamaged || c.Adjustment != nil {
+		t.Errorf("widget count = %+v", c)
+	}
+
+	// Two widgets sold after counting stay sold: the adjustment is -3
+	if _, err := products.AdjustStock(ctx, widget.ID, -2); err != nil {
+		t.Fatal(err)
+	}
+	detail, err = service.Complete(ctx, take.ID, "tester")
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if detail.Status != StatusCompleted || detail.ClosedAt == nil || *detail.Counts[0].Adjustment != -3 {
+		t.Errorf("Complete() = %+v", detail.StockTake)
+	}
+	if p, _ := products.GetByID(ctx, widget.ID); p.Quantity != 5 {
+		t.Errorf("widget quantity = %d, want 5", p.Quantity)
+	}
+	if p, _ := products.GetByID(ctx, gadget.ID); p.Quantity != 5 {
+		t.Errorf("gadget quantity = %d, want 5", p.Quantity)
+	}
+
+	if _, err := service.Complete(ctx, take.ID, "tester"); !errors.Is(err, ErrNotOpen) {
+		t.Errorf("Complete() again error = %v, want ErrNotOpen", err)
+	}
+	if _, err := service.Get(ctx, 999); err == nil || err.Error() != "stock take not found" {
+		t.Errorf("Get(999) error = %v, want stock take not found", err)
+	}
+
+	entries, err := audit.List(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := map[string]int{}
+	for _, e := range entries {
+		actions[e.Action]++
+	}
+	if actions["stock_take.open"] != 2 || actions["stock_take.count"] != 2 || actions["stock_take.complete"] != 1 || actions["stock_take.cancel"] != 1 || actions["stock.adjust"] != 1 {
+		t.Errorf("audited actions = %v", actions)
+	}
+}
+
+func TestService_CompleteAllOrNothing_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "stocktake.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	products := repository.NewProductRepository(db)
+	repo := repository.NewStockTakeRepository(db)
+	service := NewService(repo, products, repository.NewAuditRepository(db), db, events.NewBus(logger), logger)
+
+	found := &models.Product{SKU: "AON-1", Name: "Found", Quantity: 1, UnitPrice: 1}
+	short := &models.Product{SKU: "AON-2", Name: "Short", Quantity: 4, UnitPrice: 1}
+	for _, p := range []*models.Product{found, short} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	take, err := service.Open(ctx, "", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := func(i int) *int { return &i }
+	counts := []models.StockCount{{SKU: "AON-1", Counted: n(3), Reason: ReasonFound}, {SKU: "AON-2", Counted: n(1), Reason: ReasonLost}}
+	if _, err := service.Record(ctx, take.ID, counts, "tester"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The 4 counted as 1 sold out since: -3 would go below zero
+	if _, err := products.AdjustStock(ctx, short.ID, -4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Complete(ctx, take.ID, "tester"); !errors.Is(err, repository.ErrInsufficientStock) {
+		t.Fatalf("Complete() error = %v, want ErrInsufficientStock", err)
+	}
+	if p, _ := products.GetByID(ctx, found.ID); p.Quantity != 1 {
+		t.Errorf("found quantity = %d, want 1: nothing posted", p.Quantity)
+	}
+	if got, _ := repo.GetByID(ctx, take.ID); got.Status != StatusOpen {
+		t.Errorf("status = %s, want open", got.Status)
+	}
+}
+
+// FuzzReadCSV reads arbitrary uploads: every problem must be reported as
+// ErrInvalidCount, never a panic, and every count read must name a product
+// or a count. Seeds are in testdata/fuzz/FuzzReadCSV.
+func FuzzReadCSV(f *testing.F) {
+	f.Fuzz(func(t *testing.T, input string) {
+		counts, err := ReadCSV(strings.NewReader(input))
+		if err != nil {
+			if !errors.Is(err, ErrInvalidCount) {
+				t.Fatalf("ReadCSV error %v isn't ErrInvalidCount", err)
+			}
+			return
+		}
+		for _, count := range counts {
+			if count.SKU == "" && count.Counted == nil {
+				t.Fatalf("ReadCSV returned an empty count: %+v", count)
+			}
+			if count.Reason != strings.ToLower(count.Reason) {
+				t.Fatalf("reason %q isn't lowercase", count.Reason)
+			}
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMintVerify(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	scope := Scope{ReadOnly: true, ProductID: 42}
+
+	token, claims, err := Mint(key, "ticket 4711", scope, time.Hour)
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if !IsToken(token) {
+		t.Fatalf("token %q lacks the %s prefix", token, Prefix)
+	}
+
+	got, err := Verify(key, token, time.Now())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.ID != claims.ID || got.Subject != "ticket 4711" || got.Scope != scope || !got.ExpiresAt.Equal(claims.ExpiresAt) {
+		t.Errorf("claims = %+v, want %+v", got, claims)
+	}
+
+	if _, err := Verify(key, token, claims.ExpiresAt); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify at expiry: err = %v, want ErrExpired", err)
+	}
+
+	unsigned, signature, _ := strings.Cut(token, ".")
+	forged, _, _ := Mint(key, "ticket 4711", Scope{ReadOnly: true}, time.Hour)
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	invalid := map[string]string{
+		"wrong key":       "",
+		"swapped payload": forgedPayload + "." + signature,
+		"truncated":       unsigned,
+		"bad signature":   unsigned + ".AAAA",
+		"admin token":     "change-me",
+		"missing prefix":  strings.TrimPrefix(token, Prefix),
+	}
+	for name, tok := range invalid {
+		verifyKey := key
+		if name == "wrong key" {
+			tok, verifyKey = token, []byte("another key of at least 32 bytes!")
+		}
+		if _, err := Verify(verifyKey, tok, time.Now()); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+
+	empty, _, _ := Mint(key, "nobody", Scope{}, time.Hour)
+	if _, err := Verify(key, empty, time.Now()); !errors.Is(err, ErrInvalid) {
+		t.Errorf("unscoped token: err = %v, want ErrInvalid", err)
+	}
+
+	// Tokens minted with the former tenant scope alone are unscoped now
+	payload := fmt.Sprintf(`{"jti":"1","sub":"ticket 4711","scope":{"tenant":"acme"},"exp":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	tenant := Prefix + encoding.EncodeToString([]byte(payload))
+	tenant += "." + encoding.EncodeToString(sign(key, tenant))
+	if _, err := Verify(key, tenant, time.Now()); !errors.Is(err, ErrInvalid) {
+		t.Errorf("tenant token: err = %v, want ErrInvalid", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Package units converts quantities between units of measure, using the
+// conversion table in the units table. Every unit is a whole multiple of its
+// dimension's base unit, so conversions are exact or refused.
+package units
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+var (
+	ErrUnknownUnit      = errors.New("unknown unit")
+	ErrIncompatible     = errors.New("units of different dimensions")
+	ErrFractionalAmount = errors.New("not a whole number of units")
+)
+
+// Table is the conversion table. It's read once and safe for concurrent use.
+type Table struct {
+	units map[string]models.Unit
+}
+
+// New builds a table from units, rejecting a dimension with no base unit or
+// more than one
+func New(units []*models.Unit) (*Table, error) {
+	t := &Table{units: make(map[string]models.Unit, len(units))}
+	bases := make(map[string]int)
+	for _, u := range units {
+		if u.Factor < 1 {
+			return nil, fmt.Errorf("unit %s: factor must be positive", u.Code)
+		}
+		t.units[u.Code] = *u
+		if u.Factor == 1 {
+			bases[u.Dimension]++
+		}
+	}
+	for _, u := range units {
+		if bases[u.Dimension] != 1 {
+			return nil, fmt.Errorf("dimension %s: needs exactly one unit with factor 1", u.Dimension)
+		}
+	}
+	return t, nil
+}
+
+// Load reads the table from the repository
+func Load(ctx context.Context, repo repository.UnitRepository) (*Table, error) {
+	units, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return New(units)
+}
+
+// Lookup returns a unit by code
+func (t *Table) Lookup(code string) (models.Unit, error) {
+	u, ok := t.units[code]
+	if !ok {
+		return models.Unit{}, fmt.Errorf("%w: %q", ErrUnknownUnit, code)
+	}
+	return u, nil
+}
+
+// Codes returns the known units, sorted
+func (t *Table) Codes() []string {
+	codes := make([]string, 0, len(t.units))
+	for code := range t.units {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Convert returns amount of from in units of to. It fails for units of
+// different dimensions, and for an amount that isn't a whole number of to,
+// e.g. 5 each in box-of-12.
+func (t *Table) Convert(amount int, from, to string) (int, error) {
+	src, err := t.Lookup(from)
+	if err != nil {
+		return 0, err
+	}
+	dst, err := t.Lookup(to)
+	if err != nil {
+		return 0, err
+	}
+	if src.Dimension != dst.Dimension {
+		return 0, fmt.Errorf("%w: %s is %s, %s is %s", ErrIncompatible, from, src.Dimension, to, dst.Dimension)
+	}
+
+	base := amount * src.Factor
+	if base%dst.Factor != 0 {
+		return 0, fmt.Errorf("%w: %d %s is %d/%d %s", ErrFractionalAmount, amount, from, base, dst.Factor, to)
+	}
+	return base / dst.Factor, nil
+}
@@ -0,0 +1,61 @@
+package units
+
+import (
+	"errors"
+	"testing"
+
+	"{{MODULE_NAME}}/internal/models"
+)
+
+var seeded = []*models.Unit{
+	{Code: "each", Dimension: "count", Factor: 1},
+	{Code: "box-of-12", Dimension: "count", Factor: 12},
+	{Code: "g", Dimension: "mass", Factor: 1},
+	{Code: "kg", Dimension: "mass", Factor: 1000},
+	{Code: "ml", Dimension: "volume", Factor: 1},
+	{Code: "liter", Dimension: "volume", Factor: 1000},
+}
+
+func TestConvert(t *testing.T) {
+	table, err := New(seeded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		amount   int
+		from, to string
+		want     int
+		wantErr  error
+	}{
+		{3, "box-of-12", "each", 36, nil},
+		{24, "each", "box-of-12", 2, nil},
+		{5, "each", "box-of-12", 0, ErrFractionalAmount},
+		{2, "kg", "g", 2000, nil},
+		{1500, "g", "kg", 0, ErrFractionalAmount},
+		{7, "liter", "liter", 7, nil},
+		{1, "kg", "liter", 0, ErrIncompatible},
+		{1, "each", "kg", 0, ErrIncompatible},
+		{1, "bushel", "each", 0, ErrUnknownUnit},
+		{1, "each", "", 0, ErrUnknownUnit},
+	}
+
+	for _, tt := range tests {
+		got, err := table.Convert(tt.amount, tt.from, tt.to)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("Convert(%d, %s, %s) = %d, %v, want %d, %v", tt.amount, tt.from, tt.to, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNew_BaseUnits(t *testing.T) {
+	for name, units := range map[string][]*models.Unit{
+		"no base unit":   {{Code: "box-of-12", Dimension: "count", Factor: 12}},
+		"two base units": {{Code: "each", Dimension: "count", Factor: 1}, {Code: "piece", Dimension: "count", Factor: 1}},
+		"zero factor":    {{Code: "each", Dimension: "count", Factor: 0}},
+	} {
+		if _, err := New(units); err == nil {
+			t.Errorf("%s: New succeeded", name)
+		}
+	}
+}
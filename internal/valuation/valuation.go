@@ -0,0 +1,266 @@
+// Package valuation computes what inventory cost from the receipts it came
+// in with, instead of pricing it at what it sells for.
+//
+// Each receipt is a cost lot. FIFO assumes the oldest units leave first, so
+// the units on hand are those of the most recent lots, at their costs.
+// Weighted average values every unit on hand at the average cost of all
+// units received. Units on hand beyond what receipts account for, such as
+// stock counted before receipts were recorded, have no known cost: they're
+// reported as uncosted and left out of the value.
+package valuation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// Valuation methods
+const (
+	FIFO            = "fifo"
+	WeightedAverage = "weighted_average"
+)
+
+// Methods are the valuation methods, as computed by the valuation job
+var Methods = []string{FIFO, WeightedAverage}
+
+// ErrInvalidReceipt is returned by Receive for receipts that can't be
+// recorded
+var ErrInvalidReceipt = errors.New("invalid receipt")
+
+var (
+	inventoryValue = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "inventory_valuation_value",
+		Help: "Cost of stock on hand at the last valuation, by method.",
+	}, []string{"method"})
+	uncostedUnits = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "inventory_valuation_uncosted_units",
+		Help: "Units on hand no stock receipt accounts for at the last valuation.",
+	})
+)
+
+// ValidMethod reports whether method is one of Methods
+func ValidMethod(method string) bool {
+	for _, m := range Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Value values onHand units of a product under method, from its receipts,
+// most recently received first. It returns the value, rounded to cents,
+// and how many of the units it could value.
+func Value(method string, onHand int, receipts []*models.StockReceipt) (float64, int) {
+	if onHand <= 0 {
+		return 0, 0
+	}
+
+	var value float64
+	valued := 0
+	switch method {
+	case WeightedAverage:
+		received, cost := 0, 0.0
+		for _, r := range receipts {
+			received += r.Quantity
+			cost += float64(r.Quantity) * r.UnitCost
+		}
+		if received > 0 {
+			valued = min(onHand, received)
+			value = float64(valued) * cost / float64(received)
+		}
+	default:
+		for _, r := range receipts {
+			if valued == onHand {
+				break
+			}
+			n := min(onHand-valued, r.Quantity)
+			valued += n
+			value += float64(n) * r.UnitCost
+		}
+	}
+	return math.Round(value*100) / 100, valued
+}
+
+// Service records stock receipts and values inventory from them
+type Service struct {
+	repo      repository.ValuationRepository
+	products  repository.ProductRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	method    string
+	logger    *slog.Logger
+}
+
+// NewService creates the valuation service. method is the one used when a
+// caller names none, FIFO if it's "".
+func NewService(repo repository.ValuationRepository, products repository.ProductRepository, tx repository.Transactor, publisher events.Publisher, method string, logger *slog.Logger) *Service {
+	if method == "" {
+		method = FIFO
+	}
+	return &Service{
+		repo:      repo,
+		products:  products,
+		tx:        tx,
+		publisher: publisher,
+		method:    method,
+		logger:    logger,
+	}
+}
+
+// Method returns the default valuation method
+func (s *Service) Method() string {
+	return s.method
+}
+
+// Receive adds a receipt's units to its product's stock and records it as a
+// cost lot, in one transaction, publishing StockAdjusted with reason
+// "receipt". It returns the product as updated.
+func (s *Service) Receive(ctx context.Context, receipt *models.StockReceipt) (*models.Product, error) {
+	if receipt.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidReceipt)
+	}
+	if receipt.UnitCost < 0 || math.IsNaN(receipt.UnitCost) || math.IsInf(receipt.UnitCost, 0) {
+		return nil, fmt.Errorf("%w: unit_cost must not be negative", ErrInvalidReceipt)
+	}
+	if receipt.ReceivedAt.After(time.Now().Add(time.Minute)) { // Allowing for clock skew
+		return nil, fmt.Errorf("%w: received_at must not be in the future", ErrInvalidReceipt)
+	}
+
+	var product *models.Product
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if product, err = s.products.AdjustStock(ctx, receipt.ProductID, receipt.Quantity); err != nil {
+			return err
+		}
+		if err := s.repo.CreateReceipt(ctx, receipt); err != nil {
+			return err
+		}
+		return s.publisher.Publish(ctx, events.New(events.StockAdjusted{
+			ProductID: product.ID,
+			SKU:       product.SKU,
+			Previous:  product.Quantity - receipt.Quantity,
+			Current:   product.Quantity,
+			Delta:     receipt.Quantity,
+			Reason:    "receipt",
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// Product values a product's stock on hand now
+func (s *Service) Product(ctx context.Context, productID int, method string) (*models.Valuation, error) {
+	product, err := s.products.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := s.receipts(ctx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	value, valued := Value(method, product.Quantity, receipts)
+	return &models.Valuation{
+		Method:           method,
+		Value:            value,
+		Quantity:         valued,
+		UncostedQuantity: max(product.Quantity, 0) - valued,
+		ValuedAt:         time.Now(),
+	}, nil
+}
+
+// receipts returns every receipt of a product, most recent first
+func (s *Service) receipts(ctx context.Context, productID int) ([]*models.StockReceipt, error) {
+	const page = 1000
+	var receipts []*models.StockReceipt
+	for {
+		batch, err := s.repo.ListReceipts(ctx, productID, page, len(receipts))
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, batch...)
+		if len(batch) < page {
+			return receipts, nil
+		}
+	}
+}
+
+// Inventory values the stock on hand of every product under each of
+// methods, from one snapshot
+func (s *Service) Inventory(ctx context.Context, methods ...string) ([]*models.Valuation, error) {
+	valuations := make([]*models.Valuation, len(methods))
+	for i, method := range methods {
+		valuations[i] = &models.Valuation{Method: method}
+	}
+
+	valuedAt, err := s.repo.EachStock(ctx, func(productID, quantity int, receipts []*models.StockReceipt) error {
+		for _, v := range valuations {
+			value, valued := Value(v.Method, quantity, receipts)
+			v.Value += value
+			v.Quantity += valued
+			v.UncostedQuantity += quantity - valued
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range valuations {
+		v.Value = math.Round(v.Value*100) / 100
+		v.ValuedAt = valuedAt
+	}
+	return valuations, nil
+}
+
+// Latest returns the last valuation recorded with method, or values the
+// inventory now when none has been
+func (s *Service) Latest(ctx context.Context, method string) (*models.Valuation, error) {
+	valuation, err := s.repo.LatestValuation(ctx, method)
+	if err != nil || valuation != nil {
+		return valuation, err
+	}
+
+	valuations, err := s.Inventory(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	return valuations[0], nil
+}
+
+// Run values the inventory under every method and records the valuations,
+// for use as a scheduled job
+func (s *Service) Run(ctx context.Context) error {
+	valuations, err := s.Inventory(ctx, Methods...)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range valuations {
+		if err := s.repo.RecordValuation(ctx, v); err != nil {
+			return err
+		}
+		inventoryValue.WithLabelValues(v.Method).Set(v.Value)
+	}
+	uncostedUnits.Set(float64(valuations[0].UncostedQuantity))
+
+	attrs := []interface{}{"valued_at", valuations[0].ValuedAt, "uncosted_quantity", valuations[0].UncostedQuantity}
+	for _, v := range valuations {
+		attrs = append(attrs, v.Method, v.Value)
+	}
+	s.logger.Info("inventory valued", attrs...)
+	return nil
+}
@@ -0,0 +1,145 @@
+package valuation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestValue(t *testing.T) {
+	// Most recent first: 10 at 3.00, then 10 at 2.00, then 5 at 1.00
+	lots := []*models.StockReceipt{
+		{Quantity: 10, UnitCost: 3},
+		{Quantity: 10, UnitCost: 2},
+		{Quantity: 5, UnitCost: 1},
+	}
+	for _, tt := range []struct {
+		method string
+		onHand int
+		value  float64
+		valued int
+	}{
+		{FIFO, 0, 0, 0},
+		{FIFO, 4, 12, 4},
+		{FIFO, 15, 40, 15},
+		{FIFO, 25, 55, 25},
+		{FIFO, 30, 55, 25}, // 5 uncosted
+		{WeightedAverage, 15, 33, 15},
+		{WeightedAverage, 30, 55, 25},
+	} {
+		value, valued := Value(tt.method, tt.onHand, lots)
+		if value != tt.value || valued != tt.valued {
+			t.Errorf("Value(%s, %d) = %v, %d; want %v, %d", tt.method, tt.onHand, value, valued, tt.value, tt.valued)
+		}
+	}
+
+	if value, valued := Value(WeightedAverage, 3, nil); value != 0 || valued != 0 {
+		t.Errorf("Value without receipts = %v, %d; want nothing valued", value, valued)
+	}
+	// Rounded to cents
+	if value, _ := Value(FIFO, 3, []*models.StockReceipt{{Quantity: 3, UnitCost: 0.3333}}); value != 1 {
+		t.Errorf("Value = %v, want 1", value)
+	}
+}
+
+func TestService_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "valuation.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	products := repository.NewProductRepository(db)
+	repo := repository.NewValuationRepository(db)
+	service := NewService(repo, products, db, events.NewBus(logger), FIFO, logger)
+
+	// 5 units counted before receipts were recorded
+	product := &models.Product{SKU: "VAL-1", Name: "Valued", Quantity: 5, UnitPrice: 9.99}
+	if err := products.Create(ctx, product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	other := &models.Product{SKU: "VAL-2", Name: "Out of stock", UnitPrice: 1}
+	if err := products.Create(ctx, other); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	now := time.Now()
+	for _, r := range []*models.StockReceipt{
+		{ProductID: product.ID, Quantity: 10, UnitCost: 2, Reference: "PO-1", ReceivedAt: now.Add(-2 * time.Hour)},
+		{ProductID: product.ID, Quantity: 10, UnitCost: 4, Reference: "PO-2", ReceivedAt: now.Add(-time.Hour)},
+	} {
+		if _, err := service.Receive(ctx, r); err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+	}
+	if _, err := service.Receive(ctx, &models.StockReceipt{ProductID: product.ID, Quantity: 0, UnitCost: 1}); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("Receive(0 units) error = %v, want ErrInvalidReceipt", err)
+	}
+	if _, err := service.Receive(ctx, &models.StockReceipt{ProductID: 999, Quantity: 1}); err == nil || err.Error() != "product not found" {
+		t.Errorf("Receive(unknown product) error = %v, want product not found", err)
+	}
+
+	// 12 of the 25 sold: under FIFO the 13 left are the newer lot and 3 of
+	// the older one
+	if _, err := products.AdjustStock(ctx, product.ID, -12); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := service.Product(ctx, product.ID, FIFO)
+	if err != nil {
+		t.Fatalf("Product() error = %v", err)
+	}
+	if got.Value != 46 || got.Quantity != 13 || got.UncostedQuantity != 0 {
+		t.Errorf("FIFO valuation = %+v, want 46 for 13 units", got)
+	}
+	got, err = service.Product(ctx, product.ID, WeightedAverage)
+	if err != nil || got.Value != 39 || got.Quantity != 13 {
+		t.Errorf("weighted average valuation = %+v, %v; want 39 for 13 units", got, err)
+	}
+
+	receipts, err := repo.ListReceipts(ctx, product.ID, 10, 0)
+	if err != nil || len(receipts) != 2 || receipts[0].Reference != "PO-2" {
+		t.Errorf("ListReceipts = %v, %v; want PO-2 first", receipts, err)
+	}
+
+	// Before the job has run, the summary is computed live
+	latest, err := service.Latest(ctx, WeightedAverage)
+	if err != nil || latest.Value != 39 {
+		t.Errorf("Latest() = %+v, %v; want 39", latest, err)
+	}
+	if err := service.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if _, err := products.AdjustStock(ctx, product.ID, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recorded valuations stay as they were
+	latest, err = service.Latest(ctx, FIFO)
+	if err != nil || latest.Value != 46 || latest.Quantity != 13 || latest.ValuedAt.IsZero() {
+		t.Errorf("Latest() = %+v, %v; want the recorded 46", latest, err)
+	}
+
+	// Units beyond the receipts are uncosted
+	valuations, err := service.Inventory(ctx, Methods...)
+	if err != nil {
+		t.Fatalf("Inventory() error = %v", err)
+	}
+	if v := valuations[0]; v.Method != FIFO || v.Value != 60 || v.Quantity != 20 || v.UncostedQuantity != 3 {
+		t.Errorf("FIFO inventory = %+v, want 60 for 20 units and 3 uncosted", v)
+	}
+}
@@ -0,0 +1,114 @@
+// Package visibility announces products entering and leaving their
+// visibility windows.
+//
+// Public lists and searches on Postgres apply a product's window as of each
+// request, so nothing needs to happen for it to show or hide. Others can't
+// tell: external search indexes, the response cache, and subscribers only
+// learn of products through events, and a window opening or closing isn't a
+// write. Run finds the products whose visibility no longer matches the one
+// last announced, and publishes product.visibility_changed for each,
+// recording the announcement in the same transaction.
+package visibility
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+// batchSize is the number of products announced per transaction
+const batchSize = 100
+
+var changes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "product_visibility_changes_total",
+	Help: "Products announced as entering (visible) or leaving (hidden) their visibility window.",
+}, []string{"visibility"})
+
+// Service announces visibility changes
+type Service struct {
+	repo      repository.ProductVisibilityRepository
+	products  repository.ProductRepository
+	tx        repository.Transactor
+	publisher events.Publisher
+	logger    *slog.Logger
+}
+
+func NewService(repo repository.ProductVisibilityRepository, products repository.ProductRepository, tx repository.Transactor, publisher events.Publisher, logger *slog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		products:  products,
+		tx:        tx,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Run publishes ProductVisibilityChanged for every product whose visibility
+// changed since it was last announced, for use as a scheduled job
+func (s *Service) Run(ctx context.Context) error {
+	announced := 0
+	for {
+		now := time.Now()
+		var ids []int
+		err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+			var err error
+			if ids, err = s.repo.Pending(ctx, now, batchSize); err != nil {
+				return err
+			}
+
+			evts := make([]events.Event, 0, len(ids))
+			for _, id := range ids {
+				product, err := s.products.GetByIDForUpdate(ctx, id)
+				if err != nil {
+					return err
+				}
+				// Its window may have been changed back since
+				visible := product.VisibleAt(now)
+				if visible == product.Listed {
+					continue
+				}
+				if err := s.repo.SetListed(ctx, id, visible); err != nil {
+					return err
+				}
+				product.Listed = visible
+				evts = append(evts, events.New(events.ProductVisibilityChanged{Product: *product, Visible: visible}))
+			}
+			if err := s.publisher.Publish(ctx, evts...); err != nil {
+				return err
+			}
+
+			database.AfterCommit(ctx, func() {
+				for _, evt := range evts {
+					e := evt.Payload.(events.ProductVisibilityChanged)
+					changes.WithLabelValues(visibilityLabel(e.Visible)).Inc()
+					s.logger.Info("product visibility changed", "product_id", e.Product.ID, "sku", e.Product.SKU, "visible", e.Visible)
+				}
+			})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		announced += len(ids)
+		if len(ids) < batchSize {
+			if announced > 0 {
+				s.logger.Debug("product visibility changes announced", "products", announced)
+			}
+			return nil
+		}
+	}
+}
+
+func visibilityLabel(visible bool) string {
+	if visible {
+		return "visible"
+	}
+	return "hidden"
+}
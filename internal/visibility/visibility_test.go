@@ -0,0 +1,79 @@
+package visibility
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/events"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func TestService_SQLite(t *testing.T) {
+	db, err := database.NewConnection(database.Config{URL: filepath.Join(t.TempDir(), "visibility.db"), Driver: "sqlite"})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	products := repository.NewProductRepository(db)
+	bus := events.NewBus(logger)
+	var changed []events.ProductVisibilityChanged
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		changed = append(changed, event.Payload.(events.ProductVisibilityChanged))
+		return nil
+	}, events.TypeProductVisibilityChanged)
+	service := NewService(repository.NewProductVisibilityRepository(db), products, db, bus, logger)
+
+	soon := time.Now().Add(time.Hour)
+	always := &models.Product{SKU: "VIS-1", Name: "Always", UnitPrice: 1}
+	upcoming := &models.Product{SKU: "VIS-2", Name: "Upcoming", UnitPrice: 1, VisibleFrom: &soon}
+	for _, p := range []*models.Product{always, upcoming} {
+		if err := products.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+	if err := service.Run(ctx); err != nil || len(changed) != 0 {
+		t.Fatalf("Run() = %v, announced %d changes, want none", err, len(changed))
+	}
+
+	// Opening its window is announced once
+	past := time.Now().Add(-time.Minute)
+	upcoming.VisibleFrom = &past
+	if err := products.Update(ctx, upcoming); err != nil {
+		t.Fatalf("failed to update product: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := service.Run(ctx); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+	if len(changed) != 1 || changed[0].Product.ID != upcoming.ID || !changed[0].Visible || !changed[0].Product.Listed {
+		t.Fatalf("announced %+v, want %s visible", changed, upcoming.SKU)
+	}
+
+	// And so is closing it
+	always.VisibleUntil = &past
+	if err := products.Update(ctx, always); err != nil {
+		t.Fatalf("failed to update product: %v", err)
+	}
+	if err := service.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(changed) != 2 || changed[1].Product.ID != always.ID || changed[1].Visible {
+		t.Errorf("announced %+v, want %s hidden", changed, always.SKU)
+	}
+	if p, _ := products.GetByID(ctx, always.ID); p.Listed {
+		t.Errorf("Listed = true after the window closed")
+	}
+}
@@ -0,0 +1,384 @@
+// Package workflow runs sagas: workflows whose steps change several systems,
+// such as order fulfillment reserving stock, pushing it to a sales channel,
+// and notifying subscribers, which no transaction spans. Steps run in order;
+// when one fails, the steps done before it are undone by their
+// compensations, last first.
+//
+// A saga's state is recorded in the sagas table after every step. The
+// instance running a saga claims it, renewing the claim while it runs; a
+// saga whose instance crashed is resumed where it stopped, by whichever
+// instance's resume job claims it once the claim has lapsed. The step a
+// crash interrupted runs again, as does a compensation, so both must be
+// idempotent.
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+var (
+	sagasFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sagas_total",
+		Help: "Sagas finished, by name and status (completed, compensated).",
+	}, []string{"name", "status"})
+	compensationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "saga_compensation_failures_total",
+		Help: "Compensations that failed, retried when the saga is resumed, by saga name and step.",
+	}, []string{"name", "step"})
+)
+
+var (
+	// ErrRunning is returned by Start for a saga running elsewhere
+	ErrRunning = errors.New("saga is already running")
+	// ErrCompensated wraps the error of the step that failed a saga, whose
+	// steps before it were undone
+	ErrCompensated = errors.New("saga compensated")
+	// ErrUnknown is returned by Start for a name no definition is registered
+	// under
+	ErrUnknown = errors.New("unknown saga")
+)
+
+const (
+	// claimDuration is how long a claim on a saga lasts unless renewed, and
+	// so how long a crashed instance's sagas wait to be resumed
+	claimDuration = time.Minute
+	// resumeBatch is how many interrupted sagas one resume run takes on
+	resumeBatch = 100
+)
+
+// Step is one step of a saga
+type Step struct {
+	Name string
+	// Do makes the step's change, recording in s what later steps and its
+	// compensation need
+	Do func(ctx context.Context, s *Saga) error
+	// Compensate undoes Do's change after a later step failed. Nil for
+	// steps with nothing to undo, such as notifications, which are best
+	// made last.
+	Compensate func(ctx context.Context, s *Saga) error
+}
+
+// Definition is a saga's steps, registered under its name
+type Definition struct {
+	Name  string
+	Steps []Step
+}
+
+// Saga is a running saga, through which steps read its input and what
+// earlier steps recorded, and record what they did
+type Saga struct {
+	state *models.Saga
+	data  map[string]json.RawMessage
+}
+
+func newSaga(state *models.Saga) (*Saga, error) {
+	s := &Saga{state: state, data: make(map[string]json.RawMessage)}
+	if len(state.Data) > 0 {
+		if err := json.Unmarshal(state.Data, &s.data); err != nil {
+			return nil, fmt.Errorf("failed to decode data of saga %d: %w", state.ID, err)
+		}
+	}
+	return s, nil
+}
+
+// ID returns the saga's ID
+func (s *Saga) ID() int {
+	return s.state.ID
+}
+
+// Key returns the key the saga was started with, such as an order's
+func (s *Saga) Key() string {
+	return s.state.Key
+}
+
+// Input decodes the input the saga was started with into v
+func (s *Saga) Input(v interface{}) error {
+	_, err := s.Get("input", v)
+	return err
+}
+
+// Get decodes what was recorded under key into v, and reports false when
+// nothing was
+func (s *Saga) Get(key string, v interface{}) (bool, error) {
+	raw, ok := s.data[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("failed to decode saga data %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// Set records v under key, saved with the step
+func (s *Saga) Set(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode saga data %s: %w", key, err)
+	}
+	s.data[key] = raw
+	return nil
+}
+
+// Coordinator starts sagas and resumes interrupted ones
+type Coordinator struct {
+	repo          repository.SagaRepository
+	logger        *slog.Logger
+	owner         string // Claims this instance's sagas
+	claimDuration time.Duration
+
+	mu          sync.RWMutex
+	definitions map[string]Definition
+}
+
+func NewCoordinator(repo repository.SagaRepository, logger *slog.Logger) *Coordinator {
+	return &Coordinator{
+		repo:          repo,
+		logger:        logger,
+		owner:         uuid.NewString(),
+		claimDuration: claimDuration,
+		definitions:   make(map[string]Definition),
+	}
+}
+
+// Register makes sagas of def's name startable and resumable here. Every
+// instance should register the same definitions, before its resume job
+// first runs.
+func (c *Coordinator) Register(def Definition) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.definitions[def.Name] = def
+}
+
+func (c *Coordinator) definition(name string) (Definition, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	def, ok := c.definitions[name]
+	return def, ok
+}
+
+// Start runs the saga name for key, such as an order's ID, with input, to
+// its end: completed, or compensated after a step failed, which returns
+// ErrCompensated wrapping the step's error. A saga of name and key is run
+// once: starting it again returns it as it is once it has finished, with
+// ErrRunning while another instance runs it, and resumes it otherwise. A
+// saga ctx interrupts is left to be resumed.
+func (c *Coordinator) Start(ctx context.Context, name, key string, input interface{}) (*models.Saga, error) {
+	def, ok := c.definition(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+	data, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode saga input: %w", err)
+	}
+
+	until := time.Now().Add(c.claimDuration)
+	saga := &models.Saga{
+		Name:         name,
+		Key:          key,
+		Status:       models.SagaRunning,
+		Data:         data,
+		ClaimedBy:    c.owner,
+		ClaimedUntil: &until,
+	}
+	created, err := c.repo.Create(ctx, saga)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		saga, err = c.repo.GetByKey(ctx, name, key)
+		if err != nil {
+			return nil, err
+		}
+		if saga.Done() {
+			return saga, nil
+		}
+		claimed, err := c.repo.Claim(ctx, saga.ID, c.owner, until)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return saga, ErrRunning
+		}
+		saga.ClaimedBy, saga.ClaimedUntil = c.owner, &until
+	}
+
+	return saga, c.run(ctx, def, saga)
+}
+
+// Resume claims sagas whose instance stopped running them, by crashing or
+// shutting down, and runs them to their end. It's the saga-resume job.
+func (c *Coordinator) Resume(ctx context.Context) error {
+	sagas, err := c.repo.ListInterrupted(ctx, resumeBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, saga := range sagas {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		def, ok := c.definition(saga.Name)
+		if !ok {
+			c.logger.Warn("not resuming saga without a definition", "saga", saga.ID, "name", saga.Name)
+			continue
+		}
+
+		until := time.Now().Add(c.claimDuration)
+		claimed, err := c.repo.Claim(ctx, saga.ID, c.owner, until)
+		if err != nil {
+			return err
+		}
+		if !claimed { // By another instance since it was listed
+			continue
+		}
+		saga.ClaimedBy, saga.ClaimedUntil = c.owner, &until
+
+		c.logger.Info("resuming saga", "saga", saga.ID, "name", saga.Name, "key", saga.Key, "status", saga.Status, "step", saga.Step)
+		if err := c.run(ctx, def, saga); err != nil && !errors.Is(err, ErrCompensated) {
+			c.logger.Error("failed to resume saga", "saga", saga.ID, "name", saga.Name, "error", err)
+		}
+	}
+	return nil
+}
+
+// run runs the claimed saga from where it is, renewing the claim until it
+// returns, and gives the claim up when the saga doesn't finish
+func (c *Coordinator) run(ctx context.Context, def Definition, saga *models.Saga) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go c.renew(ctx, saga.ID, cancel)
+
+	err := c.execute(ctx, def, saga)
+	if !saga.Done() {
+		if err := c.repo.Release(context.WithoutCancel(ctx), saga); err != nil {
+			c.logger.Warn("failed to release saga", "saga", saga.ID, "error", err)
+		}
+	}
+	return err
+}
+
+// errClaimLost is the cause of the context of a saga whose claim was taken
+// over, after this instance failed to renew it in time
+var errClaimLost = errors.New("saga claimed elsewhere")
+
+// renew renews the claim on the saga id until ctx ends, and cancels the
+// saga if it's lost
+func (c *Coordinator) renew(ctx context.Context, id int, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(c.claimDuration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			claimed, err := c.repo.Claim(ctx, id, c.owner, time.Now().Add(c.claimDuration))
+			if err != nil {
+				if ctx.Err() == nil {
+					c.logger.Warn("failed to renew saga claim", "saga", id, "error", err)
+				}
+				continue
+			}
+			if !claimed {
+				cancel(errClaimLost)
+				return
+			}
+		}
+	}
+}
+
+// execute runs the saga's remaining steps, or its remaining compensations
+func (c *Coordinator) execute(ctx context.Context, def Definition, state *models.Saga) error {
+	s, err := newSaga(state)
+	if err != nil {
+		return err
+	}
+	logger := c.logger.With("saga", state.ID, "name", state.Name, "key", state.Key)
+	// Recorded even when ctx ended after the step's change was made
+	save := func() error {
+		data, err := json.Marshal(s.data)
+		if err != nil {
+			return fmt.Errorf("failed to encode saga data: %w", err)
+		}
+		state.Data = data
+		return c.repo.Save(context.WithoutCancel(ctx), state)
+	}
+
+	for state.Status == models.SagaRunning {
+		if state.Step >= len(def.Steps) {
+			state.Status = models.SagaCompleted
+			if err := save(); err != nil {
+				return err
+			}
+			break
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("saga interrupted: %w", context.Cause(ctx))
+		}
+
+		step := def.Steps[state.Step]
+		if err := step.Do(ctx, s); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("saga interrupted at step %s: %w", step.Name, context.Cause(ctx))
+			}
+			logger.Warn("saga step failed, compensating", "step", step.Name, "error", err)
+			msg := fmt.Sprintf("%s: %v", step.Name, err)
+			state.Status, state.Error = models.SagaCompensating, &msg
+		} else {
+			state.Step++
+		}
+		if err := save(); err != nil {
+			return err
+		}
+	}
+
+	// While compensating, step counts the steps done and not yet undone
+	for state.Status == models.SagaCompensating {
+		if state.Step == 0 {
+			state.Status = models.SagaCompensated
+			if err := save(); err != nil {
+				return err
+			}
+			break
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("saga interrupted: %w", context.Cause(ctx))
+		}
+
+		step := def.Steps[state.Step-1]
+		if step.Compensate != nil {
+			if err := step.Compensate(ctx, s); err != nil {
+				if ctx.Err() == nil {
+					compensationFailures.WithLabelValues(state.Name, step.Name).Inc()
+					logger.Error("saga compensation failed, retried when resumed", "step", step.Name, "error", err)
+				}
+				return fmt.Errorf("failed to compensate saga step %s: %w", step.Name, err)
+			}
+		}
+		state.Step--
+		if err := save(); err != nil {
+			return err
+		}
+	}
+
+	sagasFinished.WithLabelValues(state.Name, state.Status).Inc()
+	if state.Status == models.SagaCompensated {
+		logger.Info("saga compensated", "error", *state.Error)
+		return fmt.Errorf("%w: %s", ErrCompensated, *state.Error)
+	}
+	logger.Info("saga completed")
+	return nil
+}
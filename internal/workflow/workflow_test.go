@@ -0,0 +1,279 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"{{MODULE_NAME}}/internal/database"
+	"{{MODULE_NAME}}/internal/models"
+	"{{MODULE_NAME}}/internal/repository"
+)
+
+func setupSQLiteDB(t *testing.T) *database.DB {
+	t.Helper()
+
+	db, err := database.NewConnection(database.Config{
+		URL:    filepath.Join(t.TempDir(), "workflow.db"),
+		Driver: "sqlite",
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.RunMigrations(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return db
+}
+
+func newTestCoordinator(repo repository.SagaRepository) *Coordinator {
+	return NewCoordinator(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// journal records what steps and compensations did, in order
+type journal struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (j *journal) add(entry string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, entry)
+}
+
+func (j *journal) list() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]string(nil), j.entries...)
+}
+
+type order struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+// fulfillment returns a saga definition reserving stock, pushing it to a
+// channel, and notifying, where push fails with pushErr
+func fulfillment(j *journal, pushErr func(ctx context.Context) error) Definition {
+	return Definition{
+		Name: "order-fulfillment",
+		Steps: []Step{
+			{
+				Name: "reserve",
+				Do: func(ctx context.Context, s *Saga) error {
+					var o order
+					if err := s.Input(&o); err != nil {
+						return err
+					}
+					j.add("reserve " + o.SKU)
+					return s.Set("reservation", "r-"+o.SKU)
+				},
+				Compensate: func(ctx context.Context, s *Saga) error {
+					var reservation string
+					if _, err := s.Get("reservation", &reservation); err != nil {
+						return err
+					}
+					j.add("release " + reservation)
+					return nil
+				},
+			},
+			{
+				Name: "push",
+				Do: func(ctx context.Context, s *Saga) error {
+					j.add("push")
+					return pushErr(ctx)
+				},
+				Compensate: func(ctx context.Context, s *Saga) error {
+					j.add("unpush")
+					return nil
+				},
+			},
+			{
+				Name: "notify",
+				Do: func(ctx context.Context, s *Saga) error {
+					j.add("notify")
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func succeed(ctx context.Context) error { return nil }
+
+func TestStart_Completes(t *testing.T) {
+	repo := repository.NewSagaRepository(setupSQLiteDB(t))
+	c := newTestCoordinator(repo)
+	j := &journal{}
+	c.Register(fulfillment(j, succeed))
+
+	saga, err := c.Start(context.Background(), "order-fulfillment", "1001", order{SKU: "A-1", Quantity: 2})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if want := []string{"reserve A-1", "push", "notify"}; !reflect.DeepEqual(j.list(), want) {
+		t.Errorf("journal = %q, want %q", j.list(), want)
+	}
+	got, err := repo.GetByID(context.Background(), saga.ID)
+	if err != nil || got.Status != models.SagaCompleted || got.Step != 3 || got.FinishedAt == nil || got.Error != nil {
+		t.Fatalf("saga = %+v, %v; want completed", got, err)
+	}
+	s, err := newSaga(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reservation string
+	if ok, err := s.Get("reservation", &reservation); err != nil || !ok || reservation != "r-A-1" {
+		t.Errorf("reservation = %q, %v, %v; want recorded", reservation, ok, err)
+	}
+
+	// Run once per key
+	again, err := c.Start(context.Background(), "order-fulfillment", "1001", order{SKU: "A-1", Quantity: 2})
+	if err != nil || again.ID != saga.ID || again.Status != models.SagaCompleted {
+		t.Errorf("Start again = %+v, %v; want the completed saga", again, err)
+	}
+	if len(j.list()) != 3 {
+		t.Errorf("journal = %q, want the steps run once", j.list())
+	}
+
+	if _, err := c.Start(context.Background(), "order-cancellation", "1001", nil); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Start of an unregistered saga = %v, want ErrUnknown", err)
+	}
+}
+
+func TestStart_Compensates(t *testing.T) {
+	repo := repository.NewSagaRepository(setupSQLiteDB(t))
+	c := newTestCoordinator(repo)
+	j := &journal{}
+	c.Register(fulfillment(j, func(ctx context.Context) error { return errors.New("connector unavailable") }))
+
+	saga, err := c.Start(context.Background(), "order-fulfillment", "1001", order{SKU: "A-1", Quantity: 2})
+	if !errors.Is(err, ErrCompensated) || err.Error() != "saga compensated: push: connector unavailable" {
+		t.Fatalf("Start = %v, want compensated by the push", err)
+	}
+	// The failed push isn't undone, the reservation before it is
+	if want := []string{"reserve A-1", "push", "release r-A-1"}; !reflect.DeepEqual(j.list(), want) {
+		t.Errorf("journal = %q, want %q", j.list(), want)
+	}
+	got, err := repo.GetByID(context.Background(), saga.ID)
+	if err != nil || got.Status != models.SagaCompensated || got.Step != 0 || got.FinishedAt == nil || got.Error == nil || *got.Error != "push: connector unavailable" {
+		t.Errorf("saga = %+v, %v; want compensated with the push's error", got, err)
+	}
+}
+
+func TestResume_AfterInterruption(t *testing.T) {
+	repo := repository.NewSagaRepository(setupSQLiteDB(t))
+	j := &journal{}
+	ctx, shutdown := context.WithCancel(context.Background())
+	pushes := 0
+	push := func(ctx context.Context) error {
+		pushes++
+		if pushes == 1 { // Shut down mid-push
+			shutdown()
+			return ctx.Err()
+		}
+		return nil
+	}
+	stopped := newTestCoordinator(repo)
+	stopped.Register(fulfillment(j, push))
+
+	saga, err := stopped.Start(ctx, "order-fulfillment", "1001", order{SKU: "A-1", Quantity: 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Start = %v, want interrupted", err)
+	}
+	got, err := repo.GetByID(context.Background(), saga.ID)
+	if err != nil || got.Status != models.SagaRunning || got.Step != 1 || got.ClaimedUntil != nil {
+		t.Fatalf("saga = %+v, %v; want running at the push, released", got, err)
+	}
+
+	// Another instance resumes it at the push, without compensating
+	resumer := newTestCoordinator(repo)
+	resumer.Register(fulfillment(j, push))
+	if err := resumer.Resume(context.Background()); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if want := []string{"reserve A-1", "push", "push", "notify"}; !reflect.DeepEqual(j.list(), want) {
+		t.Errorf("journal = %q, want %q", j.list(), want)
+	}
+	if got, err := repo.GetByID(context.Background(), saga.ID); err != nil || got.Status != models.SagaCompleted {
+		t.Errorf("saga = %+v, %v; want completed", got, err)
+	}
+}
+
+func TestResume_RetriesCompensation(t *testing.T) {
+	repo := repository.NewSagaRepository(setupSQLiteDB(t))
+	c := newTestCoordinator(repo)
+	j := &journal{}
+	def := fulfillment(j, func(ctx context.Context) error { return errors.New("connector unavailable") })
+	release := def.Steps[0].Compensate
+	failures := 1
+	def.Steps[0].Compensate = func(ctx context.Context, s *Saga) error {
+		if failures > 0 {
+			failures--
+			return errors.New("inventory unavailable")
+		}
+		return release(ctx, s)
+	}
+	c.Register(def)
+
+	saga, err := c.Start(context.Background(), "order-fulfillment", "1001", order{SKU: "A-1", Quantity: 2})
+	if err == nil || errors.Is(err, ErrCompensated) {
+		t.Fatalf("Start = %v, want the compensation's failure", err)
+	}
+	got, err := repo.GetByID(context.Background(), saga.ID)
+	if err != nil || got.Status != models.SagaCompensating || got.Step != 1 {
+		t.Fatalf("saga = %+v, %v; want compensating the reservation", got, err)
+	}
+
+	if err := c.Resume(context.Background()); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if want := []string{"reserve A-1", "push", "release r-A-1"}; !reflect.DeepEqual(j.list(), want) {
+		t.Errorf("journal = %q, want %q", j.list(), want)
+	}
+	if got, err := repo.GetByID(context.Background(), saga.ID); err != nil || got.Status != models.SagaCompensated {
+		t.Errorf("saga = %+v, %v; want compensated", got, err)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	repo := repository.NewSagaRepository(setupSQLiteDB(t))
+	j := &journal{}
+
+	// Running on another instance
+	until := time.Now().Add(time.Minute)
+	running := &models.Saga{Name: "order-fulfillment", Key: "1001", Status: models.SagaRunning, Data: []byte(`{}`), ClaimedBy: "elsewhere", ClaimedUntil: &until}
+	if _, err := repo.Create(context.Background(), running); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestCoordinator(repo)
+	c.Register(fulfillment(j, succeed))
+	if _, err := c.Start(context.Background(), "order-fulfillment", "1001", nil); !errors.Is(err, ErrRunning) {
+		t.Errorf("Start of a saga running elsewhere = %v, want ErrRunning", err)
+	}
+
+	// A step outlasting the claim keeps it by renewing it
+	c.claimDuration = 30 * time.Millisecond
+	other := newTestCoordinator(repo)
+	other.Register(fulfillment(j, succeed))
+	def := fulfillment(j, func(ctx context.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		return other.Resume(ctx)
+	})
+	c.Register(def)
+	if _, err := c.Start(context.Background(), "order-fulfillment", "1002", order{SKU: "B-2"}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if want := []string{"reserve B-2", "push", "notify"}; !reflect.DeepEqual(j.list(), want) {
+		t.Errorf("journal = %q, want %q: the saga resumed elsewhere while running", j.list(), want)
+	}
+}
@@ -0,0 +1,4 @@
+-- Drop the event_outbox table and its associated indexes
+DROP INDEX IF EXISTS idx_event_outbox_aggregate;
+DROP INDEX IF EXISTS idx_event_outbox_pending;
+DROP TABLE IF EXISTS event_outbox;
@@ -0,0 +1,22 @@
+-- Create the event_outbox table
+-- Domain events are written here in the same transaction as the change that
+-- caused them, then relayed to external brokers (at-least-once delivery)
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(36) NOT NULL UNIQUE,
+    event_type VARCHAR(100) NOT NULL,
+    schema_version INTEGER NOT NULL,
+    aggregate_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP
+);
+
+-- Partial index so the relay only scans undelivered messages
+CREATE INDEX idx_event_outbox_pending ON event_outbox(id) WHERE published_at IS NULL;
+CREATE INDEX idx_event_outbox_aggregate ON event_outbox(aggregate_id, id);
@@ -0,0 +1,3 @@
+-- Drop the processed_orders table and its associated indexes
+DROP INDEX IF EXISTS idx_processed_orders_processed_at;
+DROP TABLE IF EXISTS processed_orders;
@@ -0,0 +1,3 @@
+-- Allow NULL descriptions again
+ALTER TABLE products ALTER COLUMN description DROP NOT NULL;
+ALTER TABLE products ALTER COLUMN description DROP DEFAULT;
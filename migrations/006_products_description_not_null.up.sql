@@ -0,0 +1,6 @@
+-- Make products.description NOT NULL
+-- models.Product maps it to a plain string, which can't hold NULL; rows with a
+-- NULL description failed to scan
+UPDATE products SET description = '' WHERE description IS NULL;
+ALTER TABLE products ALTER COLUMN description SET DEFAULT '';
+ALTER TABLE products ALTER COLUMN description SET NOT NULL;
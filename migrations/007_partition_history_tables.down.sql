@@ -0,0 +1,30 @@
+-- Drop stock_movements and turn audit_log back into a plain table
+DROP TRIGGER IF EXISTS products_stock_movement ON products;
+DROP FUNCTION IF EXISTS record_stock_movement();
+DROP TABLE IF EXISTS stock_movements;
+
+ALTER TABLE audit_log RENAME TO audit_log_partitioned;
+ALTER SEQUENCE audit_log_id_seq OWNED BY NONE;
+
+CREATE TABLE audit_log (
+    id BIGINT PRIMARY KEY DEFAULT nextval('audit_log_id_seq'),
+    action VARCHAR(100) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    entity_type VARCHAR(100) NOT NULL,
+    entity_id VARCHAR(255),
+    details JSONB,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER SEQUENCE audit_log_id_seq OWNED BY audit_log.id;
+
+INSERT INTO audit_log (id, action, actor, entity_type, entity_id, details, created_at)
+SELECT id, action, actor, entity_type, entity_id, details, created_at FROM audit_log_partitioned;
+
+-- Drops the attached partitions too; detached ones are left alone
+DROP TABLE audit_log_partitioned;
+
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at DESC);
@@ -0,0 +1,91 @@
+-- Partition audit_log by month and add stock_movements, partitioned the same way
+-- Partitions are named <table>_pYYYY_MM; the partition maintenance job creates
+-- upcoming months and drops or detaches expired ones
+
+-- Move audit_log aside, keeping its id sequence for the partitioned table
+ALTER TABLE audit_log RENAME TO audit_log_unpartitioned;
+ALTER SEQUENCE audit_log_id_seq OWNED BY NONE;
+
+CREATE TABLE audit_log (
+    id BIGINT NOT NULL DEFAULT nextval('audit_log_id_seq'),
+    action VARCHAR(100) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    entity_type VARCHAR(100) NOT NULL,
+    entity_id VARCHAR(255),
+    details JSONB,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- The partition key must be part of the primary key
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+ALTER SEQUENCE audit_log_id_seq OWNED BY audit_log.id;
+
+-- Stock changes of products, recorded by trigger
+CREATE TABLE stock_movements (
+    id BIGSERIAL,
+    product_id INTEGER NOT NULL,
+    sku VARCHAR(255) NOT NULL,
+    delta INTEGER NOT NULL,
+    quantity_after INTEGER NOT NULL,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+-- Create partitions from the oldest audit entry through three months ahead
+DO $$
+DECLARE
+    month DATE;
+    oldest DATE;
+BEGIN
+    SELECT date_trunc('month', LEAST(MIN(created_at), CURRENT_TIMESTAMP))::DATE
+    INTO oldest FROM audit_log_unpartitioned;
+
+    FOR month IN
+        SELECT generate_series(oldest, date_trunc('month', CURRENT_TIMESTAMP) + INTERVAL '3 months', INTERVAL '1 month')::DATE
+    LOOP
+        EXECUTE format('CREATE TABLE audit_log_p%s PARTITION OF audit_log FOR VALUES FROM (%L) TO (%L)',
+            to_char(month, 'YYYY_MM'), month, month + INTERVAL '1 month');
+        IF month >= date_trunc('month', CURRENT_TIMESTAMP) THEN
+            EXECUTE format('CREATE TABLE stock_movements_p%s PARTITION OF stock_movements FOR VALUES FROM (%L) TO (%L)',
+                to_char(month, 'YYYY_MM'), month, month + INTERVAL '1 month');
+        END IF;
+    END LOOP;
+END
+$$;
+
+INSERT INTO audit_log (id, action, actor, entity_type, entity_id, details, created_at)
+SELECT id, action, actor, entity_type, entity_id, details, created_at FROM audit_log_unpartitioned;
+
+DROP TABLE audit_log_unpartitioned;
+
+-- Create indexes for better query performance, queries filter on created_at so
+-- that only the partitions in range are scanned
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at DESC);
+CREATE INDEX idx_stock_movements_product ON stock_movements(product_id, created_at DESC);
+
+-- Record every change of a product's quantity, whichever code path made it
+CREATE OR REPLACE FUNCTION record_stock_movement() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        IF NEW.quantity <> 0 THEN
+            INSERT INTO stock_movements (product_id, sku, delta, quantity_after)
+            VALUES (NEW.id, NEW.sku, NEW.quantity, NEW.quantity);
+        END IF;
+    ELSIF NEW.quantity <> OLD.quantity THEN
+        INSERT INTO stock_movements (product_id, sku, delta, quantity_after)
+        VALUES (NEW.id, NEW.sku, NEW.quantity - OLD.quantity, NEW.quantity);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_stock_movement
+    AFTER INSERT OR UPDATE OF quantity ON products
+    FOR EACH ROW EXECUTE FUNCTION record_stock_movement();
@@ -0,0 +1,2 @@
+-- Drop the delivered outbox messages index
+DROP INDEX IF EXISTS idx_event_outbox_published_at;
@@ -0,0 +1,2 @@
+-- Index delivered outbox messages by delivery time for the retention purge
+CREATE INDEX IF NOT EXISTS idx_event_outbox_published_at ON event_outbox(published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,5 @@
+-- Drop the product_history table and its trigger
+DROP TRIGGER IF EXISTS products_history ON products;
+DROP FUNCTION IF EXISTS record_product_history();
+DROP INDEX IF EXISTS idx_product_history_product;
+DROP TABLE IF EXISTS product_history;
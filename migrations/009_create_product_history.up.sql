@@ -0,0 +1,55 @@
+-- Create the product_history table
+-- Every version of a product row, valid in [valid_from, valid_to); the current
+-- version has no valid_to. Maintained by trigger so every write path is covered.
+CREATE TABLE IF NOT EXISTS product_history (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    operation VARCHAR(10) NOT NULL, -- INSERT, UPDATE, or DELETE
+
+    -- Product state in this version
+    sku VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_price DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP
+);
+
+-- Create indexes for better query performance
+CREATE INDEX idx_product_history_product ON product_history(product_id, valid_from DESC);
+
+-- Close the current version and, unless the product was deleted, open a new one
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE product_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE product_id = OLD.id AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO product_history (
+        product_id, operation, sku, name, description, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, TG_OP, NEW.sku, NEW.name, NEW.description, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_history
+    AFTER INSERT OR UPDATE OR DELETE ON products
+    FOR EACH ROW EXECUTE FUNCTION record_product_history();
+
+-- Existing products are known from their last update onwards
+INSERT INTO product_history (
+    product_id, operation, sku, name, description, quantity, unit_price, created_at, updated_at, valid_from
+)
+SELECT id, 'INSERT', sku, name, description, quantity, unit_price, created_at, updated_at, updated_at
+FROM products;
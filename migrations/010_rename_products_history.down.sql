@@ -0,0 +1,24 @@
+-- Rename products_history back to product_history
+ALTER TABLE products_history RENAME TO product_history;
+ALTER SEQUENCE products_history_id_seq RENAME TO product_history_id_seq;
+ALTER INDEX idx_products_history_product RENAME TO idx_product_history_product;
+
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE product_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE product_id = OLD.id AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO product_history (
+        product_id, operation, sku, name, description, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, TG_OP, NEW.sku, NEW.name, NEW.description, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,25 @@
+-- Rename product_history to products_history, matching the table it versions
+ALTER TABLE product_history RENAME TO products_history;
+ALTER SEQUENCE product_history_id_seq RENAME TO products_history_id_seq;
+ALTER INDEX idx_product_history_product RENAME TO idx_products_history_product;
+
+-- Close the current version and, unless the product was deleted, open a new one
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE products_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE product_id = OLD.id AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, TG_OP, NEW.sku, NEW.name, NEW.description, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
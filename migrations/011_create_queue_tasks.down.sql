@@ -0,0 +1,3 @@
+-- Drop the queue_tasks table and its associated indexes
+DROP INDEX IF EXISTS idx_queue_tasks_run_after;
+DROP TABLE IF EXISTS queue_tasks;
@@ -0,0 +1,18 @@
+-- Create the queue_tasks table
+-- Worker pool tasks that couldn't run right away (destination saturated,
+-- failed attempt, or shutdown) wait here until run_after
+CREATE TABLE IF NOT EXISTS queue_tasks (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(100) NOT NULL,
+    destination VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    run_after TIMESTAMP NOT NULL,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_queue_tasks_run_after ON queue_tasks(run_after, id);
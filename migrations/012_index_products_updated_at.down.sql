@@ -0,0 +1,2 @@
+-- Drop the products last change index
+DROP INDEX IF EXISTS idx_products_updated_at;
@@ -0,0 +1,3 @@
+-- Index products by last change for catalog sync (ListUpdatedSince), found
+-- by the index advisor test
+CREATE INDEX IF NOT EXISTS idx_products_updated_at ON products(updated_at);
@@ -0,0 +1,4 @@
+-- Drop product stats and the materialized view registry
+DROP MATERIALIZED VIEW IF EXISTS product_stats;
+DROP VIEW IF EXISTS product_stats_source;
+DROP TABLE IF EXISTS materialized_views;
@@ -0,0 +1,31 @@
+-- Materialized views and their last refresh. A view with refreshed_at NULL
+-- holds no data and is populated once migrations have run; migrations that
+-- recreate a view reset it.
+CREATE TABLE materialized_views (
+    name VARCHAR(255) PRIMARY KEY,
+    refreshed_at TIMESTAMP
+);
+
+-- Stock statistics per product, computed live; product_stats materializes it.
+-- Movements count within the stock_movements retention.
+CREATE VIEW product_stats_source AS
+SELECT
+    p.id AS product_id,
+    p.sku,
+    p.quantity,
+    ROUND(p.quantity * p.unit_price, 2) AS inventory_value,
+    COUNT(m.id) AS movements,
+    COALESCE(SUM(CASE WHEN m.delta > 0 THEN m.delta END), 0) AS units_in,
+    COALESCE(SUM(CASE WHEN m.delta < 0 THEN -m.delta END), 0) AS units_out
+FROM products p
+LEFT JOIN stock_movements m ON m.product_id = p.id
+GROUP BY p.id, p.sku, p.quantity, p.unit_price;
+
+CREATE MATERIALIZED VIEW product_stats AS
+SELECT * FROM product_stats_source
+WITH NO DATA;
+
+-- REFRESH MATERIALIZED VIEW CONCURRENTLY needs a unique index
+CREATE UNIQUE INDEX idx_product_stats_product ON product_stats(product_id);
+
+INSERT INTO materialized_views (name) VALUES ('product_stats');
@@ -0,0 +1,24 @@
+-- Drop the products category
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE products_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE product_id = OLD.id AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, TG_OP, NEW.sku, NEW.name, NEW.description, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_products_category;
+ALTER TABLE products_history DROP COLUMN IF EXISTS category;
+ALTER TABLE products DROP COLUMN IF EXISTS category;
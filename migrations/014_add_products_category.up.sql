@@ -0,0 +1,27 @@
+-- Add a category to products, which tax rules and promotions can target.
+-- Empty means uncategorized.
+ALTER TABLE products ADD COLUMN category VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE products_history ADD COLUMN category VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_products_category ON products(category);
+
+-- Record the category in every version
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE products_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE product_id = OLD.id AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, category, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, TG_OP, NEW.sku, NEW.name, NEW.description, NEW.category, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
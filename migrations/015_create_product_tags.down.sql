@@ -0,0 +1,2 @@
+-- Drop the product_tags table
+DROP TABLE IF EXISTS product_tags;
@@ -0,0 +1,12 @@
+-- Create the bundle_components table
+-- A product with components is a bundle, sold as a kit of the other products;
+-- bundles don't nest, and a product in a bundle can't be deleted
+CREATE TABLE IF NOT EXISTS bundle_components (
+    bundle_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    component_id INTEGER NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (bundle_id, component_id),
+    CHECK (bundle_id <> component_id)
+);
+
+CREATE INDEX idx_bundle_components_component_id ON bundle_components(component_id);
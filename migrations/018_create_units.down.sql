@@ -0,0 +1,48 @@
+-- Drop units of measure
+DROP MATERIALIZED VIEW IF EXISTS product_stats;
+DROP VIEW IF EXISTS product_stats_source;
+
+CREATE VIEW product_stats_source AS
+SELECT
+    p.id AS product_id,
+    p.sku,
+    p.quantity,
+    ROUND(p.quantity * p.unit_price, 2) AS inventory_value,
+    COUNT(m.id) AS movements,
+    COALESCE(SUM(CASE WHEN m.delta > 0 THEN m.delta END), 0) AS units_in,
+    COALESCE(SUM(CASE WHEN m.delta < 0 THEN -m.delta END), 0) AS units_out
+FROM products p
+LEFT JOIN stock_movements m ON m.product_id = p.id
+GROUP BY p.id, p.sku, p.quantity, p.unit_price;
+
+CREATE MATERIALIZED VIEW product_stats AS
+SELECT * FROM product_stats_source
+WITH NO DATA;
+
+CREATE UNIQUE INDEX idx_product_stats_product ON product_stats(product_id);
+
+UPDATE materialized_views SET refreshed_at = NULL WHERE name = 'product_stats';
+
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE products_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE product_id = OLD.id AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, category, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, TG_OP, NEW.sku, NEW.name, NEW.description, NEW.category, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE products_history DROP COLUMN IF EXISTS unit;
+ALTER TABLE products DROP COLUMN IF EXISTS unit;
+DROP TABLE IF EXISTS units;
@@ -0,0 +1,73 @@
+-- Units of measure and their conversions. Each unit is factor times the base
+-- unit of its dimension (the unit with factor 1): quantities convert exactly
+-- between units of one dimension, and add up in base units.
+CREATE TABLE IF NOT EXISTS units (
+    code VARCHAR(20) PRIMARY KEY,
+    dimension VARCHAR(20) NOT NULL,
+    factor INTEGER NOT NULL CHECK (factor > 0)
+);
+
+-- One base unit per dimension
+CREATE UNIQUE INDEX idx_units_base ON units(dimension) WHERE factor = 1;
+
+INSERT INTO units (code, dimension, factor) VALUES
+    ('each', 'count', 1),
+    ('box-of-12', 'count', 12),
+    ('g', 'mass', 1),
+    ('kg', 'mass', 1000),
+    ('ml', 'volume', 1),
+    ('liter', 'volume', 1000);
+
+-- The unit a product's quantity and unit price are in
+ALTER TABLE products ADD COLUMN unit VARCHAR(20) NOT NULL DEFAULT 'each' REFERENCES units(code);
+ALTER TABLE products_history ADD COLUMN unit VARCHAR(20) NOT NULL DEFAULT 'each';
+
+-- Record the unit in every version
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE products_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE product_id = OLD.id AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, category, unit, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, TG_OP, NEW.sku, NEW.name, NEW.description, NEW.category, NEW.unit, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Stats normalized to base units, so totals add up per dimension
+DROP MATERIALIZED VIEW IF EXISTS product_stats;
+DROP VIEW IF EXISTS product_stats_source;
+
+CREATE VIEW product_stats_source AS
+SELECT
+    p.id AS product_id,
+    p.sku,
+    p.unit,
+    u.dimension,
+    p.quantity,
+    p.quantity * u.factor AS base_quantity,
+    ROUND(p.quantity * p.unit_price, 2) AS inventory_value,
+    COUNT(m.id) AS movements,
+    COALESCE(SUM(CASE WHEN m.delta > 0 THEN m.delta END), 0) AS units_in,
+    COALESCE(SUM(CASE WHEN m.delta < 0 THEN -m.delta END), 0) AS units_out
+FROM products p
+JOIN units u ON u.code = p.unit
+LEFT JOIN stock_movements m ON m.product_id = p.id
+GROUP BY p.id, p.sku, p.unit, u.dimension, u.factor, p.quantity, p.unit_price;
+
+CREATE MATERIALIZED VIEW product_stats AS
+SELECT * FROM product_stats_source
+WITH NO DATA;
+
+CREATE UNIQUE INDEX idx_product_stats_product ON product_stats(product_id);
+
+UPDATE materialized_views SET refreshed_at = NULL WHERE name = 'product_stats';
@@ -0,0 +1,3 @@
+-- Drop the numbering for generated SKUs
+DROP SEQUENCE IF EXISTS product_sku_seq;
+DROP TABLE IF EXISTS sku_counters;
@@ -0,0 +1,10 @@
+-- Create the numbering for generated SKUs
+-- sku_counters holds the last number of each scope (the SKU pattern without
+-- its number), taken under a row lock for gapless numbering; product_sku_seq
+-- is used instead with SKU_SEQUENCE=sequence
+CREATE TABLE IF NOT EXISTS sku_counters (
+    scope VARCHAR(255) PRIMARY KEY,
+    value BIGINT NOT NULL CHECK (value > 0)
+);
+
+CREATE SEQUENCE IF NOT EXISTS product_sku_seq;
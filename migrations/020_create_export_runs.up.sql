@@ -0,0 +1,23 @@
+-- Create the export_runs table
+-- History of catalog exports to the blob store, scheduled or triggered by an
+-- admin; runs still running at startup were interrupted and are failed
+CREATE TABLE IF NOT EXISTS export_runs (
+    id SERIAL PRIMARY KEY,
+    triggered_by VARCHAR(20) NOT NULL,
+    format VARCHAR(20) NOT NULL,
+    filter_category VARCHAR(100) NOT NULL DEFAULT '',
+    filter_tag VARCHAR(100) NOT NULL DEFAULT '',
+
+    status VARCHAR(20) NOT NULL,
+    object_key VARCHAR(1024) NOT NULL DEFAULT '',
+    url VARCHAR(2048) NOT NULL DEFAULT '',
+    row_count BIGINT NOT NULL DEFAULT 0,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+
+    -- Metadata
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_export_runs_started_at ON export_runs(started_at DESC);
@@ -0,0 +1,8 @@
+-- Record the transaction that wrote each outbox message. Ids are assigned
+-- when a message is inserted but become visible when its transaction commits,
+-- so the change feed reads in (txid, id) order and stops short of
+-- transactions still in flight, which lets it hand out a cursor that never
+-- skips a late commit.
+ALTER TABLE event_outbox ADD COLUMN txid BIGINT NOT NULL DEFAULT (pg_current_xact_id()::text::bigint);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_txid ON event_outbox(txid, id);
@@ -0,0 +1,3 @@
+-- Drop the search index state and the full-text search index
+DROP TABLE IF EXISTS search_index_state;
+DROP INDEX IF EXISTS idx_products_search;
@@ -0,0 +1,19 @@
+-- Full-text search over products. The expression must match
+-- productSearchDocument in internal/repository/search.go for the index to be
+-- used.
+CREATE INDEX IF NOT EXISTS idx_products_search ON products USING GIN ((
+    setweight(to_tsvector('simple', sku), 'A') ||
+    setweight(to_tsvector('english', name), 'A') ||
+    setweight(to_tsvector('english', description), 'B')
+));
+
+-- Create the search_index_state table
+-- Tracks the external search index (OpenSearch) an alias points to and the
+-- change feed cursor applied to it
+CREATE TABLE IF NOT EXISTS search_index_state (
+    name VARCHAR(255) PRIMARY KEY,
+    index_name VARCHAR(255) NOT NULL,
+    feed_cursor VARCHAR(100) NOT NULL,
+    reindexed_at TIMESTAMP NOT NULL,
+    synced_at TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- Drop the completion indexes; pg_trgm stays, other objects may use it
+DROP INDEX IF EXISTS idx_products_suggest_sku;
+DROP INDEX IF EXISTS idx_products_suggest_name;
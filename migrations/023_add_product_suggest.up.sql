@@ -0,0 +1,8 @@
+-- Trigram indexes for name and SKU completions (GET /products/suggest). The
+-- expressions must match the suggest query in internal/repository/search.go
+-- for the indexes to be used; they serve both the prefix LIKE and the <%
+-- word similarity conditions.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_products_suggest_name ON products USING GIN (LOWER(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_suggest_sku ON products USING GIN (LOWER(sku) gin_trgm_ops);
@@ -0,0 +1,12 @@
+-- Create the saved_searches table
+-- Named product filters for GET /products?view={id}; filter is the JSON of
+-- models.ProductFilter, validated before it's stored
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    filter JSONB NOT NULL,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
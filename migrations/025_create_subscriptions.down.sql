@@ -0,0 +1,2 @@
+-- Drop the subscriptions table
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,12 @@
+-- Create the trashed_products table
+-- Deleted products, as the JSON of models.Product with their tags, kept until
+-- restored or purged after TRASH_RETENTION; the ID is the product's
+CREATE TABLE IF NOT EXISTS trashed_products (
+    id INTEGER PRIMARY KEY,
+    sku VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    product JSONB NOT NULL,
+    trashed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_trashed_products_trashed_at ON trashed_products(trashed_at);
@@ -0,0 +1,3 @@
+-- Drop the api_usage and api_keys tables
+DROP TABLE IF EXISTS api_usage;
+DROP TABLE IF EXISTS api_keys;
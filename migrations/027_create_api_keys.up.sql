@@ -0,0 +1,27 @@
+-- Create the api_keys and api_usage tables
+-- Keys of external API consumers with a monthly request quota. Only the
+-- SHA-256 of a key is stored; prefix identifies it in listings. Keys are
+-- revoked rather than deleted so their usage can still be billed.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    prefix VARCHAR(16) NOT NULL,
+    monthly_quota BIGINT NOT NULL DEFAULT 0, -- Requests per calendar month (UTC), 0 for no limit
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+-- Requests and response bytes per key, month ("2026-10"), and endpoint class
+CREATE TABLE IF NOT EXISTS api_usage (
+    api_key_id INTEGER NOT NULL REFERENCES api_keys(id),
+    month VARCHAR(7) NOT NULL,
+    endpoint_class VARCHAR(64) NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (api_key_id, month, endpoint_class)
+);
+
+CREATE INDEX idx_api_usage_month ON api_usage(month);
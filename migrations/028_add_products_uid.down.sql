@@ -0,0 +1,3 @@
+-- Drop the globally unique ID of products
+DROP INDEX IF EXISTS idx_products_uid;
+ALTER TABLE products DROP COLUMN IF EXISTS uid;
@@ -0,0 +1,6 @@
+-- Add the globally unique ID of products (ID_STRATEGY), which stays unique
+-- when catalogs of several regions are merged. Empty until assigned, for
+-- products created with the serial strategy.
+ALTER TABLE products ADD COLUMN uid VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_uid ON products(uid) WHERE uid <> '';
@@ -0,0 +1,3 @@
+-- Add the time of the snapshot an export read the catalog from, which every
+-- row of it reflects. NULL for runs that failed before taking it.
+ALTER TABLE export_runs ADD COLUMN snapshot_at TIMESTAMP;
@@ -0,0 +1,27 @@
+-- Create the stock_receipts and inventory_valuations tables
+-- A receipt is a cost lot: units of a product, in its unit, received at a
+-- unit cost. Inventory is valued from the lots, FIFO or at weighted average
+-- cost; inventory_valuations keeps the totals of the inventory-valuation job.
+CREATE TABLE IF NOT EXISTS stock_receipts (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_cost DECIMAL(12,4) NOT NULL CHECK (unit_cost >= 0),
+    reference VARCHAR(255) NOT NULL DEFAULT '', -- e.g. the purchase order
+
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_stock_receipts_product ON stock_receipts(product_id, received_at DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS inventory_valuations (
+    id SERIAL PRIMARY KEY,
+    method VARCHAR(20) NOT NULL, -- fifo or weighted_average
+    value DECIMAL(14,2) NOT NULL,
+    quantity BIGINT NOT NULL, -- Units valued
+    uncosted_quantity BIGINT NOT NULL, -- Units on hand no receipt accounts for
+
+    valued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_inventory_valuations_method ON inventory_valuations(method, valued_at DESC);
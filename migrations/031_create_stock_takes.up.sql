@@ -0,0 +1,32 @@
+-- Create the stock_takes and stock_take_counts tables
+-- A stock take is a counting session: products are counted while it's open,
+-- each count keeping the system quantity at the time of counting, and
+-- completing it adjusts stock by the difference, with the count's reason
+-- code. Completed and cancelled takes are kept for the record.
+CREATE TABLE IF NOT EXISTS stock_takes (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, completed, or cancelled
+    opened_by VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- Metadata
+    opened_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMP
+);
+
+CREATE INDEX idx_stock_takes_opened_at ON stock_takes(opened_at DESC);
+
+CREATE TABLE IF NOT EXISTS stock_take_counts (
+    stock_take_id INTEGER NOT NULL REFERENCES stock_takes(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(255) NOT NULL,
+    counted INTEGER NOT NULL CHECK (counted >= 0),
+    expected INTEGER NOT NULL, -- The product's quantity when it was counted
+    reason VARCHAR(20) NOT NULL,
+    adjustment INTEGER, -- Posted on completion; NULL until then
+
+    counted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (stock_take_id, product_id)
+);
+
+CREATE INDEX idx_stock_take_counts_product ON stock_take_counts(product_id);
@@ -0,0 +1,2 @@
+-- Drop the product_lots table
+DROP TABLE IF EXISTS product_lots;
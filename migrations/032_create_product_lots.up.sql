@@ -0,0 +1,19 @@
+-- Create the product_lots table
+-- A lot is a batch of a product received together under a lot number, with
+-- the time it expires (NULL for lots that don't). quantity is what's left of
+-- it, in the product's unit; lots are consumed first-expired-first-out.
+-- alerted_at is set once the lot's expiry alert has been sent.
+CREATE TABLE IF NOT EXISTS product_lots (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    lot_number VARCHAR(100) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    expires_at TIMESTAMP,
+
+    -- Metadata
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    alerted_at TIMESTAMP,
+    UNIQUE (product_id, lot_number)
+);
+
+CREATE INDEX idx_product_lots_expires_at ON product_lots(expires_at) WHERE quantity > 0;
@@ -0,0 +1,2 @@
+-- Drop the serial_numbers table
+DROP TABLE IF EXISTS serial_numbers;
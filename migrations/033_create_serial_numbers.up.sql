@@ -0,0 +1,18 @@
+-- Create the serial_numbers table
+-- A serial number identifies one unit of a serialized product. Active serials
+-- are units on hand, so a serialized product's quantity should equal the
+-- count of its active serials; retired ones left stock, for retired_reason.
+-- Serials are unique across products, so scanning one finds its unit.
+CREATE TABLE IF NOT EXISTS serial_numbers (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    serial VARCHAR(100) NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    retired_reason VARCHAR(20) NOT NULL DEFAULT '',
+
+    -- Metadata
+    registered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    retired_at TIMESTAMP
+);
+
+CREATE INDEX idx_serial_numbers_product_id ON serial_numbers(product_id, status);
@@ -0,0 +1,2 @@
+-- Drop the product_returns table
+DROP TABLE IF EXISTS product_returns;
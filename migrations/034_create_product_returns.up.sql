@@ -0,0 +1,29 @@
+-- Create the product_returns table
+-- A return (RMA) authorizes units of a product sold, optionally on a given
+-- order, to come back. Once received they're inspected and dispositioned:
+-- restocked into the product's quantity, scrapped, or sent for repair and
+-- dispositioned again after. stock_movement_id is the movement a restock
+-- recorded.
+CREATE TABLE IF NOT EXISTS product_returns (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    order_source VARCHAR(100) NOT NULL DEFAULT '',
+    order_id VARCHAR(255) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'authorized', -- authorized, received, repairing, restocked, scrapped, or cancelled
+    disposition VARCHAR(20) NOT NULL DEFAULT '', -- restock, scrap, or repair, as last inspected
+    notes TEXT NOT NULL DEFAULT '',
+    stock_movement_id BIGINT,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    received_at TIMESTAMP,
+    closed_at TIMESTAMP
+);
+
+CREATE INDEX idx_product_returns_created_at ON product_returns(created_at DESC);
+CREATE INDEX idx_product_returns_product_id ON product_returns(product_id);
+CREATE INDEX idx_product_returns_order ON product_returns(order_source, order_id);
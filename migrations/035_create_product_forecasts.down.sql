@@ -0,0 +1,3 @@
+-- Drop the product_forecasts and product_lead_times tables
+DROP TABLE IF EXISTS product_lead_times;
+DROP TABLE IF EXISTS product_forecasts;
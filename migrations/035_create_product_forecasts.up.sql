@@ -0,0 +1,28 @@
+-- Create the product_forecasts and product_lead_times tables
+-- A forecast projects a product's demand, the units stock movements took
+-- out over the forecast window, as a moving average with a linear trend; the
+-- demand-forecast job recomputes every product's. product_lead_times keeps
+-- the supplier lead times of products that don't use the default.
+CREATE TABLE IF NOT EXISTS product_forecasts (
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(100) NOT NULL,
+    window_days INTEGER NOT NULL,
+    demand BIGINT NOT NULL, -- Units out over the window
+    average_daily_demand DECIMAL(12,4) NOT NULL,
+    trend DECIMAL(12,4) NOT NULL, -- Change of daily demand per day
+    horizon_days INTEGER NOT NULL,
+    projected_demand DECIMAL(14,4) NOT NULL, -- Over the horizon
+    lead_time_days INTEGER NOT NULL,
+    lead_time_demand DECIMAL(14,4) NOT NULL, -- Over the lead time
+    reorder_point INTEGER NOT NULL,
+    order_up_to INTEGER NOT NULL,
+
+    computed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS product_lead_times (
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    lead_time_days INTEGER NOT NULL CHECK (lead_time_days >= 0),
+
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,43 @@
+-- Create the product_suppliers, purchase_orders, and purchase_order_lines tables
+-- product_suppliers names who supplies a product and optionally fixes its
+-- economic order quantity. Purchase orders are drafted per supplier for the
+-- products at or below their reorder point, and go from draft through
+-- approved and submitted to received, or are cancelled before submission.
+CREATE TABLE IF NOT EXISTS product_suppliers (
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    supplier VARCHAR(255) NOT NULL,
+    economic_order_quantity INTEGER CHECK (economic_order_quantity > 0), -- Computed when NULL
+
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_product_suppliers_supplier ON product_suppliers(supplier);
+
+CREATE TABLE IF NOT EXISTS purchase_orders (
+    id SERIAL PRIMARY KEY,
+    supplier VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    approved_by VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    approved_at TIMESTAMP,
+    submitted_at TIMESTAMP,
+    closed_at TIMESTAMP -- Received or cancelled
+);
+
+CREATE INDEX idx_purchase_orders_status ON purchase_orders(status, supplier);
+CREATE INDEX idx_purchase_orders_created_at ON purchase_orders(created_at DESC);
+
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(100) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    suggested_quantity INTEGER NOT NULL, -- Bringing the product up to its order-up-to level
+    economic_order_quantity INTEGER NOT NULL, -- 0 when it couldn't be computed
+    PRIMARY KEY (purchase_order_id, product_id)
+);
+
+CREATE INDEX idx_purchase_order_lines_product_id ON purchase_order_lines(product_id);
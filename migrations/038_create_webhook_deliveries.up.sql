@@ -0,0 +1,20 @@
+-- Create the webhook_deliveries table
+-- Every attempt to deliver a change notification to a subscription's callback,
+-- for product timelines. Not tied to subscriptions, which may be deleted since;
+-- status_code is 0 when the callback didn't answer.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    callback_url TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_deliveries_product ON webhook_deliveries(product_id, created_at DESC);
+CREATE INDEX idx_webhook_deliveries_created_at ON webhook_deliveries(created_at);
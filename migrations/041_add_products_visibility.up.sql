@@ -0,0 +1,13 @@
+-- Add visibility windows to products. Public lists and searches show a
+-- product from visible_from (always when NULL) until visible_until (for
+-- good when NULL). listed is the visibility last announced by the
+-- product-visibility job, which publishes product.visibility_changed as
+-- products enter and leave their windows.
+ALTER TABLE products ADD COLUMN visible_from TIMESTAMP;
+ALTER TABLE products ADD COLUMN visible_until TIMESTAMP;
+ALTER TABLE products ADD COLUMN listed BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- The job only looks at products with a window, or hidden by one
+CREATE INDEX IF NOT EXISTS idx_products_visibility ON products(id)
+    WHERE visible_from IS NOT NULL OR visible_until IS NOT NULL OR NOT listed;
+CREATE INDEX IF NOT EXISTS idx_products_visible_until ON products(visible_until) WHERE visible_until IS NOT NULL;
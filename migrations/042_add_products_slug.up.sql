@@ -0,0 +1,15 @@
+-- Add the URL slug of products, made from the name and unique among
+-- products, and product_slugs, the slugs products had before, which keep
+-- redirecting to them. Empty until assigned (api admin assign-slugs) for
+-- products created before.
+ALTER TABLE products ADD COLUMN slug VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_slug ON products(slug) WHERE slug <> '';
+
+CREATE TABLE IF NOT EXISTS product_slugs (
+    slug VARCHAR(255) PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    replaced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_product_slugs_product ON product_slugs(product_id);
@@ -0,0 +1,2 @@
+-- Drop the product_notes table
+DROP TABLE IF EXISTS product_notes;
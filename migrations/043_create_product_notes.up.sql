@@ -0,0 +1,17 @@
+-- Create the product_notes table
+-- Notes internal users leave on products, e.g. where stock is kept or why a
+-- count is off. author is the subject of the writer's access token, or
+-- "admin". Pinned notes are listed first.
+CREATE TABLE IF NOT EXISTS product_notes (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    author VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_product_notes_product ON product_notes(product_id, created_at);
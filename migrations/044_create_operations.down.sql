@@ -0,0 +1,2 @@
+-- Drop the operations table
+DROP TABLE IF EXISTS operations;
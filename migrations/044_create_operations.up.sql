@@ -0,0 +1,20 @@
+-- Create the operations table
+-- Long-running operations started by async endpoints (exports, reindexes),
+-- polled at /api/v1/operations/{id}. result links to the outcome once the
+-- operation succeeded; operations still running at startup were interrupted
+-- and are failed.
+CREATE TABLE IF NOT EXISTS operations (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    progress INTEGER NOT NULL DEFAULT 0 CHECK (progress BETWEEN 0 AND 100),
+    result VARCHAR(2048) NOT NULL DEFAULT '',
+    error TEXT,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_operations_status ON operations(status);
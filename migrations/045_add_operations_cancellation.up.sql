@@ -0,0 +1,7 @@
+-- Record how far operations have got and cancellations asked for.
+-- checkpoint describes the work done so far, such as "1200 of 5000 products
+-- exported", and is kept when an operation fails or is cancelled. An
+-- operation with cancel_requested_at stops at its next check and is
+-- cancelled.
+ALTER TABLE operations ADD COLUMN checkpoint VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE operations ADD COLUMN cancel_requested_at TIMESTAMP;
@@ -0,0 +1,2 @@
+-- Drop the sagas table
+DROP TABLE IF EXISTS sagas;
@@ -0,0 +1,18 @@
+-- Create the inbox_messages table
+-- The inbox of messages from other systems, such as order webhooks and queue
+-- messages: a message's ID is recorded in the transaction that applies it, so
+-- a message delivered again under the same ID is discarded instead of
+-- applied twice. Rows are kept for INBOX_RETENTION, longer than senders
+-- retry.
+CREATE TABLE IF NOT EXISTS inbox_messages (
+    source VARCHAR(50) NOT NULL,
+    message_id VARCHAR(255) NOT NULL,
+
+    -- Metadata
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (source, message_id)
+);
+
+-- Create indexes for better query performance
+CREATE INDEX idx_inbox_messages_received_at ON inbox_messages(received_at);
@@ -0,0 +1,4 @@
+-- Drop the DEFAULT partitions and the rows still in them; afterwards inserts
+-- for a month without a partition fail again
+DROP TABLE IF EXISTS stock_movements_default;
+DROP TABLE IF EXISTS audit_log_default;
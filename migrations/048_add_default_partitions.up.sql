@@ -0,0 +1,6 @@
+-- Give the partitioned history tables a DEFAULT partition, so inserts for a
+-- month without a partition (e.g. a clock far ahead, or a restore of old rows)
+-- land there instead of failing. The partition maintenance job moves the rows
+-- into their monthly partitions.
+CREATE TABLE audit_log_default PARTITION OF audit_log DEFAULT;
+CREATE TABLE stock_movements_default PARTITION OF stock_movements DEFAULT;
@@ -0,0 +1,2 @@
+-- Drop the products table
+DROP TABLE IF EXISTS products;
@@ -0,0 +1,2 @@
+-- Drop the audit_log table
+DROP TABLE IF EXISTS audit_log;
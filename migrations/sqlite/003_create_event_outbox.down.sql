@@ -0,0 +1,2 @@
+-- Drop the event_outbox table
+DROP TABLE IF EXISTS event_outbox;
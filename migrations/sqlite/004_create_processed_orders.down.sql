@@ -0,0 +1,2 @@
+-- Drop the processed_orders table
+DROP TABLE IF EXISTS processed_orders;
@@ -0,0 +1,16 @@
+-- Create the processed_orders table
+-- This table makes order ingestion idempotent: an order is applied to stock at
+-- most once per source, no matter how often it is delivered
+CREATE TABLE IF NOT EXISTS processed_orders (
+    source VARCHAR(50) NOT NULL,
+    order_id VARCHAR(255) NOT NULL,
+    line_count INTEGER NOT NULL,
+
+    -- Metadata
+    processed_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+
+    PRIMARY KEY (source, order_id)
+);
+
+-- Create indexes for better query performance
+CREATE INDEX idx_processed_orders_processed_at ON processed_orders(processed_at DESC);
@@ -0,0 +1,2 @@
+-- Drop the catalog_sync_state table
+DROP TABLE IF EXISTS catalog_sync_state;
@@ -0,0 +1,17 @@
+-- Create the catalog_sync_state table
+-- One row per catalog connector, holding its sync watermark and the outcome
+-- of the last run
+CREATE TABLE IF NOT EXISTS catalog_sync_state (
+    connector VARCHAR(50) PRIMARY KEY,
+    last_run_at TIMESTAMP,
+    last_success_at TIMESTAMP,
+    last_error TEXT,
+
+    -- Counters of the last run
+    pulled INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    pushed INTEGER NOT NULL DEFAULT 0,
+    conflicts INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0
+);
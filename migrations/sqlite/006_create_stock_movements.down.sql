@@ -0,0 +1,4 @@
+-- Drop the stock_movements table and its triggers
+DROP TRIGGER IF EXISTS products_stock_movement_update;
+DROP TRIGGER IF EXISTS products_stock_movement_insert;
+DROP TABLE IF EXISTS stock_movements;
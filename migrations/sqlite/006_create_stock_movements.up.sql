@@ -0,0 +1,30 @@
+-- Stock changes of products, recorded by trigger
+-- Unlike on PostgreSQL it isn't partitioned
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL,
+    sku VARCHAR(255) NOT NULL,
+    delta INTEGER NOT NULL,
+    quantity_after INTEGER NOT NULL,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_stock_movements_product ON stock_movements(product_id, created_at DESC);
+
+CREATE TRIGGER products_stock_movement_insert
+    AFTER INSERT ON products
+    FOR EACH ROW WHEN NEW.quantity <> 0
+BEGIN
+    INSERT INTO stock_movements (product_id, sku, delta, quantity_after)
+    VALUES (NEW.id, NEW.sku, NEW.quantity, NEW.quantity);
+END;
+
+CREATE TRIGGER products_stock_movement_update
+    AFTER UPDATE OF quantity ON products
+    FOR EACH ROW WHEN NEW.quantity <> OLD.quantity
+BEGIN
+    INSERT INTO stock_movements (product_id, sku, delta, quantity_after)
+    VALUES (NEW.id, NEW.sku, NEW.quantity - OLD.quantity, NEW.quantity);
+END;
@@ -0,0 +1,5 @@
+-- Drop the products_history table and its triggers
+DROP TRIGGER IF EXISTS products_history_delete;
+DROP TRIGGER IF EXISTS products_history_update;
+DROP TRIGGER IF EXISTS products_history_insert;
+DROP TABLE IF EXISTS products_history;
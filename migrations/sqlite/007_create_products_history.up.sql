@@ -0,0 +1,59 @@
+-- Create the products_history table
+-- Every version of a product row, valid in [valid_from, valid_to); the current
+-- version has no valid_to. Maintained by triggers so every write path is covered.
+CREATE TABLE IF NOT EXISTS products_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL,
+    operation VARCHAR(10) NOT NULL, -- INSERT or UPDATE
+
+    -- Product state in this version
+    sku VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_price DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP
+);
+
+-- Create indexes for better query performance
+CREATE INDEX idx_products_history_product ON products_history(product_id, valid_from DESC);
+
+-- Open a version for every new product
+CREATE TRIGGER products_history_insert
+    AFTER INSERT ON products
+    FOR EACH ROW
+BEGIN
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, 'INSERT', NEW.sku, NEW.name, NEW.description, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    );
+END;
+
+-- Close the current version and open a new one
+CREATE TRIGGER products_history_update
+    AFTER UPDATE ON products
+    FOR EACH ROW
+BEGIN
+    UPDATE products_history SET valid_to = (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    WHERE product_id = OLD.id AND valid_to IS NULL;
+
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, 'UPDATE', NEW.sku, NEW.name, NEW.description, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    );
+END;
+
+-- Close the last version of a deleted product
+CREATE TRIGGER products_history_delete
+    AFTER DELETE ON products
+    FOR EACH ROW
+BEGIN
+    UPDATE products_history SET valid_to = (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    WHERE product_id = OLD.id AND valid_to IS NULL;
+END;
@@ -0,0 +1,2 @@
+-- Drop the queue_tasks table
+DROP TABLE IF EXISTS queue_tasks;
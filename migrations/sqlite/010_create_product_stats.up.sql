@@ -0,0 +1,31 @@
+-- SQLite has no materialized views: product_stats is a table rebuilt from
+-- product_stats_source on refresh, see internal/database/matview.go
+CREATE TABLE materialized_views (
+    name VARCHAR(255) PRIMARY KEY,
+    refreshed_at TIMESTAMP
+);
+
+CREATE VIEW product_stats_source AS
+SELECT
+    p.id AS product_id,
+    p.sku,
+    p.quantity,
+    ROUND(p.quantity * p.unit_price, 2) AS inventory_value,
+    COUNT(m.id) AS movements,
+    COALESCE(SUM(CASE WHEN m.delta > 0 THEN m.delta END), 0) AS units_in,
+    COALESCE(SUM(CASE WHEN m.delta < 0 THEN -m.delta END), 0) AS units_out
+FROM products p
+LEFT JOIN stock_movements m ON m.product_id = p.id
+GROUP BY p.id, p.sku, p.quantity, p.unit_price;
+
+CREATE TABLE product_stats (
+    product_id INTEGER PRIMARY KEY,
+    sku VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL,
+    inventory_value DECIMAL(12,2) NOT NULL,
+    movements INTEGER NOT NULL,
+    units_in INTEGER NOT NULL,
+    units_out INTEGER NOT NULL
+);
+
+INSERT INTO materialized_views (name) VALUES ('product_stats');
@@ -0,0 +1,31 @@
+-- Drop the products category
+DROP TRIGGER IF EXISTS products_history_insert;
+CREATE TRIGGER products_history_insert
+    AFTER INSERT ON products
+    FOR EACH ROW
+BEGIN
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, 'INSERT', NEW.sku, NEW.name, NEW.description, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    );
+END;
+
+DROP TRIGGER IF EXISTS products_history_update;
+CREATE TRIGGER products_history_update
+    AFTER UPDATE ON products
+    FOR EACH ROW
+BEGIN
+    UPDATE products_history SET valid_to = (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    WHERE product_id = OLD.id AND valid_to IS NULL;
+
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, 'UPDATE', NEW.sku, NEW.name, NEW.description, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    );
+END;
+
+DROP INDEX IF EXISTS idx_products_category;
+ALTER TABLE products_history DROP COLUMN category;
+ALTER TABLE products DROP COLUMN category;
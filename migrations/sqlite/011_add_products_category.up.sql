@@ -0,0 +1,34 @@
+-- Add a category to products, which tax rules and promotions can target.
+-- Empty means uncategorized.
+ALTER TABLE products ADD COLUMN category VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE products_history ADD COLUMN category VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_products_category ON products(category);
+
+-- Record the category in every version
+DROP TRIGGER IF EXISTS products_history_insert;
+CREATE TRIGGER products_history_insert
+    AFTER INSERT ON products
+    FOR EACH ROW
+BEGIN
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, category, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, 'INSERT', NEW.sku, NEW.name, NEW.description, NEW.category, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    );
+END;
+
+DROP TRIGGER IF EXISTS products_history_update;
+CREATE TRIGGER products_history_update
+    AFTER UPDATE ON products
+    FOR EACH ROW
+BEGIN
+    UPDATE products_history SET valid_to = (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    WHERE product_id = OLD.id AND valid_to IS NULL;
+
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, category, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, 'UPDATE', NEW.sku, NEW.name, NEW.description, NEW.category, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    );
+END;
@@ -0,0 +1,10 @@
+-- Create the product_tags table
+-- Free-form labels on products, which promotions can target; tags are stored
+-- lowercase
+CREATE TABLE IF NOT EXISTS product_tags (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    tag VARCHAR(100) NOT NULL,
+    PRIMARY KEY (product_id, tag)
+);
+
+CREATE INDEX idx_product_tags_tag ON product_tags(tag);
@@ -0,0 +1,2 @@
+-- Drop the promotions table
+DROP TABLE IF EXISTS promotions;
@@ -0,0 +1,19 @@
+-- Create the promotions table
+-- Discounts on unit prices, in effect during [starts_at, ends_at) for the
+-- products their scope covers
+CREATE TABLE IF NOT EXISTS promotions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL,  -- percentage or fixed
+    value DECIMAL(10,2) NOT NULL,
+    scope VARCHAR(20) NOT NULL, -- all, product, category, or tag
+    target VARCHAR(255) NOT NULL DEFAULT '',
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    stackable BOOLEAN NOT NULL DEFAULT FALSE,
+    priority INTEGER NOT NULL DEFAULT 0,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
@@ -0,0 +1,2 @@
+-- Drop the bundle_components table
+DROP TABLE IF EXISTS bundle_components;
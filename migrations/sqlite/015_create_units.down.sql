@@ -0,0 +1,59 @@
+-- Drop units of measure
+DROP TABLE IF EXISTS product_stats;
+DROP VIEW IF EXISTS product_stats_source;
+
+CREATE VIEW product_stats_source AS
+SELECT
+    p.id AS product_id,
+    p.sku,
+    p.quantity,
+    ROUND(p.quantity * p.unit_price, 2) AS inventory_value,
+    COUNT(m.id) AS movements,
+    COALESCE(SUM(CASE WHEN m.delta > 0 THEN m.delta END), 0) AS units_in,
+    COALESCE(SUM(CASE WHEN m.delta < 0 THEN -m.delta END), 0) AS units_out
+FROM products p
+LEFT JOIN stock_movements m ON m.product_id = p.id
+GROUP BY p.id, p.sku, p.quantity, p.unit_price;
+
+CREATE TABLE product_stats (
+    product_id INTEGER PRIMARY KEY,
+    sku VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL,
+    inventory_value DECIMAL(12,2) NOT NULL,
+    movements INTEGER NOT NULL,
+    units_in INTEGER NOT NULL,
+    units_out INTEGER NOT NULL
+);
+
+UPDATE materialized_views SET refreshed_at = NULL WHERE name = 'product_stats';
+
+DROP TRIGGER IF EXISTS products_history_insert;
+CREATE TRIGGER products_history_insert
+    AFTER INSERT ON products
+    FOR EACH ROW
+BEGIN
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, category, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, 'INSERT', NEW.sku, NEW.name, NEW.description, NEW.category, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    );
+END;
+
+DROP TRIGGER IF EXISTS products_history_update;
+CREATE TRIGGER products_history_update
+    AFTER UPDATE ON products
+    FOR EACH ROW
+BEGIN
+    UPDATE products_history SET valid_to = (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    WHERE product_id = OLD.id AND valid_to IS NULL;
+
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, category, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, 'UPDATE', NEW.sku, NEW.name, NEW.description, NEW.category, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    );
+END;
+
+ALTER TABLE products_history DROP COLUMN unit;
+ALTER TABLE products DROP COLUMN unit;
+DROP TABLE IF EXISTS units;
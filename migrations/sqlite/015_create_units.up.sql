@@ -0,0 +1,88 @@
+-- Units of measure and their conversions. Each unit is factor times the base
+-- unit of its dimension (the unit with factor 1): quantities convert exactly
+-- between units of one dimension, and add up in base units.
+CREATE TABLE IF NOT EXISTS units (
+    code VARCHAR(20) PRIMARY KEY,
+    dimension VARCHAR(20) NOT NULL,
+    factor INTEGER NOT NULL CHECK (factor > 0)
+);
+
+-- One base unit per dimension
+CREATE UNIQUE INDEX idx_units_base ON units(dimension) WHERE factor = 1;
+
+INSERT INTO units (code, dimension, factor) VALUES
+    ('each', 'count', 1),
+    ('box-of-12', 'count', 12),
+    ('g', 'mass', 1),
+    ('kg', 'mass', 1000),
+    ('ml', 'volume', 1),
+    ('liter', 'volume', 1000);
+
+-- The unit a product's quantity and unit price are in. SQLite can't add a
+-- column referencing units with a default, so the API checks it.
+ALTER TABLE products ADD COLUMN unit VARCHAR(20) NOT NULL DEFAULT 'each';
+ALTER TABLE products_history ADD COLUMN unit VARCHAR(20) NOT NULL DEFAULT 'each';
+
+-- Record the unit in every version
+DROP TRIGGER IF EXISTS products_history_insert;
+CREATE TRIGGER products_history_insert
+    AFTER INSERT ON products
+    FOR EACH ROW
+BEGIN
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, category, unit, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, 'INSERT', NEW.sku, NEW.name, NEW.description, NEW.category, NEW.unit, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    );
+END;
+
+DROP TRIGGER IF EXISTS products_history_update;
+CREATE TRIGGER products_history_update
+    AFTER UPDATE ON products
+    FOR EACH ROW
+BEGIN
+    UPDATE products_history SET valid_to = (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    WHERE product_id = OLD.id AND valid_to IS NULL;
+
+    INSERT INTO products_history (
+        product_id, operation, sku, name, description, category, unit, quantity, unit_price, created_at, updated_at, valid_from
+    ) VALUES (
+        NEW.id, 'UPDATE', NEW.sku, NEW.name, NEW.description, NEW.category, NEW.unit, NEW.quantity, NEW.unit_price, NEW.created_at, NEW.updated_at, (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+    );
+END;
+
+-- Stats normalized to base units, so totals add up per dimension
+DROP TABLE IF EXISTS product_stats;
+DROP VIEW IF EXISTS product_stats_source;
+
+CREATE VIEW product_stats_source AS
+SELECT
+    p.id AS product_id,
+    p.sku,
+    p.unit,
+    u.dimension,
+    p.quantity,
+    p.quantity * u.factor AS base_quantity,
+    ROUND(p.quantity * p.unit_price, 2) AS inventory_value,
+    COUNT(m.id) AS movements,
+    COALESCE(SUM(CASE WHEN m.delta > 0 THEN m.delta END), 0) AS units_in,
+    COALESCE(SUM(CASE WHEN m.delta < 0 THEN -m.delta END), 0) AS units_out
+FROM products p
+JOIN units u ON u.code = p.unit
+LEFT JOIN stock_movements m ON m.product_id = p.id
+GROUP BY p.id, p.sku, p.unit, u.dimension, u.factor, p.quantity, p.unit_price;
+
+CREATE TABLE product_stats (
+    product_id INTEGER PRIMARY KEY,
+    sku VARCHAR(255) NOT NULL,
+    unit VARCHAR(20) NOT NULL,
+    dimension VARCHAR(20) NOT NULL,
+    quantity INTEGER NOT NULL,
+    base_quantity INTEGER NOT NULL,
+    inventory_value DECIMAL(12,2) NOT NULL,
+    movements INTEGER NOT NULL,
+    units_in INTEGER NOT NULL,
+    units_out INTEGER NOT NULL
+);
+
+UPDATE materialized_views SET refreshed_at = NULL WHERE name = 'product_stats';
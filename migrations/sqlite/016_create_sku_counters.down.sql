@@ -0,0 +1,2 @@
+-- Drop the numbering for generated SKUs
+DROP TABLE IF EXISTS sku_counters;
@@ -0,0 +1,7 @@
+-- Create the numbering for generated SKUs
+-- sku_counters holds the last number of each scope (the SKU pattern without
+-- its number); SQLite has no sequences, so numbering is always gapless
+CREATE TABLE IF NOT EXISTS sku_counters (
+    scope VARCHAR(255) PRIMARY KEY,
+    value INTEGER NOT NULL CHECK (value > 0)
+);
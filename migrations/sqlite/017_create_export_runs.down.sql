@@ -0,0 +1,2 @@
+-- Drop the export_runs table
+DROP TABLE IF EXISTS export_runs;
@@ -0,0 +1,3 @@
+-- Drop the outbox transaction ids
+DROP INDEX IF EXISTS idx_event_outbox_txid;
+ALTER TABLE event_outbox DROP COLUMN txid;
@@ -0,0 +1,6 @@
+-- Record the transaction that wrote each outbox message for the change feed.
+-- SQLite has a single writer, so messages commit in id order and the feed
+-- needs no transaction ids; every message records 0.
+ALTER TABLE event_outbox ADD COLUMN txid INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_txid ON event_outbox(txid, id);
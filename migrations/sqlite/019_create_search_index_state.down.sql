@@ -0,0 +1,2 @@
+-- Drop the search_index_state table
+DROP TABLE IF EXISTS search_index_state;
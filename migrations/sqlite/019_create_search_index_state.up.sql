@@ -0,0 +1,11 @@
+-- Create the search_index_state table
+-- Tracks the external search index (OpenSearch) an alias points to and the
+-- change feed cursor applied to it. SQLite searches products without a
+-- full-text index.
+CREATE TABLE IF NOT EXISTS search_index_state (
+    name VARCHAR(255) PRIMARY KEY,
+    index_name VARCHAR(255) NOT NULL,
+    feed_cursor VARCHAR(100) NOT NULL,
+    reindexed_at TIMESTAMP NOT NULL,
+    synced_at TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- Drop the saved_searches table
+DROP TABLE IF EXISTS saved_searches;
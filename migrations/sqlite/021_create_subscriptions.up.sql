@@ -0,0 +1,15 @@
+-- Create the subscriptions table
+-- Callbacks notified when the listed fields of a product change; fields is a
+-- JSON array of product field names
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    fields JSONB NOT NULL,
+    callback_url TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_subscriptions_product_id ON subscriptions(product_id);
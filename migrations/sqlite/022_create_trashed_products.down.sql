@@ -0,0 +1,2 @@
+-- Drop the trashed_products table
+DROP TABLE IF EXISTS trashed_products;
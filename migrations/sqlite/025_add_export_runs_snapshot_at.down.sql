@@ -0,0 +1,2 @@
+-- Drop the snapshot time of exports
+ALTER TABLE export_runs DROP COLUMN snapshot_at;
@@ -0,0 +1,3 @@
+-- Drop the inventory_valuations and stock_receipts tables
+DROP TABLE IF EXISTS inventory_valuations;
+DROP TABLE IF EXISTS stock_receipts;
@@ -0,0 +1,3 @@
+-- Drop the stock_take_counts and stock_takes tables
+DROP TABLE IF EXISTS stock_take_counts;
+DROP TABLE IF EXISTS stock_takes;
@@ -0,0 +1,4 @@
+-- Drop the purchase_order_lines, purchase_orders, and product_suppliers tables
+DROP TABLE IF EXISTS purchase_order_lines;
+DROP TABLE IF EXISTS purchase_orders;
+DROP TABLE IF EXISTS product_suppliers;
@@ -0,0 +1,2 @@
+-- Drop the report_definitions table
+DROP TABLE IF EXISTS report_definitions;
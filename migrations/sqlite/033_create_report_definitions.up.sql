@@ -0,0 +1,14 @@
+-- Create the report_definitions table
+-- Named SQL templates admins run with POST /reports/{name}/run; params is the
+-- JSON of []models.ReportParam, and the query is validated before it's stored
+CREATE TABLE IF NOT EXISTS report_definitions (
+    name VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    query TEXT NOT NULL,
+    params JSONB NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
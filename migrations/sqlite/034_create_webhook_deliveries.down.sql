@@ -0,0 +1,2 @@
+-- Drop the webhook_deliveries table
+DROP TABLE IF EXISTS webhook_deliveries;
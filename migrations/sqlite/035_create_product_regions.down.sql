@@ -0,0 +1,3 @@
+-- Drop the product_regions table and the region of api_keys
+ALTER TABLE api_keys DROP COLUMN region;
+DROP TABLE IF EXISTS product_regions;
@@ -0,0 +1,13 @@
+-- Create the product_regions table and add a region to api_keys
+-- The sales regions a product is limited to, stored uppercase ("DE"); a
+-- product without any is sold everywhere. Keys with a region are regional
+-- storefronts, which only see the products sold there.
+CREATE TABLE IF NOT EXISTS product_regions (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    region VARCHAR(32) NOT NULL,
+    PRIMARY KEY (product_id, region)
+);
+
+CREATE INDEX idx_product_regions_region ON product_regions(region);
+
+ALTER TABLE api_keys ADD COLUMN region VARCHAR(32) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Drop the product_revisions table
+DROP TABLE IF EXISTS product_revisions;
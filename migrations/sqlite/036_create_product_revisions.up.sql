@@ -0,0 +1,21 @@
+-- Create the product_revisions table
+-- Updates of products by editors, as the JSON of models.Product, held until
+-- an approver approves (applies) or rejects them. base_updated_at is the
+-- product's updated_at when the revision was proposed; a revision whose
+-- product has changed since can't be approved.
+CREATE TABLE IF NOT EXISTS product_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    product JSONB NOT NULL,
+    base_updated_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, approved, or rejected
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    reviewer VARCHAR(255) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    reviewed_at TIMESTAMP
+);
+
+CREATE INDEX idx_product_revisions_product_status ON product_revisions(product_id, status);
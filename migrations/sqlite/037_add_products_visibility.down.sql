@@ -0,0 +1,6 @@
+-- Drop the visibility windows of products
+DROP INDEX IF EXISTS idx_products_visible_until;
+DROP INDEX IF EXISTS idx_products_visibility;
+ALTER TABLE products DROP COLUMN listed;
+ALTER TABLE products DROP COLUMN visible_until;
+ALTER TABLE products DROP COLUMN visible_from;
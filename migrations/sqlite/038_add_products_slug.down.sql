@@ -0,0 +1,4 @@
+-- Drop the slugs of products and their history
+DROP TABLE IF EXISTS product_slugs;
+DROP INDEX IF EXISTS idx_products_slug;
+ALTER TABLE products DROP COLUMN slug;
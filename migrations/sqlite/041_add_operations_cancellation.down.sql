@@ -0,0 +1,3 @@
+-- Drop operation checkpoints and cancellations
+ALTER TABLE operations DROP COLUMN cancel_requested_at;
+ALTER TABLE operations DROP COLUMN checkpoint;
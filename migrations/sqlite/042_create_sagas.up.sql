@@ -0,0 +1,28 @@
+-- Create the sagas table
+-- State of multi-step workflows across systems (internal/workflow). step is
+-- the next step to run while running, and the number of steps left to
+-- compensate while compensating. data holds the input and what steps
+-- recorded for later steps and compensations. The instance running a saga
+-- claims it until claimed_until, renewing the claim while it runs; sagas
+-- still running or compensating whose claim has lapsed were interrupted and
+-- are resumed.
+CREATE TABLE IF NOT EXISTS sagas (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    saga_key VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    step INTEGER NOT NULL DEFAULT 0,
+    data JSONB NOT NULL DEFAULT '{}',
+    error TEXT,
+    claimed_by VARCHAR(64) NOT NULL DEFAULT '',
+    claimed_until TIMESTAMP,
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    finished_at TIMESTAMP,
+
+    UNIQUE (name, saga_key)
+);
+
+CREATE INDEX idx_sagas_unfinished ON sagas(id) WHERE finished_at IS NULL;
@@ -0,0 +1,3 @@
+-- Drop the inbox_messages table and its associated indexes
+DROP INDEX IF EXISTS idx_inbox_messages_received_at;
+DROP TABLE IF EXISTS inbox_messages;